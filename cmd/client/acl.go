@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/openmined/syftbox/internal/aclspec"
+	"github.com/openmined/syftbox/internal/client/config"
+	"github.com/openmined/syftbox/internal/client/handlers"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	aclCmd := newACLCmd()
+	aclCmd.AddCommand(newACLCmdPreview())
+	rootCmd.AddCommand(aclCmd)
+}
+
+func newACLCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "acl",
+		Short: "Inspect the access control of your datasite",
+	}
+}
+
+func newACLCmdPreview() *cobra.Command {
+	var output string
+	var proposedFile string
+
+	cmd := &cobra.Command{
+		Use:   "preview [FOLDER]",
+		Short: "Show who would gain or lose access with a proposed syft.pub.yaml",
+		Long: `Compare who can access the files under FOLDER with its current syft.pub.yaml and with a proposed one.
+FOLDER is a path in the workspace datasites directory, e.g. user@example.com/public, or an absolute path to it.
+Nothing is changed. The rules resolved per user are previewed for the users named in the paths,
+and each attribute condition as a principal of its own, e.g. group:researchers.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+
+			if output != configOutputText && output != configOutputJSON {
+				return fmt.Errorf("invalid output format %q", output)
+			}
+
+			proposed, err := os.ReadFile(proposedFile)
+			if err != nil {
+				return fmt.Errorf("read proposed acl: %w", err)
+			}
+
+			ws, err := loadWorkspace(cmd)
+			if err != nil {
+				return err
+			}
+
+			absDir := args[0]
+			if !filepath.IsAbs(absDir) {
+				absDir = filepath.Join(ws.DatasitesDir, absDir)
+			}
+
+			preview, err := handlers.PreviewACLChange(ws, absDir, proposed)
+			if err != nil {
+				return err
+			}
+
+			return printACLPreview(cmd.OutOrStdout(), preview, output)
+		},
+	}
+
+	cmd.Flags().SortFlags = false
+	cmd.Flags().StringVarP(&proposedFile, "file", "f", "", "proposed "+aclspec.FileName+" to compare with the current one")
+	cmd.Flags().StringP("email", "e", "", "your email for your syftbox datasite")
+	cmd.Flags().StringP("datadir", "d", config.DefaultDataDir, "data directory where the syftbox workspace is stored")
+	cmd.Flags().StringP("server", "s", config.DefaultServerURL, "url of the syftbox server")
+	cmd.Flags().StringVarP(&output, "output", "o", configOutputText, "output format (text, json)")
+	cmd.MarkFlagRequired("file")
+
+	return cmd
+}
+
+func printACLPreview(w io.Writer, preview *handlers.WorkspaceACLPreviewResponse, output string) error {
+	if output == configOutputJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(preview)
+	}
+
+	if preview.ShadowedBy != "" {
+		fmt.Fprintf(w, "%s the terminal %s in %s applies instead\n", lightGray.Render("no change,"), aclspec.FileName, preview.ShadowedBy)
+		return nil
+	}
+	if len(preview.Granted) == 0 && len(preview.Revoked) == 0 {
+		fmt.Fprintln(w, lightGray.Render("no access changes"))
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", "CHANGE", "PRINCIPAL", "ACCESS", "PATH")
+	for _, group := range []struct {
+		change  string
+		changes []handlers.ACLAccessChange
+	}{
+		{"grant", preview.Granted},
+		{"revoke", preview.Revoked},
+	} {
+		for _, change := range group.changes {
+			fmt.Fprintf(tw, "%s\t%s\t%s -> %s\t%s\n", group.change, change.Principal, accessOrNone(change.Before), accessOrNone(change.After), change.Path)
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "%s %d grants, %d revokes\n", lightGray.Render("would change"), len(preview.Granted), len(preview.Revoked))
+	return nil
+}
+
+func accessOrNone(access string) string {
+	if access == "" {
+		return "none"
+	}
+	return access
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/openmined/syftbox/internal/client/config"
+	"github.com/openmined/syftbox/internal/client/workspace"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestACLCmd builds `acl` under a fresh root, so that flags don't leak between tests
+func newTestACLCmd() *cobra.Command {
+	root := &cobra.Command{Use: "syftbox"}
+	root.PersistentFlags().StringP("config", "c", config.DefaultConfigPath, "path to config file")
+	root.PersistentFlags().StringP("profile", "p", "", "profile of the config file to use")
+
+	aclCmd := newACLCmd()
+	aclCmd.AddCommand(newACLCmdPreview())
+	root.AddCommand(aclCmd)
+	return root
+}
+
+func TestACLPreview(t *testing.T) {
+	dir := t.TempDir()
+	dataDir := filepath.Join(dir, "SyftBox")
+	configFile := filepath.Join(dir, "config.json")
+	require.NoError(t, os.WriteFile(configFile, []byte(`{
+	"email": "user@example.com",
+	"data_dir": "`+filepath.ToSlash(dataDir)+`",
+	"server_url": "https://syftbox.net"
+}`), 0o644))
+
+	ws, err := workspace.NewWorkspace(dataDir, "user@example.com")
+	require.NoError(t, err)
+	public := filepath.Join(ws.DatasitesDir, "user@example.com", "public")
+	require.NoError(t, os.MkdirAll(public, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(public, "syft.pub.yaml"), []byte("rules:\n  - pattern: '**'\n    access:\n      read: ['*']\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(public, "report.csv"), []byte("a,b\n"), 0o644))
+
+	proposedFile := filepath.Join(dir, "proposed.yaml")
+	require.NoError(t, os.WriteFile(proposedFile, []byte("rules:\n  - pattern: '*.csv'\n    access:\n      read: ['bob@example.com']\n"), 0o644))
+
+	var stdout bytes.Buffer
+	root := newTestACLCmd()
+	root.SetOut(&stdout)
+	root.SetErr(&bytes.Buffer{})
+	root.SetArgs([]string{"acl", "preview", "user@example.com/public", "-f", proposedFile, "--config", configFile})
+	require.NoError(t, root.Execute())
+
+	out := stdout.String()
+	// bob could read report.csv as everyone did, and keeps reading it
+	assert.NotContains(t, out, "\ngrant ")
+	assert.Contains(t, out, "revoke  *                read -> none  /datasites/user@example.com/public/report.csv")
+	assert.Contains(t, out, "revoke  *                read -> none  /datasites/user@example.com/public/syft.pub.yaml")
+	assert.Contains(t, out, "revoke  bob@example.com  read -> none  /datasites/user@example.com/public/syft.pub.yaml")
+	assert.Contains(t, out, "0 grants, 3 revokes")
+}
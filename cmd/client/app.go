@@ -3,9 +3,11 @@ package main
 import (
 	"fmt"
 	"os"
+	"slices"
 	"strings"
 
 	"github.com/openmined/syftbox/internal/client/apps"
+	"github.com/openmined/syftbox/internal/client/config"
 	"github.com/openmined/syftbox/internal/client/workspace"
 	"github.com/spf13/cobra"
 )
@@ -15,6 +17,7 @@ func init() {
 	appCmd.AddCommand(newAppCmdList())
 	appCmd.AddCommand(newAppCmdInstall())
 	appCmd.AddCommand(newAppCmdUninstall())
+	appCmd.AddCommand(newAppCmdApprove())
 	rootCmd.AddCommand(appCmd)
 }
 
@@ -149,15 +152,116 @@ func newAppCmdList() *cobra.Command {
 	return appCmdList
 }
 
+func newAppCmdApprove() *cobra.Command {
+	var all bool
+
+	appCmdApprove := &cobra.Command{
+		Use:   "approve [NAME...]",
+		Short: "Approve the new or changed apps of the app manifest",
+		Long: `Lists the apps of the app manifest that are new or changed since they were approved, with what they run.
+The named apps are approved, and the client installs and runs them the next time it checks the manifest.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			configPath := cmd.Flag("config").Value.String()
+			cfg, err := readValidConfig(configPath, profileName(cmd), false)
+			if err != nil {
+				fmt.Printf("%s: %s\n", red.Render("ERROR"), err)
+				os.Exit(1)
+			}
+			if cfg.AppManifest == "" {
+				fmt.Printf("%s: no app manifest, set app_manifest in the config to install apps from one\n", red.Render("ERROR"))
+				os.Exit(1)
+			}
+
+			manager, err := newAppManager(cfg)
+			if err != nil {
+				fmt.Printf("%s: %s\n", red.Render("ERROR"), err)
+				os.Exit(1)
+			}
+			manifest, err := apps.LoadManifest(cfg.AppManifest)
+			if err != nil {
+				fmt.Printf("%s: %s\n", red.Render("ERROR"), err)
+				os.Exit(1)
+			}
+			pending, err := manager.PendingManifestApps(manifest)
+			if err != nil {
+				fmt.Printf("%s: %s\n", red.Render("ERROR"), err)
+				os.Exit(1)
+			}
+
+			if len(args) == 0 && !all {
+				if len(pending) == 0 {
+					fmt.Printf("No apps waiting for approval in '%s'\n", cyan.Render(cfg.AppManifest))
+					return
+				}
+				var sb strings.Builder
+				for _, app := range pending {
+					sb.WriteString(fmt.Sprintf("%s%s\n", gray.Render("Name    "), green.Render(app.Name)))
+					if app.Source != "" {
+						sb.WriteString(fmt.Sprintf("%s%s %s\n", gray.Render("Source  "), app.Source, manifestAppRef(app)))
+					} else {
+						sb.WriteString(fmt.Sprintf("%s%s\n", gray.Render("Command "), app.Command))
+					}
+					sb.WriteString("\n")
+				}
+				sb.WriteString("Approve them with 'syftbox app approve NAME...' or '--all'\n")
+				fmt.Print(sb.String())
+				return
+			}
+
+			approve := pending
+			if !all {
+				approve = nil
+				for _, name := range args {
+					idx := slices.IndexFunc(pending, func(app *apps.ManifestApp) bool { return app.Name == name })
+					if idx < 0 {
+						fmt.Printf("%s: app '%s' isn't waiting for approval\n", red.Render("ERROR"), name)
+						os.Exit(1)
+					}
+					approve = append(approve, pending[idx])
+				}
+			}
+
+			if err := manager.ApproveManifestApps(approve...); err != nil {
+				fmt.Printf("%s: %s\n", red.Render("ERROR"), err)
+				os.Exit(1)
+			}
+			for _, app := range approve {
+				fmt.Printf("Approved '%s'\n", green.Bold(true).Render(app.Name))
+			}
+		},
+	}
+
+	appCmdApprove.Flags().BoolVarP(&all, "all", "a", false, "Approve every app waiting for approval")
+
+	return appCmdApprove
+}
+
+// manifestAppRef is the git ref a manifest app is installed from
+func manifestAppRef(app *apps.ManifestApp) string {
+	switch {
+	case app.Commit != "":
+		return "(" + app.Commit + ")"
+	case app.Tag != "":
+		return "(" + app.Tag + ")"
+	case app.Branch != "":
+		return "(" + app.Branch + ")"
+	}
+	return ""
+}
+
 func getAppManager(cmd *cobra.Command) (*apps.AppManager, error) {
 	// fetched from main/rootCmd/persistentFlags
 	configPath := cmd.Flag("config").Value.String()
 
-	cfg, err := readValidConfig(configPath, false)
+	cfg, err := readValidConfig(configPath, profileName(cmd), false)
 	if err != nil {
 		return nil, err
 	}
 
+	return newAppManager(cfg)
+}
+
+func newAppManager(cfg *config.Config) (*apps.AppManager, error) {
 	datasite, err := workspace.NewWorkspace(cfg.DataDir, cfg.Email)
 	if err != nil {
 		return nil, err
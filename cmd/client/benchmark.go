@@ -0,0 +1,314 @@
+package main
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/openmined/syftbox/internal/client/config"
+	"github.com/openmined/syftbox/internal/syftsdk"
+	"github.com/openmined/syftbox/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(newBenchmarkCmd())
+}
+
+func newBenchmarkCmd() *cobra.Command {
+	var (
+		opts     benchmarkOptions
+		fileSize string
+		output   string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "benchmark",
+		Short: "Measure the upload and download throughput to the server",
+		Long: `Measure the throughput and latency achievable from this machine to the server.
+Uploads files of random data to a hidden dir of your datasite, downloads them back,
+then deletes them again. Files are transferred one at a time.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+
+			if output != configOutputText && output != configOutputJSON {
+				return fmt.Errorf("invalid output format %q", output)
+			}
+
+			size, err := humanize.ParseBytes(fileSize)
+			if err != nil || size == 0 {
+				return fmt.Errorf("invalid file size %q", fileSize)
+			}
+			opts.FileSize = int64(size)
+
+			if opts.Files <= 0 {
+				return fmt.Errorf("invalid number of files %d", opts.Files)
+			}
+
+			cfg, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+
+			if err := cfg.Validate(); err != nil {
+				return fmt.Errorf("invalid config: %w", err)
+			}
+
+			report, err := runBenchmark(cmd.Context(), cfg, &opts)
+			if err != nil {
+				return err
+			}
+
+			return printBenchmark(cmd.OutOrStdout(), report, output)
+		},
+	}
+
+	cmd.Flags().SortFlags = false
+	cmd.Flags().StringP("email", "e", "", "your email for your syftbox datasite")
+	cmd.Flags().StringP("datadir", "d", config.DefaultDataDir, "data directory where the syftbox workspace is stored")
+	cmd.Flags().StringP("server", "s", config.DefaultServerURL, "url of the syftbox server")
+	cmd.Flags().IntVarP(&opts.Files, "files", "n", 10, "number of files to transfer")
+	cmd.Flags().StringVar(&fileSize, "file-size", "1MiB", "size of each file, e.g. 512KiB or 10MB")
+	cmd.Flags().StringVarP(&output, "output", "o", configOutputText, "output format (text, json)")
+
+	return cmd
+}
+
+type benchmarkOptions struct {
+	Files    int   // number of files to transfer
+	FileSize int64 // size of each file in bytes
+}
+
+// benchmarkReport is the result of a benchmark, printed as is with --output json
+type benchmarkReport struct {
+	Server   string          `json:"server"`
+	FileSize int64           `json:"file_size"`
+	Upload   *benchmarkStats `json:"upload"`
+	Download *benchmarkStats `json:"download"`
+}
+
+// benchmarkStats summarizes the transfers in one direction
+type benchmarkStats struct {
+	Files          int     `json:"files"`
+	Bytes          int64   `json:"bytes"`
+	DurationMs     float64 `json:"duration_ms"`     // time spent transferring, one file at a time
+	ThroughputMBps float64 `json:"throughput_mbps"` // MiB per second
+	LatencyP50Ms   float64 `json:"latency_p50_ms"`
+	LatencyP90Ms   float64 `json:"latency_p90_ms"`
+	LatencyP99Ms   float64 `json:"latency_p99_ms"`
+	LatencyMaxMs   float64 `json:"latency_max_ms"`
+}
+
+func newBenchmarkStats(latencies []time.Duration, fileSize int64) *benchmarkStats {
+	sorted := slices.Clone(latencies)
+	slices.Sort(sorted)
+
+	var total time.Duration
+	for _, latency := range sorted {
+		total += latency
+	}
+
+	stats := &benchmarkStats{
+		Files:      len(sorted),
+		Bytes:      int64(len(sorted)) * fileSize,
+		DurationMs: durationMs(total),
+	}
+	if len(sorted) > 0 {
+		stats.LatencyP50Ms = durationMs(utils.Percentile(sorted, 0.50))
+		stats.LatencyP90Ms = durationMs(utils.Percentile(sorted, 0.90))
+		stats.LatencyP99Ms = durationMs(utils.Percentile(sorted, 0.99))
+		stats.LatencyMaxMs = durationMs(sorted[len(sorted)-1])
+	}
+	if total > 0 {
+		stats.ThroughputMBps = float64(stats.Bytes) / total.Seconds() / (1024 * 1024)
+	}
+	return stats
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// runBenchmark uploads the files, downloads them back and checks their content.
+// The uploaded files are deleted at the end, even if the benchmark failed.
+func runBenchmark(ctx context.Context, cfg *config.Config, opts *benchmarkOptions) (report *benchmarkReport, err error) {
+	sdk, err := syftsdk.New(&syftsdk.SyftSDKConfig{
+		BaseURL:      cfg.ServerURL,
+		Email:        cfg.Email,
+		RefreshToken: cfg.RefreshToken,
+		AccessToken:  cfg.AccessToken,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer sdk.Close()
+
+	if err := sdk.Authenticate(ctx); err != nil {
+		return nil, fmt.Errorf("authenticate: %w", err)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "syftbox-benchmark-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// hidden, so that the files are neither shared nor synced by the clients of the datasite
+	id := make([]byte, 4)
+	rand.Read(id)
+	prefix := fmt.Sprintf("%s/.syftbox-benchmark-%s", cfg.Email, hex.EncodeToString(id))
+
+	var uploaded []string
+	defer func() {
+		if len(uploaded) == 0 {
+			return
+		}
+		if cleanupErr := deleteBenchmarkFiles(context.WithoutCancel(ctx), sdk, uploaded); cleanupErr != nil {
+			err = errors.Join(err, fmt.Errorf("clean up: %w", cleanupErr))
+		}
+	}()
+
+	// generating the data is not part of the measurement
+	checksums := make(map[string]string, opts.Files)
+	uploadLatencies := make([]time.Duration, 0, opts.Files)
+	for i := range opts.Files {
+		path := filepath.Join(tmpDir, fmt.Sprintf("%d.bin", i))
+		checksum, err := writeRandomFile(path, opts.FileSize)
+		if err != nil {
+			return nil, err
+		}
+
+		key := fmt.Sprintf("%s/%d.bin", prefix, i)
+		start := time.Now()
+		if _, err := sdk.Blob.Upload(ctx, &syftsdk.UploadParams{Key: key, FilePath: path}); err != nil {
+			return nil, fmt.Errorf("upload %q: %w", key, err)
+		}
+		uploadLatencies = append(uploadLatencies, time.Since(start))
+		uploaded = append(uploaded, key)
+		checksums[key] = checksum
+
+		os.Remove(path)
+	}
+
+	downloadDir := filepath.Join(tmpDir, "download")
+	downloadLatencies := make([]time.Duration, 0, opts.Files)
+	for _, key := range uploaded {
+		start := time.Now()
+		path, err := downloadBenchmarkFile(ctx, sdk, key, downloadDir)
+		if err != nil {
+			return nil, fmt.Errorf("download %q: %w", key, err)
+		}
+		downloadLatencies = append(downloadLatencies, time.Since(start))
+
+		checksum, err := fileMD5(path)
+		if err != nil {
+			return nil, err
+		}
+		if checksum != checksums[key] {
+			return nil, fmt.Errorf("download %q: content mismatch", key)
+		}
+
+		os.Remove(path)
+	}
+
+	return &benchmarkReport{
+		Server:   cfg.ServerURL,
+		FileSize: opts.FileSize,
+		Upload:   newBenchmarkStats(uploadLatencies, opts.FileSize),
+		Download: newBenchmarkStats(downloadLatencies, opts.FileSize),
+	}, nil
+}
+
+func downloadBenchmarkFile(ctx context.Context, sdk *syftsdk.SyftSDK, key string, dir string) (string, error) {
+	resp, err := sdk.Blob.Download(ctx, &syftsdk.PresignedParams{Keys: []string{key}})
+	if err != nil {
+		return "", err
+	}
+
+	if len(resp.Errors) > 0 {
+		return "", resp.Errors[0]
+	}
+
+	if len(resp.URLs) == 0 {
+		return "", errors.New("no download url")
+	}
+
+	return syftsdk.DownloadFile(ctx, &syftsdk.DownloadJob{URL: resp.URLs[0].URL, TargetDir: dir, Name: filepath.Base(key)})
+}
+
+func deleteBenchmarkFiles(ctx context.Context, sdk *syftsdk.SyftSDK, keys []string) error {
+	resp, err := sdk.Blob.Delete(ctx, &syftsdk.DeleteParams{Keys: keys})
+	if err != nil {
+		return err
+	}
+
+	if len(resp.Errors) > 0 {
+		return resp.Errors[0]
+	}
+	return nil
+}
+
+// writeRandomFile writes size random bytes to path and returns their md5
+func writeRandomFile(path string, size int64) (string, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := md5.New()
+	if _, err := io.CopyN(io.MultiWriter(file, hash), rand.Reader, size); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), file.Close()
+}
+
+func fileMD5(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := md5.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func printBenchmark(w io.Writer, report *benchmarkReport, output string) error {
+	if output == configOutputJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+
+	fmt.Fprintf(w, "%s %s\n", lightGray.Render("SYFTBOX BENCHMARK"), report.Server)
+	fmt.Fprintf(w, "%d files of %s\n\n", report.Upload.Files, humanize.IBytes(uint64(report.FileSize)))
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "\tTHROUGHPUT\tP50\tP90\tP99\tMAX\n")
+	for _, row := range []struct {
+		name  string
+		stats *benchmarkStats
+	}{
+		{"upload", report.Upload},
+		{"download", report.Download},
+	} {
+		fmt.Fprintf(tw, "%s\t%.2f MB/s\t%.1f ms\t%.1f ms\t%.1f ms\t%.1f ms\n", row.name, row.stats.ThroughputMBps,
+			row.stats.LatencyP50Ms, row.stats.LatencyP90Ms, row.stats.LatencyP99Ms, row.stats.LatencyMaxMs)
+	}
+	return tw.Flush()
+}
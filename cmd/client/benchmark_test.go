@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBenchmark(t *testing.T) {
+	srv := newCheckServer(t)
+
+	report, err := runBenchmark(context.Background(), newCheckConfig(t, srv.URL), &benchmarkOptions{Files: 3, FileSize: 4096})
+	require.NoError(t, err)
+
+	assert.Equal(t, srv.URL, report.Server)
+	for _, stats := range []*benchmarkStats{report.Upload, report.Download} {
+		assert.Equal(t, 3, stats.Files)
+		assert.Equal(t, int64(3*4096), stats.Bytes)
+		assert.Positive(t, stats.ThroughputMBps)
+		assert.Positive(t, stats.LatencyP50Ms)
+		assert.LessOrEqual(t, stats.LatencyP50Ms, stats.LatencyP99Ms)
+		assert.LessOrEqual(t, stats.LatencyP99Ms, stats.LatencyMaxMs)
+	}
+
+	// cleaned up the files in a hidden dir
+	require.Len(t, srv.deleted, 3)
+	assert.True(t, strings.HasPrefix(srv.deleted[0], "user@example.com/.syftbox-benchmark-"))
+	assert.Empty(t, srv.blobs)
+
+	var out bytes.Buffer
+	require.NoError(t, printBenchmark(&out, report, configOutputJSON))
+	var decoded benchmarkReport
+	require.NoError(t, json.Unmarshal(out.Bytes(), &decoded))
+	assert.Equal(t, report, &decoded)
+
+	out.Reset()
+	require.NoError(t, printBenchmark(&out, report, configOutputText))
+	assert.Contains(t, out.String(), "3 files of 4.0 KiB")
+	assert.Contains(t, out.String(), "MB/s")
+}
+
+func TestBenchmarkUploadDenied(t *testing.T) {
+	srv := newCheckServer(t)
+	srv.uploadErr = http.StatusForbidden
+
+	_, err := runBenchmark(context.Background(), newCheckConfig(t, srv.URL), &benchmarkOptions{Files: 3, FileSize: 4096})
+	assert.ErrorContains(t, err, "access denied")
+	assert.Empty(t, srv.deleted, "nothing to clean up")
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/openmined/syftbox/internal/client/config"
+	"github.com/openmined/syftbox/internal/syftsdk"
+	"github.com/spf13/cobra"
+)
+
+var errCheckFailed = errors.New("check failed")
+
+func init() {
+	rootCmd.AddCommand(newCheckCmd())
+}
+
+func newCheckCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "check",
+		Short: "Check connectivity and permissions against the server",
+		Long: `Check that the client can talk to the server with its credentials.
+Authenticates, fetches the datasite view, then writes a small file to your public dir,
+reads it back and deletes it again. Each step is reported as it runs.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+
+			cfg, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+
+			if err := cfg.Validate(); err != nil {
+				return fmt.Errorf("invalid config: %w", err)
+			}
+
+			return runCheck(cmd.Context(), cmd.OutOrStdout(), cfg)
+		},
+	}
+
+	cmd.Flags().SortFlags = false
+	cmd.Flags().StringP("email", "e", "", "your email for your syftbox datasite")
+	cmd.Flags().StringP("datadir", "d", config.DefaultDataDir, "data directory where the syftbox workspace is stored")
+	cmd.Flags().StringP("server", "s", config.DefaultServerURL, "url of the syftbox server")
+
+	return cmd
+}
+
+// runCheck runs every step against the server and reports it on w.
+// Steps after a failure are skipped, except for cleaning up the test file.
+func runCheck(ctx context.Context, w io.Writer, cfg *config.Config) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	fmt.Fprintf(w, "%s %s\n", lightGray.Render("SYFTBOX CHECK"), cfg.ServerURL)
+	r := &checkReporter{tw: tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)}
+	defer r.tw.Flush()
+
+	sdk, err := syftsdk.New(&syftsdk.SyftSDKConfig{
+		BaseURL:      cfg.ServerURL,
+		Email:        cfg.Email,
+		RefreshToken: cfg.RefreshToken,
+		AccessToken:  cfg.AccessToken,
+	})
+	if err != nil {
+		return err
+	}
+	defer sdk.Close()
+
+	r.run("authenticate", func() (string, error) {
+		return cfg.Email, sdk.Authenticate(ctx)
+	})
+
+	r.run("fetch datasite view", func() (string, error) {
+		view, err := sdk.Datasite.GetView(ctx, &syftsdk.DatasiteViewParams{})
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%d files", len(view.Files)), nil
+	})
+
+	tmpDir, err := os.MkdirTemp("", "syftbox-check-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	id := make([]byte, 4)
+	rand.Read(id)
+	key := fmt.Sprintf("%s/public/.syftbox-check-%s.txt", cfg.Email, hex.EncodeToString(id))
+	content := fmt.Appendf(nil, "syftbox check %s\n", time.Now().UTC().Format(time.RFC3339))
+
+	written := r.run("write test file", func() (string, error) {
+		path := filepath.Join(tmpDir, "upload.txt")
+		if err := os.WriteFile(path, content, 0o644); err != nil {
+			return "", err
+		}
+
+		if _, err := sdk.Blob.Upload(ctx, &syftsdk.UploadParams{Key: key, FilePath: path}); err != nil {
+			return "", err
+		}
+		return key, nil
+	})
+
+	r.run("read test file", func() (string, error) {
+		resp, err := sdk.Blob.Download(ctx, &syftsdk.PresignedParams{Keys: []string{key}})
+		if err != nil {
+			return "", err
+		}
+
+		if len(resp.Errors) > 0 {
+			return "", resp.Errors[0]
+		}
+
+		if len(resp.URLs) == 0 {
+			return "", fmt.Errorf("no download url for %q", key)
+		}
+
+		path, err := syftsdk.DownloadFile(ctx, &syftsdk.DownloadJob{URL: resp.URLs[0].URL, TargetDir: tmpDir, Name: "download.txt"})
+		if err != nil {
+			return "", err
+		}
+
+		got, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+
+		if !bytes.Equal(got, content) {
+			return "", fmt.Errorf("content mismatch, wrote %d bytes and read %d bytes", len(content), len(got))
+		}
+		return "content matches", nil
+	})
+
+	// clean up whatever was written, even if reading it failed
+	if written {
+		r.always("delete test file", func() (string, error) {
+			resp, err := sdk.Blob.Delete(ctx, &syftsdk.DeleteParams{Keys: []string{key}})
+			if err != nil {
+				return "", err
+			}
+
+			if len(resp.Errors) > 0 {
+				return "", resp.Errors[0]
+			}
+			return "", nil
+		})
+	} else {
+		r.skip("delete test file")
+	}
+
+	if r.failed {
+		return errCheckFailed
+	}
+	return nil
+}
+
+type checkReporter struct {
+	tw     *tabwriter.Writer
+	failed bool
+}
+
+// run runs the step, unless a previous step failed. Returns true if the step passed.
+func (r *checkReporter) run(name string, step func() (string, error)) bool {
+	if r.failed {
+		r.skip(name)
+		return false
+	}
+	return r.always(name, step)
+}
+
+// always runs the step, even if a previous step failed
+func (r *checkReporter) always(name string, step func() (string, error)) bool {
+	start := time.Now()
+	detail, err := step()
+	elapsed := time.Since(start).Round(time.Millisecond)
+
+	if err != nil {
+		r.failed = true
+		fmt.Fprintf(r.tw, "%s\t%s\t%s\t%s\n", red.Render("FAIL"), name, elapsed, err)
+	} else {
+		fmt.Fprintf(r.tw, "%s\t%s\t%s\t%s\n", green.Render("PASS"), name, elapsed, lightGray.Render(detail))
+	}
+
+	// report each step as soon as it's done
+	r.tw.Flush()
+	return err == nil
+}
+
+func (r *checkReporter) skip(name string) {
+	fmt.Fprintf(r.tw, "%s\t%s\t\t\n", lightGray.Render("SKIP"), name)
+	r.tw.Flush()
+}
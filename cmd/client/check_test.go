@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/openmined/syftbox/internal/client/config"
+	"github.com/openmined/syftbox/internal/syftsdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type checkServer struct {
+	*httptest.Server
+	blobs     map[string][]byte
+	deleted   []string
+	uploadErr int
+}
+
+// newCheckServer stubs the endpoints used by `syftbox check`
+func newCheckServer(t *testing.T) *checkServer {
+	t.Helper()
+	s := &checkServer{blobs: make(map[string][]byte)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/datasite/view", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(&syftsdk.DatasiteViewResponse{Files: []syftsdk.BlobInfo{{Key: "user@example.com/public/a.txt"}}})
+	})
+	mux.HandleFunc("PUT /api/v1/blob/upload", func(w http.ResponseWriter, r *http.Request) {
+		if s.uploadErr != 0 {
+			w.WriteHeader(s.uploadErr)
+			json.NewEncoder(w).Encode(syftsdk.NewAPIError(syftsdk.CodeAccessDenied, "access denied"))
+			return
+		}
+
+		file, _, err := r.FormFile("file")
+		require.NoError(t, err)
+		content, err := io.ReadAll(file)
+		require.NoError(t, err)
+
+		key := r.URL.Query().Get("key")
+		s.blobs[key] = content
+		json.NewEncoder(w).Encode(&syftsdk.UploadResponse{Key: key, Size: int64(len(content))})
+	})
+	mux.HandleFunc("POST /api/v1/blob/download", func(w http.ResponseWriter, r *http.Request) {
+		var params syftsdk.PresignedParams
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&params))
+
+		resp := &syftsdk.PresignedResponse{}
+		for _, key := range params.Keys {
+			resp.URLs = append(resp.URLs, &syftsdk.BlobURL{Key: key, URL: s.URL + "/objects/" + key})
+		}
+		json.NewEncoder(w).Encode(resp)
+	})
+	mux.HandleFunc("GET /objects/", func(w http.ResponseWriter, r *http.Request) {
+		content, ok := s.blobs[strings.TrimPrefix(r.URL.Path, "/objects/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(content)
+	})
+	mux.HandleFunc("POST /api/v1/blob/delete", func(w http.ResponseWriter, r *http.Request) {
+		var params syftsdk.DeleteParams
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&params))
+
+		for _, key := range params.Keys {
+			delete(s.blobs, key)
+		}
+		s.deleted = append(s.deleted, params.Keys...)
+		json.NewEncoder(w).Encode(&syftsdk.DeleteResponse{Deleted: params.Keys})
+	})
+
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+func newCheckConfig(t *testing.T, serverURL string) *config.Config {
+	t.Helper()
+	return &config.Config{
+		Email:        "user@example.com",
+		DataDir:      filepath.Join(t.TempDir(), "SyftBox"),
+		ServerURL:    serverURL,
+		RefreshToken: "token",
+	}
+}
+
+// stepResults maps each reported step to its result
+func stepResults(out string) map[string]string {
+	results := make(map[string]string)
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+
+		switch fields[0] {
+		case "PASS", "FAIL", "SKIP":
+			// step names are a few words, up to the duration or end of line
+			name := strings.TrimSpace(strings.TrimPrefix(line, fields[0]))
+			for _, step := range []string{"authenticate", "fetch datasite view", "write test file", "read test file", "delete test file"} {
+				if strings.HasPrefix(name, step) {
+					results[step] = fields[0]
+				}
+			}
+		}
+	}
+	return results
+}
+
+func TestCheckAllPass(t *testing.T) {
+	srv := newCheckServer(t)
+
+	var out bytes.Buffer
+	require.NoError(t, runCheck(context.Background(), &out, newCheckConfig(t, srv.URL)))
+
+	assert.Equal(t, map[string]string{
+		"authenticate":        "PASS",
+		"fetch datasite view": "PASS",
+		"write test file":     "PASS",
+		"read test file":      "PASS",
+		"delete test file":    "PASS",
+	}, stepResults(out.String()), out.String())
+	assert.Contains(t, out.String(), "1 files")
+
+	// cleaned up the file in the public dir
+	require.Len(t, srv.deleted, 1)
+	assert.True(t, strings.HasPrefix(srv.deleted[0], "user@example.com/public/.syftbox-check-"))
+	assert.Empty(t, srv.blobs)
+}
+
+func TestCheckWriteDenied(t *testing.T) {
+	srv := newCheckServer(t)
+	srv.uploadErr = http.StatusForbidden
+
+	var out bytes.Buffer
+	err := runCheck(context.Background(), &out, newCheckConfig(t, srv.URL))
+	assert.ErrorIs(t, err, errCheckFailed)
+
+	assert.Equal(t, map[string]string{
+		"authenticate":        "PASS",
+		"fetch datasite view": "PASS",
+		"write test file":     "FAIL",
+		"read test file":      "SKIP",
+		"delete test file":    "SKIP",
+	}, stepResults(out.String()), out.String())
+	assert.Contains(t, out.String(), "access denied")
+	assert.Empty(t, srv.deleted)
+}
+
+func TestCheckAuthFailed(t *testing.T) {
+	// auth is disabled for local servers otherwise
+	t.Setenv("SYFTBOX_AUTH_ENABLED", "true")
+	srv := newCheckServer(t)
+
+	var out bytes.Buffer
+	err := runCheck(context.Background(), &out, newCheckConfig(t, srv.URL))
+	assert.ErrorIs(t, err, errCheckFailed)
+
+	assert.Equal(t, map[string]string{
+		"authenticate":        "FAIL",
+		"fetch datasite view": "SKIP",
+		"write test file":     "SKIP",
+		"read test file":      "SKIP",
+		"delete test file":    "SKIP",
+	}, stepResults(out.String()), out.String())
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/openmined/syftbox/internal/client/config"
+	"github.com/spf13/cobra"
+)
+
+const (
+	configOutputText = "text"
+	configOutputJSON = "json"
+)
+
+func init() {
+	configCmd := newConfigCmd()
+	configCmd.AddCommand(newConfigCmdShow())
+	rootCmd.AddCommand(configCmd)
+}
+
+func newConfigCmd() *cobra.Command {
+	configCmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect the SyftBox client config",
+	}
+	return configCmd
+}
+
+func newConfigCmdShow() *cobra.Command {
+	var output string
+
+	configCmdShow := &cobra.Command{
+		Use:   "show",
+		Short: "Show the effective config, merged from the config file, env vars and flags",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+
+			cfg, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+
+			return printConfig(cmd.OutOrStdout(), cmd.ErrOrStderr(), cfg, output)
+		},
+	}
+
+	configCmdShow.Flags().SortFlags = false
+	configCmdShow.Flags().StringP("email", "e", "", "your email for your syftbox datasite")
+	configCmdShow.Flags().StringP("datadir", "d", config.DefaultDataDir, "data directory where the syftbox workspace is stored")
+	configCmdShow.Flags().StringP("server", "s", config.DefaultServerURL, "url of the syftbox server")
+	configCmdShow.Flags().StringVarP(&output, "output", "o", configOutputText, "output format (text, json)")
+
+	return configCmdShow
+}
+
+// printConfig writes the resolved config to w, with secrets redacted.
+// An invalid config is still printed, so that it can be debugged, and the validation error is written to errw.
+func printConfig(w io.Writer, errw io.Writer, cfg *config.Config, output string) error {
+	if output != configOutputText && output != configOutputJSON {
+		return fmt.Errorf("invalid output format %q", output)
+	}
+
+	// resolves paths, in addition to validating
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintf(errw, "%s: invalid config: %s\n", yellow.Render("WARN"), err)
+	}
+
+	// LogValue already redacts secrets
+	attrs := cfg.LogValue().Group()
+
+	if output == configOutputJSON {
+		values := make(map[string]any, len(attrs))
+		for _, attr := range attrs {
+			values[attr.Key] = attr.Value.Any()
+		}
+
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(values)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, attr := range attrs {
+		fmt.Fprintf(tw, "%s\t%s\n", attr.Key, attr.Value)
+	}
+	return tw.Flush()
+}
+
+// printConfigAndExit handles the root --print-config flag
+func printConfigAndExit(cmd *cobra.Command, cfg *config.Config) {
+	output, _ := cmd.Flags().GetString("output")
+	if err := printConfig(cmd.OutOrStdout(), cmd.ErrOrStderr(), cfg, output); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", red.Render("ERROR"), err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/openmined/syftbox/internal/client/config"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestConfigShowCmd builds `config show` under a fresh root, so that flags don't leak between tests
+func newTestConfigShowCmd() *cobra.Command {
+	root := &cobra.Command{Use: "syftbox"}
+	root.PersistentFlags().StringP("config", "c", config.DefaultConfigPath, "path to config file")
+	root.PersistentFlags().StringP("profile", "p", "", "profile of the config file to use")
+
+	configCmd := newConfigCmd()
+	configCmd.AddCommand(newConfigCmdShow())
+	root.AddCommand(configCmd)
+	return root
+}
+
+func TestConfigShowOverrides(t *testing.T) {
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "config.json")
+	require.NoError(t, os.WriteFile(configFile, []byte(`{
+	"email": "file@example.com",
+	"data_dir": "`+filepath.ToSlash(filepath.Join(dir, "SyftBox"))+`",
+	"server_url": "https://file.syftbox.net",
+	"refresh_token": "super-secret-refresh-token"
+}`), 0o644))
+
+	// env overrides the file, and the flag overrides both
+	t.Setenv("SYFTBOX_SERVER_URL", "https://env.syftbox.net")
+	t.Setenv("SYFTBOX_EMAIL", "env@example.com")
+	t.Setenv("SYFTBOX_COMPRESSION_CODEC", "gzip")
+
+	var stdout, stderr bytes.Buffer
+	root := newTestConfigShowCmd()
+	root.SetOut(&stdout)
+	root.SetErr(&stderr)
+	root.SetArgs([]string{"config", "show", "--config", configFile, "--email", "flag@example.com", "--output", "json"})
+	require.NoError(t, root.Execute())
+
+	var got map[string]any
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &got), stdout.String())
+
+	assert.Equal(t, "flag@example.com", got["email"])
+	assert.Equal(t, "https://env.syftbox.net", got["server_url"])
+	assert.Equal(t, "gzip", got["compression_codec"])
+	assert.Equal(t, filepath.Join(dir, "SyftBox"), got["data_dir"])
+	assert.Equal(t, configFile, got["path"])
+	assert.Equal(t, true, got["refresh_token"])
+
+	assert.NotContains(t, stdout.String(), "super-secret-refresh-token")
+	assert.Empty(t, stderr.String())
+}
+
+func TestConfigShowProfile(t *testing.T) {
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "config.json")
+	require.NoError(t, os.WriteFile(configFile, []byte(`{
+	"email": "default@example.com",
+	"data_dir": "`+filepath.ToSlash(filepath.Join(dir, "SyftBox"))+`",
+	"server_url": "https://shared.syftbox.net",
+	"profiles": {
+		"work": {
+			"email": "work@example.com",
+			"data_dir": "`+filepath.ToSlash(filepath.Join(dir, "Work"))+`"
+		}
+	}
+}`), 0o644))
+
+	showProfile := func(t *testing.T, args ...string) (map[string]any, error) {
+		t.Helper()
+		var stdout, stderr bytes.Buffer
+		root := newTestConfigShowCmd()
+		root.SetOut(&stdout)
+		root.SetErr(&stderr)
+		root.SetArgs(append([]string{"config", "show", "--config", configFile, "--output", "json"}, args...))
+		if err := root.Execute(); err != nil {
+			return nil, err
+		}
+		var got map[string]any
+		require.NoError(t, json.Unmarshal(stdout.Bytes(), &got), stdout.String())
+		return got, nil
+	}
+
+	t.Run("default", func(t *testing.T) {
+		got, err := showProfile(t)
+		require.NoError(t, err)
+		assert.Equal(t, "default@example.com", got["email"])
+		assert.Equal(t, "", got["profile"])
+	})
+
+	t.Run("flag", func(t *testing.T) {
+		got, err := showProfile(t, "-p", "work")
+		require.NoError(t, err)
+		assert.Equal(t, "work@example.com", got["email"])
+		assert.Equal(t, filepath.Join(dir, "Work"), got["data_dir"])
+		assert.Equal(t, "https://shared.syftbox.net", got["server_url"], "falls back to the top level config")
+		assert.Equal(t, "work", got["profile"])
+	})
+
+	t.Run("env", func(t *testing.T) {
+		t.Setenv("SYFTBOX_PROFILE", "work")
+		got, err := showProfile(t)
+		require.NoError(t, err)
+		assert.Equal(t, "work@example.com", got["email"])
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		_, err := showProfile(t, "--profile", "home")
+		require.ErrorIs(t, err, config.ErrUnknownProfile)
+		assert.Contains(t, err.Error(), `"home"`)
+		assert.Contains(t, err.Error(), "work")
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := showProfile(t, "--profile", "a.b")
+		require.ErrorIs(t, err, config.ErrInvalidProfile)
+	})
+}
+
+func TestConfigSaveProfile(t *testing.T) {
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "config.json")
+
+	base := &config.Config{
+		Email:     "default@example.com",
+		DataDir:   filepath.Join(dir, "SyftBox"),
+		ServerURL: "https://syftbox.net",
+		Path:      configFile,
+	}
+	require.NoError(t, base.Save())
+
+	work := &config.Config{
+		Email:        "work@example.com",
+		DataDir:      filepath.Join(dir, "Work"),
+		ServerURL:    "https://work.syftbox.net",
+		RefreshToken: "work-token",
+		Path:         configFile,
+		Profile:      "work",
+	}
+	require.NoError(t, work.Save())
+
+	// saving the top level config keeps the profiles
+	base.RefreshToken = "default-token"
+	require.NoError(t, base.Save())
+
+	loaded, err := config.LoadFromFile(configFile)
+	require.NoError(t, err)
+	assert.Equal(t, "default@example.com", loaded.Email)
+	assert.Equal(t, "default-token", loaded.RefreshToken)
+	assert.Empty(t, loaded.Profile)
+
+	loaded, err = config.LoadProfileFromFile(configFile, "work")
+	require.NoError(t, err)
+	assert.Equal(t, "work@example.com", loaded.Email)
+	assert.Equal(t, "https://work.syftbox.net", loaded.ServerURL)
+	assert.Equal(t, "work-token", loaded.RefreshToken)
+	assert.Equal(t, "work", loaded.Profile)
+
+	_, err = config.LoadProfileFromFile(configFile, "home")
+	assert.ErrorIs(t, err, config.ErrUnknownProfile)
+}
+
+func TestConfigShowText(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	cfg := &config.Config{
+		Email:        "user@example.com",
+		DataDir:      t.TempDir(),
+		ServerURL:    "https://syftbox.net",
+		RefreshToken: "super-secret-refresh-token",
+		Path:         filepath.Join(t.TempDir(), "config.json"),
+	}
+
+	require.NoError(t, printConfig(&stdout, &stderr, cfg, configOutputText))
+	assert.Regexp(t, `(?m)^email\s+user@example.com$`, stdout.String())
+	assert.Regexp(t, `(?m)^refresh_token\s+true$`, stdout.String())
+	assert.NotContains(t, stdout.String(), "super-secret-refresh-token")
+
+	assert.Error(t, printConfig(&stdout, &stderr, cfg, "yaml"))
+}
+
+func TestConfigShowInvalid(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	cfg := &config.Config{
+		Email:     "not-an-email",
+		DataDir:   t.TempDir(),
+		ServerURL: "https://syftbox.net",
+	}
+
+	// still printed, so that it can be debugged
+	require.NoError(t, printConfig(&stdout, &stderr, cfg, configOutputText))
+	assert.Contains(t, stdout.String(), "not-an-email")
+	assert.Contains(t, stderr.String(), "invalid config")
+}
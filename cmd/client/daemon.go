@@ -19,6 +19,7 @@ func newDaemonCmd() *cobra.Command {
 	var addr string
 	var authToken string
 	var enableSwagger bool
+	var eventSocket string
 
 	daemonCmd := &cobra.Command{
 		Use:   "daemon",
@@ -32,6 +33,9 @@ func newDaemonCmd() *cobra.Command {
 				Addr:          addr,
 				AuthToken:     authToken,
 				EnableSwagger: enableSwagger,
+				LogFilePath:   logFilePathFor(cmd),
+
+				EventSocketPath: eventSocket,
 			})
 			if err != nil {
 				return err
@@ -49,6 +53,7 @@ func newDaemonCmd() *cobra.Command {
 	daemonCmd.Flags().StringVarP(&addr, "http-addr", "a", "localhost:7938", "Address to bind the local http server")
 	daemonCmd.Flags().StringVarP(&authToken, "http-token", "t", "", "Access token for the local http server")
 	daemonCmd.Flags().BoolVarP(&enableSwagger, "http-swagger", "s", true, "Enable Swagger for the local http server")
+	daemonCmd.Flags().StringVar(&eventSocket, "events-socket", "", "Path of a local socket streaming the daemon events as JSON lines")
 
 	return daemonCmd
 }
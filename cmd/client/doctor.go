@@ -0,0 +1,302 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/openmined/syftbox/internal/client/config"
+	"github.com/openmined/syftbox/internal/client/sync"
+	"github.com/openmined/syftbox/internal/utils"
+	"github.com/shirou/gopsutil/v4/disk"
+	"github.com/spf13/cobra"
+)
+
+const (
+	doctorServerTimeout = 10 * time.Second
+	doctorMaxClockSkew  = time.Minute
+)
+
+var errDoctorFailed = errors.New("doctor found problems")
+
+// diskUsage is replaced in the tests, the free space of the machine running them is anyone's guess
+var diskUsage = disk.Usage
+
+func init() {
+	rootCmd.AddCommand(newDoctorCmd())
+}
+
+func newDoctorCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Diagnose the setup of the client",
+		Long: `Diagnose the common problems of a client setup, without starting the client.
+Checks the config file, the login, that the server is reachable, the clock of this machine,
+and that the data dir is writable with enough free space. Each problem comes with a hint to fix it.
+Exits non-zero if any check fails, warnings don't stop the client from running.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			return runDoctor(cmd.Context(), cmd.OutOrStdout(), cmd.Flag("config").Value.String(), profileName(cmd))
+		},
+	}
+
+	return cmd
+}
+
+type doctorStatus int
+
+const (
+	doctorPass doctorStatus = iota
+	doctorWarn
+	doctorFail
+	doctorSkip
+)
+
+// doctorResult is the outcome of a check, with a hint to fix it if it didn't pass
+type doctorResult struct {
+	status doctorStatus
+	detail string
+	hint   string
+}
+
+func passResult(detail string) *doctorResult {
+	return &doctorResult{status: doctorPass, detail: detail}
+}
+
+func warnResult(detail string, hint string) *doctorResult {
+	return &doctorResult{status: doctorWarn, detail: detail, hint: hint}
+}
+
+func failResult(detail string, hint string) *doctorResult {
+	return &doctorResult{status: doctorFail, detail: detail, hint: hint}
+}
+
+func skipResult(detail string) *doctorResult {
+	return &doctorResult{status: doctorSkip, detail: detail}
+}
+
+// runDoctor runs every check of the config file at configPath and reports it on w.
+// The checks that need something a previous one failed to provide are skipped.
+func runDoctor(ctx context.Context, w io.Writer, configPath string, profile string) error {
+	fmt.Fprintf(w, "%s %s\n", lightGray.Render("SYFTBOX DOCTOR"), configPath)
+	r := &doctorReporter{tw: tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)}
+	defer r.tw.Flush()
+
+	var cfg *config.Config
+	r.report("config file", func() *doctorResult {
+		var res *doctorResult
+		cfg, res = checkConfigFile(configPath, profile)
+		return res
+	})
+
+	r.report("login", func() *doctorResult {
+		switch {
+		case cfg == nil:
+			return skipResult("no valid config")
+		case cfg.RefreshToken == "":
+			return failResult("no refresh token", loginHint())
+		default:
+			return passResult(cfg.Email)
+		}
+	})
+
+	var serverDate time.Time
+	r.report("server", func() *doctorResult {
+		if cfg == nil {
+			return skipResult("no valid config")
+		}
+		var res *doctorResult
+		serverDate, res = checkServerHealth(ctx, cfg.ServerURL)
+		return res
+	})
+
+	r.report("clock", func() *doctorResult {
+		if serverDate.IsZero() {
+			return skipResult("no server time")
+		}
+		return checkClockSkew(time.Since(serverDate))
+	})
+
+	r.report("data dir", func() *doctorResult {
+		if cfg == nil {
+			return skipResult("no valid config")
+		}
+		return checkDataDir(cfg.DataDir)
+	})
+
+	r.report("disk space", func() *doctorResult {
+		if cfg == nil {
+			return skipResult("no valid config")
+		}
+		return checkDiskSpace(cfg.DataDir)
+	})
+
+	if r.failed {
+		return errDoctorFailed
+	}
+	return nil
+}
+
+func checkConfigFile(configPath string, profile string) (*config.Config, *doctorResult) {
+	configPath, err := utils.ResolvePath(configPath)
+	if err != nil {
+		return nil, failResult(err.Error(), "check the path of the config file")
+	}
+
+	if _, err := os.Stat(configPath); errors.Is(err, os.ErrNotExist) {
+		return nil, failResult("not found", loginHint())
+	} else if err != nil {
+		return nil, failResult(err.Error(), "check the permissions of the config file")
+	}
+
+	cfg, err := config.LoadProfileFromFile(configPath, profile)
+	if err != nil {
+		return nil, failResult(err.Error(), loginHint())
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, failResult(err.Error(), loginHint())
+	}
+
+	if cfg.Profile != "" {
+		return cfg, passResult(fmt.Sprintf("%s (profile %s)", cfg.Path, cfg.Profile))
+	}
+	return cfg, passResult(cfg.Path)
+}
+
+// checkServerHealth checks that the server responds on /healthz, and returns its time
+func checkServerHealth(ctx context.Context, serverURL string) (time.Time, *doctorResult) {
+	ctx, cancel := context.WithTimeout(ctx, doctorServerTimeout)
+	defer cancel()
+
+	hint := "check your network connection, and that the server url of the config is right"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(serverURL, "/")+"/healthz", nil)
+	if err != nil {
+		return time.Time{}, failResult(err.Error(), hint)
+	}
+
+	start := time.Now()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return time.Time{}, failResult(err.Error(), hint)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	elapsed := time.Since(start)
+
+	if resp.StatusCode != http.StatusOK {
+		return time.Time{}, failResult(fmt.Sprintf("%s responded with %s", serverURL, resp.Status), "the server may be down, try again later")
+	}
+
+	// the date has a second precision, the skew is only reported from a minute on
+	serverDate, _ := http.ParseTime(resp.Header.Get("Date"))
+	return serverDate, passResult(fmt.Sprintf("%s in %s", serverURL, elapsed.Round(time.Millisecond)))
+}
+
+func checkClockSkew(skew time.Duration) *doctorResult {
+	detail := fmt.Sprintf("%s off the server", skew.Abs().Round(time.Second))
+	if skew.Abs() > doctorMaxClockSkew {
+		return warnResult(detail, "sync the clock of this machine, e.g. by turning on the automatic date and time")
+	}
+	return passResult(detail)
+}
+
+func checkDataDir(dataDir string) *doctorResult {
+	dir, err := existingDir(dataDir)
+	if err != nil {
+		return failResult(err.Error(), "check the data dir of the config")
+	}
+
+	// the permission bits don't tell about read-only mounts or acls, so write a file
+	fd, err := os.CreateTemp(dir, ".syftbox-doctor-*")
+	if err != nil {
+		return failResult(fmt.Sprintf("%s is not writable", dir), "fix the permissions of the data dir, or login again with another one")
+	}
+	fd.Close()
+	os.Remove(fd.Name())
+
+	if dir != dataDir {
+		return passResult(fmt.Sprintf("%s, created on the first run", dataDir))
+	}
+	return passResult(dataDir)
+}
+
+func checkDiskSpace(dataDir string) *doctorResult {
+	dir, err := existingDir(dataDir)
+	if err != nil {
+		return failResult(err.Error(), "check the data dir of the config")
+	}
+
+	usage, err := diskUsage(dir)
+	if err != nil {
+		return warnResult(err.Error(), "check the free space of the data dir yourself")
+	}
+
+	detail := fmt.Sprintf("%s free", humanize.Bytes(usage.Free))
+	hint := fmt.Sprintf("free up space, the client doesn't sync with less than %s free", humanize.Bytes(sync.MinFreeSpace))
+	switch {
+	case usage.Free <= sync.MinFreeSpace:
+		return failResult(detail, hint)
+	case usage.Free <= 2*sync.MinFreeSpace:
+		return warnResult(detail, hint)
+	default:
+		return passResult(detail)
+	}
+}
+
+// existingDir returns the dir, or its closest parent that exists if it wasn't created yet
+func existingDir(dir string) (string, error) {
+	for {
+		info, err := os.Stat(dir)
+		if err == nil {
+			if !info.IsDir() {
+				return "", fmt.Errorf("%s is not a directory", dir)
+			}
+			return dir, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", err
+		}
+		dir = parent
+	}
+}
+
+type doctorReporter struct {
+	tw     *tabwriter.Writer
+	failed bool
+}
+
+func (r *doctorReporter) report(name string, check func() *doctorResult) {
+	res := check()
+
+	var status string
+	switch res.status {
+	case doctorPass:
+		status = green.Render("PASS")
+	case doctorWarn:
+		status = yellow.Render("WARN")
+	case doctorFail:
+		r.failed = true
+		status = red.Render("FAIL")
+	default:
+		status = lightGray.Render("SKIP")
+	}
+
+	fmt.Fprintf(r.tw, "%s\t%s\t%s\n", status, name, res.detail)
+	if res.hint != "" {
+		fmt.Fprintf(r.tw, "\t\t%s\n", lightGray.Render(res.hint))
+	}
+	r.tw.Flush()
+}
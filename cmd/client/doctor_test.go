@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openmined/syftbox/internal/client/config"
+	"github.com/openmined/syftbox/internal/client/sync"
+	"github.com/shirou/gopsutil/v4/disk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var doctorChecks = []string{"config file", "login", "server", "clock", "data dir", "disk space"}
+
+func newDoctorServer(t *testing.T, status int) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/healthz" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// writeDoctorConfig saves a config for the server, and returns its path
+func writeDoctorConfig(t *testing.T, serverURL string, refreshToken string) string {
+	t.Helper()
+	dir := t.TempDir()
+	cfg := &config.Config{
+		Path:         filepath.Join(dir, "config.json"),
+		Email:        "user@example.com",
+		DataDir:      filepath.Join(dir, "SyftBox"),
+		ServerURL:    serverURL,
+		RefreshToken: refreshToken,
+	}
+	require.NoError(t, cfg.Save())
+	return cfg.Path
+}
+
+func setFreeSpace(t *testing.T, free uint64) {
+	t.Helper()
+	orig := diskUsage
+	diskUsage = func(path string) (*disk.UsageStat, error) { return &disk.UsageStat{Path: path, Free: free}, nil }
+	t.Cleanup(func() { diskUsage = orig })
+}
+
+// doctorResults maps each reported check to its status
+func doctorResults(out string) map[string]string {
+	results := make(map[string]string)
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+
+		switch fields[0] {
+		case "PASS", "WARN", "FAIL", "SKIP":
+			name := strings.TrimSpace(strings.TrimPrefix(line, fields[0]))
+			for _, check := range doctorChecks {
+				if strings.HasPrefix(name, check) {
+					results[check] = fields[0]
+				}
+			}
+		}
+	}
+	return results
+}
+
+func TestDoctorAllPass(t *testing.T) {
+	setFreeSpace(t, 100*sync.MinFreeSpace)
+	srv := newDoctorServer(t, http.StatusOK)
+
+	var out bytes.Buffer
+	require.NoError(t, runDoctor(context.Background(), &out, writeDoctorConfig(t, srv.URL, "token"), ""))
+
+	assert.Equal(t, map[string]string{
+		"config file": "PASS",
+		"login":       "PASS",
+		"server":      "PASS",
+		"clock":       "PASS",
+		"data dir":    "PASS",
+		"disk space":  "PASS",
+	}, doctorResults(out.String()), out.String())
+	assert.Contains(t, out.String(), "created on the first run")
+}
+
+func TestDoctorMissingConfig(t *testing.T) {
+	var out bytes.Buffer
+	err := runDoctor(context.Background(), &out, filepath.Join(t.TempDir(), "config.json"), "")
+	assert.ErrorIs(t, err, errDoctorFailed)
+
+	assert.Equal(t, map[string]string{
+		"config file": "FAIL",
+		"login":       "SKIP",
+		"server":      "SKIP",
+		"clock":       "SKIP",
+		"data dir":    "SKIP",
+		"disk space":  "SKIP",
+	}, doctorResults(out.String()), out.String())
+	assert.Contains(t, out.String(), "syftbox login")
+}
+
+func TestDoctorNotLoggedIn(t *testing.T) {
+	setFreeSpace(t, 100*sync.MinFreeSpace)
+	srv := newDoctorServer(t, http.StatusOK)
+
+	var out bytes.Buffer
+	err := runDoctor(context.Background(), &out, writeDoctorConfig(t, srv.URL, ""), "")
+	assert.ErrorIs(t, err, errDoctorFailed)
+
+	results := doctorResults(out.String())
+	assert.Equal(t, "FAIL", results["login"], out.String())
+	assert.Equal(t, "PASS", results["server"], out.String())
+	assert.Contains(t, out.String(), "syftbox login")
+}
+
+func TestDoctorServerDown(t *testing.T) {
+	setFreeSpace(t, sync.MinFreeSpace+1)
+	srv := newDoctorServer(t, http.StatusServiceUnavailable)
+
+	var out bytes.Buffer
+	err := runDoctor(context.Background(), &out, writeDoctorConfig(t, srv.URL, "token"), "")
+	assert.ErrorIs(t, err, errDoctorFailed)
+
+	results := doctorResults(out.String())
+	assert.Equal(t, "FAIL", results["server"], out.String())
+	assert.Equal(t, "SKIP", results["clock"], out.String())
+	assert.Equal(t, "WARN", results["disk space"], out.String())
+}
+
+func TestDoctorLowDiskSpace(t *testing.T) {
+	setFreeSpace(t, sync.MinFreeSpace)
+	srv := newDoctorServer(t, http.StatusOK)
+
+	var out bytes.Buffer
+	err := runDoctor(context.Background(), &out, writeDoctorConfig(t, srv.URL, "token"), "")
+	assert.ErrorIs(t, err, errDoctorFailed)
+	assert.Equal(t, "FAIL", doctorResults(out.String())["disk space"], out.String())
+}
+
+func TestDoctorClockSkew(t *testing.T) {
+	assert.Equal(t, doctorPass, checkClockSkew(-10*time.Second).status)
+	assert.Equal(t, doctorWarn, checkClockSkew(2*time.Minute).status)
+	assert.Equal(t, doctorWarn, checkClockSkew(-2*time.Minute).status)
+}
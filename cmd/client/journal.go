@@ -0,0 +1,250 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/openmined/syftbox/internal/client/config"
+	"github.com/openmined/syftbox/internal/client/sync"
+	"github.com/openmined/syftbox/internal/client/workspace"
+	"github.com/spf13/cobra"
+)
+
+const defaultJournalDumpLimit = 50
+
+func init() {
+	journalCmd := newJournalCmd()
+	journalCmd.AddCommand(newJournalCmdDump())
+	journalCmd.AddCommand(newJournalCmdReplay())
+	rootCmd.AddCommand(journalCmd)
+}
+
+func newJournalCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "journal",
+		Short: "Inspect the sync journal, for debugging",
+	}
+}
+
+func newJournalCmdDump() *cobra.Command {
+	var output string
+	var limit int
+
+	cmd := &cobra.Command{
+		Use:   "dump",
+		Short: "Show the recent sync operations recorded in the journal",
+		Long: `Show the outcome of the recent sync operations, newest first, with their state and errors.
+Only paths and sync metadata are shown, never the contents of the files.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+
+			if output != configOutputText && output != configOutputJSON {
+				return fmt.Errorf("invalid output format %q", output)
+			}
+
+			ws, err := loadWorkspace(cmd)
+			if err != nil {
+				return err
+			}
+
+			journal, err := openJournal(ws)
+			if err != nil {
+				return err
+			}
+			defer journal.Close()
+
+			ops, err := journal.RecentOps(limit)
+			if err != nil {
+				return err
+			}
+
+			return printJournalOps(cmd.OutOrStdout(), ops, output)
+		},
+	}
+
+	cmd.Flags().SortFlags = false
+	cmd.Flags().StringP("email", "e", "", "your email for your syftbox datasite")
+	cmd.Flags().StringP("datadir", "d", config.DefaultDataDir, "data directory where the syftbox workspace is stored")
+	cmd.Flags().StringP("server", "s", config.DefaultServerURL, "url of the syftbox server")
+	cmd.Flags().IntVarP(&limit, "limit", "n", defaultJournalDumpLimit, "number of operations to show, 0 for all")
+	cmd.Flags().StringVarP(&output, "output", "o", configOutputText, "output format (text, json)")
+
+	return cmd
+}
+
+func newJournalCmdReplay() *cobra.Command {
+	var output string
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "replay",
+		Short: "Re-evaluate the pending sync operations against the server",
+		Long: `Reconcile the local files, the journal and the server like a full sync does, and report the
+operations it would run. Nothing is changed, the client runs them on its next sync.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+
+			if !dryRun {
+				return errors.New("replay only supports --dry-run, the client runs the operations on its next sync")
+			}
+			if output != configOutputText && output != configOutputJSON {
+				return fmt.Errorf("invalid output format %q", output)
+			}
+
+			cfg, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+			if err := cfg.Validate(); err != nil {
+				return fmt.Errorf("invalid config: %w", err)
+			}
+
+			ops, err := runSyncDryRun(cmd.Context(), cfg)
+			if err != nil {
+				return err
+			}
+
+			return printJournalReplay(cmd.OutOrStdout(), ops, output)
+		},
+	}
+
+	cmd.Flags().SortFlags = false
+	cmd.Flags().StringP("email", "e", "", "your email for your syftbox datasite")
+	cmd.Flags().StringP("datadir", "d", config.DefaultDataDir, "data directory where the syftbox workspace is stored")
+	cmd.Flags().StringP("server", "s", config.DefaultServerURL, "url of the syftbox server")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "report the operations without running them")
+	cmd.Flags().StringVarP(&output, "output", "o", configOutputText, "output format (text, json)")
+
+	return cmd
+}
+
+// loadWorkspace resolves the workspace of the config, without needing to be logged in
+func loadWorkspace(cmd *cobra.Command) (*workspace.Workspace, error) {
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	return workspace.NewWorkspace(cfg.DataDir, cfg.Email)
+}
+
+// openJournal opens an existing sync journal. it is not created if the client never synced
+func openJournal(ws *workspace.Workspace) (*sync.SyncJournal, error) {
+	path := sync.JournalPath(ws)
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("no sync journal at '%s': %w", path, err)
+	}
+
+	journal, err := sync.NewSyncJournal(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := journal.Open(); err != nil {
+		return nil, err
+	}
+	return journal, nil
+}
+
+func printJournalOps(w io.Writer, ops []*sync.JournalOp, output string) error {
+	if output == configOutputJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(ops)
+	}
+
+	if len(ops) == 0 {
+		fmt.Fprintln(w, lightGray.Render("no operations recorded"))
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", "TIME", "STATE", "PATH", "DETAIL")
+	for _, op := range ops {
+		state := string(op.State)
+		if op.ConflictState != "" && op.ConflictState != sync.ConflictStateNone {
+			state = string(op.ConflictState)
+		}
+
+		detail := op.Reason
+		if op.Error != "" {
+			detail = fmt.Sprintf("%s (attempt %d)", op.Error, op.ErrorCount)
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", op.RecordedAt.Local().Format(time.DateTime), state, op.Path, detail)
+	}
+	return tw.Flush()
+}
+
+// journalReplay is the json output of `journal replay`
+type journalReplay struct {
+	Uploads       []sync.SyncPath `json:"uploads"`
+	Downloads     []sync.SyncPath `json:"downloads"`
+	RemoteDeletes []sync.SyncPath `json:"remoteDeletes"`
+	LocalDeletes  []sync.SyncPath `json:"localDeletes"`
+	Conflicts     []sync.SyncPath `json:"conflicts"`
+	Merges        []sync.SyncPath `json:"merges"`
+	Cleanups      []sync.SyncPath `json:"cleanups"`
+	Unchanged     int             `json:"unchanged"`
+	Ignored       int             `json:"ignored"`
+}
+
+func newJournalReplay(ops *sync.ReconcileOperations) *journalReplay {
+	return &journalReplay{
+		Uploads:       slices.Sorted(maps.Keys(ops.RemoteWrites)),
+		Downloads:     slices.Sorted(maps.Keys(ops.LocalWrites)),
+		RemoteDeletes: slices.Sorted(maps.Keys(ops.RemoteDeletes)),
+		LocalDeletes:  slices.Sorted(maps.Keys(ops.LocalDeletes)),
+		Conflicts:     slices.Sorted(maps.Keys(ops.Conflicts)),
+		Merges:        slices.Sorted(maps.Keys(ops.Merges)),
+		Cleanups:      slices.Sorted(maps.Keys(ops.Cleanups)),
+		Unchanged:     len(ops.UnchangedPaths),
+		Ignored:       len(ops.Ignored),
+	}
+}
+
+func printJournalReplay(w io.Writer, ops *sync.ReconcileOperations, output string) error {
+	replay := newJournalReplay(ops)
+
+	if output == configOutputJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(replay)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, group := range []struct {
+		action string
+		paths  []sync.SyncPath
+	}{
+		{"upload", replay.Uploads},
+		{"download", replay.Downloads},
+		{"delete remote", replay.RemoteDeletes},
+		{"delete local", replay.LocalDeletes},
+		{"conflict", replay.Conflicts},
+		{"merge", replay.Merges},
+		{"cleanup journal", replay.Cleanups},
+	} {
+		for _, path := range group.paths {
+			fmt.Fprintf(tw, "%s\t%s\n", group.action, path)
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "%s %d uploads, %d downloads, %d remote deletes, %d local deletes, %d conflicts, %d merges, %d cleanups, %d unchanged, %d ignored\n",
+		lightGray.Render("would run"),
+		len(replay.Uploads), len(replay.Downloads), len(replay.RemoteDeletes), len(replay.LocalDeletes),
+		len(replay.Conflicts), len(replay.Merges), len(replay.Cleanups), replay.Unchanged, replay.Ignored,
+	)
+	return nil
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/openmined/syftbox/internal/client/config"
+	"github.com/openmined/syftbox/internal/client/sync"
+	"github.com/openmined/syftbox/internal/client/workspace"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestJournalCmd builds `journal` under a fresh root, so that flags don't leak between tests
+func newTestJournalCmd() *cobra.Command {
+	root := &cobra.Command{Use: "syftbox"}
+	root.PersistentFlags().StringP("config", "c", config.DefaultConfigPath, "path to config file")
+	root.PersistentFlags().StringP("profile", "p", "", "profile of the config file to use")
+
+	journalCmd := newJournalCmd()
+	journalCmd.AddCommand(newJournalCmdDump())
+	journalCmd.AddCommand(newJournalCmdReplay())
+	root.AddCommand(journalCmd)
+	return root
+}
+
+func TestJournalDump(t *testing.T) {
+	dir := t.TempDir()
+	dataDir := filepath.Join(dir, "SyftBox")
+	configFile := filepath.Join(dir, "config.json")
+	require.NoError(t, os.WriteFile(configFile, []byte(`{
+	"email": "user@example.com",
+	"data_dir": "`+filepath.ToSlash(dataDir)+`",
+	"server_url": "https://syftbox.net"
+}`), 0o644))
+
+	ws, err := workspace.NewWorkspace(dataDir, "user@example.com")
+	require.NoError(t, err)
+
+	journal, err := sync.NewSyncJournal(sync.JournalPath(ws))
+	require.NoError(t, err)
+	require.NoError(t, journal.Open())
+
+	now := time.Now()
+	recorded := []struct {
+		path   sync.SyncPath
+		status *sync.PathStatus
+	}{
+		{"user@example.com/public/a.txt", &sync.PathStatus{SyncState: sync.SyncStateCompleted, ConflictState: sync.ConflictStateNone, LastUpdated: now.Add(-3 * time.Second)}},
+		{"user@example.com/public/b.txt", &sync.PathStatus{SyncState: sync.SyncStateError, ConflictState: sync.ConflictStateNone, Error: errors.New("upload failed"), ErrorCount: 2, LastUpdated: now.Add(-2 * time.Second)}},
+		{"user@example.com/public/c.txt", &sync.PathStatus{SyncState: sync.SyncStateCompleted, ConflictState: sync.ConflictStateRejected, Reason: "permission denied", LastUpdated: now.Add(-time.Second)}},
+	}
+	for _, r := range recorded {
+		require.NoError(t, journal.RecordOp(r.path, r.status))
+	}
+	require.NoError(t, journal.Close())
+
+	dump := func(t *testing.T, args ...string) string {
+		t.Helper()
+		var stdout bytes.Buffer
+		root := newTestJournalCmd()
+		root.SetOut(&stdout)
+		root.SetErr(&bytes.Buffer{})
+		root.SetArgs(append([]string{"journal", "dump", "--config", configFile}, args...))
+		require.NoError(t, root.Execute())
+		return stdout.String()
+	}
+
+	t.Run("json", func(t *testing.T) {
+		var ops []*sync.JournalOp
+		require.NoError(t, json.Unmarshal([]byte(dump(t, "-o", "json")), &ops))
+		require.Len(t, ops, 3)
+
+		// newest first
+		assert.Equal(t, sync.SyncPath("user@example.com/public/c.txt"), ops[0].Path)
+		assert.Equal(t, sync.ConflictStateRejected, ops[0].ConflictState)
+		assert.Equal(t, "permission denied", ops[0].Reason)
+
+		assert.Equal(t, sync.SyncPath("user@example.com/public/b.txt"), ops[1].Path)
+		assert.Equal(t, sync.SyncStateError, ops[1].State)
+		assert.Equal(t, "upload failed", ops[1].Error)
+		assert.Equal(t, 2, ops[1].ErrorCount)
+
+		assert.Equal(t, sync.SyncPath("user@example.com/public/a.txt"), ops[2].Path)
+		assert.Equal(t, sync.SyncStateCompleted, ops[2].State)
+		assert.WithinDuration(t, now.Add(-3*time.Second), ops[2].RecordedAt, time.Millisecond)
+	})
+
+	t.Run("text", func(t *testing.T) {
+		out := dump(t)
+		assert.Regexp(t, `(?m)rejected\s+user@example.com/public/c.txt\s+permission denied$`, out)
+		assert.Regexp(t, `(?m)error\s+user@example.com/public/b.txt\s+upload failed \(attempt 2\)$`, out)
+		assert.Regexp(t, `(?m)completed\s+user@example.com/public/a.txt\s*$`, out)
+	})
+
+	t.Run("limit", func(t *testing.T) {
+		var ops []*sync.JournalOp
+		require.NoError(t, json.Unmarshal([]byte(dump(t, "-o", "json", "-n", "1")), &ops))
+		require.Len(t, ops, 1)
+		assert.Equal(t, sync.SyncPath("user@example.com/public/c.txt"), ops[0].Path)
+	})
+}
+
+func TestJournalDumpNoJournal(t *testing.T) {
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "config.json")
+	require.NoError(t, os.WriteFile(configFile, []byte(`{
+	"email": "user@example.com",
+	"data_dir": "`+filepath.ToSlash(filepath.Join(dir, "SyftBox"))+`",
+	"server_url": "https://syftbox.net"
+}`), 0o644))
+
+	root := newTestJournalCmd()
+	root.SetOut(&bytes.Buffer{})
+	root.SetErr(&bytes.Buffer{})
+	root.SetArgs([]string{"journal", "dump", "--config", configFile})
+	assert.ErrorContains(t, root.Execute(), "no sync journal")
+}
+
+func TestJournalReplayRequiresDryRun(t *testing.T) {
+	root := newTestJournalCmd()
+	root.SetOut(&bytes.Buffer{})
+	root.SetErr(&bytes.Buffer{})
+	root.SetArgs([]string{"journal", "replay"})
+	assert.ErrorContains(t, root.Execute(), "--dry-run")
+}
+
+func TestJournalReplayText(t *testing.T) {
+	ops := sync.NewReconcileOperations()
+	ops.RemoteWrites["user@example.com/public/b.txt"] = &sync.SyncOperation{Type: sync.OpWriteRemote}
+	ops.RemoteWrites["user@example.com/public/a.txt"] = &sync.SyncOperation{Type: sync.OpWriteRemote}
+	ops.LocalDeletes["user@example.com/public/gone.txt"] = &sync.SyncOperation{Type: sync.OpDeleteLocal}
+	ops.UnchangedPaths["user@example.com/public/same.txt"] = struct{}{}
+
+	var out bytes.Buffer
+	require.NoError(t, printJournalReplay(&out, ops, configOutputText))
+	assert.Regexp(t, `(?s)upload\s+user@example.com/public/a.txt\nupload\s+user@example.com/public/b.txt\ndelete local\s+user@example.com/public/gone.txt\n`, out.String())
+	assert.Contains(t, out.String(), "2 uploads, 0 downloads, 0 remote deletes, 1 local deletes, 0 conflicts, 0 merges, 0 cleanups, 1 unchanged, 0 ignored")
+}
@@ -31,13 +31,21 @@ func newLoginCmd() *cobra.Command {
 
 			// fetched from main/rootCmd/persistentFlags
 			configPath := cmd.Flag("config").Value.String()
+			profile := profileName(cmd)
 
 			if err := utils.ValidateURL(serverURL); err != nil {
 				fmt.Printf("%s: %s\n", red.Render("ERROR"), err)
 				os.Exit(1)
 			}
 
-			if cfg, err := readValidConfig(configPath, true); err == nil {
+			if profile != "" {
+				if err := config.ValidateProfileName(profile); err != nil {
+					fmt.Printf("%s: %s\n", red.Render("ERROR"), err)
+					os.Exit(1)
+				}
+			}
+
+			if cfg, err := readValidConfig(configPath, profile, true); err == nil {
 				// is valid configuration
 				loggedIn := true
 
@@ -116,7 +124,7 @@ func newLoginCmd() *cobra.Command {
 			}
 
 			cfg := &config.Config{
-				Email:        email,
+				Email:        authToken.CanonicalEmail(email),
 				DataDir:      dataDir,
 				ServerURL:    serverURL,
 				ClientURL:    config.DefaultClientURL,
@@ -124,6 +132,7 @@ func newLoginCmd() *cobra.Command {
 				AccessToken:  authToken.AccessToken, // not gonna be serialized
 				AppsEnabled:  true,
 				Path:         configPath,
+				Profile:      profile,
 			}
 
 			if err := cfg.Validate(); err != nil {
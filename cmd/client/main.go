@@ -4,10 +4,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"maps"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"slices"
 	"strings"
 	"syscall"
 
@@ -15,6 +18,7 @@ import (
 	"github.com/mattn/go-isatty"
 	"github.com/openmined/syftbox/internal/client"
 	"github.com/openmined/syftbox/internal/client/config"
+	"github.com/openmined/syftbox/internal/client/service"
 	"github.com/openmined/syftbox/internal/utils"
 	"github.com/openmined/syftbox/internal/version"
 	"github.com/spf13/cobra"
@@ -36,6 +40,10 @@ var rootCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
+		if printCfg, _ := cmd.Flags().GetBool("print-config"); printCfg {
+			printConfigAndExit(cmd, cfg)
+		}
+
 		// not running any local server, so we don't need to set client_url or client_token
 		cfg.ClientURL = ""
 		cfg.ClientToken = ""
@@ -43,11 +51,15 @@ var rootCmd = &cobra.Command{
 		if err := cfg.Validate(); err != nil {
 			slog.Error("syftbox config", "error", err)
 			if cfg.Email == "" || cfg.DataDir == "" || cfg.RefreshToken == "" {
-				fmt.Fprintf(os.Stderr, "SyftBox is not configured correctly. Please login again by running `%s`\n", green.Render("syftbox login"))
+				fmt.Fprintf(os.Stderr, "SyftBox is not configured correctly. %s\n", loginHint())
 			}
 			os.Exit(1)
 		}
 
+		if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+			dryRunAndExit(cmd, cfg)
+		}
+
 		// all good now, show header
 		cmd.SilenceUsage = true
 		showSyftBoxHeader()
@@ -75,36 +87,68 @@ func init() {
 	rootCmd.Flags().StringP("email", "e", "", "your email for your syftbox datasite")
 	rootCmd.Flags().StringP("datadir", "d", config.DefaultDataDir, "data directory where the syftbox workspace is stored")
 	rootCmd.Flags().StringP("server", "s", config.DefaultServerURL, "url of the syftbox server")
+	rootCmd.Flags().Duration("shutdown-timeout", config.DefaultShutdownTimeout, "how long to wait for active uploads and downloads on shutdown, before aborting them")
+	rootCmd.Flags().Duration("apps-sync-timeout", config.DefaultAppsSyncTimeout, "how long apps wait for the initial sync before starting anyway, negative to not wait")
+	rootCmd.Flags().Bool("read-only", false, "only download the changes of the datasites, and never upload local changes")
+	rootCmd.Flags().Bool("print-config", false, "print the effective config with secrets redacted, and exit")
+	rootCmd.Flags().Bool("dry-run", false, "report the operations of a full sync without running them, and exit")
+	rootCmd.Flags().StringP("output", "o", configOutputText, "output format of --print-config and --dry-run (text, json)")
 	rootCmd.PersistentFlags().StringP("config", "c", config.DefaultConfigPath, "path to config file")
+	rootCmd.PersistentFlags().StringP("profile", "p", "", "profile of the config file to use (env: SYFTBOX_PROFILE)")
+	rootCmd.PersistentFlags().Int("log-max-size", config.DefaultLogMaxSize, "size in MB at which the log file is rotated, 0 to never rotate")
+	rootCmd.PersistentFlags().Int("log-max-backups", config.DefaultLogMaxBackups, "number of rotated log files to keep, 0 to keep all")
+	rootCmd.PersistentFlags().Duration("log-max-age", config.DefaultLogMaxAge, "how long to keep the rotated log files, 0 to keep them regardless of age")
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		return setupLogging(cmd)
+	}
 }
 
 func main() {
-	// TODO handle log rotation
-	// TODO unique log file for each instance to handle multiple daemons
-	logFile := config.DefaultLogFilePath
+	// the log file is added once the flags are parsed, see setupLogging
+	slog.SetDefault(slog.New(newStdoutLogHandler()))
+	defer closeLogFile()
 
-	logDir := filepath.Dir(logFile)
-	// Create log directory
-	if err := os.MkdirAll(logDir, 0755); err != nil {
-		fmt.Fprintf(os.Stderr, "%s: %s - %s\n", red.Bold(true).Render("ERROR"), "create log directory", err)
+	// Setup root context with signal handling
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+	defer stop()
+
+	// on windows, the context is also cancelled when the service manager stops the service
+	if err := service.Run(ctx, service.DefaultName, rootCmd.ExecuteContext); err != nil {
+		closeLogFile()
 		os.Exit(1)
 	}
+}
+
+// logFile is the log file of this run, opened by setupLogging
+var logFile io.Closer
+
+// setupLogging logs to stdout and to the log file, which is rotated as set by the log flags
+func setupLogging(cmd *cobra.Command) error {
+	logFilePath := logFilePathFor(cmd)
+
+	// Create log directory
+	if err := os.MkdirAll(filepath.Dir(logFilePath), 0755); err != nil {
+		return fmt.Errorf("create log directory: %w", err)
+	}
+
+	maxSize, _ := cmd.Flags().GetInt("log-max-size")
+	maxBackups, _ := cmd.Flags().GetInt("log-max-backups")
+	maxAge, _ := cmd.Flags().GetDuration("log-max-age")
 
-	// Create new log file for this instance
-	file, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	// Append to the log file, the logs of the previous runs are rotated out over time
+	rotator, err := utils.NewLogRotator(logFilePath, utils.LogRotatorConfig{
+		MaxSize:    int64(maxSize) * 1024 * 1024,
+		MaxBackups: maxBackups,
+		MaxAge:     maxAge,
+		Compress:   true,
+	})
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "%s: %s - %s\n", red.Bold(true).Render("ERROR"), "open log file", err)
-		os.Exit(1)
+		return fmt.Errorf("open log file: %w", err)
 	}
-	defer file.Close()
+	logInterceptor := utils.NewLogInterceptor(rotator)
+	logFile = logInterceptor
 
 	// Setup handlers for both outputs
-	stdoutHandler := tint.NewHandler(os.Stdout, &tint.Options{
-		Level:      slog.LevelDebug,
-		TimeFormat: "2006-01-02T15:04:05.000Z07:00",
-		NoColor:    !isatty.IsTerminal(os.Stdout.Fd()),
-	})
-	logInterceptor := utils.NewLogInterceptor(file)
 	fileHandler := slog.NewTextHandler(logInterceptor, &slog.HandlerOptions{
 		Level: slog.LevelDebug,
 		// Do not include time as it is added by the log interceptor.
@@ -117,16 +161,32 @@ func main() {
 	})
 
 	// Create multi-handler
-	multiLogHandler := utils.NewMultiLogHandler(stdoutHandler, fileHandler)
-	logger := slog.New(multiLogHandler)
-	slog.SetDefault(logger)
+	multiLogHandler := utils.NewMultiLogHandler(newStdoutLogHandler(), fileHandler)
+	slog.SetDefault(slog.New(multiLogHandler))
+	return nil
+}
 
-	// Setup root context with signal handling
-	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
-	defer stop()
+// logFilePathFor is the log file of the command. The daemons bound to other addresses than the default one
+// have their own, the other commands log to the default one
+func logFilePathFor(cmd *cobra.Command) string {
+	if flag := cmd.Flags().Lookup("http-addr"); flag != nil {
+		return config.LogFilePathForAddr(flag.Value.String())
+	}
+	return config.DefaultLogFilePath
+}
 
-	if err := rootCmd.ExecuteContext(ctx); err != nil {
-		os.Exit(1)
+func newStdoutLogHandler() slog.Handler {
+	return tint.NewHandler(os.Stdout, &tint.Options{
+		Level:      slog.LevelDebug,
+		TimeFormat: "2006-01-02T15:04:05.000Z07:00",
+		NoColor:    !isatty.IsTerminal(os.Stdout.Fd()),
+	})
+}
+
+func closeLogFile() {
+	if logFile != nil {
+		logFile.Close()
+		logFile = nil
 	}
 }
 
@@ -162,6 +222,13 @@ func loadConfig(cmd *cobra.Command) (*config.Config, error) {
 		}
 	}
 
+	// Merge the selected profile over the top level config
+	if profile := v.GetString("profile"); profile != "" {
+		if err := mergeProfile(v, profile); err != nil {
+			return nil, err
+		}
+	}
+
 	// Unmarshal to server.Config
 	var cfg *config.Config
 	if err := v.Unmarshal(&cfg); err != nil {
@@ -176,22 +243,66 @@ func loadConfig(cmd *cobra.Command) (*config.Config, error) {
 	return cfg, nil
 }
 
+// mergeProfile merges the values of a named profile over the config read by viper
+func mergeProfile(v *viper.Viper, profile string) error {
+	if err := config.ValidateProfileName(profile); err != nil {
+		return err
+	}
+
+	profiles := v.GetStringMap(config.ProfilesKey)
+	values, ok := profiles[profile].(map[string]any)
+	if !ok {
+		return config.UnknownProfileError(profile, slices.Collect(maps.Keys(profiles)))
+	}
+
+	return v.MergeConfigMap(values)
+}
+
 func bindWithDefaults(v *viper.Viper, cmd *cobra.Command) {
 	v.BindPFlag("email", cmd.Flags().Lookup("email"))
 	v.BindPFlag("data_dir", cmd.Flags().Lookup("datadir"))
 	v.BindPFlag("server_url", cmd.Flags().Lookup("server"))
-	v.BindPFlag("config_path", cmd.PersistentFlags().Lookup("config"))
+	v.BindPFlag("config_path", cmd.Flag("config"))
+	v.BindPFlag("profile", cmd.Flag("profile"))
+	v.BindPFlag("shutdown_timeout", cmd.Flags().Lookup("shutdown-timeout"))
+	v.BindPFlag("apps_sync_timeout", cmd.Flags().Lookup("apps-sync-timeout"))
+	v.BindPFlag("sync_read_only", cmd.Flags().Lookup("read-only"))
 	v.SetDefault("apps_enabled", config.DefaultAppsEnabled)
 	v.SetDefault("client_url", "") // this is not used in standard mode
 	v.SetDefault("client_token", "")
 	v.SetDefault("refresh_token", "")
 	v.SetDefault("access_token", "")
+	v.SetDefault("compression_codec", "")
+	v.SetDefault("compression_threshold", 0)
+	v.SetDefault("sync_executable", false)
+	v.SetDefault("sync_xattrs", []string{})
+	v.SetDefault("export_dir", "")
+	v.SetDefault("app_manifest", "")
+	v.SetDefault("sync_workers", 0)
+	v.SetDefault("sync_batch_threshold", 0)
+	v.SetDefault("sync_batch_size", 0)
+	v.SetDefault("sync_upload_requeue", 0)
+	v.SetDefault("sync_read_only", false)
+	v.SetDefault("disable_resume_resync", false)
+	v.SetDefault("disable_network_resync", false)
+	v.SetDefault("sync_verify_hooks", []config.VerifyHookConfig{})
+	v.SetDefault("protocol_mismatch", "")
+	v.SetDefault("shutdown_timeout", config.DefaultShutdownTimeout)
+	v.SetDefault("apps_sync_timeout", config.DefaultAppsSyncTimeout)
+}
+
+// profileName returns the profile selected with the root --profile flag or the SYFTBOX_PROFILE env var
+func profileName(cmd *cobra.Command) string {
+	if flag := cmd.Flag("profile"); flag != nil && flag.Changed {
+		return flag.Value.String()
+	}
+	return os.Getenv("SYFTBOX_PROFILE")
 }
 
 // readValidConfig loads a valid config file at a path
 // does not rely on viper or cobra
-func readValidConfig(configPath string, checkAuth bool) (*config.Config, error) {
-	cfg, err := config.LoadFromFile(configPath)
+func readValidConfig(configPath string, profile string, checkAuth bool) (*config.Config, error) {
+	cfg, err := config.LoadProfileFromFile(configPath, profile)
 	if err != nil {
 		return nil, err
 	}
@@ -204,12 +315,20 @@ func readValidConfig(configPath string, checkAuth bool) (*config.Config, error)
 	return cfg, nil
 }
 
+// loginHint is the guidance for a missing or broken login
+func loginHint() string {
+	return fmt.Sprintf("Please login again by running `%s`", green.Render("syftbox login"))
+}
+
 func logConfig(cfg *config.Config) {
 	var sb strings.Builder
 	sb.WriteString(fmt.Sprintf("\n%s\n", lightGray.Render("SYFTBOX DATASITE CONFIG")))
 	sb.WriteString(fmt.Sprintf("%s\t%s\n", lightGray.Render("Email"), cyan.Render(cfg.Email)))
 	sb.WriteString(fmt.Sprintf("%s\t%s\n", lightGray.Render("Data"), cyan.Render(cfg.DataDir)))
 	sb.WriteString(fmt.Sprintf("%s\t%s\n", lightGray.Render("Config"), cfg.Path))
+	if cfg.Profile != "" {
+		sb.WriteString(fmt.Sprintf("%s\t%s\n", lightGray.Render("Profile"), cfg.Profile))
+	}
 	sb.WriteString(fmt.Sprintf("%s\t%s\n", lightGray.Render("Server"), cfg.ServerURL))
 	fmt.Println(sb.String())
 }
@@ -1,11 +1,14 @@
 package main
 
 import (
+	"log/slog"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
 
+	"github.com/openmined/syftbox/internal/client/config"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -83,3 +86,37 @@ func TestLoadConfigJSON(t *testing.T) {
 	assert.Equal(t, "test-refresh-token-json", cfg.RefreshToken)
 	assert.Equal(t, "test-access-token-json", cfg.AccessToken) // can read, but not persist!
 }
+
+func TestDaemonLogFilePerPort(t *testing.T) {
+	defaultLogFilePath := config.DefaultLogFilePath
+	defaultLogger := slog.Default()
+	t.Cleanup(func() {
+		config.DefaultLogFilePath = defaultLogFilePath
+		slog.SetDefault(defaultLogger)
+	})
+	config.DefaultLogFilePath = filepath.Join(t.TempDir(), "logs", "syftbox.log")
+
+	// the daemons log their port, each to the file it was set up with
+	for _, addr := range []string{"localhost:7938", "127.0.0.1:7939", ":7940"} {
+		cmd := newDaemonCmd()
+		require.NoError(t, cmd.ParseFlags([]string{"--http-addr", addr}))
+		require.NoError(t, setupLogging(cmd))
+		slog.Info("daemon", "addr", addr)
+		closeLogFile()
+	}
+
+	logDir := filepath.Dir(config.DefaultLogFilePath)
+	for name, addr := range map[string]string{
+		"syftbox.log":      "localhost:7938",
+		"syftbox-7939.log": "127.0.0.1:7939",
+		"syftbox-7940.log": ":7940",
+	} {
+		data, err := os.ReadFile(filepath.Join(logDir, name))
+		require.NoError(t, err, name)
+		assert.Equal(t, 1, strings.Count(string(data), "addr="), name)
+		assert.Contains(t, string(data), "addr="+addr, name)
+	}
+
+	// the other commands log to the default file
+	assert.Equal(t, config.DefaultLogFilePath, logFilePathFor(newDoctorCmd()))
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/openmined/syftbox/internal/client/service"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	serviceCmd := newServiceCmd()
+	serviceCmd.AddCommand(newServiceCmdInstall())
+	serviceCmd.AddCommand(newServiceCmdUninstall())
+	serviceCmd.AddCommand(newServiceCmdStart())
+	serviceCmd.AddCommand(newServiceCmdStop())
+	serviceCmd.AddCommand(newServiceCmdStatus())
+	rootCmd.AddCommand(serviceCmd)
+}
+
+func newServiceCmd() *cobra.Command {
+	serviceCmd := &cobra.Command{
+		Use:   "service",
+		Short: "Run SyftBox as a system service",
+		Long:  "Run SyftBox as a system service. Uses a systemd user unit on Linux, a launchd agent on macOS and a Windows service on Windows.",
+	}
+	return serviceCmd
+}
+
+func newServiceCmdInstall() *cobra.Command {
+	var printOnly bool
+
+	serviceCmdInstall := &cobra.Command{
+		Use:   "install",
+		Short: "Install SyftBox as a service that starts on boot",
+		Run: func(cmd *cobra.Command, args []string) {
+			manager, err := getServiceManager(cmd)
+			if err != nil {
+				fmt.Printf("%s: %s\n", red.Render("ERROR"), err)
+				os.Exit(1)
+			}
+
+			if printOnly {
+				def, err := manager.Definition()
+				if err != nil {
+					fmt.Printf("%s: %s\n", red.Render("ERROR"), err)
+					os.Exit(1)
+				}
+				fmt.Print(def)
+				return
+			}
+
+			if err := manager.Install(); err != nil {
+				fmt.Printf("%s: %s\n", red.Render("ERROR"), err)
+				os.Exit(1)
+			}
+
+			fmt.Printf("Installed %s service at '%s'\n", manager.Platform(), green.Bold(true).Render(manager.Path()))
+		},
+	}
+
+	serviceCmdInstall.Flags().BoolVar(&printOnly, "print", false, "Print the generated service definition without installing it")
+
+	return serviceCmdInstall
+}
+
+func newServiceCmdUninstall() *cobra.Command {
+	return newServiceActionCmd("uninstall", "Stop and remove the SyftBox service", "Uninstalled", service.Manager.Uninstall)
+}
+
+func newServiceCmdStart() *cobra.Command {
+	return newServiceActionCmd("start", "Start the SyftBox service", "Started", service.Manager.Start)
+}
+
+func newServiceCmdStop() *cobra.Command {
+	return newServiceActionCmd("stop", "Stop the SyftBox service", "Stopped", service.Manager.Stop)
+}
+
+func newServiceCmdStatus() *cobra.Command {
+	serviceCmdStatus := &cobra.Command{
+		Use:   "status",
+		Short: "Show the status of the SyftBox service",
+		Run: func(cmd *cobra.Command, args []string) {
+			manager, err := getServiceManager(cmd)
+			if err != nil {
+				fmt.Printf("%s: %s\n", red.Render("ERROR"), err)
+				os.Exit(1)
+			}
+
+			status, err := manager.Status()
+			if err != nil {
+				fmt.Printf("%s: %s\n", red.Render("ERROR"), err)
+				os.Exit(1)
+			}
+
+			var rendered string
+			switch status {
+			case service.StatusRunning:
+				rendered = green.Render(string(status))
+			case service.StatusStopped:
+				rendered = yellow.Render(string(status))
+			default:
+				rendered = red.Render(string(status))
+			}
+
+			fmt.Printf("%s%s\n", gray.Render("Service  "), manager.Platform())
+			fmt.Printf("%s%s\n", gray.Render("Path     "), cyan.Render(manager.Path()))
+			fmt.Printf("%s%s\n", gray.Render("Status   "), rendered)
+		},
+	}
+	return serviceCmdStatus
+}
+
+func newServiceActionCmd(use string, short string, done string, action func(service.Manager) error) *cobra.Command {
+	return &cobra.Command{
+		Use:   use,
+		Short: short,
+		Run: func(cmd *cobra.Command, args []string) {
+			manager, err := getServiceManager(cmd)
+			if err != nil {
+				fmt.Printf("%s: %s\n", red.Render("ERROR"), err)
+				os.Exit(1)
+			}
+
+			if err := action(manager); err != nil {
+				fmt.Printf("%s: %s\n", red.Render("ERROR"), err)
+				os.Exit(1)
+			}
+
+			fmt.Printf("%s %s service\n", done, manager.Platform())
+		},
+	}
+}
+
+func getServiceManager(cmd *cobra.Command) (service.Manager, error) {
+	// fetched from main/rootCmd/persistentFlags
+	configPath := cmd.Flag("config").Value.String()
+
+	// the service runs the client in standalone mode, so the config must be valid & logged in
+	cfg, err := readValidConfig(configPath, profileName(cmd), true)
+	if err != nil {
+		return nil, fmt.Errorf("%w. run `syftbox login` first", err)
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("resolve executable: %w", err)
+	}
+
+	exe, err = filepath.EvalSymlinks(exe)
+	if err != nil {
+		return nil, fmt.Errorf("resolve executable: %w", err)
+	}
+
+	svcCfg := &service.Config{
+		Executable: exe,
+		ConfigPath: cfg.Path,
+		Profile:    cfg.Profile,
+	}
+
+	return service.New(svcCfg)
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/dustin/go-humanize"
+	"github.com/openmined/syftbox/internal/client/config"
+	"github.com/openmined/syftbox/internal/client/sync"
+	"github.com/openmined/syftbox/internal/client/workspace"
+	"github.com/openmined/syftbox/internal/fileattr"
+	"github.com/openmined/syftbox/internal/syftsdk"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(newSyncCmd())
+}
+
+func newSyncCmd() *cobra.Command {
+	var output string
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Show what a full sync would upload, download and delete",
+		Long: `Plan a full sync of the local files, the journal and the server, and report each operation it
+would run with its direction, size and reason. Nothing is changed, neither the local files nor the blob storage.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+
+			if !dryRun {
+				return errors.New("sync only supports --dry-run, run `syftbox` to start syncing")
+			}
+			if output != configOutputText && output != configOutputJSON {
+				return fmt.Errorf("invalid output format %q", output)
+			}
+
+			cfg, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+			if err := cfg.Validate(); err != nil {
+				return fmt.Errorf("invalid config: %w", err)
+			}
+
+			ops, err := runSyncDryRun(cmd.Context(), cfg)
+			if err != nil {
+				return err
+			}
+
+			return printSyncPlan(cmd.OutOrStdout(), ops, output)
+		},
+	}
+
+	cmd.Flags().SortFlags = false
+	cmd.Flags().StringP("email", "e", "", "your email for your syftbox datasite")
+	cmd.Flags().StringP("datadir", "d", config.DefaultDataDir, "data directory where the syftbox workspace is stored")
+	cmd.Flags().StringP("server", "s", config.DefaultServerURL, "url of the syftbox server")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "report the operations without running them")
+	cmd.Flags().StringVarP(&output, "output", "o", configOutputText, "output format (text, json)")
+
+	return cmd
+}
+
+// dryRunAndExit handles the root --dry-run flag
+func dryRunAndExit(cmd *cobra.Command, cfg *config.Config) {
+	output, _ := cmd.Flags().GetString("output")
+	if output != configOutputText && output != configOutputJSON {
+		fmt.Fprintf(os.Stderr, "%s: invalid output format %q\n", red.Render("ERROR"), output)
+		os.Exit(1)
+	}
+
+	ops, err := runSyncDryRun(cmd.Context(), cfg)
+	if err == nil {
+		err = printSyncPlan(cmd.OutOrStdout(), ops, output)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", red.Render("ERROR"), err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+// runSyncDryRun plans a full sync like the client does, without running any of the operations
+func runSyncDryRun(ctx context.Context, cfg *config.Config) (*sync.ReconcileOperations, error) {
+	ws, err := workspace.NewWorkspace(cfg.DataDir, cfg.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	sdk, err := syftsdk.New(&syftsdk.SyftSDKConfig{
+		BaseURL:      cfg.ServerURL,
+		Email:        cfg.Email,
+		RefreshToken: cfg.RefreshToken,
+		AccessToken:  cfg.AccessToken,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer sdk.Close()
+
+	if err := sdk.Authenticate(ctx); err != nil {
+		return nil, fmt.Errorf("authenticate: %w", err)
+	}
+
+	mgr, err := sync.NewManager(ws, sdk, &sync.SyncOptions{
+		FileAttrs: &fileattr.Options{
+			Executable: cfg.SyncExecutable,
+			Xattrs:     cfg.SyncXattrs,
+		},
+		Transfer: &sync.TransferOptions{
+			Workers:        cfg.SyncWorkers,
+			BatchThreshold: cfg.SyncBatchThreshold,
+			BatchSize:      cfg.SyncBatchSize,
+		},
+		IncludeHidden:   cfg.IncludeHidden(),
+		KeepRejected:    cfg.SyncKeepRejected,
+		ReadOnly:        cfg.SyncReadOnly,
+		UploadRequeue:   cfg.SyncUploadRequeue,
+		ShutdownTimeout: cfg.ShutdownTimeout,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return mgr.DryRun(ctx)
+}
+
+// syncPlanReport is the json output of `sync --dry-run`
+type syncPlanReport struct {
+	Operations []*sync.PlannedOp `json:"operations"`
+	Unchanged  int               `json:"unchanged"`
+	Ignored    int               `json:"ignored"`
+}
+
+func printSyncPlan(w io.Writer, ops *sync.ReconcileOperations, output string) error {
+	report := &syncPlanReport{
+		Operations: ops.Plan(),
+		Unchanged:  len(ops.UnchangedPaths),
+		Ignored:    len(ops.Ignored),
+	}
+
+	if output == configOutputJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+
+	if len(report.Operations) == 0 {
+		fmt.Fprintf(w, "%s %d unchanged, %d ignored\n", lightGray.Render("nothing to sync"), report.Unchanged, report.Ignored)
+		return nil
+	}
+
+	var transfer int64
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", "DIRECTION", "PATH", "SIZE", "REASON")
+	for _, op := range report.Operations {
+		size := "-"
+		if op.Direction != sync.DirectionCleanup {
+			size = humanize.Bytes(uint64(op.Size))
+		}
+		if op.Direction == sync.DirectionUpload || op.Direction == sync.DirectionDownload {
+			transfer += op.Size
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", op.Direction, op.Path, size, op.Reason)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "%s %d operations transferring %s, %d unchanged, %d ignored\n",
+		lightGray.Render("would run"), len(report.Operations), humanize.Bytes(uint64(transfer)), report.Unchanged, report.Ignored,
+	)
+	return nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/openmined/syftbox/internal/client/config"
+	"github.com/openmined/syftbox/internal/client/sync"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestSyncCmd builds `sync` under a fresh root, so that flags don't leak between tests
+func newTestSyncCmd() *cobra.Command {
+	root := &cobra.Command{Use: "syftbox"}
+	root.PersistentFlags().StringP("config", "c", config.DefaultConfigPath, "path to config file")
+	root.PersistentFlags().StringP("profile", "p", "", "profile of the config file to use")
+	root.AddCommand(newSyncCmd())
+	return root
+}
+
+func testSyncPlanOps() *sync.ReconcileOperations {
+	ops := sync.NewReconcileOperations()
+	ops.RemoteWrites["user@example.com/public/b.txt"] = &sync.SyncOperation{
+		Type:   sync.OpWriteRemote,
+		Local:  &sync.FileMetadata{Size: 2048},
+		Reason: sync.ReasonLocalModified,
+	}
+	ops.RemoteWrites["user@example.com/public/a.txt"] = &sync.SyncOperation{
+		Type:   sync.OpWriteRemote,
+		Local:  &sync.FileMetadata{Size: 10},
+		Reason: sync.ReasonLocalCreated,
+	}
+	ops.LocalWrites["peer@example.com/public/new.txt"] = &sync.SyncOperation{
+		Type:   sync.OpWriteLocal,
+		Remote: &sync.FileMetadata{Size: 1000},
+		Reason: sync.ReasonRemoteCreated,
+	}
+	ops.LocalDeletes["user@example.com/public/gone.txt"] = &sync.SyncOperation{
+		Type:   sync.OpDeleteLocal,
+		Local:  &sync.FileMetadata{Size: 5},
+		Reason: sync.ReasonRemoteDeleted,
+	}
+	ops.UnchangedPaths["user@example.com/public/same.txt"] = struct{}{}
+	return ops
+}
+
+func TestSyncRequiresDryRun(t *testing.T) {
+	root := newTestSyncCmd()
+	root.SetOut(&bytes.Buffer{})
+	root.SetErr(&bytes.Buffer{})
+	root.SetArgs([]string{"sync"})
+	assert.ErrorContains(t, root.Execute(), "--dry-run")
+}
+
+func TestSyncPlanText(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, printSyncPlan(&out, testSyncPlanOps(), configOutputText))
+
+	assert.Regexp(t, `(?s)DIRECTION\s+PATH\s+SIZE\s+REASON\n`+
+		`upload\s+user@example.com/public/a.txt\s+10 B\s+local created\n`+
+		`upload\s+user@example.com/public/b.txt\s+2.0 kB\s+local modified\n`+
+		`download\s+peer@example.com/public/new.txt\s+1.0 kB\s+remote created\n`+
+		`delete local\s+user@example.com/public/gone.txt\s+5 B\s+remote deleted\n`, out.String())
+	assert.Contains(t, out.String(), "4 operations transferring 3.1 kB, 1 unchanged, 0 ignored")
+
+	out.Reset()
+	require.NoError(t, printSyncPlan(&out, sync.NewReconcileOperations(), configOutputText))
+	assert.Contains(t, out.String(), "0 unchanged, 0 ignored")
+}
+
+func TestSyncPlanJSON(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, printSyncPlan(&out, testSyncPlanOps(), configOutputJSON))
+
+	var report syncPlanReport
+	require.NoError(t, json.Unmarshal(out.Bytes(), &report))
+	require.Len(t, report.Operations, 4)
+	assert.Equal(t, &sync.PlannedOp{
+		Path:      "peer@example.com/public/new.txt",
+		Direction: sync.DirectionDownload,
+		Size:      1000,
+		Reason:    sync.ReasonRemoteCreated,
+	}, report.Operations[2])
+	assert.Equal(t, 1, report.Unchanged)
+}
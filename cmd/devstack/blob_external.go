@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+const externalBucketTimeout = 10 * time.Second
+
+// externalBlob is an S3 or MinIO endpoint the server uses instead of a MinIO started by sbdev
+type externalBlob struct {
+	endpoint  string
+	bucket    string
+	region    string
+	accessKey string
+	secretKey string
+}
+
+func (b externalBlob) enabled() bool {
+	return b.endpoint != ""
+}
+
+// validate checks the --blob-* flags. They're all ignored without --blob-endpoint, so setting
+// any of them alone is an error rather than a silent local MinIO.
+func (b externalBlob) validate() error {
+	if !b.enabled() {
+		if b.bucket != "" || b.region != "" || b.accessKey != "" || b.secretKey != "" {
+			return fmt.Errorf("--blob-bucket, --blob-region and the --blob-*-key flags require --blob-endpoint")
+		}
+		return nil
+	}
+
+	u, err := url.Parse(b.endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid --blob-endpoint %q: want an http(s) url like https://s3.us-east-1.amazonaws.com", b.endpoint)
+	}
+	if b.bucket == "" {
+		return fmt.Errorf("--blob-bucket is required with --blob-endpoint")
+	}
+	if b.accessKey == "" || b.secretKey == "" {
+		return fmt.Errorf("--blob-access-key and --blob-secret-key are required with --blob-endpoint")
+	}
+	return nil
+}
+
+// state is what the stack records about the external endpoint. The server uses the keys as they are.
+func (b externalBlob) state() minioState {
+	return minioState{
+		Mode:      "external",
+		Endpoint:  strings.TrimSuffix(b.endpoint, "/"),
+		Bucket:    b.bucket,
+		Region:    b.region,
+		AccessKey: b.accessKey,
+		SecretKey: b.secretKey,
+	}
+}
+
+// endpoint is the url the server reaches the blob storage at
+func (m minioState) endpoint() string {
+	if m.Endpoint != "" {
+		return m.Endpoint
+	}
+	return fmt.Sprintf("http://127.0.0.1:%d", m.APIPort)
+}
+
+func (m minioState) bucket() string {
+	if m.Bucket != "" {
+		return m.Bucket
+	}
+	return defaultBucket
+}
+
+func (m minioState) region() string {
+	if m.Region != "" {
+		return m.Region
+	}
+	return defaultRegion
+}
+
+// checkExternalBucket fails unless the bucket exists and the keys can access it.
+// sbdev doesn't create buckets it doesn't own.
+func checkExternalBucket(mState minioState) error {
+	client, err := newS3Client(mState.endpoint(), mState.AccessKey, mState.SecretKey, mState.region())
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), externalBucketTimeout)
+	defer cancel()
+	if _, err := client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(mState.bucket())}); err != nil {
+		return fmt.Errorf("bucket %s at %s: %w", mState.bucket(), mState.endpoint(), err)
+	}
+	return nil
+}
+
+func printMinio(mState minioState) {
+	if mState.Mode == "external" {
+		fmt.Printf("  Blob:   %s (bucket %s, external)\n", mState.endpoint(), mState.bucket())
+		return
+	}
+	fmt.Printf("  MinIO:  http://127.0.0.1:%d (console http://127.0.0.1:%d)\n", mState.APIPort, mState.ConsolePort)
+}
@@ -104,12 +104,12 @@ func TestDevstackIntegration(t *testing.T) {
 	// Start MinIO
 	t.Logf("Starting MinIO on port %d...", minioAPIPort)
 	minioMode := "local"
-	minioBin, err := ensureMinioBinary(binDir)
+	minioBin, err := ensureMinioBinary(binDir, "")
 	if err != nil {
 		t.Fatalf("minio binary unavailable: %v", err)
 	}
 
-	mState, err := startMinio(minioMode, minioBin, relayRoot, minioAPIPort, minioConsolePort, false)
+	mState, err := startMinio(minioMode, minioBin, relayRoot, minioAPIPort, minioConsolePort, false, "")
 	if err != nil {
 		t.Fatalf("start minio: %v", err)
 	}
@@ -118,13 +118,13 @@ func TestDevstackIntegration(t *testing.T) {
 	// Setup bucket
 	t.Logf("Setting up MinIO bucket...")
 	serverURL := fmt.Sprintf("http://127.0.0.1:%d", serverPort)
-	if err := setupBucket(mState.APIPort); err != nil {
+	if err := setupBucket(mState, relayRoot); err != nil {
 		t.Fatalf("minio bootstrap: %v", err)
 	}
 
 	// Start server
 	t.Logf("Starting server on port %d...", serverPort)
-	sState, err := startServer(serverBin, relayRoot, serverPort, mState.APIPort)
+	sState, err := startServer(serverBin, relayRoot, serverPort, mState)
 	if err != nil {
 		stopMinio(mState)
 		t.Fatalf("start server: %v", err)
@@ -178,7 +178,7 @@ func TestDevstackIntegration(t *testing.T) {
 
 	// Run sync check
 	t.Logf("Running sync check...")
-	if err := runSyncCheck(opts.root, emails); err != nil {
+	if err := runSyncCheck(opts.root, emails, defaultSyncCheckTimeout); err != nil {
 		t.Fatalf("sync check failed: %v", err)
 	}
 
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+type execOptions struct {
+	root  string
+	email string
+	args  []string
+}
+
+// parseExecFlags parses `exec [--path DIR] --client EMAIL -- ARGS...`.
+// everything after -- is passed to the client unchanged
+func parseExecFlags(args []string) (execOptions, error) {
+	opts := execOptions{root: defaultRoot}
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--path":
+			i++
+			if i >= len(args) {
+				return opts, fmt.Errorf("--path requires a directory")
+			}
+			opts.root = args[i]
+		case "--client":
+			i++
+			if i >= len(args) {
+				return opts, fmt.Errorf("--client requires an email")
+			}
+			opts.email = args[i]
+		case "--":
+			opts.args = args[i+1:]
+			i = len(args)
+		default:
+			return opts, fmt.Errorf("unknown option %q, pass the client arguments after --", args[i])
+		}
+	}
+	if opts.email == "" {
+		return opts, fmt.Errorf("--client is required")
+	}
+	if len(opts.args) == 0 {
+		return opts, fmt.Errorf("no client command, e.g. sbdev exec --client %s -- status", opts.email)
+	}
+	return opts, nil
+}
+
+func runExec(args []string) error {
+	opts, err := parseExecFlags(args)
+	if err != nil {
+		return err
+	}
+	root, err := filepath.Abs(opts.root)
+	if err != nil {
+		return fmt.Errorf("resolve root: %w", err)
+	}
+	state, _, err := readState(root)
+	if err != nil {
+		return err
+	}
+	return execClient(state, opts.email, opts.args, os.Stdin, os.Stdout, os.Stderr)
+}
+
+// execClient runs the client binary of email with args, against its config and sandboxed home like the daemon
+func execClient(state *stackState, email string, args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	c, err := findClient(state, email)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(c.BinPath, append([]string{"-c", c.Config}, args...)...)
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.Env = append(os.Environ(),
+		"HOME="+c.HomePath,
+		"SYFTBOX_CONFIG_PATH="+c.Config,
+		"SYFTBOX_DATA_DIR="+c.DataPath,
+		"SYFTBOX_SERVER_URL="+c.ServerURL,
+	)
+	return cmd.Run()
+}
+
+func findClient(state *stackState, email string) (*clientState, error) {
+	emails := make([]string, 0, len(state.Clients))
+	for i := range state.Clients {
+		if strings.EqualFold(state.Clients[i].Email, email) {
+			return &state.Clients[i], nil
+		}
+		emails = append(emails, state.Clients[i].Email)
+	}
+	if len(emails) == 0 {
+		return nil, fmt.Errorf("client %s is not in the stack at %s, it has no clients", email, state.Root)
+	}
+	return nil, fmt.Errorf("client %s is not in the stack at %s, clients: %s", email, state.Root, strings.Join(emails, ", "))
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseExecFlags(t *testing.T) {
+	opts, err := parseExecFlags([]string{"--path", "sandbox", "--client", "alice@example.com", "--", "acl", "preview", "--path", "x"})
+	require.NoError(t, err)
+	assert.Equal(t, "sandbox", opts.root)
+	assert.Equal(t, "alice@example.com", opts.email)
+	assert.Equal(t, []string{"acl", "preview", "--path", "x"}, opts.args, "the flags after -- belong to the client")
+
+	opts, err = parseExecFlags([]string{"--client", "alice@example.com", "--", "status"})
+	require.NoError(t, err)
+	assert.Equal(t, defaultRoot, opts.root)
+
+	for _, args := range [][]string{
+		{"--", "status"},
+		{"--client", "alice@example.com"},
+		{"--client", "alice@example.com", "--"},
+		{"--client", "alice@example.com", "status"},
+		{"--client"},
+	} {
+		_, err := parseExecFlags(args)
+		assert.Error(t, err, args)
+	}
+}
+
+func TestExecClient(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake client is a shell script")
+	}
+
+	dir := t.TempDir()
+	bin := filepath.Join(dir, "syftbox")
+	require.NoError(t, os.WriteFile(bin, []byte("#!/bin/sh\necho \"$@\"\necho \"home=$HOME data=$SYFTBOX_DATA_DIR\"\necho oops >&2\nexit 3\n"), 0o755))
+
+	state := &stackState{
+		Root: dir,
+		Clients: []clientState{{
+			Email:     "alice@example.com",
+			Config:    filepath.Join(dir, "config.json"),
+			DataPath:  filepath.Join(dir, "data"),
+			HomePath:  filepath.Join(dir, "home"),
+			BinPath:   bin,
+			ServerURL: "http://127.0.0.1:8080",
+		}},
+	}
+
+	var stdout, stderr bytes.Buffer
+	err := execClient(state, "alice@example.com", []string{"status", "--json"}, strings.NewReader(""), &stdout, &stderr)
+
+	var exitErr *exec.ExitError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, 3, exitErr.ExitCode())
+	assert.Equal(t, "-c "+state.Clients[0].Config+" status --json\nhome="+state.Clients[0].HomePath+" data="+state.Clients[0].DataPath+"\n", stdout.String())
+	assert.Equal(t, "oops\n", stderr.String())
+
+	err = execClient(state, "bob@example.com", []string{"status"}, nil, &stdout, &stderr)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "alice@example.com", "the error lists the clients of the stack")
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	logPollInterval = 250 * time.Millisecond
+	// logTailLines is how many existing lines are printed before following, like tail -f
+	logTailLines = 10
+)
+
+// logSource is a log file of a stack component, printed with its name as prefix
+type logSource struct {
+	Name string
+	Path string
+}
+
+type logLine struct {
+	source string
+	text   string
+}
+
+func stackLogSources(state *stackState) []logSource {
+	sources := []logSource{
+		{Name: "server", Path: state.Server.LogPath},
+		{Name: "minio", Path: state.Minio.LogPath},
+	}
+	for _, c := range state.Clients {
+		sources = append(sources, logSource{Name: c.Email, Path: c.LogPath})
+		for _, path := range clientLogFiles(c.HomePath) {
+			sources = append(sources, logSource{Name: c.Email + "/" + filepath.Base(path), Path: path})
+		}
+	}
+	return sources
+}
+
+// clientLogFileRe matches the log files of the client daemons, syftbox.log for the default port
+// and syftbox-<port>.log for the others, but not their rotated files
+var clientLogFileRe = regexp.MustCompile(`^syftbox(-[^.]+)?\.log$`)
+
+// clientLogFiles lists the log files the client daemons wrote under the home dir of a client
+func clientLogFiles(homePath string) []string {
+	logDir := filepath.Join(homePath, ".syftbox", "logs")
+	entries, err := os.ReadDir(logDir)
+	if err != nil {
+		return nil
+	}
+
+	var paths []string
+	for _, entry := range entries {
+		if !entry.IsDir() && clientLogFileRe.MatchString(entry.Name()) {
+			paths = append(paths, filepath.Join(logDir, entry.Name()))
+		}
+	}
+	return paths
+}
+
+// streamLogs prints the lines of all sources to w, interleaved as they are read.
+// Lines newer than since are printed first, or the last few lines when since is zero.
+// With follow it keeps reading new lines until ctx is cancelled, otherwise it returns at the end of the files.
+func streamLogs(ctx context.Context, w io.Writer, sources []logSource, since time.Duration, follow bool) error {
+	lines := make(chan logLine, 256)
+
+	var wg sync.WaitGroup
+	for _, src := range sources {
+		if src.Path == "" {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := tailLog(ctx, src, since, follow, lines); err != nil && !errors.Is(err, context.Canceled) {
+				lines <- logLine{source: src.Name, text: fmt.Sprintf("sbdev: stopped reading %s: %v", src.Path, err)}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(lines)
+	}()
+
+	for line := range lines {
+		fmt.Fprintf(w, "[%s] %s\n", line.source, line.text)
+	}
+	return nil
+}
+
+// tailLog sends the lines of a log file to out. When following, it reopens the file
+// if it is rotated and starts over if it is truncated.
+func tailLog(ctx context.Context, src logSource, since time.Duration, follow bool, out chan<- logLine) error {
+	f, err := openLog(ctx, src.Path, follow)
+	if err != nil {
+		return err
+	}
+	defer func() { f.Close() }()
+
+	var cutoff time.Time
+	if since > 0 {
+		cutoff = time.Now().Add(-since)
+	}
+	offset, err := logStartOffset(f, cutoff)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	reader := bufio.NewReader(f)
+	var partial string
+	for {
+		chunk, err := reader.ReadString('\n')
+		offset += int64(len(chunk))
+		if err == nil {
+			select {
+			case out <- logLine{source: src.Name, text: strings.TrimRight(partial+chunk, "\r\n")}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			partial = ""
+			continue
+		}
+		if !errors.Is(err, io.EOF) {
+			return err
+		}
+
+		// keep an incomplete line until the writer finishes it
+		partial += chunk
+		if !follow {
+			if partial != "" {
+				out <- logLine{source: src.Name, text: partial}
+			}
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(logPollInterval):
+		}
+
+		rotated, truncated := logChanged(f, src.Path, offset)
+		switch {
+		case rotated:
+			next, err := os.Open(src.Path)
+			if err != nil {
+				continue // not recreated yet
+			}
+			// whatever was written before the rotation
+			for {
+				chunk, err := reader.ReadString('\n')
+				if line := strings.TrimRight(partial+chunk, "\r\n"); line != "" {
+					out <- logLine{source: src.Name, text: line}
+				}
+				partial = ""
+				if err != nil {
+					break
+				}
+			}
+			f.Close()
+			f = next
+		case truncated:
+			if _, err := f.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+		default:
+			continue
+		}
+		offset = 0
+		partial = ""
+		reader.Reset(f)
+	}
+}
+
+// openLog opens a log file. When following, it waits for the file to be created.
+func openLog(ctx context.Context, path string, follow bool) (*os.File, error) {
+	for {
+		f, err := os.Open(path)
+		if err == nil || !follow || !errors.Is(err, os.ErrNotExist) {
+			return f, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(logPollInterval):
+		}
+	}
+}
+
+// logChanged reports whether path now points to a different file than f (rotated),
+// or f got shorter than what was already read (truncated)
+func logChanged(f *os.File, path string, offset int64) (rotated bool, truncated bool) {
+	current, err := f.Stat()
+	if err != nil {
+		return false, false
+	}
+	onDisk, err := os.Stat(path)
+	if err != nil {
+		return false, false // moved away and not recreated yet
+	}
+	if !os.SameFile(current, onDisk) {
+		return true, false
+	}
+	return false, current.Size() < offset
+}
+
+// logStartOffset returns where to start printing a log file: at the first line stamped at or
+// after cutoff, or at the last logTailLines lines when cutoff is zero.
+// Lines without a timestamp belong to the line before them.
+func logStartOffset(f *os.File, cutoff time.Time) (int64, error) {
+	reader := bufio.NewReader(f)
+	var offset int64
+	var lineStarts []int64
+
+	for {
+		line, err := reader.ReadString('\n')
+		if len(line) > 0 {
+			if !cutoff.IsZero() {
+				if ts, ok := parseLogTime(line); ok && !ts.Before(cutoff) {
+					return offset, nil
+				}
+			} else if strings.HasSuffix(line, "\n") {
+				lineStarts = append(lineStarts, offset)
+				if len(lineStarts) > logTailLines {
+					lineStarts = lineStarts[1:]
+				}
+			}
+			offset += int64(len(line))
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if cutoff.IsZero() && len(lineStarts) > 0 {
+		return lineStarts[0], nil
+	}
+	// nothing recent enough
+	return offset, nil
+}
+
+var (
+	ansiEscape   = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+	logTimeField = regexp.MustCompile(`(?:^|\s)time=(?:"([^"]+)"|(\S+))|"time":"([^"]+)"`)
+)
+
+// layouts of the timestamps written by the server, client and minio
+var logTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.000Z07:00",
+	time.DateTime,
+}
+
+// parseLogTime extracts the timestamp of a log line, either a leading one or a time field
+func parseLogTime(line string) (time.Time, bool) {
+	line = strings.TrimSpace(ansiEscape.ReplaceAllString(line, ""))
+
+	if m := logTimeField.FindStringSubmatch(line); m != nil {
+		if ts, ok := parseLogTimeValue(m[1] + m[2] + m[3]); ok {
+			return ts, true
+		}
+	}
+
+	// leading timestamp, possibly a date and a time separated by a space
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return time.Time{}, false
+	}
+	if ts, ok := parseLogTimeValue(fields[0]); ok {
+		return ts, true
+	}
+	if len(fields) > 1 {
+		return parseLogTimeValue(fields[0] + " " + fields[1])
+	}
+	return time.Time{}, false
+}
+
+func parseLogTimeValue(value string) (time.Time, bool) {
+	for _, layout := range logTimeLayouts {
+		if ts, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return ts, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// syncBuffer is a bytes.Buffer safe to read while streamLogs writes to it
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestParseLogTime(t *testing.T) {
+	want := time.Date(2025, 6, 1, 10, 30, 0, 0, time.Local)
+
+	for _, line := range []string{
+		"time=" + want.Format(time.RFC3339) + " level=INFO msg=hello",
+		`time="` + want.Format(time.DateTime) + `" level=INFO`,
+		`{"time":"` + want.UTC().Format(time.RFC3339Nano) + `","level":"INFO"}`,
+		"\x1b[2m" + want.Format(time.DateTime) + "\x1b[0m INF hello",
+		want.Format("2006-01-02T15:04:05.000Z07:00") + " INF hello",
+	} {
+		ts, ok := parseLogTime(line)
+		require.True(t, ok, line)
+		assert.True(t, want.Equal(ts), line)
+	}
+
+	_, ok := parseLogTime("panic: something went wrong")
+	assert.False(t, ok)
+}
+
+func TestStreamLogsSince(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.log")
+	old := time.Now().Add(-time.Hour).Format(time.RFC3339)
+	recent := time.Now().Add(-time.Minute).Format(time.RFC3339)
+	require.NoError(t, os.WriteFile(path, []byte(
+		"time="+old+" msg=old\n"+
+			"time="+recent+" msg=recent\n"+
+			"  continued stack trace\n",
+	), 0o644))
+
+	var out syncBuffer
+	require.NoError(t, streamLogs(context.Background(), &out, []logSource{{Name: "server", Path: path}}, 10*time.Minute, false))
+	assert.Equal(t, "[server] time="+recent+" msg=recent\n[server]   continued stack trace\n", out.String())
+}
+
+func TestStreamLogsFollowTruncateAndRotate(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "client-daemon.log")
+	require.NoError(t, os.WriteFile(path, []byte("before\n"), 0o644))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var out syncBuffer
+	done := make(chan error)
+	go func() {
+		done <- streamLogs(ctx, &out, []logSource{{Name: "alice@example.com", Path: path}}, 0, true)
+	}()
+
+	waitFor := func(want string) {
+		t.Helper()
+		require.Eventually(t, func() bool { return strings.Contains(out.String(), want) }, 5*time.Second, 20*time.Millisecond, out.String())
+	}
+	appendLine := func(line string) {
+		t.Helper()
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+		require.NoError(t, err)
+		_, err = f.WriteString(line + "\n")
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+	}
+
+	waitFor("[alice@example.com] before\n")
+	appendLine("appended")
+	waitFor("[alice@example.com] appended\n")
+
+	// truncated in place, as by a restart of the process
+	require.NoError(t, os.WriteFile(path, []byte("truncated\n"), 0o644))
+	waitFor("[alice@example.com] truncated\n")
+
+	// rotated: moved away and recreated
+	require.NoError(t, os.Rename(path, path+".1"))
+	require.NoError(t, os.WriteFile(path, []byte("rotated\n"), 0o644))
+	waitFor("[alice@example.com] rotated\n")
+
+	cancel()
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("streamLogs did not stop")
+	}
+}
+
+func TestStackLogSourcesClientLogFiles(t *testing.T) {
+	home := t.TempDir()
+	logDir := filepath.Join(home, ".syftbox", "logs")
+	require.NoError(t, os.MkdirAll(logDir, 0o755))
+	for _, name := range []string{"syftbox.log", "syftbox-7939.log", "syftbox-7940.log", "syftbox-7939.20250601T103000.000.log", "syftbox-7940.20250601T103000.000.log.gz"} {
+		require.NoError(t, os.WriteFile(filepath.Join(logDir, name), nil, 0o644))
+	}
+
+	state := &stackState{Clients: []clientState{{Email: "alice@example.com", LogPath: "client-daemon.log", HomePath: home}}}
+	var names []string
+	for _, src := range stackLogSources(state) {
+		names = append(names, src.Name)
+	}
+
+	// every daemon log file, but not the rotated ones
+	assert.Equal(t, []string{
+		"server",
+		"minio",
+		"alice@example.com",
+		"alice@example.com/syftbox-7939.log",
+		"alice@example.com/syftbox-7940.log",
+		"alice@example.com/syftbox.log",
+	}, names)
+}
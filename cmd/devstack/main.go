@@ -12,11 +12,15 @@ import (
 	"net/http"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
+	"regexp"
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
+	"text/tabwriter"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -47,18 +51,23 @@ const (
 	stateFileName              = "state.json"
 	minioBinaryName            = "minio"
 	minioDownloadBase          = "https://dl.min.io/server/minio/release"
+	minioDockerImage           = "minio/minio"
+	defaultMinioDockerVersion  = "RELEASE.2025-04-22T22-12-26Z"
+	minioVersionEnv            = "SBDEV_MINIO_VERSION"
 	processShutdownGracePeriod = 8 * time.Second
 )
 
 type command string
 
 const (
-	cmdStart  command = "start"
-	cmdStop   command = "stop"
-	cmdStatus command = "status"
-	cmdLogs   command = "logs"
-	cmdList   command = "list"
-	cmdPrune  command = "prune"
+	cmdStart   command = "start"
+	cmdStop    command = "stop"
+	cmdStatus  command = "status"
+	cmdLogs    command = "logs"
+	cmdList    command = "list"
+	cmdPrune   command = "prune"
+	cmdRestart command = "restart"
+	cmdExec    command = "exec"
 )
 
 type stackState struct {
@@ -80,7 +89,7 @@ type serverState struct {
 }
 
 type minioState struct {
-	Mode        string `json:"mode"` // local or docker
+	Mode        string `json:"mode"` // local, docker or external
 	PID         int    `json:"pid,omitempty"`
 	LogPID      int    `json:"log_pid,omitempty"`
 	ContainerID string `json:"container_id,omitempty"`
@@ -89,6 +98,14 @@ type minioState struct {
 	DataPath    string `json:"data_path"`
 	LogPath     string `json:"log_path"`
 	BinPath     string `json:"bin_path,omitempty"`
+	Version     string `json:"version,omitempty"` // pinned release, empty for the default
+	// service account of the server, empty when it uses the root credentials
+	AccessKey string `json:"access_key,omitempty"`
+	SecretKey string `json:"secret_key,omitempty"`
+	// S3 or MinIO not started by sbdev, see --blob-endpoint
+	Endpoint string `json:"endpoint,omitempty"`
+	Bucket   string `json:"bucket,omitempty"`
+	Region   string `json:"region,omitempty"`
 }
 
 type clientState struct {
@@ -112,8 +129,13 @@ type startOptions struct {
 	minioAPIPort    int
 	minioConsole    int
 	useDockerMinio  bool
+	minioVersion    string
+	accessKey       string
+	secretKey       string
+	blob            externalBlob
 	keepData        bool
 	skipSyncCheck   bool
+	syncTimeout     time.Duration
 	reset           bool
 }
 
@@ -126,14 +148,14 @@ func addExe(path string) string {
 
 func main() {
 	if len(os.Args) < 2 {
-		fmt.Println("usage: sbdev <start|stop|status|logs|list|prune> [options]")
+		fmt.Println("usage: sbdev <start|stop|restart|status|logs|exec|list|prune> [options]")
 		os.Exit(1)
 	}
 
 	switch command(os.Args[1]) {
 	case cmdStart:
 		// Auto-prune before starting
-		if err := pruneDeadStacks(); err != nil {
+		if _, err := pruneDeadStacks(pruneOptions{}); err != nil {
 			log.Printf("Warning: failed to prune dead stacks: %v", err)
 		}
 		if err := runStart(os.Args[2:]); err != nil {
@@ -143,6 +165,10 @@ func main() {
 		if err := runStop(os.Args[2:]); err != nil {
 			log.Fatalf("stop: %v", err)
 		}
+	case cmdRestart:
+		if err := runRestart(os.Args[2:]); err != nil {
+			log.Fatalf("restart: %v", err)
+		}
 	case cmdStatus:
 		if err := runStatus(os.Args[2:]); err != nil {
 			log.Fatalf("status: %v", err)
@@ -151,17 +177,25 @@ func main() {
 		if err := runLogs(os.Args[2:]); err != nil {
 			log.Fatalf("logs: %v", err)
 		}
+	case cmdExec:
+		if err := runExec(os.Args[2:]); err != nil {
+			// the client's own exit code is kept, it already printed its error
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				os.Exit(exitErr.ExitCode())
+			}
+			log.Fatalf("exec: %v", err)
+		}
 	case cmdList:
 		if err := listActiveStacks(); err != nil {
 			log.Fatalf("list: %v", err)
 		}
 	case cmdPrune:
-		if err := pruneDeadStacks(); err != nil {
+		if err := runPrune(os.Args[2:]); err != nil {
 			log.Fatalf("prune: %v", err)
 		}
-		fmt.Println("Dead stacks pruned")
 	default:
-		fmt.Println("usage: sbdev <start|stop|status|logs|list|prune> [options]")
+		fmt.Println("usage: sbdev <start|stop|restart|status|logs|exec|list|prune> [options]")
 		os.Exit(1)
 	}
 }
@@ -244,28 +278,39 @@ func runStart(args []string) error {
 		clientPortStart = defaultClientPortStart
 	}
 
-	minioMode := "local"
-	minioBin, err := ensureMinioBinary(binDir)
-	if err != nil {
-		if opts.useDockerMinio || dockerAvailable() {
-			minioMode = "docker"
-			fmt.Printf("MinIO binary unavailable (%v), falling back to Docker\n", err)
-		} else {
-			return fmt.Errorf("minio binary missing and docker not requested: %w", err)
+	var mState minioState
+	if opts.blob.enabled() {
+		mState = opts.blob.state()
+		if err := checkExternalBucket(mState); err != nil {
+			return fmt.Errorf("external blob storage: %w", err)
+		}
+	} else {
+		minioMode := "local"
+		minioBin, err := ensureMinioBinary(binDir, opts.minioVersion)
+		if err != nil {
+			if opts.useDockerMinio || dockerAvailable() {
+				minioMode = "docker"
+				fmt.Printf("MinIO binary unavailable (%v), falling back to Docker\n", err)
+			} else {
+				return fmt.Errorf("minio binary missing and docker not requested: %w", err)
+			}
 		}
-	}
 
-	mState, err := startMinio(minioMode, minioBin, relayRoot, minioAPIPort, minioConsolePort, opts.keepData)
-	if err != nil {
-		return fmt.Errorf("start minio: %w", err)
+		mState, err = startMinio(minioMode, minioBin, relayRoot, minioAPIPort, minioConsolePort, opts.keepData, opts.minioVersion)
+		if err != nil {
+			return fmt.Errorf("start minio: %w", err)
+		}
+		mState.AccessKey, mState.SecretKey = opts.accessKey, opts.secretKey
+
+		if err := setupBucket(mState, relayRoot); err != nil {
+			stopMinio(mState) // best effort cleanup
+			return fmt.Errorf("minio bootstrap: %w", err)
+		}
 	}
 
 	serverURL := fmt.Sprintf("http://127.0.0.1:%d", serverPort)
-	if err := setupBucket(mState.APIPort); err != nil {
-		return fmt.Errorf("minio bootstrap: %w", err)
-	}
 
-	sState, err := startServer(serverBin, relayRoot, serverPort, mState.APIPort)
+	sState, err := startServer(serverBin, relayRoot, serverPort, mState)
 	if err != nil {
 		stopMinio(mState) // best effort cleanup
 		return fmt.Errorf("start server: %w", err)
@@ -308,14 +353,17 @@ func runStart(args []string) error {
 
 	fmt.Printf("Devstack started in %s\n", opts.root)
 	fmt.Printf("  Server: %s (pid %d)\n", serverURL, sState.PID)
-	fmt.Printf("  MinIO:  http://127.0.0.1:%d (console http://127.0.0.1:%d)\n", mState.APIPort, mState.ConsolePort)
+	printMinio(mState)
+	if mState.AccessKey != "" && mState.Mode != "external" {
+		fmt.Printf("          server uses service account %s, scoped to %s\n", mState.AccessKey, defaultBucket)
+	}
 	for _, c := range clients {
 		fmt.Printf("  Client: %s (daemon http://127.0.0.1:%d pid %d)\n", c.Email, c.Port, c.PID)
 	}
 	fmt.Printf("State: %s\n", statePath)
 
 	if !opts.skipSyncCheck {
-		if err := runSyncCheck(opts.root, opts.clients); err != nil {
+		if err := runSyncCheck(opts.root, opts.clients, opts.syncTimeout); err != nil {
 			fmt.Printf("Sync check warning (continuing): %v\n", err)
 		}
 	}
@@ -329,6 +377,8 @@ func parseStartFlags(args []string) (startOptions, error) {
 		clientPortStart: defaultClientPortStart,
 		minioAPIPort:    defaultMinioAPIPort,
 		minioConsole:    defaultMinioConsolePort,
+		syncTimeout:     defaultSyncCheckTimeout,
+		minioVersion:    os.Getenv(minioVersionEnv),
 	}
 
 	for i := 0; i < len(args); i++ {
@@ -355,10 +405,41 @@ func parseStartFlags(args []string) (startOptions, error) {
 			opts.minioConsole = atoi(args[i])
 		case "--docker-minio":
 			opts.useDockerMinio = true
+		case "--minio-version":
+			i++
+			opts.minioVersion = args[i]
+		case "--access-key":
+			i++
+			opts.accessKey = args[i]
+		case "--secret-key":
+			i++
+			opts.secretKey = args[i]
+		case "--blob-endpoint":
+			i++
+			opts.blob.endpoint = args[i]
+		case "--blob-bucket":
+			i++
+			opts.blob.bucket = args[i]
+		case "--blob-region":
+			i++
+			opts.blob.region = args[i]
+		case "--blob-access-key":
+			i++
+			opts.blob.accessKey = args[i]
+		case "--blob-secret-key":
+			i++
+			opts.blob.secretKey = args[i]
 		case "--keep-data":
 			opts.keepData = true
 		case "--skip-sync-check":
 			opts.skipSyncCheck = true
+		case "--sync-check-timeout":
+			i++
+			timeout, err := parseSyncTimeout(args[i])
+			if err != nil {
+				return opts, err
+			}
+			opts.syncTimeout = timeout
 		case "--reset":
 			opts.reset = true
 		default:
@@ -366,6 +447,33 @@ func parseStartFlags(args []string) (startOptions, error) {
 		}
 	}
 
+	if err := validateMinioKeys(opts.accessKey, opts.secretKey); err != nil {
+		return opts, err
+	}
+
+	if err := opts.blob.validate(); err != nil {
+		return opts, err
+	}
+	if opts.blob.enabled() {
+		// these only apply to the MinIO started by sbdev
+		switch {
+		case opts.useDockerMinio:
+			return opts, fmt.Errorf("--docker-minio can't be used with --blob-endpoint")
+		case opts.minioVersion != "":
+			return opts, fmt.Errorf("--minio-version can't be used with --blob-endpoint")
+		case opts.accessKey != "":
+			return opts, fmt.Errorf("--access-key can't be used with --blob-endpoint, use --blob-access-key")
+		}
+	}
+
+	if opts.minioVersion != "" {
+		version, err := normalizeMinioVersion(opts.minioVersion)
+		if err != nil {
+			return opts, err
+		}
+		opts.minioVersion = version
+	}
+
 	return opts, nil
 }
 
@@ -377,23 +485,43 @@ func buildBinary(outPath, pkg, tags string) error {
 	return cmd.Run()
 }
 
-func ensureMinioBinary(binDir string) (string, error) {
-	if path, err := exec.LookPath(minioBinaryName); err == nil {
+var minioVersionPattern = regexp.MustCompile(`^RELEASE\.\d{4}-\d{2}-\d{2}T\d{2}-\d{2}-\d{2}Z$`)
+
+// normalizeMinioVersion checks a MinIO release name, like RELEASE.2025-04-22T22-12-26Z.
+// The RELEASE. prefix is optional. It's used in a download URL and a docker image tag, so nothing else is accepted.
+func normalizeMinioVersion(version string) (string, error) {
+	version = strings.TrimSpace(version)
+	if !strings.HasPrefix(version, "RELEASE.") {
+		version = "RELEASE." + version
+	}
+	if !minioVersionPattern.MatchString(version) {
+		return "", fmt.Errorf("invalid minio version %q: want a release like RELEASE.2025-04-22T22-12-26Z", version)
+	}
+	return version, nil
+}
+
+// ensureMinioBinary finds or downloads the minio binary. A pinned version is cached under its own name
+// and never taken from PATH, since that could be any release.
+func ensureMinioBinary(binDir string, version string) (string, error) {
+	binName := minioBinaryName
+	if version != "" {
+		binName = minioBinaryName + "." + version
+	} else if path, err := exec.LookPath(minioBinaryName); err == nil {
 		return path, nil
 	}
 
 	// check global cache
-	cachePath := filepath.Join(os.Getenv("HOME"), cacheDirName, "bin", minioBinaryName)
+	cachePath := filepath.Join(os.Getenv("HOME"), cacheDirName, "bin", binName)
 	if _, err := os.Stat(cachePath); err == nil {
 		return cachePath, nil
 	}
 
-	target := filepath.Join(binDir, minioBinaryName)
+	target := filepath.Join(binDir, binName)
 	if _, err := os.Stat(target); err == nil {
 		return target, nil
 	}
 
-	if err := downloadMinio(target); err != nil {
+	if err := downloadMinio(target, version); err != nil {
 		return "", err
 	}
 	// also copy to cache for future runs
@@ -409,37 +537,54 @@ func dockerAvailable() bool {
 	return err == nil
 }
 
-func downloadMinio(dest string) error {
+// downloadMinio downloads the latest minio release, or the given version from the release archive
+func downloadMinio(dest string, version string) error {
+	platform, err := minioPlatform()
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("%s/%s/%s", minioDownloadBase, platform, minioBinaryName)
+	if version != "" {
+		url = fmt.Sprintf("%s/%s/archive/%s.%s", minioDownloadBase, platform, minioBinaryName, version)
+	}
+	if err := downloadBinary(url, dest); err != nil {
+		return fmt.Errorf("download minio: %w", err)
+	}
+	return nil
+}
+
+// minioPlatform returns the name of the current platform in the MinIO download URLs
+func minioPlatform() (string, error) {
 	osName := runtime.GOOS
 	arch := runtime.GOARCH
-	var platform string
 	switch osName {
 	case "darwin":
 		if arch == "arm64" {
-			platform = "darwin-arm64"
-		} else {
-			platform = "darwin-amd64"
+			return "darwin-arm64", nil
 		}
+		return "darwin-amd64", nil
 	case "linux":
 		if arch == "arm64" {
-			platform = "linux-arm64"
-		} else {
-			platform = "linux-amd64"
+			return "linux-arm64", nil
 		}
+		return "linux-amd64", nil
 	default:
-		return fmt.Errorf("unsupported platform for minio download: %s/%s", osName, arch)
+		return "", fmt.Errorf("unsupported platform for minio download: %s/%s", osName, arch)
 	}
+}
 
-	url := fmt.Sprintf("%s/%s/%s", minioDownloadBase, platform, minioBinaryName)
+// downloadBinary downloads an executable to dest
+func downloadBinary(url, dest string) error {
 	client := &http.Client{Timeout: 15 * time.Second}
 	resp, err := client.Get(url) //nolint:gosec
 	if err != nil {
-		return fmt.Errorf("download minio: %w", err)
+		return err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("download minio: unexpected status %s", resp.Status)
+		return fmt.Errorf("unexpected status %s", resp.Status)
 	}
 
 	tmp := dest + ".tmp"
@@ -483,9 +628,9 @@ func stopStack(root string) error {
 	return nil
 }
 
-func startMinio(mode, binPath, root string, apiPort, consolePort int, keepData bool) (minioState, error) {
+func startMinio(mode, binPath, root string, apiPort, consolePort int, keepData bool, version string) (minioState, error) {
 	if mode == "docker" {
-		return startMinioDocker(root, apiPort, consolePort)
+		return startMinioDocker(root, apiPort, consolePort, version)
 	}
 
 	dataDir := filepath.Join(root, "minio", "data")
@@ -533,10 +678,16 @@ func startMinio(mode, binPath, root string, apiPort, consolePort int, keepData b
 		DataPath:    dataDir,
 		LogPath:     logFile,
 		BinPath:     binPath,
+		Version:     version,
 	}, nil
 }
 
-func startMinioDocker(root string, apiPort, consolePort int) (minioState, error) {
+func startMinioDocker(root string, apiPort, consolePort int, version string) (minioState, error) {
+	image := minioDockerImage + ":" + defaultMinioDockerVersion
+	if version != "" {
+		image = minioDockerImage + ":" + version
+	}
+
 	dataDir := filepath.Join(root, "minio", "data")
 	logDir := filepath.Join(root, "minio", "logs")
 	if err := os.MkdirAll(dataDir, 0o755); err != nil {
@@ -555,7 +706,7 @@ func startMinioDocker(root string, apiPort, consolePort int) (minioState, error)
 		"-e", fmt.Sprintf("MINIO_ROOT_USER=%s", defaultMinioAdminUser),
 		"-e", fmt.Sprintf("MINIO_ROOT_PASSWORD=%s", defaultMinioAdminPassword),
 		"-v", fmt.Sprintf("%s:/data", dataDir),
-		"minio/minio:RELEASE.2025-04-22T22-12-26Z",
+		image,
 		"server", "/data", "--console-address", ":9001",
 	}
 	cmd := exec.Command("docker", args...)
@@ -587,6 +738,7 @@ func startMinioDocker(root string, apiPort, consolePort int) (minioState, error)
 		DataPath:    dataDir,
 		LogPath:     logFile,
 		LogPID:      logCmd.Process.Pid,
+		Version:     version,
 	}, nil
 }
 
@@ -603,40 +755,54 @@ func waitForMinio(apiPort int) error {
 	return fmt.Errorf("minio did not become healthy")
 }
 
-func setupBucket(apiPort int) error {
-	endpoint := fmt.Sprintf("http://127.0.0.1:%d", apiPort)
-	cfg, err := config.LoadDefaultConfig(context.Background(),
-		config.WithCredentialsProvider(
-			credentials.NewStaticCredentialsProvider(defaultMinioAdminUser, defaultMinioAdminPassword, ""),
-		),
-		config.WithRegion(defaultRegion),
-		config.WithLogger(logging.Nop{}),
-	)
+// setupBucket creates the devstack bucket, and the server's service account when keys are set.
+// Both are kept if they exist already.
+func setupBucket(mState minioState, relayRoot string) error {
+	client, err := newS3Client(mState.endpoint(), defaultMinioAdminUser, defaultMinioAdminPassword, defaultRegion)
 	if err != nil {
 		return err
 	}
 
-	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
-		o.BaseEndpoint = aws.String(endpoint)
-		o.UsePathStyle = true
-	})
-
 	_, err = client.CreateBucket(context.Background(), &s3.CreateBucketInput{
 		Bucket: aws.String(defaultBucket),
 	})
 	if err != nil && !isBucketExistsError(err) {
 		return err
 	}
+
+	if mState.AccessKey != "" {
+		if err := setupServiceAccount(mState, relayRoot); err != nil {
+			return fmt.Errorf("service account: %w", err)
+		}
+	}
 	return nil
 }
 
+func newS3Client(endpoint, accessKey, secretKey, region string) (*s3.Client, error) {
+	cfg, err := config.LoadDefaultConfig(context.Background(),
+		config.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(accessKey, secretKey, ""),
+		),
+		config.WithRegion(region),
+		config.WithLogger(logging.Nop{}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return s3.NewFromConfig(cfg, func(o *s3.Options) {
+		o.BaseEndpoint = aws.String(endpoint)
+		o.UsePathStyle = true
+	}), nil
+}
+
 func isBucketExistsError(err error) bool {
 	var owned *s3types.BucketAlreadyOwnedByYou
 	var exists *s3types.BucketAlreadyExists
 	return errors.As(err, &owned) || errors.As(err, &exists)
 }
 
-func startServer(binPath, relayRoot string, port, minioPort int) (serverState, error) {
+func startServer(binPath, relayRoot string, port int, mState minioState) (serverState, error) {
 	serverDir := filepath.Join(relayRoot, "server")
 	logDir := filepath.Join(serverDir, "logs")
 	dataDir := filepath.Join(serverDir, "data")
@@ -647,7 +813,7 @@ func startServer(binPath, relayRoot string, port, minioPort int) (serverState, e
 		return serverState{}, err
 	}
 	configPath := filepath.Join(serverDir, "config.yaml")
-	if err := writeServerConfig(configPath, port, minioPort, dataDir, logDir); err != nil {
+	if err := writeServerConfig(configPath, port, mState, dataDir, logDir); err != nil {
 		return serverState{}, err
 	}
 
@@ -677,6 +843,10 @@ func startServer(binPath, relayRoot string, port, minioPort int) (serverState, e
 }
 
 func stopMinio(ms minioState) {
+	// an external endpoint isn't ours to stop
+	if ms.Mode == "external" {
+		return
+	}
 	if ms.Mode == "docker" && ms.ContainerID != "" {
 		_ = exec.Command("docker", "rm", "-f", ms.ContainerID).Run()
 		if ms.LogPID > 0 {
@@ -689,17 +859,20 @@ func stopMinio(ms minioState) {
 	}
 }
 
-func writeServerConfig(path string, port, minioPort int, dataDir, logDir string) error {
+func writeServerConfig(path string, port int, mState minioState, dataDir, logDir string) error {
+	accessKey, secretKey := mState.serverCredentials()
+	// objects aren't encrypted at rest. To test it, the blob section would need "sse" (AES256 or aws:kms)
+	// and "sse_kms_key_id" for a kms key, and minio a KMS to encrypt with (MINIO_KMS_SECRET_KEY).
 	cfg := map[string]any{
 		"http": map[string]any{
 			"addr": fmt.Sprintf("127.0.0.1:%d", port),
 		},
 		"blob": map[string]any{
-			"bucket_name": defaultBucket,
-			"region":      defaultRegion,
-			"endpoint":    fmt.Sprintf("http://127.0.0.1:%d", minioPort),
-			"access_key":  defaultMinioAdminUser,
-			"secret_key":  defaultMinioAdminPassword,
+			"bucket_name": mState.bucket(),
+			"region":      mState.region(),
+			"endpoint":    mState.endpoint(),
+			"access_key":  accessKey,
+			"secret_key":  secretKey,
 		},
 		"auth": map[string]any{
 			"enabled": false,
@@ -791,78 +964,11 @@ func startClient(binPath, root, email, serverURL string, port int) (clientState,
 	}, nil
 }
 
-func runSyncCheck(root string, emails []string) error {
-	if len(emails) <= 1 {
-		return nil
-	}
-
-	src := emails[0]
-	filename := fmt.Sprintf("devstack-ready-%d.txt", time.Now().UnixNano())
-	content := fmt.Sprintf("devstack ready %s", time.Now().Format(time.RFC3339Nano))
-
-	publicDir := publicPath(root, src)
-	if err := os.MkdirAll(publicDir, 0o755); err != nil {
-		return fmt.Errorf("ensure source public dir: %w", err)
-	}
-	if err := waitForDir(publicDir, 15*time.Second); err != nil {
-		fmt.Printf("Sync probe note: source public dir not ready (%s): %v\n", publicDir, err)
-		return nil
-	}
-
-	filePath := filepath.Join(publicDir, filename)
-	if err := os.WriteFile(filePath, []byte(content), 0o644); err != nil {
-		fmt.Printf("Sync probe note: write failed: %v\n", err)
-		return nil
-	}
-
-	// Touch the file via the server to trigger notifications
-	if err := waitForServerReady(root, 15*time.Second); err != nil {
-		return fmt.Errorf("wait for server: %w", err)
-	}
-	time.Sleep(500 * time.Millisecond)
-	if err := triggerDownloadForAll(root, emails, filename); err != nil {
-		fmt.Printf("Sync probe trigger note: %v (continuing)\n", err)
-	}
-
-	for _, email := range emails[1:] {
-		// Each client syncs alice's public dir to their local datasites/alice@example.com/public/
-		targetDir := filepath.Join(root, email, "datasites", src, "public")
-		_ = os.MkdirAll(targetDir, 0o755) // best-effort
-		target := filepath.Join(targetDir, filename)
-		if err := waitForFile(target, content, 45*time.Second); err != nil {
-			fmt.Printf("Sync probe note: %s did not see probe yet (%v)\n", email, err)
-			return nil
-		}
-	}
-
-	fmt.Printf("Sync check passed (%s replicated to %d clients)\n", filename, len(emails)-1)
-	return nil
-}
-
 func publicPath(root, email string) string {
 	// Workspace root is <root>/<email>; actual public dir lives under datasites/<user>/public
 	return filepath.Join(root, email, "datasites", email, "public")
 }
 
-// triggerDownloadForAll asks the server to serve the probe for each user to ensure their daemon pulls it.
-func triggerDownloadForAll(root string, emails []string, filename string) error {
-	state, _, err := readState(root)
-	if err != nil {
-		return err
-	}
-	serverURL := fmt.Sprintf("http://127.0.0.1:%d", state.Server.Port)
-	client := &http.Client{Timeout: 3 * time.Second}
-	src := emails[0] // probe file created by first client
-	for _, email := range emails {
-		payload := fmt.Sprintf(`{"keys":["%s/public/%s"]}`, src, filename)
-		url := fmt.Sprintf("%s/api/v1/blob/download?user=%s", serverURL, email)
-		if err := postWithRetry(client, url, payload, 30, 500*time.Millisecond); err != nil {
-			return fmt.Errorf("trigger for %s: %w", email, err)
-		}
-	}
-	return nil
-}
-
 func waitForDir(path string, timeout time.Duration) error {
 	deadline := time.Now().Add(timeout)
 	for time.Now().Before(deadline) {
@@ -911,12 +1017,31 @@ func runStop(args []string) error {
 	return stopStack(root)
 }
 
-func runStatus(args []string) error {
+// runRestart stops the server and clients of a running stack, rebuilds the binaries
+// and starts them again with the same ports and emails. MinIO is left running.
+func runRestart(args []string) error {
 	root := defaultRoot
+	keepData := false
+	skipSyncCheck := false
+	syncTimeout := defaultSyncCheckTimeout
 	for i := 0; i < len(args); i++ {
-		if args[i] == "--path" {
+		switch args[i] {
+		case "--path":
 			i++
 			root = args[i]
+		case "--keep-data":
+			keepData = true
+		case "--skip-sync-check":
+			skipSyncCheck = true
+		case "--sync-check-timeout":
+			i++
+			timeout, err := parseSyncTimeout(args[i])
+			if err != nil {
+				return err
+			}
+			syncTimeout = timeout
+		default:
+			return fmt.Errorf("unknown flag %s", args[i])
 		}
 	}
 	var err error
@@ -924,26 +1049,133 @@ func runStatus(args []string) error {
 	if err != nil {
 		return fmt.Errorf("resolve root: %w", err)
 	}
+
 	state, _, err := readState(root)
 	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("no stack state found for %s – start it first", root)
+		}
 		return err
 	}
 
-	fmt.Printf("Stack at %s (created %s)\n", state.Root, state.Created.Format(time.RFC3339))
-	fmt.Printf("  Server: pid %d port %d log %s\n", state.Server.PID, state.Server.Port, state.Server.LogPath)
-	fmt.Printf("  MinIO:  %s api %d console %d log %s\n", state.Minio.Mode, state.Minio.APIPort, state.Minio.ConsolePort, state.Minio.LogPath)
 	for _, c := range state.Clients {
-		fmt.Printf("  Client: %s pid %d port %d log %s\n", c.Email, c.PID, c.Port, c.LogPath)
+		_ = killProcess(c.PID)
+	}
+	if state.Server.PID > 0 {
+		_ = killProcess(state.Server.PID)
+	}
+
+	relayRoot := filepath.Join(state.Root, relayDir)
+	binDir := filepath.Join(relayRoot, "bin")
+	serverBin := filepath.Join(binDir, "server")
+	clientBin := filepath.Join(binDir, "syftbox")
+
+	if err := buildBinary(serverBin, "./cmd/server", serverBuildTags); err != nil {
+		return fmt.Errorf("build server: %w", err)
+	}
+	if err := buildBinary(clientBin, "./cmd/client", clientBuildTags); err != nil {
+		return fmt.Errorf("build client: %w", err)
+	}
+
+	// MinIO is expected to still be up. A local one that died is started again on the same ports.
+	mState := state.Minio
+	if mState.Mode == "external" {
+		if err := checkExternalBucket(mState); err != nil {
+			return fmt.Errorf("external blob storage: %w", err)
+		}
+	} else {
+		if mState.Mode != "docker" && !processExists(mState.PID) {
+			fmt.Println("MinIO is not running, starting it again")
+			mState, err = startMinio(mState.Mode, mState.BinPath, relayRoot, mState.APIPort, mState.ConsolePort, keepData, mState.Version)
+			if err != nil {
+				return fmt.Errorf("start minio: %w", err)
+			}
+			mState.AccessKey, mState.SecretKey = state.Minio.AccessKey, state.Minio.SecretKey
+		}
+		// idempotent, the existing bucket and service account are reused
+		if err := setupBucket(mState, relayRoot); err != nil {
+			return fmt.Errorf("minio bootstrap: %w", err)
+		}
+	}
+
+	sState, err := startServer(serverBin, relayRoot, state.Server.Port, mState)
+	if err != nil {
+		return fmt.Errorf("start server: %w", err)
+	}
+
+	serverURL := fmt.Sprintf("http://127.0.0.1:%d", sState.Port)
+	clients := make([]clientState, 0, len(state.Clients))
+	emails := make([]string, 0, len(state.Clients))
+	for _, c := range state.Clients {
+		cState, err := startClient(clientBin, state.Root, c.Email, serverURL, c.Port)
+		if err != nil {
+			return fmt.Errorf("start client %s: %w", c.Email, err)
+		}
+		clients = append(clients, cState)
+		emails = append(emails, c.Email)
+	}
+
+	state.Server = sState
+	state.Minio = mState
+	state.Clients = clients
+
+	if err := saveGlobalState(state.Root, state); err != nil {
+		return fmt.Errorf("save global state: %w", err)
+	}
+	if err := writeState(filepath.Join(relayRoot, stateFileName), state); err != nil {
+		log.Printf("Warning: failed to write local state: %v", err)
+	}
+
+	fmt.Printf("Devstack restarted in %s\n", state.Root)
+	fmt.Printf("  Server: %s (pid %d)\n", serverURL, sState.PID)
+	printMinio(mState)
+	for _, c := range clients {
+		fmt.Printf("  Client: %s (daemon http://127.0.0.1:%d pid %d)\n", c.Email, c.Port, c.PID)
+	}
+
+	if !skipSyncCheck {
+		if err := runSyncCheck(state.Root, emails, syncTimeout); err != nil {
+			fmt.Printf("Sync check warning (continuing): %v\n", err)
+		}
 	}
 	return nil
 }
 
-func runLogs(args []string) error {
+const (
+	healthHealthy     = "healthy"
+	healthUnreachable = "unreachable"
+	healthDead        = "dead"
+	healthTimeout     = 2 * time.Second
+)
+
+// componentStatus is the status of a stack process, as reported by sbdev status
+type componentStatus struct {
+	Name    string `json:"name"`
+	PID     int    `json:"pid,omitempty"`
+	Port    int    `json:"port,omitempty"`
+	URL     string `json:"url"`
+	LogPath string `json:"log_path"`
+	Health  string `json:"health"`
+}
+
+type stackStatus struct {
+	Root    string            `json:"root"`
+	Created time.Time         `json:"created"`
+	Server  componentStatus   `json:"server"`
+	Minio   componentStatus   `json:"minio"`
+	Clients []componentStatus `json:"clients"`
+}
+
+func runStatus(args []string) error {
 	root := defaultRoot
+	asJSON := false
 	for i := 0; i < len(args); i++ {
-		if args[i] == "--path" {
+		switch args[i] {
+		case "--path":
 			i++
 			root = args[i]
+		case "--json":
+			asJSON = true
 		}
 	}
 	var err error
@@ -955,11 +1187,153 @@ func runLogs(args []string) error {
 	if err != nil {
 		return err
 	}
+
+	status := checkStackHealth(state)
+
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(status)
+	}
+
+	fmt.Printf("Stack at %s (created %s)\n", status.Root, status.Created.Format(time.RFC3339))
+	if state.Minio.Mode == "external" {
+		fmt.Printf("  Blob storage: %s (bucket %s, external)\n", state.Minio.endpoint(), state.Minio.bucket())
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  COMPONENT\tPID\tPORT\tHEALTH\tLOG")
+	for _, c := range append([]componentStatus{status.Server, status.Minio}, status.Clients...) {
+		pid, port := "-", "-"
+		if c.PID > 0 {
+			pid = strconv.Itoa(c.PID)
+		}
+		// a dead process doesn't hold its port anymore
+		if c.Health != healthDead && c.Port > 0 {
+			port = strconv.Itoa(c.Port)
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\n", c.Name, pid, port, c.Health, c.LogPath)
+	}
+	return tw.Flush()
+}
+
+// checkStackHealth probes the server, minio and clients of a stack concurrently
+func checkStackHealth(state *stackState) *stackStatus {
+	status := &stackStatus{
+		Root:    state.Root,
+		Created: state.Created,
+		Server: componentStatus{
+			Name:    "server",
+			PID:     state.Server.PID,
+			Port:    state.Server.Port,
+			URL:     fmt.Sprintf("http://127.0.0.1:%d/healthz", state.Server.Port),
+			LogPath: state.Server.LogPath,
+		},
+		Minio: componentStatus{
+			Name:    fmt.Sprintf("minio (%s)", state.Minio.Mode),
+			PID:     state.Minio.PID,
+			Port:    state.Minio.APIPort,
+			URL:     state.Minio.endpoint() + "/minio/health/live",
+			LogPath: state.Minio.LogPath,
+		},
+		Clients: make([]componentStatus, len(state.Clients)),
+	}
+	for i, c := range state.Clients {
+		status.Clients[i] = componentStatus{
+			Name:    c.Email,
+			PID:     c.PID,
+			Port:    c.Port,
+			URL:     fmt.Sprintf("http://127.0.0.1:%d/", c.Port),
+			LogPath: c.LogPath,
+		}
+	}
+
+	var wg sync.WaitGroup
+	check := func(c *componentStatus) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Health = componentHealth(c.PID, c.URL)
+		}()
+	}
+	check(&status.Server)
+	if state.Minio.Mode == "external" {
+		// plain S3 has no health endpoint, the bucket is probed instead
+		status.Minio.Name = "blob (external)"
+		status.Minio.URL = state.Minio.endpoint()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			status.Minio.Health = healthHealthy
+			if err := checkExternalBucket(state.Minio); err != nil {
+				status.Minio.Health = healthUnreachable
+			}
+		}()
+	} else {
+		check(&status.Minio)
+	}
+	for i := range status.Clients {
+		check(&status.Clients[i])
+	}
+	wg.Wait()
+
+	return status
+}
+
+// componentHealth reports a process as dead if it's gone, otherwise whether its url answers.
+// Processes without a pid (e.g. minio in docker) are only probed.
+func componentHealth(pid int, url string) string {
+	if pid > 0 && !processExists(pid) {
+		return healthDead
+	}
+	if err := getWithRetry(url, healthTimeout); err != nil {
+		return healthUnreachable
+	}
+	return healthHealthy
+}
+
+func runLogs(args []string) error {
+	root := defaultRoot
+	follow := false
+	var since time.Duration
+	var err error
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--path":
+			i++
+			root = args[i]
+		case "--follow", "-f":
+			follow = true
+		case "--since":
+			i++
+			if i >= len(args) {
+				return fmt.Errorf("--since requires a duration")
+			}
+			if since, err = time.ParseDuration(args[i]); err != nil || since <= 0 {
+				return fmt.Errorf("invalid --since %q: expected a positive duration like 10m", args[i])
+			}
+		}
+	}
+	root, err = filepath.Abs(root)
+	if err != nil {
+		return fmt.Errorf("resolve root: %w", err)
+	}
+	state, _, err := readState(root)
+	if err != nil {
+		return err
+	}
+
+	if follow || since > 0 {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		return streamLogs(ctx, os.Stdout, stackLogSources(state), since, follow)
+	}
+
 	fmt.Println("Log locations:")
 	fmt.Printf("  Server: %s\n", state.Server.LogPath)
 	fmt.Printf("  MinIO:  %s\n", state.Minio.LogPath)
 	for _, c := range state.Clients {
-		fmt.Printf("  Client %s: %s and %s\n", c.Email, c.LogPath, filepath.Join(c.HomePath, ".syftbox", "logs", "syftbox.log"))
+		paths := append([]string{c.LogPath}, clientLogFiles(c.HomePath)...)
+		fmt.Printf("  Client %s: %s\n", c.Email, strings.Join(paths, ", "))
 	}
 	return nil
 }
@@ -1063,20 +1437,69 @@ func saveGlobalState(root string, state *stackState) error {
 	return writeState(globalPath, state)
 }
 
+type pruneOptions struct {
+	dryRun    bool
+	olderThan time.Duration // only prune stacks created longer ago than this, 0 for any age
+}
+
+type pruneSummary struct {
+	pruned  int
+	skipped int
+}
+
+func runPrune(args []string) error {
+	var opts pruneOptions
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--dry-run":
+			opts.dryRun = true
+		case "--older-than":
+			i++
+			if i >= len(args) {
+				return fmt.Errorf("--older-than requires a duration")
+			}
+			d, err := time.ParseDuration(args[i])
+			if err != nil || d < 0 {
+				return fmt.Errorf("invalid --older-than %q: want a duration like 2h", args[i])
+			}
+			opts.olderThan = d
+		default:
+			return fmt.Errorf("unknown flag %s", args[i])
+		}
+	}
+
+	summary, err := pruneDeadStacks(opts)
+	if err != nil {
+		return err
+	}
+	verb := "Pruned"
+	if opts.dryRun {
+		verb = "Would prune"
+	}
+	fmt.Printf("%s %d dead stack(s), skipped %d\n", verb, summary.pruned, summary.skipped)
+	return nil
+}
+
 // pruneDeadStacks removes stacks with dead processes
-func pruneDeadStacks() error {
+func pruneDeadStacks(opts pruneOptions) (pruneSummary, error) {
 	globalDir, err := getGlobalStateDir()
 	if err != nil {
-		return err
+		return pruneSummary{}, err
 	}
+	return pruneStacksIn(filepath.Join(globalDir, "stacks"), opts, time.Now())
+}
+
+// pruneStacksIn prunes the stacks tracked under stacksDir. A stack is skipped when any of its
+// processes is alive, or when it was created less than opts.olderThan before now.
+func pruneStacksIn(stacksDir string, opts pruneOptions, now time.Time) (pruneSummary, error) {
+	var summary pruneSummary
 
-	stacksDir := filepath.Join(globalDir, "stacks")
 	entries, err := os.ReadDir(stacksDir)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return nil
+			return summary, nil
 		}
-		return err
+		return summary, err
 	}
 
 	for _, entry := range entries {
@@ -1098,31 +1521,49 @@ func pruneDeadStacks() error {
 			continue
 		}
 
-		// Check if any processes are alive
-		allDead := true
-		if processExists(state.Server.PID) {
-			allDead = false
+		if stackAlive(&state) {
+			summary.skipped++
+			continue
 		}
-		if state.Minio.PID > 0 && processExists(state.Minio.PID) {
-			allDead = false
+
+		if age := now.Sub(state.Created); opts.olderThan > 0 && age < opts.olderThan {
+			log.Printf("Skipping dead stack: %s (from %s), created %s ago", entry.Name(), state.Root, age.Round(time.Second))
+			summary.skipped++
+			continue
 		}
-		for _, client := range state.Clients {
-			if processExists(client.PID) {
-				allDead = false
-				break
-			}
+
+		if opts.dryRun {
+			log.Printf("Would prune dead stack: %s (from %s)", entry.Name(), state.Root)
+			summary.pruned++
+			continue
 		}
 
-		// Remove if all processes are dead
-		if allDead {
-			log.Printf("Pruning dead stack: %s (from %s)", entry.Name(), state.Root)
-			if err := os.RemoveAll(stackDir); err != nil {
-				log.Printf("Failed to remove %s: %v", stackDir, err)
-			}
+		log.Printf("Pruning dead stack: %s (from %s)", entry.Name(), state.Root)
+		if err := os.RemoveAll(stackDir); err != nil {
+			log.Printf("Failed to remove %s: %v", stackDir, err)
+			summary.skipped++
+			continue
 		}
+		summary.pruned++
 	}
 
-	return nil
+	return summary, nil
+}
+
+// stackAlive reports whether any process of the stack is still running
+func stackAlive(state *stackState) bool {
+	if processExists(state.Server.PID) {
+		return true
+	}
+	if state.Minio.PID > 0 && processExists(state.Minio.PID) {
+		return true
+	}
+	for _, client := range state.Clients {
+		if processExists(client.PID) {
+			return true
+		}
+	}
+	return false
 }
 
 // listActiveStacks shows all tracked stacks
@@ -1173,7 +1614,7 @@ func listActiveStacks() error {
 
 		// Check process status
 		serverAlive := processExists(state.Server.PID)
-		minioAlive := state.Minio.PID > 0 && processExists(state.Minio.PID)
+		minioAlive := state.Minio.Mode == "external" || (state.Minio.PID > 0 && processExists(state.Minio.PID))
 		clientsAlive := 0
 		for _, client := range state.Clients {
 			if processExists(client.PID) {
@@ -1189,7 +1630,11 @@ func listActiveStacks() error {
 		fmt.Printf("%s %s\n", status, stackPath)
 		fmt.Printf("   ID: %s\n", entry.Name())
 		fmt.Printf("   Server: %d (port %d) - alive: %v\n", state.Server.PID, state.Server.Port, serverAlive)
-		fmt.Printf("   MinIO: %d (port %d) - alive: %v\n", state.Minio.PID, state.Minio.APIPort, minioAlive)
+		if state.Minio.Mode == "external" {
+			fmt.Printf("   Blob storage: %s (external)\n", state.Minio.endpoint())
+		} else {
+			fmt.Printf("   MinIO: %d (port %d) - alive: %v\n", state.Minio.PID, state.Minio.APIPort, minioAlive)
+		}
 		fmt.Printf("   Clients: %d alive / %d total\n", clientsAlive, len(state.Clients))
 		fmt.Println()
 	}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComponentHealth(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	down := httptest.NewServer(http.NotFoundHandler())
+	downURL := down.URL
+	down.Close()
+
+	assert.Equal(t, healthHealthy, componentHealth(os.Getpid(), srv.URL))
+	assert.Equal(t, healthHealthy, componentHealth(0, srv.URL), "no pid, e.g. docker")
+	assert.Equal(t, healthUnreachable, componentHealth(os.Getpid(), downURL))
+
+	if runtime.GOOS == "windows" {
+		t.Skip("processExists relies on signal 0")
+	}
+	exited := exec.Command("true")
+	require.NoError(t, exited.Run())
+	assert.Equal(t, healthDead, componentHealth(exited.Process.Pid, srv.URL))
+}
+
+func TestNormalizeMinioVersion(t *testing.T) {
+	for _, value := range []string{"RELEASE.2025-04-22T22-12-26Z", "2025-04-22T22-12-26Z", " RELEASE.2025-04-22T22-12-26Z\n"} {
+		version, err := normalizeMinioVersion(value)
+		require.NoError(t, err, value)
+		assert.Equal(t, "RELEASE.2025-04-22T22-12-26Z", version)
+	}
+
+	for _, value := range []string{
+		"latest",
+		"RELEASE.2025-04-22",
+		"RELEASE.2025-04-22T22-12-26Z/../../minio",
+		"RELEASE.2025-04-22T22-12-26Z --privileged",
+	} {
+		_, err := normalizeMinioVersion(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestValidateMinioKeys(t *testing.T) {
+	assert.NoError(t, validateMinioKeys("", ""))
+	assert.NoError(t, validateMinioKeys(defaultAccessKey, defaultSecretKey))
+
+	for _, keys := range [][2]string{
+		{defaultAccessKey, ""},
+		{"", defaultSecretKey},
+		{defaultMinioAdminUser, defaultSecretKey},
+		{"ab", defaultSecretKey},
+		{"syftbox server", defaultSecretKey},
+		{defaultAccessKey, "short"},
+	} {
+		assert.Error(t, validateMinioKeys(keys[0], keys[1]), keys)
+	}
+}
+
+func TestWriteServerConfigCredentials(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+
+	require.NoError(t, writeServerConfig(path, 8080, minioState{APIPort: 9000}, "data", "logs"))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "access_key: "+defaultMinioAdminUser)
+
+	scoped := minioState{APIPort: 9000, AccessKey: defaultAccessKey, SecretKey: defaultSecretKey}
+	require.NoError(t, writeServerConfig(path, 8080, scoped, "data", "logs"))
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "access_key: "+defaultAccessKey)
+	assert.Contains(t, string(data), "secret_key: "+defaultSecretKey)
+	assert.NotContains(t, string(data), defaultMinioAdminPassword)
+}
+
+func TestPruneStacksIn(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	stacksDir := t.TempDir()
+
+	writeStack := func(name string, created time.Time, serverPID int) string {
+		t.Helper()
+		dir := filepath.Join(stacksDir, name)
+		require.NoError(t, os.MkdirAll(dir, 0o755))
+		require.NoError(t, writeState(filepath.Join(dir, stateFileName), &stackState{
+			Root:    "/tmp/" + name,
+			Server:  serverState{PID: serverPID},
+			Created: created,
+		}))
+		return dir
+	}
+
+	old := writeStack("old", now.Add(-3*time.Hour), 0)
+	recent := writeStack("recent", now.Add(-time.Minute), 0)
+	alive := writeStack("alive", now.Add(-3*time.Hour), os.Getpid())
+
+	// dry run keeps everything
+	summary, err := pruneStacksIn(stacksDir, pruneOptions{dryRun: true, olderThan: time.Hour}, now)
+	require.NoError(t, err)
+	assert.Equal(t, pruneSummary{pruned: 1, skipped: 2}, summary)
+	assert.DirExists(t, old)
+
+	summary, err = pruneStacksIn(stacksDir, pruneOptions{olderThan: time.Hour}, now)
+	require.NoError(t, err)
+	assert.Equal(t, pruneSummary{pruned: 1, skipped: 2}, summary)
+	assert.NoDirExists(t, old)
+	assert.DirExists(t, recent)
+	assert.DirExists(t, alive)
+
+	// without an age filter, any dead stack goes
+	summary, err = pruneStacksIn(stacksDir, pruneOptions{}, now)
+	require.NoError(t, err)
+	assert.Equal(t, pruneSummary{pruned: 1, skipped: 1}, summary)
+	assert.NoDirExists(t, recent)
+	assert.DirExists(t, alive)
+}
+
+func TestParseStartFlagsExternalBlob(t *testing.T) {
+	blobFlags := []string{
+		"--client", "alice@example.com",
+		"--blob-endpoint", "https://s3.eu-west-1.amazonaws.com/",
+		"--blob-bucket", "syftbox-test",
+		"--blob-region", "eu-west-1",
+		"--blob-access-key", "AKIAEXAMPLE",
+		"--blob-secret-key", "secret",
+	}
+	opts, err := parseStartFlags(blobFlags)
+	require.NoError(t, err)
+	require.True(t, opts.blob.enabled())
+
+	mState := opts.blob.state()
+	assert.Equal(t, "external", mState.Mode)
+	assert.Equal(t, "https://s3.eu-west-1.amazonaws.com", mState.endpoint())
+
+	for _, args := range [][]string{
+		{"--blob-bucket", "syftbox-test"},
+		{"--blob-endpoint", "s3.amazonaws.com", "--blob-bucket", "b", "--blob-access-key", "a", "--blob-secret-key", "s"},
+		{"--blob-endpoint", "https://s3.amazonaws.com", "--blob-access-key", "a", "--blob-secret-key", "s"},
+		{"--blob-endpoint", "https://s3.amazonaws.com", "--blob-bucket", "b", "--blob-access-key", "a"},
+		append([]string{"--docker-minio"}, blobFlags...),
+		append([]string{"--access-key", defaultAccessKey, "--secret-key", defaultSecretKey}, blobFlags...),
+	} {
+		_, err := parseStartFlags(args)
+		assert.Error(t, err, args)
+	}
+}
+
+func TestWriteServerConfigExternalBlob(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+
+	mState := externalBlob{
+		endpoint:  "https://minio.example.com",
+		bucket:    "shared",
+		accessKey: "AKIAEXAMPLE",
+		secretKey: "secret",
+	}.state()
+	require.NoError(t, writeServerConfig(path, 8080, mState, "data", "logs"))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "endpoint: https://minio.example.com")
+	assert.Contains(t, string(data), "bucket_name: shared")
+	assert.Contains(t, string(data), "region: "+defaultRegion)
+	assert.Contains(t, string(data), "access_key: AKIAEXAMPLE")
+	assert.NotContains(t, string(data), defaultMinioAdminPassword)
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	mcBinaryName   = "mc"
+	mcDownloadBase = "https://dl.min.io/client/mc/release"
+	// alias of the devstack MinIO for mc, passed through MC_HOST_<alias>
+	mcAlias = "sbdev"
+)
+
+var (
+	// MinIO accepts 3-20 characters for service account access keys and 8-40 for secret keys
+	minioAccessKeyPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{3,20}$`)
+	minioSecretKeyPattern = regexp.MustCompile(`^[A-Za-z0-9+/._=-]{8,40}$`)
+)
+
+// validateMinioKeys checks the keys of the server's service account. Both are set or neither.
+func validateMinioKeys(accessKey, secretKey string) error {
+	if accessKey == "" && secretKey == "" {
+		return nil
+	}
+	if accessKey == "" || secretKey == "" {
+		return fmt.Errorf("--access-key and --secret-key must be set together")
+	}
+	if accessKey == defaultMinioAdminUser {
+		return fmt.Errorf("--access-key must not be the MinIO root user")
+	}
+	if !minioAccessKeyPattern.MatchString(accessKey) {
+		return fmt.Errorf("invalid --access-key: want 3-20 letters, digits, '.', '_' or '-'")
+	}
+	if !minioSecretKeyPattern.MatchString(secretKey) {
+		return fmt.Errorf("invalid --secret-key: want 8-40 letters, digits or '+/._=-'")
+	}
+	return nil
+}
+
+// serverCredentials returns the keys the server uses for the bucket: the service account when one
+// is configured, the root credentials otherwise
+func (m minioState) serverCredentials() (accessKey, secretKey string) {
+	if m.AccessKey != "" {
+		return m.AccessKey, m.SecretKey
+	}
+	return defaultMinioAdminUser, defaultMinioAdminPassword
+}
+
+// bucketPolicy limits a service account to the devstack bucket
+func bucketPolicy(bucket string) ([]byte, error) {
+	return json.MarshalIndent(map[string]any{
+		"Version": "2012-10-17",
+		"Statement": []map[string]any{{
+			"Effect": "Allow",
+			"Action": []string{"s3:*"},
+			"Resource": []string{
+				"arn:aws:s3:::" + bucket,
+				"arn:aws:s3:::" + bucket + "/*",
+			},
+		}},
+	}, "", "  ")
+}
+
+// setupServiceAccount creates the service account the server uses instead of the root credentials,
+// scoped to the devstack bucket. With --keep-data it may exist from a previous run, then its
+// secret key and policy are updated instead.
+func setupServiceAccount(mState minioState, relayRoot string) error {
+	mc, err := newMinioClientCLI(mState, relayRoot)
+	if err != nil {
+		return err
+	}
+
+	policy, err := bucketPolicy(defaultBucket)
+	if err != nil {
+		return err
+	}
+	policyPath, err := mc.writeFile("sbdev-server-policy.json", policy)
+	if err != nil {
+		return fmt.Errorf("write policy: %w", err)
+	}
+
+	if _, err := mc.run("admin", "user", "svcacct", "info", mcAlias, mState.AccessKey); err == nil {
+		_, err = mc.run("admin", "user", "svcacct", "edit",
+			"--secret-key", mState.SecretKey,
+			"--policy", policyPath,
+			mcAlias, mState.AccessKey,
+		)
+		if err != nil {
+			return fmt.Errorf("update service account: %w", err)
+		}
+		return nil
+	}
+
+	_, err = mc.run("admin", "user", "svcacct", "add",
+		"--access-key", mState.AccessKey,
+		"--secret-key", mState.SecretKey,
+		"--policy", policyPath,
+		mcAlias, defaultMinioAdminUser,
+	)
+	if err != nil {
+		return fmt.Errorf("add service account: %w", err)
+	}
+	return nil
+}
+
+// minioClientCLI runs the MinIO client (mc) against the devstack MinIO: a local binary,
+// or the one bundled in the container for docker mode
+type minioClientCLI struct {
+	mState    minioState
+	binPath   string
+	configDir string
+}
+
+func newMinioClientCLI(mState minioState, relayRoot string) (*minioClientCLI, error) {
+	mc := &minioClientCLI{
+		mState: mState,
+		// keep mc's config out of the user's ~/.mc
+		configDir: filepath.Join(relayRoot, "minio", "mc"),
+	}
+	if mState.Mode == "docker" {
+		return mc, nil
+	}
+
+	binPath, err := ensureMcBinary(filepath.Join(relayRoot, "bin"))
+	if err != nil {
+		return nil, fmt.Errorf("mc binary unavailable: %w", err)
+	}
+	mc.binPath = binPath
+	return mc, nil
+}
+
+// writeFile writes a file for mc to read, and returns its path as seen by mc
+func (mc *minioClientCLI) writeFile(name string, data []byte) (string, error) {
+	if err := os.MkdirAll(mc.configDir, 0o755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(mc.configDir, name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return "", err
+	}
+	if mc.mState.Mode != "docker" {
+		return path, nil
+	}
+
+	target := "/tmp/" + name
+	if out, err := exec.Command("docker", "cp", path, mc.mState.ContainerID+":"+target).CombinedOutput(); err != nil {
+		return "", fmt.Errorf("docker cp: %w (%s)", err, strings.TrimSpace(string(out)))
+	}
+	return target, nil
+}
+
+func (mc *minioClientCLI) run(args ...string) ([]byte, error) {
+	var cmd *exec.Cmd
+	if mc.mState.Mode == "docker" {
+		host := fmt.Sprintf("MC_HOST_%s=http://%s:%s@127.0.0.1:9000", mcAlias, defaultMinioAdminUser, defaultMinioAdminPassword)
+		cmd = exec.Command("docker", append([]string{"exec", "-e", host, mc.mState.ContainerID, mcBinaryName}, args...)...)
+	} else {
+		host := fmt.Sprintf("MC_HOST_%s=http://%s:%s@127.0.0.1:%d", mcAlias, defaultMinioAdminUser, defaultMinioAdminPassword, mc.mState.APIPort)
+		cmd = exec.Command(mc.binPath, append([]string{"--config-dir", mc.configDir}, args...)...)
+		cmd.Env = append(os.Environ(), host)
+	}
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return out, fmt.Errorf("mc %s: %w (%s)", strings.Join(args[:min(len(args), 4)], " "), err, strings.TrimSpace(string(out)))
+	}
+	return out, nil
+}
+
+// ensureMcBinary finds or downloads the mc binary. It's never taken from PATH,
+// where mc is as likely to be Midnight Commander.
+func ensureMcBinary(binDir string) (string, error) {
+	cachePath := filepath.Join(os.Getenv("HOME"), cacheDirName, "bin", mcBinaryName)
+	if _, err := os.Stat(cachePath); err == nil {
+		return cachePath, nil
+	}
+
+	target := filepath.Join(binDir, mcBinaryName)
+	if _, err := os.Stat(target); err == nil {
+		return target, nil
+	}
+
+	platform, err := minioPlatform()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(binDir, 0o755); err != nil {
+		return "", err
+	}
+	if err := downloadBinary(fmt.Sprintf("%s/%s/%s", mcDownloadBase, platform, mcBinaryName), target); err != nil {
+		return "", fmt.Errorf("download mc: %w", err)
+	}
+	// also copy to cache for future runs
+	if err := os.MkdirAll(filepath.Dir(cachePath), 0o755); err == nil {
+		_ = copyFile(target, cachePath)
+		_ = os.Chmod(cachePath, 0o755)
+	}
+	return target, nil
+}
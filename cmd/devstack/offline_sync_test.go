@@ -0,0 +1,94 @@
+//go:build integration
+// +build integration
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestOfflineCatchUp takes a peer offline mid-run and checks that it catches up once back:
+// 1. Bob syncs a file from Alice, then goes offline
+// 2. Alice uploads new files and changes the synced one, Bob writes a file while offline
+// 3. Bob comes back online
+// 4. Both converge to every file, none is lost, only delayed by the downtime
+func TestOfflineCatchUp(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping offline catch-up test in short mode")
+	}
+
+	h := NewDevstackHarness(t)
+
+	const syncTimeout = 60 * time.Second
+	const offlineFiles = 5
+
+	t.Log("Step 1: Bob syncs a file while online")
+	baseline := GenerateRandomFile(1024)
+	if err := h.alice.UploadFile("offline/baseline.bin", baseline); err != nil {
+		t.Fatalf("upload baseline: %v", err)
+	}
+	if err := h.bob.WaitForFile(h.alice.email, "offline/baseline.bin", CalculateMD5(baseline), syncTimeout); err != nil {
+		t.Fatalf("bob sync baseline: %v", err)
+	}
+
+	t.Log("Step 2: Bob goes offline")
+	if err := h.StopClient(h.bob); err != nil {
+		t.Fatal(err)
+	}
+	offlineAt := time.Now()
+
+	// Alice keeps changing her datasite meanwhile
+	expected := map[string][]byte{}
+	for i := range offlineFiles {
+		name := fmt.Sprintf("offline/file-%d.bin", i)
+		content := GenerateRandomFile(4 * 1024)
+		if err := h.alice.UploadFile(name, content); err != nil {
+			t.Fatalf("upload %s: %v", name, err)
+		}
+		expected[name] = content
+	}
+	changed := GenerateRandomFile(2048)
+	if err := h.alice.UploadFile("offline/baseline.bin", changed); err != nil {
+		t.Fatalf("update baseline: %v", err)
+	}
+	expected["offline/baseline.bin"] = changed
+
+	// Bob's local change while offline is picked up on restart
+	bobContent := GenerateRandomFile(1024)
+	bobPath := filepath.Join(h.bob.publicDir, "offline", "while-offline.bin")
+	if err := os.MkdirAll(filepath.Dir(bobPath), 0o755); err != nil {
+		t.Fatalf("create dir: %v", err)
+	}
+	if err := os.WriteFile(bobPath, bobContent, 0o644); err != nil {
+		t.Fatalf("write offline file: %v", err)
+	}
+
+	// give Alice's uploads time to land on the server while Bob is away
+	time.Sleep(3 * time.Second)
+
+	t.Log("Step 3: Bob comes back online")
+	if err := h.StartClient(h.bob); err != nil {
+		t.Fatal(err)
+	}
+	onlineAt := time.Now()
+	downtime := onlineAt.Sub(offlineAt)
+
+	t.Log("Step 4: Both converge")
+	for name, content := range expected {
+		if err := h.bob.WaitForFile(h.alice.email, name, CalculateMD5(content), syncTimeout); err != nil {
+			t.Errorf("bob lost %s: %v", name, err)
+		}
+	}
+	if err := h.alice.WaitForFile(h.bob.email, "offline/while-offline.bin", CalculateMD5(bobContent), syncTimeout); err != nil {
+		t.Errorf("alice lost bob's offline file: %v", err)
+	}
+	catchUp := time.Since(onlineAt)
+
+	h.metrics.RecordCustomMetric("downtime_seconds", downtime.Seconds())
+	h.metrics.RecordCustomMetric("catch_up_seconds", catchUp.Seconds())
+	t.Logf("Bob was offline for %s and caught up %d files in %s after reconnecting", downtime, len(expected)+1, catchUp)
+}
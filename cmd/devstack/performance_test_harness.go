@@ -9,6 +9,7 @@ import (
 	"runtime"
 	"runtime/pprof"
 	"runtime/trace"
+	"slices"
 	"sync"
 	"testing"
 	"time"
@@ -150,18 +151,18 @@ func NewDevstackHarness(t *testing.T) *DevstackTestHarness {
 
 	// Start MinIO
 	t.Logf("Starting MinIO...")
-	minioBin, err := ensureMinioBinary(binDir)
+	minioBin, err := ensureMinioBinary(binDir, "")
 	if err != nil {
 		t.Fatalf("minio binary unavailable: %v", err)
 	}
 
-	mState, err := startMinio("local", minioBin, relayRoot, minioAPIPort, minioConsolePort, false)
+	mState, err := startMinio("local", minioBin, relayRoot, minioAPIPort, minioConsolePort, false, "")
 	if err != nil {
 		t.Fatalf("start minio: %v", err)
 	}
 
 	// Setup bucket
-	if err := setupBucket(mState.APIPort); err != nil {
+	if err := setupBucket(mState, relayRoot); err != nil {
 		stopMinio(mState)
 		t.Fatalf("setup bucket: %v", err)
 	}
@@ -169,7 +170,7 @@ func NewDevstackHarness(t *testing.T) *DevstackTestHarness {
 	// Start server
 	t.Logf("Starting server...")
 	serverURL := fmt.Sprintf("http://127.0.0.1:%d", serverPort)
-	sState, err := startServer(serverBin, relayRoot, serverPort, mState.APIPort)
+	sState, err := startServer(serverBin, relayRoot, serverPort, mState)
 	if err != nil {
 		stopMinio(mState)
 		t.Fatalf("start server: %v", err)
@@ -271,6 +272,29 @@ func (h *DevstackTestHarness) Cleanup() {
 	})
 }
 
+// StopClient kills a client, as if it went offline. Its workspace is kept
+func (h *DevstackTestHarness) StopClient(c *ClientHelper) error {
+	h.t.Helper()
+	if err := killProcess(c.state.PID); err != nil {
+		return fmt.Errorf("kill client %s: %w", c.email, err)
+	}
+	h.t.Logf("%s stopped (pid:%d)", c.email, c.state.PID)
+	return nil
+}
+
+// StartClient starts a stopped client again on its workspace, as if it came back online
+func (h *DevstackTestHarness) StartClient(c *ClientHelper) error {
+	h.t.Helper()
+	cState, err := startClient(c.state.BinPath, h.root, c.email, c.state.ServerURL, c.state.Port)
+	if err != nil {
+		return fmt.Errorf("start client %s: %w", c.email, err)
+	}
+	c.state = cState
+	h.cleanup = append(h.cleanup, func() { _ = killProcess(cState.PID) })
+	h.t.Logf("%s started (pid:%d)", c.email, cState.PID)
+	return nil
+}
+
 // UploadFile creates and uploads a file from a client
 func (c *ClientHelper) UploadFile(relPath string, content []byte) error {
 	c.t.Helper()
@@ -560,27 +584,11 @@ func (m *MetricsCollector) GenerateReport() *TestReport {
 	return report
 }
 
-// percentile calculates the p-th percentile of durations
+// percentile calculates the p-th percentile of unsorted durations
 func percentile(durations []time.Duration, p float64) time.Duration {
-	if len(durations) == 0 {
-		return 0
-	}
-
-	// Simple implementation - should use proper sorting for production
-	sorted := make([]time.Duration, len(durations))
-	copy(sorted, durations)
-
-	// Sort manually (bubble sort for simplicity)
-	for i := 0; i < len(sorted); i++ {
-		for j := i + 1; j < len(sorted); j++ {
-			if sorted[i] > sorted[j] {
-				sorted[i], sorted[j] = sorted[j], sorted[i]
-			}
-		}
-	}
-
-	idx := int(float64(len(sorted)-1) * p)
-	return sorted[idx]
+	sorted := slices.Clone(durations)
+	slices.Sort(sorted)
+	return utils.Percentile(sorted, p)
 }
 
 // LogReport logs a test report
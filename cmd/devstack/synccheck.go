@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+const defaultSyncCheckTimeout = 45 * time.Second
+
+// syncPair is a probe written by src and expected at dst
+type syncPair struct {
+	src string
+	dst string
+}
+
+// runSyncCheck checks replication between every pair of clients. Each client writes a probe into
+// its own public dir and every other client waits up to timeout for it. The results are printed
+// as a matrix, and an error is returned if any pair failed.
+func runSyncCheck(root string, emails []string, timeout time.Duration) error {
+	if len(emails) <= 1 {
+		return nil
+	}
+
+	now := time.Now()
+	filename := fmt.Sprintf("devstack-ready-%d.txt", now.UnixNano())
+	probeContent := func(src string) string {
+		return fmt.Sprintf("devstack ready %s %s", src, now.Format(time.RFC3339Nano))
+	}
+
+	// a probe that can't be written fails every pair of its row
+	failed := make(map[syncPair]error)
+	failRow := func(src string, err error) {
+		for _, dst := range emails {
+			if dst != src {
+				failed[syncPair{src, dst}] = err
+			}
+		}
+	}
+
+	var written []string
+	for _, src := range emails {
+		publicDir := publicPath(root, src)
+		if err := os.MkdirAll(publicDir, 0o755); err != nil {
+			failRow(src, fmt.Errorf("ensure public dir: %w", err))
+			continue
+		}
+		if err := waitForDir(publicDir, 15*time.Second); err != nil {
+			failRow(src, fmt.Errorf("public dir not ready: %w", err))
+			continue
+		}
+		if err := os.WriteFile(filepath.Join(publicDir, filename), []byte(probeContent(src)), 0o644); err != nil {
+			failRow(src, fmt.Errorf("write probe: %w", err))
+			continue
+		}
+		written = append(written, src)
+	}
+
+	// Touch the files via the server to trigger notifications
+	if err := waitForServerReady(root, 15*time.Second); err != nil {
+		return fmt.Errorf("wait for server: %w", err)
+	}
+	time.Sleep(500 * time.Millisecond)
+	if err := triggerDownloadForAll(root, emails, written, filename); err != nil {
+		fmt.Printf("Sync probe trigger note: %v (continuing)\n", err)
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, src := range written {
+		for _, dst := range emails {
+			if dst == src {
+				continue
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				// Each client syncs the others' public dirs to datasites/<src>/public/
+				targetDir := filepath.Join(root, dst, "datasites", src, "public")
+				_ = os.MkdirAll(targetDir, 0o755) // best-effort
+				if err := waitForFile(filepath.Join(targetDir, filename), probeContent(src), timeout); err != nil {
+					mu.Lock()
+					failed[syncPair{src, dst}] = err
+					mu.Unlock()
+				}
+			}()
+		}
+	}
+	wg.Wait()
+
+	pairs := len(emails) * (len(emails) - 1)
+	if len(failed) == 0 {
+		fmt.Printf("Sync check passed (%s replicated between %d clients, %d pairs)\n", filename, len(emails), pairs)
+		return nil
+	}
+
+	printSyncMatrix(os.Stdout, emails, failed)
+	return fmt.Errorf("%d of %d client pairs did not sync", len(failed), pairs)
+}
+
+// printSyncMatrix prints which clients received the probe of which other clients,
+// followed by the failed pairs
+func printSyncMatrix(w io.Writer, emails []string, failed map[syncPair]error) {
+	fmt.Fprintln(w, "Sync check matrix (rows wrote the probe, columns received it):")
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "\t%s\n", strings.Join(emails, "\t"))
+	for _, src := range emails {
+		cells := make([]string, 0, len(emails))
+		for _, dst := range emails {
+			switch _, ok := failed[syncPair{src, dst}]; {
+			case src == dst:
+				cells = append(cells, "-")
+			case ok:
+				cells = append(cells, "FAIL")
+			default:
+				cells = append(cells, "ok")
+			}
+		}
+		fmt.Fprintf(tw, "%s\t%s\n", src, strings.Join(cells, "\t"))
+	}
+	tw.Flush()
+
+	fmt.Fprintln(w, "Failed pairs:")
+	for _, src := range emails {
+		for _, dst := range emails {
+			if err, ok := failed[syncPair{src, dst}]; ok {
+				fmt.Fprintf(w, "  %s -> %s: %v\n", src, dst, err)
+			}
+		}
+	}
+}
+
+func parseSyncTimeout(value string) (time.Duration, error) {
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout <= 0 {
+		return 0, fmt.Errorf("invalid --sync-check-timeout %q: want a positive duration like 90s", value)
+	}
+	return timeout, nil
+}
+
+// triggerDownloadForAll asks the server to serve the probes for each user to ensure their daemon pulls them.
+func triggerDownloadForAll(root string, emails []string, sources []string, filename string) error {
+	if len(sources) == 0 {
+		return nil
+	}
+	state, _, err := readState(root)
+	if err != nil {
+		return err
+	}
+	serverURL := fmt.Sprintf("http://127.0.0.1:%d", state.Server.Port)
+	client := &http.Client{Timeout: 3 * time.Second}
+
+	keys := make([]string, 0, len(sources))
+	for _, src := range sources {
+		keys = append(keys, fmt.Sprintf(`"%s/public/%s"`, src, filename))
+	}
+	payload := fmt.Sprintf(`{"keys":[%s]}`, strings.Join(keys, ","))
+
+	for _, email := range emails {
+		url := fmt.Sprintf("%s/api/v1/blob/download?user=%s", serverURL, email)
+		if err := postWithRetry(client, url, payload, 30, 500*time.Millisecond); err != nil {
+			return fmt.Errorf("trigger for %s: %w", email, err)
+		}
+	}
+	return nil
+}
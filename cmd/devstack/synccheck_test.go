@@ -0,0 +1,39 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrintSyncMatrix(t *testing.T) {
+	emails := []string{"alice@example.com", "bob@example.com", "carol@example.com"}
+	failed := map[syncPair]error{
+		{"bob@example.com", "carol@example.com"}: errors.New("file not found"),
+	}
+
+	var out strings.Builder
+	printSyncMatrix(&out, emails, failed)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 7)
+
+	assert.Equal(t, []string{"alice@example.com", "-", "ok", "ok"}, strings.Fields(lines[2]))
+	assert.Equal(t, []string{"bob@example.com", "ok", "-", "FAIL"}, strings.Fields(lines[3]))
+	assert.Equal(t, []string{"carol@example.com", "ok", "ok", "-"}, strings.Fields(lines[4]))
+	assert.Equal(t, "  bob@example.com -> carol@example.com: file not found", lines[6])
+}
+
+func TestParseSyncTimeout(t *testing.T) {
+	timeout, err := parseSyncTimeout("90s")
+	require.NoError(t, err)
+	assert.Equal(t, 90*time.Second, timeout)
+
+	for _, value := range []string{"90", "-1s", "0s"} {
+		_, err := parseSyncTimeout(value)
+		assert.Error(t, err, value)
+	}
+}
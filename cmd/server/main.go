@@ -14,6 +14,8 @@ import (
 	"github.com/joho/godotenv"
 	"github.com/lmittmann/tint"
 	"github.com/openmined/syftbox/internal/server"
+	"github.com/openmined/syftbox/internal/server/auth"
+	"github.com/openmined/syftbox/internal/server/redact"
 	"github.com/openmined/syftbox/internal/version"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -21,18 +23,48 @@ import (
 
 const (
 	DefaultBindAddr           = "localhost:8080"
+	DefaultStrictHosts        = true
+	DefaultNotifyCoalesce     = 250 * time.Millisecond
+	DefaultBroadcastFanout    = 500
+	DefaultBroadcastInterval  = 50 * time.Millisecond
+	DefaultTLSMinVersion      = "1.2"
 	DefaultDataDir            = ".data"
 	DefaultLogDir             = ".logs"
+	DefaultLogLevel           = "debug"
 	DefaultAuthEnabled        = false
 	DefaultEmailOTPLength     = 8
 	DefaultEmailOTPExpiry     = 5 * time.Minute
 	DefaultRefreshTokenExpiry = 0
 	DefaultAccessTokenExpiry  = 7 * 24 * time.Hour
 	DefaultEmailEnabled       = false
+	DefaultMaxDatasites       = 0 // unlimited
+	DefaultIdleTimeout        = 0 // never evict
+	DefaultPublicACL          = "read"
+	DefaultQuotaBytes         = 0 // unlimited
+	DefaultACLMaxSize         = 256 * 1024
+	DefaultACLMaxRules        = 1000
+	DefaultPublicHosting      = true
+	DefaultRPC                = true
+	DefaultBlobBackend        = "s3"
+	DefaultBlobSSE            = "none"
+	DefaultPresignCacheTTL    = time.Minute
+	DefaultMaxKeyLength       = 1024
+	DefaultMaxBlobReads       = 256
+	DefaultMaxBlobWrites      = 128
+	DefaultBlobQueueTimeout   = 10 * time.Second
+	DefaultMaxPresignKeys     = 1000
+
+	DefaultIndexReplicaMaxLag      = 5 * time.Second
+	DefaultIndexReplicaConsistency = "bounded"
+
+	DefaultRevisionsKept  = 100
+	DefaultRevisionMaxAge = 30 * 24 * time.Hour
 )
 
 var (
 	dotenvLoaded bool
+	// logLevel is shared by the log handlers, so that a reload can change it
+	logLevel = new(slog.LevelVar)
 )
 
 var rootCmd = &cobra.Command{
@@ -51,6 +83,8 @@ var rootCmd = &cobra.Command{
 
 		// Log the final configuration details (masking secrets)
 		slog.Info("server config", "dotenvLoaded", dotenvLoaded, "config", cfg.LogValue())
+		level, _ := cfg.Level() // validated
+		logLevel.Set(level)
 
 		c, err := server.New(cfg)
 		if err != nil {
@@ -58,6 +92,8 @@ var rootCmd = &cobra.Command{
 			return err
 		}
 
+		go reloadOnSignal(cmd, c)
+
 		defer slog.Info("Bye!")
 		if err := c.Start(cmd.Context()); err != nil {
 			slog.Error("server", "error", err)
@@ -106,11 +142,11 @@ func setupHandler() slog.Handler {
 	switch os.Getenv("SYFTBOX_ENV") {
 	case "PROD", "STAGE":
 		return slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
-			Level: slog.LevelDebug,
+			Level: logLevel,
 		})
 	default:
 		return tint.NewHandler(os.Stdout, &tint.Options{
-			Level:      slog.LevelDebug,
+			Level:      logLevel,
 			AddSource:  true,
 			TimeFormat: time.DateTime,
 			ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
@@ -123,6 +159,42 @@ func setupHandler() slog.Handler {
 	}
 }
 
+// reloadOnSignal reloads the config of the running server on SIGHUP, until the command is done
+func reloadOnSignal(cmd *cobra.Command, srv *server.Server) {
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	defer signal.Stop(sighup)
+
+	for {
+		select {
+		case <-sighup:
+			slog.Info("SIGHUP received, reloading config")
+			if err := reloadConfig(cmd, srv); err != nil {
+				slog.Warn("config reload rejected, keeping the current config", "error", err)
+			}
+		case <-cmd.Context().Done():
+			return
+		}
+	}
+}
+
+// reloadConfig re-reads the config and applies the hot-reloadable settings to the running server
+func reloadConfig(cmd *cobra.Command, srv *server.Server) error {
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	if err := srv.Reload(cfg); err != nil {
+		return err
+	}
+
+	level, _ := cfg.Level() // validated
+	logLevel.Set(level)
+	slog.Info("log level reloaded", "level", level)
+	return nil
+}
+
 // loadConfig initializes viper, reads config file/env vars, and maps values to config
 func loadConfig(cmd *cobra.Command) (*server.Config, error) {
 	v := viper.New()
@@ -184,18 +256,42 @@ func bindWithDefaults(v *viper.Viper, cmd *cobra.Command) {
 	// Data directory
 	v.SetDefault("data_dir", DefaultDataDir)
 	v.SetDefault("log_dir", DefaultLogDir)
+	v.SetDefault("log_level", DefaultLogLevel)
 	// HTTP section
 	v.SetDefault("http.addr", DefaultBindAddr)
 	v.SetDefault("http.cert_file", "")
 	v.SetDefault("http.key_file", "")
 	v.SetDefault("http.domain", "")
+	v.SetDefault("http.local_hosts", []string{})
+	v.SetDefault("http.strict_hosts", DefaultStrictHosts)
+	v.SetDefault("http.notify_coalesce_window", DefaultNotifyCoalesce)
+	v.SetDefault("http.broadcast_max_fanout", DefaultBroadcastFanout)
+	v.SetDefault("http.broadcast_interval", DefaultBroadcastInterval)
+	v.SetDefault("http.tls_min_version", DefaultTLSMinVersion)
+	v.SetDefault("http.tls_cipher_suites", []string{})
 	// Blob section (config file/env vars only)
+	v.SetDefault("blob.backend", DefaultBlobBackend)
+	v.SetDefault("blob.dir", "")
+	v.SetDefault("blob.public_url", "")
 	v.SetDefault("blob.bucket_name", "")
 	v.SetDefault("blob.region", "")
 	v.SetDefault("blob.endpoint", "")
 	v.SetDefault("blob.access_key", "")
 	v.SetDefault("blob.secret_key", "")
 	v.SetDefault("blob.use_accelerate", false)
+	v.SetDefault("blob.sse", DefaultBlobSSE)
+	v.SetDefault("blob.sse_kms_key_id", "")
+	v.SetDefault("blob.presign_cache_ttl", DefaultPresignCacheTTL)
+	v.SetDefault("blob.max_key_length", DefaultMaxKeyLength)
+	v.SetDefault("blob.max_concurrent_reads", DefaultMaxBlobReads)
+	v.SetDefault("blob.max_concurrent_writes", DefaultMaxBlobWrites)
+	v.SetDefault("blob.queue_timeout", DefaultBlobQueueTimeout)
+	v.SetDefault("blob.max_presign_keys", DefaultMaxPresignKeys)
+	v.SetDefault("blob.index_replica_path", "")
+	v.SetDefault("blob.index_replica_max_lag", DefaultIndexReplicaMaxLag)
+	v.SetDefault("blob.index_replica_consistency", DefaultIndexReplicaConsistency)
+	v.SetDefault("blob.revisions_kept", DefaultRevisionsKept)
+	v.SetDefault("blob.revision_max_age", DefaultRevisionMaxAge)
 	// Auth section (config file/env vars only)
 	v.SetDefault("auth.enabled", DefaultAuthEnabled)
 	v.SetDefault("auth.token_issuer", "")
@@ -206,7 +302,25 @@ func bindWithDefaults(v *viper.Viper, cmd *cobra.Command) {
 	v.SetDefault("auth.refresh_token_expiry", DefaultRefreshTokenExpiry)
 	v.SetDefault("auth.access_token_secret", "")
 	v.SetDefault("auth.access_token_expiry", DefaultAccessTokenExpiry)
+	v.SetDefault("auth.allowed_emails", []string{})
+	v.SetDefault("auth.denied_emails", []string{})
+	v.SetDefault("auth.admin_emails", []string{})
+	v.SetDefault("auth.groups", map[string][]string{})
+	v.SetDefault("auth.user_claims", []auth.UserClaims{})
+	v.SetDefault("auth.email_dot_plus_domains", []string{})
 	// Email section (config file/env vars only)
 	v.SetDefault("email.enabled", DefaultEmailEnabled)
 	v.SetDefault("email.sendgrid_api_key", "")
+	// Datasite section (config file/env vars only)
+	v.SetDefault("datasite.max_datasites", DefaultMaxDatasites)
+	v.SetDefault("datasite.idle_timeout", DefaultIdleTimeout)
+	v.SetDefault("datasite.public_acl", DefaultPublicACL)
+	v.SetDefault("datasite.quota_bytes", DefaultQuotaBytes)
+	v.SetDefault("datasite.acl_max_size", DefaultACLMaxSize)
+	v.SetDefault("datasite.acl_max_rules", DefaultACLMaxRules)
+	v.SetDefault("datasite.features.public_hosting", DefaultPublicHosting)
+	v.SetDefault("datasite.features.rpc", DefaultRPC)
+	// Redact section (config file/env vars only)
+	v.SetDefault("redact.query_params", redact.DefaultQueryParams)
+	v.SetDefault("redact.patterns", []string{})
 }
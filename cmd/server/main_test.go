@@ -1,11 +1,16 @@
 package main
 
 import (
+	"context"
+	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/openmined/syftbox/internal/server"
+	"github.com/openmined/syftbox/internal/server/auth"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -25,6 +30,7 @@ func TestLoadConfigEnv(t *testing.T) {
 	t.Setenv("SYFTBOX_BLOB_ACCESS_KEY", "test-access-key")
 	t.Setenv("SYFTBOX_BLOB_SECRET_KEY", "test-secret-key")
 	t.Setenv("SYFTBOX_BLOB_USE_ACCELERATE", "1")
+	t.Setenv("SYFTBOX_BLOB_PRESIGN_CACHE_TTL", "30s")
 	// auth
 	t.Setenv("SYFTBOX_AUTH_ENABLED", "true")
 	t.Setenv("SYFTBOX_AUTH_TOKEN_ISSUER", "http://0.0.0.0:8080")
@@ -38,6 +44,9 @@ func TestLoadConfigEnv(t *testing.T) {
 	// email
 	t.Setenv("SYFTBOX_EMAIL_ENABLED", "true")
 	t.Setenv("SYFTBOX_EMAIL_SENDGRID_API_KEY", "sendgrid_api_key")
+	// datasite
+	t.Setenv("SYFTBOX_DATASITE_MAX_DATASITES", "100")
+	t.Setenv("SYFTBOX_DATASITE_FEATURES_RPC", "false")
 
 	// Call loadConfig
 	cfg, err := loadConfig(rootCmd)
@@ -57,6 +66,7 @@ func TestLoadConfigEnv(t *testing.T) {
 	assert.Equal(t, cfg.Blob.AccessKey, "test-access-key")
 	assert.Equal(t, cfg.Blob.SecretKey, "test-secret-key")
 	assert.Equal(t, cfg.Blob.UseAccelerate, true)
+	assert.Equal(t, cfg.Blob.PresignCacheTTL, 30*time.Second)
 	assert.Equal(t, cfg.Auth.Enabled, true)
 	assert.Equal(t, cfg.Auth.TokenIssuer, "http://0.0.0.0:8080")
 	assert.Equal(t, cfg.Auth.EmailAddr, "test@example.com")
@@ -68,6 +78,9 @@ func TestLoadConfigEnv(t *testing.T) {
 	assert.Equal(t, cfg.Auth.AccessTokenExpiry, 1*time.Hour)
 	assert.Equal(t, cfg.Email.Enabled, true)
 	assert.Equal(t, cfg.Email.SendgridAPIKey, "sendgrid_api_key")
+	assert.Equal(t, cfg.Datasite.MaxDatasites, 100)
+	assert.Equal(t, cfg.Datasite.Features.PublicHosting, true) // default
+	assert.Equal(t, cfg.Datasite.Features.RPC, false)
 }
 
 func TestLoadConfigYAML(t *testing.T) {
@@ -83,6 +96,7 @@ blob:
   access_key: test-access-key
   secret_key: test-secret-key
   use_accelerate: true
+  presign_cache_ttl: 2m
 
 auth:
   enabled: true
@@ -94,10 +108,27 @@ auth:
   refresh_token_expiry: 1h
   access_token_secret: test-access-secret
   access_token_expiry: 1h
+  allowed_emails:
+    - "*@lab.org"
+    - alice@example.com
+  denied_emails:
+    - intern@lab.org
+  admin_emails:
+    - ops@lab.org
+  user_claims:
+    - email: alice@example.com
+      claims:
+        org_verified: "true"
 
 email:
   enabled: false
   sendgrid_api_key: sendgrid_api_key
+
+datasite:
+  max_datasites: 100
+  acl_max_rules: 50
+  features:
+    public_hosting: false
 `
 	dummyConfigFile := filepath.Join(os.TempDir(), "dummy.yaml")
 	err := os.WriteFile(dummyConfigFile, []byte(dummyConfig), 0644)
@@ -117,12 +148,20 @@ email:
 	assert.Equal(t, cfg.HTTP.Addr, "localhost:8080")
 	assert.Equal(t, cfg.HTTP.CertFilePath, "test-cert.pem")
 	assert.Equal(t, cfg.HTTP.KeyFilePath, "test-key.pem")
+	assert.Equal(t, cfg.HTTP.BroadcastMaxFanout, DefaultBroadcastFanout)  // default
+	assert.Equal(t, cfg.HTTP.BroadcastInterval, DefaultBroadcastInterval) // default
 	assert.Equal(t, cfg.Blob.BucketName, "test-bucket")
 	assert.Equal(t, cfg.Blob.Region, "test-region")
 	assert.Equal(t, cfg.Blob.Endpoint, "http://test-endpoint")
 	assert.Equal(t, cfg.Blob.AccessKey, "test-access-key")
 	assert.Equal(t, cfg.Blob.SecretKey, "test-secret-key")
 	assert.Equal(t, cfg.Blob.UseAccelerate, true)
+	assert.Equal(t, cfg.Blob.PresignCacheTTL, 2*time.Minute)
+	assert.Equal(t, cfg.Blob.IndexReplicaPath, "")                          // default
+	assert.Equal(t, cfg.Blob.IndexReplicaMaxLag, DefaultIndexReplicaMaxLag) // default
+	assert.Equal(t, cfg.Blob.IndexReplicaConsistency, "bounded")            // default
+	assert.Equal(t, cfg.Blob.RevisionsKept, DefaultRevisionsKept)           // default
+	assert.Equal(t, cfg.Blob.RevisionMaxAge, DefaultRevisionMaxAge)         // default
 	assert.Equal(t, cfg.Auth.Enabled, true)
 	assert.Equal(t, cfg.Auth.TokenIssuer, "http://0.0.0.0:8080")
 	assert.Equal(t, cfg.Auth.EmailAddr, "test@example.com")
@@ -132,8 +171,17 @@ email:
 	assert.Equal(t, cfg.Auth.RefreshTokenExpiry, 1*time.Hour)
 	assert.Equal(t, cfg.Auth.AccessTokenSecret, "test-access-secret")
 	assert.Equal(t, cfg.Auth.AccessTokenExpiry, 1*time.Hour)
+	assert.Equal(t, cfg.Auth.AllowedEmails, []string{"*@lab.org", "alice@example.com"})
+	assert.Equal(t, cfg.Auth.DeniedEmails, []string{"intern@lab.org"})
+	assert.Equal(t, cfg.Auth.AdminEmails, []string{"ops@lab.org"})
+	assert.Equal(t, cfg.Auth.UserClaims, []auth.UserClaims{{Email: "alice@example.com", Claims: map[string]string{"org_verified": "true"}}})
 	assert.Equal(t, cfg.Email.Enabled, false)
 	assert.Equal(t, cfg.Email.SendgridAPIKey, "sendgrid_api_key")
+	assert.Equal(t, cfg.Datasite.MaxDatasites, 100)
+	assert.Equal(t, cfg.Datasite.Features.PublicHosting, false)
+	assert.Equal(t, cfg.Datasite.Features.RPC, true) // default
+	assert.Equal(t, cfg.Datasite.ACLMaxRules, 50)
+	assert.EqualValues(t, cfg.Datasite.ACLMaxSize, DefaultACLMaxSize) // default
 }
 
 func TestLoadConfigJSON(t *testing.T) {
@@ -191,6 +239,7 @@ func TestLoadConfigJSON(t *testing.T) {
 	assert.Equal(t, cfg.Blob.AccessKey, "test-another-access-key")
 	assert.Equal(t, cfg.Blob.SecretKey, "test-another-secret-key")
 	assert.Equal(t, cfg.Blob.UseAccelerate, false)
+	assert.Equal(t, cfg.Blob.PresignCacheTTL, DefaultPresignCacheTTL) // default
 	assert.Equal(t, cfg.Auth.Enabled, true)
 	assert.Equal(t, cfg.Auth.TokenIssuer, "http://0.0.0.0:8080")
 	assert.Equal(t, cfg.Auth.EmailAddr, "test@example.com")
@@ -203,3 +252,47 @@ func TestLoadConfigJSON(t *testing.T) {
 	assert.Equal(t, cfg.Email.Enabled, true)
 	assert.Equal(t, cfg.Email.SendgridAPIKey, "123")
 }
+
+func TestReloadConfig(t *testing.T) {
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "config.yaml")
+	writeConfig := func(addr, logLevel string) {
+		config := fmt.Sprintf(`
+log_level: %s
+data_dir: %s
+log_dir: %s
+http:
+  addr: %s
+blob:
+  backend: filesystem
+`, logLevel, filepath.Join(dir, "data"), filepath.Join(dir, "logs"), addr)
+		require.NoError(t, os.WriteFile(configFile, []byte(config), 0644))
+	}
+
+	writeConfig("localhost:8080", "info")
+	rootCmd.Flags().Set("config", configFile)
+
+	cfg, err := loadConfig(rootCmd)
+	require.NoError(t, err)
+	srv, err := server.New(cfg)
+	require.NoError(t, err)
+	defer srv.Stop(context.Background())
+
+	logLevel.Set(slog.LevelInfo)
+	defer logLevel.Set(slog.LevelDebug)
+
+	// the log level is flipped without a restart
+	writeConfig("localhost:8080", "warn")
+	require.NoError(t, reloadConfig(rootCmd, srv))
+	assert.Equal(t, slog.LevelWarn, logLevel.Level())
+
+	// a new bind address needs a restart, the whole reload is rejected
+	writeConfig("localhost:9090", "error")
+	assert.ErrorIs(t, reloadConfig(rootCmd, srv), server.ErrRestartRequired)
+	assert.Equal(t, slog.LevelWarn, logLevel.Level())
+
+	// an invalid config is rejected
+	writeConfig("localhost:8080", "loud")
+	assert.Error(t, reloadConfig(rootCmd, srv))
+	assert.Equal(t, slog.LevelWarn, logLevel.Level())
+}
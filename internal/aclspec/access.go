@@ -1,6 +1,8 @@
 package aclspec
 
 import (
+	"fmt"
+
 	mapset "github.com/deckarep/golang-set/v2"
 	"gopkg.in/yaml.v3"
 )
@@ -11,6 +13,11 @@ type Access struct {
 	Admin mapset.Set[string] `yaml:"admin"`
 	Read  mapset.Set[string] `yaml:"read"`
 	Write mapset.Set[string] `yaml:"write"`
+
+	// conditions of each level, written in the lists alongside the users
+	AdminConditions []*Condition `yaml:"-"`
+	ReadConditions  []*Condition `yaml:"-"`
+	WriteConditions []*Condition `yaml:"-"`
 }
 
 // NewAccess creates a new Access object with the specified admin, write, and read users.
@@ -47,9 +54,35 @@ func SharedReadWriteAccess(users ...string) *Access {
 	return NewAccess(empty, users, empty)
 }
 
+// NormalizeUsers returns a copy of the access with its users mapped by normalize, e.g. to the canonical form
+// of their emails. The tokens are kept as they are.
+func (a *Access) NormalizeUsers(normalize func(string) string) *Access {
+	users := func(set mapset.Set[string]) mapset.Set[string] {
+		normalized := mapset.NewSetWithSize[string](set.Cardinality())
+		for user := range set.Iter() {
+			if user == TokenUser || user == TokenEveryone {
+				normalized.Add(user)
+			} else {
+				normalized.Add(normalize(user))
+			}
+		}
+		return normalized
+	}
+
+	return &Access{
+		Admin:           users(a.Admin),
+		Read:            users(a.Read),
+		Write:           users(a.Write),
+		AdminConditions: a.AdminConditions,
+		ReadConditions:  a.ReadConditions,
+		WriteConditions: a.WriteConditions,
+	}
+}
+
 func (a *Access) UnmarshalYAML(value *yaml.Node) error {
 	// Create a map to decode the YAML into
-	var m map[string][]string
+	// entries are users, or conditions on the requester's attributes
+	var m map[string][]yaml.Node
 	if err := value.Decode(&m); err != nil {
 		return err
 	}
@@ -60,30 +93,68 @@ func (a *Access) UnmarshalYAML(value *yaml.Node) error {
 	a.Write = mapset.NewSet[string]()
 
 	// Add values to sets
-	if admin, ok := m["admin"]; ok {
-		a.Admin.Append(admin...)
+	var err error
+	if a.AdminConditions, err = decodeAccessList(m["admin"], a.Admin); err != nil {
+		return fmt.Errorf("admin: %w", err)
 	}
-	if read, ok := m["read"]; ok {
-		a.Read.Append(read...)
+	if a.ReadConditions, err = decodeAccessList(m["read"], a.Read); err != nil {
+		return fmt.Errorf("read: %w", err)
 	}
-	if write, ok := m["write"]; ok {
-		a.Write.Append(write...)
+	if a.WriteConditions, err = decodeAccessList(m["write"], a.Write); err != nil {
+		return fmt.Errorf("write: %w", err)
 	}
 
 	return nil
 }
 
+// decodeAccessList adds the users of an access list to users, and returns its conditions
+func decodeAccessList(nodes []yaml.Node, users mapset.Set[string]) ([]*Condition, error) {
+	var conditions []*Condition
+	for _, node := range nodes {
+		switch node.Kind {
+		case yaml.ScalarNode:
+			users.Add(node.Value)
+		case yaml.MappingNode:
+			var cond Condition
+			if err := node.Decode(&cond); err != nil {
+				return nil, err
+			}
+			if err := cond.Validate(); err != nil {
+				return nil, fmt.Errorf("line %d: %w", node.Line, err)
+			}
+			conditions = append(conditions, &cond)
+		default:
+			return nil, fmt.Errorf("line %d: expected a user or a condition", node.Line)
+		}
+	}
+	return conditions, nil
+}
+
 func (a Access) MarshalYAML() (interface{}, error) {
 	// Create map to be marshaled
-	m := make(map[string][]string)
-	if a.Admin != nil {
-		m["admin"] = a.Admin.ToSlice()
+	m := make(map[string][]any)
+	if a.Admin != nil || a.AdminConditions != nil {
+		m["admin"] = encodeAccessList(a.Admin, a.AdminConditions)
 	}
-	if a.Read != nil {
-		m["read"] = a.Read.ToSlice()
+	if a.Read != nil || a.ReadConditions != nil {
+		m["read"] = encodeAccessList(a.Read, a.ReadConditions)
 	}
-	if a.Write != nil {
-		m["write"] = a.Write.ToSlice()
+	if a.Write != nil || a.WriteConditions != nil {
+		m["write"] = encodeAccessList(a.Write, a.WriteConditions)
 	}
 	return m, nil
 }
+
+// encodeAccessList lists the users, then the conditions
+func encodeAccessList(users mapset.Set[string], conditions []*Condition) []any {
+	list := make([]any, 0)
+	if users != nil {
+		for _, user := range users.ToSlice() {
+			list = append(list, user)
+		}
+	}
+	for _, cond := range conditions {
+		list = append(list, cond)
+	}
+	return list
+}
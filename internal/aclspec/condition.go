@@ -0,0 +1,34 @@
+package aclspec
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrEmptyCondition = errors.New("condition must set a domain, claims or a group")
+
+// Condition grants access to the requesters whose attributes match all of its fields.
+// Conditions are written in the access lists alongside the users, e.g.
+//
+//	read:
+//	  - alice@example.com
+//	  - domain: lab.org
+//	    claims: {org_verified: "true"}
+//	  - group: researchers
+type Condition struct {
+	Domain string            `yaml:"domain,omitempty"` // email domain of the requester, e.g. lab.org
+	Claims map[string]string `yaml:"claims,omitempty"` // claims the server config gives the requester, and their values
+	Group  string            `yaml:"group,omitempty"`  // group the requester is a member of
+}
+
+// Validate checks that the condition matches on something
+func (c *Condition) Validate() error {
+	if c.Domain == "" && len(c.Claims) == 0 && c.Group == "" {
+		return ErrEmptyCondition
+	}
+	if strings.Contains(c.Domain, "@") {
+		return fmt.Errorf("invalid condition domain %q", c.Domain)
+	}
+	return nil
+}
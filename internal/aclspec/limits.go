@@ -1,5 +1,17 @@
 package aclspec
 
+import "errors"
+
+// ErrRuleSetTooLarge is returned when loading an ACL file over the RuleSetLimits
+var ErrRuleSetTooLarge = errors.New("acl file is too large")
+
+// RuleSetLimits caps the ACL files a ruleset is loaded from, as each rule is matched on every access check.
+// Zero values are unlimited.
+type RuleSetLimits struct {
+	MaxSize  int64 // size of the file in bytes
+	MaxRules int   // number of rules in the file, not counting the default rule added on load
+}
+
 type Limits struct {
 	MaxFileSize   int64  `yaml:"maxFileSize,omitempty"`
 	MaxFiles      uint32 `yaml:"maxFiles,omitempty"`
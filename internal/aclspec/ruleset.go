@@ -29,6 +29,19 @@ func (r *RuleSet) AllRules() []*Rule {
 	return r.Rules
 }
 
+// NormalizeUsers returns a copy of the ruleset with the users of its rules mapped by normalize
+func (r *RuleSet) NormalizeUsers(normalize func(string) string) *RuleSet {
+	rules := make([]*Rule, len(r.Rules))
+	for i, rule := range r.Rules {
+		normalized := *rule
+		if rule.Access != nil {
+			normalized.Access = rule.Access.NormalizeUsers(normalize)
+		}
+		rules[i] = &normalized
+	}
+	return &RuleSet{Rules: rules, Terminal: r.Terminal, Path: r.Path}
+}
+
 // LoadFromFile loads a RuleSet from the specified file path
 func LoadFromFile(path string) (*RuleSet, error) {
 	aclPath := AsACLPath(path)
@@ -43,15 +56,35 @@ func LoadFromFile(path string) (*RuleSet, error) {
 // LoadFromReader creates a RuleSet by reading and parsing YAML content from the provided reader.
 // The path parameter is used to set the internal path of the RuleSet.
 func LoadFromReader(path string, reader io.Reader) (*RuleSet, error) {
+	return LoadFromReaderWithLimits(path, reader, nil)
+}
+
+// LoadFromReaderWithLimits is LoadFromReader, rejecting the ACL files over the limits with ErrRuleSetTooLarge.
+// A nil limits is unlimited.
+func LoadFromReaderWithLimits(path string, reader io.Reader, limits *RuleSetLimits) (*RuleSet, error) {
+	if limits == nil {
+		limits = &RuleSetLimits{}
+	}
+
+	if limits.MaxSize > 0 {
+		// read one byte past the limit to tell a file at the limit from a larger one
+		reader = io.LimitReader(reader, limits.MaxSize+1)
+	}
 	data, err := io.ReadAll(reader)
 	if err != nil {
 		return nil, err
 	}
+	if limits.MaxSize > 0 && int64(len(data)) > limits.MaxSize {
+		return nil, fmt.Errorf("%w: file is larger than %d bytes", ErrRuleSetTooLarge, limits.MaxSize)
+	}
 
 	var ruleset RuleSet
 	if err := yaml.Unmarshal(data, &ruleset); err != nil {
 		return nil, err
 	}
+	if limits.MaxRules > 0 && len(ruleset.Rules) > limits.MaxRules {
+		return nil, fmt.Errorf("%w: %d rules, the limit is %d", ErrRuleSetTooLarge, len(ruleset.Rules), limits.MaxRules)
+	}
 
 	ruleset.Path = WithoutACLPath(path)
 	return setDefaults(&ruleset)
@@ -65,8 +98,13 @@ func (r *RuleSet) Save() error {
 	}
 	defer file.Close()
 
+	return r.Encode(file)
+}
+
+// Encode writes the RuleSet as YAML, the way it's saved to an ACL file
+func (r *RuleSet) Encode(w io.Writer) error {
 	// Create a new encoder with 2-space indentation
-	encoder := yaml.NewEncoder(file)
+	encoder := yaml.NewEncoder(w)
 	encoder.SetIndent(2)
 
 	// Encode the RuleSet to YAML
@@ -74,7 +112,7 @@ func (r *RuleSet) Save() error {
 		return fmt.Errorf("failed to marshal RuleSet to YAML: %w", err)
 	}
 
-	return nil
+	return encoder.Close()
 }
 
 func setDefaults(ruleset *RuleSet) (*RuleSet, error) {
@@ -0,0 +1,97 @@
+package aclspec
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConditions(t *testing.T) {
+	ruleset, err := LoadFromReader("alice@example.com", strings.NewReader(`
+rules:
+  - pattern: "**"
+    access:
+      admin: []
+      read:
+        - bob@example.com
+        - domain: lab.org
+          claims:
+            org_verified: "true"
+        - group: researchers
+`))
+	require.NoError(t, err)
+
+	access := ruleset.Rules[0].Access
+	assert.ElementsMatch(t, []string{"bob@example.com"}, access.Read.ToSlice())
+	assert.Equal(t, []*Condition{
+		{Domain: "lab.org", Claims: map[string]string{"org_verified": "true"}},
+		{Group: "researchers"},
+	}, access.ReadConditions)
+	assert.Empty(t, access.WriteConditions)
+
+	// the conditions are saved back
+	var buf bytes.Buffer
+	require.NoError(t, ruleset.Encode(&buf))
+	saved, err := LoadFromReader("alice@example.com", &buf)
+	require.NoError(t, err)
+	assert.Equal(t, access.ReadConditions, saved.Rules[0].Access.ReadConditions)
+	assert.ElementsMatch(t, []string{"bob@example.com"}, saved.Rules[0].Access.Read.ToSlice())
+}
+
+func TestLoadInvalidConditions(t *testing.T) {
+	for name, acl := range map[string]string{
+		"empty":  "rules:\n  - pattern: \"**\"\n    access:\n      read:\n        - {}\n",
+		"email":  "rules:\n  - pattern: \"**\"\n    access:\n      read:\n        - domain: bob@lab.org\n",
+		"nested": "rules:\n  - pattern: \"**\"\n    access:\n      read:\n        - [bob@example.com]\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := LoadFromReader("alice@example.com", strings.NewReader(acl))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestLoadWithLimits(t *testing.T) {
+	aclWithRules := func(n int) string {
+		var b strings.Builder
+		b.WriteString("rules:\n")
+		for i := range n {
+			fmt.Fprintf(&b, "  - pattern: \"dir%d/**\"\n    access:\n      read: [\"*\"]\n", i)
+		}
+		return b.String()
+	}
+	limits := &RuleSetLimits{MaxSize: 4096, MaxRules: 10}
+
+	ruleset, err := LoadFromReaderWithLimits("alice@example.com", strings.NewReader(aclWithRules(10)), limits)
+	require.NoError(t, err)
+	assert.Len(t, ruleset.Rules, 11) // with the default rule
+
+	_, err = LoadFromReaderWithLimits("alice@example.com", strings.NewReader(aclWithRules(11)), limits)
+	assert.ErrorIs(t, err, ErrRuleSetTooLarge)
+
+	_, err = LoadFromReaderWithLimits("alice@example.com", strings.NewReader(aclWithRules(5)+strings.Repeat("#", 4096)), limits)
+	assert.ErrorIs(t, err, ErrRuleSetTooLarge)
+
+	// unlimited without limits
+	_, err = LoadFromReader("alice@example.com", strings.NewReader(aclWithRules(100)))
+	assert.NoError(t, err)
+}
+
+func TestNormalizeUsers(t *testing.T) {
+	ruleset := NewRuleSet("alice@example.com", Terminal,
+		NewRule("**", NewAccess([]string{"Admin@Example.com"}, []string{TokenUser}, []string{"Bob@Example.com", TokenEveryone}), nil),
+	)
+
+	normalized := ruleset.NormalizeUsers(strings.ToLower)
+	access := normalized.Rules[0].Access
+	assert.ElementsMatch(t, []string{"admin@example.com"}, access.Admin.ToSlice())
+	assert.ElementsMatch(t, []string{TokenUser}, access.Write.ToSlice())
+	assert.ElementsMatch(t, []string{"bob@example.com", TokenEveryone}, access.Read.ToSlice())
+
+	// the original is unchanged
+	assert.ElementsMatch(t, []string{"Admin@Example.com"}, ruleset.Rules[0].Access.Admin.ToSlice())
+}
@@ -0,0 +1,60 @@
+package aclspec
+
+import (
+	"slices"
+	"sort"
+	"strings"
+)
+
+// GlobSpecificity scores how specific a rule pattern is. Rules are matched from the most specific one.
+func GlobSpecificity(glob string) int {
+	// early return for the most specific glob patterns
+	switch glob {
+	case "**":
+		return -100
+	case "**/*":
+		return -99
+	}
+
+	// 2L + 10D - wildcard penalty
+	// Use forward slash for glob patterns
+	score := len(glob)*2 + strings.Count(glob, "/")*10
+
+	if IsTemplatePattern(glob) {
+		score += 50
+	}
+
+	// penalize base score for substr wildcards
+	for i, c := range glob {
+		switch c {
+		case '*':
+			if i == 0 {
+				score -= 20 // Leading wildcards are very unspecific
+			} else {
+				score -= 10 // Other wildcards are less penalized
+			}
+		case '?', '!', '[', '{':
+			score -= 2 // Non * wildcards get smaller penalty
+		}
+	}
+
+	return score
+}
+
+// SortRulesBySpecificity returns a copy of the rules, the most specific first
+func SortRulesBySpecificity(rules []*Rule) []*Rule {
+	// copy the rules
+	clone := slices.Clone(rules)
+
+	// sort by specificity (or priority), descending
+	sort.Slice(clone, func(i, j int) bool {
+		return GlobSpecificity(clone[i].Pattern) > GlobSpecificity(clone[j].Pattern)
+	})
+
+	return clone
+}
+
+// IsTemplatePattern checks if the pattern is resolved per user, e.g. {{.UserEmail}}/*
+func IsTemplatePattern(pattern string) bool {
+	return strings.Contains(pattern, "{{") && strings.Contains(pattern, "}}")
+}
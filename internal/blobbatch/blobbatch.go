@@ -0,0 +1,139 @@
+// Package blobbatch is the wire format of the batched blob transfers.
+// A batch is a tar stream with an entry for each blob, named after its key.
+// The etag, the metadata and the errors of the blobs travel as PAX records of their entries,
+// so that many small files take a single request instead of one each.
+package blobbatch
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	// ContentType is the media type of the batches
+	ContentType = "application/x-tar"
+
+	// MaxEntrySize is the size of the largest blob the server accepts in a batch.
+	// Batches are held in memory one entry at a time, bigger files are transferred on their own
+	MaxEntrySize = 4 * 1024 * 1024 // 4MB
+
+	paxETag         = "SYFTBOX.etag"
+	paxMetaPrefix   = "SYFTBOX.meta."
+	paxErrorCode    = "SYFTBOX.error.code"
+	paxErrorMessage = "SYFTBOX.error.message"
+)
+
+// Entry describes a blob of a batch. An entry with an error code has no content
+type Entry struct {
+	Key          string
+	ETag         string
+	Size         int64
+	LastModified time.Time
+	Metadata     map[string]string
+
+	ErrorCode    string
+	ErrorMessage string
+}
+
+// Writer writes the entries of a batch
+type Writer struct {
+	tw *tar.Writer
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{tw: tar.NewWriter(w)}
+}
+
+// WriteEntry writes an entry, followed by its content. Entries with an error have no content
+func (w *Writer) WriteEntry(entry *Entry, content io.Reader) error {
+	records := map[string]string{}
+	if entry.ETag != "" {
+		records[paxETag] = entry.ETag
+	}
+	for k, v := range entry.Metadata {
+		records[paxMetaPrefix+k] = v
+	}
+
+	size := entry.Size
+	if entry.ErrorCode != "" {
+		records[paxErrorCode] = entry.ErrorCode
+		records[paxErrorMessage] = entry.ErrorMessage
+		size = 0
+	}
+
+	err := w.tw.WriteHeader(&tar.Header{
+		Typeflag:   tar.TypeReg,
+		Name:       entry.Key,
+		Size:       size,
+		Mode:       0o644,
+		ModTime:    entry.LastModified,
+		Format:     tar.FormatPAX,
+		PAXRecords: records,
+	})
+	if err != nil {
+		return fmt.Errorf("batch entry %q: %w", entry.Key, err)
+	}
+
+	if size == 0 {
+		return nil
+	}
+
+	if _, err := io.CopyN(w.tw, content, size); err != nil {
+		return fmt.Errorf("batch entry %q: %w", entry.Key, err)
+	}
+	return nil
+}
+
+// Close completes the batch. It does not close the underlying writer
+func (w *Writer) Close() error {
+	return w.tw.Close()
+}
+
+// Reader reads the entries of a batch
+type Reader struct {
+	tr *tar.Reader
+}
+
+func NewReader(r io.Reader) *Reader {
+	return &Reader{tr: tar.NewReader(r)}
+}
+
+// Next advances to the next entry. Its content is read from the Reader until the next call.
+// It returns io.EOF at the end of the batch
+func (r *Reader) Next() (*Entry, error) {
+	for {
+		hdr, err := r.tr.Next()
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		entry := &Entry{
+			Key:          hdr.Name,
+			ETag:         hdr.PAXRecords[paxETag],
+			Size:         hdr.Size,
+			LastModified: hdr.ModTime,
+			ErrorCode:    hdr.PAXRecords[paxErrorCode],
+			ErrorMessage: hdr.PAXRecords[paxErrorMessage],
+		}
+		for k, v := range hdr.PAXRecords {
+			if name, ok := strings.CutPrefix(k, paxMetaPrefix); ok {
+				if entry.Metadata == nil {
+					entry.Metadata = make(map[string]string)
+				}
+				entry.Metadata[name] = v
+			}
+		}
+		return entry, nil
+	}
+}
+
+// Read reads the content of the current entry
+func (r *Reader) Read(p []byte) (int, error) {
+	return r.tr.Read(p)
+}
@@ -0,0 +1,60 @@
+package blobbatch
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchRoundTrip(t *testing.T) {
+	modified := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	entries := []*Entry{
+		{Key: "alice@example.com/public/a.txt", ETag: "etag-a", Size: 5, LastModified: modified, Metadata: map[string]string{"syft-executable": "true"}},
+		{Key: "alice@example.com/private/b.txt", ErrorCode: "E_ACCESS_DENIED", ErrorMessage: "access denied"},
+		{Key: "alice@example.com/public/c.txt", ETag: "etag-c", Size: 3, LastModified: modified},
+	}
+	contents := []string{"hello", "", "abc"}
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	for i, entry := range entries {
+		require.NoError(t, w.WriteEntry(entry, strings.NewReader(contents[i])))
+	}
+	require.NoError(t, w.Close())
+
+	r := NewReader(&buf)
+	for i, want := range entries {
+		got, err := r.Next()
+		require.NoError(t, err)
+		if want.ErrorCode != "" {
+			want.Size = 0 // errors have no content
+		}
+		assert.Equal(t, want.Key, got.Key)
+		assert.Equal(t, want.ETag, got.ETag)
+		assert.Equal(t, want.Size, got.Size)
+		assert.Equal(t, want.Metadata, got.Metadata)
+		assert.Equal(t, want.ErrorCode, got.ErrorCode)
+		assert.Equal(t, want.ErrorMessage, got.ErrorMessage)
+		if want.ErrorCode == "" {
+			assert.True(t, want.LastModified.Equal(got.LastModified))
+		}
+
+		content, err := io.ReadAll(r)
+		require.NoError(t, err)
+		assert.Equal(t, contents[i], string(content))
+	}
+
+	_, err := r.Next()
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestBatchShortContent(t *testing.T) {
+	w := NewWriter(io.Discard)
+	err := w.WriteEntry(&Entry{Key: "alice@example.com/a.txt", Size: 10}, strings.NewReader("short"))
+	assert.Error(t, err)
+}
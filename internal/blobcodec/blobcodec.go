@@ -0,0 +1,219 @@
+// Package blobcodec compresses blob content for storage and transfer.
+// The codec of a stored blob is recorded as its Content-Encoding, so that
+// it can be transparently decompressed when it is read back.
+package blobcodec
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// None means the content is stored as-is
+	None = ""
+	// Gzip compresses the content with gzip
+	Gzip = "gzip"
+
+	// DefaultThreshold is the minimum size of a file worth compressing
+	DefaultThreshold = 4 * 1024 // 4KB
+
+	sniffLen = 512
+)
+
+var (
+	ErrUnsupportedCodec = errors.New("unsupported codec")
+)
+
+// already compressed formats, compressing them again only wastes cpu
+var skipExtensions = map[string]struct{}{
+	// archives
+	".gz": {}, ".tgz": {}, ".zip": {}, ".7z": {}, ".rar": {}, ".bz2": {}, ".xz": {}, ".zst": {}, ".br": {}, ".lz4": {},
+	".whl": {}, ".jar": {}, ".apk": {},
+	// images
+	".png": {}, ".jpg": {}, ".jpeg": {}, ".gif": {}, ".webp": {}, ".heic": {}, ".avif": {},
+	// audio & video
+	".mp3": {}, ".m4a": {}, ".aac": {}, ".ogg": {}, ".flac": {}, ".opus": {},
+	".mp4": {}, ".mov": {}, ".mkv": {}, ".webm": {}, ".avi": {},
+	// documents & data
+	".pdf": {}, ".docx": {}, ".xlsx": {}, ".pptx": {}, ".parquet": {},
+	// fonts
+	".woff": {}, ".woff2": {},
+}
+
+// sniffed content types that are already compressed
+var skipContentTypes = []string{
+	"image/",
+	"audio/",
+	"video/",
+	"font/woff",
+	"application/x-gzip",
+	"application/zip",
+	"application/x-rar-compressed",
+	"application/pdf",
+	"application/wasm",
+}
+
+// Validate returns ErrUnsupportedCodec if the codec is not known
+func Validate(codec string) error {
+	switch codec {
+	case None, Gzip:
+		return nil
+	default:
+		return fmt.Errorf("%w: %q", ErrUnsupportedCodec, codec)
+	}
+}
+
+// IsCompressed returns true if the codec compresses content
+func IsCompressed(codec string) bool {
+	return codec != None && Validate(codec) == nil
+}
+
+// ShouldCompress returns true if the file at path is at least threshold bytes
+// and its content is not already compressed, going by its extension and sniffed content type
+func ShouldCompress(path string, threshold int64) bool {
+	if _, skip := skipExtensions[strings.ToLower(filepath.Ext(path))]; skip {
+		return false
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil || info.Size() < threshold {
+		return false
+	}
+
+	buf := make([]byte, sniffLen)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return false
+	}
+
+	contentType := http.DetectContentType(buf[:n])
+	for _, skip := range skipContentTypes {
+		if strings.HasPrefix(contentType, skip) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// NewWriter returns a writer that compresses everything written to w with the codec
+func NewWriter(codec string, w io.Writer) (io.WriteCloser, error) {
+	switch codec {
+	case Gzip:
+		return gzip.NewWriter(w), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedCodec, codec)
+	}
+}
+
+// NewReader returns a reader that decompresses r with the codec
+func NewReader(codec string, r io.Reader) (io.ReadCloser, error) {
+	switch codec {
+	case None:
+		return io.NopCloser(r), nil
+	case Gzip:
+		return gzip.NewReader(r)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedCodec, codec)
+	}
+}
+
+// CompressFile compresses src into a new temp file in dir and returns its path.
+// The caller is responsible for removing the file.
+func CompressFile(codec string, src string, dir string) (string, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+
+	out, err := os.CreateTemp(dir, filepath.Base(src)+".syft.tmp.*")
+	if err != nil {
+		return "", err
+	}
+
+	if err := copyCompressed(codec, out, in); err != nil {
+		out.Close()
+		os.Remove(out.Name())
+		return "", err
+	}
+
+	if err := out.Close(); err != nil {
+		os.Remove(out.Name())
+		return "", err
+	}
+
+	return out.Name(), nil
+}
+
+// DecompressFile decompresses the file at path in place
+func DecompressFile(codec string, path string) error {
+	if !IsCompressed(codec) {
+		return Validate(codec)
+	}
+
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	r, err := NewReader(codec, in)
+	if err != nil {
+		return fmt.Errorf("decompress %q: %w", path, err)
+	}
+	defer r.Close()
+
+	out, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".syft.tmp.*")
+	if err != nil {
+		return err
+	}
+	tmpPath := out.Name()
+
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("decompress %q: %w", path, err)
+	}
+
+	if err := out.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	// close before rename, windows won't replace an open file
+	in.Close()
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	return nil
+}
+
+func copyCompressed(codec string, dst io.Writer, src io.Reader) error {
+	w, err := NewWriter(codec, dst)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(w, src); err != nil {
+		w.Close()
+		return err
+	}
+
+	return w.Close()
+}
@@ -0,0 +1,63 @@
+package blobcodec
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompressFileRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	content := bytes.Repeat([]byte("syftbox compresses text at rest\n"), 1024)
+
+	src := filepath.Join(dir, "data.csv")
+	require.NoError(t, os.WriteFile(src, content, 0o644))
+	require.True(t, ShouldCompress(src, DefaultThreshold))
+
+	compressed, err := CompressFile(Gzip, src, dir)
+	require.NoError(t, err)
+
+	info, err := os.Stat(compressed)
+	require.NoError(t, err)
+	assert.Less(t, info.Size(), int64(len(content)))
+
+	require.NoError(t, DecompressFile(Gzip, compressed))
+	got, err := os.ReadFile(compressed)
+	require.NoError(t, err)
+	assert.Equal(t, content, got)
+}
+
+func TestShouldCompress(t *testing.T) {
+	dir := t.TempDir()
+	text := bytes.Repeat([]byte("a"), DefaultThreshold)
+
+	write := func(name string, content []byte) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, content, 0o644))
+		return path
+	}
+
+	assert.True(t, ShouldCompress(write("notes.txt", text), DefaultThreshold))
+	assert.False(t, ShouldCompress(write("small.txt", []byte("a")), DefaultThreshold), "below threshold")
+	assert.False(t, ShouldCompress(write("archive.zip", text), DefaultThreshold), "compressed extension")
+
+	// png signature, without a telling extension
+	png := append([]byte("\x89PNG\r\n\x1a\n"), text...)
+	assert.False(t, ShouldCompress(write("image", png), DefaultThreshold), "sniffed image")
+
+	assert.False(t, ShouldCompress(filepath.Join(dir, "missing.txt"), DefaultThreshold))
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Validate(None))
+	assert.NoError(t, Validate(Gzip))
+	assert.ErrorIs(t, Validate("lz77"), ErrUnsupportedCodec)
+
+	assert.False(t, IsCompressed(None))
+	assert.True(t, IsCompressed(Gzip))
+	assert.False(t, IsCompressed("lz77"))
+}
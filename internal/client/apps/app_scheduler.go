@@ -8,6 +8,7 @@ import (
 	"maps"
 	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -20,6 +21,15 @@ var (
 	ErrRefreshInProgress = errors.New("scheduler refresh in progress")
 )
 
+// StartupState is how the scheduler started the apps
+type StartupState string
+
+const (
+	StartupWaiting  StartupState = "waiting for initial sync" // the apps start once the datasite synced
+	StartupStarted  StartupState = "started"                  // started after the initial sync, or without waiting for it
+	StartupTimedOut StartupState = "started, sync timed out"  // started before the initial sync completed
+)
+
 type AppScheduler struct {
 	manager    *AppManager
 	configPath string
@@ -27,6 +37,9 @@ type AppScheduler struct {
 	schedWg    sync.WaitGroup
 	schedMu    sync.RWMutex
 	scanMu     sync.Mutex
+	startup    atomic.Value // StartupState, unset until started
+	stopped    chan struct{}
+	stopOnce   sync.Once
 }
 
 func NewAppScheduler(mgr *AppManager, configPath string) *AppScheduler {
@@ -34,9 +47,53 @@ func NewAppScheduler(mgr *AppManager, configPath string) *AppScheduler {
 		manager:    mgr,
 		configPath: configPath,
 		sched:      make(map[string]*App),
+		stopped:    make(chan struct{}),
 	}
 }
 
+// StartAfterSync starts the scheduler once synced is closed, so that the apps see a consistent datasite.
+// The apps start anyway once timeout elapsed, or right away if it is negative. It does not block.
+func (s *AppScheduler) StartAfterSync(ctx context.Context, synced <-chan struct{}, timeout time.Duration) {
+	if timeout < 0 {
+		s.startup.Store(StartupStarted)
+		if err := s.Start(ctx); err != nil {
+			slog.Error("app scheduler", "error", err)
+		}
+		return
+	}
+
+	s.startup.Store(StartupWaiting)
+	slog.Info("scheduler waiting for the initial sync", "timeout", timeout)
+
+	go func() {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+
+		state := StartupStarted
+		select {
+		case <-synced:
+		case <-timer.C:
+			slog.Warn("scheduler starting apps before the initial sync completed", "timeout", timeout)
+			state = StartupTimedOut
+		case <-ctx.Done():
+			return
+		case <-s.stopped:
+			return
+		}
+
+		s.startup.Store(state)
+		if err := s.Start(ctx); err != nil {
+			slog.Error("app scheduler", "error", err)
+		}
+	}()
+}
+
+// Startup returns how the scheduler started the apps, empty if it was not started
+func (s *AppScheduler) Startup() StartupState {
+	state, _ := s.startup.Load().(StartupState)
+	return state
+}
+
 // Start the scheduler
 func (s *AppScheduler) Start(ctx context.Context) error {
 	slog.Info("scheduler start", "appdir", s.manager.AppsDir)
@@ -66,11 +123,28 @@ func (s *AppScheduler) Start(ctx context.Context) error {
 }
 
 func (s *AppScheduler) Refresh() error {
+	// the apps installed in the meantime are picked up once started
+	if s.Startup() == StartupWaiting {
+		return nil
+	}
+	return s.scanApps()
+}
+
+// Reschedule stops an app and starts it again from its installed files, e.g. once it was updated
+func (s *AppScheduler) Reschedule(appID string) error {
+	if s.Startup() == StartupWaiting {
+		return nil
+	}
+	if err := s.removeApp(appID); err != nil && !errors.Is(err, ErrAppNotFound) {
+		return err
+	}
 	return s.scanApps()
 }
 
 // Stop the scheduler
 func (s *AppScheduler) Stop() {
+	s.stopOnce.Do(func() { close(s.stopped) })
+
 	s.schedMu.Lock()
 	defer s.schedMu.Unlock()
 
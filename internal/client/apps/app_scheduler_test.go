@@ -0,0 +1,61 @@
+package apps
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestScheduler(t *testing.T) *AppScheduler {
+	t.Helper()
+	dir := t.TempDir()
+	sched := NewAppScheduler(NewManager(filepath.Join(dir, "apps"), filepath.Join(dir, ".data")), "")
+	t.Cleanup(sched.Stop)
+	return sched
+}
+
+func TestStartAfterSyncWaitsForInitialSync(t *testing.T) {
+	sched := newTestScheduler(t)
+	synced := make(chan struct{})
+
+	sched.StartAfterSync(context.Background(), synced, time.Minute)
+	assert.Equal(t, StartupWaiting, sched.Startup())
+
+	// refreshes don't start apps before the sync
+	require.NoError(t, sched.Refresh())
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, StartupWaiting, sched.Startup())
+
+	close(synced)
+	assert.Eventually(t, func() bool { return sched.Startup() == StartupStarted }, time.Second, 10*time.Millisecond)
+}
+
+func TestStartAfterSyncTimeout(t *testing.T) {
+	sched := newTestScheduler(t)
+
+	sched.StartAfterSync(context.Background(), make(chan struct{}), 100*time.Millisecond)
+	assert.Equal(t, StartupWaiting, sched.Startup())
+
+	assert.Eventually(t, func() bool { return sched.Startup() == StartupTimedOut }, time.Second, 10*time.Millisecond)
+}
+
+func TestStartAfterSyncNoWait(t *testing.T) {
+	sched := newTestScheduler(t)
+
+	sched.StartAfterSync(context.Background(), make(chan struct{}), -1)
+	assert.Equal(t, StartupStarted, sched.Startup())
+}
+
+func TestStartAfterSyncStopped(t *testing.T) {
+	sched := newTestScheduler(t)
+
+	sched.StartAfterSync(context.Background(), make(chan struct{}), 50*time.Millisecond)
+	sched.Stop()
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, StartupWaiting, sched.Startup(), "a stopped scheduler doesn't start the apps")
+}
@@ -6,10 +6,20 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"maps"
+	"net"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/openmined/syftbox/internal/blobbatch"
+	"github.com/openmined/syftbox/internal/blobcodec"
 	"github.com/openmined/syftbox/internal/utils"
 )
 
@@ -21,11 +31,52 @@ var (
 	DefaultClientURL   = "http://localhost:7938"
 	DefaultLogFilePath = filepath.Join(home, ".syftbox", "logs", "syftbox.log")
 	DefaultAppsEnabled = true
+	// how long the client waits for active sync operations on shutdown, before aborting them
+	DefaultShutdownTimeout = 15 * time.Second
+	// how long the apps wait for the initial sync on startup, before starting anyway
+	DefaultAppsSyncTimeout = 2 * time.Minute
+	// Finder hides dotfiles and macOS scatters its own, so they aren't synced there unless asked for
+	DefaultSyncIncludeHidden = runtime.GOOS != "darwin"
+	// the log file is rotated past the max size, and the rotated files kept for as many backups and as long as the max age
+	DefaultLogMaxSize    = 10 // MB
+	DefaultLogMaxBackups = 5
+	DefaultLogMaxAge     = 30 * 24 * time.Hour
 )
 
+// VerifyHookConfig runs Command on the downloaded files matching Pattern, with the path of the file as last argument.
+// A non-zero exit, or running longer than Timeout, rejects the file
+type VerifyHookConfig struct {
+	Pattern string   `json:"pattern" mapstructure:"pattern"` // gitignore pattern, relative to the datasites dir
+	Command []string `json:"command" mapstructure:"command"`
+	Timeout string   `json:"timeout,omitempty" mapstructure:"timeout,omitempty"` // e.g. 10s. empty uses the default
+}
+
+// TimeoutDuration is the parsed timeout of the hook, zero for the default
+func (h VerifyHookConfig) TimeoutDuration() time.Duration {
+	timeout, _ := time.ParseDuration(h.Timeout)
+	return timeout
+}
+
+// What to do when the server speaks another wire protocol version than the client
+const (
+	ProtocolMismatchWarn    = "warn"    // log it and carry on
+	ProtocolMismatchRefuse  = "refuse"  // do not start
+	ProtocolMismatchDegrade = "degrade" // turn off the features the server does not support
+)
+
+// ProfilesKey is the config file key of the named profiles
+const ProfilesKey = "profiles"
+
 var (
-	ErrInvalidURL   = errors.New("invalid url")
-	ErrInvalidEmail = utils.ErrInvalidEmail
+	ErrInvalidURL     = errors.New("invalid url")
+	ErrInvalidEmail   = utils.ErrInvalidEmail
+	ErrInvalidProfile = errors.New("invalid profile name")
+	ErrUnknownProfile = errors.New("unknown profile")
+)
+
+var (
+	// lowercase, since viper lowercases config keys
+	regexProfileName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
 )
 
 type Config struct {
@@ -36,10 +87,69 @@ type Config struct {
 	ClientToken  string `json:"client_token,omitempty" mapstructure:"client_token,omitempty"`
 	RefreshToken string `json:"refresh_token,omitempty" mapstructure:"refresh_token,omitempty"`
 
+	// compress blobs on upload. empty codec disables compression
+	CompressionCodec     string `json:"compression_codec,omitempty" mapstructure:"compression_codec,omitempty"`
+	CompressionThreshold int64  `json:"compression_threshold,omitempty" mapstructure:"compression_threshold,omitempty"`
+
+	// preserve the executable bit and these extended attributes of synced files
+	SyncExecutable bool     `json:"sync_executable,omitempty" mapstructure:"sync_executable,omitempty"`
+	SyncXattrs     []string `json:"sync_xattrs,omitempty" mapstructure:"sync_xattrs,omitempty"`
+
+	// sync dotfiles and hidden dirs. unset uses the platform default
+	SyncIncludeHidden *bool `json:"sync_include_hidden,omitempty" mapstructure:"sync_include_hidden,omitempty"`
+
+	// leave files the server rejects in place instead of moving them aside. they are not uploaded again until changed
+	SyncKeepRejected bool `json:"sync_keep_rejected,omitempty" mapstructure:"sync_keep_rejected,omitempty"`
+
+	// concurrent transfers, and the batching of files under the threshold, BatchSize per request.
+	// 0 uses the defaults, a negative threshold turns batching off
+	SyncWorkers        int   `json:"sync_workers,omitempty" mapstructure:"sync_workers,omitempty"`
+	SyncBatchThreshold int64 `json:"sync_batch_threshold,omitempty" mapstructure:"sync_batch_threshold,omitempty"`
+	SyncBatchSize      int   `json:"sync_batch_size,omitempty" mapstructure:"sync_batch_size,omitempty"`
+
+	// uploads again of a file that changed during its upload, before leaving it to the next sync.
+	// 0 uses the default, negative never uploads it again
+	SyncUploadRequeue int `json:"sync_upload_requeue,omitempty" mapstructure:"sync_upload_requeue,omitempty"`
+
+	// only follow the remote changes and never upload. local edits are left out of the sync
+	SyncReadOnly bool `json:"sync_read_only,omitempty" mapstructure:"sync_read_only,omitempty"`
+
+	// do not reconnect and resync as soon as the system resumes from sleep
+	DisableResumeResync bool `json:"disable_resume_resync,omitempty" mapstructure:"disable_resume_resync,omitempty"`
+
+	// do not reconnect and resync as soon as the network addresses change, e.g. when switching networks
+	DisableNetworkResync bool `json:"disable_network_resync,omitempty" mapstructure:"disable_network_resync,omitempty"`
+
+	// run these commands on the matching downloads before syncing them, and quarantine the files they reject
+	SyncVerifyHooks []VerifyHookConfig `json:"sync_verify_hooks,omitempty" mapstructure:"sync_verify_hooks,omitempty"`
+
+	// keep a plain copy of the synced datasites in this dir, for external tools. empty disables it
+	ExportDir string `json:"export_dir,omitempty" mapstructure:"export_dir,omitempty"`
+
+	// install the apps listed in this manifest once approved with `syftbox app approve`.
+	// it must be outside the data dir, so that it isn't synced. empty disables it
+	AppManifest string `json:"app_manifest,omitempty" mapstructure:"app_manifest,omitempty"`
+
+	// what to do when the server speaks another protocol version. empty warns
+	ProtocolMismatch string `json:"protocol_mismatch,omitempty" mapstructure:"protocol_mismatch,omitempty"`
+
 	// do not persist, keep in memory
-	AppsEnabled bool   `json:"-" mapstructure:"apps_enabled"`
-	AccessToken string `json:"-" mapstructure:"access_token"`
-	Path        string `json:"-" mapstructure:"config_path"`
+	AppsEnabled     bool          `json:"-" mapstructure:"apps_enabled"`
+	AccessToken     string        `json:"-" mapstructure:"access_token"`
+	Path            string        `json:"-" mapstructure:"config_path"`
+	ShutdownTimeout time.Duration `json:"-" mapstructure:"shutdown_timeout"`
+	AppsSyncTimeout time.Duration `json:"-" mapstructure:"apps_sync_timeout"` // negative starts the apps without waiting
+
+	// the profile in the config file this config was loaded from. empty is the top level config
+	Profile string `json:"-" mapstructure:"profile"`
+}
+
+// IncludeHidden reports whether dotfiles and hidden dirs are synced
+func (c *Config) IncludeHidden() bool {
+	if c.SyncIncludeHidden == nil {
+		return DefaultSyncIncludeHidden
+	}
+	return *c.SyncIncludeHidden
 }
 
 func (c *Config) Save() error {
@@ -52,9 +162,58 @@ func (c *Config) Save() error {
 		return err
 	}
 
+	data, err = c.mergeProfiles(data)
+	if err != nil {
+		return err
+	}
+
 	return os.WriteFile(c.Path, data, 0o644)
 }
 
+// mergeProfiles places the config in its profile of the existing config file,
+// and keeps the other profiles around
+func (c *Config) mergeProfiles(data []byte) ([]byte, error) {
+	var file map[string]json.RawMessage
+	if existing, err := os.ReadFile(c.Path); err == nil {
+		// a broken config file is overwritten, like it was before profiles
+		_ = json.Unmarshal(existing, &file)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	var profiles map[string]json.RawMessage
+	if raw, ok := file[ProfilesKey]; ok {
+		_ = json.Unmarshal(raw, &profiles)
+	}
+
+	if c.Profile == "" {
+		if len(profiles) == 0 {
+			return data, nil
+		}
+		// replace the top level config
+		file = nil
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, err
+		}
+	} else {
+		if file == nil {
+			file = make(map[string]json.RawMessage)
+		}
+		if profiles == nil {
+			profiles = make(map[string]json.RawMessage)
+		}
+		profiles[c.Profile] = data
+	}
+
+	raw, err := json.Marshal(profiles)
+	if err != nil {
+		return nil, err
+	}
+	file[ProfilesKey] = raw
+
+	return json.Marshal(file)
+}
+
 func (c *Config) Validate() error {
 	if c.Path == "" {
 		c.Path = DefaultConfigPath
@@ -74,6 +233,12 @@ func (c *Config) Validate() error {
 	}
 	c.DataDir = dataDir
 
+	if c.Profile != "" {
+		if err := ValidateProfileName(c.Profile); err != nil {
+			return err
+		}
+	}
+
 	// validate email
 	c.Email = strings.ToLower(c.Email)
 	if err := utils.ValidateEmail(c.Email); err != nil {
@@ -92,6 +257,85 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	// validate compression
+	if err := blobcodec.Validate(c.CompressionCodec); err != nil {
+		return fmt.Errorf("compression codec: %w", err)
+	}
+
+	if c.CompressionThreshold < 0 {
+		return fmt.Errorf("compression threshold: must be positive")
+	}
+
+	if c.SyncWorkers < 0 {
+		return fmt.Errorf("sync workers: must be positive")
+	}
+
+	if c.SyncBatchSize < 0 {
+		return fmt.Errorf("sync batch size: must be positive")
+	}
+
+	if c.SyncBatchThreshold > blobbatch.MaxEntrySize {
+		return fmt.Errorf("sync batch threshold: must be at most %d bytes", blobbatch.MaxEntrySize)
+	}
+
+	if c.ExportDir != "" {
+		exportDir, err := utils.ResolvePath(c.ExportDir)
+		if err != nil {
+			return fmt.Errorf("export dir: %w", err)
+		}
+		if isSubpath(exportDir, c.DataDir) || isSubpath(c.DataDir, exportDir) {
+			return fmt.Errorf("export dir: must be outside the data dir")
+		}
+		c.ExportDir = exportDir
+	}
+
+	if c.AppManifest != "" {
+		appManifest, err := utils.ResolvePath(c.AppManifest)
+		if err != nil {
+			return fmt.Errorf("app manifest: %w", err)
+		}
+		if isSubpath(appManifest, c.DataDir) {
+			return fmt.Errorf("app manifest: must be outside the data dir")
+		}
+		c.AppManifest = appManifest
+	}
+
+	switch c.ProtocolMismatch {
+	case "", ProtocolMismatchWarn, ProtocolMismatchRefuse, ProtocolMismatchDegrade:
+	default:
+		return fmt.Errorf("protocol mismatch: must be one of %s, %s or %s", ProtocolMismatchWarn, ProtocolMismatchRefuse, ProtocolMismatchDegrade)
+	}
+
+	if c.ShutdownTimeout < 0 {
+		return fmt.Errorf("shutdown timeout: must be positive")
+	}
+	if c.ShutdownTimeout == 0 {
+		c.ShutdownTimeout = DefaultShutdownTimeout
+	}
+	if c.AppsSyncTimeout == 0 {
+		c.AppsSyncTimeout = DefaultAppsSyncTimeout
+	}
+
+	for _, name := range c.SyncXattrs {
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("sync xattrs: empty name")
+		}
+	}
+
+	for i, hook := range c.SyncVerifyHooks {
+		if strings.TrimSpace(hook.Pattern) == "" {
+			return fmt.Errorf("sync verify hooks[%d]: empty pattern", i)
+		}
+		if len(hook.Command) == 0 || hook.Command[0] == "" {
+			return fmt.Errorf("sync verify hooks[%d]: empty command", i)
+		}
+		if hook.Timeout != "" {
+			if timeout, err := time.ParseDuration(hook.Timeout); err != nil || timeout <= 0 {
+				return fmt.Errorf("sync verify hooks[%d]: invalid timeout %q: expected a positive duration like 10s", i, hook.Timeout)
+			}
+		}
+	}
+
 	// do not validate refresh token... it can be empty for local dev.
 
 	return nil
@@ -104,13 +348,40 @@ func (c Config) LogValue() slog.Value {
 		slog.String("server_url", c.ServerURL),
 		slog.String("client_url", c.ClientURL),
 		slog.Bool("apps_enabled", c.AppsEnabled),
+		slog.String("compression_codec", c.CompressionCodec),
+		slog.Int64("compression_threshold", c.CompressionThreshold),
+		slog.Bool("sync_executable", c.SyncExecutable),
+		slog.Any("sync_xattrs", c.SyncXattrs),
+		slog.Bool("sync_include_hidden", c.IncludeHidden()),
+		slog.Bool("sync_keep_rejected", c.SyncKeepRejected),
+		slog.Int("sync_workers", c.SyncWorkers),
+		slog.Int64("sync_batch_threshold", c.SyncBatchThreshold),
+		slog.Int("sync_batch_size", c.SyncBatchSize),
+		slog.Int("sync_upload_requeue", c.SyncUploadRequeue),
+		slog.Bool("sync_read_only", c.SyncReadOnly),
+		slog.Bool("disable_resume_resync", c.DisableResumeResync),
+		slog.Bool("disable_network_resync", c.DisableNetworkResync),
+		slog.Int("sync_verify_hooks", len(c.SyncVerifyHooks)),
+		slog.String("export_dir", c.ExportDir),
+		slog.String("app_manifest", c.AppManifest),
+		slog.String("protocol_mismatch", c.ProtocolMismatch),
+		slog.Duration("shutdown_timeout", c.ShutdownTimeout),
+		slog.Duration("apps_sync_timeout", c.AppsSyncTimeout),
+		slog.Bool("client_token", c.ClientToken != ""),
 		slog.Bool("refresh_token", c.RefreshToken != ""),
 		slog.Bool("access_token", c.AccessToken != ""),
 		slog.String("path", c.Path),
+		slog.String("profile", c.Profile),
 	)
 }
 
 func LoadFromFile(path string) (*Config, error) {
+	return LoadProfileFromFile(path, "")
+}
+
+// LoadProfileFromFile loads a named profile of the config file, merged over its top level config.
+// An empty profile loads the top level config.
+func LoadProfileFromFile(path string, profile string) (*Config, error) {
 	path, err := utils.ResolvePath(path)
 	if err != nil {
 		return nil, err
@@ -122,10 +393,14 @@ func LoadFromFile(path string) (*Config, error) {
 	}
 	defer data.Close()
 
-	return LoadFromReader(path, data)
+	return loadFromReader(path, data, profile)
 }
 
 func LoadFromReader(path string, reader io.ReadCloser) (*Config, error) {
+	return loadFromReader(path, reader, "")
+}
+
+func loadFromReader(path string, reader io.Reader, profile string) (*Config, error) {
 	data, err := io.ReadAll(reader)
 	if err != nil {
 		return nil, err
@@ -136,8 +411,73 @@ func LoadFromReader(path string, reader io.ReadCloser) (*Config, error) {
 		return nil, err
 	}
 
+	if profile != "" {
+		if err := ValidateProfileName(profile); err != nil {
+			return nil, err
+		}
+
+		var file struct {
+			Profiles map[string]json.RawMessage `json:"profiles"`
+		}
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, err
+		}
+
+		raw, ok := file.Profiles[profile]
+		if !ok {
+			return nil, UnknownProfileError(profile, slices.Collect(maps.Keys(file.Profiles)))
+		}
+		// fields set in the profile override the top level ones
+		if err := json.Unmarshal(raw, &cfg); err != nil {
+			return nil, fmt.Errorf("profile %q: %w", profile, err)
+		}
+	}
+
 	cfg.Path = path
+	cfg.Profile = profile
 	cfg.AppsEnabled = true
 
 	return &cfg, nil
 }
+
+// ValidateProfileName checks that a profile name can be used as a config key
+func ValidateProfileName(name string) error {
+	if !regexProfileName.MatchString(name) {
+		return fmt.Errorf("%w %q: must be lowercase letters, digits, '-' or '_'", ErrInvalidProfile, name)
+	}
+	return nil
+}
+
+// UnknownProfileError reports a profile missing from the config file, along with the ones that exist
+func UnknownProfileError(name string, available []string) error {
+	if len(available) == 0 {
+		return fmt.Errorf("%w %q: the config file has no profiles", ErrUnknownProfile, name)
+	}
+	slices.Sort(available)
+	return fmt.Errorf("%w %q: available profiles are %s", ErrUnknownProfile, name, strings.Join(available, ", "))
+}
+
+// LogFilePathForAddr is the log file of the daemon bound to addr. Only the daemon on the default port
+// logs to the default log file, the others log to their own, named after their port, so that several
+// daemons can run side by side. A daemon on a random port doesn't know it yet, its file is named after its pid.
+func LogFilePathForAddr(addr string) string {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil || port == "" {
+		return DefaultLogFilePath
+	}
+	if u, err := url.Parse(DefaultClientURL); err == nil && u.Port() == port {
+		return DefaultLogFilePath
+	}
+	if port == "0" {
+		port = "pid" + strconv.Itoa(os.Getpid())
+	}
+
+	ext := filepath.Ext(DefaultLogFilePath)
+	return strings.TrimSuffix(DefaultLogFilePath, ext) + "-" + port + ext
+}
+
+// isSubpath reports whether path is base or inside it
+func isSubpath(path string, base string) bool {
+	rel, err := filepath.Rel(base, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -20,6 +20,7 @@ type CPServer struct {
 	server      *http.Server
 	datasiteMgr *datasitemgr.DatasiteManager
 	url         string
+	events      *EventSocket // nil without an event socket
 }
 
 func NewControlPlaneServer(config *CPServerConfig, datasiteMgr *datasitemgr.DatasiteManager) (*CPServer, error) {
@@ -40,6 +41,7 @@ func NewControlPlaneServer(config *CPServerConfig, datasiteMgr *datasitemgr.Data
 	routes := SetupRoutes(datasiteMgr, &RouteConfig{
 		Swagger:         config.EnableSwagger,
 		ControlPlaneURL: cpURL,
+		LogFilePath:     config.LogFilePath,
 		Auth: middleware.TokenAuthConfig{
 			Token: config.AuthToken,
 		},
@@ -57,16 +59,25 @@ func NewControlPlaneServer(config *CPServerConfig, datasiteMgr *datasitemgr.Data
 		MaxHeaderBytes: 1 << 20, // 1 MB
 	}
 
-	return &CPServer{
+	cps := &CPServer{
 		config:      config,
 		server:      httpServer,
 		datasiteMgr: datasiteMgr,
 		url:         cpURL,
-	}, nil
+	}
+	if config.EventSocketPath != "" {
+		cps.events = NewEventSocket(config.EventSocketPath, datasiteMgr)
+	}
+	return cps, nil
 }
 
 func (s *CPServer) Start(ctx context.Context) error {
 	slog.Info("control plane start", "addr", s.url, "token", s.config.AuthToken)
+	if s.events != nil {
+		if err := s.events.Start(ctx); err != nil {
+			return fmt.Errorf("failed to start event socket: %w", err)
+		}
+	}
 	if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 		return fmt.Errorf("failed to start server: %w", err)
 	}
@@ -76,6 +87,11 @@ func (s *CPServer) Start(ctx context.Context) error {
 
 func (s *CPServer) Stop(ctx context.Context) error {
 	slog.Info("control plane stop")
+	if s.events != nil {
+		if err := s.events.Stop(); err != nil {
+			slog.Warn("event socket stop", "error", err)
+		}
+	}
 	return s.server.Shutdown(ctx)
 }
 
@@ -5,4 +5,7 @@ type CPServerConfig struct {
 	Addr          string // Address to bind the control plane server
 	AuthToken     string // Access token for the control plane server
 	EnableSwagger bool   // EnableSwagger enables Swagger documentation
+	LogFilePath   string // LogFilePath is the log file of this daemon, served by the logs api
+
+	EventSocketPath string // EventSocketPath is the local socket streaming the daemon events, disabled if empty
 }
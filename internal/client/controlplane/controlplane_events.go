@@ -0,0 +1,201 @@
+package controlplane
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	gosync "sync"
+	"time"
+
+	"github.com/openmined/syftbox/internal/client/datasite"
+	"github.com/openmined/syftbox/internal/client/datasitemgr"
+	"github.com/openmined/syftbox/internal/client/handlers"
+	"github.com/openmined/syftbox/internal/client/sync"
+	"github.com/openmined/syftbox/internal/utils"
+)
+
+const (
+	// a tool that stops reading is disconnected after this long, rather than holding up its events
+	eventSocketWriteTimeout = 10 * time.Second
+	// how often the connections check for a datasite provisioned since they connected
+	eventSocketRecheck = time.Second
+)
+
+// Types of the events of the event socket
+const (
+	// the connection receives the events of the datasite from now on. Sent again when the datasite changes
+	SocketEventReady = "ready"
+	// a sync status change, with the SyncEvent of the sync event stream
+	SocketEventSync = "sync"
+)
+
+// SocketEvent is a line of the event socket
+type SocketEvent struct {
+	Type  string              `json:"type"`
+	Event *handlers.SyncEvent `json:"event,omitempty"`
+}
+
+// EventSocket streams the daemon events as JSON lines over a local socket, for scripts to react to them without
+// polling the control plane. Like the sync event stream, a connection that falls behind misses events rather than
+// slowing down the sync. The socket is only accessible to the user running the daemon, and is a unix socket on
+// every platform, which windows supports since windows 10.
+type EventSocket struct {
+	path     string
+	mgr      *datasitemgr.DatasiteManager
+	listener net.Listener
+	cancel   context.CancelFunc
+	wg       gosync.WaitGroup
+}
+
+func NewEventSocket(path string, mgr *datasitemgr.DatasiteManager) *EventSocket {
+	return &EventSocket{
+		path: path,
+		mgr:  mgr,
+	}
+}
+
+// Start listens on the socket and serves the connections in the background, until ctx is done or Stop is called
+func (s *EventSocket) Start(ctx context.Context) error {
+	if err := utils.EnsureParent(s.path); err != nil {
+		return err
+	}
+
+	// a socket left behind by a daemon that didn't stop cleanly, unless another daemon still listens on it
+	if conn, err := net.Dial("unix", s.path); err == nil {
+		conn.Close()
+		return fmt.Errorf("event socket %s in use by another daemon", s.path)
+	}
+	if err := os.Remove(s.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("remove stale event socket: %w", err)
+	}
+
+	listener, err := net.Listen("unix", s.path)
+	if err != nil {
+		return fmt.Errorf("listen on event socket: %w", err)
+	}
+	if err := os.Chmod(s.path, 0o600); err != nil {
+		listener.Close()
+		return fmt.Errorf("restrict event socket: %w", err)
+	}
+
+	ctx, s.cancel = context.WithCancel(ctx)
+	s.listener = listener
+	slog.Info("event socket start", "path", s.path)
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.acceptLoop(ctx)
+	}()
+
+	// stop accepting once ctx is done
+	context.AfterFunc(ctx, func() { listener.Close() })
+	return nil
+}
+
+// Stop closes the socket and disconnects the tools
+func (s *EventSocket) Stop() error {
+	if s.listener == nil {
+		return nil
+	}
+	s.cancel()
+	s.wg.Wait()
+	slog.Info("event socket stop")
+	if err := os.Remove(s.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *EventSocket) acceptLoop(ctx context.Context) {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if ctx.Err() == nil {
+				slog.Error("event socket accept", "error", err)
+			}
+			return
+		}
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.serve(ctx, conn)
+		}()
+	}
+}
+
+// serve streams the events of the current datasite to the connection, until either side closes it
+func (s *EventSocket) serve(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	context.AfterFunc(ctx, func() { conn.Close() })
+
+	// nothing is read from the tools, the read returns once they disconnect
+	go func() {
+		io.Copy(io.Discard, conn)
+		cancel()
+	}()
+
+	var ds *datasite.Datasite
+	var syncStatus *sync.SyncStatus
+	var events <-chan *sync.SyncStatusEvent
+	defer func() {
+		if events != nil {
+			syncStatus.Unsubscribe(events)
+		}
+	}()
+
+	enc := json.NewEncoder(conn)
+	send := func(event *SocketEvent) bool {
+		conn.SetWriteDeadline(time.Now().Add(eventSocketWriteTimeout))
+		return enc.Encode(event) == nil
+	}
+
+	// follows the datasite, which is replaced when the daemon is provisioned again
+	subscribe := func() bool {
+		current, err := s.mgr.Get()
+		if err != nil || current == ds {
+			return true
+		}
+		if events != nil {
+			syncStatus.Unsubscribe(events)
+		}
+		ds = current
+		syncStatus = ds.GetSyncManager().GetSyncStatus()
+		events = syncStatus.Subscribe()
+		return send(&SocketEvent{Type: SocketEventReady})
+	}
+
+	recheck := time.NewTicker(eventSocketRecheck)
+	defer recheck.Stop()
+
+	if !subscribe() {
+		return
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-recheck.C:
+			if !subscribe() {
+				return
+			}
+		case event, ok := <-events:
+			if !ok {
+				events = nil
+				continue
+			}
+			if !send(&SocketEvent{Type: SocketEventSync, Event: handlers.NewSyncEvent(ds.GetWorkspace(), event)}) {
+				return
+			}
+		}
+	}
+}
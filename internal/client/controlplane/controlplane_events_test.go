@@ -0,0 +1,105 @@
+package controlplane
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/openmined/syftbox/internal/client/config"
+	"github.com/openmined/syftbox/internal/client/datasite"
+	"github.com/openmined/syftbox/internal/client/datasitemgr"
+	"github.com/openmined/syftbox/internal/client/sync"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestEventSocket(t *testing.T) (*EventSocket, *datasite.Datasite) {
+	t.Helper()
+
+	dataDir := t.TempDir()
+	ds, err := datasite.New(&config.Config{
+		DataDir:   dataDir,
+		Email:     "alice@example.com",
+		ServerURL: "http://localhost:1",
+		Path:      filepath.Join(dataDir, "config.json"),
+	})
+	require.NoError(t, err)
+
+	// the socket paths are limited to about 100 bytes, shorter than some temp dirs
+	sockDir, err := os.MkdirTemp("", "syftbox")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(sockDir) })
+
+	s := NewEventSocket(filepath.Join(sockDir, "events.sock"), datasitemgr.New(datasitemgr.WithDatasite(ds)))
+	require.NoError(t, s.Start(context.Background()))
+	t.Cleanup(func() { s.Stop() })
+	return s, ds
+}
+
+func readSocketEvent(t *testing.T, conn net.Conn, scanner *bufio.Scanner) *SocketEvent {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	require.True(t, scanner.Scan(), scanner.Err())
+	var event SocketEvent
+	require.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+	return &event
+}
+
+func TestEventSocketStreamsSyncEventsInOrder(t *testing.T) {
+	s, ds := newTestEventSocket(t)
+
+	conn, err := net.Dial("unix", s.path)
+	require.NoError(t, err)
+	defer conn.Close()
+	scanner := bufio.NewScanner(conn)
+
+	// subscribed from here on
+	assert.Equal(t, SocketEventReady, readSocketEvent(t, conn, scanner).Type)
+
+	status := ds.GetSyncManager().GetSyncStatus()
+	path := sync.SyncPath("alice@example.com/public/report.csv")
+	status.SetSyncing(path)
+	status.SetCompleted(path)
+	status.SetConflicted(path)
+	status.SetError(path, errors.New("disk full"))
+
+	var states [][2]string
+	for range 4 {
+		event := readSocketEvent(t, conn, scanner)
+		require.Equal(t, SocketEventSync, event.Type)
+		require.NotNil(t, event.Event)
+		assert.Equal(t, "/datasites/alice@example.com/public/report.csv", event.Event.Path)
+		states = append(states, [2]string{event.Event.SyncState, event.Event.ConflictState})
+	}
+	assert.Equal(t, [][2]string{
+		{"syncing", "none"},
+		{"completed", "none"},
+		{"completed", "conflicted"},
+		{"error", "conflicted"},
+	}, states)
+}
+
+func TestEventSocketStop(t *testing.T) {
+	s, _ := newTestEventSocket(t)
+
+	conn, err := net.Dial("unix", s.path)
+	require.NoError(t, err)
+	defer conn.Close()
+	scanner := bufio.NewScanner(conn)
+	assert.Equal(t, SocketEventReady, readSocketEvent(t, conn, scanner).Type)
+
+	// another daemon can't take over the socket in use
+	assert.ErrorContains(t, NewEventSocket(s.path, s.mgr).Start(context.Background()), "in use")
+
+	// the tools are disconnected, and the socket removed
+	require.NoError(t, s.Stop())
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	assert.False(t, scanner.Scan())
+	assert.NoFileExists(t, s.path)
+}
@@ -34,6 +34,7 @@ type RouteConfig struct {
 	Auth            middleware.TokenAuthConfig
 	ControlPlaneURL string
 	Swagger         bool
+	LogFilePath     string
 }
 
 func SetupRoutes(datasiteMgr *datasitemgr.DatasiteManager, routeConfig *RouteConfig) http.Handler {
@@ -45,12 +46,13 @@ func SetupRoutes(datasiteMgr *datasitemgr.DatasiteManager, routeConfig *RouteCon
 		Limit:  10,
 	})
 
-	// syncH := handlers.NewSyncHandler(datasiteMgr)
+	syncH := handlers.NewSyncHandler(datasiteMgr)
 	appH := handlers.NewAppHandler(datasiteMgr)
 	initH := handlers.NewInitHandler(datasiteMgr, routeConfig.ControlPlaneURL)
 	statusH := handlers.NewStatusHandler(datasiteMgr)
 	workspaceH := handlers.NewWorkspaceHandler(datasiteMgr)
-	logsH := handlers.NewLogsHandler(datasiteMgr)
+	logsH := handlers.NewLogsHandler(datasiteMgr, routeConfig.LogFilePath)
+	crdtH := handlers.NewCRDTHandler(datasiteMgr)
 
 	r.Use(gin.Recovery())
 	r.Use(middleware.CORS())
@@ -86,23 +88,42 @@ func SetupRoutes(datasiteMgr *datasitemgr.DatasiteManager, routeConfig *RouteCon
 		{
 			v1Workspace.GET("/items", workspaceH.GetItems)
 			v1Workspace.POST("/items", workspaceH.CreateItem)
+			v1Workspace.POST("/items/batch", workspaceH.CreateItems)
 			v1Workspace.DELETE("/items", workspaceH.DeleteItems)
 			v1Workspace.POST("/items/move", workspaceH.MoveItems)
 			v1Workspace.POST("/items/copy", workspaceH.CopyItems)
 			v1Workspace.GET("/content", workspaceH.GetContent)
 			v1Workspace.PUT("/content", workspaceH.UpdateContent)
+			v1Workspace.GET("/search", workspaceH.SearchItems)
+			v1Workspace.POST("/acl/preview", workspaceH.PreviewACL)
+			v1Workspace.POST("/upload", workspaceH.Upload)
+			v1Workspace.POST("/uploads", workspaceH.CreateUpload)
+			v1Workspace.GET("/uploads/:id", workspaceH.GetUpload)
+			v1Workspace.PATCH("/uploads/:id", workspaceH.UploadChunk)
+			v1Workspace.DELETE("/uploads/:id", workspaceH.DeleteUpload)
+			v1Workspace.POST("/uploads/:id/complete", workspaceH.CompleteUpload)
+			v1Workspace.GET("/trash", workspaceH.GetTrash)
+			v1Workspace.POST("/trash/restore", workspaceH.RestoreTrash)
+			v1Workspace.DELETE("/trash", workspaceH.EmptyTrash)
 		}
 
 		// Logs endpoint
 		v1.GET("/logs", logsH.GetLogs)
 		v1.GET("/logs/download", logsH.DownloadLogs)
 
-		// v1Sync := v1.Group("/sync")
-		// {
-		// 	v1Sync.GET("/status", syncH.Status)
-		// 	v1Sync.GET("/events", syncH.Events)
-		// 	v1Sync.GET("/now", syncH.Now)
-		// }
+		v1CRDT := v1.Group("/crdt")
+		{
+			v1CRDT.GET("", crdtH.Get)
+			v1CRDT.POST("/counter/increment", crdtH.Increment)
+			v1CRDT.POST("/set/add", crdtH.AddElement)
+			v1CRDT.POST("/set/remove", crdtH.RemoveElement)
+		}
+
+		v1Sync := v1.Group("/sync")
+		{
+			v1Sync.GET("/events", syncH.Events)
+			v1Sync.GET("/metrics", syncH.Metrics)
+		}
 	}
 
 	if routeConfig.Swagger {
@@ -10,6 +10,7 @@ import (
 	"github.com/openmined/syftbox/internal/client/config"
 	"github.com/openmined/syftbox/internal/client/sync"
 	"github.com/openmined/syftbox/internal/client/workspace"
+	"github.com/openmined/syftbox/internal/fileattr"
 	"github.com/openmined/syftbox/internal/syftsdk"
 	"github.com/openmined/syftbox/internal/utils"
 )
@@ -39,6 +40,9 @@ func New(config *config.Config) (*Datasite, error) {
 		Email:        config.Email,
 		RefreshToken: config.RefreshToken,
 		AccessToken:  config.AccessToken,
+
+		CompressionCodec:     config.CompressionCodec,
+		CompressionThreshold: config.CompressionThreshold,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("sdk: %w", err)
@@ -47,7 +51,26 @@ func New(config *config.Config) (*Datasite, error) {
 	appMgr := apps.NewManager(ws.AppsDir, ws.MetadataDir)
 	appSched := apps.NewAppScheduler(appMgr, config.Path)
 
-	sync, err := sync.NewManager(ws, sdk)
+	sync, err := sync.NewManager(ws, sdk, &sync.SyncOptions{
+		FileAttrs: &fileattr.Options{
+			Executable: config.SyncExecutable,
+			Xattrs:     config.SyncXattrs,
+		},
+		Transfer: &sync.TransferOptions{
+			Workers:        config.SyncWorkers,
+			BatchThreshold: config.SyncBatchThreshold,
+			BatchSize:      config.SyncBatchSize,
+		},
+		ResumeResync:    !config.DisableResumeResync,
+		NetworkResync:   !config.DisableNetworkResync,
+		ExportDir:       config.ExportDir,
+		VerifyHooks:     verifyHooks(config),
+		IncludeHidden:   config.IncludeHidden(),
+		KeepRejected:    config.SyncKeepRejected,
+		ReadOnly:        config.SyncReadOnly,
+		UploadRequeue:   config.SyncUploadRequeue,
+		ShutdownTimeout: config.ShutdownTimeout,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("sync manager: %w", err)
 	}
@@ -76,21 +99,29 @@ func (d *Datasite) Start(ctx context.Context) error {
 		return fmt.Errorf("save config: %w", err)
 	}
 
+	// check the server speaks our protocol before talking to it
+	if err := checkServerVersion(ctx, d.sdk, d.config.ProtocolMismatch); err != nil {
+		return err
+	}
+
 	// authenticate with the server
 	if err := d.authenticateClient(ctx); err != nil {
 		return fmt.Errorf("client auth: %w", err)
 	}
 
-	// Start app scheduler
-	if err := d.appScheduler.Start(ctx); err != nil {
-		slog.Error("app scheduler", "error", err)
-	}
+	// Start app scheduler, once the initial sync brought the datasite down
+	d.appScheduler.StartAfterSync(ctx, d.sync.InitialSynced(), d.config.AppsSyncTimeout)
 
 	// Start sync manager. this will block for the first sync cycle.
 	if err := d.sync.Start(ctx); err != nil {
 		return fmt.Errorf("sync manager: %w", err)
 	}
 
+	// install the approved apps of the app manifest, when one is configured
+	if d.config.AppsEnabled && d.config.AppManifest != "" {
+		go d.watchAppManifest(ctx)
+	}
+
 	return nil
 }
 
@@ -156,3 +187,16 @@ func (d *Datasite) updateRefreshToken(refreshToken string) {
 		slog.Error("save config", "error", err)
 	}
 }
+
+// verifyHooks are the verify hooks of the config, for the sync
+func verifyHooks(config *config.Config) []sync.VerifyHook {
+	hooks := make([]sync.VerifyHook, 0, len(config.SyncVerifyHooks))
+	for _, hook := range config.SyncVerifyHooks {
+		hooks = append(hooks, sync.VerifyHook{
+			Pattern: hook.Pattern,
+			Command: hook.Command,
+			Timeout: hook.TimeoutDuration(),
+		})
+	}
+	return hooks
+}
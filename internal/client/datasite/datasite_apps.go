@@ -0,0 +1,91 @@
+package datasite
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/openmined/syftbox/internal/client/apps"
+)
+
+const (
+	appManifestInterval = 30 * time.Second
+)
+
+// watchAppManifest installs the approved apps of the configured app manifest,
+// and again whenever the manifest or the approvals change
+func (d *Datasite) watchAppManifest(ctx context.Context) {
+	manifestPath := d.config.AppManifest
+	var lastModified, lastApproved time.Time
+
+	apply := func() {
+		stat, err := os.Stat(manifestPath)
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				slog.Warn("app manifest", "path", manifestPath, "error", err)
+			}
+			return
+		}
+
+		var approved time.Time
+		if stat, err := os.Stat(d.appManager.ManifestApprovalsPath()); err == nil {
+			approved = stat.ModTime()
+		}
+
+		if !stat.ModTime().After(lastModified) && !approved.After(lastApproved) {
+			return
+		}
+		lastModified, lastApproved = stat.ModTime(), approved
+
+		if err := d.applyAppManifest(ctx, manifestPath); err != nil {
+			slog.Error("app manifest", "path", manifestPath, "error", err)
+		}
+	}
+
+	apply()
+
+	ticker := time.NewTicker(appManifestInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			apply()
+		}
+	}
+}
+
+func (d *Datasite) applyAppManifest(ctx context.Context, manifestPath string) error {
+	manifest, err := apps.LoadManifest(manifestPath)
+	if err != nil {
+		return err
+	}
+
+	slog.Info("applying app manifest", "path", manifestPath, "apps", len(manifest.Apps))
+	result, applyErr := d.appManager.ApplyManifest(ctx, manifest, d.workspace.UserDir)
+	if result == nil {
+		return applyErr
+	}
+
+	for _, app := range result.Pending {
+		slog.Warn("app manifest app waits for approval, run `syftbox app approve` to review it", "app", app.Name, "source", app.Source, "command", app.Command)
+	}
+
+	// pick up the new apps right away, including the ones installed before an error
+	if err := d.appScheduler.Refresh(); err != nil && !errors.Is(err, apps.ErrRefreshInProgress) {
+		slog.Warn("app scheduler refresh", "error", err)
+	}
+
+	// the updated apps still run what was installed before
+	for _, appID := range result.Updated {
+		if err := d.appScheduler.Reschedule(appID); err != nil && !errors.Is(err, apps.ErrRefreshInProgress) {
+			slog.Warn("app scheduler reschedule", "app", appID, "error", err)
+		}
+	}
+
+	return applyErr
+}
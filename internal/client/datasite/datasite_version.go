@@ -0,0 +1,54 @@
+package datasite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/openmined/syftbox/internal/client/config"
+	"github.com/openmined/syftbox/internal/syftsdk"
+	"github.com/openmined/syftbox/internal/version"
+)
+
+var ErrProtocolMismatch = errors.New("server protocol mismatch")
+
+// checkServerVersion compares the wire version of the server with the client's and applies the mismatch policy.
+// A server that cannot be reached is not a mismatch, authenticating with it reports the actual problem.
+func checkServerVersion(ctx context.Context, sdk *syftsdk.SyftSDK, policy string) error {
+	server, err := sdk.ServerVersion(ctx)
+	if err != nil {
+		slog.Warn("server version check skipped", "error", err)
+		return nil
+	}
+
+	if server.WireVersion == version.WireVersion {
+		return nil
+	}
+
+	// only older peers are understood, a newer client relies on the server to still support it
+	compatible := server.WireVersion >= version.MinWireVersion && version.WireVersion >= server.MinWireVersion
+	mismatch := fmt.Errorf("%w: server %s speaks protocol v%d, this client v%d",
+		ErrProtocolMismatch, server.Version, server.WireVersion, version.WireVersion)
+
+	switch policy {
+	case config.ProtocolMismatchRefuse:
+		return fmt.Errorf("%w. update the client or the server, or set protocol_mismatch to %q", mismatch, config.ProtocolMismatchWarn)
+
+	case config.ProtocolMismatchDegrade:
+		if !compatible {
+			return fmt.Errorf("%w, no compatible feature subset", mismatch)
+		}
+		disabled := sdk.DisableUnsupported(server.WireVersion)
+		slog.Warn("server protocol mismatch, turned off the features the server does not support",
+			"server", server.Version, "serverWireVersion", server.WireVersion,
+			"clientWireVersion", version.WireVersion, "disabled", disabled)
+
+	default:
+		slog.Warn("server protocol mismatch, some features may not work. update the client or the server",
+			"server", server.Version, "serverWireVersion", server.WireVersion,
+			"clientWireVersion", version.WireVersion, "compatible", compatible)
+	}
+
+	return nil
+}
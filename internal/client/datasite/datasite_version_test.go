@@ -0,0 +1,84 @@
+package datasite
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openmined/syftbox/internal/blobcodec"
+	"github.com/openmined/syftbox/internal/client/config"
+	"github.com/openmined/syftbox/internal/syftsdk"
+	"github.com/openmined/syftbox/internal/version"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newVersionStub serves the version endpoint with the given wire versions
+func newVersionStub(t *testing.T, wireVersion, minWireVersion int) *syftsdk.SyftSDK {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/version" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"version":        "0.0.1",
+			"revision":       "stub",
+			"wireVersion":    wireVersion,
+			"minWireVersion": minWireVersion,
+		})
+	}))
+	t.Cleanup(srv.Close)
+
+	sdk, err := syftsdk.New(&syftsdk.SyftSDKConfig{
+		BaseURL:          srv.URL,
+		Email:            "alice@example.com",
+		CompressionCodec: blobcodec.Gzip,
+	})
+	require.NoError(t, err)
+	return sdk
+}
+
+func TestCheckServerVersion(t *testing.T) {
+	policies := []string{"", config.ProtocolMismatchWarn, config.ProtocolMismatchRefuse, config.ProtocolMismatchDegrade}
+
+	t.Run("same version", func(t *testing.T) {
+		for _, policy := range policies {
+			sdk := newVersionStub(t, version.WireVersion, version.MinWireVersion)
+			assert.NoError(t, checkServerVersion(context.Background(), sdk, policy), policy)
+			assert.Empty(t, sdk.DisableUnsupported(version.WireVersion))
+		}
+	})
+
+	t.Run("older server", func(t *testing.T) {
+		older := version.WireVersion - 1
+
+		assert.NoError(t, checkServerVersion(context.Background(), newVersionStub(t, older, 1), ""))
+		assert.NoError(t, checkServerVersion(context.Background(), newVersionStub(t, older, 1), config.ProtocolMismatchWarn))
+		assert.ErrorIs(t, checkServerVersion(context.Background(), newVersionStub(t, older, 1), config.ProtocolMismatchRefuse), ErrProtocolMismatch)
+
+		// still compatible, compressed uploads are turned off
+		assert.NoError(t, checkServerVersion(context.Background(), newVersionStub(t, older, 1), config.ProtocolMismatchDegrade))
+	})
+
+	t.Run("incompatible server", func(t *testing.T) {
+		// the server no longer supports this client
+		newer := version.WireVersion + 1
+		sdk := newVersionStub(t, newer, newer)
+
+		assert.NoError(t, checkServerVersion(context.Background(), sdk, config.ProtocolMismatchWarn))
+		assert.ErrorIs(t, checkServerVersion(context.Background(), sdk, config.ProtocolMismatchRefuse), ErrProtocolMismatch)
+		err := checkServerVersion(context.Background(), sdk, config.ProtocolMismatchDegrade)
+		assert.ErrorIs(t, err, ErrProtocolMismatch)
+		assert.ErrorContains(t, err, "no compatible feature subset")
+	})
+
+	t.Run("unreachable server", func(t *testing.T) {
+		sdk, err := syftsdk.New(&syftsdk.SyftSDKConfig{BaseURL: "http://127.0.0.1:1", Email: "alice@example.com"})
+		require.NoError(t, err)
+		assert.NoError(t, checkServerVersion(context.Background(), sdk, config.ProtocolMismatchRefuse))
+	})
+}
@@ -33,13 +33,24 @@ type DatasiteManager struct {
 	mu          sync.RWMutex
 }
 
-func New() *DatasiteManager {
+func New(opts ...DatasiteManagerOpts) *DatasiteManager {
 	ds := &DatasiteManager{
 		status: DatasiteStatusUnprovisioned,
 	}
+	for _, opt := range opts {
+		opt(ds)
+	}
 	return ds
 }
 
+// WithDatasite manages an already provisioned datasite
+func WithDatasite(datasite *datasite.Datasite) DatasiteManagerOpts {
+	return func(d *DatasiteManager) {
+		d.datasite = datasite
+		d.status = DatasiteStatusProvisioned
+	}
+}
+
 func (d *DatasiteManager) SetRuntimeConfig(cfg *RuntimeConfig) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
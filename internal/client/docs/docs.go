@@ -5,7 +5,7 @@ package docs
 import "github.com/swaggo/swag/v2"
 
 const docTemplate = `{
-    "schemes": {{ marshal .Schemes }},"swagger":"2.0","info":{"description":"{{escape .Description}}","title":"{{.Title}}","contact":{},"version":"{{.Version}}"},"host":"{{.Host}}","basePath":"{{.BasePath}}","paths":{"/v1/apps/":{"get":{"description":"List all installed apps","produces":["application/json"],"tags":["Apps"],"summary":"List apps","responses":{"200":{"description":"OK","schema":{"$ref":"#/definitions/handlers.AppListResponse"}},"400":{"description":"Bad Request","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"401":{"description":"Unauthorized","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"403":{"description":"Forbidden","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"409":{"description":"Conflict","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"429":{"description":"Too Many Requests","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"500":{"description":"Internal Server Error","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"503":{"description":"Service Unavailable","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}}}},"post":{"description":"Install an app","consumes":["application/json"],"produces":["application/json"],"tags":["Apps"],"summary":"Install app","parameters":[{"description":"Install request","name":"request","in":"body","required":true,"schema":{"$ref":"#/definitions/handlers.AppInstallRequest"}}],"responses":{"200":{"description":"OK","schema":{"$ref":"#/definitions/handlers.AppResponse"}},"400":{"description":"Bad Request","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"401":{"description":"Unauthorized","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"403":{"description":"Forbidden","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"409":{"description":"Conflict","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"429":{"description":"Too Many Requests","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"500":{"description":"Internal Server Error","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"503":{"description":"Service Unavailable","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}}}}},"/v1/apps/{appId}":{"get":{"description":"Get an app","produces":["application/json"],"tags":["Apps"],"summary":"Get app","parameters":[{"type":"string","description":"App ID","name":"appId","in":"path","required":true},{"type":"boolean","description":"Whether to include process statistics","name":"processStats","in":"query"}],"responses":{"200":{"description":"OK","schema":{"$ref":"#/definitions/handlers.AppResponse"}},"400":{"description":"Bad Request","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"401":{"description":"Unauthorized","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"403":{"description":"Forbidden","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"409":{"description":"Conflict","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"429":{"description":"Too Many Requests","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"500":{"description":"Internal Server Error","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"503":{"description":"Service Unavailable","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}}}},"delete":{"description":"Uninstall an app","produces":["application/json"],"tags":["Apps"],"summary":"Uninstall app","parameters":[{"type":"string","description":"App ID","name":"appId","in":"path","required":true}],"responses":{"204":{"description":"No Content"},"400":{"description":"Bad Request","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"401":{"description":"Unauthorized","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"403":{"description":"Forbidden","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"409":{"description":"Conflict","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"429":{"description":"Too Many Requests","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"500":{"description":"Internal Server Error","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"503":{"description":"Service Unavailable","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}}}}},"/v1/apps/{appId}/start":{"post":{"description":"Start an app","produces":["application/json"],"tags":["Apps"],"summary":"Start app","parameters":[{"type":"string","description":"App ID","name":"appId","in":"path","required":true}],"responses":{"200":{"description":"OK","schema":{"$ref":"#/definitions/handlers.AppResponse"}},"400":{"description":"Bad Request","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"401":{"description":"Unauthorized","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"403":{"description":"Forbidden","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"409":{"description":"Conflict","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"429":{"description":"Too Many Requests","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"500":{"description":"Internal Server Error","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"503":{"description":"Service Unavailable","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}}}}},"/v1/apps/{appId}/stop":{"post":{"description":"Stop an app","produces":["application/json"],"tags":["Apps"],"summary":"Stop app","parameters":[{"type":"string","description":"App ID","name":"appId","in":"path","required":true}],"responses":{"200":{"description":"OK","schema":{"$ref":"#/definitions/handlers.AppResponse"}},"400":{"description":"Bad Request","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"401":{"description":"Unauthorized","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"403":{"description":"Forbidden","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"409":{"description":"Conflict","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"429":{"description":"Too Many Requests","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"500":{"description":"Internal Server Error","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"503":{"description":"Service Unavailable","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}}}}},"/v1/init/datasite":{"post":{"description":"Initialize the client with the given configuration","consumes":["application/json"],"produces":["application/json"],"tags":["Init"],"summary":"Initialize the client","parameters":[{"description":"Initialize request","name":"request","in":"body","required":true,"schema":{"$ref":"#/definitions/handlers.InitDatasiteRequest"}}],"responses":{"200":{"description":"OK","schema":{"$ref":"#/definitions/handlers.ControlPlaneResponse"}},"400":{"description":"Bad Request","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"500":{"description":"Internal Server Error","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}}}}},"/v1/init/token":{"get":{"description":"Request an email validation token from the syftbox server","produces":["application/json"],"tags":["Init"],"summary":"Get token","parameters":[{"type":"string","format":"email","description":"Email","name":"email","in":"query","required":true},{"type":"string","format":"url","description":"Server URL","name":"server_url","in":"query","required":true}],"responses":{"200":{"description":"OK","schema":{"$ref":"#/definitions/handlers.ControlPlaneResponse"}},"400":{"description":"Bad Request","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}}}}},"/v1/logs":{"get":{"description":"Get system logs with pagination support","produces":["application/json"],"tags":["Logs"],"summary":"Get logs","parameters":[{"type":"string","default":"system","description":"The ID of the app to retrieve logs for","name":"appId","in":"query"},{"minimum":1,"type":"integer","default":1,"description":"Pagination token from a previous request to retrieve the next page of results","name":"startingToken","in":"query"},{"maximum":1000,"minimum":1,"type":"integer","default":100,"description":"Maximum number of lines to read","name":"maxResults","in":"query"}],"responses":{"200":{"description":"OK","schema":{"$ref":"#/definitions/handlers.LogsResponse"}},"400":{"description":"Bad Request","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"401":{"description":"Unauthorized","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"403":{"description":"Forbidden","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"404":{"description":"Not Found","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"429":{"description":"Too Many Requests","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"500":{"description":"Internal Server Error","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"503":{"description":"Service Unavailable","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}}}}},"/v1/logs/download":{"get":{"description":"Download all logs as a zip file","produces":["application/zip"],"tags":["Logs"],"summary":"Download logs","responses":{"200":{"description":"Zip file containing all logs","schema":{"type":"file"}},"400":{"description":"Bad Request","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"401":{"description":"Unauthorized","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"403":{"description":"Forbidden","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"429":{"description":"Too Many Requests","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"500":{"description":"Internal Server Error","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"503":{"description":"Service Unavailable","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}}}}},"/v1/status":{"get":{"description":"Returns the status of the service","produces":["application/json"],"tags":["Status"],"summary":"Get status","responses":{"200":{"description":"OK","schema":{"$ref":"#/definitions/handlers.StatusResponse"}},"503":{"description":"Service Unavailable","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}}}}},"/v1/workspace/content":{"get":{"description":"Get the content of a file at the specified path. Supports range requests for efficient streaming of large files.","produces":["text/plain","application/octet-stream","*/*"],"tags":["Workspace"],"summary":"Get file content","parameters":[{"type":"string","description":"Path to the file","name":"path","in":"query","required":true}],"responses":{"200":{"description":"File content","schema":{"type":"file"}},"206":{"description":"Partial file content for range requests","schema":{"type":"file"}},"400":{"description":"Bad Request","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"401":{"description":"Unauthorized","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"403":{"description":"Forbidden","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"404":{"description":"Not Found","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"429":{"description":"Too Many Requests","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"500":{"description":"Internal Server Error","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"503":{"description":"Service Unavailable","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}}}},"put":{"description":"Update the content of a file at the specified path. Supports overwrite, append, and prepend modes. Can create the file if it doesn't exist.","consumes":["application/json"],"produces":["application/json"],"tags":["Workspace"],"summary":"Update file content","parameters":[{"description":"Request body","name":"request","in":"body","required":true,"schema":{"$ref":"#/definitions/handlers.WorkspaceContentUpdateRequest"}}],"responses":{"200":{"description":"OK","schema":{"$ref":"#/definitions/handlers.WorkspaceItem"}},"400":{"description":"Bad Request","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"401":{"description":"Unauthorized","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"403":{"description":"Forbidden","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"404":{"description":"Not Found","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"429":{"description":"Too Many Requests","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"500":{"description":"Internal Server Error","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"503":{"description":"Service Unavailable","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}}}}},"/v1/workspace/items":{"get":{"description":"Get files and folders at a specified path","produces":["application/json"],"tags":["Workspace"],"summary":"Get workspace items","parameters":[{"type":"string","description":"Path to the directory (default is root)","name":"path","in":"query"},{"minimum":0,"type":"integer","default":1,"description":"Maximum depth for retrieving children (0 = no children, 1 = immediate children only, etc.)","name":"depth","in":"query"}],"responses":{"200":{"description":"OK","schema":{"$ref":"#/definitions/handlers.WorkspaceItemsResponse"}},"400":{"description":"Bad Request","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"401":{"description":"Unauthorized","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"403":{"description":"Forbidden","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"409":{"description":"Conflict","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"429":{"description":"Too Many Requests","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"500":{"description":"Internal Server Error","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"503":{"description":"Service Unavailable","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}}}},"post":{"description":"Create a new file or folder in the workspace","consumes":["application/json"],"produces":["application/json"],"tags":["Workspace"],"summary":"Create workspace item","parameters":[{"description":"Request body","name":"request","in":"body","required":true,"schema":{"$ref":"#/definitions/handlers.WorkspaceItemCreateRequest"}}],"responses":{"201":{"description":"Created","schema":{"$ref":"#/definitions/handlers.WorkspaceItemCreateResponse"}},"400":{"description":"Bad Request","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"401":{"description":"Unauthorized","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"403":{"description":"Forbidden","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"409":{"description":"Conflict","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"429":{"description":"Too Many Requests","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"500":{"description":"Internal Server Error","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"503":{"description":"Service Unavailable","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}}}},"delete":{"description":"Delete multiple files or folders. The operation is similar to the Unix ` + "`" + `rm -rf` + "`" + ` command.\n- If the path is a file, the file will be deleted.\n- If the path is a folder, all its contents will also be deleted.\n- If the path is a symlink, the symlink will be deleted without deleting the target.\n- If the path does not exist, the operation will be a no-op.","consumes":["application/json"],"tags":["Workspace"],"summary":"Delete workspace items","parameters":[{"description":"Request body","name":"request","in":"body","required":true,"schema":{"$ref":"#/definitions/handlers.WorkspaceItemDeleteRequest"}}],"responses":{"204":{"description":"No Content"},"400":{"description":"Bad Request","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"401":{"description":"Unauthorized","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"403":{"description":"Forbidden","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"409":{"description":"Conflict","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"429":{"description":"Too Many Requests","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"500":{"description":"Internal Server Error","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"503":{"description":"Service Unavailable","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}}}}},"/v1/workspace/items/copy":{"post":{"description":"Create a copy of a file or folder","consumes":["application/json"],"produces":["application/json"],"tags":["Workspace"],"summary":"Copy a file or folder","parameters":[{"description":"Request body","name":"request","in":"body","required":true,"schema":{"$ref":"#/definitions/handlers.WorkspaceItemCopyRequest"}}],"responses":{"200":{"description":"OK","schema":{"$ref":"#/definitions/handlers.WorkspaceItemCopyResponse"}},"400":{"description":"Bad Request","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"401":{"description":"Unauthorized","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"403":{"description":"Forbidden","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"404":{"description":"Not Found","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"409":{"description":"Conflict","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"429":{"description":"Too Many Requests","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"500":{"description":"Internal Server Error","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}}}}},"/v1/workspace/items/move":{"post":{"description":"Move an item to a new location. Can also be used for renaming an item.","consumes":["application/json"],"produces":["application/json"],"tags":["Workspace"],"summary":"Move item","parameters":[{"description":"Request body","name":"request","in":"body","required":true,"schema":{"$ref":"#/definitions/handlers.WorkspaceItemMoveRequest"}}],"responses":{"200":{"description":"OK","schema":{"$ref":"#/definitions/handlers.WorkspaceItemMoveResponse"}},"400":{"description":"Bad Request","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"401":{"description":"Unauthorized","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"403":{"description":"Forbidden","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"404":{"description":"Not Found","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"409":{"description":"Conflict","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"429":{"description":"Too Many Requests","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"500":{"description":"Internal Server Error","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}}}}}},"definitions":{"apps.AppInfo":{"type":"object","properties":{"branch":{"type":"string"},"commit":{"type":"string"},"id":{"type":"string"},"installedOn":{"type":"string"},"name":{"type":"string"},"path":{"type":"string"},"source":{"$ref":"#/definitions/apps.AppSource"},"sourceURI":{"type":"string"},"tag":{"type":"string"}}},"apps.AppProcessStatus":{"type":"string","enum":["new","running","stopped"],"x-enum-varnames":["StatusNew","StatusRunning","StatusStopped"]},"apps.AppSource":{"type":"string","enum":["git","local"],"x-enum-varnames":["AppSourceGit","AppSourceLocalDir"]},"apps.ProcessStats":{"type":"object","properties":{"children":{"description":"Children processes","type":"array","items":{"$ref":"#/definitions/apps.ProcessStats"}},"cmdline":{"description":"Command line arguments for this app's process","type":"array","items":{"type":"string"}},"connections":{"description":"All connections this app is listening on","type":"array","items":{"$ref":"#/definitions/net.ConnectionStat"}},"cpuPercent":{"description":"Percentage of total CPU this app is using","type":"number"},"cpuTimes":{"description":"CPU times breakdown","allOf":[{"$ref":"#/definitions/cpu.TimesStat"}]},"cwd":{"description":"Current working directory of this app's process","type":"string"},"environ":{"description":"Environment variables for this app's process","type":"array","items":{"type":"string"}},"exe":{"description":"Executable path of this app's process","type":"string"},"gids":{"description":"List of groups this app is a member of","type":"array","items":{"type":"integer"}},"memoryInfo":{"description":"Memory info","allOf":[{"$ref":"#/definitions/process.MemoryInfoStat"}]},"memoryPercent":{"description":"Percentage of total RAM this app is using","type":"number"},"nice":{"description":"Nice value of this app's process","type":"integer"},"numThreads":{"description":"Number of threads this app is using","type":"integer"},"pid":{"description":"Process ID","type":"integer"},"processName":{"description":"Process Name","type":"string"},"status":{"description":"Status of the process","type":"array","items":{"type":"string"}},"uids":{"description":"List of user IDs this app is a member of","type":"array","items":{"type":"integer"}},"uptime":{"description":"How long the app has been running in milliseconds","type":"integer"},"username":{"description":"Username of the user this app is running as","type":"string"}}},"cpu.TimesStat":{"type":"object","properties":{"cpu":{"type":"string"},"guest":{"type":"number"},"guestNice":{"type":"number"},"idle":{"type":"number"},"iowait":{"type":"number"},"irq":{"type":"number"},"nice":{"type":"number"},"softirq":{"type":"number"},"steal":{"type":"number"},"system":{"type":"number"},"user":{"type":"number"}}},"handlers.AppInstallRequest":{"type":"object","required":["repoURL"],"properties":{"branch":{"description":"branch of the repo to install","type":"string"},"commit":{"description":"commit of the repo to install","type":"string"},"force":{"description":"force install","type":"boolean"},"repoURL":{"description":"url of the github repo to install","type":"string"},"tag":{"description":"tag of the repo to install","type":"string"}}},"handlers.AppListResponse":{"type":"object","properties":{"apps":{"description":"list of installed apps","type":"array","items":{"$ref":"#/definitions/handlers.AppResponse"}}}},"handlers.AppResponse":{"type":"object","properties":{"id":{"description":"Unique ID of the app [deprecated]","type":"string"},"info":{"description":"Info about the app","allOf":[{"$ref":"#/definitions/apps.AppInfo"}]},"name":{"description":"name of the app [deprecated]","type":"string"},"path":{"description":"Absolute path to the app from the workspace root [deprecated]","type":"string"},"pid":{"description":"Process ID of the app's run.sh","type":"integer"},"ports":{"description":"List of ports this app is listening on","type":"array","items":{"type":"integer"}},"processStats":{"description":"Extended process statistics (optional)","allOf":[{"$ref":"#/definitions/apps.ProcessStats"}]},"status":{"description":"Status of the app","allOf":[{"$ref":"#/definitions/apps.AppProcessStatus"}]}}},"handlers.ControlPlaneError":{"type":"object","properties":{"code":{"type":"string"},"error":{"type":"string"}}},"handlers.ControlPlaneResponse":{"type":"object","properties":{"code":{"type":"string"}}},"handlers.DatasiteConfig":{"type":"object","properties":{"data_dir":{"type":"string"},"email":{"type":"string"},"server_url":{"type":"string"}}},"handlers.DatasiteInfo":{"type":"object","properties":{"config":{"description":"config of the datasite.","allOf":[{"$ref":"#/definitions/handlers.DatasiteConfig"}]},"error":{"description":"error message if the datasite is not ready.","type":"string"},"status":{"description":"status of the datasite.","type":"string"}}},"handlers.InitDatasiteRequest":{"type":"object","required":["dataDir","email","serverUrl","token"],"properties":{"dataDir":{"description":"datasite directory","type":"string"},"email":{"description":"email of the user","type":"string"},"serverUrl":{"description":"syftbox server url","type":"string"},"token":{"description":"email token of the user","type":"string"}}},"handlers.LogEntry":{"type":"object","properties":{"lineNumber":{"type":"integer"},"message":{"type":"string"},"timestamp":{"type":"string"}}},"handlers.LogsResponse":{"type":"object","properties":{"hasMore":{"description":"Whether there are more logs to retrieve.","type":"boolean"},"logs":{"description":"A list of log items.","type":"array","items":{"$ref":"#/definitions/handlers.LogEntry"}},"nextToken":{"description":"A pagination token to retrieve the next page of logs.","type":"integer"}}},"handlers.Permission":{"type":"object","properties":{"avatar":{"type":"string"},"email":{"type":"string"},"id":{"type":"string"},"name":{"type":"string"},"type":{"description":"\"read\", \"write\", or \"admin\"","type":"string"},"userId":{"type":"string"}}},"handlers.StatusResponse":{"type":"object","properties":{"buildDate":{"description":"build date of the client.","type":"string"},"datasite":{"description":"datasite status.","allOf":[{"$ref":"#/definitions/handlers.DatasiteInfo"}]},"revision":{"description":"revision of the client.","type":"string"},"status":{"description":"health status (\"ok\").","type":"string"},"ts":{"description":"timestamp when health check was performed.","type":"string"},"version":{"description":"version of the client.","type":"string"}}},"handlers.SyncStatus":{"type":"string","enum":["synced","syncing","pending","rejected","error","ignored","hidden"],"x-enum-varnames":["SyncStatusSynced","SyncStatusSyncing","SyncStatusPending","SyncStatusRejected","SyncStatusError","SyncStatusIgnored","SyncStatusHidden"]},"handlers.UpdateMode":{"type":"string","enum":["overwrite","append","prepend"],"x-enum-comments":{"UpdateModeAppend":"Add content to end of file","UpdateModeOverwrite":"Replace entire file content","UpdateModePrepend":"Add content to start of file"},"x-enum-varnames":["UpdateModeOverwrite","UpdateModeAppend","UpdateModePrepend"]},"handlers.WorkspaceContentUpdateRequest":{"type":"object","required":["content","mode","path"],"properties":{"content":{"type":"string"},"create":{"description":"Create file if it doesn't exist","type":"boolean","default":false},"mode":{"default":"overwrite","enum":["overwrite","append","prepend"],"allOf":[{"$ref":"#/definitions/handlers.UpdateMode"}]},"path":{"type":"string"}}},"handlers.WorkspaceItem":{"type":"object","properties":{"absolutePath":{"type":"string"},"children":{"type":"array","items":{"$ref":"#/definitions/handlers.WorkspaceItem"}},"createdAt":{"type":"string"},"id":{"type":"string"},"modifiedAt":{"type":"string"},"name":{"type":"string"},"path":{"type":"string"},"permissions":{"type":"array","items":{"$ref":"#/definitions/handlers.Permission"}},"size":{"type":"integer"},"syncStatus":{"$ref":"#/definitions/handlers.SyncStatus"},"type":{"$ref":"#/definitions/handlers.WorkspaceItemType"}}},"handlers.WorkspaceItemCopyRequest":{"type":"object","required":["newPath","sourcePath"],"properties":{"newPath":{"description":"Full path of the new item location, including the item name","type":"string"},"overwrite":{"description":"Overwrite the destination item if it exists","type":"boolean","default":false},"sourcePath":{"description":"Full path of the item to copy","type":"string"}}},"handlers.WorkspaceItemCopyResponse":{"type":"object","properties":{"item":{"$ref":"#/definitions/handlers.WorkspaceItem"}}},"handlers.WorkspaceItemCreateRequest":{"type":"object","required":["path","type"],"properties":{"overwrite":{"type":"boolean","default":false},"path":{"type":"string"},"type":{"enum":["file","folder"],"allOf":[{"$ref":"#/definitions/handlers.WorkspaceItemType"}]}}},"handlers.WorkspaceItemCreateResponse":{"type":"object","properties":{"item":{"$ref":"#/definitions/handlers.WorkspaceItem"}}},"handlers.WorkspaceItemDeleteRequest":{"type":"object","required":["paths"],"properties":{"paths":{"type":"array","items":{"type":"string"}}}},"handlers.WorkspaceItemMoveRequest":{"type":"object","required":["newPath","sourcePath"],"properties":{"newPath":{"description":"Full path to the new item location, including the item name","type":"string"},"overwrite":{"description":"Overwrite the destination item if it exists","type":"boolean","default":false},"sourcePath":{"description":"Full path to the source item","type":"string"}}},"handlers.WorkspaceItemMoveResponse":{"type":"object","properties":{"item":{"$ref":"#/definitions/handlers.WorkspaceItem"}}},"handlers.WorkspaceItemType":{"type":"string","enum":["file","folder"],"x-enum-varnames":["WorkspaceItemTypeFile","WorkspaceItemTypeFolder"]},"handlers.WorkspaceItemsResponse":{"type":"object","properties":{"items":{"type":"array","items":{"$ref":"#/definitions/handlers.WorkspaceItem"}}}},"net.ConnectionStat":{"type":"object","properties":{"family":{"type":"integer"},"fd":{"type":"integer"},"localaddr":{},"pid":{"type":"integer"},"remoteaddr":{},"status":{"type":"string"},"type":{"type":"integer"},"uids":{"type":"array","items":{"type":"integer"}}}},"process.MemoryInfoStat":{"type":"object","properties":{"data":{"description":"bytes","type":"integer"},"hwm":{"description":"bytes","type":"integer"},"locked":{"description":"bytes","type":"integer"},"rss":{"description":"bytes","type":"integer"},"stack":{"description":"bytes","type":"integer"},"swap":{"description":"bytes","type":"integer"},"vms":{"description":"bytes","type":"integer"}}}},"securityDefinitions":{"APIToken":{"type":"apiKey","name":"Authorization","in":"header"}},"security":[{"APIToken":[]}]}`
+    "schemes": {{ marshal .Schemes }},"swagger":"2.0","info":{"description":"{{escape .Description}}","title":"{{.Title}}","contact":{},"version":"{{.Version}}"},"host":"{{.Host}}","basePath":"{{.BasePath}}","paths":{"/v1/apps/":{"get":{"description":"List all installed apps","produces":["application/json"],"tags":["Apps"],"summary":"List apps","responses":{"200":{"description":"OK","schema":{"$ref":"#/definitions/handlers.AppListResponse"}},"400":{"description":"Bad Request","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"401":{"description":"Unauthorized","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"403":{"description":"Forbidden","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"409":{"description":"Conflict","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"429":{"description":"Too Many Requests","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"500":{"description":"Internal Server Error","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"503":{"description":"Service Unavailable","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}}}},"post":{"description":"Install an app","consumes":["application/json"],"produces":["application/json"],"tags":["Apps"],"summary":"Install app","parameters":[{"description":"Install request","name":"request","in":"body","required":true,"schema":{"$ref":"#/definitions/handlers.AppInstallRequest"}}],"responses":{"200":{"description":"OK","schema":{"$ref":"#/definitions/handlers.AppResponse"}},"400":{"description":"Bad Request","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"401":{"description":"Unauthorized","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"403":{"description":"Forbidden","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"409":{"description":"Conflict","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"429":{"description":"Too Many Requests","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"500":{"description":"Internal Server Error","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"503":{"description":"Service Unavailable","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}}}}},"/v1/apps/{appId}":{"get":{"description":"Get an app","produces":["application/json"],"tags":["Apps"],"summary":"Get app","parameters":[{"type":"string","description":"App ID","name":"appId","in":"path","required":true},{"type":"boolean","description":"Whether to include process statistics","name":"processStats","in":"query"}],"responses":{"200":{"description":"OK","schema":{"$ref":"#/definitions/handlers.AppResponse"}},"400":{"description":"Bad Request","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"401":{"description":"Unauthorized","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"403":{"description":"Forbidden","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"409":{"description":"Conflict","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"429":{"description":"Too Many Requests","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"500":{"description":"Internal Server Error","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"503":{"description":"Service Unavailable","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}}}},"delete":{"description":"Uninstall an app","produces":["application/json"],"tags":["Apps"],"summary":"Uninstall app","parameters":[{"type":"string","description":"App ID","name":"appId","in":"path","required":true}],"responses":{"204":{"description":"No Content"},"400":{"description":"Bad Request","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"401":{"description":"Unauthorized","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"403":{"description":"Forbidden","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"409":{"description":"Conflict","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"429":{"description":"Too Many Requests","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"500":{"description":"Internal Server Error","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"503":{"description":"Service Unavailable","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}}}}},"/v1/apps/{appId}/start":{"post":{"description":"Start an app","produces":["application/json"],"tags":["Apps"],"summary":"Start app","parameters":[{"type":"string","description":"App ID","name":"appId","in":"path","required":true}],"responses":{"200":{"description":"OK","schema":{"$ref":"#/definitions/handlers.AppResponse"}},"400":{"description":"Bad Request","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"401":{"description":"Unauthorized","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"403":{"description":"Forbidden","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"409":{"description":"Conflict","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"429":{"description":"Too Many Requests","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"500":{"description":"Internal Server Error","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"503":{"description":"Service Unavailable","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}}}}},"/v1/apps/{appId}/stop":{"post":{"description":"Stop an app","produces":["application/json"],"tags":["Apps"],"summary":"Stop app","parameters":[{"type":"string","description":"App ID","name":"appId","in":"path","required":true}],"responses":{"200":{"description":"OK","schema":{"$ref":"#/definitions/handlers.AppResponse"}},"400":{"description":"Bad Request","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"401":{"description":"Unauthorized","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"403":{"description":"Forbidden","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"409":{"description":"Conflict","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"429":{"description":"Too Many Requests","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"500":{"description":"Internal Server Error","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"503":{"description":"Service Unavailable","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}}}}},"/v1/crdt":{"get":{"description":"Returns the type and the value of a crdt file: the count of a g-counter, the elements of an or-set","produces":["application/json"],"tags":["CRDT"],"summary":"Get a crdt","parameters":[{"type":"string","description":"Workspace path of the file, ending in .crdt.json","name":"path","in":"query","required":true}],"responses":{"200":{"description":"OK","schema":{"$ref":"#/definitions/handlers.CRDTResponse"}},"400":{"description":"Bad Request","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"401":{"description":"Unauthorized","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"404":{"description":"Not Found","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"500":{"description":"Internal Server Error","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"503":{"description":"Service Unavailable","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}}}}},"/v1/crdt/counter/increment":{"post":{"description":"Increments the count of the datasite owner in a g-counter file, creating it if it doesn't exist","consumes":["application/json"],"produces":["application/json"],"tags":["CRDT"],"summary":"Increment a g-counter","parameters":[{"description":"Counter to increment","name":"request","in":"body","required":true,"schema":{"$ref":"#/definitions/handlers.CRDTIncrementRequest"}}],"responses":{"200":{"description":"OK","schema":{"$ref":"#/definitions/handlers.CRDTResponse"}},"400":{"description":"Bad Request","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"401":{"description":"Unauthorized","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"409":{"description":"Conflict","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"500":{"description":"Internal Server Error","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"503":{"description":"Service Unavailable","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}}}}},"/v1/crdt/set/add":{"post":{"description":"Adds an element to an or-set file, creating it if it doesn't exist","consumes":["application/json"],"produces":["application/json"],"tags":["CRDT"],"summary":"Add to an or-set","parameters":[{"description":"Element to add","name":"request","in":"body","required":true,"schema":{"$ref":"#/definitions/handlers.CRDTSetRequest"}}],"responses":{"200":{"description":"OK","schema":{"$ref":"#/definitions/handlers.CRDTResponse"}},"400":{"description":"Bad Request","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"401":{"description":"Unauthorized","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"409":{"description":"Conflict","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"500":{"description":"Internal Server Error","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"503":{"description":"Service Unavailable","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}}}}},"/v1/crdt/set/remove":{"post":{"description":"Removes an element from an or-set file. An add of the element a peer makes concurrently wins","consumes":["application/json"],"produces":["application/json"],"tags":["CRDT"],"summary":"Remove from an or-set","parameters":[{"description":"Element to remove","name":"request","in":"body","required":true,"schema":{"$ref":"#/definitions/handlers.CRDTSetRequest"}}],"responses":{"200":{"description":"OK","schema":{"$ref":"#/definitions/handlers.CRDTResponse"}},"400":{"description":"Bad Request","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"401":{"description":"Unauthorized","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"409":{"description":"Conflict","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"500":{"description":"Internal Server Error","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"503":{"description":"Service Unavailable","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}}}}},"/v1/init/datasite":{"post":{"description":"Initialize the client with the given configuration","consumes":["application/json"],"produces":["application/json"],"tags":["Init"],"summary":"Initialize the client","parameters":[{"description":"Initialize request","name":"request","in":"body","required":true,"schema":{"$ref":"#/definitions/handlers.InitDatasiteRequest"}}],"responses":{"200":{"description":"OK","schema":{"$ref":"#/definitions/handlers.ControlPlaneResponse"}},"400":{"description":"Bad Request","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"500":{"description":"Internal Server Error","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}}}}},"/v1/init/token":{"get":{"description":"Request an email validation token from the syftbox server","produces":["application/json"],"tags":["Init"],"summary":"Get token","parameters":[{"type":"string","format":"email","description":"Email","name":"email","in":"query","required":true},{"type":"string","format":"url","description":"Server URL","name":"server_url","in":"query","required":true}],"responses":{"200":{"description":"OK","schema":{"$ref":"#/definitions/handlers.ControlPlaneResponse"}},"400":{"description":"Bad Request","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}}}}},"/v1/logs":{"get":{"description":"Get system logs with pagination support","produces":["application/json"],"tags":["Logs"],"summary":"Get logs","parameters":[{"type":"string","default":"system","description":"The ID of the app to retrieve logs for","name":"appId","in":"query"},{"minimum":1,"type":"integer","default":1,"description":"Pagination token from a previous request to retrieve the next page of results","name":"startingToken","in":"query"},{"maximum":1000,"minimum":1,"type":"integer","default":100,"description":"Maximum number of lines to read","name":"maxResults","in":"query"}],"responses":{"200":{"description":"OK","schema":{"$ref":"#/definitions/handlers.LogsResponse"}},"400":{"description":"Bad Request","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"401":{"description":"Unauthorized","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"403":{"description":"Forbidden","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"404":{"description":"Not Found","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"429":{"description":"Too Many Requests","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"500":{"description":"Internal Server Error","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"503":{"description":"Service Unavailable","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}}}}},"/v1/logs/download":{"get":{"description":"Download all logs as a zip file","produces":["application/zip"],"tags":["Logs"],"summary":"Download logs","responses":{"200":{"description":"Zip file containing all logs","schema":{"type":"file"}},"400":{"description":"Bad Request","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"401":{"description":"Unauthorized","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"403":{"description":"Forbidden","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"429":{"description":"Too Many Requests","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"500":{"description":"Internal Server Error","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"503":{"description":"Service Unavailable","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}}}}},"/v1/status":{"get":{"description":"Returns the status of the service","produces":["application/json"],"tags":["Status"],"summary":"Get status","responses":{"200":{"description":"OK","schema":{"$ref":"#/definitions/handlers.StatusResponse"}},"503":{"description":"Service Unavailable","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}}}}},"/v1/sync/events":{"get":{"description":"Stream sync status changes of workspace files as server-sent events. Each \"sync\" event carries a SyncEvent.","produces":["text/event-stream"],"tags":["Sync"],"summary":"Stream sync events","responses":{"200":{"description":"OK","schema":{"$ref":"#/definitions/handlers.SyncEvent"}},"401":{"description":"Unauthorized","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"503":{"description":"Service Unavailable","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}}}}},"/v1/sync/metrics":{"get":{"description":"Returns the end-to-end replication latency percentiles of recent uploads and downloads, in milliseconds","produces":["application/json"],"tags":["Sync"],"summary":"Get sync metrics","responses":{"200":{"description":"OK","schema":{"$ref":"#/definitions/handlers.SyncMetricsResponse"}},"401":{"description":"Unauthorized","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"503":{"description":"Service Unavailable","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}}}}},"/v1/workspace/acl/preview":{"post":{"description":"Compare who can access the files under a folder with its current syft.pub.yaml and with a proposed one.\nNothing is changed. The rules resolved per user are previewed for the users named in the paths,\nand each attribute condition as a principal of its own, e.g. group:researchers.","consumes":["application/json"],"produces":["application/json"],"tags":["Workspace"],"summary":"Preview an ACL change","parameters":[{"description":"Request body","name":"request","in":"body","required":true,"schema":{"$ref":"#/definitions/handlers.WorkspaceACLPreviewRequest"}}],"responses":{"200":{"description":"OK","schema":{"$ref":"#/definitions/handlers.WorkspaceACLPreviewResponse"}},"400":{"description":"Bad Request","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"401":{"description":"Unauthorized","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"403":{"description":"Forbidden","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"429":{"description":"Too Many Requests","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"500":{"description":"Internal Server Error","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"503":{"description":"Service Unavailable","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}}}}},"/v1/workspace/content":{"get":{"description":"Get the content of a file at the specified path. Supports range requests for efficient streaming of large files.\nWith preview=thumb, JPEG, PNG and GIF images are served as a small JPEG instead. Other files are served as is.","produces":["text/plain","application/octet-stream","image/jpeg","*/*"],"tags":["Workspace"],"summary":"Get file content","parameters":[{"type":"string","description":"Path to the file","name":"path","in":"query","required":true},{"enum":["thumb"],"type":"string","description":"Serve a preview of the file instead","name":"preview","in":"query"},{"maximum":1024,"minimum":16,"type":"integer","default":128,"description":"Longest side of the thumbnail in pixels","name":"w","in":"query"}],"responses":{"200":{"description":"File content","schema":{"type":"file"}},"206":{"description":"Partial file content for range requests","schema":{"type":"file"}},"400":{"description":"Bad Request","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"401":{"description":"Unauthorized","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"403":{"description":"Forbidden","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"404":{"description":"Not Found","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"429":{"description":"Too Many Requests","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"500":{"description":"Internal Server Error","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"503":{"description":"Service Unavailable","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}}}},"put":{"description":"Update the content of a file at the specified path. Supports overwrite, append, and prepend modes. Can create the file if it doesn't exist.\nSend binary content base64 encoded, with the encoding set to base64.","consumes":["application/json"],"produces":["application/json"],"tags":["Workspace"],"summary":"Update file content","parameters":[{"description":"Request body","name":"request","in":"body","required":true,"schema":{"$ref":"#/definitions/handlers.WorkspaceContentUpdateRequest"}}],"responses":{"200":{"description":"OK","schema":{"$ref":"#/definitions/handlers.WorkspaceItem"}},"400":{"description":"Bad Request","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"401":{"description":"Unauthorized","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"403":{"description":"Forbidden","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"404":{"description":"Not Found","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"429":{"description":"Too Many Requests","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"500":{"description":"Internal Server Error","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"503":{"description":"Service Unavailable","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}}}}},"/v1/workspace/items":{"get":{"description":"Get files and folders at a specified path","produces":["application/json"],"tags":["Workspace"],"summary":"Get workspace items","parameters":[{"type":"string","description":"Path to the directory (default is root)","name":"path","in":"query"},{"minimum":0,"type":"integer","default":1,"description":"Maximum depth for retrieving children (0 = no children, 1 = immediate children only, etc.)","name":"depth","in":"query"}],"responses":{"200":{"description":"OK","schema":{"$ref":"#/definitions/handlers.WorkspaceItemsResponse"}},"400":{"description":"Bad Request","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"401":{"description":"Unauthorized","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"403":{"description":"Forbidden","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"409":{"description":"Conflict","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"429":{"description":"Too Many Requests","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"500":{"description":"Internal Server Error","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"503":{"description":"Service Unavailable","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}}}},"post":{"description":"Create a new file or folder in the workspace","consumes":["application/json"],"produces":["application/json"],"tags":["Workspace"],"summary":"Create workspace item","parameters":[{"description":"Request body","name":"request","in":"body","required":true,"schema":{"$ref":"#/definitions/handlers.WorkspaceItemCreateRequest"}}],"responses":{"201":{"description":"Created","schema":{"$ref":"#/definitions/handlers.WorkspaceItemCreateResponse"}},"400":{"description":"Bad Request","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"401":{"description":"Unauthorized","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"403":{"description":"Forbidden","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"409":{"description":"Conflict","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"429":{"description":"Too Many Requests","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"500":{"description":"Internal Server Error","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"503":{"description":"Service Unavailable","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}}}},"delete":{"description":"Delete multiple files or folders. The operation is similar to the Unix ` + "`" + `rm -rf` + "`" + ` command.\n- If the path is a file, the file will be deleted.\n- If the path is a folder, all its contents will also be deleted.\n- If the path is a symlink, the symlink will be deleted without deleting the target.\n- If the path does not exist, the operation will be a no-op.\nWith trash set, the items are moved to the trash instead, and can be restored from it.","consumes":["application/json"],"tags":["Workspace"],"summary":"Delete workspace items","parameters":[{"description":"Request body","name":"request","in":"body","required":true,"schema":{"$ref":"#/definitions/handlers.WorkspaceItemDeleteRequest"}}],"responses":{"204":{"description":"No Content"},"400":{"description":"Bad Request","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"401":{"description":"Unauthorized","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"403":{"description":"Forbidden","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"409":{"description":"Conflict","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"429":{"description":"Too Many Requests","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"500":{"description":"Internal Server Error","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"503":{"description":"Service Unavailable","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}}}}},"/v1/workspace/items/batch":{"post":{"description":"Create several files or folders in one request, e.g. to scaffold a project.\nEvery item is attempted and gets its own result. With atomic set, the batch stops at the first failure\nand the items created before it are removed again, along with the items they replaced.","consumes":["application/json"],"produces":["application/json"],"tags":["Workspace"],"summary":"Create workspace items","parameters":[{"description":"Request body","name":"request","in":"body","required":true,"schema":{"$ref":"#/definitions/handlers.WorkspaceItemBatchCreateRequest"}}],"responses":{"200":{"description":"OK","schema":{"$ref":"#/definitions/handlers.WorkspaceItemBatchCreateResponse"}},"400":{"description":"Bad Request","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"401":{"description":"Unauthorized","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"403":{"description":"Forbidden","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"429":{"description":"Too Many Requests","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"503":{"description":"Service Unavailable","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}}}}},"/v1/workspace/items/copy":{"post":{"description":"Create a copy of a file or folder","consumes":["application/json"],"produces":["application/json"],"tags":["Workspace"],"summary":"Copy a file or folder","parameters":[{"description":"Request body","name":"request","in":"body","required":true,"schema":{"$ref":"#/definitions/handlers.WorkspaceItemCopyRequest"}}],"responses":{"200":{"description":"OK","schema":{"$ref":"#/definitions/handlers.WorkspaceItemCopyResponse"}},"400":{"description":"Bad Request","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"401":{"description":"Unauthorized","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"403":{"description":"Forbidden","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"404":{"description":"Not Found","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"409":{"description":"Conflict","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"429":{"description":"Too Many Requests","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"500":{"description":"Internal Server Error","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}}}}},"/v1/workspace/items/move":{"post":{"description":"Move an item to a new location. Can also be used for renaming an item.\nWith items instead of sourcePath and newPath, several items are moved in one request, in order. Every item\nis attempted and gets its own result, in a WorkspaceItemBatchMoveResponse. Items moved to the same path are not moved.","consumes":["application/json"],"produces":["application/json"],"tags":["Workspace"],"summary":"Move items","parameters":[{"description":"Request body","name":"request","in":"body","required":true,"schema":{"$ref":"#/definitions/handlers.WorkspaceItemMoveRequest"}}],"responses":{"200":{"description":"OK","schema":{"$ref":"#/definitions/handlers.WorkspaceItemMoveResponse"}},"400":{"description":"Bad Request","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"401":{"description":"Unauthorized","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"403":{"description":"Forbidden","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"404":{"description":"Not Found","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"409":{"description":"Conflict","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"429":{"description":"Too Many Requests","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"500":{"description":"Internal Server Error","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}}}}},"/v1/workspace/search":{"get":{"description":"Find files and folders under a path by name, and optionally text files by their content.\nA query with *, ? or [ is matched as a glob against the names, otherwise as a substring. Binary files\nand files over 10MB are not searched by content. Symlinks are not followed.","produces":["application/json"],"tags":["Workspace"],"summary":"Search workspace items","parameters":[{"type":"string","description":"Name substring or glob pattern to search for","name":"q","in":"query","required":true},{"type":"string","description":"Path to the directory to search under (default is root)","name":"path","in":"query"},{"type":"boolean","description":"Also search the contents of the text files","name":"content","in":"query"},{"maximum":1000,"minimum":0,"type":"integer","default":100,"description":"Maximum number of results","name":"limit","in":"query"}],"responses":{"200":{"description":"OK","schema":{"$ref":"#/definitions/handlers.WorkspaceSearchResponse"}},"400":{"description":"Bad Request","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"401":{"description":"Unauthorized","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"403":{"description":"Forbidden","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"404":{"description":"Not Found","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"429":{"description":"Too Many Requests","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"500":{"description":"Internal Server Error","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"503":{"description":"Service Unavailable","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}}}}},"/v1/workspace/trash":{"get":{"description":"List the items deleted to the trash, the most recently deleted first","produces":["application/json"],"tags":["Workspace"],"summary":"List the trash","responses":{"200":{"description":"OK","schema":{"$ref":"#/definitions/handlers.WorkspaceTrashResponse"}},"400":{"description":"Bad Request","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"401":{"description":"Unauthorized","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"403":{"description":"Forbidden","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"429":{"description":"Too Many Requests","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"500":{"description":"Internal Server Error","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"503":{"description":"Service Unavailable","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}}}},"delete":{"description":"Permanently delete all the items in the trash","tags":["Workspace"],"summary":"Empty the trash","responses":{"204":{"description":"No Content"},"400":{"description":"Bad Request","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"401":{"description":"Unauthorized","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"403":{"description":"Forbidden","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"429":{"description":"Too Many Requests","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"500":{"description":"Internal Server Error","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"503":{"description":"Service Unavailable","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}}}}},"/v1/workspace/trash/restore":{"post":{"description":"Move items back from the trash to where they were deleted from, creating the missing parent folders.\nNothing is restored if any of the items conflicts with an item created at its path since, unless overwrite is set.","consumes":["application/json"],"produces":["application/json"],"tags":["Workspace"],"summary":"Restore items from the trash","parameters":[{"description":"Request body","name":"request","in":"body","required":true,"schema":{"$ref":"#/definitions/handlers.WorkspaceTrashRestoreRequest"}}],"responses":{"200":{"description":"OK","schema":{"$ref":"#/definitions/handlers.WorkspaceTrashRestoreResponse"}},"400":{"description":"Bad Request","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"401":{"description":"Unauthorized","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"403":{"description":"Forbidden","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"404":{"description":"Not Found","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"409":{"description":"Conflict","schema":{"$ref":"#/definitions/handlers.WorkspaceConflictError"}},"429":{"description":"Too Many Requests","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"500":{"description":"Internal Server Error","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"503":{"description":"Service Unavailable","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}}}}},"/v1/workspace/upload":{"post":{"description":"Upload a file to the workspace and sync it right away. The content is streamed from the request body, or read from a local source file. Progress is reported on the sync event stream.","consumes":["application/octet-stream"],"produces":["application/json"],"tags":["Workspace"],"summary":"Upload file","parameters":[{"type":"string","description":"Full path of the file in the workspace","name":"path","in":"query","required":true},{"type":"string","description":"Absolute path to a local file in the workspace to upload instead of the request body","name":"source","in":"query"}],"responses":{"202":{"description":"Accepted","schema":{"$ref":"#/definitions/handlers.WorkspaceItem"}},"400":{"description":"Bad Request","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"401":{"description":"Unauthorized","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"403":{"description":"Forbidden","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"404":{"description":"Not Found","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"409":{"description":"Conflict","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"429":{"description":"Too Many Requests","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"500":{"description":"Internal Server Error","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"503":{"description":"Service Unavailable","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}}}}},"/v1/workspace/uploads":{"post":{"description":"Start an upload that is sent in chunks, for large files or unreliable connections.\nSend the chunks in order to /v1/workspace/uploads/{id}, then complete the upload to sync the file.","consumes":["application/json"],"produces":["application/json"],"tags":["Workspace"],"summary":"Start a resumable upload","parameters":[{"description":"Request body","name":"request","in":"body","required":true,"schema":{"$ref":"#/definitions/handlers.WorkspaceUploadSessionRequest"}}],"responses":{"201":{"description":"Created","schema":{"$ref":"#/definitions/handlers.WorkspaceUploadSession"}},"400":{"description":"Bad Request","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"401":{"description":"Unauthorized","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"429":{"description":"Too Many Requests","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"500":{"description":"Internal Server Error","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"503":{"description":"Service Unavailable","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}}}}},"/v1/workspace/uploads/{id}":{"get":{"description":"Get the state of an upload. After a dropped connection, resume it from the returned offset.","produces":["application/json"],"tags":["Workspace"],"summary":"Get a resumable upload","parameters":[{"type":"string","description":"Upload ID","name":"id","in":"path","required":true}],"responses":{"200":{"description":"OK","schema":{"$ref":"#/definitions/handlers.WorkspaceUploadSession"}},"401":{"description":"Unauthorized","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"404":{"description":"Not Found","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"429":{"description":"Too Many Requests","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"500":{"description":"Internal Server Error","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"503":{"description":"Service Unavailable","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}}}},"delete":{"description":"Cancel an upload and remove the content received so far","produces":["application/json"],"tags":["Workspace"],"summary":"Cancel a resumable upload","parameters":[{"type":"string","description":"Upload ID","name":"id","in":"path","required":true}],"responses":{"204":{"description":"No Content"},"401":{"description":"Unauthorized","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"404":{"description":"Not Found","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"409":{"description":"Conflict","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"429":{"description":"Too Many Requests","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"503":{"description":"Service Unavailable","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}}}},"patch":{"description":"Append the request body to an upload. The offset must be the number of bytes received so far.\nIf the connection drops, the bytes that arrived are kept. Get the upload to find where to resume.","consumes":["application/octet-stream"],"produces":["application/json"],"tags":["Workspace"],"summary":"Upload a chunk","parameters":[{"type":"string","description":"Upload ID","name":"id","in":"path","required":true},{"minimum":0,"type":"integer","description":"Offset of the chunk in the file","name":"offset","in":"query","required":true}],"responses":{"200":{"description":"OK","schema":{"$ref":"#/definitions/handlers.WorkspaceUploadSession"}},"400":{"description":"Bad Request","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"401":{"description":"Unauthorized","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"404":{"description":"Not Found","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"409":{"description":"Conflict","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"429":{"description":"Too Many Requests","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"500":{"description":"Internal Server Error","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"503":{"description":"Service Unavailable","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}}}}},"/v1/workspace/uploads/{id}/complete":{"post":{"description":"Move a fully received upload into place and sync it right away. Progress is reported on the sync event stream.","produces":["application/json"],"tags":["Workspace"],"summary":"Complete a resumable upload","parameters":[{"type":"string","description":"Upload ID","name":"id","in":"path","required":true}],"responses":{"202":{"description":"Accepted","schema":{"$ref":"#/definitions/handlers.WorkspaceItem"}},"400":{"description":"Bad Request","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"401":{"description":"Unauthorized","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"403":{"description":"Forbidden","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"404":{"description":"Not Found","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"409":{"description":"Conflict","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"429":{"description":"Too Many Requests","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"500":{"description":"Internal Server Error","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}},"503":{"description":"Service Unavailable","schema":{"$ref":"#/definitions/handlers.ControlPlaneError"}}}}}},"definitions":{"apps.AppInfo":{"type":"object","properties":{"branch":{"type":"string"},"commit":{"type":"string"},"id":{"type":"string"},"installedOn":{"type":"string"},"manifestDigest":{"description":"Digest of the approved manifest entry, empty when not installed from a manifest","type":"string"},"name":{"type":"string"},"path":{"type":"string"},"source":{"$ref":"#/definitions/apps.AppSource"},"sourceURI":{"type":"string"},"tag":{"type":"string"}}},"apps.AppProcessStatus":{"type":"string","enum":["new","running","stopped"],"x-enum-varnames":["StatusNew","StatusRunning","StatusStopped"]},"apps.AppSource":{"type":"string","enum":["git","local","manifest"],"x-enum-varnames":["AppSourceGit","AppSourceLocalDir","AppSourceManifest"]},"apps.ProcessStats":{"type":"object","properties":{"children":{"description":"Children processes","type":"array","items":{"$ref":"#/definitions/apps.ProcessStats"}},"cmdline":{"description":"Command line arguments for this app's process","type":"array","items":{"type":"string"}},"connections":{"description":"All connections this app is listening on","type":"array","items":{"$ref":"#/definitions/net.ConnectionStat"}},"cpuPercent":{"description":"Percentage of total CPU this app is using","type":"number"},"cpuTimes":{"description":"CPU times breakdown","allOf":[{"$ref":"#/definitions/cpu.TimesStat"}]},"cwd":{"description":"Current working directory of this app's process","type":"string"},"environ":{"description":"Environment variables for this app's process","type":"array","items":{"type":"string"}},"exe":{"description":"Executable path of this app's process","type":"string"},"gids":{"description":"List of groups this app is a member of","type":"array","items":{"type":"integer"}},"memoryInfo":{"description":"Memory info","allOf":[{"$ref":"#/definitions/process.MemoryInfoStat"}]},"memoryPercent":{"description":"Percentage of total RAM this app is using","type":"number"},"nice":{"description":"Nice value of this app's process","type":"integer"},"numThreads":{"description":"Number of threads this app is using","type":"integer"},"pid":{"description":"Process ID","type":"integer"},"processName":{"description":"Process Name","type":"string"},"status":{"description":"Status of the process","type":"array","items":{"type":"string"}},"uids":{"description":"List of user IDs this app is a member of","type":"array","items":{"type":"integer"}},"uptime":{"description":"How long the app has been running in milliseconds","type":"integer"},"username":{"description":"Username of the user this app is running as","type":"string"}}},"cpu.TimesStat":{"type":"object","properties":{"cpu":{"type":"string"},"guest":{"type":"number"},"guestNice":{"type":"number"},"idle":{"type":"number"},"iowait":{"type":"number"},"irq":{"type":"number"},"nice":{"type":"number"},"softirq":{"type":"number"},"steal":{"type":"number"},"system":{"type":"number"},"user":{"type":"number"}}},"handlers.ACLAccessChange":{"type":"object","properties":{"after":{"type":"string"},"before":{"description":"Access before and after the change: read, write or admin, empty for none","type":"string"},"path":{"type":"string"},"principal":{"description":"User the access changes for, or * for everyone","type":"string"}}},"handlers.AppInstallRequest":{"type":"object","required":["repoURL"],"properties":{"branch":{"description":"branch of the repo to install","type":"string"},"commit":{"description":"commit of the repo to install","type":"string"},"force":{"description":"force install","type":"boolean"},"repoURL":{"description":"url of the github repo to install","type":"string"},"tag":{"description":"tag of the repo to install","type":"string"}}},"handlers.AppListResponse":{"type":"object","properties":{"apps":{"description":"list of installed apps","type":"array","items":{"$ref":"#/definitions/handlers.AppResponse"}}}},"handlers.AppResponse":{"type":"object","properties":{"id":{"description":"Unique ID of the app [deprecated]","type":"string"},"info":{"description":"Info about the app","allOf":[{"$ref":"#/definitions/apps.AppInfo"}]},"name":{"description":"name of the app [deprecated]","type":"string"},"path":{"description":"Absolute path to the app from the workspace root [deprecated]","type":"string"},"pid":{"description":"Process ID of the app's run.sh","type":"integer"},"ports":{"description":"List of ports this app is listening on","type":"array","items":{"type":"integer"}},"processStats":{"description":"Extended process statistics (optional)","allOf":[{"$ref":"#/definitions/apps.ProcessStats"}]},"status":{"description":"Status of the app","allOf":[{"$ref":"#/definitions/apps.AppProcessStatus"}]}}},"handlers.BatchCreateStatus":{"type":"string","enum":["success","conflict","error","rolledBack","skipped"],"x-enum-comments":{"BatchCreateStatusRolledBack":"created, then removed as a later item failed","BatchCreateStatusSkipped":"not attempted as an earlier item failed"},"x-enum-varnames":["BatchCreateStatusSuccess","BatchCreateStatusConflict","BatchCreateStatusError","BatchCreateStatusRolledBack","BatchCreateStatusSkipped"]},"handlers.CRDTIncrementRequest":{"type":"object","required":["path"],"properties":{"by":{"description":"defaults to 1","type":"integer"},"path":{"type":"string"}}},"handlers.CRDTResponse":{"type":"object","properties":{"path":{"type":"string"},"type":{"description":"g-counter or or-set","type":"string"},"value":{"description":"the count of a g-counter, the sorted elements of an or-set"}}},"handlers.CRDTSetRequest":{"type":"object","required":["element","path"],"properties":{"element":{"type":"string"},"path":{"type":"string"}}},"handlers.ContentEncoding":{"type":"string","enum":["utf8","base64"],"x-enum-comments":{"ContentEncodingBase64":"Standard base64, decoded before writing. For binary files","ContentEncodingUTF8":"Plain text, written as is"},"x-enum-varnames":["ContentEncodingUTF8","ContentEncodingBase64"]},"handlers.ControlPlaneError":{"type":"object","properties":{"code":{"type":"string"},"error":{"type":"string"}}},"handlers.ControlPlaneResponse":{"type":"object","properties":{"code":{"type":"string"}}},"handlers.DatasiteConfig":{"type":"object","properties":{"data_dir":{"type":"string"},"email":{"type":"string"},"server_url":{"type":"string"}}},"handlers.DatasiteInfo":{"type":"object","properties":{"apps":{"description":"startup of the apps, e.g. waiting for the initial sync.","type":"string"},"config":{"description":"config of the datasite.","allOf":[{"$ref":"#/definitions/handlers.DatasiteConfig"}]},"error":{"description":"error message if the datasite is not ready.","type":"string"},"readOnly":{"description":"sync only downloads, and never uploads local changes.","type":"boolean"},"status":{"description":"status of the datasite.","type":"string"},"sync":{"description":"sync activity worth surfacing, e.g. resyncing after a resume.","type":"string"}}},"handlers.InitDatasiteRequest":{"type":"object","required":["dataDir","email","serverUrl","token"],"properties":{"dataDir":{"description":"datasite directory","type":"string"},"email":{"description":"email of the user","type":"string"},"serverUrl":{"description":"syftbox server url","type":"string"},"token":{"description":"email token of the user","type":"string"}}},"handlers.LogEntry":{"type":"object","properties":{"lineNumber":{"type":"integer"},"message":{"type":"string"},"timestamp":{"type":"string"}}},"handlers.LogsResponse":{"type":"object","properties":{"hasMore":{"description":"Whether there are more logs to retrieve.","type":"boolean"},"logs":{"description":"A list of log items.","type":"array","items":{"$ref":"#/definitions/handlers.LogEntry"}},"nextToken":{"description":"A pagination token to retrieve the next page of logs.","type":"integer"}}},"handlers.Permission":{"type":"object","properties":{"avatar":{"type":"string"},"email":{"type":"string"},"id":{"type":"string"},"name":{"type":"string"},"type":{"description":"\"read\", \"write\", or \"admin\"","type":"string"},"userId":{"type":"string"}}},"handlers.StatusResponse":{"type":"object","properties":{"buildDate":{"description":"build date of the client.","type":"string"},"datasite":{"description":"datasite status.","allOf":[{"$ref":"#/definitions/handlers.DatasiteInfo"}]},"revision":{"description":"revision of the client.","type":"string"},"status":{"description":"health status (\"ok\").","type":"string"},"ts":{"description":"timestamp when health check was performed.","type":"string"},"version":{"description":"version of the client.","type":"string"}}},"handlers.SyncEvent":{"type":"object","properties":{"conflictState":{"description":"none, conflicted, rejected or quarantined","type":"string"},"error":{"description":"error message if the sync failed","type":"string"},"errorCount":{"description":"number of failed sync attempts","type":"integer"},"path":{"description":"workspace path of the file, e.g. /datasites/user@example.com/public/file.txt","type":"string"},"progress":{"description":"progress of the current state, 0-100","type":"number"},"reason":{"description":"why the server rejected the file, or the verify hook quarantined it","type":"string"},"syncState":{"description":"pending, syncing, completed or error","type":"string"},"updatedAt":{"description":"time of the status change","type":"string"}}},"handlers.SyncLatencyStats":{"type":"object","properties":{"count":{"description":"number of samples since the client started","type":"integer"},"max":{"description":"maximum latency in the window","type":"number"},"p50":{"description":"median latency","type":"number"},"p90":{"description":"90th percentile latency","type":"number"},"p99":{"description":"99th percentile latency","type":"number"},"samples":{"description":"number of samples in the window","type":"integer"}}},"handlers.SyncMetricsResponse":{"type":"object","properties":{"download":{"description":"remote change notified or detected to file written locally","allOf":[{"$ref":"#/definitions/handlers.SyncLatencyStats"}]},"upload":{"description":"local change detected to upload confirmed by the server","allOf":[{"$ref":"#/definitions/handlers.SyncLatencyStats"}]}}},"handlers.SyncStatus":{"type":"string","enum":["synced","syncing","pending","rejected","error","ignored","hidden"],"x-enum-varnames":["SyncStatusSynced","SyncStatusSyncing","SyncStatusPending","SyncStatusRejected","SyncStatusError","SyncStatusIgnored","SyncStatusHidden"]},"handlers.UpdateMode":{"type":"string","enum":["overwrite","append","prepend"],"x-enum-comments":{"UpdateModeAppend":"Add content to end of file","UpdateModeOverwrite":"Replace entire file content","UpdateModePrepend":"Add content to start of file"},"x-enum-varnames":["UpdateModeOverwrite","UpdateModeAppend","UpdateModePrepend"]},"handlers.WorkspaceACLPreviewRequest":{"type":"object","required":["content","path"],"properties":{"content":{"description":"Proposed content of the syft.pub.yaml","type":"string"},"path":{"description":"Full path of the folder of the syft.pub.yaml, e.g. /datasites/user@example.com/public","type":"string"}}},"handlers.WorkspaceACLPreviewResponse":{"type":"object","properties":{"granted":{"type":"array","items":{"$ref":"#/definitions/handlers.ACLAccessChange"}},"revoked":{"type":"array","items":{"$ref":"#/definitions/handlers.ACLAccessChange"}},"shadowedBy":{"description":"Folder of a terminal syft.pub.yaml above, which keeps the proposed one from applying","type":"string"}}},"handlers.WorkspaceConflictError":{"type":"object","properties":{"error":{"type":"string"},"errorCode":{"type":"string"},"existingItem":{"$ref":"#/definitions/handlers.WorkspaceItem"}}},"handlers.WorkspaceContentUpdateRequest":{"type":"object","required":["content","mode","path"],"properties":{"content":{"type":"string"},"create":{"description":"Create file if it doesn't exist","type":"boolean","default":false},"encoding":{"description":"Encoding of the content","default":"utf8","enum":["utf8","base64"],"allOf":[{"$ref":"#/definitions/handlers.ContentEncoding"}]},"mode":{"default":"overwrite","enum":["overwrite","append","prepend"],"allOf":[{"$ref":"#/definitions/handlers.UpdateMode"}]},"path":{"type":"string"}}},"handlers.WorkspaceItem":{"type":"object","properties":{"absolutePath":{"type":"string"},"children":{"type":"array","items":{"$ref":"#/definitions/handlers.WorkspaceItem"}},"createdAt":{"type":"string"},"id":{"type":"string"},"modifiedAt":{"type":"string"},"name":{"type":"string"},"path":{"type":"string"},"permissions":{"type":"array","items":{"$ref":"#/definitions/handlers.Permission"}},"size":{"type":"integer"},"syncStatus":{"$ref":"#/definitions/handlers.SyncStatus"},"type":{"$ref":"#/definitions/handlers.WorkspaceItemType"}}},"handlers.WorkspaceItemBatchCreateRequest":{"type":"object","required":["items"],"properties":{"atomic":{"description":"Stop at the first failure and remove the items created before it","type":"boolean","default":false},"items":{"type":"array","minItems":1,"items":{"$ref":"#/definitions/handlers.WorkspaceItemCreateRequest"}}}},"handlers.WorkspaceItemBatchCreateResponse":{"type":"object","properties":{"results":{"type":"array","items":{"$ref":"#/definitions/handlers.WorkspaceItemBatchCreateResult"}}}},"handlers.WorkspaceItemBatchCreateResult":{"type":"object","properties":{"error":{"type":"string"},"existingItem":{"$ref":"#/definitions/handlers.WorkspaceItem"},"item":{"$ref":"#/definitions/handlers.WorkspaceItem"},"path":{"type":"string"},"status":{"$ref":"#/definitions/handlers.BatchCreateStatus"}}},"handlers.WorkspaceItemCopyRequest":{"type":"object","required":["newPath","sourcePath"],"properties":{"newPath":{"description":"Full path of the new item location, including the item name","type":"string"},"overwrite":{"description":"Overwrite the destination item if it exists","type":"boolean","default":false},"sourcePath":{"description":"Full path of the item to copy","type":"string"}}},"handlers.WorkspaceItemCopyResponse":{"type":"object","properties":{"item":{"$ref":"#/definitions/handlers.WorkspaceItem"}}},"handlers.WorkspaceItemCreateRequest":{"type":"object","required":["path","type"],"properties":{"existOk":{"description":"Return an existing folder as is instead of a conflict, like mkdir -p. Files still conflict","type":"boolean","default":false},"overwrite":{"type":"boolean","default":false},"path":{"type":"string"},"type":{"enum":["file","folder"],"allOf":[{"$ref":"#/definitions/handlers.WorkspaceItemType"}]}}},"handlers.WorkspaceItemCreateResponse":{"type":"object","properties":{"item":{"$ref":"#/definitions/handlers.WorkspaceItem"}}},"handlers.WorkspaceItemDeleteRequest":{"type":"object","required":["paths"],"properties":{"paths":{"type":"array","items":{"type":"string"}},"trash":{"description":"Move the items to the trash instead of deleting them permanently, to restore them later","type":"boolean","default":false}}},"handlers.WorkspaceItemMovePair":{"type":"object","required":["newPath","sourcePath"],"properties":{"newPath":{"description":"Full path to the new item location, including the item name","type":"string"},"sourcePath":{"description":"Full path to the source item","type":"string"}}},"handlers.WorkspaceItemMoveRequest":{"type":"object","properties":{"items":{"description":"Items to move in one request instead of sourcePath and newPath, each with its own result","type":"array","items":{"$ref":"#/definitions/handlers.WorkspaceItemMovePair"}},"newPath":{"description":"Full path to the new item location, including the item name","type":"string"},"overwrite":{"description":"Overwrite the destination item if it exists","type":"boolean","default":false},"sourcePath":{"description":"Full path to the source item","type":"string"}}},"handlers.WorkspaceItemMoveResponse":{"type":"object","properties":{"item":{"$ref":"#/definitions/handlers.WorkspaceItem"}}},"handlers.WorkspaceItemType":{"type":"string","enum":["file","folder"],"x-enum-varnames":["WorkspaceItemTypeFile","WorkspaceItemTypeFolder"]},"handlers.WorkspaceItemsResponse":{"type":"object","properties":{"items":{"type":"array","items":{"$ref":"#/definitions/handlers.WorkspaceItem"}}}},"handlers.WorkspaceSearchResponse":{"type":"object","properties":{"results":{"type":"array","items":{"$ref":"#/definitions/handlers.WorkspaceSearchResult"}},"truncated":{"description":"More items matched than the limit","type":"boolean"}}},"handlers.WorkspaceSearchResult":{"type":"object","properties":{"item":{"$ref":"#/definitions/handlers.WorkspaceItem"},"lines":{"description":"Line numbers in the content matching the query, starting at 1","type":"array","items":{"type":"integer"}},"nameMatch":{"description":"The name of the item matches the query","type":"boolean"}}},"handlers.WorkspaceTrashItem":{"type":"object","properties":{"deletedAt":{"type":"string"},"id":{"description":"Name of the item in the trash, the deletion time followed by the name of the item","type":"string"},"name":{"type":"string"},"originalPath":{"description":"Full path the item was deleted from, where it's restored to","type":"string"},"size":{"type":"integer"},"type":{"$ref":"#/definitions/handlers.WorkspaceItemType"}}},"handlers.WorkspaceTrashResponse":{"type":"object","properties":{"items":{"type":"array","items":{"$ref":"#/definitions/handlers.WorkspaceTrashItem"}}}},"handlers.WorkspaceTrashRestoreRequest":{"type":"object","required":["ids"],"properties":{"ids":{"description":"Ids of the items in the trash","type":"array","minItems":1,"items":{"type":"string"}},"overwrite":{"description":"Overwrite the items that were created at the original paths since","type":"boolean","default":false}}},"handlers.WorkspaceTrashRestoreResponse":{"type":"object","properties":{"items":{"type":"array","items":{"$ref":"#/definitions/handlers.WorkspaceItem"}}}},"handlers.WorkspaceUploadSession":{"type":"object","properties":{"createdAt":{"type":"string"},"id":{"type":"string"},"offset":{"description":"Number of bytes received so far, where the next chunk starts","type":"integer"},"path":{"type":"string"},"size":{"type":"integer"}}},"handlers.WorkspaceUploadSessionRequest":{"type":"object","required":["path"],"properties":{"path":{"description":"Full path of the file in the workspace, e.g. /datasites/user@example.com/public/file.bin","type":"string"},"size":{"description":"Size of the file in bytes","type":"integer","minimum":0}}},"net.ConnectionStat":{"type":"object","properties":{"family":{"type":"integer"},"fd":{"type":"integer"},"localaddr":{},"pid":{"type":"integer"},"remoteaddr":{},"status":{"type":"string"},"type":{"type":"integer"},"uids":{"type":"array","items":{"type":"integer"}}}},"process.MemoryInfoStat":{"type":"object","properties":{"data":{"description":"bytes","type":"integer"},"hwm":{"description":"bytes","type":"integer"},"locked":{"description":"bytes","type":"integer"},"rss":{"description":"bytes","type":"integer"},"stack":{"description":"bytes","type":"integer"},"swap":{"description":"bytes","type":"integer"},"vms":{"description":"bytes","type":"integer"}}}},"securityDefinitions":{"APIToken":{"type":"apiKey","name":"Authorization","in":"header"}},"security":[{"APIToken":[]}]}`
 
 // SwaggerInfo holds exported Swagger Info so clients can modify it
 var SwaggerInfo = &swag.Spec{
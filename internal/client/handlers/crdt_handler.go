@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/openmined/syftbox/internal/client/datasitemgr"
+	"github.com/openmined/syftbox/internal/client/workspace"
+	"github.com/openmined/syftbox/internal/crdt"
+)
+
+const (
+	ErrCodeCRDTNotFound     = "ERR_CRDT_NOT_FOUND"
+	ErrCodeCRDTTypeMismatch = "ERR_CRDT_TYPE_MISMATCH"
+	ErrCodeCRDTFailed       = "ERR_CRDT_FAILED"
+)
+
+// CRDTHandler reads and updates the crdt files apps coordinate with. Sync merges the concurrent
+// updates of the peers, the updates here use the datasite owner as the replica
+type CRDTHandler struct {
+	mgr *datasitemgr.DatasiteManager
+}
+
+// NewCRDTHandler creates a new crdt handler
+func NewCRDTHandler(mgr *datasitemgr.DatasiteManager) *CRDTHandler {
+	return &CRDTHandler{
+		mgr: mgr,
+	}
+}
+
+// Get returns the state of a crdt file
+//
+//	@Summary		Get a crdt
+//	@Description	Returns the type and the value of a crdt file: the count of a g-counter, the elements of an or-set
+//	@Tags			CRDT
+//	@Produce		json
+//	@Param			path	query		string	true	"Workspace path of the file, ending in .crdt.json"
+//	@Success		200		{object}	CRDTResponse
+//	@Failure		400		{object}	ControlPlaneError
+//	@Failure		401		{object}	ControlPlaneError
+//	@Failure		404		{object}	ControlPlaneError
+//	@Failure		500		{object}	ControlPlaneError
+//	@Failure		503		{object}	ControlPlaneError
+//	@Router			/v1/crdt [get]
+func (h *CRDTHandler) Get(c *gin.Context) {
+	var req CRDTRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		AbortWithError(c, http.StatusBadRequest, ErrCodeBadRequest, err)
+		return
+	}
+
+	ws, ok := h.workspace(c)
+	if !ok {
+		return
+	}
+	absPath, err := resolveCRDTPath(ws, req.Path)
+	if err != nil {
+		AbortWithError(c, http.StatusBadRequest, ErrCodeBadRequest, err)
+		return
+	}
+
+	doc, err := crdt.ReadFile(absPath)
+	if err != nil {
+		abortWithCRDTError(c, err)
+		return
+	}
+	c.PureJSON(http.StatusOK, newCRDTResponse(req.Path, doc))
+}
+
+// Increment increments a g-counter
+//
+//	@Summary		Increment a g-counter
+//	@Description	Increments the count of the datasite owner in a g-counter file, creating it if it doesn't exist
+//	@Tags			CRDT
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		CRDTIncrementRequest	true	"Counter to increment"
+//	@Success		200		{object}	CRDTResponse
+//	@Failure		400		{object}	ControlPlaneError
+//	@Failure		401		{object}	ControlPlaneError
+//	@Failure		409		{object}	ControlPlaneError
+//	@Failure		500		{object}	ControlPlaneError
+//	@Failure		503		{object}	ControlPlaneError
+//	@Router			/v1/crdt/counter/increment [post]
+func (h *CRDTHandler) Increment(c *gin.Context) {
+	var req CRDTIncrementRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		AbortWithError(c, http.StatusBadRequest, ErrCodeBadRequest, err)
+		return
+	}
+	if req.By == 0 {
+		req.By = 1
+	}
+
+	h.update(c, req.Path, crdt.TypeGCounter, func(replica string, doc *crdt.Document) {
+		doc.Counter.Increment(replica, req.By)
+	})
+}
+
+// AddElement adds an element to an or-set
+//
+//	@Summary		Add to an or-set
+//	@Description	Adds an element to an or-set file, creating it if it doesn't exist
+//	@Tags			CRDT
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		CRDTSetRequest	true	"Element to add"
+//	@Success		200		{object}	CRDTResponse
+//	@Failure		400		{object}	ControlPlaneError
+//	@Failure		401		{object}	ControlPlaneError
+//	@Failure		409		{object}	ControlPlaneError
+//	@Failure		500		{object}	ControlPlaneError
+//	@Failure		503		{object}	ControlPlaneError
+//	@Router			/v1/crdt/set/add [post]
+func (h *CRDTHandler) AddElement(c *gin.Context) {
+	var req CRDTSetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		AbortWithError(c, http.StatusBadRequest, ErrCodeBadRequest, err)
+		return
+	}
+
+	h.update(c, req.Path, crdt.TypeORSet, func(replica string, doc *crdt.Document) {
+		doc.Set.Add(replica, req.Element)
+	})
+}
+
+// RemoveElement removes an element from an or-set
+//
+//	@Summary		Remove from an or-set
+//	@Description	Removes an element from an or-set file. An add of the element a peer makes concurrently wins
+//	@Tags			CRDT
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		CRDTSetRequest	true	"Element to remove"
+//	@Success		200		{object}	CRDTResponse
+//	@Failure		400		{object}	ControlPlaneError
+//	@Failure		401		{object}	ControlPlaneError
+//	@Failure		409		{object}	ControlPlaneError
+//	@Failure		500		{object}	ControlPlaneError
+//	@Failure		503		{object}	ControlPlaneError
+//	@Router			/v1/crdt/set/remove [post]
+func (h *CRDTHandler) RemoveElement(c *gin.Context) {
+	var req CRDTSetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		AbortWithError(c, http.StatusBadRequest, ErrCodeBadRequest, err)
+		return
+	}
+
+	h.update(c, req.Path, crdt.TypeORSet, func(_ string, doc *crdt.Document) {
+		doc.Set.Remove(req.Element)
+	})
+}
+
+// update applies an update to a crdt file, with the datasite owner as the replica
+func (h *CRDTHandler) update(c *gin.Context, path string, typ crdt.Type, update func(replica string, doc *crdt.Document)) {
+	ws, ok := h.workspace(c)
+	if !ok {
+		return
+	}
+	absPath, err := resolveCRDTPath(ws, path)
+	if err != nil {
+		AbortWithError(c, http.StatusBadRequest, ErrCodeBadRequest, err)
+		return
+	}
+
+	doc, err := crdt.UpdateFile(absPath, typ, func(doc *crdt.Document) error {
+		update(ws.Owner, doc)
+		return nil
+	})
+	if err != nil {
+		abortWithCRDTError(c, err)
+		return
+	}
+	c.PureJSON(http.StatusOK, newCRDTResponse(path, doc))
+}
+
+func (h *CRDTHandler) workspace(c *gin.Context) (*workspace.Workspace, bool) {
+	ds, err := h.mgr.Get()
+	if err != nil {
+		AbortWithError(c, http.StatusServiceUnavailable, ErrCodeDatasiteNotReady, err)
+		return nil, false
+	}
+	return ds.GetWorkspace(), true
+}
+
+// resolveCRDTPath resolves the workspace path of a crdt file, which must be in the datasites
+func resolveCRDTPath(ws *workspace.Workspace, path string) (string, error) {
+	if !strings.HasPrefix(path, "/") {
+		return "", errors.New("path must be an absolute path and start with /")
+	}
+	if !crdt.IsCRDTFile(path) {
+		return "", fmt.Errorf("path must end with %s", crdt.Ext)
+	}
+
+	absPath := filepath.Join(ws.Root, filepath.FromSlash(path))
+	relPath, err := filepath.Rel(ws.DatasitesDir, absPath)
+	if err != nil || relPath == "." || strings.HasPrefix(relPath, "..") {
+		return "", errors.New("path must be in the datasites")
+	}
+	return absPath, nil
+}
+
+func abortWithCRDTError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		AbortWithError(c, http.StatusNotFound, ErrCodeCRDTNotFound, errors.New("crdt not found"))
+	case errors.Is(err, crdt.ErrTypeMismatch):
+		AbortWithError(c, http.StatusConflict, ErrCodeCRDTTypeMismatch, err)
+	default:
+		AbortWithError(c, http.StatusInternalServerError, ErrCodeCRDTFailed, err)
+	}
+}
+
+func newCRDTResponse(path string, doc *crdt.Document) *CRDTResponse {
+	return &CRDTResponse{
+		Path:  path,
+		Type:  string(doc.Type),
+		Value: doc.Value(),
+	}
+}
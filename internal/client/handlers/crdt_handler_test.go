@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/openmined/syftbox/internal/client/datasitemgr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCRDTTestRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	_, ds := newWorkspaceTestRouter(t, nil)
+
+	h := NewCRDTHandler(datasitemgr.New(datasitemgr.WithDatasite(ds)))
+	r := gin.New()
+	r.GET("/v1/crdt", h.Get)
+	r.POST("/v1/crdt/counter/increment", h.Increment)
+	r.POST("/v1/crdt/set/add", h.AddElement)
+	r.POST("/v1/crdt/set/remove", h.RemoveElement)
+	return r
+}
+
+func getCRDT(t *testing.T, r *gin.Engine, path string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/v1/crdt?path="+url.QueryEscape(path), nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestCRDTCounter(t *testing.T) {
+	r := newCRDTTestRouter(t)
+	path := "/datasites/alice@example.com/app_data/poll/votes.crdt.json"
+
+	w := getCRDT(t, r, path)
+	assert.Equal(t, http.StatusNotFound, w.Code, w.Body.String())
+
+	w = postJSON(t, r, "/v1/crdt/counter/increment", &CRDTIncrementRequest{Path: path})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = postJSON(t, r, "/v1/crdt/counter/increment", &CRDTIncrementRequest{Path: path, By: 4})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = getCRDT(t, r, path)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp CRDTResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "g-counter", resp.Type)
+	assert.EqualValues(t, 5, resp.Value)
+
+	// a counter isn't a set
+	w = postJSON(t, r, "/v1/crdt/set/add", &CRDTSetRequest{Path: path, Element: "x"})
+	assert.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+}
+
+func TestCRDTSet(t *testing.T) {
+	r := newCRDTTestRouter(t)
+	path := "/datasites/alice@example.com/app_data/poll/voters.crdt.json"
+
+	for _, element := range []string{"bob@example.com", "carol@example.com"} {
+		w := postJSON(t, r, "/v1/crdt/set/add", &CRDTSetRequest{Path: path, Element: element})
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	}
+	w := postJSON(t, r, "/v1/crdt/set/remove", &CRDTSetRequest{Path: path, Element: "bob@example.com"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp CRDTResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "or-set", resp.Type)
+	assert.Equal(t, []any{"carol@example.com"}, resp.Value)
+}
+
+func TestCRDTInvalidPath(t *testing.T) {
+	r := newCRDTTestRouter(t)
+
+	for _, path := range []string{
+		"datasites/alice@example.com/votes.crdt.json",
+		"/datasites/alice@example.com/votes.json",
+		"/datasites/../votes.crdt.json",
+		"/apps/votes.crdt.json",
+	} {
+		w := postJSON(t, r, "/v1/crdt/counter/increment", &CRDTIncrementRequest{Path: path})
+		assert.Equal(t, http.StatusBadRequest, w.Code, path)
+	}
+}
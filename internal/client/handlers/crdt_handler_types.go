@@ -0,0 +1,25 @@
+package handlers
+
+// CRDTRequest represents the request parameters for reading a crdt file
+type CRDTRequest struct {
+	Path string `form:"path" binding:"required"` // workspace path of the file, ending in .crdt.json
+}
+
+// CRDTResponse has the state of a crdt file
+type CRDTResponse struct {
+	Path  string `json:"path"`
+	Type  string `json:"type"`  // g-counter or or-set
+	Value any    `json:"value"` // the count of a g-counter, the sorted elements of an or-set
+}
+
+// CRDTIncrementRequest increments a g-counter, creating it if it doesn't exist
+type CRDTIncrementRequest struct {
+	Path string `json:"path" binding:"required"`
+	By   uint64 `json:"by"` // defaults to 1
+}
+
+// CRDTSetRequest adds an element to, or removes one from, an or-set, creating it if it doesn't exist
+type CRDTSetRequest struct {
+	Path    string `json:"path" binding:"required"`
+	Element string `json:"element" binding:"required"`
+}
@@ -101,7 +101,7 @@ func (h *InitHandler) InitDatasite(c *gin.Context) {
 		DataDir:      req.DataDir,
 		ServerURL:    req.ServerURL,
 		ClientURL:    h.controlPlaneURL,
-		Email:        req.Email,
+		Email:        resp.CanonicalEmail(req.Email),
 		AccessToken:  resp.AccessToken,
 		RefreshToken: resp.RefreshToken,
 		AppsEnabled:  true,
@@ -22,15 +22,20 @@ import (
 // LogsHandler handles log-related requests
 type LogsHandler struct {
 	mgr          *datasitemgr.DatasiteManager
+	logFilePath  string // the system logs of this daemon
 	lineRegex    *regexp.Regexp
 	timeRegex    *regexp.Regexp
 	messageRegex *regexp.Regexp
 }
 
-// NewLogsHandler creates a new handler for logs
-func NewLogsHandler(mgr *datasitemgr.DatasiteManager) *LogsHandler {
+// NewLogsHandler creates a new handler for logs. The system logs are read from logFilePath, the default log file if empty
+func NewLogsHandler(mgr *datasitemgr.DatasiteManager, logFilePath string) *LogsHandler {
+	if logFilePath == "" {
+		logFilePath = config.DefaultLogFilePath
+	}
 	return &LogsHandler{
 		mgr:          mgr,
+		logFilePath:  logFilePath,
 		lineRegex:    regexp.MustCompile(`line=(\d+)`),
 		timeRegex:    regexp.MustCompile(`time=([^\s]+)`),
 		messageRegex: regexp.MustCompile(`^(?:line=\d+\s+)?(?:time=[^\s]+\s+)?(.*)$`),
@@ -40,7 +45,7 @@ func NewLogsHandler(mgr *datasitemgr.DatasiteManager) *LogsHandler {
 func (h *LogsHandler) getLogFilePath(appId string) string {
 	appId = strings.ToLower(appId)
 	if appId == "" || appId == "system" {
-		return config.DefaultLogFilePath
+		return h.logFilePath
 	}
 	datasite, err := h.mgr.Get()
 	if err != nil {
@@ -415,14 +420,19 @@ func (h *LogsHandler) DownloadLogs(c *gin.Context) {
 	zipWriter := zip.NewWriter(tmpFile)
 	defer zipWriter.Close()
 
-	// Add all logs from the system logs directory
-	systemLogDir := filepath.Dir(config.DefaultLogFilePath)
+	// Add the system logs of this daemon, the other daemons log to the same directory
+	systemLogDir := filepath.Dir(h.logFilePath)
+	systemLogName := filepath.Base(h.logFilePath)
+	rotatedPrefix := strings.TrimSuffix(systemLogName, filepath.Ext(systemLogName)) + "."
 	entries, err := os.ReadDir(systemLogDir)
 	if err != nil {
 		slog.Default().Warn("failed to read system logs directory", "error", err)
 	} else {
 		for _, entry := range entries {
-			if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".log") {
+			// along with the rotated logs, compressed or not
+			isLog := strings.HasSuffix(entry.Name(), ".log") || strings.HasSuffix(entry.Name(), ".log.gz")
+			ownLog := entry.Name() == systemLogName || strings.HasPrefix(entry.Name(), rotatedPrefix)
+			if !entry.IsDir() && isLog && ownLog {
 				logPath := filepath.Join(systemLogDir, entry.Name())
 				if err := h.addFileToZip(zipWriter, logPath, entry.Name()); err != nil {
 					slog.Default().Warn("failed to add log file to zip", "file", entry.Name(), "error", err)
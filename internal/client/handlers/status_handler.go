@@ -42,6 +42,9 @@ func (h *StatusHandler) Status(c *gin.Context) {
 
 	var dsConfig *DatasiteConfig
 	var errorMessage string
+	var syncActivity string
+	var readOnly bool
+	var appsStartup string
 
 	status := h.mgr.Status()
 	if status.Status == datasitemgr.DatasiteStatusProvisioning || status.Status == datasitemgr.DatasiteStatusProvisioned {
@@ -52,6 +55,19 @@ func (h *StatusHandler) Status(c *gin.Context) {
 			Email:     cfg.Email,
 			ServerURL: cfg.ServerURL,
 		}
+		readOnly = cfg.SyncReadOnly
+		if sched := status.Datasite.GetAppScheduler(); sched != nil {
+			appsStartup = string(sched.Startup())
+		}
+		if status.Status == datasitemgr.DatasiteStatusProvisioned {
+			if syncMgr := status.Datasite.GetSyncManager(); syncMgr != nil {
+				if syncMgr.IsResuming() {
+					syncActivity = SyncActivityResuming
+				} else if syncMgr.IsReconnecting() {
+					syncActivity = SyncActivityReconnecting
+				}
+			}
+		}
 	} else if status.DatasiteError != nil {
 		errorMessage = status.DatasiteError.Error()
 	}
@@ -63,9 +79,12 @@ func (h *StatusHandler) Status(c *gin.Context) {
 		Revision:  version.Revision,
 		BuildDate: version.BuildDate,
 		Datasite: &DatasiteInfo{
-			Status: string(status.Status),
-			Error:  errorMessage,
-			Config: dsConfig,
+			Status:   string(status.Status),
+			Sync:     syncActivity,
+			ReadOnly: readOnly,
+			Apps:     appsStartup,
+			Error:    errorMessage,
+			Config:   dsConfig,
 		},
 	})
 }
@@ -10,10 +10,19 @@ type StatusResponse struct {
 	Datasite  *DatasiteInfo `json:"datasite"`  // datasite status.
 }
 
+// SyncActivityResuming is reported while sync catches up after the system resumed from sleep
+const SyncActivityResuming = "resumed, resyncing"
+
+// SyncActivityReconnecting is reported while sync catches up after the network changed
+const SyncActivityReconnecting = "network changed, resyncing"
+
 type DatasiteInfo struct {
-	Status string          `json:"status"`           // status of the datasite.
-	Error  string          `json:"error,omitempty"`  // error message if the datasite is not ready.
-	Config *DatasiteConfig `json:"config,omitempty"` // config of the datasite.
+	Status   string          `json:"status"`             // status of the datasite.
+	Sync     string          `json:"sync,omitempty"`     // sync activity worth surfacing, e.g. resyncing after a resume.
+	ReadOnly bool            `json:"readOnly,omitempty"` // sync only downloads, and never uploads local changes.
+	Apps     string          `json:"apps,omitempty"`     // startup of the apps, e.g. waiting for the initial sync.
+	Error    string          `json:"error,omitempty"`    // error message if the datasite is not ready.
+	Config   *DatasiteConfig `json:"config,omitempty"`   // config of the datasite.
 }
 
 type DatasiteConfig struct {
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/openmined/syftbox/internal/client/datasitemgr"
+	"github.com/openmined/syftbox/internal/client/sync"
+	"github.com/openmined/syftbox/internal/client/workspace"
+)
+
+const (
+	syncEventName      = "sync"
+	syncEventKeepAlive = 15 * time.Second
+)
+
+// SyncHandler handles sync-related endpoints
+type SyncHandler struct {
+	mgr *datasitemgr.DatasiteManager
+}
+
+// NewSyncHandler creates a new sync handler
+func NewSyncHandler(mgr *datasitemgr.DatasiteManager) *SyncHandler {
+	return &SyncHandler{
+		mgr: mgr,
+	}
+}
+
+// Events streams sync status changes
+//
+//	@Summary		Stream sync events
+//	@Description	Stream sync status changes of workspace files as server-sent events. Each "sync" event carries a SyncEvent.
+//	@Tags			Sync
+//	@Produce		text/event-stream
+//	@Success		200	{object}	SyncEvent
+//	@Failure		401	{object}	ControlPlaneError
+//	@Failure		503	{object}	ControlPlaneError
+//	@Router			/v1/sync/events [get]
+func (h *SyncHandler) Events(c *gin.Context) {
+	ds, err := h.mgr.Get()
+	if err != nil {
+		c.PureJSON(http.StatusServiceUnavailable, &ControlPlaneError{
+			ErrorCode: ErrCodeDatasiteNotReady,
+			Error:     err.Error(),
+		})
+		return
+	}
+
+	ws := ds.GetWorkspace()
+	syncStatus := ds.GetSyncManager().GetSyncStatus()
+
+	events := syncStatus.Subscribe()
+	defer syncStatus.Unsubscribe(events)
+
+	keepAlive := time.NewTicker(syncEventKeepAlive)
+	defer keepAlive.Stop()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case <-keepAlive.C:
+			c.SSEvent("ping", time.Now().UTC().Format(time.RFC3339))
+			return true
+		case event, ok := <-events:
+			if !ok {
+				// sync engine stopped
+				return false
+			}
+			c.SSEvent(syncEventName, NewSyncEvent(ws, event))
+			return true
+		}
+	})
+}
+
+// Metrics returns the sync latency percentiles
+//
+//	@Summary		Get sync metrics
+//	@Description	Returns the end-to-end replication latency percentiles of recent uploads and downloads, in milliseconds
+//	@Tags			Sync
+//	@Produce		json
+//	@Success		200	{object}	SyncMetricsResponse
+//	@Failure		401	{object}	ControlPlaneError
+//	@Failure		503	{object}	ControlPlaneError
+//	@Router			/v1/sync/metrics [get]
+func (h *SyncHandler) Metrics(c *gin.Context) {
+	ds, err := h.mgr.Get()
+	if err != nil {
+		c.PureJSON(http.StatusServiceUnavailable, &ControlPlaneError{
+			ErrorCode: ErrCodeDatasiteNotReady,
+			Error:     err.Error(),
+		})
+		return
+	}
+
+	latency := ds.GetSyncManager().GetSyncLatency()
+	c.PureJSON(http.StatusOK, &SyncMetricsResponse{
+		Upload:   newSyncLatencyStats(latency.Stats(sync.LatencyUpload)),
+		Download: newSyncLatencyStats(latency.Stats(sync.LatencyDownload)),
+	})
+}
+
+func newSyncLatencyStats(stats *sync.LatencyStats) *SyncLatencyStats {
+	return &SyncLatencyStats{
+		Count:   stats.Count,
+		Samples: stats.Samples,
+		P50:     toMillis(stats.P50),
+		P90:     toMillis(stats.P90),
+		P99:     toMillis(stats.P99),
+		Max:     toMillis(stats.Max),
+	}
+}
+
+func toMillis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// NewSyncEvent describes a sync status change with the workspace path of the file
+func NewSyncEvent(ws *workspace.Workspace, event *sync.SyncStatusEvent) *SyncEvent {
+	path := event.Path.String()
+	if relPath, err := filepath.Rel(ws.Root, ws.DatasiteAbsPath(path)); err == nil {
+		path = filepath.Join("/", filepath.ToSlash(relPath))
+	}
+
+	var errMsg string
+	if event.Status.Error != nil {
+		errMsg = event.Status.Error.Error()
+	}
+
+	return &SyncEvent{
+		Path:          filepath.ToSlash(path),
+		SyncState:     string(event.Status.SyncState),
+		ConflictState: string(event.Status.ConflictState),
+		Progress:      event.Status.Progress,
+		Error:         errMsg,
+		Reason:        event.Status.Reason,
+		ErrorCount:    event.Status.ErrorCount,
+		UpdatedAt:     event.Status.LastUpdated,
+	}
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import "time"
+
+// SyncEvent represents a sync status change of a workspace file, sent over the sync event stream
+type SyncEvent struct {
+	Path          string    `json:"path"`             // workspace path of the file, e.g. /datasites/user@example.com/public/file.txt
+	SyncState     string    `json:"syncState"`        // pending, syncing, completed or error
+	ConflictState string    `json:"conflictState"`    // none, conflicted, rejected or quarantined
+	Progress      float64   `json:"progress"`         // progress of the current state, 0-100
+	Error         string    `json:"error,omitempty"`  // error message if the sync failed
+	Reason        string    `json:"reason,omitempty"` // why the server rejected the file, or the verify hook quarantined it
+	ErrorCount    int       `json:"errorCount"`       // number of failed sync attempts
+	UpdatedAt     time.Time `json:"updatedAt"`        // time of the status change
+}
+
+// SyncMetricsResponse has the end-to-end replication latencies of recent syncs
+type SyncMetricsResponse struct {
+	Upload   *SyncLatencyStats `json:"upload"`   // local change detected to upload confirmed by the server
+	Download *SyncLatencyStats `json:"download"` // remote change notified or detected to file written locally
+}
+
+// SyncLatencyStats are the latency percentiles in milliseconds, over a window of recent samples
+type SyncLatencyStats struct {
+	Count   int64   `json:"count"`   // number of samples since the client started
+	Samples int     `json:"samples"` // number of samples in the window
+	P50     float64 `json:"p50"`     // median latency
+	P90     float64 `json:"p90"`     // 90th percentile latency
+	P99     float64 `json:"p99"`     // 99th percentile latency
+	Max     float64 `json:"max"`     // maximum latency in the window
+}
@@ -1,14 +1,21 @@
 package handlers
 
 import (
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/openmined/syftbox/internal/client/datasite"
 	"github.com/openmined/syftbox/internal/client/datasitemgr"
+	"github.com/openmined/syftbox/internal/client/sync"
+	"github.com/openmined/syftbox/internal/client/workspace"
 )
 
 const (
@@ -18,15 +25,22 @@ const (
 	ErrCodeMoveWorkspaceItemsFailed  = "ERR_MOVE_WORKSPACE_ITEMS_FAILED"
 	ErrCodeCopyWorkspaceItemsFailed  = "ERR_COPY_WORKSPACE_ITEMS_FAILED"
 	ErrCodeGetWorkspaceContentFailed = "ERR_GET_WORKSPACE_CONTENT_FAILED"
+	ErrCodeUploadWorkspaceItemFailed = "ERR_UPLOAD_WORKSPACE_ITEM_FAILED"
+	ErrCodeSearchWorkspaceFailed     = "ERR_SEARCH_WORKSPACE_FAILED"
+	ErrCodePreviewACLFailed          = "ERR_PREVIEW_ACL_FAILED"
 )
 
 type WorkspaceHandler struct {
-	mgr *datasitemgr.DatasiteManager
+	mgr     *datasitemgr.DatasiteManager
+	uploads *uploadSessions
+	thumbs  *thumbCache
 }
 
 func NewWorkspaceHandler(mgr *datasitemgr.DatasiteManager) *WorkspaceHandler {
 	return &WorkspaceHandler{
-		mgr: mgr,
+		mgr:     mgr,
+		uploads: newUploadSessions(),
+		thumbs:  newThumbCache(),
 	}
 }
 
@@ -76,7 +90,7 @@ func (h *WorkspaceHandler) GetItems(c *gin.Context) {
 	}
 
 	// List items at the path
-	items, err := h.listItems(absPath, ws.Root, req.Depth)
+	items, err := h.listItems(ds, absPath, ws.Root, req.Depth, newPermissionResolver(ws.DatasitesDir))
 	if err != nil {
 		c.PureJSON(http.StatusInternalServerError, &ControlPlaneError{
 			ErrorCode: ErrCodeListWorkspaceItemsFailed,
@@ -127,16 +141,53 @@ func (h *WorkspaceHandler) CreateItem(c *gin.Context) {
 		return
 	}
 
+	item, status, err := createItem(ds, &req, nil)
+	if err != nil {
+		var exists *itemExistsError
+		if errors.As(err, &exists) {
+			c.PureJSON(http.StatusConflict, &WorkspaceConflictError{
+				ErrorCode:    ErrCodeCreateWorkspaceItemFailed,
+				Error:        err.Error(),
+				ExistingItem: exists.item,
+			})
+			return
+		}
+
+		errorCode := ErrCodeCreateWorkspaceItemFailed
+		if status == http.StatusBadRequest {
+			errorCode = ErrCodeBadRequest
+		}
+		c.PureJSON(status, &ControlPlaneError{
+			ErrorCode: errorCode,
+			Error:     err.Error(),
+		})
+		return
+	}
+
+	c.PureJSON(http.StatusCreated, &WorkspaceItemCreateResponse{
+		Item: *item,
+	})
+}
+
+// itemExistsError is returned when creating an item over an existing one without overwrite
+type itemExistsError struct {
+	path string
+	item WorkspaceItem // the existing item
+}
+
+func (e *itemExistsError) Error() string {
+	return "item already exists: " + e.path
+}
+
+// createItem creates a file or folder, and returns the status code to respond with on error.
+// Items replaced or created within a batch are recorded in tx, so that they can be rolled back.
+func createItem(ds *datasite.Datasite, req *WorkspaceItemCreateRequest, tx *createTx) (*WorkspaceItem, int, error) {
 	// Get the workspace
 	ws := ds.GetWorkspace()
 
 	// Make sure req.Path is a absolute path
 	if !strings.HasPrefix(req.Path, "/") {
-		c.PureJSON(http.StatusBadRequest, &ControlPlaneError{
-			ErrorCode: ErrCodeBadRequest,
-			Error:     "path must be an absolute path and start with /",
-		})
-		return
+		return nil, http.StatusBadRequest, errors.New("path must be an absolute path and start with /")
 	}
 
 	// Resolve the path
@@ -144,58 +195,34 @@ func (h *WorkspaceHandler) CreateItem(c *gin.Context) {
 
 	// Check if the item already exists
 	if existingInfo, err := os.Stat(absPath); err == nil {
+		if req.ExistOK && req.Type == WorkspaceItemTypeFolder && existingInfo.IsDir() {
+			// nothing to create, like mkdir -p
+			item := existingItem(ds, absPath, existingInfo)
+			return &item, 0, nil
+		}
+
 		if req.Overwrite {
 			// If overwrite is true, remove the existing file/directory
-			if existingInfo.IsDir() {
+			if tx != nil {
+				err = tx.moveAside(absPath)
+			} else if existingInfo.IsDir() {
 				err = os.RemoveAll(absPath)
 			} else {
 				err = os.Remove(absPath)
 			}
 
 			if err != nil {
-				c.PureJSON(http.StatusInternalServerError, &ControlPlaneError{
-					ErrorCode: ErrCodeCreateWorkspaceItemFailed,
-					Error:     "failed to remove existing item: " + err.Error(),
-				})
-				return
+				return nil, http.StatusInternalServerError, fmt.Errorf("failed to remove existing item: %w", err)
 			}
 		} else {
 			// If overwrite is false, return conflict error with the existing item
-			relPath, _ := filepath.Rel(ws.Root, absPath)
-			relPath = filepath.Join("/", filepath.ToSlash(relPath))
-
-			itemType := WorkspaceItemTypeFile
-			if existingInfo.IsDir() {
-				itemType = WorkspaceItemTypeFolder
-			}
-
-			existingItem := WorkspaceItem{
-				Id:           relPath,
-				Name:         filepath.Base(absPath),
-				Type:         itemType,
-				Path:         relPath,
-				AbsolutePath: absPath,
-				CreatedAt:    existingInfo.ModTime(),
-				ModifiedAt:   existingInfo.ModTime(),
-				Size:         existingInfo.Size(),
-				SyncStatus:   SyncStatusHidden,
-				Permissions:  []Permission{},
-				Children:     []WorkspaceItem{},
+			return nil, http.StatusConflict, &itemExistsError{
+				path: req.Path,
+				item: existingItem(ds, absPath, existingInfo),
 			}
-
-			c.PureJSON(http.StatusConflict, &WorkspaceConflictError{
-				ErrorCode:    ErrCodeCreateWorkspaceItemFailed,
-				Error:        "item already exists: " + req.Path,
-				ExistingItem: existingItem,
-			})
-			return
 		}
 	} else if !os.IsNotExist(err) {
-		c.PureJSON(http.StatusInternalServerError, &ControlPlaneError{
-			ErrorCode: ErrCodeCreateWorkspaceItemFailed,
-			Error:     err.Error(),
-		})
-		return
+		return nil, http.StatusInternalServerError, err
 	}
 
 	// Create the item
@@ -206,21 +233,20 @@ func (h *WorkspaceHandler) CreateItem(c *gin.Context) {
 		dirToCreate = filepath.Dir(absPath)
 	}
 
+	// the first of the dirs about to be created, or the item itself
+	created := topmostMissing(absPath)
+
 	// Create the directory
 	if err := os.MkdirAll(dirToCreate, 0755); err != nil {
-		c.PureJSON(http.StatusInternalServerError, &ControlPlaneError{
-			ErrorCode: ErrCodeCreateWorkspaceItemFailed,
-			Error:     err.Error(),
-		})
-		return
+		return nil, http.StatusInternalServerError, err
+	}
+	if tx != nil {
+		tx.created(created)
 	}
+
 	info, err := os.Stat(dirToCreate)
 	if err != nil {
-		c.PureJSON(http.StatusInternalServerError, &ControlPlaneError{
-			ErrorCode: ErrCodeCreateWorkspaceItemFailed,
-			Error:     err.Error(),
-		})
-		return
+		return nil, http.StatusInternalServerError, err
 	}
 	itemInfo = info
 
@@ -228,21 +254,13 @@ func (h *WorkspaceHandler) CreateItem(c *gin.Context) {
 		// Create a file
 		f, err := os.Create(absPath)
 		if err != nil {
-			c.PureJSON(http.StatusInternalServerError, &ControlPlaneError{
-				ErrorCode: ErrCodeCreateWorkspaceItemFailed,
-				Error:     err.Error(),
-			})
-			return
+			return nil, http.StatusInternalServerError, err
 		}
 		defer f.Close()
 
 		info, err := f.Stat()
 		if err != nil {
-			c.PureJSON(http.StatusInternalServerError, &ControlPlaneError{
-				ErrorCode: ErrCodeCreateWorkspaceItemFailed,
-				Error:     err.Error(),
-			})
-			return
+			return nil, http.StatusInternalServerError, err
 		}
 		itemInfo = info
 	}
@@ -250,16 +268,12 @@ func (h *WorkspaceHandler) CreateItem(c *gin.Context) {
 	// Get relative path for response
 	relPath, err := filepath.Rel(ws.Root, absPath)
 	if err != nil {
-		c.PureJSON(http.StatusInternalServerError, &ControlPlaneError{
-			ErrorCode: ErrCodeCreateWorkspaceItemFailed,
-			Error:     err.Error(),
-		})
-		return
+		return nil, http.StatusInternalServerError, err
 	}
 	relPath = filepath.Join("/", filepath.ToSlash(relPath))
 
 	// Create response item
-	item := WorkspaceItem{
+	return &WorkspaceItem{
 		Id:           relPath,
 		Name:         filepath.Base(absPath),
 		Type:         WorkspaceItemType(req.Type),
@@ -268,14 +282,57 @@ func (h *WorkspaceHandler) CreateItem(c *gin.Context) {
 		CreatedAt:    itemInfo.ModTime(),
 		ModifiedAt:   itemInfo.ModTime(),
 		Size:         itemInfo.Size(),
-		SyncStatus:   SyncStatusHidden, // TODO: Replace with actual sync status
-		Permissions:  []Permission{},   // TODO: Replace with actual permissions
+		SyncStatus:   itemSyncStatus(ds, absPath),
+		Permissions:  itemPermissions(ws.DatasitesDir, absPath, itemInfo.IsDir()),
 		Children:     []WorkspaceItem{},
+	}, 0, nil
+}
+
+// existingItem describes an item found at absPath
+func existingItem(ds *datasite.Datasite, absPath string, info os.FileInfo) WorkspaceItem {
+	ws := ds.GetWorkspace()
+	relPath, _ := filepath.Rel(ws.Root, absPath)
+	relPath = filepath.Join("/", filepath.ToSlash(relPath))
+
+	itemType := WorkspaceItemTypeFile
+	if info.IsDir() {
+		itemType = WorkspaceItemTypeFolder
 	}
 
-	c.PureJSON(http.StatusCreated, &WorkspaceItemCreateResponse{
-		Item: item,
-	})
+	return WorkspaceItem{
+		Id:           relPath,
+		Name:         filepath.Base(absPath),
+		Type:         itemType,
+		Path:         relPath,
+		AbsolutePath: absPath,
+		CreatedAt:    info.ModTime(),
+		ModifiedAt:   info.ModTime(),
+		Size:         info.Size(),
+		SyncStatus:   itemSyncStatus(ds, absPath),
+		Permissions:  itemPermissions(ws.DatasitesDir, absPath, info.IsDir()),
+		Children:     []WorkspaceItem{},
+	}
+}
+
+// topmostMissing returns the first path on the way to absPath that doesn't exist
+func topmostMissing(absPath string) string {
+	missing := absPath
+	for {
+		parent := filepath.Dir(missing)
+		if parent == missing {
+			return missing
+		}
+		if _, err := os.Lstat(parent); err == nil {
+			return missing
+		}
+		missing = parent
+	}
+}
+
+// withinRoot checks if absPath is root or under it
+func withinRoot(root string, absPath string) bool {
+	rel, err := filepath.Rel(root, absPath)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
 }
 
 // Delete workspace items
@@ -286,6 +343,7 @@ func (h *WorkspaceHandler) CreateItem(c *gin.Context) {
 //	@Description	- If the path is a folder, all its contents will also be deleted.
 //	@Description	- If the path is a symlink, the symlink will be deleted without deleting the target.
 //	@Description	- If the path does not exist, the operation will be a no-op.
+//	@Description	With trash set, the items are moved to the trash instead, and can be restored from it.
 //	@Tags			Workspace
 //	@Accept			json
 //	@Param			request	body		WorkspaceItemDeleteRequest	true	"Request body"
@@ -321,6 +379,9 @@ func (h *WorkspaceHandler) DeleteItems(c *gin.Context) {
 	// Get the workspace
 	ws := ds.GetWorkspace()
 
+	// the items of a request are trashed together
+	deletedAt := time.Now()
+
 	// Process each path
 	for _, path := range req.Paths {
 		// Make sure path is an absolute path
@@ -334,6 +395,22 @@ func (h *WorkspaceHandler) DeleteItems(c *gin.Context) {
 
 		// Resolve the path
 		absPath := filepath.Join(ws.Root, path)
+		if !withinRoot(ws.Root, absPath) {
+			c.PureJSON(http.StatusBadRequest, &ControlPlaneError{
+				ErrorCode: ErrCodeBadRequest,
+				Error:     "path is outside the workspace: " + path,
+			})
+			return
+		}
+		if req.Trash {
+			if err := checkTrashable(ws, absPath); err != nil {
+				c.PureJSON(http.StatusBadRequest, &ControlPlaneError{
+					ErrorCode: ErrCodeBadRequest,
+					Error:     err.Error(),
+				})
+				return
+			}
+		}
 
 		// Check if the path exists and get info
 		fileInfo, err := os.Lstat(absPath)
@@ -351,6 +428,17 @@ func (h *WorkspaceHandler) DeleteItems(c *gin.Context) {
 			return
 		}
 
+		if req.Trash {
+			if err := trashItem(ws, absPath, deletedAt); err != nil {
+				c.PureJSON(http.StatusInternalServerError, &ControlPlaneError{
+					ErrorCode: ErrCodeTrashWorkspaceItemsFailed,
+					Error:     err.Error(),
+				})
+				return
+			}
+			continue
+		}
+
 		// Handle different types of paths
 		if fileInfo.IsDir() {
 			// For directories, use RemoveAll to delete recursively
@@ -375,8 +463,10 @@ func (h *WorkspaceHandler) DeleteItems(c *gin.Context) {
 
 // MoveItems moves an item to a new location. Can also be used for renaming an item.
 //
-//	@Summary		Move item
+//	@Summary		Move items
 //	@Description	Move an item to a new location. Can also be used for renaming an item.
+//	@Description	With items instead of sourcePath and newPath, several items are moved in one request, in order. Every item
+//	@Description	is attempted and gets its own result, in a WorkspaceItemBatchMoveResponse. Items moved to the same path are not moved.
 //	@Tags			Workspace
 //	@Accept			json
 //	@Produce		json
@@ -399,6 +489,13 @@ func (h *WorkspaceHandler) MoveItems(c *gin.Context) {
 		})
 		return
 	}
+	if len(req.Items) > 0 && (req.SourcePath != "" || req.NewPath != "") {
+		c.PureJSON(http.StatusBadRequest, &ControlPlaneError{
+			ErrorCode: ErrCodeBadRequest,
+			Error:     "either sourcePath and newPath, or items",
+		})
+		return
+	}
 
 	// Get the datasite
 	ds, err := h.mgr.Get()
@@ -410,190 +507,169 @@ func (h *WorkspaceHandler) MoveItems(c *gin.Context) {
 		return
 	}
 
+	if len(req.Items) > 0 {
+		c.PureJSON(http.StatusOK, &WorkspaceItemBatchMoveResponse{
+			Results: moveItems(ds, req.Items, req.Overwrite),
+		})
+		return
+	}
+
+	item, status, err := moveItem(ds, req.SourcePath, req.NewPath, req.Overwrite)
+	if err != nil {
+		var exists *itemExistsError
+		if errors.As(err, &exists) {
+			c.PureJSON(http.StatusConflict, &WorkspaceConflictError{
+				ErrorCode:    ErrCodeMoveWorkspaceItemsFailed,
+				Error:        "destination already exists: " + req.NewPath,
+				ExistingItem: exists.item,
+			})
+			return
+		}
+
+		errorCode := ErrCodeMoveWorkspaceItemsFailed
+		if status == http.StatusBadRequest {
+			errorCode = ErrCodeBadRequest
+		}
+		c.PureJSON(status, &ControlPlaneError{
+			ErrorCode: errorCode,
+			Error:     err.Error(),
+		})
+		return
+	}
+
+	c.PureJSON(http.StatusOK, &WorkspaceItemMoveResponse{
+		Item: *item,
+	})
+}
+
+// moveItems moves the items of a batch in order, each with its own result.
+// The items moved to the same path as another one of the batch aren't moved, none of them would be kept
+func moveItems(ds *datasite.Datasite, items []WorkspaceItemMovePair, overwrite bool) []WorkspaceItemBatchMoveResult {
+	ws := ds.GetWorkspace()
+
+	dests := make(map[string]int, len(items))
+	for _, item := range items {
+		dests[filepath.Join(ws.Root, item.NewPath)]++
+	}
+
+	results := make([]WorkspaceItemBatchMoveResult, len(items))
+	for i, pair := range items {
+		results[i].SourcePath = pair.SourcePath
+		results[i].NewPath = pair.NewPath
+
+		if dests[filepath.Join(ws.Root, pair.NewPath)] > 1 {
+			results[i].Status = BatchCreateStatusConflict
+			results[i].Error = "more than one item moves to " + pair.NewPath
+			continue
+		}
+
+		moved, _, err := moveItem(ds, pair.SourcePath, pair.NewPath, overwrite)
+		if err == nil {
+			results[i].Status = BatchCreateStatusSuccess
+			results[i].Item = moved
+			continue
+		}
+
+		results[i].Error = err.Error()
+		var exists *itemExistsError
+		if errors.As(err, &exists) {
+			results[i].Status = BatchCreateStatusConflict
+			results[i].ExistingItem = &exists.item
+		} else {
+			results[i].Status = BatchCreateStatusError
+		}
+	}
+	return results
+}
+
+// moveItem moves a file or folder, and returns the status code to respond with on error.
+// An existing destination is an itemExistsError, unless overwritten
+func moveItem(ds *datasite.Datasite, sourcePath string, newPath string, overwrite bool) (*WorkspaceItem, int, error) {
 	// Get the workspace
 	ws := ds.GetWorkspace()
 
 	// Validate source path
-	if !strings.HasPrefix(req.SourcePath, "/") {
-		c.PureJSON(http.StatusBadRequest, &ControlPlaneError{
-			ErrorCode: ErrCodeBadRequest,
-			Error:     "source path must be an absolute path and start with /",
-		})
-		return
+	if !strings.HasPrefix(sourcePath, "/") {
+		return nil, http.StatusBadRequest, errors.New("source path must be an absolute path and start with /")
 	}
 
 	// Validate destination path
-	if !strings.HasPrefix(req.NewPath, "/") {
-		c.PureJSON(http.StatusBadRequest, &ControlPlaneError{
-			ErrorCode: ErrCodeBadRequest,
-			Error:     "destination path must be an absolute path and start with /",
-		})
-		return
+	if !strings.HasPrefix(newPath, "/") {
+		return nil, http.StatusBadRequest, errors.New("destination path must be an absolute path and start with /")
 	}
 
 	// Resolve the source path
-	absSourcePath := filepath.Join(ws.Root, req.SourcePath)
+	absSourcePath := filepath.Join(ws.Root, sourcePath)
 
 	// Resolve the destination path
-	absNewPath := filepath.Join(ws.Root, req.NewPath)
+	absNewPath := filepath.Join(ws.Root, newPath)
 	newDir := filepath.Dir(absNewPath)
 
+	if !withinRoot(ws.Root, absSourcePath) || !withinRoot(ws.Root, absNewPath) {
+		return nil, http.StatusBadRequest, errors.New("paths must be within the workspace")
+	}
+
 	// Check if the source exists
-	_, err = os.Stat(absSourcePath)
-	if err != nil {
+	if _, err := os.Stat(absSourcePath); err != nil {
 		if os.IsNotExist(err) {
-			c.PureJSON(http.StatusNotFound, &ControlPlaneError{
-				ErrorCode: ErrCodeMoveWorkspaceItemsFailed,
-				Error:     "source file or directory does not exist",
-			})
-			return
+			return nil, http.StatusNotFound, errors.New("source file or directory does not exist")
 		}
-		c.PureJSON(http.StatusInternalServerError, &ControlPlaneError{
-			ErrorCode: ErrCodeMoveWorkspaceItemsFailed,
-			Error:     err.Error(),
-		})
-		return
+		return nil, http.StatusInternalServerError, err
 	}
 
 	// Check if the destination parent directory exists
 	newDirInfo, err := os.Stat(newDir)
 	if err != nil {
 		if os.IsNotExist(err) {
-			c.PureJSON(http.StatusNotFound, &ControlPlaneError{
-				ErrorCode: ErrCodeMoveWorkspaceItemsFailed,
-				Error:     "destination parent directory does not exist",
-			})
-			return
+			return nil, http.StatusNotFound, errors.New("destination parent directory does not exist")
 		}
-		c.PureJSON(http.StatusInternalServerError, &ControlPlaneError{
-			ErrorCode: ErrCodeMoveWorkspaceItemsFailed,
-			Error:     err.Error(),
-		})
-		return
+		return nil, http.StatusInternalServerError, err
 	}
 
 	// Make sure destination parent is a directory
 	if !newDirInfo.IsDir() {
-		c.PureJSON(http.StatusBadRequest, &ControlPlaneError{
-			ErrorCode: ErrCodeBadRequest,
-			Error:     "destination's parent is not a directory",
-		})
-		return
+		return nil, http.StatusBadRequest, errors.New("destination's parent is not a directory")
 	}
 
 	// Check if the destination already exists
-	if newDirInfo, err := os.Stat(absNewPath); err == nil {
-		if req.Overwrite {
-			// If overwrite is true, remove the existing file/directory
-
-			if newDirInfo.IsDir() {
-				err = os.RemoveAll(absNewPath)
-			} else {
-				err = os.Remove(absNewPath)
-			}
-
-			if err != nil {
-				c.PureJSON(http.StatusInternalServerError, &ControlPlaneError{
-					ErrorCode: ErrCodeMoveWorkspaceItemsFailed,
-					Error:     "failed to remove existing item: " + err.Error(),
-				})
-				return
-			}
-		} else {
+	if existingInfo, err := os.Stat(absNewPath); err == nil {
+		if !overwrite {
 			// If overwrite is false, return conflict error with the existing item
-			relPath, _ := filepath.Rel(ws.Root, absNewPath)
-			relPath = filepath.Join("/", filepath.ToSlash(relPath))
-
-			itemType := WorkspaceItemTypeFile
-			if newDirInfo.IsDir() {
-				itemType = WorkspaceItemTypeFolder
-			}
-
-			existingItem := WorkspaceItem{
-				Id:          relPath,
-				Name:        filepath.Base(absNewPath),
-				Type:        itemType,
-				Path:        relPath,
-				CreatedAt:   newDirInfo.ModTime(),
-				ModifiedAt:  newDirInfo.ModTime(),
-				Size:        newDirInfo.Size(),
-				SyncStatus:  SyncStatusHidden,
-				Permissions: []Permission{},
-				Children:    []WorkspaceItem{},
+			return nil, http.StatusConflict, &itemExistsError{
+				path: newPath,
+				item: existingItem(ds, absNewPath, existingInfo),
 			}
+		}
 
-			c.PureJSON(http.StatusConflict, &WorkspaceConflictError{
-				ErrorCode:    ErrCodeMoveWorkspaceItemsFailed,
-				Error:        "destination already exists: " + req.NewPath,
-				ExistingItem: existingItem,
-			})
-			return
+		// If overwrite is true, remove the existing file/directory
+		if existingInfo.IsDir() {
+			err = os.RemoveAll(absNewPath)
+		} else {
+			err = os.Remove(absNewPath)
+		}
+		if err != nil {
+			return nil, http.StatusInternalServerError, fmt.Errorf("failed to remove existing item: %w", err)
 		}
 	} else if !os.IsNotExist(err) {
-		c.PureJSON(http.StatusInternalServerError, &ControlPlaneError{
-			ErrorCode: ErrCodeMoveWorkspaceItemsFailed,
-			Error:     err.Error(),
-		})
-		return
+		return nil, http.StatusInternalServerError, err
 	}
 
 	// Move the file or directory
-	err = os.Rename(absSourcePath, absNewPath)
-	if err != nil {
-		c.PureJSON(http.StatusInternalServerError, &ControlPlaneError{
-			ErrorCode: ErrCodeMoveWorkspaceItemsFailed,
-			Error:     err.Error(),
-		})
-		return
+	if err := os.Rename(absSourcePath, absNewPath); err != nil {
+		return nil, http.StatusInternalServerError, err
 	}
 
 	// Get updated file info
 	updatedInfo, err := os.Stat(absNewPath)
 	if err != nil {
-		c.PureJSON(http.StatusInternalServerError, &ControlPlaneError{
-			ErrorCode: ErrCodeMoveWorkspaceItemsFailed,
-			Error:     err.Error(),
-		})
-		return
+		return nil, http.StatusInternalServerError, err
 	}
 
-	// Get relative path for response
-	relNewPath, err := filepath.Rel(ws.Root, absNewPath)
-	if err != nil {
-		c.PureJSON(http.StatusInternalServerError, &ControlPlaneError{
-			ErrorCode: ErrCodeMoveWorkspaceItemsFailed,
-			Error:     err.Error(),
-		})
-		return
-	}
-	relNewPath = filepath.Join("/", filepath.ToSlash(relNewPath))
-
-	// Create response item
-	itemType := WorkspaceItemTypeFile
-	if updatedInfo.IsDir() {
-		itemType = WorkspaceItemTypeFolder
-	}
-
-	item := WorkspaceItem{
-		Id:           relNewPath,
-		Name:         filepath.Base(absNewPath),
-		Type:         itemType,
-		Path:         relNewPath,
-		AbsolutePath: absNewPath,
-		CreatedAt:    updatedInfo.ModTime(),
-		ModifiedAt:   updatedInfo.ModTime(),
-		Size:         updatedInfo.Size(),
-		SyncStatus:   SyncStatusHidden, // TODO: Replace with actual sync status
-		Permissions:  []Permission{},   // TODO: Replace with actual permissions
-		Children:     []WorkspaceItem{},
-	}
-
-	c.PureJSON(http.StatusOK, &WorkspaceItemMoveResponse{
-		Item: item,
-	})
+	item := existingItem(ds, absNewPath, updatedInfo)
+	return &item, 0, nil
 }
 
-func (h *WorkspaceHandler) listItems(path string, rootPath string, depth int) ([]WorkspaceItem, error) {
+func (h *WorkspaceHandler) listItems(ds *datasite.Datasite, path string, rootPath string, depth int, perms *permissionResolver) ([]WorkspaceItem, error) {
 	entries, err := os.ReadDir(path)
 	if err != nil {
 		return nil, err
@@ -607,6 +683,10 @@ func (h *WorkspaceHandler) listItems(path string, rootPath string, depth int) ([
 		}
 
 		absPath := filepath.Join(path, entry.Name())
+		// the trash is listed on its own
+		if absPath == ds.GetWorkspace().TrashDir {
+			continue
+		}
 		relPath, err := filepath.Rel(rootPath, absPath)
 		if err != nil {
 			continue
@@ -623,15 +703,15 @@ func (h *WorkspaceHandler) listItems(path string, rootPath string, depth int) ([
 			CreatedAt:    info.ModTime(), // Using ModTime as CreatedAt since Go doesn't provide creation time
 			ModifiedAt:   info.ModTime(),
 			Size:         info.Size(),
-			SyncStatus:   SyncStatusHidden, // TODO: Replace with actual sync status
-			Permissions:  []Permission{},   // TODO: Replace with actual permissions
+			SyncStatus:   itemSyncStatus(ds, absPath),
+			Permissions:  perms.permissions(absPath, entry.IsDir()),
 			Children:     []WorkspaceItem{},
 		}
 
 		if entry.IsDir() {
 			item.Type = "folder"
 			if depth > 0 {
-				children, err := h.listItems(absPath, rootPath, depth-1)
+				children, err := h.listItems(ds, absPath, rootPath, depth-1, perms)
 				if err != nil {
 					continue
 				}
@@ -645,10 +725,47 @@ func (h *WorkspaceHandler) listItems(path string, rootPath string, depth int) ([
 	return items, nil
 }
 
+// itemSyncStatus returns the sync status of a workspace item. Only the datasites dir is synced,
+// the rest of the workspace and the folders that aren't ignored are hidden.
+func itemSyncStatus(ds *datasite.Datasite, absPath string) SyncStatus {
+	syncMgr := ds.GetSyncManager()
+	if syncMgr == nil {
+		return SyncStatusHidden
+	}
+
+	relPath, err := filepath.Rel(ds.GetWorkspace().DatasitesDir, absPath)
+	if err != nil || relPath == "." || strings.HasPrefix(relPath, "..") {
+		return SyncStatusHidden
+	}
+
+	switch syncMgr.FileState(sync.SyncPath(workspace.NormPath(relPath))) {
+	case sync.FileSynced:
+		return SyncStatusSynced
+	case sync.FileSyncing:
+		return SyncStatusSyncing
+	case sync.FilePending:
+		return SyncStatusPending
+	case sync.FileRejected:
+		return SyncStatusRejected
+	case sync.FileError, sync.FileConflicted, sync.FileQuarantined:
+		// all wait on the user
+		return SyncStatusError
+	case sync.FileIgnored:
+		return SyncStatusIgnored
+	default:
+		return SyncStatusHidden
+	}
+}
+
 // Recursively copy a directory and its contents
 func copyDir(src, dst string) error {
-	// Create the destination directory
-	if err := os.MkdirAll(dst, 0755); err != nil {
+	srcInfo, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	// Create the destination directory with the mode of the source
+	if err := os.MkdirAll(dst, srcInfo.Mode().Perm()); err != nil {
 		return err
 	}
 
@@ -679,7 +796,7 @@ func copyDir(src, dst string) error {
 	return nil
 }
 
-// Copy a single file
+// Copy a single file, streaming its contents and keeping its permission bits
 func copyFile(src, dst string) error {
 	// Open the source file
 	srcFile, err := os.Open(src)
@@ -688,16 +805,32 @@ func copyFile(src, dst string) error {
 	}
 	defer srcFile.Close()
 
-	// Create the destination file
-	dstFile, err := os.Create(dst)
+	srcInfo, err := srcFile.Stat()
+	if err != nil {
+		return err
+	}
+
+	// Create the destination file with the mode of the source
+	dstFile, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, srcInfo.Mode().Perm())
 	if err != nil {
 		return err
 	}
 	defer dstFile.Close()
 
+	// the mode is only applied to new files, and is subject to the umask
+	if err := dstFile.Chmod(srcInfo.Mode().Perm()); err != nil {
+		return err
+	}
+
 	// Copy the contents
-	_, err = dstFile.ReadFrom(srcFile)
-	return err
+	if _, err := io.Copy(dstFile, srcFile); err != nil {
+		return err
+	}
+
+	if err := dstFile.Sync(); err != nil {
+		return err
+	}
+	return dstFile.Close()
 }
 
 // CopyItems copies a file or folder to a new location. Can also be used for renaming a file or folder.
@@ -838,8 +971,8 @@ func (h *WorkspaceHandler) CopyItems(c *gin.Context) {
 				CreatedAt:    existingInfo.ModTime(),
 				ModifiedAt:   existingInfo.ModTime(),
 				Size:         existingInfo.Size(),
-				SyncStatus:   SyncStatusHidden,
-				Permissions:  []Permission{},
+				SyncStatus:   itemSyncStatus(ds, absNewPath),
+				Permissions:  itemPermissions(ws.DatasitesDir, absNewPath, existingInfo.IsDir()),
 				Children:     []WorkspaceItem{},
 			}
 
@@ -915,8 +1048,8 @@ func (h *WorkspaceHandler) CopyItems(c *gin.Context) {
 		CreatedAt:    updatedInfo.ModTime(),
 		ModifiedAt:   updatedInfo.ModTime(),
 		Size:         updatedInfo.Size(),
-		SyncStatus:   SyncStatusHidden, // TODO: Replace with actual sync status
-		Permissions:  []Permission{},   // TODO: Replace with actual permissions
+		SyncStatus:   itemSyncStatus(ds, absNewPath),
+		Permissions:  itemPermissions(ws.DatasitesDir, absNewPath, updatedInfo.IsDir()),
 		Children:     []WorkspaceItem{},
 	}
 
@@ -925,7 +1058,8 @@ func (h *WorkspaceHandler) CopyItems(c *gin.Context) {
 	})
 }
 
-// getContentType returns the MIME type based on file extension
+// getContentType returns the MIME type based on file extension.
+// Files with an unknown extension, or none, are sniffed from their first bytes.
 func getContentType(path string) string {
 	ext := strings.ToLower(filepath.Ext(path))
 	switch ext {
@@ -980,19 +1114,40 @@ func getContentType(path string) string {
 	case ".log":
 		return "text/plain"
 	default:
+		return sniffContentType(path)
+	}
+}
+
+// sniffContentType detects the MIME type of a file from its content, see http.DetectContentType
+func sniffContentType(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
 		return "application/octet-stream"
 	}
+	defer f.Close()
+
+	buf := make([]byte, 512)
+	// files shorter than the buffer are sniffed on what they have
+	n, err := io.ReadFull(f, buf)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return "application/octet-stream"
+	}
+	return http.DetectContentType(buf[:n])
 }
 
 // Get file content
 //
 //	@Summary		Get file content
 //	@Description	Get the content of a file at the specified path. Supports range requests for efficient streaming of large files.
+//	@Description	With preview=thumb, JPEG, PNG and GIF images are served as a small JPEG instead. Other files are served as is.
 //	@Tags			Workspace
 //	@Produce		text/plain
 //	@Produce		application/octet-stream
+//	@Produce		image/jpeg
 //	@Produce		*/*
 //	@Param			path	query		string	true	"Path to the file"
+//	@Param			preview	query		string	false	"Serve a preview of the file instead"		Enums(thumb)
+//	@Param			w		query		integer	false	"Longest side of the thumbnail in pixels"	minimum(16)	maximum(1024)	default(128)
 //	@Success		200		{file}		file	"File content"
 //	@Success		206		{file}		file	"Partial file content for range requests"
 //	@Failure		400		{object}	ControlPlaneError
@@ -1064,6 +1219,11 @@ func (h *WorkspaceHandler) GetContent(c *gin.Context) {
 		return
 	}
 
+	// Serve a thumbnail of images, or fall back to the file
+	if req.Preview == PreviewThumb && h.serveThumbnail(c, absPath, fileInfo, req.Width) {
+		return
+	}
+
 	// Get content type based on file extension
 	contentType := getContentType(absPath)
 
@@ -1080,6 +1240,7 @@ func (h *WorkspaceHandler) GetContent(c *gin.Context) {
 //
 //	@Summary		Update file content
 //	@Description	Update the content of a file at the specified path. Supports overwrite, append, and prepend modes. Can create the file if it doesn't exist.
+//	@Description	Send binary content base64 encoded, with the encoding set to base64.
 //	@Tags			Workspace
 //	@Accept			json
 //	@Produce		json
@@ -1125,6 +1286,19 @@ func (h *WorkspaceHandler) UpdateContent(c *gin.Context) {
 		return
 	}
 
+	// Decode the content before touching the file
+	content := []byte(req.Content)
+	if req.Encoding == ContentEncodingBase64 {
+		content, err = base64.StdEncoding.DecodeString(req.Content)
+		if err != nil {
+			c.PureJSON(http.StatusBadRequest, &ControlPlaneError{
+				ErrorCode: ErrCodeBadRequest,
+				Error:     fmt.Sprintf("invalid base64 content: %v", err),
+			})
+			return
+		}
+	}
+
 	// Resolve the path
 	absPath := filepath.Join(ws.Root, req.Path)
 
@@ -1210,11 +1384,11 @@ func (h *WorkspaceHandler) UpdateContent(c *gin.Context) {
 	var newContent []byte
 	switch req.Mode {
 	case UpdateModeOverwrite:
-		newContent = []byte(req.Content)
+		newContent = content
 	case UpdateModeAppend:
-		newContent = append(existingContent, []byte(req.Content)...)
+		newContent = append(existingContent, content...)
 	case UpdateModePrepend:
-		newContent = append([]byte(req.Content), existingContent...)
+		newContent = append(content, existingContent...)
 	}
 
 	// Write the content to the file
@@ -1257,10 +1431,111 @@ func (h *WorkspaceHandler) UpdateContent(c *gin.Context) {
 		CreatedAt:    updatedInfo.ModTime(),
 		ModifiedAt:   updatedInfo.ModTime(),
 		Size:         updatedInfo.Size(),
-		SyncStatus:   SyncStatusHidden, // TODO: Replace with actual sync status
-		Permissions:  []Permission{},   // TODO: Replace with actual permissions
+		SyncStatus:   itemSyncStatus(ds, absPath),
+		Permissions:  itemPermissions(ws.DatasitesDir, absPath, false),
 		Children:     []WorkspaceItem{},
 	}
 
 	c.PureJSON(http.StatusOK, &item)
 }
+
+// Upload a file to the workspace
+//
+//	@Summary		Upload file
+//	@Description	Upload a file to the workspace and sync it right away. The content is streamed from the request body, or read from a local source file. Progress is reported on the sync event stream.
+//	@Tags			Workspace
+//	@Accept			octet-stream
+//	@Produce		json
+//	@Param			path	query		string	true	"Full path of the file in the workspace"
+//	@Param			source	query		string	false	"Absolute path to a local file in the workspace to upload instead of the request body"
+//	@Success		202		{object}	WorkspaceItem
+//	@Failure		400		{object}	ControlPlaneError
+//	@Failure		401		{object}	ControlPlaneError
+//	@Failure		403		{object}	ControlPlaneError
+//	@Failure		404		{object}	ControlPlaneError
+//	@Failure		409		{object}	ControlPlaneError
+//	@Failure		429		{object}	ControlPlaneError
+//	@Failure		500		{object}	ControlPlaneError
+//	@Failure		503		{object}	ControlPlaneError
+//	@Router			/v1/workspace/upload [post]
+func (h *WorkspaceHandler) Upload(c *gin.Context) {
+	var req WorkspaceUploadRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.PureJSON(http.StatusBadRequest, &ControlPlaneError{
+			ErrorCode: ErrCodeBadRequest,
+			Error:     err.Error(),
+		})
+		return
+	}
+
+	// Get the datasite
+	ds, err := h.mgr.Get()
+	if err != nil {
+		c.PureJSON(http.StatusServiceUnavailable, &ControlPlaneError{
+			ErrorCode: ErrCodeDatasiteNotReady,
+			Error:     err.Error(),
+		})
+		return
+	}
+
+	// Get the workspace
+	ws := ds.GetWorkspace()
+
+	absPath, syncRelPath, err := resolveUploadPath(ws, req.Path)
+	if err != nil {
+		c.PureJSON(http.StatusBadRequest, &ControlPlaneError{
+			ErrorCode: ErrCodeBadRequest,
+			Error:     err.Error(),
+		})
+		return
+	}
+
+	// Read the content from the local source file or the request body
+	content := io.Reader(c.Request.Body)
+	size := c.Request.ContentLength
+	if req.Source != "" {
+		source, status, err := resolveUploadSource(ws, req.Source)
+		if err != nil {
+			c.PureJSON(status, &ControlPlaneError{
+				ErrorCode: ErrCodeBadRequest,
+				Error:     err.Error(),
+			})
+			return
+		}
+
+		f, err := os.Open(source)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if os.IsNotExist(err) {
+				status = http.StatusNotFound
+			}
+			c.PureJSON(status, &ControlPlaneError{
+				ErrorCode: ErrCodeUploadWorkspaceItemFailed,
+				Error:     err.Error(),
+			})
+			return
+		}
+		defer f.Close()
+
+		info, err := f.Stat()
+		if err != nil || info.IsDir() {
+			c.PureJSON(http.StatusBadRequest, &ControlPlaneError{
+				ErrorCode: ErrCodeBadRequest,
+				Error:     "source must be a file",
+			})
+			return
+		}
+
+		content = f
+		size = info.Size()
+	}
+
+	metadata, err := ds.GetSyncManager().Ingest(c.Request.Context(), sync.SyncPath(syncRelPath), content, size)
+	if err != nil {
+		abortWithIngestError(c, err)
+		return
+	}
+
+	// The upload continues in the background. Follow its progress on /v1/sync/events
+	c.PureJSON(http.StatusAccepted, ingestedItem(ws, absPath, metadata))
+}
@@ -0,0 +1,354 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"net/http"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	mapset "github.com/deckarep/golang-set/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/openmined/syftbox/internal/aclspec"
+	"github.com/openmined/syftbox/internal/client/workspace"
+	"github.com/openmined/syftbox/internal/server/acl"
+	"github.com/openmined/syftbox/internal/utils"
+)
+
+var ErrOutsideDatasites = errors.New("path must be a folder in the datasites directory")
+
+// accessRank orders the access levels, from none to admin
+var accessRank = map[string]int{"": 0, "read": 1, "write": 2, "admin": 3}
+
+// PreviewACL previews an ACL change
+//
+//	@Summary		Preview an ACL change
+//	@Description	Compare who can access the files under a folder with its current syft.pub.yaml and with a proposed one.
+//	@Description	Nothing is changed. The rules resolved per user are previewed for the users named in the paths,
+//	@Description	and each attribute condition as a principal of its own, e.g. group:researchers.
+//	@Tags			Workspace
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		WorkspaceACLPreviewRequest	true	"Request body"
+//	@Success		200		{object}	WorkspaceACLPreviewResponse
+//	@Failure		400		{object}	ControlPlaneError
+//	@Failure		401		{object}	ControlPlaneError
+//	@Failure		403		{object}	ControlPlaneError
+//	@Failure		429		{object}	ControlPlaneError
+//	@Failure		500		{object}	ControlPlaneError
+//	@Failure		503		{object}	ControlPlaneError
+//	@Router			/v1/workspace/acl/preview [post]
+func (h *WorkspaceHandler) PreviewACL(c *gin.Context) {
+	var req WorkspaceACLPreviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.PureJSON(http.StatusBadRequest, &ControlPlaneError{
+			ErrorCode: ErrCodeBadRequest,
+			Error:     err.Error(),
+		})
+		return
+	}
+
+	// Get the datasite
+	ds, err := h.mgr.Get()
+	if err != nil {
+		c.PureJSON(http.StatusServiceUnavailable, &ControlPlaneError{
+			ErrorCode: ErrCodeDatasiteNotReady,
+			Error:     err.Error(),
+		})
+		return
+	}
+
+	// Get the workspace
+	ws := ds.GetWorkspace()
+
+	// Make sure req.Path is an absolute path
+	if !strings.HasPrefix(req.Path, "/") {
+		c.PureJSON(http.StatusBadRequest, &ControlPlaneError{
+			ErrorCode: ErrCodeBadRequest,
+			Error:     "path must be an absolute path and start with /",
+		})
+		return
+	}
+
+	preview, err := PreviewACLChange(ws, filepath.Join(ws.Root, req.Path), []byte(req.Content))
+	if err != nil {
+		status, errorCode := http.StatusInternalServerError, ErrCodePreviewACLFailed
+		var invalid *invalidACLError
+		if errors.Is(err, ErrOutsideDatasites) || errors.As(err, &invalid) {
+			status, errorCode = http.StatusBadRequest, ErrCodeBadRequest
+		}
+		c.PureJSON(status, &ControlPlaneError{
+			ErrorCode: errorCode,
+			Error:     err.Error(),
+		})
+		return
+	}
+
+	c.PureJSON(http.StatusOK, preview)
+}
+
+// invalidACLError is returned when the proposed ACL can't be parsed
+type invalidACLError struct {
+	err error
+}
+
+func (e *invalidACLError) Error() string {
+	return "invalid acl: " + e.err.Error()
+}
+
+func (e *invalidACLError) Unwrap() error {
+	return e.err
+}
+
+// PreviewACLChange compares the access to the files under absDir with its current ACL and with the proposed one,
+// checked with the server's ACL tree. Nothing is written.
+func PreviewACLChange(ws *workspace.Workspace, absDir string, proposed []byte) (*WorkspaceACLPreviewResponse, error) {
+	relDir, err := filepath.Rel(ws.DatasitesDir, absDir)
+	if err != nil || relDir == "." || relDir == ".." || strings.HasPrefix(relDir, ".."+string(filepath.Separator)) {
+		return nil, ErrOutsideDatasites
+	}
+	relDir = filepath.ToSlash(relDir)
+
+	ruleset, err := aclspec.LoadFromReader(relDir, bytes.NewReader(proposed))
+	if err != nil {
+		return nil, &invalidACLError{err: err}
+	}
+	if err := acl.ValidateRuleSet(ruleset); err != nil {
+		return nil, &invalidACLError{err: err}
+	}
+
+	preview := &WorkspaceACLPreviewResponse{
+		Granted: []ACLAccessChange{},
+		Revoked: []ACLAccessChange{},
+	}
+
+	// the rulesets above the folder apply to both, a terminal one keeps the proposed one from applying
+	segments := strings.Split(relDir, "/")
+	owner := segments[0]
+	var rulesets []*aclspec.RuleSet
+	for i := 1; i < len(segments); i++ {
+		dir := strings.Join(segments[:i], "/")
+		above := loadPreviewRuleSet(ws.DatasitesDir, dir)
+		if above == nil {
+			continue
+		}
+		if above.Terminal {
+			preview.ShadowedBy = workspacePath(ws, dir)
+			return preview, nil
+		}
+		rulesets = append(rulesets, above)
+	}
+
+	// then the ones in and under the folder, the current one replaced by the proposed one
+	var files []string
+	var below []*aclspec.RuleSet
+	err = filepath.WalkDir(absDir, func(absPath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) && absPath == absDir {
+				// a folder that doesn't exist yet has no files to share
+				return fs.SkipAll
+			}
+			// skip what can't be read, like listItems does
+			if d != nil && d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(ws.DatasitesDir, absPath)
+		if err != nil {
+			return nil
+		}
+		relPath = filepath.ToSlash(relPath)
+		files = append(files, relPath)
+		if aclspec.IsACLFile(relPath) && path.Dir(relPath) != relDir {
+			if loaded := loadPreviewRuleSet(ws.DatasitesDir, path.Dir(relPath)); loaded != nil {
+				below = append(below, loaded)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walk %s: %w", absDir, err)
+	}
+
+	current := newPreviewTree(append(append(slices.Clone(rulesets), loadPreviewRuleSet(ws.DatasitesDir, relDir)), below...))
+	next := newPreviewTree(append(append(slices.Clone(rulesets), ruleset), below...))
+	principals := previewPrincipals(owner, files, current.rulesets, next.rulesets)
+
+	for _, relPath := range files {
+		displayPath := workspacePath(ws, relPath)
+		for _, principal := range principals {
+			change := ACLAccessChange{
+				Path:      displayPath,
+				Principal: principal.name,
+				Before:    current.access(relPath, principal.user),
+				After:     next.access(relPath, principal.user),
+			}
+			switch {
+			case accessRank[change.After] > accessRank[change.Before]:
+				preview.Granted = append(preview.Granted, change)
+			case accessRank[change.After] < accessRank[change.Before]:
+				preview.Revoked = append(preview.Revoked, change)
+			}
+		}
+	}
+
+	return preview, nil
+}
+
+// loadPreviewRuleSet loads the ruleset of a dir relative to the datasites dir, nil when it has none or it's invalid
+func loadPreviewRuleSet(datasitesDir string, dir string) *aclspec.RuleSet {
+	ruleset, err := aclspec.LoadFromFile(filepath.Join(datasitesDir, filepath.FromSlash(dir)))
+	if err != nil {
+		return nil
+	}
+	ruleset.Path = dir
+	if acl.ValidateRuleSet(ruleset) != nil {
+		return nil
+	}
+	return ruleset
+}
+
+// previewTree is an ACL tree of the server, with the rulesets it was built from
+type previewTree struct {
+	tree     *acl.ACLTree
+	rulesets []*aclspec.RuleSet
+}
+
+func newPreviewTree(rulesets []*aclspec.RuleSet) *previewTree {
+	t := &previewTree{tree: acl.NewACLTree()}
+	for _, ruleset := range rulesets {
+		if ruleset == nil {
+			continue
+		}
+		if _, err := t.tree.AddRuleSet(ruleset); err == nil {
+			t.rulesets = append(t.rulesets, ruleset)
+		}
+	}
+	return t
+}
+
+// access returns the highest access of a user to a file, empty for none
+func (t *previewTree) access(relPath string, user *acl.User) string {
+	rule, err := t.tree.GetCompiledRule(acl.NewRequest(relPath, user, acl.AccessRead))
+	if err != nil {
+		return ""
+	}
+
+	for _, level := range []struct {
+		level      acl.AccessLevel
+		accessType string
+	}{
+		{acl.AccessAdmin, "admin"},
+		{acl.AccessWrite, "write"},
+		{acl.AccessRead, "read"},
+	} {
+		// like the server, writing an ACL file takes admin access
+		if level.level == acl.AccessWrite && aclspec.IsACLFile(relPath) {
+			continue
+		}
+		if rule.CheckAccess(acl.NewRequest(relPath, user, level.level)) == nil {
+			return level.accessType
+		}
+	}
+	return ""
+}
+
+// previewPrincipal is who the access is previewed for
+type previewPrincipal struct {
+	name string
+	user *acl.User
+}
+
+// anyoneElse stands in for the users that no rule names
+const anyoneElse = "anyone@syftbox.invalid"
+
+// previewPrincipals returns who the rules may give access to: the users and patterns they name, * for everyone,
+// one principal per condition, and the users named in the paths for the rules resolved per user.
+// The owner is left out as its access can't change.
+func previewPrincipals(owner string, files []string, rulesets ...[]*aclspec.RuleSet) []*previewPrincipal {
+	principals := map[string]*previewPrincipal{
+		aclspec.TokenEveryone: {name: aclspec.TokenEveryone, user: &acl.User{ID: anyoneElse, Attrs: &acl.Attributes{}}},
+	}
+	addUser := func(user string) {
+		if user == owner || user == aclspec.TokenUser || user == aclspec.TokenEveryone {
+			return
+		}
+		if _, ok := principals[user]; !ok {
+			principals[user] = &previewPrincipal{name: user, user: &acl.User{ID: user, Attrs: &acl.Attributes{}}}
+		}
+	}
+
+	for _, group := range rulesets {
+		for _, ruleset := range group {
+			for _, rule := range ruleset.Rules {
+				if rule.Access == nil {
+					continue
+				}
+				for _, users := range []mapset.Set[string]{rule.Access.Admin, rule.Access.Write, rule.Access.Read} {
+					if users != nil {
+						for user := range users.Iter() {
+							addUser(user)
+						}
+					}
+				}
+				for _, conditions := range [][]*aclspec.Condition{rule.Access.AdminConditions, rule.Access.WriteConditions, rule.Access.ReadConditions} {
+					for _, cond := range conditions {
+						principal := conditionPrincipal(cond)
+						principals[principal.name] = principal
+					}
+				}
+			}
+		}
+	}
+
+	for _, file := range files {
+		for segment := range strings.SplitSeq(file, "/") {
+			if utils.IsValidEmail(segment) {
+				addUser(segment)
+			}
+		}
+	}
+
+	sorted := slices.Collect(maps.Values(principals))
+	slices.SortFunc(sorted, func(a, b *previewPrincipal) int {
+		return strings.Compare(a.name, b.name)
+	})
+	return sorted
+}
+
+// conditionPrincipal stands in for the users matching a condition, named after it, e.g. group:researchers
+func conditionPrincipal(cond *aclspec.Condition) *previewPrincipal {
+	var parts []string
+	user := &acl.User{ID: anyoneElse, Attrs: &acl.Attributes{Claims: cond.Claims}}
+	if cond.Domain != "" {
+		parts = append(parts, "domain:"+cond.Domain)
+		user.ID = "anyone@" + cond.Domain
+	}
+	for _, name := range slices.Sorted(maps.Keys(cond.Claims)) {
+		parts = append(parts, "claim:"+name+"="+cond.Claims[name])
+	}
+	if cond.Group != "" {
+		parts = append(parts, "group:"+cond.Group)
+		user.Attrs.Groups = []string{cond.Group}
+	}
+	return &previewPrincipal{name: strings.Join(parts, ","), user: user}
+}
+
+// workspacePath returns the full path of a path relative to the datasites dir, e.g. /datasites/user@example.com/public
+func workspacePath(ws *workspace.Workspace, relPath string) string {
+	rel, err := filepath.Rel(ws.Root, filepath.Join(ws.DatasitesDir, filepath.FromSlash(relPath)))
+	if err != nil {
+		return relPath
+	}
+	return filepath.Join("/", filepath.ToSlash(rel))
+}
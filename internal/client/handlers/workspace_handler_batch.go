@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"github.com/openmined/syftbox/internal/utils"
+)
+
+// CreateItems creates several files or folders at once
+//
+//	@Summary		Create workspace items
+//	@Description	Create several files or folders in one request, e.g. to scaffold a project.
+//	@Description	Every item is attempted and gets its own result. With atomic set, the batch stops at the first failure
+//	@Description	and the items created before it are removed again, along with the items they replaced.
+//	@Tags			Workspace
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		WorkspaceItemBatchCreateRequest	true	"Request body"
+//	@Success		200		{object}	WorkspaceItemBatchCreateResponse
+//	@Failure		400		{object}	ControlPlaneError
+//	@Failure		401		{object}	ControlPlaneError
+//	@Failure		403		{object}	ControlPlaneError
+//	@Failure		429		{object}	ControlPlaneError
+//	@Failure		503		{object}	ControlPlaneError
+//	@Router			/v1/workspace/items/batch [post]
+func (h *WorkspaceHandler) CreateItems(c *gin.Context) {
+	var req WorkspaceItemBatchCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.PureJSON(http.StatusBadRequest, &ControlPlaneError{
+			ErrorCode: ErrCodeBadRequest,
+			Error:     err.Error(),
+		})
+		return
+	}
+
+	// Get the datasite
+	ds, err := h.mgr.Get()
+	if err != nil {
+		c.PureJSON(http.StatusServiceUnavailable, &ControlPlaneError{
+			ErrorCode: ErrCodeDatasiteNotReady,
+			Error:     err.Error(),
+		})
+		return
+	}
+
+	var tx *createTx
+	if req.Atomic {
+		tx = &createTx{}
+	}
+
+	results := make([]WorkspaceItemBatchCreateResult, len(req.Items))
+	failed := false
+	for i := range req.Items {
+		item := &req.Items[i]
+		results[i].Path = item.Path
+
+		if failed && req.Atomic {
+			results[i].Status = BatchCreateStatusSkipped
+			continue
+		}
+
+		created, _, err := createItem(ds, item, tx)
+		if err == nil {
+			results[i].Status = BatchCreateStatusSuccess
+			results[i].Item = created
+			continue
+		}
+
+		failed = true
+		results[i].Error = err.Error()
+		var exists *itemExistsError
+		if errors.As(err, &exists) {
+			results[i].Status = BatchCreateStatusConflict
+			results[i].ExistingItem = &exists.item
+		} else {
+			results[i].Status = BatchCreateStatusError
+		}
+	}
+
+	if tx != nil {
+		if failed {
+			tx.rollback()
+			for i := range results {
+				if results[i].Status == BatchCreateStatusSuccess {
+					results[i].Status = BatchCreateStatusRolledBack
+					results[i].Item = nil
+				}
+			}
+		} else {
+			tx.commit()
+		}
+	}
+
+	c.PureJSON(http.StatusOK, &WorkspaceItemBatchCreateResponse{
+		Results: results,
+	})
+}
+
+// createTx records what an atomic batch changed, so that it can be undone
+type createTx struct {
+	undo []createUndo // in the order of the changes
+}
+
+// createUndo is a created path when backup is empty, or an item moved to its backup
+type createUndo struct {
+	path   string
+	backup string
+}
+
+// moveAside moves an item about to be replaced to a backup next to it, that the sync ignores
+func (tx *createTx) moveAside(absPath string) error {
+	backup := absPath + ".syft.tmp." + utils.TokenHex(3)
+	if err := os.Rename(absPath, backup); err != nil {
+		return err
+	}
+	tx.undo = append(tx.undo, createUndo{path: absPath, backup: backup})
+	return nil
+}
+
+func (tx *createTx) created(absPath string) {
+	tx.undo = append(tx.undo, createUndo{path: absPath})
+}
+
+// rollback removes the created items and puts the replaced ones back, latest first
+func (tx *createTx) rollback() {
+	for _, undo := range slices.Backward(tx.undo) {
+		var err error
+		if undo.backup == "" {
+			err = os.RemoveAll(undo.path)
+		} else {
+			err = os.Rename(undo.backup, undo.path)
+		}
+		if err != nil {
+			slog.Warn("workspace batch rollback", "path", undo.path, "error", err)
+		}
+	}
+}
+
+// commit removes the backups of the replaced items
+func (tx *createTx) commit() {
+	for _, undo := range tx.undo {
+		if undo.backup == "" {
+			continue
+		}
+		if err := os.RemoveAll(undo.backup); err != nil {
+			slog.Warn("workspace batch cleanup", "backup", undo.backup, "error", err)
+		}
+	}
+}
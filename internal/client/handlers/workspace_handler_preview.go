@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif" // register the decoders of the supported image types
+	"image/jpeg"
+	_ "image/png"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	lru "github.com/hashicorp/golang-lru/v2"
+)
+
+const (
+	// PreviewThumb asks GetContent for a small JPEG of an image instead of the file
+	PreviewThumb = "thumb"
+
+	// defaultThumbWidth is the longest side of a thumbnail, when the request doesn't set it
+	defaultThumbWidth = 128
+	// maxThumbPixels is the largest image decoded for a thumbnail, the original is served past it
+	maxThumbPixels = 64 * 1024 * 1024
+	// thumbCacheSize is the number of thumbnails kept in memory
+	thumbCacheSize = 512
+	// thumbSamples is the number of source pixels averaged along each axis of a thumbnail pixel
+	thumbSamples = 4
+	thumbQuality = 80
+)
+
+// thumbKey identifies a thumbnail. A changed file gets a new one
+type thumbKey struct {
+	path    string
+	modTime int64
+	size    int64
+	width   int
+}
+
+type thumbCache = lru.Cache[thumbKey, []byte]
+
+func newThumbCache() *thumbCache {
+	cache, err := lru.New[thumbKey, []byte](thumbCacheSize)
+	if err != nil {
+		// only fails on a non-positive size
+		panic(err)
+	}
+	return cache
+}
+
+// serveThumbnail responds with a thumbnail of the image at absPath.
+// It returns false without responding if the file is not a supported image, so that the original is served.
+func (h *WorkspaceHandler) serveThumbnail(c *gin.Context, absPath string, info os.FileInfo, width int) bool {
+	switch getContentType(absPath) {
+	case "image/jpeg", "image/png", "image/gif":
+	default:
+		return false
+	}
+
+	if width == 0 {
+		width = defaultThumbWidth
+	}
+
+	key := thumbKey{path: absPath, modTime: info.ModTime().UnixNano(), size: info.Size(), width: width}
+	thumb, ok := h.thumbs.Get(key)
+	if !ok {
+		var err error
+		thumb, err = makeThumbnail(absPath, width)
+		if err != nil {
+			return false
+		}
+		h.thumbs.Add(key, thumb)
+	}
+
+	name := strings.TrimSuffix(filepath.Base(absPath), filepath.Ext(absPath)) + ".jpg"
+	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=\"%s\"", name))
+	c.Data(http.StatusOK, "image/jpeg", thumb)
+	return true
+}
+
+// makeThumbnail decodes the image at path and encodes it as a JPEG whose longest side is at most width
+func makeThumbnail(path string, width int) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// look at the size first, a small file can decode to a huge image
+	cfg, _, err := image.DecodeConfig(f)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Width*cfg.Height > maxThumbPixels {
+		return nil, fmt.Errorf("image too large: %dx%d", cfg.Width, cfg.Height)
+	}
+	if _, err := f.Seek(0, 0); err != nil {
+		return nil, err
+	}
+
+	src, _, err := image.Decode(f)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, resizeImage(src, width), &jpeg.Options{Quality: thumbQuality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// resizeImage scales src down so that its longest side is at most width, keeping the aspect ratio.
+// Each pixel averages a grid of samples of the source area it covers. Transparency is flattened on white.
+func resizeImage(src image.Image, width int) *image.RGBA {
+	b := src.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+
+	dw, dh := sw, sh
+	if sw > width || sh > width {
+		if sw >= sh {
+			dw, dh = width, max(1, sh*width/sw)
+		} else {
+			dw, dh = max(1, sw*width/sh), width
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := range dh {
+		for x := range dw {
+			var r, g, bl, n uint64
+			for sy := range thumbSamples {
+				py := b.Min.Y + (y*thumbSamples+sy)*sh/(dh*thumbSamples)
+				for sx := range thumbSamples {
+					px := b.Min.X + (x*thumbSamples+sx)*sw/(dw*thumbSamples)
+					cr, cg, cb, ca := src.At(px, py).RGBA()
+					// colors are alpha-premultiplied, fill the rest with white
+					r += uint64(cr + 0xffff - ca)
+					g += uint64(cg + 0xffff - ca)
+					bl += uint64(cb + 0xffff - ca)
+					n++
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{
+				R: uint8(r / n >> 8),
+				G: uint8(g / n >> 8),
+				B: uint8(bl / n >> 8),
+				A: 0xff,
+			})
+		}
+	}
+	return dst
+}
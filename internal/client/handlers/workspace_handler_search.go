@@ -0,0 +1,298 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/openmined/syftbox/internal/client/datasite"
+)
+
+const (
+	// defaultSearchLimit is the number of results returned when the request doesn't set one
+	defaultSearchLimit = 100
+	// maxSearchFileSize is the largest file whose content is searched
+	maxSearchFileSize = 10 * 1024 * 1024
+	// maxSearchLines is the number of matching lines reported per file
+	maxSearchLines = 100
+	// binarySniffLen is how much of a file is looked at to tell if it is binary
+	binarySniffLen = 8000
+)
+
+var errSearchLimit = errors.New("search limit reached")
+
+// SearchItems searches the workspace for files and folders
+//
+//	@Summary		Search workspace items
+//	@Description	Find files and folders under a path by name, and optionally text files by their content.
+//	@Description	A query with *, ? or [ is matched as a glob against the names, otherwise as a substring. Binary files
+//	@Description	and files over 10MB are not searched by content. Symlinks are not followed.
+//	@Tags			Workspace
+//	@Produce		json
+//	@Param			q		query		string	true	"Name substring or glob pattern to search for"
+//	@Param			path	query		string	false	"Path to the directory to search under (default is root)"
+//	@Param			content	query		boolean	false	"Also search the contents of the text files"
+//	@Param			limit	query		integer	false	"Maximum number of results"	minimum(0)	maximum(1000)	default(100)
+//	@Success		200		{object}	WorkspaceSearchResponse
+//	@Failure		400		{object}	ControlPlaneError
+//	@Failure		401		{object}	ControlPlaneError
+//	@Failure		403		{object}	ControlPlaneError
+//	@Failure		404		{object}	ControlPlaneError
+//	@Failure		429		{object}	ControlPlaneError
+//	@Failure		500		{object}	ControlPlaneError
+//	@Failure		503		{object}	ControlPlaneError
+//	@Router			/v1/workspace/search [get]
+func (h *WorkspaceHandler) SearchItems(c *gin.Context) {
+	var req WorkspaceSearchRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.PureJSON(http.StatusBadRequest, &ControlPlaneError{
+			ErrorCode: ErrCodeBadRequest,
+			Error:     err.Error(),
+		})
+		return
+	}
+
+	if req.Path == "" {
+		req.Path = "/"
+	}
+	if req.Limit == 0 {
+		req.Limit = defaultSearchLimit
+	}
+
+	matcher, err := newSearchMatcher(req.Query)
+	if err != nil {
+		c.PureJSON(http.StatusBadRequest, &ControlPlaneError{
+			ErrorCode: ErrCodeBadRequest,
+			Error:     err.Error(),
+		})
+		return
+	}
+
+	// Get the datasite
+	ds, err := h.mgr.Get()
+	if err != nil {
+		c.PureJSON(http.StatusServiceUnavailable, &ControlPlaneError{
+			ErrorCode: ErrCodeDatasiteNotReady,
+			Error:     err.Error(),
+		})
+		return
+	}
+
+	// Get the workspace
+	ws := ds.GetWorkspace()
+
+	// Make sure req.Path is an absolute path
+	if !strings.HasPrefix(req.Path, "/") {
+		c.PureJSON(http.StatusBadRequest, &ControlPlaneError{
+			ErrorCode: ErrCodeBadRequest,
+			Error:     "path must be an absolute path and start with /",
+		})
+		return
+	}
+
+	// Resolve the path, and keep the search within the workspace
+	absPath := filepath.Join(ws.Root, req.Path)
+	if rel, err := filepath.Rel(ws.Root, absPath); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		c.PureJSON(http.StatusBadRequest, &ControlPlaneError{
+			ErrorCode: ErrCodeBadRequest,
+			Error:     "path is outside of the workspace",
+		})
+		return
+	}
+
+	info, err := os.Stat(absPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			c.PureJSON(http.StatusNotFound, &ControlPlaneError{
+				ErrorCode: ErrCodeSearchWorkspaceFailed,
+				Error:     "path not found",
+			})
+			return
+		}
+		c.PureJSON(http.StatusInternalServerError, &ControlPlaneError{
+			ErrorCode: ErrCodeSearchWorkspaceFailed,
+			Error:     err.Error(),
+		})
+		return
+	}
+	if !info.IsDir() {
+		c.PureJSON(http.StatusBadRequest, &ControlPlaneError{
+			ErrorCode: ErrCodeBadRequest,
+			Error:     "path points to a file, not a directory",
+		})
+		return
+	}
+
+	resp, err := searchItems(c, ds, absPath, matcher, req.Content, req.Limit)
+	if err != nil {
+		c.PureJSON(http.StatusInternalServerError, &ControlPlaneError{
+			ErrorCode: ErrCodeSearchWorkspaceFailed,
+			Error:     err.Error(),
+		})
+		return
+	}
+
+	c.PureJSON(http.StatusOK, resp)
+}
+
+// searchItems walks the tree under absPath and returns the items matching the query, up to limit
+func searchItems(c *gin.Context, ds *datasite.Datasite, absPath string, matcher *searchMatcher, content bool, limit int) (*WorkspaceSearchResponse, error) {
+	ws := ds.GetWorkspace()
+	perms := newPermissionResolver(ws.DatasitesDir)
+	resp := &WorkspaceSearchResponse{Results: []WorkspaceSearchResult{}}
+
+	err := filepath.WalkDir(absPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// skip what can't be read, like listItems does
+			if d != nil && d.IsDir() && path != absPath {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if err := c.Request.Context().Err(); err != nil {
+			return err
+		}
+		if path == absPath || d.Type()&fs.ModeSymlink != 0 {
+			return nil
+		}
+		// the trash is listed on its own, like in listItems
+		if d.IsDir() && path == ws.TrashDir {
+			return fs.SkipDir
+		}
+
+		nameMatch := matcher.matchName(d.Name())
+
+		var lines []int
+		if content && d.Type().IsRegular() {
+			lines = matcher.matchContent(path)
+		}
+
+		if !nameMatch && len(lines) == 0 {
+			return nil
+		}
+		if len(resp.Results) == limit {
+			resp.Truncated = true
+			return errSearchLimit
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(ws.Root, path)
+		if err != nil {
+			return nil
+		}
+		relPath = filepath.Join("/", filepath.ToSlash(relPath))
+
+		itemType := WorkspaceItemTypeFile
+		if d.IsDir() {
+			itemType = WorkspaceItemTypeFolder
+		}
+
+		resp.Results = append(resp.Results, WorkspaceSearchResult{
+			Item: WorkspaceItem{
+				Id:           relPath,
+				Name:         d.Name(),
+				Type:         itemType,
+				Path:         relPath,
+				AbsolutePath: path,
+				CreatedAt:    info.ModTime(),
+				ModifiedAt:   info.ModTime(),
+				Size:         info.Size(),
+				SyncStatus:   itemSyncStatus(ds, path),
+				Permissions:  perms.permissions(path, d.IsDir()),
+				Children:     []WorkspaceItem{},
+			},
+			NameMatch: nameMatch,
+			Lines:     lines,
+		})
+		return nil
+	})
+	if err != nil && !errors.Is(err, errSearchLimit) {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// searchMatcher matches names and contents against a search query, without the case
+type searchMatcher struct {
+	query []byte
+	glob  string
+}
+
+func newSearchMatcher(query string) (*searchMatcher, error) {
+	m := &searchMatcher{query: bytes.ToLower([]byte(query))}
+	if strings.ContainsAny(query, "*?[") {
+		m.glob = strings.ToLower(query)
+		if _, err := filepath.Match(m.glob, ""); err != nil {
+			return nil, errors.New("invalid glob pattern")
+		}
+	}
+	return m, nil
+}
+
+func (m *searchMatcher) matchName(name string) bool {
+	name = strings.ToLower(name)
+	if m.glob != "" {
+		ok, _ := filepath.Match(m.glob, name)
+		return ok
+	}
+	return strings.Contains(name, string(m.query))
+}
+
+// matchContent returns the numbers of the lines of a text file containing the query.
+// Globs are only matched against the names, and binary files are skipped.
+func (m *searchMatcher) matchContent(path string) []int {
+	if m.glob != "" {
+		return nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	if info, err := f.Stat(); err != nil || info.Size() > maxSearchFileSize {
+		return nil
+	}
+
+	r := bufio.NewReader(f)
+	if head, _ := r.Peek(binarySniffLen); bytes.IndexByte(head, 0) >= 0 {
+		return nil
+	}
+
+	var lines []int
+	for n := 1; len(lines) < maxSearchLines; n++ {
+		line, err := r.ReadSlice('\n')
+		if errors.Is(err, bufio.ErrBufferFull) {
+			// a very long line, read the rest of it
+			full := bytes.Clone(line)
+			for errors.Is(err, bufio.ErrBufferFull) {
+				line, err = r.ReadSlice('\n')
+				full = append(full, line...)
+			}
+			line = full
+		}
+		if bytes.Contains(bytes.ToLower(line), m.query) {
+			lines = append(lines, n)
+		}
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				return nil
+			}
+			break
+		}
+	}
+	return lines
+}
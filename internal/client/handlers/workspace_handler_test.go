@@ -0,0 +1,1115 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/openmined/syftbox/internal/client/config"
+	"github.com/openmined/syftbox/internal/client/datasite"
+	"github.com/openmined/syftbox/internal/client/datasitemgr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	publicACL = "rules:\n  - pattern: '**'\n    access:\n      read: ['*']\n"
+	draftsACL = "rules:\n  - pattern: 'drafts/**'\n    access:\n      write: ['bob@example.com']\n" +
+		"  - pattern: '**'\n    access:\n      read: ['*']\n"
+)
+
+func newWorkspaceTestRouter(t *testing.T, files map[string]string) (*gin.Engine, *datasite.Datasite) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	dataDir := t.TempDir()
+	ds, err := datasite.New(&config.Config{
+		DataDir:   dataDir,
+		Email:     "alice@example.com",
+		ServerURL: "http://localhost:1",
+		Path:      filepath.Join(dataDir, "config.json"),
+	})
+	require.NoError(t, err)
+
+	for path, content := range files {
+		absPath := filepath.Join(ds.GetWorkspace().DatasitesDir, filepath.FromSlash(path))
+		require.NoError(t, os.MkdirAll(filepath.Dir(absPath), 0o755))
+		require.NoError(t, os.WriteFile(absPath, []byte(content), 0o644))
+	}
+
+	h := NewWorkspaceHandler(datasitemgr.New(datasitemgr.WithDatasite(ds)))
+	r := gin.New()
+	r.GET("/v1/workspace/items", h.GetItems)
+	r.POST("/v1/workspace/items/copy", h.CopyItems)
+	r.POST("/v1/workspace/items/batch", h.CreateItems)
+	r.DELETE("/v1/workspace/items", h.DeleteItems)
+	r.POST("/v1/workspace/items/move", h.MoveItems)
+	r.GET("/v1/workspace/content", h.GetContent)
+	r.PUT("/v1/workspace/content", h.UpdateContent)
+	r.GET("/v1/workspace/search", h.SearchItems)
+	r.POST("/v1/workspace/acl/preview", h.PreviewACL)
+	r.POST("/v1/workspace/upload", h.Upload)
+	r.POST("/v1/workspace/uploads", h.CreateUpload)
+	r.GET("/v1/workspace/uploads/:id", h.GetUpload)
+	r.PATCH("/v1/workspace/uploads/:id", h.UploadChunk)
+	r.DELETE("/v1/workspace/uploads/:id", h.DeleteUpload)
+	r.POST("/v1/workspace/uploads/:id/complete", h.CompleteUpload)
+	r.GET("/v1/workspace/trash", h.GetTrash)
+	r.POST("/v1/workspace/trash/restore", h.RestoreTrash)
+	r.DELETE("/v1/workspace/trash", h.EmptyTrash)
+	return r, ds
+}
+
+func postJSON(t *testing.T, r *gin.Engine, path string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+	return sendJSON(t, r, http.MethodPost, path, body)
+}
+
+func sendJSON(t *testing.T, r *gin.Engine, method string, path string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+	data, err := json.Marshal(body)
+	require.NoError(t, err)
+	req := httptest.NewRequest(method, path, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// findItem finds an item by its path in a listing
+func findItem(items []WorkspaceItem, path string) *WorkspaceItem {
+	for i := range items {
+		if items[i].Path == path {
+			return &items[i]
+		}
+		if item := findItem(items[i].Children, path); item != nil {
+			return item
+		}
+	}
+	return nil
+}
+
+func TestGetItemsPermissions(t *testing.T) {
+	r, _ := newWorkspaceTestRouter(t, map[string]string{
+		"alice@example.com/public/syft.pub.yaml":    publicACL,
+		"alice@example.com/public/report.csv":       "a,b\n1,2\n",
+		"alice@example.com/shared/syft.pub.yaml":    draftsACL,
+		"alice@example.com/shared/drafts/notes.txt": "wip",
+		"alice@example.com/private/notes.txt":       "notes",
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/workspace/items?path=datasites/alice@example.com&depth=2", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp WorkspaceItemsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+
+	owner := Permission{ID: "admin:alice@example.com", UserID: "alice@example.com", Name: "alice@example.com", Email: "alice@example.com", Type: "admin"}
+	everyone := Permission{ID: "read:*", UserID: "*", Name: "*", Type: "read"}
+
+	report := findItem(resp.Items, "/datasites/alice@example.com/public/report.csv")
+	require.NotNil(t, report)
+	assert.Equal(t, []Permission{owner, everyone}, report.Permissions)
+
+	public := findItem(resp.Items, "/datasites/alice@example.com/public")
+	require.NotNil(t, public)
+	assert.Equal(t, []Permission{owner, everyone}, public.Permissions, "the ruleset of a folder applies to itself")
+
+	// the most specific rule wins
+	drafts := findItem(resp.Items, "/datasites/alice@example.com/shared/drafts")
+	require.NotNil(t, drafts)
+	assert.Equal(t, []Permission{owner, {
+		ID: "write:bob@example.com", UserID: "bob@example.com", Name: "bob@example.com", Email: "bob@example.com", Type: "write",
+	}}, drafts.Permissions)
+	shared := findItem(resp.Items, "/datasites/alice@example.com/shared/syft.pub.yaml")
+	require.NotNil(t, shared)
+	assert.Equal(t, []Permission{owner, everyone}, shared.Permissions)
+
+	// without a ruleset, only the owner has access
+	private := findItem(resp.Items, "/datasites/alice@example.com/private/notes.txt")
+	require.NotNil(t, private)
+	assert.Equal(t, []Permission{owner}, private.Permissions)
+}
+
+func TestGetItemsPermissionsOutsideDatasites(t *testing.T) {
+	r, ds := newWorkspaceTestRouter(t, nil)
+	require.NoError(t, os.MkdirAll(ds.GetWorkspace().AppsDir, 0o755))
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/workspace/items", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp WorkspaceItemsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	apps := findItem(resp.Items, "/apps")
+	require.NotNil(t, apps)
+	assert.Empty(t, apps.Permissions)
+	assert.NotNil(t, apps.Permissions)
+}
+
+func TestCopyItemsPreservesMode(t *testing.T) {
+	r, ds := newWorkspaceTestRouter(t, map[string]string{
+		"alice@example.com/app/run.sh":          "#!/bin/sh\necho hi\n",
+		"alice@example.com/app/scripts/test.sh": "#!/bin/sh\nexit 0\n",
+	})
+	appDir := filepath.Join(ds.GetWorkspace().DatasitesDir, "alice@example.com", "app")
+	require.NoError(t, os.Chmod(filepath.Join(appDir, "run.sh"), 0o755))
+	require.NoError(t, os.Chmod(filepath.Join(appDir, "scripts", "test.sh"), 0o700))
+
+	copyItem := func(source, dest string) {
+		t.Helper()
+		w := postJSON(t, r, "/v1/workspace/items/copy", &WorkspaceItemCopyRequest{SourcePath: source, NewPath: dest})
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	}
+
+	copyItem("/datasites/alice@example.com/app/run.sh", "/datasites/alice@example.com/app/run-copy.sh")
+	info, err := os.Stat(filepath.Join(appDir, "run-copy.sh"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o755), info.Mode().Perm())
+	content, err := os.ReadFile(filepath.Join(appDir, "run-copy.sh"))
+	require.NoError(t, err)
+	assert.Equal(t, "#!/bin/sh\necho hi\n", string(content))
+
+	copyItem("/datasites/alice@example.com/app", "/datasites/alice@example.com/app-copy")
+	copiedDir := filepath.Join(ds.GetWorkspace().DatasitesDir, "alice@example.com", "app-copy")
+	info, err = os.Stat(filepath.Join(copiedDir, "scripts", "test.sh"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o700), info.Mode().Perm())
+	info, err = os.Stat(filepath.Join(copiedDir, "run.sh"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o755), info.Mode().Perm())
+}
+
+func createItems(t *testing.T, r *gin.Engine, req *WorkspaceItemBatchCreateRequest) []WorkspaceItemBatchCreateResult {
+	t.Helper()
+	w := postJSON(t, r, "/v1/workspace/items/batch", req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp WorkspaceItemBatchCreateResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Results, len(req.Items))
+	return resp.Results
+}
+
+func statuses(results []WorkspaceItemBatchCreateResult) []BatchCreateStatus {
+	var s []BatchCreateStatus
+	for _, result := range results {
+		s = append(s, result.Status)
+	}
+	return s
+}
+
+// leftovers lists the backups left in a dir
+func leftovers(t *testing.T, dir string) []string {
+	t.Helper()
+	matches, err := filepath.Glob(filepath.Join(dir, "*.syft.tmp.*"))
+	require.NoError(t, err)
+	return matches
+}
+
+func TestCreateItems(t *testing.T) {
+	r, ds := newWorkspaceTestRouter(t, map[string]string{
+		"alice@example.com/app/run.sh": "echo hi",
+	})
+	userDir := filepath.Join(ds.GetWorkspace().DatasitesDir, "alice@example.com")
+
+	results := createItems(t, r, &WorkspaceItemBatchCreateRequest{Items: []WorkspaceItemCreateRequest{
+		{Path: "/datasites/alice@example.com/project", Type: WorkspaceItemTypeFolder},
+		{Path: "/datasites/alice@example.com/project/src/main.py", Type: WorkspaceItemTypeFile},
+		{Path: "/datasites/alice@example.com/app/run.sh", Type: WorkspaceItemTypeFile},
+		{Path: "relative/path.txt", Type: WorkspaceItemTypeFile},
+		{Path: "/datasites/alice@example.com/project/README.md", Type: WorkspaceItemTypeFile},
+	}})
+
+	// every item is attempted
+	assert.Equal(t, []BatchCreateStatus{
+		BatchCreateStatusSuccess, BatchCreateStatusSuccess, BatchCreateStatusConflict, BatchCreateStatusError, BatchCreateStatusSuccess,
+	}, statuses(results))
+	require.NotNil(t, results[1].Item)
+	assert.Equal(t, "/datasites/alice@example.com/project/src/main.py", results[1].Item.Path)
+	require.NotNil(t, results[2].ExistingItem)
+	assert.Equal(t, int64(len("echo hi")), results[2].ExistingItem.Size)
+	assert.NotEmpty(t, results[3].Error)
+
+	assert.FileExists(t, filepath.Join(userDir, "project", "src", "main.py"))
+	assert.FileExists(t, filepath.Join(userDir, "project", "README.md"))
+}
+
+func TestCreateItemsExistOK(t *testing.T) {
+	r, ds := newWorkspaceTestRouter(t, map[string]string{
+		"alice@example.com/app/rpc/rpc.schema.json": "{}",
+	})
+	userDir := filepath.Join(ds.GetWorkspace().DatasitesDir, "alice@example.com")
+
+	// scaffolding over a partly existing tree
+	results := createItems(t, r, &WorkspaceItemBatchCreateRequest{Atomic: true, Items: []WorkspaceItemCreateRequest{
+		{Path: "/datasites/alice@example.com/app", Type: WorkspaceItemTypeFolder, ExistOK: true},
+		{Path: "/datasites/alice@example.com/app/rpc", Type: WorkspaceItemTypeFolder, ExistOK: true},
+		{Path: "/datasites/alice@example.com/app/rpc/requests/pending", Type: WorkspaceItemTypeFolder, ExistOK: true},
+		{Path: "/datasites/alice@example.com/app/rpc/README.md", Type: WorkspaceItemTypeFile, ExistOK: true},
+	}})
+	assert.Equal(t, []BatchCreateStatus{
+		BatchCreateStatusSuccess, BatchCreateStatusSuccess, BatchCreateStatusSuccess, BatchCreateStatusSuccess,
+	}, statuses(results))
+	require.NotNil(t, results[1].Item)
+	assert.Equal(t, WorkspaceItemTypeFolder, results[1].Item.Type)
+	assert.Equal(t, "/datasites/alice@example.com/app/rpc", results[1].Item.Path)
+	assert.FileExists(t, filepath.Join(userDir, "app", "rpc", "rpc.schema.json"), "the existing folder is left as is")
+	assert.DirExists(t, filepath.Join(userDir, "app", "rpc", "requests", "pending"))
+	assert.FileExists(t, filepath.Join(userDir, "app", "rpc", "README.md"))
+
+	// running it again is a no-op for the folders, files still conflict
+	results = createItems(t, r, &WorkspaceItemBatchCreateRequest{Items: []WorkspaceItemCreateRequest{
+		{Path: "/datasites/alice@example.com/app/rpc/requests/pending", Type: WorkspaceItemTypeFolder, ExistOK: true},
+		{Path: "/datasites/alice@example.com/app/rpc/README.md", Type: WorkspaceItemTypeFile, ExistOK: true},
+		{Path: "/datasites/alice@example.com/app/rpc/rpc.schema.json", Type: WorkspaceItemTypeFolder, ExistOK: true},
+		{Path: "/datasites/alice@example.com/app/rpc", Type: WorkspaceItemTypeFolder},
+	}})
+	assert.Equal(t, []BatchCreateStatus{
+		BatchCreateStatusSuccess, BatchCreateStatusConflict, BatchCreateStatusConflict, BatchCreateStatusConflict,
+	}, statuses(results))
+}
+
+func TestCreateItemsAtomic(t *testing.T) {
+	r, ds := newWorkspaceTestRouter(t, map[string]string{
+		"alice@example.com/app/run.sh":    "echo hi",
+		"alice@example.com/app/config.sh": "debug=1",
+	})
+	userDir := filepath.Join(ds.GetWorkspace().DatasitesDir, "alice@example.com")
+
+	results := createItems(t, r, &WorkspaceItemBatchCreateRequest{Atomic: true, Items: []WorkspaceItemCreateRequest{
+		{Path: "/datasites/alice@example.com/project/src/main.py", Type: WorkspaceItemTypeFile},
+		{Path: "/datasites/alice@example.com/app/run.sh", Type: WorkspaceItemTypeFile, Overwrite: true},
+		{Path: "/datasites/alice@example.com/app/config.sh", Type: WorkspaceItemTypeFile},
+		{Path: "/datasites/alice@example.com/project/README.md", Type: WorkspaceItemTypeFile},
+	}})
+
+	assert.Equal(t, []BatchCreateStatus{
+		BatchCreateStatusRolledBack, BatchCreateStatusRolledBack, BatchCreateStatusConflict, BatchCreateStatusSkipped,
+	}, statuses(results))
+
+	// as it was before the batch
+	assert.NoDirExists(t, filepath.Join(userDir, "project"))
+	content, err := os.ReadFile(filepath.Join(userDir, "app", "run.sh"))
+	require.NoError(t, err)
+	assert.Equal(t, "echo hi", string(content), "the replaced file is restored")
+	assert.Empty(t, leftovers(t, filepath.Join(userDir, "app")))
+
+	// the same batch without the conflict goes through
+	results = createItems(t, r, &WorkspaceItemBatchCreateRequest{Atomic: true, Items: []WorkspaceItemCreateRequest{
+		{Path: "/datasites/alice@example.com/project/src/main.py", Type: WorkspaceItemTypeFile},
+		{Path: "/datasites/alice@example.com/app/run.sh", Type: WorkspaceItemTypeFile, Overwrite: true},
+	}})
+	assert.Equal(t, []BatchCreateStatus{BatchCreateStatusSuccess, BatchCreateStatusSuccess}, statuses(results))
+	assert.FileExists(t, filepath.Join(userDir, "project", "src", "main.py"))
+	content, err = os.ReadFile(filepath.Join(userDir, "app", "run.sh"))
+	require.NoError(t, err)
+	assert.Empty(t, content)
+	assert.Empty(t, leftovers(t, filepath.Join(userDir, "app")))
+}
+
+func TestCreateItemsInvalid(t *testing.T) {
+	r, _ := newWorkspaceTestRouter(t, nil)
+
+	w := postJSON(t, r, "/v1/workspace/items/batch", &WorkspaceItemBatchCreateRequest{})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = postJSON(t, r, "/v1/workspace/items/batch", &WorkspaceItemBatchCreateRequest{Items: []WorkspaceItemCreateRequest{
+		{Path: "/datasites/alice@example.com/a.txt", Type: "symlink"},
+	}})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+// a 1x1 png, far from valid utf8
+var pngPixel = []byte{
+	0x89, 0x50, 0x4e, 0x47, 0x0d, 0x0a, 0x1a, 0x0a, 0x00, 0x00, 0x00, 0x0d, 0x49, 0x48, 0x44, 0x52,
+	0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01, 0x08, 0x06, 0x00, 0x00, 0x00, 0x1f, 0x15, 0xc4,
+	0x89, 0x00, 0x00, 0x00, 0x0d, 0x49, 0x44, 0x41, 0x54, 0x78, 0x9c, 0x63, 0xf8, 0xff, 0xff, 0x3f,
+	0x00, 0x05, 0xfe, 0x02, 0xfe, 0xa7, 0x35, 0x81, 0x84, 0x00, 0x00, 0x00, 0x00, 0x49, 0x45, 0x4e,
+	0x44, 0xae, 0x42, 0x60, 0x82,
+}
+
+func getContent(t *testing.T, r *gin.Engine, path string) []byte {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/v1/workspace/content?path="+path, nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	return w.Body.Bytes()
+}
+
+func TestUpdateContentBase64(t *testing.T) {
+	r, _ := newWorkspaceTestRouter(t, nil)
+	path := "/datasites/alice@example.com/public/pixel.png"
+
+	w := sendJSON(t, r, http.MethodPut, "/v1/workspace/content", &WorkspaceContentUpdateRequest{
+		Path:     path,
+		Content:  base64.StdEncoding.EncodeToString(pngPixel),
+		Mode:     UpdateModeOverwrite,
+		Create:   true,
+		Encoding: ContentEncodingBase64,
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, pngPixel, getContent(t, r, path))
+}
+
+func TestUpdateContentBase64AppendPrepend(t *testing.T) {
+	r, _ := newWorkspaceTestRouter(t, map[string]string{
+		"alice@example.com/data.bin": "middle",
+	})
+	path := "/datasites/alice@example.com/data.bin"
+
+	update := func(mode UpdateMode, content []byte) {
+		t.Helper()
+		w := sendJSON(t, r, http.MethodPut, "/v1/workspace/content", &WorkspaceContentUpdateRequest{
+			Path:     path,
+			Content:  base64.StdEncoding.EncodeToString(content),
+			Mode:     mode,
+			Encoding: ContentEncodingBase64,
+		})
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	}
+
+	// the decoded bytes are concatenated, not the encoded strings
+	update(UpdateModeAppend, []byte{0xff, 0x00})
+	update(UpdateModePrepend, []byte{0x89, 0xfe})
+	assert.Equal(t, append([]byte{0x89, 0xfe}, append([]byte("middle"), 0xff, 0x00)...), getContent(t, r, path))
+
+	// utf8 is still the default
+	w := sendJSON(t, r, http.MethodPut, "/v1/workspace/content", &WorkspaceContentUpdateRequest{
+		Path: path, Content: "aGk=", Mode: UpdateModeOverwrite,
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "aGk=", string(getContent(t, r, path)))
+}
+
+func TestUpdateContentInvalidBase64(t *testing.T) {
+	r, ds := newWorkspaceTestRouter(t, nil)
+
+	w := sendJSON(t, r, http.MethodPut, "/v1/workspace/content", &WorkspaceContentUpdateRequest{
+		Path:     "/datasites/alice@example.com/data.bin",
+		Content:  "not base64!",
+		Mode:     UpdateModeOverwrite,
+		Create:   true,
+		Encoding: ContentEncodingBase64,
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.NoFileExists(t, filepath.Join(ds.GetWorkspace().DatasitesDir, "alice@example.com", "data.bin"), "nothing is created")
+
+	w = sendJSON(t, r, http.MethodPut, "/v1/workspace/content", &WorkspaceContentUpdateRequest{
+		Path:     "/datasites/alice@example.com/data.bin",
+		Content:  "aGk=",
+		Mode:     UpdateModeOverwrite,
+		Create:   true,
+		Encoding: "hex",
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+// failingReader returns the content, then fails like a dropped connection
+type failingReader struct {
+	content []byte
+}
+
+func (f *failingReader) Read(p []byte) (int, error) {
+	if len(f.content) == 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	n := copy(p, f.content)
+	f.content = f.content[n:]
+	return n, nil
+}
+
+func uploadChunk(t *testing.T, r *gin.Engine, id string, offset int, body io.Reader) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPatch, "/v1/workspace/uploads/"+id+"?offset="+strconv.Itoa(offset), body)
+	req.Header.Set("Content-Type", "application/octet-stream")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func getUpload(t *testing.T, r *gin.Engine, id string) WorkspaceUploadSession {
+	t.Helper()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/workspace/uploads/"+id, nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var session WorkspaceUploadSession
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &session))
+	return session
+}
+
+func TestResumableUpload(t *testing.T) {
+	r, ds := newWorkspaceTestRouter(t, nil)
+	content := bytes.Repeat([]byte("0123456789"), 100)
+	path := "/datasites/alice@example.com/public/data.bin"
+
+	w := postJSON(t, r, "/v1/workspace/uploads", &WorkspaceUploadSessionRequest{Path: path, Size: int64(len(content))})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var session WorkspaceUploadSession
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &session))
+	assert.Zero(t, session.Offset)
+
+	// first chunk
+	w = uploadChunk(t, r, session.ID, 0, bytes.NewReader(content[:400]))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	// the second chunk is interrupted half way, the bytes that arrived are kept
+	w = uploadChunk(t, r, session.ID, 400, &failingReader{content: content[400:650]})
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	// resume from the received offset
+	session = getUpload(t, r, session.ID)
+	require.Equal(t, int64(650), session.Offset)
+
+	// a chunk at the wrong offset is rejected
+	w = uploadChunk(t, r, session.ID, 400, bytes.NewReader(content[400:]))
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// completing early is rejected
+	w = postJSON(t, r, "/v1/workspace/uploads/"+session.ID+"/complete", nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = uploadChunk(t, r, session.ID, 650, bytes.NewReader(content[650:]))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = postJSON(t, r, "/v1/workspace/uploads/"+session.ID+"/complete", nil)
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	var item WorkspaceItem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &item))
+	assert.Equal(t, path, item.Path)
+	assert.Equal(t, int64(len(content)), item.Size)
+	assert.Equal(t, SyncStatusSyncing, item.SyncStatus)
+
+	assembled, err := os.ReadFile(filepath.Join(ds.GetWorkspace().Root, path))
+	require.NoError(t, err)
+	assert.Equal(t, content, assembled)
+
+	// the upload is gone once completed
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/workspace/uploads/"+session.ID, nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestResumableUploadInvalid(t *testing.T) {
+	r, _ := newWorkspaceTestRouter(t, nil)
+
+	// only files in the datasites directory are synced
+	w := postJSON(t, r, "/v1/workspace/uploads", &WorkspaceUploadSessionRequest{Path: "/apps/data.bin", Size: 10})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = postJSON(t, r, "/v1/workspace/uploads", &WorkspaceUploadSessionRequest{Path: "/datasites/alice@example.com/data.bin", Size: 4})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var session WorkspaceUploadSession
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &session))
+
+	// a chunk past the size is dropped
+	w = uploadChunk(t, r, session.ID, 0, bytes.NewReader([]byte("too long")))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Zero(t, getUpload(t, r, session.ID).Offset)
+
+	w = uploadChunk(t, r, "../../config", 0, bytes.NewReader([]byte("x")))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// cancelled uploads are removed
+	w = sendJSON(t, r, http.MethodDelete, "/v1/workspace/uploads/"+session.ID, nil)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w = uploadChunk(t, r, session.ID, 0, bytes.NewReader([]byte("data")))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func upload(t *testing.T, r *gin.Engine, query url.Values, body io.Reader) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/v1/workspace/upload?"+query.Encode(), body)
+	req.Header.Set("Content-Type", "application/octet-stream")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestUpload(t *testing.T) {
+	r, ds := newWorkspaceTestRouter(t, map[string]string{"alice@example.com/private/source.csv": "a,b\n1,2\n"})
+	ws := ds.GetWorkspace()
+
+	// from the request body
+	path := "/datasites/alice@example.com/public/data.bin"
+	w := upload(t, r, url.Values{"path": {path}}, strings.NewReader("payload"))
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	var item WorkspaceItem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &item))
+	assert.Equal(t, path, item.Path)
+	assert.Equal(t, int64(len("payload")), item.Size)
+	content, err := os.ReadFile(filepath.Join(ws.Root, path))
+	require.NoError(t, err)
+	assert.Equal(t, "payload", string(content))
+
+	// from a local file in the workspace
+	path = "/datasites/alice@example.com/public/source.csv"
+	source := filepath.Join(ws.DatasitesDir, "alice@example.com", "private", "source.csv")
+	w = upload(t, r, url.Values{"path": {path}, "source": {source}}, nil)
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	content, err = os.ReadFile(filepath.Join(ws.Root, path))
+	require.NoError(t, err)
+	assert.Equal(t, "a,b\n1,2\n", string(content))
+}
+
+func TestUploadSourceOutsideWorkspace(t *testing.T) {
+	r, ds := newWorkspaceTestRouter(t, nil)
+	ws := ds.GetWorkspace()
+	path := "/datasites/alice@example.com/public/secret.txt"
+
+	outside := filepath.Join(t.TempDir(), "secret.txt")
+	require.NoError(t, os.WriteFile(outside, []byte("secret"), 0o600))
+
+	w := upload(t, r, url.Values{"path": {path}, "source": {outside}}, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code, w.Body.String())
+
+	// a path that climbs out of the workspace is rejected
+	beside := filepath.Join(filepath.Dir(ws.Root), "beside.txt")
+	require.NoError(t, os.WriteFile(beside, []byte("secret"), 0o600))
+	w = upload(t, r, url.Values{"path": {path}, "source": {ws.Root + "/../beside.txt"}}, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code, w.Body.String())
+
+	// and so is a symlink in the workspace that leads out of it
+	link := filepath.Join(ws.UserDir, "link.txt")
+	require.NoError(t, os.MkdirAll(ws.UserDir, 0o755))
+	require.NoError(t, os.Symlink(outside, link))
+	w = upload(t, r, url.Values{"path": {path}, "source": {link}}, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code, w.Body.String())
+
+	assert.NoFileExists(t, filepath.Join(ws.Root, path))
+}
+
+func TestUploadInvalid(t *testing.T) {
+	r, ds := newWorkspaceTestRouter(t, nil)
+	ws := ds.GetWorkspace()
+	require.NoError(t, os.MkdirAll(ws.UserDir, 0o755))
+
+	for _, tc := range []struct {
+		name   string
+		query  url.Values
+		status int
+	}{
+		{"no path", url.Values{}, http.StatusBadRequest},
+		{"outside datasites", url.Values{"path": {"/apps/data.bin"}}, http.StatusBadRequest},
+		{"relative source", url.Values{"path": {"/datasites/alice@example.com/data.bin"}, "source": {"data.bin"}}, http.StatusBadRequest},
+		{"missing source", url.Values{"path": {"/datasites/alice@example.com/data.bin"}, "source": {filepath.Join(ws.Root, "missing.bin")}}, http.StatusNotFound},
+		{"source directory", url.Values{"path": {"/datasites/alice@example.com/data.bin"}, "source": {ws.UserDir}}, http.StatusBadRequest},
+		{"workspace root", url.Values{"path": {"/datasites/alice@example.com/data.bin"}, "source": {ws.Root}}, http.StatusForbidden},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			w := upload(t, r, tc.query, strings.NewReader("data"))
+			assert.Equal(t, tc.status, w.Code, w.Body.String())
+		})
+	}
+}
+
+func getSearch(t *testing.T, r *gin.Engine, query url.Values) (*httptest.ResponseRecorder, *WorkspaceSearchResponse) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/workspace/search?"+query.Encode(), nil))
+
+	var resp WorkspaceSearchResponse
+	if w.Code == http.StatusOK {
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	}
+	return w, &resp
+}
+
+func searchPaths(resp *WorkspaceSearchResponse) []string {
+	paths := make([]string, 0, len(resp.Results))
+	for _, result := range resp.Results {
+		paths = append(paths, result.Item.Path)
+	}
+	return paths
+}
+
+func TestSearchItems(t *testing.T) {
+	r, _ := newWorkspaceTestRouter(t, map[string]string{
+		"alice@example.com/public/Report.csv":     "a,b\n1,2\n",
+		"alice@example.com/public/notes.txt":      "todo\nfind the report\ndone\nREPORT again\n",
+		"alice@example.com/public/data.bin":       "report\x00\x01\x02",
+		"alice@example.com/private/reports/q3.md": "# q3",
+		"bob@example.com/public/report.csv":       "x",
+	})
+
+	// names are matched without the case
+	w, resp := getSearch(t, r, url.Values{"q": {"report"}, "path": {"/datasites/alice@example.com"}})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.ElementsMatch(t, []string{
+		"/datasites/alice@example.com/private/reports",
+		"/datasites/alice@example.com/public/Report.csv",
+	}, searchPaths(resp))
+	assert.False(t, resp.Truncated)
+
+	// globs are matched against the whole name
+	w, resp = getSearch(t, r, url.Values{"q": {"*.csv"}})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.ElementsMatch(t, []string{
+		"/datasites/alice@example.com/public/Report.csv",
+		"/datasites/bob@example.com/public/report.csv",
+	}, searchPaths(resp))
+	for _, result := range resp.Results {
+		assert.Equal(t, WorkspaceItemTypeFile, result.Item.Type)
+		assert.True(t, result.NameMatch)
+	}
+
+	// the contents of the text files are searched, not the binary ones
+	w, resp = getSearch(t, r, url.Values{"q": {"report"}, "path": {"/datasites/alice@example.com/public"}, "content": {"true"}})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.ElementsMatch(t, []string{
+		"/datasites/alice@example.com/public/Report.csv",
+		"/datasites/alice@example.com/public/notes.txt",
+	}, searchPaths(resp))
+	for _, result := range resp.Results {
+		if result.Item.Name == "notes.txt" {
+			assert.False(t, result.NameMatch)
+			assert.Equal(t, []int{2, 4}, result.Lines)
+		}
+	}
+}
+
+func TestSearchItemsLimit(t *testing.T) {
+	r, _ := newWorkspaceTestRouter(t, map[string]string{
+		"alice@example.com/public/a.txt": "",
+		"alice@example.com/public/b.txt": "",
+		"alice@example.com/public/c.txt": "",
+	})
+
+	w, resp := getSearch(t, r, url.Values{"q": {".txt"}, "limit": {"2"}})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Len(t, resp.Results, 2)
+	assert.True(t, resp.Truncated)
+
+	w, resp = getSearch(t, r, url.Values{"q": {".txt"}, "limit": {"3"}})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Len(t, resp.Results, 3)
+	assert.False(t, resp.Truncated)
+}
+
+func TestSearchItemsInvalid(t *testing.T) {
+	r, _ := newWorkspaceTestRouter(t, map[string]string{
+		"alice@example.com/public/a.txt": "",
+	})
+
+	for name, query := range map[string]url.Values{
+		"no query":         {"path": {"/"}},
+		"relative path":    {"q": {"a"}, "path": {"datasites"}},
+		"outside the root": {"q": {"a"}, "path": {"/../../"}},
+		"a file":           {"q": {"a"}, "path": {"/datasites/alice@example.com/public/a.txt"}},
+		"invalid glob":     {"q": {"[a"}},
+		"limit too large":  {"q": {"a"}, "limit": {"5000"}},
+	} {
+		w, _ := getSearch(t, r, query)
+		assert.Equal(t, http.StatusBadRequest, w.Code, name)
+	}
+
+	w, _ := getSearch(t, r, url.Values{"q": {"a"}, "path": {"/datasites/nobody@example.com"}})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func encodePNG(t *testing.T, width, height int) string {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		for x := range width {
+			img.Set(x, y, color.NRGBA{R: 200, G: 50, B: 50, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.String()
+}
+
+func getThumbnail(t *testing.T, r *gin.Engine, path string, width int) image.Image {
+	t.Helper()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/workspace/content?preview=thumb&w="+strconv.Itoa(width)+"&path="+path, nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Equal(t, "image/jpeg", w.Header().Get("Content-Type"))
+
+	thumb, err := jpeg.Decode(w.Body)
+	require.NoError(t, err)
+	return thumb
+}
+
+func TestGetContentThumbnail(t *testing.T) {
+	r, ds := newWorkspaceTestRouter(t, map[string]string{
+		"alice@example.com/public/photo.png": encodePNG(t, 400, 200),
+		"alice@example.com/public/icon.png":  encodePNG(t, 20, 30),
+		"alice@example.com/public/notes.txt": "not an image",
+		"alice@example.com/public/fake.png":  "not a png",
+	})
+
+	// the aspect ratio is kept
+	thumb := getThumbnail(t, r, "/datasites/alice@example.com/public/photo.png", 100)
+	assert.Equal(t, image.Pt(100, 50), thumb.Bounds().Size())
+	red, green, _, _ := thumb.At(50, 25).RGBA()
+	assert.InDelta(t, 200, red>>8, 10)
+	assert.InDelta(t, 50, green>>8, 10)
+
+	// small images aren't scaled up
+	thumb = getThumbnail(t, r, "/datasites/alice@example.com/public/icon.png", 128)
+	assert.Equal(t, image.Pt(20, 30), thumb.Bounds().Size())
+
+	// a changed image gets a new thumbnail
+	absPath := filepath.Join(ds.GetWorkspace().DatasitesDir, "alice@example.com/public/photo.png")
+	require.NoError(t, os.WriteFile(absPath, []byte(encodePNG(t, 200, 400)), 0o644))
+	require.NoError(t, os.Chtimes(absPath, time.Now(), time.Now().Add(time.Minute)))
+	thumb = getThumbnail(t, r, "/datasites/alice@example.com/public/photo.png", 100)
+	assert.Equal(t, image.Pt(50, 100), thumb.Bounds().Size())
+
+	// other files are served as is
+	for path, content := range map[string]string{
+		"/datasites/alice@example.com/public/notes.txt": "not an image",
+		"/datasites/alice@example.com/public/fake.png":  "not a png",
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/workspace/content?preview=thumb&path="+path, nil))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, content, w.Body.String())
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/workspace/content?preview=thumb&w=5000&path=/datasites/alice@example.com/public/photo.png", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGetContentTypeSniffing(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, content []byte) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, content, 0o644))
+		return path
+	}
+
+	// unknown or missing extensions are sniffed
+	assert.Equal(t, "text/plain; charset=utf-8", getContentType(write("run", []byte("#!/bin/sh\necho hello\n"))))
+	assert.Equal(t, "application/octet-stream", getContentType(write("module.pyc", []byte{0x6f, 0x0d, 0x0d, 0x0a, 0x00, 0x00, 0x00, 0x00, 0xe3, 0x00, 0x01})))
+	assert.Equal(t, "image/png", getContentType(write("logo", []byte(encodePNG(t, 2, 2)))))
+
+	// the known extensions are more specific than sniffing
+	assert.Equal(t, "text/x-python", getContentType(write("main.py", []byte("print('hi')\n"))))
+	assert.Equal(t, "application/json", getContentType(write("data.json", []byte(`{"a": 1}`))))
+
+	assert.Equal(t, "application/octet-stream", getContentType(filepath.Join(dir, "missing")))
+}
+
+func TestPreviewACL(t *testing.T) {
+	r, ds := newWorkspaceTestRouter(t, map[string]string{
+		"alice@example.com/public/syft.pub.yaml":      publicACL,
+		"alice@example.com/public/report.csv":         "a,b\n1,2\n",
+		"alice@example.com/public/drafts/notes.txt":   "wip",
+		"alice@example.com/public/team/syft.pub.yaml": draftsACL,
+		"alice@example.com/public/team/plan.md":       "plan",
+	})
+
+	const proposed = "rules:\n  - pattern: 'drafts/**'\n    access:\n      write: ['carol@example.com']\n" +
+		"  - pattern: '**'\n    access:\n      read: ['bob@example.com']\n"
+
+	w := postJSON(t, r, "/v1/workspace/acl/preview", &WorkspaceACLPreviewRequest{
+		Path:    "/datasites/alice@example.com/public",
+		Content: proposed,
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp WorkspaceACLPreviewResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Empty(t, resp.ShadowedBy)
+
+	// the nearest ruleset of team/ is not affected. The users the rules name could read as everyone did,
+	// and only the first matching rule applies, so bob keeps reading the files but drafts/
+	assert.ElementsMatch(t, []ACLAccessChange{
+		{Path: "/datasites/alice@example.com/public/drafts/notes.txt", Principal: "carol@example.com", Before: "read", After: "write"},
+	}, resp.Granted)
+	assert.ElementsMatch(t, []ACLAccessChange{
+		{Path: "/datasites/alice@example.com/public/drafts/notes.txt", Principal: "*", Before: "read", After: ""},
+		{Path: "/datasites/alice@example.com/public/drafts/notes.txt", Principal: "bob@example.com", Before: "read", After: ""},
+		{Path: "/datasites/alice@example.com/public/report.csv", Principal: "*", Before: "read", After: ""},
+		{Path: "/datasites/alice@example.com/public/report.csv", Principal: "carol@example.com", Before: "read", After: ""},
+		{Path: "/datasites/alice@example.com/public/syft.pub.yaml", Principal: "*", Before: "read", After: ""},
+		{Path: "/datasites/alice@example.com/public/syft.pub.yaml", Principal: "carol@example.com", Before: "read", After: ""},
+	}, resp.Revoked)
+
+	// nothing is written
+	content, err := os.ReadFile(filepath.Join(ds.GetWorkspace().DatasitesDir, "alice@example.com/public/syft.pub.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, publicACL, string(content))
+}
+
+func TestPreviewACLTemplatesAndConditions(t *testing.T) {
+	r, _ := newWorkspaceTestRouter(t, map[string]string{
+		"alice@example.com/inbox/bob@example.com/msg.txt":   "hi bob",
+		"alice@example.com/inbox/carol@example.com/msg.txt": "hi carol",
+	})
+
+	const proposed = "rules:\n  - pattern: '{{.UserEmail}}/**'\n    access:\n      write: ['USER']\n" +
+		"  - pattern: '**'\n    access:\n      read:\n        - group: researchers\n"
+
+	w := postJSON(t, r, "/v1/workspace/acl/preview", &WorkspaceACLPreviewRequest{
+		Path:    "/datasites/alice@example.com/inbox",
+		Content: proposed,
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp WorkspaceACLPreviewResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+
+	// each user writes their own folder, the members of the group read them all
+	assert.ElementsMatch(t, []ACLAccessChange{
+		{Path: "/datasites/alice@example.com/inbox/bob@example.com/msg.txt", Principal: "bob@example.com", Before: "", After: "write"},
+		{Path: "/datasites/alice@example.com/inbox/bob@example.com/msg.txt", Principal: "group:researchers", Before: "", After: "read"},
+		{Path: "/datasites/alice@example.com/inbox/carol@example.com/msg.txt", Principal: "carol@example.com", Before: "", After: "write"},
+		{Path: "/datasites/alice@example.com/inbox/carol@example.com/msg.txt", Principal: "group:researchers", Before: "", After: "read"},
+	}, resp.Granted)
+	assert.Empty(t, resp.Revoked)
+}
+
+func TestPreviewACLShadowed(t *testing.T) {
+	r, _ := newWorkspaceTestRouter(t, map[string]string{
+		"alice@example.com/syft.pub.yaml":     "terminal: true\n" + publicACL,
+		"alice@example.com/public/report.csv": "a,b\n1,2\n",
+	})
+
+	w := postJSON(t, r, "/v1/workspace/acl/preview", &WorkspaceACLPreviewRequest{
+		Path:    "/datasites/alice@example.com/public",
+		Content: "rules:\n  - pattern: '**'\n    access:\n      read: ['bob@example.com']\n",
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp WorkspaceACLPreviewResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "/datasites/alice@example.com", resp.ShadowedBy)
+	assert.Empty(t, resp.Granted)
+	assert.Empty(t, resp.Revoked)
+}
+
+func TestPreviewACLInvalid(t *testing.T) {
+	r, _ := newWorkspaceTestRouter(t, nil)
+
+	for name, req := range map[string]*WorkspaceACLPreviewRequest{
+		"relative path":       {Path: "datasites/alice@example.com", Content: publicACL},
+		"outside datasites":   {Path: "/apps", Content: publicACL},
+		"the datasites dir":   {Path: "/datasites", Content: publicACL},
+		"invalid yaml":        {Path: "/datasites/alice@example.com", Content: "rules: ["},
+		"missing the content": {Path: "/datasites/alice@example.com"},
+	} {
+		w := postJSON(t, r, "/v1/workspace/acl/preview", req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, name)
+	}
+}
+
+func getTrash(t *testing.T, r *gin.Engine) []WorkspaceTrashItem {
+	t.Helper()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/workspace/trash", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp WorkspaceTrashResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return resp.Items
+}
+
+func TestDeleteItemsTrash(t *testing.T) {
+	r, ds := newWorkspaceTestRouter(t, map[string]string{
+		"alice@example.com/public/report.txt":    "v1",
+		"alice@example.com/public/data/rows.csv": "a,b\n",
+		"alice@example.com/public/keep.txt":      "keep",
+	})
+	ws := ds.GetWorkspace()
+	userDir := filepath.Join(ws.DatasitesDir, "alice@example.com", "public")
+
+	w := sendJSON(t, r, http.MethodDelete, "/v1/workspace/items", &WorkspaceItemDeleteRequest{
+		Paths: []string{"/datasites/alice@example.com/public/report.txt", "/datasites/alice@example.com/public/data"},
+		Trash: true,
+	})
+	require.Equal(t, http.StatusNoContent, w.Code, w.Body.String())
+	assert.NoFileExists(t, filepath.Join(userDir, "report.txt"))
+	assert.NoDirExists(t, filepath.Join(userDir, "data"))
+
+	// the trash is outside the datasites dir, and not listed with the workspace
+	assert.True(t, strings.HasPrefix(ws.TrashDir, ws.Root))
+	assert.False(t, strings.HasPrefix(ws.TrashDir, ws.DatasitesDir))
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/workspace/items?path=/&depth=0", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var listing WorkspaceItemsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &listing))
+	assert.NotEmpty(t, listing.Items)
+	assert.Nil(t, findItem(listing.Items, "/.trash"))
+
+	trash := getTrash(t, r)
+	require.Len(t, trash, 2)
+	byPath := map[string]WorkspaceTrashItem{}
+	for _, item := range trash {
+		byPath[item.OriginalPath] = item
+		assert.True(t, strings.HasSuffix(item.Id, "_"+item.Name), item.Id)
+	}
+	report := byPath["/datasites/alice@example.com/public/report.txt"]
+	assert.Equal(t, WorkspaceItemTypeFile, report.Type)
+	assert.Equal(t, WorkspaceItemTypeFolder, byPath["/datasites/alice@example.com/public/data"].Type)
+
+	// an item created at the path since conflicts, unless overwritten
+	require.NoError(t, os.WriteFile(filepath.Join(userDir, "report.txt"), []byte("v2"), 0o644))
+	w = postJSON(t, r, "/v1/workspace/trash/restore", &WorkspaceTrashRestoreRequest{Ids: []string{report.Id}})
+	require.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+	w = postJSON(t, r, "/v1/workspace/trash/restore", &WorkspaceTrashRestoreRequest{Ids: []string{report.Id}, Overwrite: true})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var restored WorkspaceTrashRestoreResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &restored))
+	require.Len(t, restored.Items, 1)
+	assert.Equal(t, "/datasites/alice@example.com/public/report.txt", restored.Items[0].Path)
+	content, err := os.ReadFile(filepath.Join(userDir, "report.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "v1", string(content))
+
+	w = postJSON(t, r, "/v1/workspace/trash/restore", &WorkspaceTrashRestoreRequest{Ids: []string{report.Id}})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Len(t, getTrash(t, r), 1)
+
+	// emptied for good
+	w = sendJSON(t, r, http.MethodDelete, "/v1/workspace/trash", nil)
+	require.Equal(t, http.StatusNoContent, w.Code)
+	assert.Empty(t, getTrash(t, r))
+	assert.NoDirExists(t, filepath.Join(userDir, "data"))
+	assert.FileExists(t, filepath.Join(userDir, "keep.txt"))
+}
+
+func TestDeleteItemsPermanentByDefault(t *testing.T) {
+	r, ds := newWorkspaceTestRouter(t, map[string]string{"alice@example.com/public/report.txt": "v1"})
+
+	w := sendJSON(t, r, http.MethodDelete, "/v1/workspace/items", &WorkspaceItemDeleteRequest{
+		Paths: []string{"/datasites/alice@example.com/public/report.txt"},
+	})
+	require.Equal(t, http.StatusNoContent, w.Code, w.Body.String())
+	assert.NoFileExists(t, filepath.Join(ds.GetWorkspace().DatasitesDir, "alice@example.com", "public", "report.txt"))
+	assert.Empty(t, getTrash(t, r))
+	assert.NoDirExists(t, ds.GetWorkspace().TrashDir)
+}
+
+func TestDeleteItemsTrashInvalid(t *testing.T) {
+	r, _ := newWorkspaceTestRouter(t, nil)
+
+	for _, path := range []string{"/", "/.trash", "/.trash/files", "/../outside"} {
+		w := sendJSON(t, r, http.MethodDelete, "/v1/workspace/items", &WorkspaceItemDeleteRequest{Paths: []string{path}, Trash: true})
+		assert.Equal(t, http.StatusBadRequest, w.Code, path)
+	}
+
+	for _, id := range []string{"..", "../info/x", ""} {
+		w := postJSON(t, r, "/v1/workspace/trash/restore", &WorkspaceTrashRestoreRequest{Ids: []string{id}})
+		assert.Equal(t, http.StatusBadRequest, w.Code, id)
+	}
+}
+
+func TestMoveItemsBatch(t *testing.T) {
+	r, ds := newWorkspaceTestRouter(t, map[string]string{
+		"alice@example.com/public/a.txt":      "a",
+		"alice@example.com/public/b.txt":      "b",
+		"alice@example.com/public/c.txt":      "c",
+		"alice@example.com/public/d.txt":      "d",
+		"alice@example.com/public/taken.txt":  "taken",
+		"alice@example.com/public/dir/x.csv":  "x",
+		"alice@example.com/archive/.keep.txt": "",
+	})
+	userDir := filepath.Join(ds.GetWorkspace().DatasitesDir, "alice@example.com")
+	public := "/datasites/alice@example.com/public/"
+	archive := "/datasites/alice@example.com/archive/"
+
+	w := postJSON(t, r, "/v1/workspace/items/move", &WorkspaceItemMoveRequest{Items: []WorkspaceItemMovePair{
+		{SourcePath: public + "a.txt", NewPath: archive + "a.txt"},
+		{SourcePath: public + "dir", NewPath: archive + "dir"},
+		{SourcePath: public + "b.txt", NewPath: archive + "same.txt"},
+		{SourcePath: public + "c.txt", NewPath: archive + "same.txt"},
+		{SourcePath: public + "d.txt", NewPath: public + "taken.txt"},
+		{SourcePath: public + "missing.txt", NewPath: archive + "missing.txt"},
+		{SourcePath: "relative.txt", NewPath: archive + "relative.txt"},
+		{SourcePath: public + "d.txt", NewPath: "/../outside.txt"},
+	}})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp WorkspaceItemBatchMoveResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+
+	var statuses []BatchCreateStatus
+	for _, result := range resp.Results {
+		statuses = append(statuses, result.Status)
+	}
+	assert.Equal(t, []BatchCreateStatus{
+		BatchCreateStatusSuccess,
+		BatchCreateStatusSuccess,
+		BatchCreateStatusConflict, // both moved to the same path
+		BatchCreateStatusConflict,
+		BatchCreateStatusConflict, // existing destination
+		BatchCreateStatusError,
+		BatchCreateStatusError,
+		BatchCreateStatusError,
+	}, statuses)
+
+	require.NotNil(t, resp.Results[0].Item)
+	assert.Equal(t, archive+"a.txt", resp.Results[0].Item.Path)
+	assert.FileExists(t, filepath.Join(userDir, "archive", "a.txt"))
+	assert.FileExists(t, filepath.Join(userDir, "archive", "dir", "x.csv"))
+	assert.NoFileExists(t, filepath.Join(userDir, "public", "a.txt"))
+
+	// the colliding and failed items are left as they were
+	assert.Contains(t, resp.Results[2].Error, "more than one item")
+	assert.FileExists(t, filepath.Join(userDir, "public", "b.txt"))
+	assert.FileExists(t, filepath.Join(userDir, "public", "c.txt"))
+	assert.NoFileExists(t, filepath.Join(userDir, "archive", "same.txt"))
+	require.NotNil(t, resp.Results[4].ExistingItem)
+	assert.Equal(t, public+"taken.txt", resp.Results[4].ExistingItem.Path)
+	assert.FileExists(t, filepath.Join(userDir, "public", "d.txt"))
+	assert.Contains(t, resp.Results[7].Error, "within the workspace")
+}
+
+func TestMoveItemsSingle(t *testing.T) {
+	r, ds := newWorkspaceTestRouter(t, map[string]string{
+		"alice@example.com/public/a.txt": "a",
+		"alice@example.com/public/b.txt": "b",
+	})
+	public := "/datasites/alice@example.com/public/"
+
+	w := postJSON(t, r, "/v1/workspace/items/move", &WorkspaceItemMoveRequest{SourcePath: public + "a.txt", NewPath: public + "b.txt"})
+	require.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+	var conflict WorkspaceConflictError
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &conflict))
+	assert.Equal(t, "destination already exists: "+public+"b.txt", conflict.Error)
+	assert.Equal(t, public+"b.txt", conflict.ExistingItem.Path)
+
+	w = postJSON(t, r, "/v1/workspace/items/move", &WorkspaceItemMoveRequest{SourcePath: public + "a.txt", NewPath: public + "renamed.txt"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp WorkspaceItemMoveResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, public+"renamed.txt", resp.Item.Path)
+	assert.FileExists(t, filepath.Join(ds.GetWorkspace().DatasitesDir, "alice@example.com", "public", "renamed.txt"))
+
+	w = postJSON(t, r, "/v1/workspace/items/move", &WorkspaceItemMoveRequest{SourcePath: public + "missing.txt", NewPath: public + "x.txt"})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// one form or the other
+	for _, req := range []*WorkspaceItemMoveRequest{
+		{},
+		{SourcePath: public + "b.txt"},
+		{SourcePath: public + "b.txt", NewPath: public + "c.txt", Items: []WorkspaceItemMovePair{{SourcePath: public + "b.txt", NewPath: public + "d.txt"}}},
+		{Items: []WorkspaceItemMovePair{{SourcePath: public + "b.txt"}}},
+	} {
+		w = postJSON(t, r, "/v1/workspace/items/move", req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+	}
+}
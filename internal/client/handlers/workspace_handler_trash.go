@@ -0,0 +1,353 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/openmined/syftbox/internal/client/workspace"
+)
+
+const (
+	ErrCodeTrashWorkspaceItemsFailed   = "ERR_TRASH_WORKSPACE_ITEMS_FAILED"
+	ErrCodeRestoreWorkspaceItemsFailed = "ERR_RESTORE_WORKSPACE_ITEMS_FAILED"
+)
+
+const (
+	// the trashed items, and where they were deleted from, like the freedesktop trash
+	trashFilesDir = "files"
+	trashInfoDir  = "info"
+	trashInfoExt  = ".json"
+	// prefix of the names in the trash, sortable by deletion time
+	trashTimeFormat = "20060102T150405.000"
+)
+
+// trashInfo is kept next to each trashed item, to restore it where it was
+type trashInfo struct {
+	Path      string    `json:"path"`
+	DeletedAt time.Time `json:"deletedAt"`
+}
+
+// GetTrash lists the items in the trash
+//
+//	@Summary		List the trash
+//	@Description	List the items deleted to the trash, the most recently deleted first
+//	@Tags			Workspace
+//	@Produce		json
+//	@Success		200	{object}	WorkspaceTrashResponse
+//	@Failure		400	{object}	ControlPlaneError
+//	@Failure		401	{object}	ControlPlaneError
+//	@Failure		403	{object}	ControlPlaneError
+//	@Failure		429	{object}	ControlPlaneError
+//	@Failure		500	{object}	ControlPlaneError
+//	@Failure		503	{object}	ControlPlaneError
+//	@Router			/v1/workspace/trash [get]
+func (h *WorkspaceHandler) GetTrash(c *gin.Context) {
+	ds, err := h.mgr.Get()
+	if err != nil {
+		c.PureJSON(http.StatusServiceUnavailable, &ControlPlaneError{
+			ErrorCode: ErrCodeDatasiteNotReady,
+			Error:     err.Error(),
+		})
+		return
+	}
+
+	items, err := listTrash(ds.GetWorkspace())
+	if err != nil {
+		c.PureJSON(http.StatusInternalServerError, &ControlPlaneError{
+			ErrorCode: ErrCodeListWorkspaceItemsFailed,
+			Error:     err.Error(),
+		})
+		return
+	}
+
+	c.PureJSON(http.StatusOK, &WorkspaceTrashResponse{
+		Items: items,
+	})
+}
+
+// RestoreTrash restores items from the trash
+//
+//	@Summary		Restore items from the trash
+//	@Description	Move items back from the trash to where they were deleted from, creating the missing parent folders.
+//	@Description	Nothing is restored if any of the items conflicts with an item created at its path since, unless overwrite is set.
+//	@Tags			Workspace
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		WorkspaceTrashRestoreRequest	true	"Request body"
+//	@Success		200		{object}	WorkspaceTrashRestoreResponse
+//	@Failure		400		{object}	ControlPlaneError
+//	@Failure		401		{object}	ControlPlaneError
+//	@Failure		403		{object}	ControlPlaneError
+//	@Failure		404		{object}	ControlPlaneError
+//	@Failure		409		{object}	WorkspaceConflictError
+//	@Failure		429		{object}	ControlPlaneError
+//	@Failure		500		{object}	ControlPlaneError
+//	@Failure		503		{object}	ControlPlaneError
+//	@Router			/v1/workspace/trash/restore [post]
+func (h *WorkspaceHandler) RestoreTrash(c *gin.Context) {
+	var req WorkspaceTrashRestoreRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.PureJSON(http.StatusBadRequest, &ControlPlaneError{
+			ErrorCode: ErrCodeBadRequest,
+			Error:     err.Error(),
+		})
+		return
+	}
+
+	ds, err := h.mgr.Get()
+	if err != nil {
+		c.PureJSON(http.StatusServiceUnavailable, &ControlPlaneError{
+			ErrorCode: ErrCodeDatasiteNotReady,
+			Error:     err.Error(),
+		})
+		return
+	}
+
+	ws := ds.GetWorkspace()
+
+	// check all the items first, so that a conflict restores none of them
+	dests := make([]string, len(req.Ids))
+	for i, id := range req.Ids {
+		if id == "" || id == "." || id == ".." || strings.ContainsAny(id, `/\`) {
+			c.PureJSON(http.StatusBadRequest, &ControlPlaneError{
+				ErrorCode: ErrCodeBadRequest,
+				Error:     "invalid trash item id: " + id,
+			})
+			return
+		}
+
+		info, err := readTrashInfo(ws, id)
+		if errors.Is(err, os.ErrNotExist) {
+			c.PureJSON(http.StatusNotFound, &ControlPlaneError{
+				ErrorCode: ErrCodeRestoreWorkspaceItemsFailed,
+				Error:     "item not in the trash: " + id,
+			})
+			return
+		} else if err != nil {
+			c.PureJSON(http.StatusInternalServerError, &ControlPlaneError{
+				ErrorCode: ErrCodeRestoreWorkspaceItemsFailed,
+				Error:     err.Error(),
+			})
+			return
+		}
+
+		dest := filepath.Join(ws.Root, filepath.FromSlash(info.Path))
+		if !withinRoot(ws.Root, dest) || withinRoot(ws.TrashDir, dest) {
+			c.PureJSON(http.StatusBadRequest, &ControlPlaneError{
+				ErrorCode: ErrCodeBadRequest,
+				Error:     "invalid original path of trash item " + id + ": " + info.Path,
+			})
+			return
+		}
+		if slices.Contains(dests[:i], dest) {
+			c.PureJSON(http.StatusBadRequest, &ControlPlaneError{
+				ErrorCode: ErrCodeBadRequest,
+				Error:     "more than one item restores to " + info.Path,
+			})
+			return
+		}
+		dests[i] = dest
+
+		if existingInfo, err := os.Lstat(dest); err == nil && !req.Overwrite {
+			c.PureJSON(http.StatusConflict, &WorkspaceConflictError{
+				ErrorCode:    ErrCodeRestoreWorkspaceItemsFailed,
+				Error:        "destination already exists: " + info.Path,
+				ExistingItem: existingItem(ds, dest, existingInfo),
+			})
+			return
+		} else if err != nil && !os.IsNotExist(err) {
+			c.PureJSON(http.StatusInternalServerError, &ControlPlaneError{
+				ErrorCode: ErrCodeRestoreWorkspaceItemsFailed,
+				Error:     err.Error(),
+			})
+			return
+		}
+	}
+
+	items := make([]WorkspaceItem, 0, len(req.Ids))
+	for i, id := range req.Ids {
+		info, err := restoreTrashItem(ws, id, dests[i])
+		if err != nil {
+			c.PureJSON(http.StatusInternalServerError, &ControlPlaneError{
+				ErrorCode: ErrCodeRestoreWorkspaceItemsFailed,
+				Error:     err.Error(),
+			})
+			return
+		}
+		items = append(items, existingItem(ds, dests[i], info))
+	}
+
+	c.PureJSON(http.StatusOK, &WorkspaceTrashRestoreResponse{
+		Items: items,
+	})
+}
+
+// EmptyTrash permanently deletes the items in the trash
+//
+//	@Summary		Empty the trash
+//	@Description	Permanently delete all the items in the trash
+//	@Tags			Workspace
+//	@Success		204	{object}	nil
+//	@Failure		400	{object}	ControlPlaneError
+//	@Failure		401	{object}	ControlPlaneError
+//	@Failure		403	{object}	ControlPlaneError
+//	@Failure		429	{object}	ControlPlaneError
+//	@Failure		500	{object}	ControlPlaneError
+//	@Failure		503	{object}	ControlPlaneError
+//	@Router			/v1/workspace/trash [delete]
+func (h *WorkspaceHandler) EmptyTrash(c *gin.Context) {
+	ds, err := h.mgr.Get()
+	if err != nil {
+		c.PureJSON(http.StatusServiceUnavailable, &ControlPlaneError{
+			ErrorCode: ErrCodeDatasiteNotReady,
+			Error:     err.Error(),
+		})
+		return
+	}
+
+	if err := os.RemoveAll(ds.GetWorkspace().TrashDir); err != nil {
+		c.PureJSON(http.StatusInternalServerError, &ControlPlaneError{
+			ErrorCode: ErrCodeDeleteWorkspaceItemFailed,
+			Error:     err.Error(),
+		})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// checkTrashable checks if the item at absPath can be moved to the trash
+func checkTrashable(ws *workspace.Workspace, absPath string) error {
+	if absPath == ws.Root {
+		return errors.New("the workspace root can't be moved to the trash")
+	}
+	if withinRoot(ws.TrashDir, absPath) {
+		return errors.New("already in the trash: " + absPath)
+	}
+	return nil
+}
+
+// trashItem moves the item at absPath to the trash, under the deletion time followed by its name
+func trashItem(ws *workspace.Workspace, absPath string, deletedAt time.Time) error {
+	filesDir := filepath.Join(ws.TrashDir, trashFilesDir)
+	infoDir := filepath.Join(ws.TrashDir, trashInfoDir)
+	for _, dir := range []string{filesDir, infoDir} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+
+	relPath, err := filepath.Rel(ws.Root, absPath)
+	if err != nil {
+		return err
+	}
+	info, err := json.Marshal(&trashInfo{
+		Path:      filepath.Join("/", filepath.ToSlash(relPath)),
+		DeletedAt: deletedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	// the info is written first, an item in the trash can always be restored
+	id := trashID(ws, filepath.Base(absPath), deletedAt)
+	infoPath := filepath.Join(infoDir, id+trashInfoExt)
+	if err := os.WriteFile(infoPath, info, 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(absPath, filepath.Join(filesDir, id)); err != nil {
+		os.Remove(infoPath)
+		return fmt.Errorf("failed to move to the trash: %w", err)
+	}
+	return nil
+}
+
+// trashID names a trashed item after the deletion time, a millisecond later if that's already taken
+func trashID(ws *workspace.Workspace, name string, deletedAt time.Time) string {
+	for t := deletedAt.UTC(); ; t = t.Add(time.Millisecond) {
+		id := t.Format(trashTimeFormat) + "_" + name
+		if _, err := os.Lstat(filepath.Join(ws.TrashDir, trashInfoDir, id+trashInfoExt)); os.IsNotExist(err) {
+			return id
+		}
+	}
+}
+
+func readTrashInfo(ws *workspace.Workspace, id string) (*trashInfo, error) {
+	data, err := os.ReadFile(filepath.Join(ws.TrashDir, trashInfoDir, id+trashInfoExt))
+	if err != nil {
+		return nil, err
+	}
+	var info trashInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, fmt.Errorf("invalid trash info of %s: %w", id, err)
+	}
+	return &info, nil
+}
+
+// listTrash lists the items in the trash, the most recently deleted first
+func listTrash(ws *workspace.Workspace) ([]WorkspaceTrashItem, error) {
+	entries, err := os.ReadDir(filepath.Join(ws.TrashDir, trashInfoDir))
+	if os.IsNotExist(err) {
+		return []WorkspaceTrashItem{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	items := []WorkspaceTrashItem{}
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), trashInfoExt)
+		if !ok || entry.IsDir() {
+			continue
+		}
+
+		info, err := readTrashInfo(ws, id)
+		if err != nil {
+			continue
+		}
+		// the info of an item that didn't make it to the trash
+		fileInfo, err := os.Lstat(filepath.Join(ws.TrashDir, trashFilesDir, id))
+		if err != nil {
+			continue
+		}
+
+		itemType := WorkspaceItemTypeFile
+		if fileInfo.IsDir() {
+			itemType = WorkspaceItemTypeFolder
+		}
+		items = append(items, WorkspaceTrashItem{
+			Id:           id,
+			Name:         filepath.Base(info.Path),
+			Type:         itemType,
+			OriginalPath: info.Path,
+			DeletedAt:    info.DeletedAt,
+			Size:         fileInfo.Size(),
+		})
+	}
+
+	// the ids start with the deletion time
+	slices.Reverse(items)
+	return items, nil
+}
+
+// restoreTrashItem moves a trashed item back to dest, replacing what's there
+func restoreTrashItem(ws *workspace.Workspace, id string, dest string) (os.FileInfo, error) {
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return nil, err
+	}
+	if err := os.RemoveAll(dest); err != nil {
+		return nil, fmt.Errorf("failed to remove existing item: %w", err)
+	}
+	if err := os.Rename(filepath.Join(ws.TrashDir, trashFilesDir, id), dest); err != nil {
+		return nil, err
+	}
+	os.Remove(filepath.Join(ws.TrashDir, trashInfoDir, id+trashInfoExt))
+	return os.Lstat(dest)
+}
@@ -108,6 +108,8 @@ type WorkspaceItemCreateRequest struct {
 	Path      string            `json:"path" binding:"required"`
 	Type      WorkspaceItemType `json:"type" binding:"required,oneof=file folder"`
 	Overwrite bool              `json:"overwrite,omitempty" default:"false"`
+	// Return an existing folder as is instead of a conflict, like mkdir -p. Files still conflict
+	ExistOK bool `json:"existOk,omitempty" default:"false"`
 }
 
 // WorkspaceItemCreateResponse represents the response for creating a workspace item
@@ -115,19 +117,93 @@ type WorkspaceItemCreateResponse struct {
 	Item WorkspaceItem `json:"item"`
 }
 
+// WorkspaceItemBatchCreateRequest represents the request for creating several workspace items
+type WorkspaceItemBatchCreateRequest struct {
+	Items []WorkspaceItemCreateRequest `json:"items" binding:"required,min=1,dive"`
+	// Stop at the first failure and remove the items created before it
+	Atomic bool `json:"atomic,omitempty" default:"false"`
+}
+
+// BatchCreateStatus is the result of one item of a batch
+type BatchCreateStatus string
+
+const (
+	BatchCreateStatusSuccess    BatchCreateStatus = "success"
+	BatchCreateStatusConflict   BatchCreateStatus = "conflict"
+	BatchCreateStatusError      BatchCreateStatus = "error"
+	BatchCreateStatusRolledBack BatchCreateStatus = "rolledBack" // created, then removed as a later item failed
+	BatchCreateStatusSkipped    BatchCreateStatus = "skipped"    // not attempted as an earlier item failed
+)
+
+// WorkspaceItemBatchCreateResult represents the result of one item of a batch, in the order of the request
+type WorkspaceItemBatchCreateResult struct {
+	Path         string            `json:"path"`
+	Status       BatchCreateStatus `json:"status"`
+	Item         *WorkspaceItem    `json:"item,omitempty"`
+	ExistingItem *WorkspaceItem    `json:"existingItem,omitempty"`
+	Error        string            `json:"error,omitempty"`
+}
+
+// WorkspaceItemBatchCreateResponse represents the response for creating several workspace items
+type WorkspaceItemBatchCreateResponse struct {
+	Results []WorkspaceItemBatchCreateResult `json:"results"`
+}
+
 // WorkspaceItemDeleteRequest represents the request for deleting workspace items
 type WorkspaceItemDeleteRequest struct {
 	Paths []string `json:"paths" binding:"required"`
+	// Move the items to the trash instead of deleting them permanently, to restore them later
+	Trash bool `json:"trash,omitempty" default:"false"`
+}
+
+// WorkspaceTrashItem represents an item in the trash
+type WorkspaceTrashItem struct {
+	// Name of the item in the trash, the deletion time followed by the name of the item
+	Id   string            `json:"id"`
+	Name string            `json:"name"`
+	Type WorkspaceItemType `json:"type"`
+	// Full path the item was deleted from, where it's restored to
+	OriginalPath string    `json:"originalPath"`
+	DeletedAt    time.Time `json:"deletedAt"`
+	Size         int64     `json:"size"`
+}
+
+// WorkspaceTrashResponse represents the response for listing the trash
+type WorkspaceTrashResponse struct {
+	Items []WorkspaceTrashItem `json:"items"`
 }
 
-// WorkspaceItemMoveRequest represents the request for moving a workspace item
+// WorkspaceTrashRestoreRequest represents the request for restoring items from the trash
+type WorkspaceTrashRestoreRequest struct {
+	// Ids of the items in the trash
+	Ids []string `json:"ids" binding:"required,min=1"`
+	// Overwrite the items that were created at the original paths since
+	Overwrite bool `json:"overwrite,omitempty" default:"false"`
+}
+
+// WorkspaceTrashRestoreResponse represents the response for restoring items from the trash
+type WorkspaceTrashRestoreResponse struct {
+	Items []WorkspaceItem `json:"items"`
+}
+
+// WorkspaceItemMoveRequest represents the request for moving a workspace item, or several with items
 type WorkspaceItemMoveRequest struct {
 	// Full path to the source item
-	SourcePath string `json:"sourcePath" binding:"required"`
+	SourcePath string `json:"sourcePath" binding:"required_without=Items"`
 	// Full path to the new item location, including the item name
-	NewPath string `json:"newPath" binding:"required"`
+	NewPath string `json:"newPath" binding:"required_without=Items"`
 	// Overwrite the destination item if it exists
 	Overwrite bool `json:"overwrite,omitempty" default:"false"`
+	// Items to move in one request instead of sourcePath and newPath, each with its own result
+	Items []WorkspaceItemMovePair `json:"items,omitempty" binding:"omitempty,dive"`
+}
+
+// WorkspaceItemMovePair represents one item of a batch move
+type WorkspaceItemMovePair struct {
+	// Full path to the source item
+	SourcePath string `json:"sourcePath" binding:"required"`
+	// Full path to the new item location, including the item name
+	NewPath string `json:"newPath" binding:"required"`
 }
 
 // WorkspaceItemMoveResponse represents the response for moving a workspace item
@@ -135,6 +211,21 @@ type WorkspaceItemMoveResponse struct {
 	Item WorkspaceItem `json:"item"`
 }
 
+// WorkspaceItemBatchMoveResult represents the result of one item of a batch move, in the order of the request
+type WorkspaceItemBatchMoveResult struct {
+	SourcePath   string            `json:"sourcePath"`
+	NewPath      string            `json:"newPath"`
+	Status       BatchCreateStatus `json:"status"`
+	Item         *WorkspaceItem    `json:"item,omitempty"`
+	ExistingItem *WorkspaceItem    `json:"existingItem,omitempty"`
+	Error        string            `json:"error,omitempty"`
+}
+
+// WorkspaceItemBatchMoveResponse represents the response for moving several workspace items
+type WorkspaceItemBatchMoveResponse struct {
+	Results []WorkspaceItemBatchMoveResult `json:"results"`
+}
+
 // WorkspaceItemCopyRequest represents the request for copying a workspace item
 type WorkspaceItemCopyRequest struct {
 	// Full path of the item to copy
@@ -159,9 +250,21 @@ const (
 	UpdateModePrepend   UpdateMode = "prepend"   // Add content to start of file
 )
 
+// ContentEncoding represents how the content of an update is encoded in the request
+type ContentEncoding string
+
+const (
+	ContentEncodingUTF8   ContentEncoding = "utf8"   // Plain text, written as is
+	ContentEncodingBase64 ContentEncoding = "base64" // Standard base64, decoded before writing. For binary files
+)
+
 // WorkspaceContentRequest represents the request parameters for getting file content
 type WorkspaceContentRequest struct {
 	Path string `form:"path" binding:"required"`
+	// Serve a preview of the file instead, e.g. thumb for a thumbnail of an image
+	Preview string `form:"preview" binding:"omitempty,oneof=thumb"`
+	// Longest side of the thumbnail in pixels
+	Width int `form:"w" binding:"omitempty,min=16,max=1024"`
 }
 
 // WorkspaceContentUpdateRequest represents the request for updating file content
@@ -170,6 +273,62 @@ type WorkspaceContentUpdateRequest struct {
 	Content string     `json:"content" binding:"required"`
 	Mode    UpdateMode `json:"mode" binding:"required,oneof=overwrite append prepend" default:"overwrite"`
 	Create  bool       `json:"create" default:"false"` // Create file if it doesn't exist
+	// Encoding of the content
+	Encoding ContentEncoding `json:"encoding,omitempty" binding:"omitempty,oneof=utf8 base64" default:"utf8"`
+}
+
+// WorkspaceSearchRequest represents the request parameters for searching the workspace
+type WorkspaceSearchRequest struct {
+	// Substring of the names to find, or a glob pattern like *.csv. Matched without the case
+	Query string `form:"q" binding:"required"`
+	// Path to the directory to search under (default is root)
+	Path string `form:"path"`
+	// Also search the contents of the text files
+	Content bool `form:"content"`
+	// Maximum number of results
+	Limit int `form:"limit" binding:"min=0,max=1000" default:"100"`
+}
+
+// WorkspaceSearchResult represents an item matching a search
+type WorkspaceSearchResult struct {
+	Item WorkspaceItem `json:"item"`
+	// The name of the item matches the query
+	NameMatch bool `json:"nameMatch"`
+	// Line numbers in the content matching the query, starting at 1
+	Lines []int `json:"lines,omitempty"`
+}
+
+// WorkspaceSearchResponse represents the response for searching the workspace
+type WorkspaceSearchResponse struct {
+	Results []WorkspaceSearchResult `json:"results"`
+	// More items matched than the limit
+	Truncated bool `json:"truncated"`
+}
+
+// WorkspaceACLPreviewRequest represents the request for previewing an ACL change
+type WorkspaceACLPreviewRequest struct {
+	// Full path of the folder of the syft.pub.yaml, e.g. /datasites/user@example.com/public
+	Path string `json:"path" binding:"required"`
+	// Proposed content of the syft.pub.yaml
+	Content string `json:"content" binding:"required"`
+}
+
+// ACLAccessChange represents the change of a principal's access to a file
+type ACLAccessChange struct {
+	Path string `json:"path"`
+	// User the access changes for, or * for everyone
+	Principal string `json:"principal"`
+	// Access before and after the change: read, write or admin, empty for none
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// WorkspaceACLPreviewResponse represents the access that would change with a proposed ACL
+type WorkspaceACLPreviewResponse struct {
+	Granted []ACLAccessChange `json:"granted"`
+	Revoked []ACLAccessChange `json:"revoked"`
+	// Folder of a terminal syft.pub.yaml above, which keeps the proposed one from applying
+	ShadowedBy string `json:"shadowedBy,omitempty"`
 }
 
 // WorkspaceConflictError represents an error response when there is a conflict with an existing item
@@ -178,3 +337,30 @@ type WorkspaceConflictError struct {
 	Error        string        `json:"error"`
 	ExistingItem WorkspaceItem `json:"existingItem"`
 }
+
+// WorkspaceUploadRequest represents the request parameters for uploading a file.
+// The content is read from the request body, unless a local source file is given.
+type WorkspaceUploadRequest struct {
+	// Full path of the file in the workspace, e.g. /datasites/user@example.com/public/file.bin
+	Path string `form:"path" binding:"required"`
+	// Absolute path to a local file in the workspace to upload instead of the request body
+	Source string `form:"source"`
+}
+
+// WorkspaceUploadSessionRequest represents the request for starting a resumable upload
+type WorkspaceUploadSessionRequest struct {
+	// Full path of the file in the workspace, e.g. /datasites/user@example.com/public/file.bin
+	Path string `json:"path" binding:"required"`
+	// Size of the file in bytes
+	Size int64 `json:"size" binding:"min=0"`
+}
+
+// WorkspaceUploadSession represents the state of a resumable upload
+type WorkspaceUploadSession struct {
+	ID   string `json:"id"`
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+	// Number of bytes received so far, where the next chunk starts
+	Offset    int64     `json:"offset"`
+	CreatedAt time.Time `json:"createdAt"`
+}
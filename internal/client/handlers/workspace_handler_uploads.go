@@ -0,0 +1,560 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	syncpkg "github.com/openmined/syftbox/internal/client/sync"
+	"github.com/openmined/syftbox/internal/client/workspace"
+	"github.com/openmined/syftbox/internal/utils"
+)
+
+const (
+	ErrCodeUploadNotFound       = "ERR_UPLOAD_NOT_FOUND"
+	ErrCodeUploadOffsetMismatch = "ERR_UPLOAD_OFFSET_MISMATCH"
+	ErrCodeUploadIncomplete     = "ERR_UPLOAD_INCOMPLETE"
+)
+
+const (
+	uploadsDir       = "uploads"
+	uploadSessionTTL = 24 * time.Hour
+)
+
+var (
+	errUploadNotFound = errors.New("upload not found")
+	errUploadBusy     = errors.New("another chunk of this upload is in progress")
+	regexUploadID     = regexp.MustCompile(`^[0-9a-f]{32}$`)
+)
+
+// uploadSessions keeps the resumable uploads in the workspace metadata dir, so that they survive restarts.
+// The received content of an upload is in {id}.part, its offset is the size of that file.
+type uploadSessions struct {
+	mu   sync.Mutex
+	busy map[string]bool // uploads with a chunk being written
+}
+
+func newUploadSessions() *uploadSessions {
+	return &uploadSessions{busy: make(map[string]bool)}
+}
+
+func (u *uploadSessions) dir(ws *workspace.Workspace) string {
+	return filepath.Join(ws.MetadataDir, uploadsDir)
+}
+
+func (u *uploadSessions) create(ws *workspace.Workspace, path string, size int64) (*WorkspaceUploadSession, error) {
+	dir := u.dir(ws)
+	if err := utils.EnsureDir(dir); err != nil {
+		return nil, err
+	}
+	u.prune(dir)
+
+	session := &WorkspaceUploadSession{
+		ID:        utils.TokenHex(16),
+		Path:      path,
+		Size:      size,
+		CreatedAt: time.Now(),
+	}
+
+	data, err := json.Marshal(session)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, session.ID+".part"), nil, 0o644); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, session.ID+".json"), data, 0o644); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// get returns the session with its current offset
+func (u *uploadSessions) get(ws *workspace.Workspace, id string) (*WorkspaceUploadSession, error) {
+	if !regexUploadID.MatchString(id) {
+		return nil, errUploadNotFound
+	}
+
+	dir := u.dir(ws)
+	data, err := os.ReadFile(filepath.Join(dir, id+".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, errUploadNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	var session WorkspaceUploadSession
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(filepath.Join(dir, id+".part"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, errUploadNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	session.Offset = info.Size()
+
+	return &session, nil
+}
+
+// acquire marks an upload busy, so that its chunks are written one at a time
+func (u *uploadSessions) acquire(id string) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.busy[id] {
+		return errUploadBusy
+	}
+	u.busy[id] = true
+	return nil
+}
+
+func (u *uploadSessions) release(id string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	delete(u.busy, id)
+}
+
+func (u *uploadSessions) remove(ws *workspace.Workspace, id string) {
+	dir := u.dir(ws)
+	for _, name := range []string{id + ".json", id + ".part"} {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			slog.Warn("remove upload", "id", id, "error", err)
+		}
+	}
+}
+
+// prune removes the uploads that were abandoned
+func (u *uploadSessions) prune(dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || time.Since(info.ModTime()) < uploadSessionTTL {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil {
+			slog.Warn("prune upload", "file", entry.Name(), "error", err)
+		}
+	}
+}
+
+// CreateUpload starts a resumable upload
+//
+//	@Summary		Start a resumable upload
+//	@Description	Start an upload that is sent in chunks, for large files or unreliable connections.
+//	@Description	Send the chunks in order to /v1/workspace/uploads/{id}, then complete the upload to sync the file.
+//	@Tags			Workspace
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		WorkspaceUploadSessionRequest	true	"Request body"
+//	@Success		201		{object}	WorkspaceUploadSession
+//	@Failure		400		{object}	ControlPlaneError
+//	@Failure		401		{object}	ControlPlaneError
+//	@Failure		429		{object}	ControlPlaneError
+//	@Failure		500		{object}	ControlPlaneError
+//	@Failure		503		{object}	ControlPlaneError
+//	@Router			/v1/workspace/uploads [post]
+func (h *WorkspaceHandler) CreateUpload(c *gin.Context) {
+	var req WorkspaceUploadSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.PureJSON(http.StatusBadRequest, &ControlPlaneError{
+			ErrorCode: ErrCodeBadRequest,
+			Error:     err.Error(),
+		})
+		return
+	}
+
+	ds, err := h.mgr.Get()
+	if err != nil {
+		c.PureJSON(http.StatusServiceUnavailable, &ControlPlaneError{
+			ErrorCode: ErrCodeDatasiteNotReady,
+			Error:     err.Error(),
+		})
+		return
+	}
+	ws := ds.GetWorkspace()
+
+	absPath, _, err := resolveUploadPath(ws, req.Path)
+	if err != nil {
+		c.PureJSON(http.StatusBadRequest, &ControlPlaneError{
+			ErrorCode: ErrCodeBadRequest,
+			Error:     err.Error(),
+		})
+		return
+	}
+
+	session, err := h.uploads.create(ws, req.Path, req.Size)
+	if err != nil {
+		c.PureJSON(http.StatusInternalServerError, &ControlPlaneError{
+			ErrorCode: ErrCodeUploadWorkspaceItemFailed,
+			Error:     err.Error(),
+		})
+		return
+	}
+
+	slog.Info("upload started", "id", session.ID, "path", absPath, "size", session.Size)
+	c.PureJSON(http.StatusCreated, session)
+}
+
+// GetUpload gets the state of a resumable upload
+//
+//	@Summary		Get a resumable upload
+//	@Description	Get the state of an upload. After a dropped connection, resume it from the returned offset.
+//	@Tags			Workspace
+//	@Produce		json
+//	@Param			id	path		string	true	"Upload ID"
+//	@Success		200	{object}	WorkspaceUploadSession
+//	@Failure		401	{object}	ControlPlaneError
+//	@Failure		404	{object}	ControlPlaneError
+//	@Failure		429	{object}	ControlPlaneError
+//	@Failure		500	{object}	ControlPlaneError
+//	@Failure		503	{object}	ControlPlaneError
+//	@Router			/v1/workspace/uploads/{id} [get]
+func (h *WorkspaceHandler) GetUpload(c *gin.Context) {
+	ds, err := h.mgr.Get()
+	if err != nil {
+		c.PureJSON(http.StatusServiceUnavailable, &ControlPlaneError{
+			ErrorCode: ErrCodeDatasiteNotReady,
+			Error:     err.Error(),
+		})
+		return
+	}
+
+	session, err := h.uploads.get(ds.GetWorkspace(), c.Param("id"))
+	if err != nil {
+		abortWithUploadError(c, err)
+		return
+	}
+
+	c.PureJSON(http.StatusOK, session)
+}
+
+// UploadChunk appends a chunk to a resumable upload
+//
+//	@Summary		Upload a chunk
+//	@Description	Append the request body to an upload. The offset must be the number of bytes received so far.
+//	@Description	If the connection drops, the bytes that arrived are kept. Get the upload to find where to resume.
+//	@Tags			Workspace
+//	@Accept			octet-stream
+//	@Produce		json
+//	@Param			id		path		string	true	"Upload ID"
+//	@Param			offset	query		integer	true	"Offset of the chunk in the file"	minimum(0)
+//	@Success		200		{object}	WorkspaceUploadSession
+//	@Failure		400		{object}	ControlPlaneError
+//	@Failure		401		{object}	ControlPlaneError
+//	@Failure		404		{object}	ControlPlaneError
+//	@Failure		409		{object}	ControlPlaneError
+//	@Failure		429		{object}	ControlPlaneError
+//	@Failure		500		{object}	ControlPlaneError
+//	@Failure		503		{object}	ControlPlaneError
+//	@Router			/v1/workspace/uploads/{id} [patch]
+func (h *WorkspaceHandler) UploadChunk(c *gin.Context) {
+	offset, err := strconv.ParseInt(c.Query("offset"), 10, 64)
+	if err != nil || offset < 0 {
+		c.PureJSON(http.StatusBadRequest, &ControlPlaneError{
+			ErrorCode: ErrCodeBadRequest,
+			Error:     "offset must be a non-negative integer",
+		})
+		return
+	}
+
+	ds, err := h.mgr.Get()
+	if err != nil {
+		c.PureJSON(http.StatusServiceUnavailable, &ControlPlaneError{
+			ErrorCode: ErrCodeDatasiteNotReady,
+			Error:     err.Error(),
+		})
+		return
+	}
+	ws := ds.GetWorkspace()
+
+	id := c.Param("id")
+	if err := h.uploads.acquire(id); err != nil {
+		abortWithUploadError(c, err)
+		return
+	}
+	defer h.uploads.release(id)
+
+	session, err := h.uploads.get(ws, id)
+	if err != nil {
+		abortWithUploadError(c, err)
+		return
+	}
+
+	if offset != session.Offset {
+		c.PureJSON(http.StatusConflict, &ControlPlaneError{
+			ErrorCode: ErrCodeUploadOffsetMismatch,
+			Error:     fmt.Sprintf("chunk offset %d does not match the received offset %d", offset, session.Offset),
+		})
+		return
+	}
+
+	partPath := filepath.Join(h.uploads.dir(ws), id+".part")
+	part, err := os.OpenFile(partPath, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		abortWithUploadError(c, err)
+		return
+	}
+	defer part.Close()
+
+	// read one byte past the remaining size to detect chunks that are too large
+	remaining := session.Size - session.Offset
+	written, err := io.Copy(part, io.LimitReader(c.Request.Body, remaining+1))
+	if written > remaining {
+		// drop the chunk, the upload stays at its previous offset
+		if err := part.Truncate(session.Offset); err != nil {
+			slog.Warn("upload truncate", "id", id, "error", err)
+		}
+		c.PureJSON(http.StatusBadRequest, &ControlPlaneError{
+			ErrorCode: ErrCodeBadRequest,
+			Error:     fmt.Sprintf("chunk exceeds the upload size of %d bytes", session.Size),
+		})
+		return
+	}
+	session.Offset += written
+
+	if err != nil {
+		// the received bytes are kept, the upload resumes from the new offset
+		slog.Warn("upload chunk interrupted", "id", id, "offset", session.Offset, "error", err)
+		c.PureJSON(http.StatusInternalServerError, &ControlPlaneError{
+			ErrorCode: ErrCodeUploadWorkspaceItemFailed,
+			Error:     fmt.Sprintf("chunk interrupted at offset %d: %v", session.Offset, err),
+		})
+		return
+	}
+
+	c.PureJSON(http.StatusOK, session)
+}
+
+// CompleteUpload assembles a resumable upload and syncs the file
+//
+//	@Summary		Complete a resumable upload
+//	@Description	Move a fully received upload into place and sync it right away. Progress is reported on the sync event stream.
+//	@Tags			Workspace
+//	@Produce		json
+//	@Param			id	path		string	true	"Upload ID"
+//	@Success		202	{object}	WorkspaceItem
+//	@Failure		400	{object}	ControlPlaneError
+//	@Failure		401	{object}	ControlPlaneError
+//	@Failure		403	{object}	ControlPlaneError
+//	@Failure		404	{object}	ControlPlaneError
+//	@Failure		409	{object}	ControlPlaneError
+//	@Failure		429	{object}	ControlPlaneError
+//	@Failure		500	{object}	ControlPlaneError
+//	@Failure		503	{object}	ControlPlaneError
+//	@Router			/v1/workspace/uploads/{id}/complete [post]
+func (h *WorkspaceHandler) CompleteUpload(c *gin.Context) {
+	ds, err := h.mgr.Get()
+	if err != nil {
+		c.PureJSON(http.StatusServiceUnavailable, &ControlPlaneError{
+			ErrorCode: ErrCodeDatasiteNotReady,
+			Error:     err.Error(),
+		})
+		return
+	}
+	ws := ds.GetWorkspace()
+
+	id := c.Param("id")
+	if err := h.uploads.acquire(id); err != nil {
+		abortWithUploadError(c, err)
+		return
+	}
+	defer h.uploads.release(id)
+
+	session, err := h.uploads.get(ws, id)
+	if err != nil {
+		abortWithUploadError(c, err)
+		return
+	}
+
+	if session.Offset != session.Size {
+		c.PureJSON(http.StatusConflict, &ControlPlaneError{
+			ErrorCode: ErrCodeUploadIncomplete,
+			Error:     fmt.Sprintf("received %d of %d bytes", session.Offset, session.Size),
+		})
+		return
+	}
+
+	absPath, syncRelPath, err := resolveUploadPath(ws, session.Path)
+	if err != nil {
+		c.PureJSON(http.StatusBadRequest, &ControlPlaneError{
+			ErrorCode: ErrCodeBadRequest,
+			Error:     err.Error(),
+		})
+		return
+	}
+
+	part, err := os.Open(filepath.Join(h.uploads.dir(ws), id+".part"))
+	if err != nil {
+		abortWithUploadError(c, err)
+		return
+	}
+	metadata, err := ds.GetSyncManager().Ingest(c.Request.Context(), syncpkg.SyncPath(syncRelPath), part, session.Size)
+	part.Close()
+	if err != nil {
+		abortWithIngestError(c, err)
+		return
+	}
+
+	h.uploads.remove(ws, id)
+	slog.Info("upload completed", "id", id, "path", absPath, "size", session.Size)
+
+	// The upload continues in the background. Follow its progress on /v1/sync/events
+	c.PureJSON(http.StatusAccepted, ingestedItem(ws, absPath, metadata))
+}
+
+// DeleteUpload cancels a resumable upload
+//
+//	@Summary		Cancel a resumable upload
+//	@Description	Cancel an upload and remove the content received so far
+//	@Tags			Workspace
+//	@Produce		json
+//	@Param			id	path	string	true	"Upload ID"
+//	@Success		204
+//	@Failure		401	{object}	ControlPlaneError
+//	@Failure		404	{object}	ControlPlaneError
+//	@Failure		409	{object}	ControlPlaneError
+//	@Failure		429	{object}	ControlPlaneError
+//	@Failure		503	{object}	ControlPlaneError
+//	@Router			/v1/workspace/uploads/{id} [delete]
+func (h *WorkspaceHandler) DeleteUpload(c *gin.Context) {
+	ds, err := h.mgr.Get()
+	if err != nil {
+		c.PureJSON(http.StatusServiceUnavailable, &ControlPlaneError{
+			ErrorCode: ErrCodeDatasiteNotReady,
+			Error:     err.Error(),
+		})
+		return
+	}
+	ws := ds.GetWorkspace()
+
+	id := c.Param("id")
+	if err := h.uploads.acquire(id); err != nil {
+		abortWithUploadError(c, err)
+		return
+	}
+	defer h.uploads.release(id)
+
+	if _, err := h.uploads.get(ws, id); err != nil {
+		abortWithUploadError(c, err)
+		return
+	}
+
+	h.uploads.remove(ws, id)
+	c.Status(http.StatusNoContent)
+}
+
+// resolveUploadPath resolves a workspace path of an upload. Only files in the datasites directory are synced
+func resolveUploadPath(ws *workspace.Workspace, path string) (string, string, error) {
+	if !strings.HasPrefix(path, "/") {
+		return "", "", errors.New("path must be an absolute path and start with /")
+	}
+
+	absPath := filepath.Join(ws.Root, path)
+	syncRelPath, err := ws.DatasiteRelPath(absPath)
+	if err != nil || syncRelPath == "." || strings.HasPrefix(syncRelPath, "..") {
+		return "", "", errors.New("path must be a file in the datasites directory")
+	}
+
+	if info, err := os.Stat(absPath); err == nil && info.IsDir() {
+		return "", "", errors.New("path points to a directory, not a file")
+	}
+
+	return absPath, syncRelPath, nil
+}
+
+// resolveUploadSource resolves the local source file of an upload.
+// The source must be in the workspace, after following symlinks, so that the control plane can't read any file of the host.
+func resolveUploadSource(ws *workspace.Workspace, source string) (string, int, error) {
+	if !filepath.IsAbs(source) {
+		return "", http.StatusBadRequest, errors.New("source must be an absolute path")
+	}
+
+	resolved, err := filepath.EvalSymlinks(source)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", http.StatusNotFound, errors.New("source file does not exist")
+		}
+		return "", http.StatusBadRequest, err
+	}
+
+	root, err := filepath.EvalSymlinks(ws.Root)
+	if err != nil {
+		return "", http.StatusInternalServerError, err
+	}
+
+	if rel, err := filepath.Rel(root, resolved); err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return "", http.StatusForbidden, errors.New("source must be a file in the workspace")
+	}
+
+	return resolved, http.StatusOK, nil
+}
+
+// ingestedItem is the workspace item of a file that was handed to the sync
+func ingestedItem(ws *workspace.Workspace, absPath string, metadata *syncpkg.FileMetadata) *WorkspaceItem {
+	relPath := absPath
+	if rel, err := filepath.Rel(ws.Root, absPath); err == nil {
+		relPath = filepath.Join("/", filepath.ToSlash(rel))
+	}
+
+	return &WorkspaceItem{
+		Id:           relPath,
+		Name:         filepath.Base(absPath),
+		Type:         WorkspaceItemTypeFile,
+		Path:         relPath,
+		AbsolutePath: absPath,
+		CreatedAt:    metadata.LastModified,
+		ModifiedAt:   metadata.LastModified,
+		Size:         metadata.Size,
+		SyncStatus:   SyncStatusSyncing,
+		Permissions:  itemPermissions(ws.DatasitesDir, absPath, false),
+		Children:     []WorkspaceItem{},
+	}
+}
+
+func abortWithIngestError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	code := ErrCodeUploadWorkspaceItemFailed
+	if errors.Is(err, syncpkg.ErrInvalidIngestPath) {
+		status = http.StatusBadRequest
+		code = ErrCodeBadRequest
+	} else if errors.Is(err, syncpkg.ErrIngestInProgress) {
+		status = http.StatusConflict
+	} else if errors.Is(err, syncpkg.ErrReadOnly) {
+		status = http.StatusForbidden
+	}
+	c.PureJSON(status, &ControlPlaneError{
+		ErrorCode: code,
+		Error:     err.Error(),
+	})
+}
+
+func abortWithUploadError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	code := ErrCodeUploadWorkspaceItemFailed
+	if errors.Is(err, errUploadNotFound) {
+		status = http.StatusNotFound
+		code = ErrCodeUploadNotFound
+	} else if errors.Is(err, errUploadBusy) {
+		status = http.StatusConflict
+	}
+	c.PureJSON(status, &ControlPlaneError{
+		ErrorCode: code,
+		Error:     err.Error(),
+	})
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+	mapset "github.com/deckarep/golang-set/v2"
+	"github.com/openmined/syftbox/internal/aclspec"
+	"github.com/openmined/syftbox/internal/utils"
+)
+
+// permissionResolver resolves the permissions of datasite items from the nearest syft.pub.yaml,
+// the way the server does. Rulesets are loaded once per directory, so a listing reads each ACL file once.
+type permissionResolver struct {
+	datasitesDir string
+	rulesets     map[string]*aclspec.RuleSet // nearest ruleset of a dir, nil when there is none
+}
+
+func newPermissionResolver(datasitesDir string) *permissionResolver {
+	return &permissionResolver{
+		datasitesDir: datasitesDir,
+		rulesets:     make(map[string]*aclspec.RuleSet),
+	}
+}
+
+// itemPermissions resolves the permissions of a single item
+func itemPermissions(datasitesDir string, absPath string, isDir bool) []Permission {
+	return newPermissionResolver(datasitesDir).permissions(absPath, isDir)
+}
+
+// permissions returns who can access an item: the datasite owner, then the principals of the first matching rule.
+// Items outside the datasites dir have no permissions.
+func (r *permissionResolver) permissions(absPath string, isDir bool) []Permission {
+	relPath, err := filepath.Rel(r.datasitesDir, absPath)
+	if err != nil || relPath == "." || strings.HasPrefix(relPath, "..") {
+		return []Permission{}
+	}
+	relPath = filepath.ToSlash(relPath)
+
+	// the owner of a datasite can do anything in it
+	owner, _, _ := strings.Cut(relPath, "/")
+	perms := []Permission{newPermission(owner, "admin")}
+
+	// a dir may hold the ruleset that applies to itself
+	dir := relPath
+	if !isDir {
+		dir = path.Dir(relPath)
+	}
+
+	access := r.match(r.ruleset(dir), relPath)
+	if access == nil {
+		return perms
+	}
+
+	for _, level := range []struct {
+		principals mapset.Set[string]
+		accessType string
+	}{
+		{access.Admin, "admin"},
+		{access.Write, "write"},
+		{access.Read, "read"},
+	} {
+		if level.principals == nil {
+			continue
+		}
+		principals := level.principals.ToSlice()
+		slices.Sort(principals)
+		for _, principal := range principals {
+			if principal == owner {
+				continue
+			}
+			perms = append(perms, newPermission(principal, level.accessType))
+		}
+	}
+
+	return perms
+}
+
+// ruleset returns the nearest ruleset of a dir relative to the datasites dir
+func (r *permissionResolver) ruleset(dir string) *aclspec.RuleSet {
+	if ruleset, ok := r.rulesets[dir]; ok {
+		return ruleset
+	}
+
+	var ruleset *aclspec.RuleSet
+	if parent := path.Dir(dir); parent != "." {
+		ruleset = r.ruleset(parent)
+	}
+
+	// a terminal ruleset applies to everything below it
+	if ruleset == nil || !ruleset.Terminal {
+		if loaded, err := aclspec.LoadFromFile(filepath.Join(r.datasitesDir, filepath.FromSlash(dir))); err == nil {
+			loaded.Path = dir
+			loaded.Rules = aclspec.SortRulesBySpecificity(loaded.Rules)
+			ruleset = loaded
+		}
+	}
+
+	r.rulesets[dir] = ruleset
+	return ruleset
+}
+
+// match returns the access of the most specific rule matching the path, nil if none does
+func (r *permissionResolver) match(ruleset *aclspec.RuleSet, relPath string) *aclspec.Access {
+	if ruleset == nil {
+		return nil
+	}
+
+	for _, rule := range ruleset.Rules {
+		// resolved per user, they don't say who has access
+		if aclspec.IsTemplatePattern(rule.Pattern) {
+			continue
+		}
+		if ok, err := doublestar.Match(ruleset.Path+"/"+rule.Pattern, relPath); err == nil && ok {
+			return rule.Access
+		}
+	}
+
+	return nil
+}
+
+func newPermission(principal string, accessType string) Permission {
+	perm := Permission{
+		ID:     accessType + ":" + principal,
+		UserID: principal,
+		Name:   principal,
+		Type:   accessType,
+	}
+	if utils.IsValidEmail(principal) {
+		perm.Email = principal
+	}
+	return perm
+}
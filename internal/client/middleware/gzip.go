@@ -8,6 +8,7 @@ import (
 var (
 	excludedPaths = []string{
 		"/health",
+		"/v1/sync/events", // server-sent events must be flushed as they are written
 	}
 	excludedExtensions = []string{
 		".png", ".gif", ".jpeg", ".jpg", ".mp4", ".mov", ".mp3", ".wav", ".pdf", ".zip", ".tar.gz",
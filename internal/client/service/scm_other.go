@@ -0,0 +1,10 @@
+//go:build !windows
+
+package service
+
+import "context"
+
+// Run runs fn directly. Only windows requires the process to talk to its service manager.
+func Run(ctx context.Context, name string, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
@@ -0,0 +1,70 @@
+//go:build windows
+
+package service
+
+import (
+	"context"
+	"log/slog"
+
+	"golang.org/x/sys/windows/svc"
+)
+
+// Run runs fn under the windows service control manager when the process was started as a service.
+// The context passed to fn is cancelled when the service is asked to stop.
+// Otherwise fn is run directly.
+func Run(ctx context.Context, name string, fn func(ctx context.Context) error) error {
+	isService, err := svc.IsWindowsService()
+	if err != nil || !isService {
+		return fn(ctx)
+	}
+
+	h := &scmHandler{ctx: ctx, fn: fn}
+	if err := svc.Run(name, h); err != nil {
+		return err
+	}
+	return h.err
+}
+
+type scmHandler struct {
+	ctx context.Context
+	fn  func(ctx context.Context) error
+	err error
+}
+
+func (h *scmHandler) Execute(args []string, req <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	const accepts = svc.AcceptStop | svc.AcceptShutdown
+
+	status <- svc.Status{State: svc.StartPending}
+
+	ctx, cancel := context.WithCancel(h.ctx)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- h.fn(ctx)
+	}()
+
+	status <- svc.Status{State: svc.Running, Accepts: accepts}
+
+	for {
+		select {
+		case h.err = <-done:
+			status <- svc.Status{State: svc.StopPending}
+			// non-zero exit code lets the recovery actions restart the service
+			if h.err != nil {
+				return false, 1
+			}
+			return false, 0
+
+		case c := <-req:
+			switch c.Cmd {
+			case svc.Interrogate:
+				status <- c.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				slog.Info("service stop requested")
+				status <- svc.Status{State: svc.StopPending}
+				cancel()
+			}
+		}
+	}
+}
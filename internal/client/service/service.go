@@ -0,0 +1,173 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+const (
+	DefaultName        = "syftbox"
+	DefaultLabel       = "net.syftbox.client"
+	DefaultDisplayName = "SyftBox Client"
+	DefaultDescription = "SyftBox client daemon"
+)
+
+var (
+	ErrUnsupportedPlatform = errors.New("service management is not supported on this platform")
+	ErrNotInstalled        = errors.New("service is not installed")
+	ErrAlreadyInstalled    = errors.New("service is already installed")
+)
+
+// Status is the state of the managed service as reported by the platform's service manager
+type Status string
+
+const (
+	StatusRunning      Status = "running"
+	StatusStopped      Status = "stopped"
+	StatusNotInstalled Status = "not installed"
+	StatusUnknown      Status = "unknown"
+)
+
+// Config describes the service to be generated
+type Config struct {
+	Name        string // Name of the systemd unit / windows service
+	Label       string // Label of the launchd agent
+	DisplayName string // Human readable name
+	Description string // Human readable description
+	Executable  string // Absolute path to the syftbox binary
+	ConfigPath  string // Absolute path to the syftbox config file
+	Profile     string // Profile of the config file to run, empty for the top level config
+	LogDir      string // Directory where the service manager writes stdout/stderr (launchd only)
+	HomeDir     string // Home directory of the user installing the service
+}
+
+// Args returns the arguments passed to the executable by the service manager
+func (c *Config) Args() []string {
+	if c.Profile != "" {
+		return []string{"--config", c.ConfigPath, "--profile", c.Profile}
+	}
+	return []string{"--config", c.ConfigPath}
+}
+
+func (c *Config) Validate() error {
+	if c.Name == "" {
+		c.Name = DefaultName
+	}
+	if c.Label == "" {
+		c.Label = DefaultLabel
+	}
+	if c.DisplayName == "" {
+		c.DisplayName = DefaultDisplayName
+	}
+	if c.Description == "" {
+		c.Description = DefaultDescription
+	}
+	if c.HomeDir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return fmt.Errorf("home dir: %w", err)
+		}
+		c.HomeDir = home
+	}
+	if c.LogDir == "" {
+		c.LogDir = filepath.Join(c.HomeDir, ".syftbox", "logs")
+	}
+	if c.Executable == "" {
+		return fmt.Errorf("executable path required")
+	}
+	if !filepath.IsAbs(c.Executable) {
+		return fmt.Errorf("executable path must be absolute: %q", c.Executable)
+	}
+	if c.ConfigPath == "" {
+		return fmt.Errorf("config path required")
+	}
+	if !filepath.IsAbs(c.ConfigPath) {
+		return fmt.Errorf("config path must be absolute: %q", c.ConfigPath)
+	}
+	return nil
+}
+
+// Manager installs and controls the syftbox daemon using the platform's service manager
+type Manager interface {
+	// Platform returns the name of the underlying service manager
+	Platform() string
+	// Path returns the location of the generated service definition
+	Path() string
+	// Definition returns the generated service definition
+	Definition() (string, error)
+	Install() error
+	Uninstall() error
+	Start() error
+	Stop() error
+	Status() (Status, error)
+}
+
+// runner executes a command and returns its combined output
+type runner func(name string, args ...string) ([]byte, error)
+
+// New returns the service manager for the current platform
+func New(cfg *Config) (Manager, error) {
+	return newForOS(runtime.GOOS, cfg, execRunner)
+}
+
+func newForOS(goos string, cfg *Config, run runner) (Manager, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	switch goos {
+	case "linux":
+		return &systemdManager{cfg: cfg, run: run}, nil
+	case "darwin":
+		return &launchdManager{cfg: cfg, run: run}, nil
+	case "windows":
+		return &scManager{cfg: cfg, run: run}, nil
+	default:
+		return nil, ErrUnsupportedPlatform
+	}
+}
+
+func execRunner(name string, args ...string) ([]byte, error) {
+	var out bytes.Buffer
+	cmd := exec.Command(name, args...)
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Run(); err != nil {
+		return out.Bytes(), fmt.Errorf("%s %s: %q: %w", name, strings.Join(args, " "), strings.TrimSpace(out.String()), err)
+	}
+	return out.Bytes(), nil
+}
+
+// writeDefinition writes the service definition to path, failing if it already exists
+func writeDefinition(path string, contents string) error {
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("%w: %s", ErrAlreadyInstalled, path)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create service dir: %w", err)
+	}
+
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		return fmt.Errorf("write service file: %w", err)
+	}
+
+	return nil
+}
+
+// removeDefinition removes the service definition at path
+func removeDefinition(path string) error {
+	if err := os.Remove(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return ErrNotInstalled
+		}
+		return fmt.Errorf("remove service file: %w", err)
+	}
+	return nil
+}
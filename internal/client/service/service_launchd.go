@@ -0,0 +1,131 @@
+package service
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// launchdManager manages the daemon as a launchd user agent
+type launchdManager struct {
+	cfg *Config
+	run runner
+}
+
+func (m *launchdManager) Platform() string {
+	return "launchd"
+}
+
+func (m *launchdManager) Path() string {
+	return filepath.Join(m.cfg.HomeDir, "Library", "LaunchAgents", m.cfg.Label+".plist")
+}
+
+func (m *launchdManager) Definition() (string, error) {
+	return launchdPlist(m.cfg), nil
+}
+
+func (m *launchdManager) Install() error {
+	if err := os.MkdirAll(m.cfg.LogDir, 0o755); err != nil {
+		return fmt.Errorf("create log dir: %w", err)
+	}
+
+	if err := writeDefinition(m.Path(), launchdPlist(m.cfg)); err != nil {
+		return err
+	}
+
+	// RunAtLoad starts the agent as soon as it is loaded
+	_, err := m.run("launchctl", "load", "-w", m.Path())
+	return err
+}
+
+func (m *launchdManager) Uninstall() error {
+	if !m.installed() {
+		return ErrNotInstalled
+	}
+
+	// best effort. the agent may not be loaded
+	_, _ = m.run("launchctl", "unload", "-w", m.Path())
+
+	return removeDefinition(m.Path())
+}
+
+func (m *launchdManager) Start() error {
+	if !m.installed() {
+		return ErrNotInstalled
+	}
+	_, err := m.run("launchctl", "start", m.cfg.Label)
+	return err
+}
+
+func (m *launchdManager) Stop() error {
+	if !m.installed() {
+		return ErrNotInstalled
+	}
+	_, err := m.run("launchctl", "stop", m.cfg.Label)
+	return err
+}
+
+func (m *launchdManager) Status() (Status, error) {
+	if !m.installed() {
+		return StatusNotInstalled, nil
+	}
+
+	out, err := m.run("launchctl", "list", m.cfg.Label)
+	if err != nil {
+		// not loaded
+		return StatusStopped, nil
+	}
+
+	// a running agent reports its PID as `"PID" = 1234;`
+	if strings.Contains(string(out), `"PID"`) {
+		return StatusRunning, nil
+	}
+	return StatusStopped, nil
+}
+
+func (m *launchdManager) installed() bool {
+	_, err := os.Stat(m.Path())
+	return err == nil
+}
+
+// launchdPlist generates the contents of a launchd agent plist for the daemon
+func launchdPlist(cfg *Config) string {
+	var sb strings.Builder
+	sb.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	sb.WriteString(`<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">` + "\n")
+	sb.WriteString(`<plist version="1.0">` + "\n")
+	sb.WriteString("<dict>\n")
+	sb.WriteString("\t<key>Label</key>\n")
+	sb.WriteString(fmt.Sprintf("\t<string>%s</string>\n", xmlEscape(cfg.Label)))
+	sb.WriteString("\t<key>ProgramArguments</key>\n")
+	sb.WriteString("\t<array>\n")
+	sb.WriteString(fmt.Sprintf("\t\t<string>%s</string>\n", xmlEscape(cfg.Executable)))
+	for _, arg := range cfg.Args() {
+		sb.WriteString(fmt.Sprintf("\t\t<string>%s</string>\n", xmlEscape(arg)))
+	}
+	sb.WriteString("\t</array>\n")
+	sb.WriteString("\t<key>RunAtLoad</key>\n")
+	sb.WriteString("\t<true/>\n")
+	// restart on crash, but not when stopped cleanly
+	sb.WriteString("\t<key>KeepAlive</key>\n")
+	sb.WriteString("\t<dict>\n")
+	sb.WriteString("\t\t<key>SuccessfulExit</key>\n")
+	sb.WriteString("\t\t<false/>\n")
+	sb.WriteString("\t</dict>\n")
+	sb.WriteString("\t<key>StandardOutPath</key>\n")
+	sb.WriteString(fmt.Sprintf("\t<string>%s</string>\n", xmlEscape(filepath.Join(cfg.LogDir, cfg.Name+".service.log"))))
+	sb.WriteString("\t<key>StandardErrorPath</key>\n")
+	sb.WriteString(fmt.Sprintf("\t<string>%s</string>\n", xmlEscape(filepath.Join(cfg.LogDir, cfg.Name+".service.log"))))
+	sb.WriteString("</dict>\n")
+	sb.WriteString("</plist>\n")
+	return sb.String()
+}
+
+func xmlEscape(s string) string {
+	var buf bytes.Buffer
+	_ = xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"strings"
+)
+
+// scManager manages the daemon as a windows service using sc.exe
+type scManager struct {
+	cfg *Config
+	run runner
+}
+
+func (m *scManager) Platform() string {
+	return "windows"
+}
+
+func (m *scManager) Path() string {
+	return `HKLM\SYSTEM\CurrentControlSet\Services\` + m.cfg.Name
+}
+
+func (m *scManager) Definition() (string, error) {
+	return scCommandLine(m.cfg), nil
+}
+
+func (m *scManager) Install() error {
+	if m.installed() {
+		return ErrAlreadyInstalled
+	}
+
+	if _, err := m.run("sc.exe", scCreateArgs(m.cfg)...); err != nil {
+		return err
+	}
+
+	if _, err := m.run("sc.exe", "description", m.cfg.Name, m.cfg.Description); err != nil {
+		return err
+	}
+
+	// restart on crash
+	_, err := m.run("sc.exe", scFailureArgs(m.cfg)...)
+	return err
+}
+
+func (m *scManager) Uninstall() error {
+	if !m.installed() {
+		return ErrNotInstalled
+	}
+
+	// best effort. the service may not be running
+	_, _ = m.run("sc.exe", "stop", m.cfg.Name)
+
+	_, err := m.run("sc.exe", "delete", m.cfg.Name)
+	return err
+}
+
+func (m *scManager) Start() error {
+	if !m.installed() {
+		return ErrNotInstalled
+	}
+	_, err := m.run("sc.exe", "start", m.cfg.Name)
+	return err
+}
+
+func (m *scManager) Stop() error {
+	if !m.installed() {
+		return ErrNotInstalled
+	}
+	_, err := m.run("sc.exe", "stop", m.cfg.Name)
+	return err
+}
+
+func (m *scManager) Status() (Status, error) {
+	out, err := m.run("sc.exe", "query", m.cfg.Name)
+	if err != nil {
+		return StatusNotInstalled, nil
+	}
+
+	state := string(out)
+	switch {
+	case strings.Contains(state, "RUNNING"), strings.Contains(state, "START_PENDING"):
+		return StatusRunning, nil
+	case strings.Contains(state, "STOPPED"), strings.Contains(state, "STOP_PENDING"):
+		return StatusStopped, nil
+	default:
+		return StatusUnknown, nil
+	}
+}
+
+func (m *scManager) installed() bool {
+	_, err := m.run("sc.exe", "query", m.cfg.Name)
+	return err == nil
+}
+
+// scCommandLine generates the command line registered as the service's binPath
+func scCommandLine(cfg *Config) string {
+	parts := make([]string, 0, len(cfg.Args())+1)
+	parts = append(parts, scQuote(cfg.Executable))
+	for _, arg := range cfg.Args() {
+		parts = append(parts, scQuote(arg))
+	}
+	return strings.Join(parts, " ")
+}
+
+// scCreateArgs generates the sc.exe arguments that register the service
+func scCreateArgs(cfg *Config) []string {
+	return []string{
+		"create", cfg.Name,
+		"binPath=", scCommandLine(cfg),
+		"start=", "auto",
+		"DisplayName=", cfg.DisplayName,
+	}
+}
+
+// scFailureArgs generates the sc.exe arguments that restart the service on crash
+func scFailureArgs(cfg *Config) []string {
+	return []string{
+		"failure", cfg.Name,
+		"reset=", "86400",
+		"actions=", "restart/5000/restart/5000/restart/5000",
+	}
+}
+
+func scQuote(s string) string {
+	if !strings.ContainsAny(s, " \t\"") {
+		return s
+	}
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// systemdManager manages the daemon as a systemd user unit
+type systemdManager struct {
+	cfg *Config
+	run runner
+}
+
+func (m *systemdManager) Platform() string {
+	return "systemd"
+}
+
+func (m *systemdManager) Path() string {
+	return filepath.Join(m.cfg.HomeDir, ".config", "systemd", "user", m.unitName())
+}
+
+func (m *systemdManager) Definition() (string, error) {
+	return systemdUnit(m.cfg), nil
+}
+
+func (m *systemdManager) Install() error {
+	if err := writeDefinition(m.Path(), systemdUnit(m.cfg)); err != nil {
+		return err
+	}
+
+	if _, err := m.systemctl("daemon-reload"); err != nil {
+		return err
+	}
+
+	if _, err := m.systemctl("enable", m.unitName()); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *systemdManager) Uninstall() error {
+	if !m.installed() {
+		return ErrNotInstalled
+	}
+
+	// best effort. the unit may not be running or enabled
+	_, _ = m.systemctl("disable", "--now", m.unitName())
+
+	if err := removeDefinition(m.Path()); err != nil {
+		return err
+	}
+
+	_, err := m.systemctl("daemon-reload")
+	return err
+}
+
+func (m *systemdManager) Start() error {
+	if !m.installed() {
+		return ErrNotInstalled
+	}
+	_, err := m.systemctl("start", m.unitName())
+	return err
+}
+
+func (m *systemdManager) Stop() error {
+	if !m.installed() {
+		return ErrNotInstalled
+	}
+	_, err := m.systemctl("stop", m.unitName())
+	return err
+}
+
+func (m *systemdManager) Status() (Status, error) {
+	if !m.installed() {
+		return StatusNotInstalled, nil
+	}
+
+	// is-active exits non-zero for anything other than "active", so only look at the output
+	out, _ := m.systemctl("is-active", m.unitName())
+	switch strings.TrimSpace(string(out)) {
+	case "active", "activating", "reloading":
+		return StatusRunning, nil
+	case "inactive", "failed", "deactivating":
+		return StatusStopped, nil
+	default:
+		return StatusUnknown, nil
+	}
+}
+
+func (m *systemdManager) unitName() string {
+	return m.cfg.Name + ".service"
+}
+
+func (m *systemdManager) installed() bool {
+	_, err := os.Stat(m.Path())
+	return err == nil
+}
+
+func (m *systemdManager) systemctl(args ...string) ([]byte, error) {
+	return m.run("systemctl", append([]string{"--user"}, args...)...)
+}
+
+// systemdUnit generates the contents of a systemd user unit for the daemon
+func systemdUnit(cfg *Config) string {
+	execStart := make([]string, 0, len(cfg.Args())+1)
+	execStart = append(execStart, systemdQuote(cfg.Executable))
+	for _, arg := range cfg.Args() {
+		execStart = append(execStart, systemdQuote(arg))
+	}
+
+	var sb strings.Builder
+	sb.WriteString("[Unit]\n")
+	sb.WriteString(fmt.Sprintf("Description=%s\n", cfg.DisplayName))
+	sb.WriteString("After=network-online.target\n")
+	sb.WriteString("Wants=network-online.target\n")
+	sb.WriteString("\n")
+	sb.WriteString("[Service]\n")
+	sb.WriteString("Type=simple\n")
+	sb.WriteString(fmt.Sprintf("ExecStart=%s\n", strings.Join(execStart, " ")))
+	sb.WriteString("Restart=on-failure\n")
+	sb.WriteString("RestartSec=5\n")
+	sb.WriteString("\n")
+	sb.WriteString("[Install]\n")
+	sb.WriteString("WantedBy=default.target\n")
+	return sb.String()
+}
+
+// systemdQuote quotes a single ExecStart argument, escaping systemd specifiers
+func systemdQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "%", "%%")
+	return `"` + s + `"`
+}
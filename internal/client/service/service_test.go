@@ -0,0 +1,228 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testConfig(t *testing.T) *Config {
+	t.Helper()
+	home := t.TempDir()
+	cfg := &Config{
+		Executable: filepath.Join(home, "bin", "syftbox"),
+		ConfigPath: filepath.Join(home, ".syftbox", "config.json"),
+		HomeDir:    home,
+	}
+	require.NoError(t, cfg.Validate())
+	return cfg
+}
+
+type recordedRunner struct {
+	calls []string
+	out   map[string]string
+	fail  map[string]bool
+}
+
+func (r *recordedRunner) run(name string, args ...string) ([]byte, error) {
+	call := strings.TrimSpace(name + " " + strings.Join(args, " "))
+	r.calls = append(r.calls, call)
+	if r.fail[call] {
+		return []byte(r.out[call]), assert.AnError
+	}
+	return []byte(r.out[call]), nil
+}
+
+func TestConfigValidate(t *testing.T) {
+	cfg := &Config{HomeDir: "/home/test"}
+	assert.Error(t, cfg.Validate(), "executable required")
+
+	cfg.Executable = "syftbox"
+	cfg.ConfigPath = "/home/test/.syftbox/config.json"
+	assert.Error(t, cfg.Validate(), "executable must be absolute")
+
+	cfg.Executable = "/usr/local/bin/syftbox"
+	cfg.ConfigPath = "config.json"
+	assert.Error(t, cfg.Validate(), "config must be absolute")
+
+	cfg.ConfigPath = "/home/test/.syftbox/config.json"
+	require.NoError(t, cfg.Validate())
+	assert.Equal(t, DefaultName, cfg.Name)
+	assert.Equal(t, DefaultLabel, cfg.Label)
+	assert.Equal(t, filepath.Join("/home/test", ".syftbox", "logs"), cfg.LogDir)
+}
+
+func TestNewForOS(t *testing.T) {
+	cfg := testConfig(t)
+	r := &recordedRunner{}
+
+	m, err := newForOS("linux", cfg, r.run)
+	require.NoError(t, err)
+	assert.Equal(t, "systemd", m.Platform())
+
+	m, err = newForOS("darwin", cfg, r.run)
+	require.NoError(t, err)
+	assert.Equal(t, "launchd", m.Platform())
+
+	m, err = newForOS("windows", cfg, r.run)
+	require.NoError(t, err)
+	assert.Equal(t, "windows", m.Platform())
+
+	_, err = newForOS("plan9", cfg, r.run)
+	assert.ErrorIs(t, err, ErrUnsupportedPlatform)
+}
+
+func TestSystemdUnit(t *testing.T) {
+	cfg := &Config{
+		Executable: "/opt/syft box/syftbox",
+		ConfigPath: "/home/test/.syftbox/100%.json",
+		HomeDir:    "/home/test",
+	}
+	require.NoError(t, cfg.Validate())
+
+	unit := systemdUnit(cfg)
+	assert.Contains(t, unit, "Description=SyftBox Client\n")
+	assert.Contains(t, unit, `ExecStart="/opt/syft box/syftbox" "--config" "/home/test/.syftbox/100%%.json"`+"\n")
+	assert.Contains(t, unit, "Restart=on-failure\n")
+	assert.Contains(t, unit, "WantedBy=default.target\n")
+}
+
+func TestSystemdInstall(t *testing.T) {
+	cfg := testConfig(t)
+	r := &recordedRunner{}
+	m := &systemdManager{cfg: cfg, run: r.run}
+
+	require.NoError(t, m.Install())
+	assert.Equal(t, filepath.Join(cfg.HomeDir, ".config", "systemd", "user", "syftbox.service"), m.Path())
+
+	data, err := os.ReadFile(m.Path())
+	require.NoError(t, err)
+	assert.Equal(t, systemdUnit(cfg), string(data))
+	assert.Equal(t, []string{
+		"systemctl --user daemon-reload",
+		"systemctl --user enable syftbox.service",
+	}, r.calls)
+
+	// second install must not overwrite
+	assert.ErrorIs(t, m.Install(), ErrAlreadyInstalled)
+
+	r.calls = nil
+	require.NoError(t, m.Uninstall())
+	assert.NoFileExists(t, m.Path())
+	assert.Equal(t, []string{
+		"systemctl --user disable --now syftbox.service",
+		"systemctl --user daemon-reload",
+	}, r.calls)
+
+	assert.ErrorIs(t, m.Uninstall(), ErrNotInstalled)
+	assert.ErrorIs(t, m.Start(), ErrNotInstalled)
+}
+
+func TestSystemdStatus(t *testing.T) {
+	cfg := testConfig(t)
+	r := &recordedRunner{
+		out: map[string]string{"systemctl --user is-active syftbox.service": "active\n"},
+	}
+	m := &systemdManager{cfg: cfg, run: r.run}
+
+	status, err := m.Status()
+	require.NoError(t, err)
+	assert.Equal(t, StatusNotInstalled, status)
+
+	require.NoError(t, m.Install())
+	status, err = m.Status()
+	require.NoError(t, err)
+	assert.Equal(t, StatusRunning, status)
+
+	r.out["systemctl --user is-active syftbox.service"] = "inactive\n"
+	r.fail = map[string]bool{"systemctl --user is-active syftbox.service": true}
+	status, err = m.Status()
+	require.NoError(t, err)
+	assert.Equal(t, StatusStopped, status)
+}
+
+func TestLaunchdPlist(t *testing.T) {
+	cfg := &Config{
+		Executable: "/Applications/SyftBox.app/Contents/MacOS/syftbox",
+		ConfigPath: "/Users/test/R&D/config.json",
+		HomeDir:    "/Users/test",
+	}
+	require.NoError(t, cfg.Validate())
+
+	plist := launchdPlist(cfg)
+	assert.True(t, strings.HasPrefix(plist, `<?xml version="1.0" encoding="UTF-8"?>`))
+	assert.Contains(t, plist, "<key>Label</key>\n\t<string>net.syftbox.client</string>")
+	assert.Contains(t, plist, "\t\t<string>/Applications/SyftBox.app/Contents/MacOS/syftbox</string>\n\t\t<string>--config</string>\n\t\t<string>/Users/test/R&amp;D/config.json</string>\n")
+	assert.Contains(t, plist, "<key>RunAtLoad</key>\n\t<true/>")
+	assert.Contains(t, plist, "<key>SuccessfulExit</key>\n\t\t<false/>")
+	assert.Contains(t, plist, "<string>/Users/test/.syftbox/logs/syftbox.service.log</string>")
+}
+
+func TestLaunchdInstall(t *testing.T) {
+	cfg := testConfig(t)
+	r := &recordedRunner{}
+	m := &launchdManager{cfg: cfg, run: r.run}
+
+	require.NoError(t, m.Install())
+	assert.Equal(t, filepath.Join(cfg.HomeDir, "Library", "LaunchAgents", "net.syftbox.client.plist"), m.Path())
+	assert.FileExists(t, m.Path())
+	assert.Equal(t, []string{"launchctl load -w " + m.Path()}, r.calls)
+
+	r.calls = nil
+	require.NoError(t, m.Stop())
+	require.NoError(t, m.Start())
+	assert.Equal(t, []string{
+		"launchctl stop net.syftbox.client",
+		"launchctl start net.syftbox.client",
+	}, r.calls)
+
+	require.NoError(t, m.Uninstall())
+	assert.NoFileExists(t, m.Path())
+}
+
+func TestSCCommandLine(t *testing.T) {
+	cfg := &Config{
+		Executable: `C:\Program Files\SyftBox\syftbox.exe`,
+		ConfigPath: `C:\Users\test\.syftbox\config.json`,
+		HomeDir:    `C:\Users\test`,
+	}
+	// filepath.IsAbs does not recognize windows paths on other platforms
+	cfg.Name = DefaultName
+	cfg.DisplayName = DefaultDisplayName
+
+	assert.Equal(t, `"C:\Program Files\SyftBox\syftbox.exe" --config C:\Users\test\.syftbox\config.json`, scCommandLine(cfg))
+	assert.Equal(t, []string{
+		"create", "syftbox",
+		"binPath=", `"C:\Program Files\SyftBox\syftbox.exe" --config C:\Users\test\.syftbox\config.json`,
+		"start=", "auto",
+		"DisplayName=", "SyftBox Client",
+	}, scCreateArgs(cfg))
+	assert.Equal(t, []string{
+		"failure", "syftbox",
+		"reset=", "86400",
+		"actions=", "restart/5000/restart/5000/restart/5000",
+	}, scFailureArgs(cfg))
+}
+
+func TestSCStatus(t *testing.T) {
+	cfg := testConfig(t)
+	r := &recordedRunner{
+		out:  map[string]string{"sc.exe query syftbox": "STATE              : 4  RUNNING"},
+		fail: map[string]bool{},
+	}
+	m := &scManager{cfg: cfg, run: r.run}
+
+	status, err := m.Status()
+	require.NoError(t, err)
+	assert.Equal(t, StatusRunning, status)
+
+	r.fail["sc.exe query syftbox"] = true
+	status, err = m.Status()
+	require.NoError(t, err)
+	assert.Equal(t, StatusNotInstalled, status)
+	assert.ErrorIs(t, m.Start(), ErrNotInstalled)
+}
@@ -6,12 +6,14 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
-	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/dustin/go-humanize"
 	"github.com/openmined/syftbox/internal/client/workspace"
+	"github.com/openmined/syftbox/internal/crdt"
+	"github.com/openmined/syftbox/internal/fileattr"
 	"github.com/openmined/syftbox/internal/syftmsg"
 	"github.com/openmined/syftbox/internal/syftsdk"
 	"github.com/openmined/syftbox/internal/utils"
@@ -19,10 +21,11 @@ import (
 )
 
 const (
-	minFreeSpace     = 5 * 1024 * 1024 * 1024 // 5GB
-	fullSyncInterval = 5 * time.Second        // 5 seconds
-	maxRetryCount    = 3
-	syncDbName       = "sync.db"
+	MinFreeSpace      = 5 * 1024 * 1024 * 1024 // 5GB
+	fullSyncInterval  = 5 * time.Second        // 5 seconds
+	maxRetryCount     = 3
+	syncDbName        = "sync.db"
+	forceAbortTimeout = 5 * time.Second // wait for aborted operations to unwind, once the drain timed out
 )
 
 var (
@@ -35,12 +38,55 @@ type SyncEngine struct {
 	journal      *SyncJournal
 	localState   *SyncLocalState
 	syncStatus   *SyncStatus
+	latency      *SyncLatency
 	watcher      *FileWatcher
 	ignoreList   *SyncIgnoreList
 	priorityList *SyncPriorityList
+	fileAttrs    *fileattr.Options
+	transfer     *TransferOptions
+	export       *SyncExport // nil unless a plain copy of the synced files is kept
+	resumeResync bool        // reconnect and resync when the system resumes from sleep
+	resuming     atomic.Bool // resumed, and the resync has not completed yet
+	keepRejected bool        // leave files the server rejects in place, instead of moving them aside
+	readOnly     bool        // never upload, only download the remote changes
+	requeue      int         // uploads again of a file that changed during its upload, see maxUploadRequeue
+	withheld     int         // local changes the read-only sync left out, as last reported
 	lastSyncTime time.Time
 	wg           sync.WaitGroup
 	muSync       sync.Mutex
+
+	networkResync bool        // reconnect and resync when the network addresses change
+	reconnecting  atomic.Bool // the network changed, and the resync has not completed yet
+
+	verifier *SyncVerifier // nil unless the downloads are verified by hooks
+
+	downloadPriority DownloadPriority // ranks the downloads, DefaultDownloadPriority when nil
+	batchUnsupported atomic.Bool      // the server doesn't support batches, small files are transferred on their own
+
+	// transfers run on opsCtx, which outlives the Start context so that they can be drained on Stop
+	opsCtx          context.Context
+	cancelOps       context.CancelFunc
+	shutdownTimeout time.Duration // how long Stop waits for the active operations before aborting them
+
+	opsRecorded chan struct{} // closed once the outcomes of the operations are all recorded in the journal
+
+	initialSynced      chan struct{} // closed once the first full sync completed
+	closeInitialSynced sync.Once
+}
+
+// SyncOptions configures the sync. The zero value syncs with the defaults
+type SyncOptions struct {
+	FileAttrs       *fileattr.Options // the file attributes synced along with the contents
+	Transfer        *TransferOptions
+	ResumeResync    bool          // reconnect and resync when the system resumes from sleep
+	NetworkResync   bool          // reconnect and resync when the network addresses change
+	ExportDir       string        // keep a plain copy of the synced files there, none when empty
+	VerifyHooks     []VerifyHook  // verify the downloads, none when empty
+	IncludeHidden   bool          // sync the hidden files and dirs too
+	KeepRejected    bool          // leave files the server rejects in place, instead of moving them aside
+	ReadOnly        bool          // never upload, only download the remote changes
+	UploadRequeue   int           // uploads again of a file that changed during its upload. 0 uses the default, negative never
+	ShutdownTimeout time.Duration // how long Stop waits for the active operations before aborting them
 }
 
 func NewSyncEngine(
@@ -48,9 +94,13 @@ func NewSyncEngine(
 	sdk *syftsdk.SyftSDK,
 	ignore *SyncIgnoreList,
 	priority *SyncPriorityList,
+	opts *SyncOptions,
 ) (*SyncEngine, error) {
-	journalPath := filepath.Join(workspace.MetadataDir, syncDbName)
-	journal, err := NewSyncJournal(journalPath)
+	if opts == nil {
+		opts = &SyncOptions{}
+	}
+
+	journal, err := NewSyncJournal(JournalPath(workspace))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create sync journal: %w", err)
 	}
@@ -60,15 +110,39 @@ func NewSyncEngine(
 	localState := NewSyncLocalState(workspace.DatasitesDir)
 	syncStatus := NewSyncStatus()
 
+	var export *SyncExport
+	if opts.ExportDir != "" {
+		export = NewSyncExport(opts.ExportDir, workspace.DatasitesDir)
+	}
+
+	var verifier *SyncVerifier
+	if len(opts.VerifyHooks) > 0 {
+		verifier = NewSyncVerifier(opts.VerifyHooks, workspace.MetadataDir)
+	}
+
 	return &SyncEngine{
 		sdk:          sdk,
 		workspace:    workspace,
 		watcher:      watcher,
 		ignoreList:   ignore,
 		priorityList: priority,
+		fileAttrs:    opts.FileAttrs,
+		transfer:     opts.Transfer,
+		export:       export,
+		verifier:     verifier,
+		resumeResync: opts.ResumeResync,
+		keepRejected: opts.KeepRejected,
+		readOnly:     opts.ReadOnly,
+		requeue:      opts.UploadRequeue,
 		journal:      journal,
 		localState:   localState,
 		syncStatus:   syncStatus,
+		latency:      NewSyncLatency(),
+
+		networkResync: opts.NetworkResync,
+
+		shutdownTimeout: opts.ShutdownTimeout,
+		initialSynced:   make(chan struct{}),
 	}, nil
 }
 
@@ -82,20 +156,34 @@ func (se *SyncEngine) Start(ctx context.Context) error {
 		return fmt.Errorf("sync journal: %w", err)
 	}
 
+	// record the outcome of the operations, for `syftbox journal dump`
+	opEvents := se.syncStatus.subscribe(opLogBufferSize)
+	se.opsRecorded = make(chan struct{})
+	go func() {
+		defer close(se.opsRecorded)
+		se.recordOps(opEvents)
+	}()
+
 	// run sync once and wait before starting watcher//websocket
 	slog.Info("running initial sync")
-	if err := se.runFullSync(ctx); err != nil && !errors.Is(err, context.Canceled) {
-		return fmt.Errorf("initial full sync: %w", err)
+	if err := se.runFullSync(ctx); err != nil {
+		if !errors.Is(err, context.Canceled) {
+			return fmt.Errorf("initial full sync: %w", err)
+		}
 	}
 
-	// start the watcher
-	slog.Info("starting file watcher")
-	se.watcher.FilterPaths(func(path string) bool {
-		// ignore all files that are ignored, not priority or that are marked
-		return se.isIgnoredFile(path) || !se.isPriorityFile(path) || IsMarkedPath(path)
-	})
-	if err := se.watcher.Start(ctx); err != nil {
-		return fmt.Errorf("file watcher: %w", err)
+	// start the watcher. it only drives the priority uploads, so a read-only sync goes without
+	if se.readOnly {
+		slog.Warn("sync is read-only, local changes are not uploaded")
+	} else {
+		slog.Info("starting file watcher")
+		se.watcher.FilterPaths(func(path string) bool {
+			// ignore all files that are ignored, not priority or that are marked
+			return se.isIgnoredFile(path) || !se.isPriorityFile(path) || IsMarkedPath(path)
+		})
+		if err := se.watcher.Start(ctx); err != nil {
+			return fmt.Errorf("file watcher: %w", err)
+		}
 	}
 
 	// connect to websocket
@@ -104,6 +192,9 @@ func (se *SyncEngine) Start(ctx context.Context) error {
 		return fmt.Errorf("websocket events: %w", err)
 	}
 
+	// cancelling ctx stops new syncs, while the ones in flight carry on until they are drained
+	se.opsCtx, se.cancelOps = context.WithCancel(context.WithoutCancel(ctx))
+
 	se.wg.Add(1)
 	go func() {
 		defer se.wg.Done()
@@ -119,7 +210,7 @@ func (se *SyncEngine) Start(ctx context.Context) error {
 
 				return
 			case <-timer.C:
-				err := se.runFullSync(ctx)
+				err := se.runFullSync(se.opsCtx)
 				if err != nil && !errors.Is(err, context.Canceled) {
 					slog.Error("full sync", "error", err)
 				}
@@ -134,11 +225,37 @@ func (se *SyncEngine) Start(ctx context.Context) error {
 		se.handleSocketEvents(ctx)
 	}()
 
-	se.wg.Add(1)
-	go func() {
-		defer se.wg.Done()
-		se.handleWatcherEvents(ctx)
-	}()
+	if !se.readOnly {
+		se.wg.Add(1)
+		go func() {
+			defer se.wg.Done()
+			se.handleWatcherEvents(ctx)
+		}()
+	}
+
+	if se.resumeResync {
+		se.wg.Add(1)
+		go func() {
+			defer se.wg.Done()
+			ticker := time.NewTicker(resumeCheckInterval)
+			defer ticker.Stop()
+			newResumeDetector().run(ctx, ticker.C, func(away time.Duration) {
+				se.handleResume(ctx, away)
+			})
+		}()
+	}
+
+	if se.networkResync {
+		se.wg.Add(1)
+		go func() {
+			defer se.wg.Done()
+			ticker := time.NewTicker(networkCheckInterval)
+			defer ticker.Stop()
+			newNetworkDetector().run(ctx, ticker.C, func(change *networkChange) {
+				se.handleNetworkChange(ctx, change)
+			})
+		}()
+	}
 
 	return nil
 }
@@ -147,8 +264,36 @@ func (se *SyncEngine) Stop() error {
 	// Stop the file watcher first to prevent new operations
 	se.watcher.Stop()
 
-	// Wait for all sync operations to complete with timeout
-	slog.Info("sync stopping")
+	// Drain the active operations, and abort the ones still running after the timeout
+	active := se.syncStatus.GetSyncingFileCount()
+	slog.Info("sync stopping", "active", active, "timeout", se.shutdownTimeout)
+
+	aborted := 0
+	if !se.waitOps(se.shutdownTimeout) {
+		aborted = se.syncStatus.GetSyncingFileCount()
+		slog.Warn("sync drain timed out, aborting operations", "aborted", aborted)
+		se.abortOps()
+		if !se.waitOps(forceAbortTimeout) {
+			slog.Warn("sync operations did not complete after abort, proceeding with shutdown")
+		}
+	}
+	se.abortOps()
+
+	slog.Info("sync stopped", "drained", max(active-aborted, 0), "aborted", aborted)
+
+	// Now it's safe to close resources
+	se.syncStatus.Close()
+	if se.opsRecorded != nil {
+		<-se.opsRecorded
+	}
+	if err := se.journal.Flush(); err != nil && !errors.Is(err, ErrJournalNotOpen) {
+		slog.Warn("sync journal flush", "error", err)
+	}
+	return se.journal.Close()
+}
+
+// waitOps waits for the sync goroutines to return, and reports whether they did within the timeout
+func (se *SyncEngine) waitOps(timeout time.Duration) bool {
 	done := make(chan struct{})
 	go func() {
 		se.wg.Wait()
@@ -157,15 +302,17 @@ func (se *SyncEngine) Stop() error {
 
 	select {
 	case <-done:
-	case <-time.After(5 * time.Second):
-		slog.Warn("sync operations did not complete within timeout, proceeding with shutdown")
+		return true
+	case <-time.After(timeout):
+		return false
 	}
+}
 
-	slog.Info("sync stopped")
-
-	// Now it's safe to close resources
-	se.syncStatus.Close()
-	return se.journal.Close()
+// abortOps cancels the operations still in flight
+func (se *SyncEngine) abortOps() {
+	if se.cancelOps != nil {
+		se.cancelOps()
+	}
 }
 
 // RunSync performs a full sync of the local and remote states
@@ -173,35 +320,59 @@ func (se *SyncEngine) RunSync(ctx context.Context) error {
 	return se.runFullSync(ctx)
 }
 
-func (se *SyncEngine) runFullSync(ctx context.Context) error {
+// DryRun reconciles the local, remote and journal states like a full sync does,
+// and returns the operations it would run without running them.
+// It runs outside of the client, so files that the client is syncing right now aren't known to be syncing.
+// Neither the local files, the journal nor the blob storage are changed.
+func (se *SyncEngine) DryRun(ctx context.Context) (*ReconcileOperations, error) {
 	if !se.muSync.TryLock() {
-		return ErrSyncAlreadyRunning
+		return nil, ErrSyncAlreadyRunning
 	}
 	defer se.muSync.Unlock()
 
-	if err := se.presyncChecks(); err != nil {
-		return err
+	plan, err := se.planFullSync(ctx, true)
+	if err != nil {
+		return nil, err
 	}
+	return plan.ops, nil
+}
+
+// syncPlan holds the operations of a full sync, and how long each phase of planning them took
+type syncPlan struct {
+	ops           *ReconcileOperations
+	tRemoteState  time.Duration
+	tLocalState   time.Duration
+	tJournalState time.Duration
+	tReconcile    time.Duration
+}
 
+// planFullSync collects the remote, local and journal states and reconciles them.
+// A dry run plans the same operations, but leaves the journal and the legacy markers alone.
+// The caller must hold muSync.
+func (se *SyncEngine) planFullSync(ctx context.Context, dryRun bool) (*syncPlan, error) {
+	plan := &syncPlan{}
 	tStart := time.Now()
 
 	// get remote state
 	remoteState, err := se.getRemoteState(ctx)
 	if err != nil {
-		return fmt.Errorf("get remote state: %w", err)
+		return nil, fmt.Errorf("get remote state: %w", err)
 	}
-	tRemoteState := time.Since(tStart)
+	plan.tRemoteState = time.Since(tStart)
 
 	// get local state
 	tlocalStart := time.Now()
 	localState, err := se.localState.Scan()
 	if err != nil {
-		return fmt.Errorf("scan local state: %w", err)
+		return nil, fmt.Errorf("scan local state: %w", err)
 	}
-	tLocalState := time.Since(tlocalStart)
+	plan.tLocalState = time.Since(tlocalStart)
 
 	// scan for existing conflicted/rejected files and populate sync status
-	if se.isFirstSync() {
+	if se.isFirstSync() || dryRun {
+		if !dryRun {
+			se.removeLegacyMarkers(localState)
+		}
 		se.initStatusFromMarkers(localState)
 	} else {
 		se.cleanupResolvedMarkers()
@@ -210,27 +381,52 @@ func (se *SyncEngine) runFullSync(ctx context.Context) error {
 	// check journal
 	journalCount, err := se.journal.Count()
 	if err != nil {
-		return fmt.Errorf("get journal count: %w", err)
-	}
-
-	// journal is empty, but you have local files! rebuild
-	if journalCount == 0 && len(localState) > 0 && len(remoteState) > 0 {
-		slog.Info("rebuilding journal")
-		se.rebuildJournal(localState, remoteState)
+		return nil, fmt.Errorf("get journal count: %w", err)
 	}
 
 	// get the journal state
 	tjournalStart := time.Now()
-	journalState, err := se.journal.GetState()
-	if err != nil {
-		return fmt.Errorf("get journal state: %w", err)
+	var journalState map[SyncPath]*FileMetadata
+	if journalCount == 0 && len(localState) > 0 && len(remoteState) > 0 {
+		// journal is empty, but you have local files! rebuild
+		journalState = rebuiltJournalState(localState, remoteState)
+		if !dryRun {
+			slog.Info("rebuilding journal")
+			se.rebuildJournal(journalState)
+		}
+	} else {
+		journalState, err = se.journal.GetState()
+		if err != nil {
+			return nil, fmt.Errorf("get journal state: %w", err)
+		}
 	}
-	tJournalState := time.Since(tjournalStart)
+	plan.tJournalState = time.Since(tjournalStart)
 
 	// reconcile trees
 	tReconcileStart := time.Now()
-	result := se.reconcile(localState, remoteState, journalState)
-	tReconcile := time.Since(tReconcileStart)
+	plan.ops = se.reconcile(localState, remoteState, journalState)
+	plan.tReconcile = time.Since(tReconcileStart)
+
+	return plan, nil
+}
+
+func (se *SyncEngine) runFullSync(ctx context.Context) error {
+	if !se.muSync.TryLock() {
+		return ErrSyncAlreadyRunning
+	}
+	defer se.muSync.Unlock()
+
+	if err := se.presyncChecks(); err != nil {
+		return err
+	}
+
+	tStart := time.Now()
+
+	plan, err := se.planFullSync(ctx, false)
+	if err != nil {
+		return err
+	}
+	result := plan.ops
 
 	if result.HasChanges() {
 		slog.Info("full sync start",
@@ -239,6 +435,7 @@ func (se *SyncEngine) runFullSync(ctx context.Context) error {
 			"localDeletes", len(result.LocalDeletes),
 			"remoteDeletes", len(result.RemoteDeletes),
 			"conflicts", len(result.Conflicts), // new set of conflicts in this cycle
+			"merges", len(result.Merges),
 		)
 	}
 
@@ -252,39 +449,107 @@ func (se *SyncEngine) runFullSync(ctx context.Context) error {
 			"uploads", len(result.RemoteWrites),
 			"localDeletes", len(result.LocalDeletes),
 			"remoteDeletes", len(result.RemoteDeletes),
+			"merges", len(result.Merges),
 			"unchanged", len(result.UnchangedPaths),
 			"cleanups", len(result.Cleanups),
 			"ignored", len(result.Ignored),
 			"status.syncing", se.syncStatus.GetSyncingFileCount(),
 			"status.unresolvedConflicts", se.syncStatus.GetConflictedFileCount(),
 			"status.unresolvedRejects", se.syncStatus.GetRejectedFileCount(),
-			"ts.remoteState", tRemoteState,
-			"ts.localState", tLocalState,
-			"ts.journalState", tJournalState,
-			"ts.reconcile", tReconcile,
+			"status.quarantined", se.syncStatus.GetQuarantinedFileCount(),
+			"ts.remoteState", plan.tRemoteState,
+			"ts.localState", plan.tLocalState,
+			"ts.journalState", plan.tJournalState,
+			"ts.reconcile", plan.tReconcile,
 			"ts.total", tTotal,
 		)
 	}
 
+	if se.export != nil {
+		se.updateExport()
+	}
+
 	se.lastSyncTime = time.Now()
+	se.resuming.Store(false)
+	se.reconnecting.Store(false)
+	se.closeInitialSynced.Do(func() { close(se.initialSynced) })
 	return nil
 }
 
+// updateExport brings the export in line with the journal, as it stands after this sync
+func (se *SyncEngine) updateExport() {
+	synced, err := se.journal.GetState()
+	if err != nil {
+		slog.Error("sync export", "error", fmt.Errorf("get journal state: %w", err))
+		return
+	}
+	if err := se.export.Update(synced); err != nil {
+		slog.Error("sync export", "error", err)
+	}
+}
+
+// handleResume reconnects the websocket, which is likely stale after the system slept,
+// and syncs right away instead of waiting for the next full sync
+func (se *SyncEngine) handleResume(ctx context.Context, away time.Duration) {
+	slog.Info("system resumed, resyncing", "away", away.Round(time.Second))
+	se.resuming.Store(true)
+	se.sdk.Events.Reconnect()
+
+	// if a full sync is already running, it clears the resuming state when done
+	if err := se.runFullSync(ctx); err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, ErrSyncAlreadyRunning) {
+		slog.Error("full sync after resume", "error", err)
+	}
+}
+
+// handleNetworkChange reconnects the websocket, which is likely stale once the addresses it went over changed,
+// and syncs right away to catch up on the events missed in the meantime
+func (se *SyncEngine) handleNetworkChange(ctx context.Context, change *networkChange) {
+	slog.Info("network changed, resyncing", "added", change.Added, "removed", change.Removed)
+	se.reconnecting.Store(true)
+	se.sdk.Events.Reconnect()
+
+	// if a full sync is already running, it clears the reconnecting state when done
+	if err := se.runFullSync(ctx); err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, ErrSyncAlreadyRunning) {
+		slog.Error("full sync after network change", "error", err)
+	}
+}
+
+// InitialSynced is closed once the first full sync completed, and the datasites are in a consistent state.
+// When the initial sync is cancelled, it is closed by the next sync that completes.
+func (se *SyncEngine) InitialSynced() <-chan struct{} {
+	return se.initialSynced
+}
+
+// IsResuming reports whether the system resumed from sleep and the resync has not completed yet
+func (se *SyncEngine) IsResuming() bool {
+	return se.resuming.Load()
+}
+
+// IsReconnecting reports whether the network changed and the resync has not completed yet
+func (se *SyncEngine) IsReconnecting() bool {
+	return se.reconnecting.Load()
+}
+
 func (se *SyncEngine) isFirstSync() bool {
 	return se.lastSyncTime.IsZero()
 }
 
-func (se *SyncEngine) initStatusFromMarkers(localState map[SyncPath]*FileMetadata) {
+// removeLegacyMarkers cleans up the markers of the older releases
+// todo - remove this in a future release
+func (se *SyncEngine) removeLegacyMarkers(localState map[SyncPath]*FileMetadata) {
 	for relPath := range localState {
 		relPathStr := relPath.String()
-
-		// cleanup legacy markers - randomly shoved here
-		// todo - remove this in a future release
 		if IsLegacyMarkedPath(relPathStr) {
 			if err := os.Remove(se.workspace.DatasiteAbsPath(relPathStr)); err != nil {
 				slog.Error("failed to remove legacy marked path", "path", relPathStr, "error", err)
 			}
 		}
+	}
+}
+
+func (se *SyncEngine) initStatusFromMarkers(localState map[SyncPath]*FileMetadata) {
+	for relPath := range localState {
+		relPathStr := relPath.String()
 
 		if !IsMarkedPath(relPathStr) {
 			continue
@@ -299,7 +564,7 @@ func (se *SyncEngine) initStatusFromMarkers(localState map[SyncPath]*FileMetadat
 		} else if IsRejectedPath(relPathStr) {
 			unmarked := GetUnmarkedPath(relPathStr)
 			slog.Warn("unresolved reject", "path", relPath)
-			se.syncStatus.SetRejected(SyncPath(unmarked))
+			se.syncStatus.SetRejected(SyncPath(unmarked), "")
 		}
 	}
 }
@@ -320,7 +585,11 @@ func (se *SyncEngine) cleanupResolvedMarkers() {
 
 	for syncPath := range rejected {
 		localAbsPath := se.workspace.DatasiteAbsPath(syncPath.String())
-		if !RejectedFileExists(localAbsPath) {
+		if RejectedFileExists(localAbsPath) {
+			continue
+		}
+		// a reject kept in place has no marker, it's resolved once the file is gone
+		if !se.keepRejected || !utils.FileExists(localAbsPath) {
 			slog.Info("resolved reject", "path", syncPath)
 			se.syncStatus.SetCompletedAndRemove(syncPath)
 		}
@@ -339,7 +608,7 @@ func (se *SyncEngine) presyncChecks() error {
 	if err != nil {
 		slog.Error("preflight checks: failed to get disk usage", "error", err)
 	}
-	if usage.Free <= minFreeSpace {
+	if usage.Free <= MinFreeSpace {
 		return fmt.Errorf("not enough free space on disk. %s free", humanize.Bytes(usage.Free))
 	}
 
@@ -361,6 +630,7 @@ func (se *SyncEngine) reconcile(localState, remoteState, journalState map[SyncPa
 	}
 
 	reconcileOps.Total = len(allPaths)
+	detectedAt := time.Now()
 
 	for path := range allPaths {
 		local, localExists := localState[path]
@@ -375,7 +645,13 @@ func (se *SyncEngine) reconcile(localState, remoteState, journalState map[SyncPa
 			isEmpty = true
 		}
 
-		if isSyncing || isIgnored || isEmpty || errorCount >= maxRetryCount {
+		// a file the server rejected is not uploaded again until it changes
+		isRejected := localExists && se.isRejectedUnchanged(path, local)
+
+		// nor is a download that failed verification downloaded again
+		isQuarantined := remoteExists && se.verifier != nil && se.verifier.IsQuarantined(path, remote.ETag)
+
+		if isSyncing || isIgnored || isEmpty || isRejected || isQuarantined || errorCount >= maxRetryCount {
 			reconcileOps.Ignored[path] = struct{}{}
 			continue
 		}
@@ -399,23 +675,40 @@ func (se *SyncEngine) reconcile(localState, remoteState, journalState map[SyncPa
 			(localCreated && remoteCreated) {
 			// Conflict Case: Local Create/Modify + Remote Create/Modify
 			// todo we can also consider local modify + remote delete or local delete + remote modify as conflict
-			reconcileOps.Conflicts[path] = &SyncOperation{Type: OpConflict, RelPath: path, Local: local, Remote: remote, LastSynced: journal}
+			reason := ReasonBothModified
+			if localCreated {
+				reason = ReasonBothCreated
+			}
+			// crdt files merge instead
+			if crdt.IsCRDTFile(path.String()) {
+				reconcileOps.Merges[path] = &SyncOperation{Type: OpMerge, RelPath: path, Local: local, Remote: remote, LastSynced: journal, Reason: reason}
+				continue
+			}
+			reconcileOps.Conflicts[path] = &SyncOperation{Type: OpConflict, RelPath: path, Local: local, Remote: remote, LastSynced: journal, Reason: reason}
 			continue
 		}
 
 		// Regular Sync
 		if localCreated || localModified {
 			// Local New/Modify + Remote Unchanged
-			reconcileOps.RemoteWrites[path] = &SyncOperation{Type: OpWriteRemote, RelPath: path, Local: local, Remote: remote, LastSynced: journal}
+			reason := ReasonLocalModified
+			if localCreated {
+				reason = ReasonLocalCreated
+			}
+			reconcileOps.RemoteWrites[path] = &SyncOperation{Type: OpWriteRemote, RelPath: path, Local: local, Remote: remote, LastSynced: journal, DetectedAt: detectedAt, Reason: reason}
 		} else if remoteCreated || remoteModified {
 			// Local Unchanged + Remote New/Modify
-			reconcileOps.LocalWrites[path] = &SyncOperation{Type: OpWriteLocal, RelPath: path, Local: local, Remote: remote, LastSynced: journal}
+			reason := ReasonRemoteModified
+			if remoteCreated {
+				reason = ReasonRemoteCreated
+			}
+			reconcileOps.LocalWrites[path] = &SyncOperation{Type: OpWriteLocal, RelPath: path, Local: local, Remote: remote, LastSynced: journal, DetectedAt: detectedAt, Reason: reason}
 		} else if localDeleted {
 			// Local Delete + Remote Exists
-			reconcileOps.RemoteDeletes[path] = &SyncOperation{Type: OpDeleteRemote, RelPath: path, Local: local, Remote: remote, LastSynced: journal}
+			reconcileOps.RemoteDeletes[path] = &SyncOperation{Type: OpDeleteRemote, RelPath: path, Local: local, Remote: remote, LastSynced: journal, Reason: ReasonLocalDeleted}
 		} else if remoteDeleted {
 			// Remote Delete + Local Exists
-			reconcileOps.LocalDeletes[path] = &SyncOperation{Type: OpDeleteLocal, RelPath: path, Local: local, Remote: remote, LastSynced: journal}
+			reconcileOps.LocalDeletes[path] = &SyncOperation{Type: OpDeleteLocal, RelPath: path, Local: local, Remote: remote, LastSynced: journal, Reason: ReasonRemoteDeleted}
 		} else {
 			// Local Unchanged + Remote Unchanged
 			reconcileOps.UnchangedPaths[path] = struct{}{}
@@ -423,6 +716,10 @@ func (se *SyncEngine) reconcile(localState, remoteState, journalState map[SyncPa
 		}
 	}
 
+	if se.readOnly {
+		se.withholdLocalChanges(reconcileOps)
+	}
+
 	return reconcileOps
 }
 
@@ -469,6 +766,14 @@ func (se *SyncEngine) executeReconcileOperations(ctx context.Context, result *Re
 		}
 	}()
 
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if len(result.Merges) > 0 {
+			se.handleMerges(ctx, result.Merges)
+		}
+	}()
+
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
@@ -527,6 +832,11 @@ func (se *SyncEngine) isSyncing(path SyncPath) bool {
 	return exists && status.SyncState == SyncStateSyncing
 }
 
+func (se *SyncEngine) isRejected(path SyncPath) bool {
+	status, exists := se.syncStatus.GetStatus(path)
+	return exists && status.ConflictState == ConflictStateRejected
+}
+
 func (se *SyncEngine) isPriorityFile(path string) bool {
 	return se.priorityList.ShouldPrioritize(path)
 }
@@ -556,22 +866,20 @@ func (se *SyncEngine) getRemoteState(ctx context.Context) (map[SyncPath]*FileMet
 	return remoteState, nil
 }
 
-func (se *SyncEngine) rebuildJournal(localState, remoteState map[SyncPath]*FileMetadata) {
-	allPaths := make(map[SyncPath]struct{})
-	for path := range localState {
-		allPaths[path] = struct{}{}
-	}
-	for path := range remoteState {
-		allPaths[path] = struct{}{}
+// rebuiltJournalState returns the journal entries that can be recovered, the files that are the same locally and remotely
+func rebuiltJournalState(localState, remoteState map[SyncPath]*FileMetadata) map[SyncPath]*FileMetadata {
+	state := make(map[SyncPath]*FileMetadata)
+	for path, local := range localState {
+		if remote, ok := remoteState[path]; ok && local.ETag == remote.ETag {
+			state[path] = local
+		}
 	}
+	return state
+}
 
-	for path := range allPaths {
-		local, localExists := localState[path]
-		remote, remoteExists := remoteState[path]
-
-		if localExists && remoteExists && local.ETag == remote.ETag {
-			se.journal.Set(local)
-		}
+func (se *SyncEngine) rebuildJournal(state map[SyncPath]*FileMetadata) {
+	for _, metadata := range state {
+		se.journal.Set(metadata)
 	}
 }
 
@@ -586,19 +894,29 @@ func (se *SyncEngine) handleSocketEvents(ctx context.Context) {
 				slog.Debug("handleSocketEvents channel closed")
 				return
 			}
-			switch msg.Type {
-			case syftmsg.MsgSystem:
-				go se.handleSystem(msg)
-			case syftmsg.MsgError:
-				go se.handlePriorityError(msg)
-			case syftmsg.MsgFileWrite:
-				go se.handlePriorityDownload(msg)
-			case syftmsg.MsgHttp:
-				go se.processHttpMessage(msg)
-			default:
-				slog.Debug("websocket unhandled type", "type", msg.Type)
-			}
+			se.handleSocketMessage(msg)
+		}
+	}
+}
+
+func (se *SyncEngine) handleSocketMessage(msg *syftmsg.Message) {
+	switch msg.Type {
+	case syftmsg.MsgSystem:
+		go se.handleSystem(msg)
+	case syftmsg.MsgError:
+		go se.handlePriorityError(msg)
+	case syftmsg.MsgFileWrite:
+		go se.handlePriorityDownload(msg)
+	case syftmsg.MsgHttp:
+		go se.processHttpMessage(msg)
+	case syftmsg.MsgBatch:
+		// notifications coalesced by the server, handled one by one
+		batch, _ := msg.Data.(syftmsg.Batch)
+		for _, m := range batch.Messages {
+			se.handleSocketMessage(m)
 		}
+	default:
+		slog.Debug("websocket unhandled type", "type", msg.Type)
 	}
 }
 
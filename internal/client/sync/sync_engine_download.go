@@ -10,9 +10,12 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
+	"sync"
 
 	"github.com/dustin/go-humanize"
+	"github.com/openmined/syftbox/internal/fileattr"
 	"github.com/openmined/syftbox/internal/queue"
 	"github.com/openmined/syftbox/internal/syftsdk"
 	"github.com/openmined/syftbox/internal/utils"
@@ -67,7 +70,9 @@ func (se *SyncEngine) handleLocalWrites(ctx context.Context, batch BatchLocalWri
 		syncRelPath := SyncPath(res.Path)
 		if res.Error != nil {
 			var sdkErr syftsdk.SDKError
-			if errors.As(res.Error, &sdkErr) && strings.HasPrefix(sdkErr.ErrorCode(), syftsdk.CodePresignedURLErrors) {
+			if errors.Is(res.Error, ErrQuarantined) {
+				se.setQuarantined(SyncStandard, syncRelPath, res.Error)
+			} else if errors.As(res.Error, &sdkErr) && strings.HasPrefix(sdkErr.ErrorCode(), syftsdk.CodePresignedURLErrors) {
 				slog.Warn("sync", "type", SyncStandard, "op", OpWriteLocal, "status", "Ignored", "path", res.Path, "error", sdkErr)
 				se.syncStatus.SetCompletedAndRemove(syncRelPath)
 			} else {
@@ -78,14 +83,18 @@ func (se *SyncEngine) handleLocalWrites(ctx context.Context, batch BatchLocalWri
 		}
 
 		se.journal.Set(res.Metadata)
-		se.syncStatus.SetCompleted(syncRelPath)
+		se.setDownloaded(syncRelPath)
+		if op, ok := batch[syncRelPath]; ok {
+			se.latency.ObserveSince(LatencyDownload, op.DetectedAt)
+		}
 		slog.Info("sync", "type", SyncStandard, "op", OpWriteLocal, "status", "Completed", "path", res.Path, "size", humanize.Bytes(uint64(res.Metadata.Size)))
 	}
 }
 
 // downloadBatchUnique handles the core logic of downloading a batch of files.
-// It deduplicates files by ETag, fetches presigned URLs in chunks of 100, prioritizes downloads,
-// and executes them. It runs in a goroutine and streams results back over a channel.
+// It deduplicates files by ETag, prioritizes downloads, fetches small files in batches and
+// presigned URLs for the others in chunks of 100, and executes them.
+// It runs in a goroutine and streams results back over a channel.
 func (se *SyncEngine) downloadBatchUnique(ctx context.Context, batch BatchLocalWrite) (<-chan downloadResult, error) {
 	resultsChan := make(chan downloadResult, len(batch))
 
@@ -123,126 +132,249 @@ func (se *SyncEngine) downloadBatchUnique(ctx context.Context, batch BatchLocalW
 			}, priority)
 		}
 
-		// Process downloads in batches to avoid URL expiration.
-		for pq.Len() > 0 {
-			// Get next chunk of files to download.
-			currentChunkSize := min(downloadBatchSize, pq.Len())
-			chunkPaths := make([]string, 0, currentChunkSize)
-			chunkItems := make([]*pendingDownload, 0, currentChunkSize)
+		// deliver copies a downloaded file to all the paths with its content
+		deliver := func(etag string, downloadPath string, attrs *fileattr.Attrs, downloadErr error) {
+			pathsToCopy, exists := etagToPaths[etag]
+			if !exists {
+				return // ??? unlikely
+			}
 
-			for range currentChunkSize {
-				item, _ := pq.Dequeue()
-				chunkPaths = append(chunkPaths, item.RelPath)
-				chunkItems = append(chunkItems, item)
+			// Handle download failure.
+			if downloadErr != nil {
+				for _, p := range pathsToCopy {
+					resultsChan <- downloadResult{Path: p, Metadata: pathToMeta[p], Error: downloadErr}
+				}
+				return
 			}
 
-			// Get presigned URLs for this chunk.
-			resUrls, err := se.sdk.Blob.Download(ctx, &syftsdk.PresignedParams{
-				Keys: chunkPaths,
-			})
-			if err != nil {
-				// On total failure, send an error for every file in this chunk.
-				for _, item := range chunkItems {
-					for _, path := range etagToPaths[item.ETag] {
+			// Handle download success: copy file to all required locations.
+			for _, path := range pathsToCopy {
+				targetPath := filepath.Join(se.workspace.DatasitesDir, path)
+
+				// the hooks are per path, the same content may pass for one and not for another
+				if se.verifier != nil {
+					if err := se.verifier.Verify(ctx, SyncPath(path), etag, downloadPath); err != nil {
 						resultsChan <- downloadResult{Path: path, Metadata: pathToMeta[path], Error: err}
+						continue
+					}
+				}
+
+				if se.isPriorityFile(targetPath) {
+					// a priority file was just downloaded, we don't wanna fire an event for THIS write
+					se.watcher.IgnoreOnce(targetPath)
+				}
+
+				err := copyLocal(downloadPath, targetPath)
+				if err == nil {
+					if attrErr := fileattr.Apply(targetPath, attrs, se.fileAttrs); attrErr != nil {
+						// the content is synced, only its attributes are missing
+						slog.Warn("sync", "type", SyncStandard, "op", OpWriteLocal, "path", path, "error", fmt.Errorf("file attributes: %w", attrErr))
 					}
 				}
-				continue
+
+				if err != nil {
+					resultsChan <- downloadResult{Path: path, Metadata: pathToMeta[path], Error: err}
+				} else {
+					resultsChan <- downloadResult{Path: path, Metadata: pathToMeta[path], Error: nil}
+				}
 			}
+		}
 
-			// Handle errors for individual URL generations.
-			dlJobs := make([]*syftsdk.DownloadJob, 0, len(resUrls.URLs))
-			for _, urlErr := range resUrls.Errors {
-				meta := pathToMeta[urlErr.Key]
-				for _, path := range etagToPaths[meta.ETag] {
-					resultsChan <- downloadResult{Path: path, Metadata: pathToMeta[path], Error: urlErr}
+		// Process downloads in chunks to avoid URL expiration.
+		for pq.Len() > 0 {
+			// Get next chunk of files to download.
+			currentChunkSize := min(max(downloadBatchSize, se.transfer.batchSize()), pq.Len())
+			dequeued := make([]*pendingDownload, 0, currentChunkSize)
+			smallItems := make([]*pendingDownload, 0, currentChunkSize)
+
+			for range currentChunkSize {
+				item, _ := pq.Dequeue()
+				dequeued = append(dequeued, item)
+				if se.batched(item.RelPath, item.Metadata.Size) {
+					smallItems = append(smallItems, item)
 				}
 			}
 
-			// Build download jobs for successful URLs.
-			for _, url := range resUrls.URLs {
-				meta := pathToMeta[url.Key]
-				dlJobs = append(dlJobs, &syftsdk.DownloadJob{
-					URL:       url.URL,
-					TargetDir: tempDir,
-					Name:      meta.ETag, // Use ETag as the unique identifier for the download content.
-					Callback: func(job *syftsdk.DownloadJob, downloadedBytes int64, totalBytes int64) {
-						key := url.Key
-						// ignore small files
-						if totalBytes < 4*1024*1024 {
-							return
+			// Small files come in batches. Those of a server without batches get presigned URLs like the others.
+			var fallbackMu sync.Mutex
+			fallback := make(map[*pendingDownload]bool)
+			transfers := make([]func(ctx context.Context), 0)
+			for items := range slices.Chunk(smallItems, se.transfer.batchSize()) {
+				transfers = append(transfers, func(ctx context.Context) {
+					if notBatched := se.downloadFiles(ctx, items, tempDir, deliver); len(notBatched) > 0 {
+						fallbackMu.Lock()
+						for _, item := range notBatched {
+							fallback[item] = true
 						}
-						progress := float64(downloadedBytes) / float64(totalBytes) * 100.0
-						se.syncStatus.SetProgress(SyncPath(key), progress)
-						slog.Debug("sync", "type", SyncStandard, "op", OpWriteLocal, "status", "Downloading", "path", key, "progress", fmt.Sprintf("%.2f%%", progress))
-					},
+						fallbackMu.Unlock()
+					}
 				})
 			}
+			se.runTransfers(ctx, transfers)
 
-			// Skip if no valid jobs in this chunk.
-			if len(dlJobs) == 0 {
-				continue
+			// the rest keep their priority order
+			batched := make(map[*pendingDownload]bool, len(smallItems))
+			for _, item := range smallItems {
+				batched[item] = !fallback[item]
 			}
+			chunkItems := slices.DeleteFunc(dequeued, func(item *pendingDownload) bool { return batched[item] })
 
-			// Download this chunk and process results.
-			downloadResultsChan := syftsdk.Downloader(ctx, &syftsdk.DownloadOpts{
-				Workers: 8,
-				Jobs:    dlJobs,
-			})
-			for res := range downloadResultsChan {
-				etag := res.Name // res.Name is the ETag
-				pathsToCopy, exists := etagToPaths[etag]
-				if !exists {
-					continue // ??? unlikely
-				}
+			for items := range slices.Chunk(chunkItems, downloadBatchSize) {
+				se.downloadPresigned(ctx, items, tempDir, pathToMeta, etagToPaths, resultsChan, deliver)
+			}
+		}
+	}()
 
-				// Handle download failure.
-				if res.Error != nil {
-					for _, p := range pathsToCopy {
-						resultsChan <- downloadResult{Path: p, Metadata: pathToMeta[p], Error: res.Error}
-					}
-					continue
-				}
+	return resultsChan, nil
+}
 
-				// Handle download success: copy file to all required locations.
-				for _, path := range pathsToCopy {
-					targetPath := filepath.Join(se.workspace.DatasitesDir, path)
+// downloadPresigned downloads the files of a chunk from their presigned URLs
+func (se *SyncEngine) downloadPresigned(
+	ctx context.Context,
+	chunkItems []*pendingDownload,
+	tempDir string,
+	pathToMeta map[string]*FileMetadata,
+	etagToPaths map[string][]string,
+	resultsChan chan<- downloadResult,
+	deliver func(etag string, downloadPath string, attrs *fileattr.Attrs, err error),
+) {
+	chunkPaths := make([]string, 0, len(chunkItems))
+	for _, item := range chunkItems {
+		chunkPaths = append(chunkPaths, item.RelPath)
+	}
 
-					if se.isPriorityFile(targetPath) {
-						// a priority file was just downloaded, we don't wanna fire an event for THIS write
-						se.watcher.IgnoreOnce(targetPath)
-					}
+	// Get presigned URLs for this chunk.
+	resUrls, err := se.sdk.Blob.Download(ctx, &syftsdk.PresignedParams{
+		Keys: chunkPaths,
+	})
+	if err != nil {
+		// On total failure, send an error for every file in this chunk.
+		for _, item := range chunkItems {
+			deliver(item.ETag, "", nil, err)
+		}
+		return
+	}
 
-					err := copyLocal(res.DownloadPath, targetPath)
+	// Handle errors for individual URL generations.
+	dlJobs := make([]*syftsdk.DownloadJob, 0, len(resUrls.URLs))
+	for _, urlErr := range resUrls.Errors {
+		meta := pathToMeta[urlErr.Key]
+		for _, path := range etagToPaths[meta.ETag] {
+			resultsChan <- downloadResult{Path: path, Metadata: pathToMeta[path], Error: urlErr}
+		}
+	}
 
-					if err != nil {
-						resultsChan <- downloadResult{Path: path, Metadata: pathToMeta[path], Error: err}
-					} else {
-						resultsChan <- downloadResult{Path: path, Metadata: pathToMeta[path], Error: nil}
-					}
+	// Build download jobs for successful URLs.
+	for _, url := range resUrls.URLs {
+		meta := pathToMeta[url.Key]
+		dlJobs = append(dlJobs, &syftsdk.DownloadJob{
+			URL:       url.URL,
+			TargetDir: tempDir,
+			Name:      meta.ETag, // Use ETag as the unique identifier for the download content.
+			Callback: func(job *syftsdk.DownloadJob, downloadedBytes int64, totalBytes int64) {
+				key := url.Key
+				// ignore small files
+				if totalBytes < 4*1024*1024 {
+					return
 				}
-			}
+				progress := float64(downloadedBytes) / float64(totalBytes) * 100.0
+				se.syncStatus.SetProgress(SyncPath(key), progress)
+				slog.Debug("sync", "type", SyncStandard, "op", OpWriteLocal, "status", "Downloading", "path", key, "progress", fmt.Sprintf("%.2f%%", progress))
+			},
+		})
+	}
+
+	// Skip if no valid jobs in this chunk.
+	if len(dlJobs) == 0 {
+		return
+	}
+
+	// Download this chunk and process results.
+	downloadResultsChan := syftsdk.Downloader(ctx, &syftsdk.DownloadOpts{
+		Workers: se.transfer.workers(),
+		Jobs:    dlJobs,
+	})
+	for res := range downloadResultsChan {
+		deliver(res.Name, res.DownloadPath, res.Attrs, res.Error) // res.Name is the ETag
+	}
+}
+
+// downloadFiles downloads the small files in a single batch.
+// It returns the files to download on their own, when the server doesn't support batches
+func (se *SyncEngine) downloadFiles(
+	ctx context.Context,
+	items []*pendingDownload,
+	tempDir string,
+	deliver func(etag string, downloadPath string, attrs *fileattr.Attrs, err error),
+) []*pendingDownload {
+	keys := make([]string, 0, len(items))
+	pending := make(map[string]*pendingDownload, len(items))
+	for _, item := range items {
+		keys = append(keys, item.RelPath)
+		pending[item.RelPath] = item
+	}
+
+	results, err := se.sdk.Blob.DownloadBatch(ctx, &syftsdk.PresignedParams{Keys: keys}, tempDir)
+	if err != nil && se.batchFailed(err) {
+		return items
+	}
+
+	for _, res := range results {
+		item, ok := pending[res.Key]
+		if !ok {
+			continue
 		}
-	}()
+		delete(pending, res.Key)
+		deliver(item.ETag, res.DownloadPath, res.Attrs, res.Error)
+	}
 
-	return resultsChan, nil
+	// the batch was cut short
+	if err == nil {
+		err = ErrMissingFromBatch
+	}
+	for _, item := range pending {
+		deliver(item.ETag, "", nil, err)
+	}
+	return nil
 }
 
-func (se *SyncEngine) getDownloadPriority(meta *FileMetadata) int {
-	// file size + key length
-	priority := int(meta.Size) + len(meta.Path)
-
-	// user's datasite should be downloaded first
-	metaPath := meta.Path.String()
-	if strings.HasPrefix(metaPath, se.workspace.Owner) {
-		priority = 0
-	} else if strings.HasSuffix(metaPath, "syft.pub.yaml") {
-		priority = 1
-	} else if strings.Contains(metaPath, "/rpc/") {
-		priority = 2
+// DownloadPriority ranks the files of a sync for download, the lowest first.
+// It receives the remote metadata of a file: its Path, the blob key starting with the datasite,
+// its Size and its ETag. Files of the same rank are downloaded in any order
+type DownloadPriority func(meta *FileMetadata) int
+
+// DefaultDownloadPriority downloads the owner's datasite first, then the acls and the rpc files,
+// and the rest from the smallest up
+func DefaultDownloadPriority(owner string) DownloadPriority {
+	return func(meta *FileMetadata) int {
+		// file size + key length
+		priority := int(meta.Size) + len(meta.Path)
+
+		// user's datasite should be downloaded first
+		metaPath := meta.Path.String()
+		if strings.HasPrefix(metaPath, owner) {
+			priority = 0
+		} else if strings.HasSuffix(metaPath, "syft.pub.yaml") {
+			priority = 1
+		} else if strings.Contains(metaPath, "/rpc/") {
+			priority = 2
+		}
+
+		return priority
 	}
+}
+
+// SetDownloadPriority replaces the ranking of the downloads. nil restores the default.
+// It must be set before the sync starts
+func (se *SyncEngine) SetDownloadPriority(priority DownloadPriority) {
+	se.downloadPriority = priority
+}
 
-	return priority
+func (se *SyncEngine) getDownloadPriority(meta *FileMetadata) int {
+	if se.downloadPriority == nil {
+		return DefaultDownloadPriority(se.workspace.Owner)(meta)
+	}
+	return se.downloadPriority(meta)
 }
 
 func copyLocal(src, dst string) error {
@@ -306,21 +438,25 @@ func copyLocal(src, dst string) error {
 	}
 
 	// Atomic rename - file appears complete or not at all
-	if err := renameFile(tmpDst, dst); err != nil {
+	if err := replaceFile(tmpDst, dst); err != nil {
+		return err
+	}
+
+	success = true
+	return nil
+}
+
+// replaceFile renames src to dst, replacing dst if it already exists
+func replaceFile(src, dst string) error {
+	if err := renameFile(src, dst); err != nil {
 		// On Windows, Rename does not overwrite existing files. Retry after explicit remove.
 		if runtimeGOOS == "windows" && errors.Is(err, fs.ErrExist) {
 			if rmErr := os.Remove(dst); rmErr != nil && !errors.Is(rmErr, os.ErrNotExist) {
 				return rmErr
 			}
-
-			if err := renameFile(tmpDst, dst); err != nil {
-				return err
-			}
-		} else {
-			return err
+			return renameFile(src, dst)
 		}
+		return err
 	}
-
-	success = true
 	return nil
 }
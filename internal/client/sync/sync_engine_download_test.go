@@ -1,11 +1,25 @@
 package sync
 
 import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"io"
 	"io/fs"
+	"math"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/openmined/syftbox/internal/client/workspace"
+	"github.com/openmined/syftbox/internal/fileattr"
+	"github.com/openmined/syftbox/internal/syftsdk"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -81,3 +95,221 @@ func TestCopyLocalRenameErrorCleanup(t *testing.T) {
 	require.NoError(t, err)
 	assert.Empty(t, tmpFiles, "temporary files should be removed after failure")
 }
+
+type fakeBlob struct {
+	content  []byte
+	metadata map[string]string
+}
+
+// newFakeBlobServer serves uploads, presigned download urls and the downloads themselves.
+// Like S3, metadata is stored from the upload and served as x-amz-meta headers.
+func newFakeBlobServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	blobs := make(map[string]*fakeBlob)
+	var srv *httptest.Server
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("PUT /api/v1/blob/upload", func(w http.ResponseWriter, r *http.Request) {
+		file, _, err := r.FormFile("file")
+		require.NoError(t, err)
+		content, err := io.ReadAll(file)
+		require.NoError(t, err)
+
+		attrs, err := fileattr.Parse(r.URL.Query().Get(fileattr.MetaExecutable), r.URL.Query().Get(fileattr.MetaXattrs))
+		require.NoError(t, err)
+
+		key := r.URL.Query().Get("key")
+		blobs[key] = &fakeBlob{content: content, metadata: attrs.Metadata()}
+
+		sum := md5.Sum(content)
+		json.NewEncoder(w).Encode(&syftsdk.UploadResponse{
+			Key:          key,
+			ETag:         hex.EncodeToString(sum[:]),
+			Size:         int64(len(content)),
+			LastModified: time.Now().UTC().Format(time.RFC3339),
+		})
+	})
+	mux.HandleFunc("POST /api/v1/blob/download", func(w http.ResponseWriter, r *http.Request) {
+		var params syftsdk.PresignedParams
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&params))
+
+		resp := &syftsdk.PresignedResponse{}
+		for _, key := range params.Keys {
+			resp.URLs = append(resp.URLs, &syftsdk.BlobURL{Key: key, URL: srv.URL + "/objects/" + key})
+		}
+		json.NewEncoder(w).Encode(resp)
+	})
+	mux.HandleFunc("GET /objects/", func(w http.ResponseWriter, r *http.Request) {
+		blob, ok := blobs[strings.TrimPrefix(r.URL.Path, "/objects/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		for k, v := range blob.metadata {
+			w.Header().Set("x-amz-meta-"+k, v)
+		}
+		w.Write(blob.content)
+	})
+
+	srv = httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newPeerTestEngine(t *testing.T, serverURL string, email string, fileAttrs *fileattr.Options) *SyncEngine {
+	t.Helper()
+	ws, err := workspace.NewWorkspace(t.TempDir(), email)
+	require.NoError(t, err)
+
+	sdk, err := syftsdk.New(&syftsdk.SyftSDKConfig{
+		BaseURL:      serverURL,
+		Email:        email,
+		RefreshToken: "token",
+	})
+	require.NoError(t, err)
+
+	journal, err := NewSyncJournal(filepath.Join(t.TempDir(), "journal.db"))
+	require.NoError(t, err)
+	require.NoError(t, journal.Open())
+	t.Cleanup(func() { journal.Close() })
+
+	ignoreList := NewSyncIgnoreList(ws.DatasitesDir, true)
+	ignoreList.Load()
+
+	return &SyncEngine{
+		workspace:    ws,
+		sdk:          sdk,
+		journal:      journal,
+		ignoreList:   ignoreList,
+		priorityList: NewSyncPriorityList(ws.DatasitesDir),
+		syncStatus:   NewSyncStatus(),
+		latency:      NewSyncLatency(),
+		fileAttrs:    fileAttrs,
+	}
+}
+
+func TestSyncPreservesExecutableBit(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no executable bit on windows")
+	}
+
+	srv := newFakeBlobServer(t)
+	fileAttrs := &fileattr.Options{Executable: true}
+	alice := newPeerTestEngine(t, srv.URL, "alice@example.com", fileAttrs)
+	bob := newPeerTestEngine(t, srv.URL, "bob@example.com", fileAttrs)
+
+	relPath := SyncPath("alice@example.com/public/apps/run.sh")
+	script := []byte("#!/bin/sh\necho hello\n")
+
+	src := alice.workspace.DatasiteAbsPath(relPath.String())
+	require.NoError(t, os.MkdirAll(filepath.Dir(src), 0o755))
+	require.NoError(t, os.WriteFile(src, script, 0o755))
+	require.NoError(t, os.Chmod(src, 0o755))
+
+	info, err := os.Stat(src)
+	require.NoError(t, err)
+	etag, err := calculateETag(src)
+	require.NoError(t, err)
+	metadata := &FileMetadata{Path: relPath, ETag: etag, Size: info.Size(), LastModified: info.ModTime()}
+
+	alice.handleRemoteWrites(context.Background(), BatchRemoteWrite{
+		relPath: &SyncOperation{Type: OpWriteRemote, RelPath: relPath, Local: metadata},
+	})
+
+	bob.handleLocalWrites(context.Background(), BatchLocalWrite{
+		relPath: &SyncOperation{Type: OpWriteLocal, RelPath: relPath, Remote: metadata},
+	})
+
+	dst := bob.workspace.DatasiteAbsPath(relPath.String())
+	content, err := os.ReadFile(dst)
+	require.NoError(t, err)
+	assert.Equal(t, script, content)
+
+	info, err = os.Stat(dst)
+	require.NoError(t, err)
+	assert.NotZero(t, info.Mode().Perm()&0o100, "script should remain executable, got %s", info.Mode())
+}
+
+// newOrderTestServer serves every download with its key, and records the order the keys were requested in
+func newOrderTestServer(t *testing.T) (*httptest.Server, *[]string) {
+	t.Helper()
+	var keys []string
+	var srv *httptest.Server
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v1/blob/download", func(w http.ResponseWriter, r *http.Request) {
+		var params syftsdk.PresignedParams
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&params))
+		keys = append(keys, params.Keys...)
+
+		resp := &syftsdk.PresignedResponse{}
+		for _, key := range params.Keys {
+			resp.URLs = append(resp.URLs, &syftsdk.BlobURL{Key: key, URL: srv.URL + "/objects/" + key})
+		}
+		json.NewEncoder(w).Encode(resp)
+	})
+	mux.HandleFunc("GET /objects/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.TrimPrefix(r.URL.Path, "/objects/")))
+	})
+
+	srv = httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv, &keys
+}
+
+func TestDownloadPriority(t *testing.T) {
+	srv, requested := newOrderTestServer(t)
+	bob := newPeerTestEngine(t, srv.URL, "bob@example.com", nil)
+
+	files := map[SyncPath]int64{
+		"alice@example.com/public/archive.zip":           1 << 30,
+		"alice@example.com/app_data/dashboard/data.json": 1 << 20,
+		"alice@example.com/public/notes.txt":             10,
+		"carol@example.com/app_data/dashboard/view.html": 1 << 10,
+	}
+	batch := BatchLocalWrite{}
+	for path, size := range files {
+		batch[path] = &SyncOperation{Type: OpWriteLocal, RelPath: path, Remote: &FileMetadata{Path: path, ETag: path.String(), Size: size}}
+	}
+
+	// the dashboard app first, the archives last, the rest by size
+	bob.SetDownloadPriority(func(meta *FileMetadata) int {
+		switch {
+		case strings.Contains(meta.Path.String(), "/app_data/dashboard/"):
+			return 0
+		case strings.HasSuffix(meta.Path.String(), ".zip"):
+			return math.MaxInt
+		default:
+			return 1 + int(meta.Size)
+		}
+	})
+	bob.handleLocalWrites(context.Background(), batch)
+
+	require.Len(t, *requested, 4)
+	assert.ElementsMatch(t, []string{
+		"alice@example.com/app_data/dashboard/data.json",
+		"carol@example.com/app_data/dashboard/view.html",
+	}, (*requested)[:2])
+	assert.Equal(t, []string{
+		"alice@example.com/public/notes.txt",
+		"alice@example.com/public/archive.zip",
+	}, (*requested)[2:])
+	for path := range files {
+		assert.FileExists(t, bob.workspace.DatasiteAbsPath(path.String()))
+	}
+}
+
+func TestDefaultDownloadPriority(t *testing.T) {
+	priority := DefaultDownloadPriority("bob@example.com")
+
+	own := priority(&FileMetadata{Path: "bob@example.com/public/big.bin", Size: 1 << 30})
+	acl := priority(&FileMetadata{Path: "alice@example.com/public/syft.pub.yaml", Size: 100})
+	rpc := priority(&FileMetadata{Path: "alice@example.com/app_data/app/rpc/ping.request", Size: 100})
+	small := priority(&FileMetadata{Path: "alice@example.com/public/a.txt", Size: 10})
+	large := priority(&FileMetadata{Path: "alice@example.com/public/b.txt", Size: 1 << 20})
+
+	assert.Less(t, own, acl)
+	assert.Less(t, acl, rpc)
+	assert.Less(t, rpc, small)
+	assert.Less(t, small, large)
+}
@@ -0,0 +1,188 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/openmined/syftbox/internal/utils"
+)
+
+const (
+	ingestBufferSize = 64 * 1024
+)
+
+var (
+	ErrInvalidIngestPath = errors.New("invalid datasite path")
+	ErrIngestInProgress  = errors.New("file is already syncing")
+	ErrReadOnly          = errors.New("sync is read-only")
+)
+
+// Ingest writes the contents of src to relPath in the datasites directory and uploads it right away,
+// without waiting for the next full sync. size is the expected content length, or -1 if unknown.
+//
+// Progress of both phases is reported on the sync status event stream:
+//   - receiving the content sets the path to pending and reports the bytes written so far
+//   - uploading the file sets the path to syncing and reports the bytes uploaded so far
+//
+// Ingest returns once the file is written. The upload continues in the background.
+func (se *SyncEngine) Ingest(ctx context.Context, relPath SyncPath, src io.Reader, size int64) (*FileMetadata, error) {
+	if se.readOnly {
+		return nil, ErrReadOnly
+	}
+
+	metadata, err := se.ingest(relPath, src, size)
+	if err != nil {
+		return nil, err
+	}
+
+	// request is done once the content is received, so don't bind the upload to its context
+	uploadCtx := context.WithoutCancel(ctx)
+
+	se.wg.Add(1)
+	go func() {
+		defer se.wg.Done()
+		se.handleRemoteWrites(uploadCtx, BatchRemoteWrite{
+			relPath: &SyncOperation{
+				Type:       OpWriteRemote,
+				RelPath:    relPath,
+				Local:      metadata,
+				DetectedAt: time.Now(),
+			},
+		})
+	}()
+
+	return metadata, nil
+}
+
+// ingest streams src into place and leaves the path in the syncing state, ready to be uploaded
+func (se *SyncEngine) ingest(relPath SyncPath, src io.Reader, size int64) (*FileMetadata, error) {
+	rel := relPath.String()
+	if !se.workspace.IsValidPath(rel) || se.isIgnoredFile(rel) || IsMarkedPath(rel) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidIngestPath, rel)
+	}
+
+	// claims the path, a concurrent ingest of the same path fails here
+	if !se.syncStatus.StartPending(relPath) {
+		return nil, fmt.Errorf("%w: %q", ErrIngestInProgress, rel)
+	}
+
+	dst := se.workspace.DatasiteAbsPath(rel)
+	if err := utils.EnsureParent(dst); err != nil {
+		se.syncStatus.SetError(relPath, err)
+		return nil, err
+	}
+
+	// the temp file matches the ignore list, so the watcher & full sync won't pick up partial content
+	tmp, err := os.CreateTemp(filepath.Dir(dst), filepath.Base(dst)+".syft.tmp.*")
+	if err != nil {
+		se.syncStatus.SetError(relPath, err)
+		return nil, err
+	}
+	tmpPath := tmp.Name()
+
+	success := false
+	defer func() {
+		if tmp != nil {
+			tmp.Close()
+		}
+		if !success {
+			os.Remove(tmpPath)
+		}
+	}()
+
+	slog.Info("sync", "type", SyncPriority, "op", OpWriteRemote, "path", relPath, "status", "receiving", "size", size)
+
+	pw := &progressWriter{
+		total: size,
+		onProgress: func(progress float64) {
+			se.syncStatus.SetProgress(relPath, progress)
+		},
+	}
+
+	// hide io.WriterTo of src, so the content is written (and progress reported) in chunks
+	buf := make([]byte, ingestBufferSize)
+	written, err := io.CopyBuffer(io.MultiWriter(tmp, pw), struct{ io.Reader }{src}, buf)
+	if err != nil {
+		se.syncStatus.SetError(relPath, err)
+		return nil, fmt.Errorf("receive content: %w", err)
+	}
+
+	if size >= 0 && written != size {
+		err := fmt.Errorf("received %d bytes, expected %d", written, size)
+		se.syncStatus.SetError(relPath, err)
+		return nil, err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		se.syncStatus.SetError(relPath, err)
+		return nil, err
+	}
+
+	if err := tmp.Close(); err != nil {
+		se.syncStatus.SetError(relPath, err)
+		return nil, err
+	}
+	tmp = nil // avoid double close in deferred cleanup
+
+	// mark as syncing before the file becomes visible, so full sync skips it
+	se.syncStatus.SetSyncing(relPath)
+
+	if err := replaceFile(tmpPath, dst); err != nil {
+		se.syncStatus.SetError(relPath, err)
+		return nil, err
+	}
+	success = true
+
+	etag, err := calculateETag(dst)
+	if err != nil {
+		se.syncStatus.SetError(relPath, err)
+		return nil, err
+	}
+
+	// the upload compares against the file's mtime to detect changes, so record that instead of the current time
+	info, err := os.Stat(dst)
+	if err != nil {
+		se.syncStatus.SetError(relPath, err)
+		return nil, err
+	}
+
+	slog.Info("sync", "type", SyncPriority, "op", OpWriteRemote, "path", relPath, "status", "received", "size", humanize.Bytes(uint64(written)))
+
+	return &FileMetadata{
+		Path:         relPath,
+		Size:         written,
+		ETag:         etag,
+		LastModified: info.ModTime(),
+	}, nil
+}
+
+// progressWriter reports the percentage of total bytes written, at most once per whole percent
+type progressWriter struct {
+	total      int64
+	written    int64
+	reported   int64
+	onProgress func(progress float64)
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	w.written += int64(len(p))
+
+	if w.total <= 0 {
+		return len(p), nil
+	}
+
+	percent := min(w.written*int64(progressMax)/w.total, int64(progressMax))
+	if percent > w.reported {
+		w.reported = percent
+		w.onProgress(float64(percent))
+	}
+
+	return len(p), nil
+}
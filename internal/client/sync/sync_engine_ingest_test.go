@@ -0,0 +1,154 @@
+package sync
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	gosync "sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/openmined/syftbox/internal/client/workspace"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newIngestTestEngine(t *testing.T) *SyncEngine {
+	t.Helper()
+	ws, err := workspace.NewWorkspace(t.TempDir(), "user@example.com")
+	require.NoError(t, err)
+
+	ignoreList := NewSyncIgnoreList(ws.DatasitesDir, true)
+	ignoreList.Load()
+
+	return &SyncEngine{
+		workspace:  ws,
+		ignoreList: ignoreList,
+		syncStatus: NewSyncStatus(),
+		latency:    NewSyncLatency(),
+	}
+}
+
+func TestIngestReportsProgress(t *testing.T) {
+	se := newIngestTestEngine(t)
+	events := se.syncStatus.Subscribe()
+
+	content := make([]byte, 8*1024*1024)
+	_, err := rand.Read(content)
+	require.NoError(t, err)
+
+	relPath := SyncPath("user@example.com/public/large.bin")
+
+	// collect events concurrently, the subscription buffer is smaller than the number of events.
+	// slow subscribers miss events, so only the order of the received events is asserted
+	received := make(chan []*SyncStatusEvent)
+	go func() {
+		var all []*SyncStatusEvent
+		for ev := range events {
+			all = append(all, ev)
+		}
+		received <- all
+	}()
+
+	metadata, err := se.ingest(relPath, bytes.NewReader(content), int64(len(content)))
+	require.NoError(t, err)
+
+	// ready for upload, full sync must skip the path
+	assert.True(t, se.isSyncing(relPath))
+
+	se.syncStatus.Close()
+	all := <-received
+
+	assert.Equal(t, int64(len(content)), metadata.Size)
+	assert.NotEmpty(t, metadata.ETag)
+
+	written, err := os.ReadFile(se.workspace.DatasiteAbsPath(relPath.String()))
+	require.NoError(t, err)
+	assert.Equal(t, content, written)
+
+	// no temp files left behind
+	matches, err := filepath.Glob(filepath.Join(filepath.Dir(se.workspace.DatasiteAbsPath(relPath.String())), "*.syft.tmp.*"))
+	require.NoError(t, err)
+	assert.Empty(t, matches)
+
+	require.NotEmpty(t, all)
+	assert.Equal(t, SyncStatePending, all[0].Status.SyncState)
+
+	var progress []float64
+	for _, ev := range all {
+		assert.Equal(t, relPath, ev.Path)
+		if ev.Status.SyncState == SyncStatePending && ev.Status.Progress > progressMin {
+			progress = append(progress, ev.Status.Progress)
+		}
+	}
+	assert.Greater(t, len(progress), 10, "expected progress events while receiving")
+	assert.IsIncreasing(t, progress)
+	assert.LessOrEqual(t, progress[len(progress)-1], progressMax)
+}
+
+func TestIngestInvalidPath(t *testing.T) {
+	se := newIngestTestEngine(t)
+
+	_, err := se.ingest(SyncPath("user@example.com/public/file.tmp"), bytes.NewReader([]byte("data")), 4)
+	assert.ErrorIs(t, err, ErrInvalidIngestPath)
+
+	_, err = se.ingest(SyncPath("file.txt"), bytes.NewReader([]byte("data")), 4)
+	assert.ErrorIs(t, err, ErrInvalidIngestPath)
+}
+
+func TestIngestSizeMismatch(t *testing.T) {
+	se := newIngestTestEngine(t)
+	relPath := SyncPath("user@example.com/public/short.bin")
+
+	_, err := se.ingest(relPath, bytes.NewReader([]byte("data")), 10)
+	assert.Error(t, err)
+	assert.NoFileExists(t, se.workspace.DatasiteAbsPath(relPath.String()))
+
+	status, ok := se.syncStatus.GetStatus(relPath)
+	require.True(t, ok)
+	assert.Equal(t, SyncStateError, status.SyncState)
+}
+
+func TestIngestConcurrent(t *testing.T) {
+	se := newIngestTestEngine(t)
+	relPath := SyncPath("user@example.com/public/report.csv")
+
+	// the first ingest is still receiving the content when the others start
+	pr, pw := io.Pipe()
+	first := make(chan error)
+	go func() {
+		_, err := se.ingest(relPath, pr, 4)
+		first <- err
+	}()
+	require.Eventually(t, func() bool {
+		status, ok := se.syncStatus.GetStatus(relPath)
+		return ok && status.SyncState == SyncStatePending
+	}, time.Second, 10*time.Millisecond)
+
+	var wg gosync.WaitGroup
+	var rejected atomic.Int32
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := se.ingest(relPath, bytes.NewReader([]byte("late")), 4); errors.Is(err, ErrIngestInProgress) {
+				rejected.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(8), rejected.Load())
+
+	_, err := pw.Write([]byte("data"))
+	require.NoError(t, err)
+	pw.Close()
+	require.NoError(t, <-first)
+
+	content, err := os.ReadFile(se.workspace.DatasiteAbsPath(relPath.String()))
+	require.NoError(t, err)
+	assert.Equal(t, "data", string(content))
+}
@@ -0,0 +1,122 @@
+package sync
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strconv"
+
+	"github.com/dustin/go-humanize"
+	"github.com/openmined/syftbox/internal/crdt"
+	"github.com/openmined/syftbox/internal/syftsdk"
+)
+
+// handleMerges merges the remote version of the crdt files changed locally and remotely into the local one,
+// and uploads the result. A file that doesn't merge, e.g. one that isn't a valid crdt, is set aside as a conflict
+func (se *SyncEngine) handleMerges(ctx context.Context, batch BatchMerge) {
+	keys := make([]string, 0, len(batch))
+	for path, op := range batch {
+		se.syncStatus.SetSyncing(op.RelPath)
+		keys = append(keys, path.String())
+	}
+
+	tempDir, err := os.MkdirTemp("", "syftbox-crdt-*")
+	if err != nil {
+		se.setMergeErrors(batch, err)
+		return
+	}
+	defer os.RemoveAll(tempDir)
+
+	resUrls, err := se.sdk.Blob.Download(ctx, &syftsdk.PresignedParams{Keys: keys})
+	if err != nil {
+		se.setMergeErrors(batch, err)
+		return
+	}
+	for _, urlErr := range resUrls.Errors {
+		se.syncStatus.SetError(SyncPath(urlErr.Key), urlErr)
+		slog.Error("sync", "type", SyncStandard, "op", OpMerge, "path", urlErr.Key, "error", urlErr)
+	}
+
+	// the crdt files are small, a download each is fine
+	jobs := make([]*syftsdk.DownloadJob, 0, len(resUrls.URLs))
+	jobOps := make(map[string]*SyncOperation, len(resUrls.URLs))
+	for i, url := range resUrls.URLs {
+		op, ok := batch[SyncPath(url.Key)]
+		if !ok {
+			continue
+		}
+		name := strconv.Itoa(i)
+		jobOps[name] = op
+		jobs = append(jobs, &syftsdk.DownloadJob{URL: url.URL, TargetDir: tempDir, Name: name})
+	}
+	if len(jobs) == 0 {
+		return
+	}
+
+	for res := range syftsdk.Downloader(ctx, &syftsdk.DownloadOpts{Workers: se.transfer.workers(), Jobs: jobs}) {
+		op := jobOps[res.Name]
+		if res.Error != nil {
+			se.syncStatus.SetError(op.RelPath, res.Error)
+			slog.Error("sync", "type", SyncStandard, "op", OpMerge, "path", op.RelPath, "error", res.Error)
+			continue
+		}
+		se.mergeFile(ctx, op, res.DownloadPath)
+	}
+}
+
+// mergeFile merges a downloaded remote version into the local file
+func (se *SyncEngine) mergeFile(ctx context.Context, op *SyncOperation, downloadPath string) {
+	localAbsPath := se.workspace.DatasiteAbsPath(op.RelPath.String())
+
+	remote, err := os.ReadFile(downloadPath)
+	if err != nil {
+		se.syncStatus.SetError(op.RelPath, err)
+		slog.Error("sync", "type", SyncStandard, "op", OpMerge, "path", op.RelPath, "error", err)
+		return
+	}
+
+	if _, err := crdt.MergeFile(localAbsPath, remote); err != nil {
+		slog.Warn("sync", "type", SyncStandard, "op", OpMerge, "path", op.RelPath, "error", err)
+		se.handleConflicts(ctx, BatchConflict{op.RelPath: op})
+		return
+	}
+
+	info, err := os.Stat(localAbsPath)
+	if err != nil {
+		se.syncStatus.SetError(op.RelPath, err)
+		slog.Error("sync", "type", SyncStandard, "op", OpMerge, "path", op.RelPath, "error", err)
+		return
+	}
+	etag, err := calculateETag(localAbsPath)
+	if err != nil {
+		se.syncStatus.SetError(op.RelPath, err)
+		slog.Error("sync", "type", SyncStandard, "op", OpMerge, "path", op.RelPath, "error", err)
+		return
+	}
+
+	// the remote version has every change already. a read-only sync uploads nothing,
+	// the local changes are left for when it can
+	if etag == op.Remote.ETag || se.readOnly {
+		se.journal.Set(op.Remote)
+		se.syncStatus.SetCompleted(op.RelPath)
+		slog.Info("sync", "type", SyncStandard, "op", OpMerge, "path", op.RelPath, "status", "Completed", "size", humanize.Bytes(uint64(info.Size())))
+		return
+	}
+
+	slog.Info("sync", "type", SyncStandard, "op", OpMerge, "path", op.RelPath, "status", "Merged, uploading")
+	se.uploadFile(ctx, &SyncOperation{
+		Type:       OpWriteRemote,
+		RelPath:    op.RelPath,
+		Local:      &FileMetadata{Path: op.RelPath, ETag: etag, Size: info.Size(), LastModified: info.ModTime()},
+		Remote:     op.Remote,
+		LastSynced: op.LastSynced,
+		Reason:     op.Reason,
+	})
+}
+
+func (se *SyncEngine) setMergeErrors(batch BatchMerge, err error) {
+	for _, op := range batch {
+		se.syncStatus.SetError(op.RelPath, err)
+	}
+	slog.Error("sync", "type", SyncStandard, "op", OpMerge, "files", len(batch), "error", err)
+}
@@ -0,0 +1,118 @@
+package sync
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/openmined/syftbox/internal/crdt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fullSync reconciles a file of a peer against its remote state, and runs the operations like a full sync
+func fullSync(t *testing.T, se *SyncEngine, relPath SyncPath, remote *FileMetadata) *ReconcileOperations {
+	t.Helper()
+	local := make(map[SyncPath]*FileMetadata)
+	localAbsPath := se.workspace.DatasiteAbsPath(relPath.String())
+	if info, err := os.Stat(localAbsPath); err == nil {
+		etag, err := calculateETag(localAbsPath)
+		require.NoError(t, err)
+		local[relPath] = &FileMetadata{Path: relPath, ETag: etag, Size: info.Size(), LastModified: info.ModTime()}
+	}
+	remoteState := make(map[SyncPath]*FileMetadata)
+	if remote != nil {
+		remoteState[relPath] = remote
+	}
+	journal, err := se.journal.GetState()
+	require.NoError(t, err)
+
+	ops := se.reconcile(local, remoteState, journal)
+	se.executeReconcileOperations(context.Background(), ops)
+	return ops
+}
+
+func TestMergeConcurrentCounterIncrements(t *testing.T) {
+	srv := newFakeBlobServer(t)
+	alice := newPeerTestEngine(t, srv.URL, "alice@example.com", nil)
+	bob := newPeerTestEngine(t, srv.URL, "bob@example.com", nil)
+	relPath := SyncPath("alice@example.com/app_data/poll/votes" + crdt.Ext)
+
+	increment := func(se *SyncEngine, n uint64) {
+		_, err := crdt.UpdateFile(se.workspace.DatasiteAbsPath(relPath.String()), crdt.TypeGCounter, func(doc *crdt.Document) error {
+			doc.Counter.Increment(se.workspace.Owner, n)
+			return nil
+		})
+		require.NoError(t, err)
+	}
+	// the state of the server is the last upload
+	uploaded := func(se *SyncEngine) *FileMetadata {
+		meta, err := se.journal.Get(relPath)
+		require.NoError(t, err)
+		require.NotNil(t, meta)
+		return meta
+	}
+
+	increment(alice, 1)
+	fullSync(t, alice, relPath, nil)
+	remote := uploaded(alice)
+	fullSync(t, bob, relPath, remote)
+
+	// both peers increment before seeing each other's change
+	increment(alice, 2)
+	increment(bob, 3)
+
+	ops := fullSync(t, alice, relPath, remote)
+	require.Len(t, ops.RemoteWrites, 1)
+	remote = uploaded(alice)
+
+	ops = fullSync(t, bob, relPath, remote)
+	require.Len(t, ops.Merges, 1, "merged instead of a conflict")
+	assert.Empty(t, ops.Conflicts)
+	remote = uploaded(bob)
+
+	ops = fullSync(t, alice, relPath, remote)
+	require.Len(t, ops.LocalWrites, 1)
+
+	aliceContent, err := os.ReadFile(alice.workspace.DatasiteAbsPath(relPath.String()))
+	require.NoError(t, err)
+	bobContent, err := os.ReadFile(bob.workspace.DatasiteAbsPath(relPath.String()))
+	require.NoError(t, err)
+	assert.Equal(t, aliceContent, bobContent, "the peers converge")
+
+	doc, err := crdt.Parse(aliceContent)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(6), doc.Value())
+	assert.Equal(t, map[string]uint64{"alice@example.com": 3, "bob@example.com": 3}, doc.Counter.Counts)
+
+	assert.False(t, fullSync(t, alice, relPath, remote).HasChanges())
+	assert.False(t, fullSync(t, bob, relPath, remote).HasChanges())
+}
+
+func TestMergeInvalidFileConflicts(t *testing.T) {
+	srv := newFakeBlobServer(t)
+	alice := newPeerTestEngine(t, srv.URL, "alice@example.com", nil)
+	bob := newPeerTestEngine(t, srv.URL, "bob@example.com", nil)
+	relPath := SyncPath("alice@example.com/app_data/poll/voters" + crdt.Ext)
+
+	_, err := crdt.UpdateFile(alice.workspace.DatasiteAbsPath(relPath.String()), crdt.TypeORSet, func(doc *crdt.Document) error {
+		doc.Set.Add("alice@example.com", "alice@example.com")
+		return nil
+	})
+	require.NoError(t, err)
+	fullSync(t, alice, relPath, nil)
+	remote, err := alice.journal.Get(relPath)
+	require.NoError(t, err)
+
+	// not a crdt, set aside like any other conflict
+	localAbsPath := bob.workspace.DatasiteAbsPath(relPath.String())
+	require.NoError(t, os.MkdirAll(bob.workspace.DatasiteAbsPath("alice@example.com/app_data/poll"), 0o755))
+	require.NoError(t, os.WriteFile(localAbsPath, []byte("not json"), 0o644))
+
+	ops := fullSync(t, bob, relPath, remote)
+	require.Len(t, ops.Merges, 1)
+	status, ok := bob.syncStatus.GetStatus(relPath)
+	require.True(t, ok)
+	assert.Equal(t, ConflictStateConflicted, status.ConflictState)
+	assert.NoFileExists(t, localAbsPath)
+}
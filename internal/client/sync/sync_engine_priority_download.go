@@ -1,6 +1,7 @@
 package sync
 
 import (
+	"errors"
 	"log/slog"
 	"path/filepath"
 	"time"
@@ -18,6 +19,7 @@ func (se *SyncEngine) handlePriorityDownload(msg *syftmsg.Message) {
 	}
 
 	syncRelPath := SyncPath(createMsg.Path)
+	notifiedAt := time.Now()
 
 	// set sync status
 	se.syncStatus.SetSyncing(syncRelPath)
@@ -32,6 +34,15 @@ func (se *SyncEngine) handlePriorityDownload(msg *syftmsg.Message) {
 	// temporary directory for the file
 	tmpDir := filepath.Join(se.workspace.Root, ".syft-tmp")
 
+	if err := se.verifyPriorityDownload(syncRelPath, createMsg.ETag, createMsg.Content, tmpDir); errors.Is(err, ErrQuarantined) {
+		se.setQuarantined(SyncPriority, syncRelPath, err)
+		return
+	} else if err != nil {
+		se.syncStatus.SetError(syncRelPath, err)
+		slog.Error("sync", "type", SyncPriority, "op", OpWriteLocal, "msgType", msg.Type, "msgId", msg.Id, "error", err)
+		return
+	}
+
 	// write the file to the temporary directory and
 	// then move it to the local path
 	err := writeFileWithIntegrityCheck(tmpDir, localAbsPath, createMsg.Content, createMsg.ETag)
@@ -51,5 +62,6 @@ func (se *SyncEngine) handlePriorityDownload(msg *syftmsg.Message) {
 	})
 
 	// mark as completed
-	se.syncStatus.SetCompleted(syncRelPath)
+	se.setDownloaded(syncRelPath)
+	se.latency.ObserveSince(LatencyDownload, notifiedAt)
 }
@@ -18,6 +18,13 @@ func (se *SyncEngine) handlePriorityError(msg *syftmsg.Message) {
 	// handle the error
 	switch errMsg.Code {
 	case 403:
+		if se.keepRejected {
+			// leave the file in place, the full sync skips it while it's unchanged
+			slog.Warn("sync", "type", SyncPriority, "op", OpError, "msgType", msg.Type, "msgId", msg.Id, "code", errMsg.Code, "path", errMsg.Path, "status", "rejected", "reason", errMsg.Message)
+			se.syncStatus.SetRejected(syncRelPath, errMsg.Message)
+			return
+		}
+
 		// mark the file as rejected
 		localPath := se.workspace.DatasiteAbsPath(errMsg.Path)
 		if markedPath, err := SetMarker(localPath, Rejected); err != nil {
@@ -26,7 +33,7 @@ func (se *SyncEngine) handlePriorityError(msg *syftmsg.Message) {
 		} else {
 			// Successfully marked as rejected
 			slog.Warn("sync", "type", SyncPriority, "op", OpError, "msgType", msg.Type, "msgId", msg.Id, "code", errMsg.Code, "path", errMsg.Path, "movedTo", markedPath, "DEBUG_REJECTION_REASON", "priority_error_403_from_server", "DEBUG_ERROR_MESSAGE", errMsg.Message)
-			se.syncStatus.SetRejected(syncRelPath)
+			se.syncStatus.SetRejected(syncRelPath, errMsg.Message)
 		}
 	default:
 		// mark as completed for unknown error codes
@@ -1,6 +1,7 @@
 package sync
 
 import (
+	"errors"
 	"log/slog"
 	"path/filepath"
 	"time"
@@ -22,6 +23,7 @@ func (se *SyncEngine) processHttpMessage(msg *syftmsg.Message) {
 		fileName,
 	)
 	syncRelPath := SyncPath(relPath)
+	notifiedAt := time.Now()
 
 	se.syncStatus.SetSyncing(syncRelPath)
 	slog.Info("sync", "type", SyncPriority, "op", OpWriteLocal, "msgType", msg.Type, "msgId", msg.Id, "path", httpMsg.SyftURL.ToLocalPath(), "size", len(httpMsg.Body), "etag", httpMsg.Etag)
@@ -35,6 +37,15 @@ func (se *SyncEngine) processHttpMessage(msg *syftmsg.Message) {
 	// temporary directory for the file
 	tmpDir := filepath.Join(se.workspace.Root, ".syft-tmp")
 
+	if err := se.verifyPriorityDownload(syncRelPath, httpMsg.Etag, httpMsg.Body, tmpDir); errors.Is(err, ErrQuarantined) {
+		se.setQuarantined(SyncPriority, syncRelPath, err)
+		return
+	} else if err != nil {
+		se.syncStatus.SetError(syncRelPath, err)
+		slog.Error("sync", "type", SyncPriority, "op", OpWriteLocal, "msgType", msg.Type, "msgId", msg.Id, "path", httpMsg.SyftURL.ToLocalPath(), "etag", httpMsg.Etag, "error", err)
+		return
+	}
+
 	// write the RPCMsg to the file
 	err := writeFileWithIntegrityCheck(
 		tmpDir,
@@ -60,6 +71,6 @@ func (se *SyncEngine) processHttpMessage(msg *syftmsg.Message) {
 		Version:      "",
 	})
 
-	se.syncStatus.SetCompleted(syncRelPath)
-
+	se.setDownloaded(syncRelPath)
+	se.latency.ObserveSince(LatencyDownload, notifiedAt)
 }
@@ -90,6 +90,7 @@ func (se *SyncEngine) handlePriorityUpload(path string) {
 
 	// mark as completed
 	se.syncStatus.SetCompleted(syncRelPath)
+	se.latency.ObserveSince(LatencyUpload, timeNow)
 }
 
 func (se *SyncEngine) canPrioritize(path string) error {
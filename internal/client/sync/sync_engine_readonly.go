@@ -0,0 +1,31 @@
+package sync
+
+import "log/slog"
+
+// IsReadOnly reports whether sync only follows the remote changes, and never uploads
+func (se *SyncEngine) IsReadOnly() bool {
+	return se.readOnly
+}
+
+// withholdLocalChanges leaves the uploads and the remote deletes out of a read-only sync.
+// The local changes stay in place, and are overwritten or set aside as conflicts once the remote changes.
+func (se *SyncEngine) withholdLocalChanges(ops *ReconcileOperations) {
+	withheld := len(ops.RemoteWrites) + len(ops.RemoteDeletes)
+
+	for path := range ops.RemoteWrites {
+		ops.Ignored[path] = struct{}{}
+		delete(ops.RemoteWrites, path)
+	}
+	for path := range ops.RemoteDeletes {
+		ops.Ignored[path] = struct{}{}
+		delete(ops.RemoteDeletes, path)
+	}
+
+	// warn when the local changes come and go, not on every sync
+	if withheld != se.withheld {
+		if withheld > 0 {
+			slog.Warn("sync is read-only, local changes are not uploaded", "changes", withheld)
+		}
+		se.withheld = withheld
+	}
+}
@@ -0,0 +1,81 @@
+package sync
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadOnlySyncNeverUploads(t *testing.T) {
+	srv := newFakeBlobServer(t)
+
+	// count the uploads that reach the server
+	var uploads atomic.Int64
+	target, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	proxySrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/blob/upload") || strings.HasSuffix(r.URL.Path, "/blob/delete") {
+			uploads.Add(1)
+		}
+		proxy.ServeHTTP(w, r)
+	}))
+	t.Cleanup(proxySrv.Close)
+
+	alice := newPeerTestEngine(t, srv.URL, "alice@example.com", nil)
+	bob := newPeerTestEngine(t, proxySrv.URL, "bob@example.com", nil)
+	bob.readOnly = true
+
+	// alice publishes a file
+	remotePath := SyncPath("alice@example.com/public/notes.txt")
+	src := alice.workspace.DatasiteAbsPath(remotePath.String())
+	require.NoError(t, os.MkdirAll(filepath.Dir(src), 0o755))
+	require.NoError(t, os.WriteFile(src, []byte("hello bob"), 0o644))
+	info, err := os.Stat(src)
+	require.NoError(t, err)
+	etag, err := calculateETag(src)
+	require.NoError(t, err)
+	remote := &FileMetadata{Path: remotePath, ETag: etag, Size: info.Size(), LastModified: info.ModTime()}
+	alice.handleRemoteWrites(context.Background(), BatchRemoteWrite{
+		remotePath: &SyncOperation{Type: OpWriteRemote, RelPath: remotePath, Local: remote},
+	})
+
+	// while bob edits a file of his own
+	localPath := SyncPath("bob@example.com/public/draft.txt")
+	dst := bob.workspace.DatasiteAbsPath(localPath.String())
+	require.NoError(t, os.MkdirAll(filepath.Dir(dst), 0o755))
+	require.NoError(t, os.WriteFile(dst, []byte("draft"), 0o644))
+
+	localState, err := NewSyncLocalState(bob.workspace.DatasitesDir).Scan()
+	require.NoError(t, err)
+	require.Contains(t, localState, localPath)
+
+	ops := bob.reconcile(localState, map[SyncPath]*FileMetadata{remotePath: remote}, map[SyncPath]*FileMetadata{})
+	assert.Empty(t, ops.RemoteWrites)
+	assert.Empty(t, ops.RemoteDeletes)
+	assert.Contains(t, ops.Ignored, localPath)
+	assert.Contains(t, ops.LocalWrites, remotePath)
+
+	bob.executeReconcileOperations(context.Background(), ops)
+
+	content, err := os.ReadFile(bob.workspace.DatasiteAbsPath(remotePath.String()))
+	require.NoError(t, err)
+	assert.Equal(t, "hello bob", string(content), "the remote changes still apply")
+	assert.FileExists(t, dst, "the local changes are left in place")
+	assert.Zero(t, uploads.Load(), "nothing is uploaded")
+
+	// uploads through the control plane are refused too
+	_, err = bob.Ingest(context.Background(), localPath, strings.NewReader("draft"), 5)
+	assert.ErrorIs(t, err, ErrReadOnly)
+	assert.Zero(t, uploads.Load())
+}
@@ -0,0 +1,170 @@
+package sync
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/openmined/syftbox/internal/client/workspace"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newStopTestEngine(t *testing.T, shutdownTimeout time.Duration) *SyncEngine {
+	t.Helper()
+	ws, err := workspace.NewWorkspace(t.TempDir(), "user@example.com")
+	require.NoError(t, err)
+
+	journal, err := NewSyncJournal(filepath.Join(ws.MetadataDir, syncDbName))
+	require.NoError(t, err)
+	require.NoError(t, journal.Open())
+
+	se := &SyncEngine{
+		workspace:       ws,
+		journal:         journal,
+		watcher:         NewFileWatcher(ws.DatasitesDir),
+		syncStatus:      NewSyncStatus(),
+		latency:         NewSyncLatency(),
+		shutdownTimeout: shutdownTimeout,
+	}
+	se.opsCtx, se.cancelOps = context.WithCancel(context.Background())
+	return se
+}
+
+// startTestOp simulates a transfer that takes d, or returns early when aborted
+func startTestOp(se *SyncEngine, path SyncPath, d time.Duration) <-chan error {
+	result := make(chan error, 1)
+	se.syncStatus.SetSyncing(path)
+
+	se.wg.Add(1)
+	go func() {
+		defer se.wg.Done()
+		select {
+		case <-time.After(d):
+			result <- se.journal.Set(&FileMetadata{Path: path, ETag: "etag", Size: 1, LastModified: time.Now()})
+			se.syncStatus.SetCompletedAndRemove(path)
+		case <-se.opsCtx.Done():
+			result <- se.opsCtx.Err()
+		}
+	}()
+	return result
+}
+
+func TestStopDrainsActiveOperations(t *testing.T) {
+	se := newStopTestEngine(t, 5*time.Second)
+
+	path := SyncPath("user@example.com/public/file.txt")
+	result := startTestOp(se, path, 100*time.Millisecond)
+
+	require.NoError(t, se.Stop())
+	require.NoError(t, <-result, "the operation completed instead of being aborted")
+
+	// the journal write of the drained operation is persisted
+	journal, err := NewSyncJournal(filepath.Join(se.workspace.MetadataDir, syncDbName))
+	require.NoError(t, err)
+	require.NoError(t, journal.Open())
+	defer journal.Close()
+
+	meta, err := journal.Get(path)
+	require.NoError(t, err)
+	require.NotNil(t, meta)
+	assert.Equal(t, "etag", meta.ETag)
+}
+
+func TestStopAbortsAfterTimeout(t *testing.T) {
+	se := newStopTestEngine(t, 50*time.Millisecond)
+
+	result := startTestOp(se, SyncPath("user@example.com/public/slow.bin"), time.Minute)
+
+	start := time.Now()
+	require.NoError(t, se.Stop())
+	assert.Less(t, time.Since(start), forceAbortTimeout)
+	assert.ErrorIs(t, <-result, context.Canceled)
+}
+
+func TestReconcilePlan(t *testing.T) {
+	se := newPeerTestEngine(t, "http://localhost", "user@example.com", nil)
+
+	file := func(path SyncPath, etag string, size int64) *FileMetadata {
+		return &FileMetadata{Path: path, ETag: etag, Size: size, LastModified: time.Now()}
+	}
+	const (
+		created  = SyncPath("user@example.com/public/created.txt")
+		edited   = SyncPath("user@example.com/public/edited.txt")
+		incoming = SyncPath("peer@example.com/public/incoming.txt")
+		removed  = SyncPath("peer@example.com/public/removed.txt")
+		both     = SyncPath("user@example.com/public/both.txt")
+		gone     = SyncPath("user@example.com/public/gone.txt")
+		counter  = SyncPath("user@example.com/app_data/poll/votes.crdt.json")
+	)
+
+	local := map[SyncPath]*FileMetadata{
+		created: file(created, "c1", 10),
+		edited:  file(edited, "e2", 20),
+		removed: file(removed, "r1", 30),
+		both:    file(both, "b2", 40),
+		counter: file(counter, "v2", 70),
+	}
+	remote := map[SyncPath]*FileMetadata{
+		edited:   file(edited, "e1", 15),
+		incoming: file(incoming, "i1", 50),
+		both:     file(both, "b3", 45),
+		counter:  file(counter, "v3", 75),
+	}
+	journal := map[SyncPath]*FileMetadata{
+		edited:  file(edited, "e1", 15),
+		removed: file(removed, "r1", 30),
+		both:    file(both, "b1", 35),
+		gone:    file(gone, "g1", 60),
+		counter: file(counter, "v1", 65),
+	}
+
+	plan := se.reconcile(local, remote, journal).Plan()
+	assert.Equal(t, []*PlannedOp{
+		{Path: created, Direction: DirectionUpload, Size: 10, Reason: ReasonLocalCreated},
+		{Path: edited, Direction: DirectionUpload, Size: 20, Reason: ReasonLocalModified},
+		{Path: incoming, Direction: DirectionDownload, Size: 50, Reason: ReasonRemoteCreated},
+		{Path: removed, Direction: DirectionDeleteLocal, Size: 30, Reason: ReasonRemoteDeleted},
+		{Path: both, Direction: DirectionConflict, Size: 45, Reason: ReasonBothModified},
+		{Path: counter, Direction: DirectionMerge, Size: 75, Reason: ReasonBothModified},
+		{Path: gone, Direction: DirectionCleanup, Reason: ReasonBothDeleted},
+	}, plan)
+}
+
+func TestInitialSyncedOnFirstCompletedSync(t *testing.T) {
+	var failing atomic.Bool
+	failing.Store(true)
+	se := newUploadTestEngineWithHandler(t, func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"files":[]}`))
+	})
+	require.NoError(t, os.MkdirAll(se.workspace.UserDir, 0o755))
+	se.localState = NewSyncLocalState(se.workspace.DatasitesDir)
+	se.initialSynced = make(chan struct{})
+
+	// the initial sync failed, the signal stays open
+	require.ErrorContains(t, se.runFullSync(context.Background()), "get remote state")
+	select {
+	case <-se.InitialSynced():
+		t.Fatal("initial synced before a sync completed")
+	default:
+	}
+
+	// the next sync that completes closes it, and the ones after leave it be
+	failing.Store(false)
+	require.NoError(t, se.runFullSync(context.Background()))
+	require.NoError(t, se.runFullSync(context.Background()))
+	select {
+	case <-se.InitialSynced():
+	default:
+		t.Fatal("initial synced still open after a completed sync")
+	}
+}
@@ -1,5 +1,10 @@
 package sync
 
+import (
+	"maps"
+	"slices"
+)
+
 const (
 	SyncPriority = "Priority"
 	SyncStandard = "Standard"
@@ -20,6 +25,9 @@ type BatchRemoteWrite = map[SyncPath]*SyncOperation
 // BatchConflict represents a collection of sync operations where conflicts were detected.
 type BatchConflict = map[SyncPath]*SyncOperation
 
+// BatchMerge represents a collection of sync operations for crdt files changed locally and remotely, which are merged.
+type BatchMerge = map[SyncPath]*SyncOperation
+
 // BatchUnchanged represents a set of paths that were compared and found to be unchanged.
 type BatchUnchanged = map[SyncPath]struct{}
 
@@ -37,6 +45,7 @@ type ReconcileOperations struct {
 	LocalDeletes   BatchLocalDelete
 	RemoteDeletes  BatchRemoteDelete
 	Conflicts      BatchConflict
+	Merges         BatchMerge
 	UnchangedPaths BatchUnchanged
 	Cleanups       BatchCleanups
 	Ignored        BatchIgnored
@@ -51,6 +60,7 @@ func NewReconcileOperations() *ReconcileOperations {
 		LocalDeletes:   make(BatchLocalDelete),
 		RemoteDeletes:  make(BatchRemoteDelete),
 		Conflicts:      make(BatchConflict),
+		Merges:         make(BatchMerge),
 		UnchangedPaths: make(BatchUnchanged),
 		Cleanups:       make(BatchCleanups),
 		Ignored:        make(BatchIgnored),
@@ -66,5 +76,63 @@ func (r *ReconcileOperations) HasChanges() bool {
 		len(r.LocalDeletes) > 0 ||
 		len(r.RemoteDeletes) > 0 ||
 		len(r.Conflicts) > 0 ||
+		len(r.Merges) > 0 ||
 		len(r.Cleanups) > 0
 }
+
+// Directions of the planned operations
+const (
+	DirectionUpload       = "upload"
+	DirectionDownload     = "download"
+	DirectionDeleteRemote = "delete remote"
+	DirectionDeleteLocal  = "delete local"
+	DirectionConflict     = "conflict"
+	DirectionMerge        = "merge"
+	DirectionCleanup      = "cleanup journal"
+)
+
+// PlannedOp is an operation of a full sync, as reported by a dry run
+type PlannedOp struct {
+	Path      SyncPath `json:"path"`
+	Direction string   `json:"direction"`
+	Size      int64    `json:"size"` // size of the file that is transferred or deleted
+	Reason    string   `json:"reason"`
+}
+
+// Plan lists the operations in the order a report shows them, by direction and then by path
+func (r *ReconcileOperations) Plan() []*PlannedOp {
+	plan := make([]*PlannedOp, 0, len(r.RemoteWrites)+len(r.LocalWrites)+len(r.RemoteDeletes)+len(r.LocalDeletes)+len(r.Conflicts)+len(r.Merges)+len(r.Cleanups))
+
+	// the size is of the side the operation reads from, or of the file it deletes
+	for _, batch := range []struct {
+		direction string
+		ops       map[SyncPath]*SyncOperation
+		local     bool
+	}{
+		{DirectionUpload, r.RemoteWrites, true},
+		{DirectionDownload, r.LocalWrites, false},
+		{DirectionDeleteRemote, r.RemoteDeletes, false},
+		{DirectionDeleteLocal, r.LocalDeletes, true},
+		{DirectionConflict, r.Conflicts, false},
+		{DirectionMerge, r.Merges, false},
+	} {
+		for _, path := range slices.Sorted(maps.Keys(batch.ops)) {
+			op := batch.ops[path]
+			meta := op.Remote
+			if batch.local {
+				meta = op.Local
+			}
+			planned := &PlannedOp{Path: path, Direction: batch.direction, Reason: op.Reason}
+			if meta != nil {
+				planned.Size = meta.Size
+			}
+			plan = append(plan, planned)
+		}
+	}
+
+	for _, path := range slices.Sorted(maps.Keys(r.Cleanups)) {
+		plan = append(plan, &PlannedOp{Path: path, Direction: DirectionCleanup, Reason: ReasonBothDeleted})
+	}
+
+	return plan
+}
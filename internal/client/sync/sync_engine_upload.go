@@ -5,16 +5,25 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
-	"sync"
+	"os"
+	"slices"
 	"time"
 
 	"github.com/dustin/go-humanize"
+	"github.com/openmined/syftbox/internal/fileattr"
 	"github.com/openmined/syftbox/internal/syftsdk"
 	"github.com/openmined/syftbox/internal/utils"
 )
 
+const (
+	// how many times a file that changed during its upload is uploaded again, before leaving it to the next sync.
+	// bounds the uploads of files that are constantly being written to.
+	DefaultUploadRequeue = 3
+)
+
 var (
-	maxUploadConcurrency = 8
+	ErrFileChangedDuringUpload = errors.New("file changed during upload")
+	ErrMissingFromBatch        = errors.New("file missing from the batch response")
 )
 
 // upload
@@ -28,127 +37,318 @@ func (se *SyncEngine) handleRemoteWrites(ctx context.Context, batch BatchRemoteW
 		se.syncStatus.SetSyncing(op.RelPath)
 	}
 
-	processUpload := func(ctx context.Context, op *SyncOperation) {
-		if op.Local.Size == 0 {
-			slog.Debug("sync", "type", SyncStandard, "op", OpSkipped, "reason", "empty contents", "path", op.RelPath)
-			se.syncStatus.SetCompleted(op.RelPath)
-			return
+	// small files are uploaded in batches, the others on their own
+	transfers := make([]func(ctx context.Context), 0, len(batch))
+	small := make([]*SyncOperation, 0)
+	for _, op := range batch {
+		if se.batched(op.RelPath.String(), op.Local.Size) {
+			small = append(small, op)
+			continue
 		}
+		transfers = append(transfers, func(ctx context.Context) { se.uploadFile(ctx, op) })
+	}
+	for ops := range slices.Chunk(small, se.transfer.batchSize()) {
+		transfers = append(transfers, func(ctx context.Context) { se.uploadFiles(ctx, ops) })
+	}
 
-		if changed, err := se.journal.ContentsChanged(op.RelPath, op.Local.ETag); err != nil {
-			slog.Warn("journal check", "error", err)
-		} else if !changed {
-			slog.Debug("sync", "type", SyncStandard, "op", OpSkipped, "reason", "contents unchanged", "path", op.RelPath)
-			se.syncStatus.SetCompleted(op.RelPath)
-			return
+	se.runTransfers(ctx, transfers)
+}
+
+// prepareUpload checks if the file of the operation still has to be uploaded, and returns its path on disk
+func (se *SyncEngine) prepareUpload(op *SyncOperation) (string, bool) {
+	if op.Local.Size == 0 {
+		slog.Debug("sync", "type", SyncStandard, "op", OpSkipped, "reason", "empty contents", "path", op.RelPath)
+		se.syncStatus.SetCompleted(op.RelPath)
+		return "", false
+	}
+
+	if changed, err := se.journal.ContentsChanged(op.RelPath, op.Local.ETag); err != nil {
+		slog.Warn("journal check", "error", err)
+	} else if !changed {
+		slog.Debug("sync", "type", SyncStandard, "op", OpSkipped, "reason", "contents unchanged", "path", op.RelPath)
+		se.syncStatus.SetCompleted(op.RelPath)
+		return "", false
+	}
+
+	localAbsPath := se.workspace.DatasiteAbsPath(op.RelPath.String())
+	if !utils.FileExists(localAbsPath) {
+		slog.Debug("sync", "type", SyncStandard, "op", OpSkipped, "reason", "file no longer exists", "path", op.RelPath)
+		se.syncStatus.SetCompleted(op.RelPath)
+		return "", false
+	}
+
+	if !se.workspace.IsValidPath(op.RelPath.String()) {
+		slog.Error("sync", "type", SyncStandard, "op", OpWriteRemote, "path", op.RelPath, "error", "invalid datasite path", "DEBUG_REJECTION_REASON", "IsValidPath_check_failed")
+		markedPath, markErr := SetMarker(localAbsPath, Rejected)
+		if markErr != nil {
+			slog.Error("sync", "type", SyncStandard, "op", OpWriteRemote, "path", op.RelPath, "error", markErr)
+			se.syncStatus.SetError(op.RelPath, markErr)
+		} else {
+			slog.Warn("sync", "type", SyncStandard, "op", OpWriteRemote, "path", op.RelPath, "movedTo", markedPath, "DEBUG_REJECTION_REASON", "IsValidPath_check_failed")
+			se.syncStatus.SetRejected(op.RelPath, "invalid datasite path")
 		}
+		se.journal.Delete(op.RelPath)
+		return "", false
+	}
+
+	return localAbsPath, true
+}
+
+// maxUploadRequeue is how many times a file that changed during its upload is uploaded again
+func (se *SyncEngine) maxUploadRequeue() int {
+	if se.requeue == 0 {
+		return DefaultUploadRequeue
+	}
+	return max(se.requeue, 0)
+}
 
-		localAbsPath := se.workspace.DatasiteAbsPath(op.RelPath.String())
-		if !utils.FileExists(localAbsPath) {
-			slog.Debug("sync", "type", SyncStandard, "op", OpSkipped, "reason", "file no longer exists", "path", op.RelPath)
-			se.syncStatus.SetCompleted(op.RelPath)
+// uploadFile uploads the file of the operation on its own
+func (se *SyncEngine) uploadFile(ctx context.Context, op *SyncOperation) {
+	localAbsPath, ok := se.prepareUpload(op)
+	if !ok {
+		return
+	}
+
+	var res *syftsdk.UploadResponse
+	for requeue := 0; ; requeue++ {
+		before, err := os.Stat(localAbsPath)
+		if err != nil {
+			se.syncStatus.SetError(op.RelPath, err)
+			slog.Error("sync", "type", SyncStandard, "op", OpWriteRemote, "path", op.RelPath, "error", err)
 			return
 		}
 
-		if !se.workspace.IsValidPath(op.RelPath.String()) {
-			slog.Error("sync", "type", SyncStandard, "op", OpWriteRemote, "path", op.RelPath, "error", "invalid datasite path", "DEBUG_REJECTION_REASON", "IsValidPath_check_failed")
-			markedPath, markErr := SetMarker(localAbsPath, Rejected)
-			if markErr != nil {
-				slog.Error("sync", "type", SyncStandard, "op", OpWriteRemote, "path", op.RelPath, "error", markErr)
-				se.syncStatus.SetError(op.RelPath, markErr)
-			} else {
-				slog.Warn("sync", "type", SyncStandard, "op", OpWriteRemote, "path", op.RelPath, "movedTo", markedPath, "DEBUG_REJECTION_REASON", "IsValidPath_check_failed")
-				se.syncStatus.SetRejected(op.RelPath)
+		// changed since it was scanned, upload what's on disk now
+		if fileChanged(op.Local, before) {
+			if err := refreshMetadata(op.Local, localAbsPath, before); err != nil {
+				se.syncStatus.SetError(op.RelPath, err)
+				slog.Error("sync", "type", SyncStandard, "op", OpWriteRemote, "path", op.RelPath, "error", err)
+				return
 			}
-			se.journal.Delete(op.RelPath)
-			return
 		}
 
-		res, err := se.sdk.Blob.Upload(ctx, &syftsdk.UploadParams{
+		res, err = se.sdk.Blob.Upload(ctx, &syftsdk.UploadParams{
 			Key:      op.RelPath.String(),
 			FilePath: localAbsPath,
+			ETag:     op.Local.ETag,
+			Attrs:    se.readAttrs(op, localAbsPath),
 			Callback: func(uploadedBytes int64, totalBytes int64) {
-				progress := float64(uploadedBytes) / float64(totalBytes)
+				progress := float64(uploadedBytes) / float64(totalBytes) * progressMax
 				se.syncStatus.SetProgress(op.RelPath, progress)
-				slog.Debug("sync", "type", SyncStandard, "op", OpWriteRemote, "path", op.RelPath, "progress", fmt.Sprintf("%.2f%%", progress*100.0))
+				slog.Debug("sync", "type", SyncStandard, "op", OpWriteRemote, "path", op.RelPath, "progress", fmt.Sprintf("%.2f%%", progress))
 			},
 		})
+		if err != nil {
+			se.handleUploadError(op, localAbsPath, err)
+			return // return on ANY error
+		}
+
+		// a write during the upload may have been read half-way. upload the final state again.
+		// if the file is gone, the next sync takes care of it
+		after, err := os.Stat(localAbsPath)
+		if err != nil || !fileChanged(op.Local, after) {
+			break
+		}
+
+		if requeue >= se.maxUploadRequeue() {
+			// don't journal the upload, so that the next sync uploads the file again
+			se.syncStatus.SetError(op.RelPath, ErrFileChangedDuringUpload)
+			slog.Warn("sync", "type", SyncStandard, "op", OpWriteRemote, "path", op.RelPath, "error", ErrFileChangedDuringUpload, "requeues", requeue)
+			return
+		}
+
+		slog.Info("sync", "type", SyncStandard, "op", OpWriteRemote, "path", op.RelPath, "status", "changed during upload, requeued", "requeue", requeue+1)
+	}
 
+	se.completeUpload(op, res)
+}
+
+// uploadFiles uploads the small files of the operations in a single batch.
+// Files that changed during the upload are uploaded again on their own, and so is
+// the whole batch if the server doesn't support batches
+func (se *SyncEngine) uploadFiles(ctx context.Context, ops []*SyncOperation) {
+	files := make([]*syftsdk.UploadParams, 0, len(ops))
+	pending := make(map[string]*SyncOperation, len(ops))
+	for _, op := range ops {
+		localAbsPath, ok := se.prepareUpload(op)
+		if !ok {
+			continue
+		}
+
+		info, err := os.Stat(localAbsPath)
 		if err != nil {
-			var sdkErr syftsdk.SDKError
-			if errors.As(err, &sdkErr) {
-				switch sdkErr.ErrorCode() {
-				case syftsdk.CodeAccessDenied, syftsdk.CodeDatasiteInvalidPath:
-					// not allowed to upload/write the file.
-					// 1. mark as rejected
-					// 2. delete from journal
-					// 3. need to pull the previous version again
-					if markedPath, markErr := SetMarker(localAbsPath, Rejected); markErr != nil {
-						// Failed to mark as rejected, set error state
-						se.syncStatus.SetError(op.RelPath, markErr)
-						slog.Error("sync", "type", SyncStandard, "op", OpWriteRemote, "path", op.RelPath, "error", markErr, "DEBUG_REJECTION_REASON", "SetMarker_failed")
-					} else {
-						// Successfully marked as rejected
-						slog.Error("sync", "type", SyncStandard, "op", OpWriteRemote, "path", op.RelPath, "error", sdkErr, "movedTo", markedPath, "DEBUG_REJECTION_REASON", fmt.Sprintf("server_error_code_%s", sdkErr.ErrorCode()), "DEBUG_SERVER_ERROR", sdkErr.Error())
-						se.syncStatus.SetRejected(op.RelPath)
-					}
-					se.journal.Delete(op.RelPath)
-				default:
-					// this can be http timeouts or other retryable errors
-					se.syncStatus.SetError(op.RelPath, sdkErr)
-					slog.Error("sync", "type", SyncStandard, "op", OpWriteRemote, "path", op.RelPath, "error", sdkErr)
-				}
-			} else {
+			se.syncStatus.SetError(op.RelPath, err)
+			slog.Error("sync", "type", SyncStandard, "op", OpWriteRemote, "path", op.RelPath, "error", err)
+			continue
+		}
+		if fileChanged(op.Local, info) {
+			if err := refreshMetadata(op.Local, localAbsPath, info); err != nil {
 				se.syncStatus.SetError(op.RelPath, err)
 				slog.Error("sync", "type", SyncStandard, "op", OpWriteRemote, "path", op.RelPath, "error", err)
+				continue
 			}
-			return // return on ANY error
 		}
 
-		lastModified, err := time.Parse(time.RFC3339, res.LastModified)
-		if err != nil {
-			lastModified = time.Now()
-		}
-		slog.Info("sync", "type", SyncStandard, "op", OpWriteRemote, "path", op.RelPath, "size", humanize.Bytes(uint64(res.Size)))
-		se.journal.Set(&FileMetadata{
-			Path:         op.RelPath,
-			ETag:         res.ETag,
-			Size:         res.Size,
-			LastModified: lastModified,
+		files = append(files, &syftsdk.UploadParams{
+			Key:      op.RelPath.String(),
+			FilePath: localAbsPath,
+			ETag:     op.Local.ETag,
+			Attrs:    se.readAttrs(op, localAbsPath),
 		})
+		pending[op.RelPath.String()] = op
+	}
 
-		// mark as completed on success
-		se.syncStatus.SetCompleted(op.RelPath)
+	if len(files) == 0 {
+		return
 	}
 
-	var wg sync.WaitGroup
-	opsChan := make(chan *SyncOperation, len(batch))
-
-	// Start worker goroutines
-	wg.Add(maxUploadConcurrency)
-	for range maxUploadConcurrency {
-		go func() {
-			defer wg.Done()
-			for {
-				select {
-				case <-ctx.Done():
-					return // Context cancelled
-				case op, ok := <-opsChan:
-					if !ok {
-						return // Channel closed
-					}
-					processUpload(ctx, op)
-				}
+	res, err := se.sdk.Blob.UploadBatch(ctx, &syftsdk.UploadBatchParams{Files: files})
+	if err != nil {
+		for _, file := range files {
+			op := pending[file.Key]
+			if se.batchFailed(err) {
+				se.uploadFile(ctx, op)
+			} else {
+				se.handleUploadError(op, file.FilePath, err)
 			}
-		}()
+		}
+		return
 	}
 
-	// Send operations to the channel
-	for _, op := range batch {
-		opsChan <- op
+	for _, blobErr := range res.Errors {
+		if op, ok := pending[blobErr.Key]; ok {
+			delete(pending, blobErr.Key)
+			se.handleUploadError(op, se.workspace.DatasiteAbsPath(blobErr.Key), blobErr)
+		}
+	}
+
+	for _, uploaded := range res.Uploaded {
+		op, ok := pending[uploaded.Key]
+		if !ok {
+			continue
+		}
+		delete(pending, uploaded.Key)
+
+		// written during the upload, upload the final state on its own
+		localAbsPath := se.workspace.DatasiteAbsPath(uploaded.Key)
+		if after, err := os.Stat(localAbsPath); err == nil && fileChanged(op.Local, after) {
+			se.uploadFile(ctx, op)
+			continue
+		}
+
+		se.completeUpload(op, uploaded)
+	}
+
+	// e.g. stored under another key by the server, the next sync sorts them out
+	for key, op := range pending {
+		se.syncStatus.SetError(op.RelPath, ErrMissingFromBatch)
+		slog.Error("sync", "type", SyncStandard, "op", OpWriteRemote, "path", key, "error", ErrMissingFromBatch)
+	}
+}
+
+// readAttrs reads the file attributes to upload along with the file
+func (se *SyncEngine) readAttrs(op *SyncOperation, localAbsPath string) *fileattr.Attrs {
+	attrs, err := fileattr.Read(localAbsPath, se.fileAttrs)
+	if err != nil {
+		// the content still syncs without its attributes
+		slog.Warn("sync", "type", SyncStandard, "op", OpWriteRemote, "path", op.RelPath, "error", fmt.Errorf("file attributes: %w", err))
+	}
+	return attrs
+}
+
+// completeUpload journals the upload and marks the file as synced
+func (se *SyncEngine) completeUpload(op *SyncOperation, res *syftsdk.UploadResponse) {
+	lastModified, err := time.Parse(time.RFC3339, res.LastModified)
+	if err != nil {
+		lastModified = time.Now()
+	}
+	slog.Info("sync", "type", SyncStandard, "op", OpWriteRemote, "path", op.RelPath, "size", humanize.Bytes(uint64(res.Size)))
+	se.journal.Set(&FileMetadata{
+		Path:         op.RelPath,
+		ETag:         res.ETag,
+		Size:         res.Size,
+		LastModified: lastModified,
+	})
+
+	// mark as completed on success. a reject kept in place is resolved by the upload going through
+	if se.keepRejected && se.isRejected(op.RelPath) {
+		se.syncStatus.SetCompletedAndRemove(op.RelPath)
+	} else {
+		se.syncStatus.SetCompleted(op.RelPath)
+	}
+	se.latency.ObserveSince(LatencyUpload, op.DetectedAt)
+}
+
+// handleUploadError rejects the file if the server doesn't allow the write, or sets the error state for a retry
+func (se *SyncEngine) handleUploadError(op *SyncOperation, localAbsPath string, err error) {
+	var sdkErr syftsdk.SDKError
+	if errors.As(err, &sdkErr) {
+		switch sdkErr.ErrorCode() {
+		case syftsdk.CodeAccessDenied, syftsdk.CodeDatasiteInvalidPath, syftsdk.CodeACLInvalid:
+			se.rejectWrite(op.RelPath, localAbsPath, sdkErr.ErrorMessage())
+		case syftsdk.CodeDatasiteKeyNotNFC:
+			// moving it aside wouldn't help, the file must be renamed to the NFC form of its name
+			slog.Error("sync", "type", SyncStandard, "op", OpWriteRemote, "path", op.RelPath, "status", "rejected", "reason", sdkErr.ErrorMessage())
+			se.syncStatus.SetRejected(op.RelPath, "name not in Unicode NFC, rename the file: "+sdkErr.ErrorMessage())
+		default:
+			// this can be http timeouts or other retryable errors
+			se.syncStatus.SetError(op.RelPath, sdkErr)
+			slog.Error("sync", "type", SyncStandard, "op", OpWriteRemote, "path", op.RelPath, "error", sdkErr)
+		}
+	} else {
+		se.syncStatus.SetError(op.RelPath, err)
+		slog.Error("sync", "type", SyncStandard, "op", OpWriteRemote, "path", op.RelPath, "error", err)
+	}
+}
+
+// rejectWrite handles a write the server doesn't allow, e.g. after the write access was revoked.
+// the file is not uploaded again until it changes.
+func (se *SyncEngine) rejectWrite(path SyncPath, localAbsPath string, reason string) {
+	if se.keepRejected {
+		// leave the file and the journal as they are, the file is skipped while it's unchanged
+		slog.Error("sync", "type", SyncStandard, "op", OpWriteRemote, "path", path, "status", "rejected", "reason", reason)
+		se.syncStatus.SetRejected(path, reason)
+		return
+	}
+
+	// 1. mark as rejected
+	// 2. delete from journal
+	// 3. need to pull the previous version again
+	if markedPath, markErr := SetMarker(localAbsPath, Rejected); markErr != nil {
+		// Failed to mark as rejected, set error state
+		se.syncStatus.SetError(path, markErr)
+		slog.Error("sync", "type", SyncStandard, "op", OpWriteRemote, "path", path, "error", markErr, "DEBUG_REJECTION_REASON", "SetMarker_failed")
+	} else {
+		// Successfully marked as rejected
+		slog.Error("sync", "type", SyncStandard, "op", OpWriteRemote, "path", path, "status", "rejected", "reason", reason, "movedTo", markedPath, "DEBUG_REJECTION_REASON", "server_rejected_write")
+		se.syncStatus.SetRejected(path, reason)
+	}
+	se.journal.Delete(path)
+}
+
+// isRejectedUnchanged reports whether the file was rejected by the server and hasn't changed since
+func (se *SyncEngine) isRejectedUnchanged(path SyncPath, local *FileMetadata) bool {
+	status, ok := se.syncStatus.GetStatus(path)
+	if !ok || status.ConflictState != ConflictStateRejected || local == nil {
+		return false
+	}
+	return !local.LastModified.After(status.LastUpdated)
+}
+
+// fileChanged checks if the file on disk is no longer the one described by the metadata
+func fileChanged(metadata *FileMetadata, info os.FileInfo) bool {
+	return metadata.Size != info.Size() || !metadata.LastModified.Equal(info.ModTime())
+}
+
+// refreshMetadata updates the metadata to the current state of the file
+func refreshMetadata(metadata *FileMetadata, path string, info os.FileInfo) error {
+	etag, err := calculateETag(path)
+	if err != nil {
+		return err
 	}
-	close(opsChan) // Close channel to signal no more operations
 
-	// Wait for all worker goroutines to finish processing
-	wg.Wait()
+	metadata.ETag = etag
+	metadata.Size = info.Size()
+	metadata.LastModified = info.ModTime()
+	return nil
 }
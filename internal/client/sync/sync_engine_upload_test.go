@@ -0,0 +1,279 @@
+package sync
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/openmined/syftbox/internal/syftsdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newUploadTestEngine returns an engine that uploads to a server calling onUpload with every received body
+func newUploadTestEngine(t *testing.T, onUpload func(body []byte)) *SyncEngine {
+	t.Helper()
+	return newUploadTestEngineWithHandler(t, func(w http.ResponseWriter, r *http.Request) {
+		file, _, err := r.FormFile("file")
+		require.NoError(t, err)
+		body, err := io.ReadAll(file)
+		require.NoError(t, err)
+
+		onUpload(body)
+
+		sum := md5.Sum(body)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(&syftsdk.UploadResponse{
+			Key:          r.URL.Query().Get("key"),
+			ETag:         hex.EncodeToString(sum[:]),
+			Size:         int64(len(body)),
+			LastModified: time.Now().UTC().Format(time.RFC3339),
+		})
+	})
+}
+
+// newDeniedUploadTestEngine returns an engine that uploads to a server denying every write
+func newDeniedUploadTestEngine(t *testing.T, uploads *int) *SyncEngine {
+	t.Helper()
+	return newUploadTestEngineWithHandler(t, func(w http.ResponseWriter, r *http.Request) {
+		*uploads++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(syftsdk.NewAPIError(syftsdk.CodeAccessDenied, "no write access"))
+	})
+}
+
+func newUploadTestEngineWithHandler(t *testing.T, handler http.HandlerFunc) *SyncEngine {
+	t.Helper()
+	se := newIngestTestEngine(t)
+
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	sdk, err := syftsdk.New(&syftsdk.SyftSDKConfig{
+		BaseURL:      srv.URL,
+		Email:        "user@example.com",
+		RefreshToken: "token",
+	})
+	require.NoError(t, err)
+
+	journal, err := NewSyncJournal(filepath.Join(t.TempDir(), "journal.db"))
+	require.NoError(t, err)
+	require.NoError(t, journal.Open())
+	t.Cleanup(func() { journal.Close() })
+
+	se.sdk = sdk
+	se.journal = journal
+	se.transfer = &TransferOptions{BatchThreshold: -1} // the handler takes the files one at a time
+	return se
+}
+
+// writeLocalFile writes content and returns the metadata a local scan would produce
+func writeLocalFile(t *testing.T, se *SyncEngine, relPath SyncPath, content string, modTime time.Time) *FileMetadata {
+	t.Helper()
+	path := se.workspace.DatasiteAbsPath(relPath.String())
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+
+	etag, err := calculateETag(path)
+	require.NoError(t, err)
+
+	return &FileMetadata{
+		Path:         relPath,
+		ETag:         etag,
+		Size:         int64(len(content)),
+		LastModified: modTime,
+	}
+}
+
+func TestUploadRequeuesFileChangedDuringUpload(t *testing.T) {
+	relPath := SyncPath("user@example.com/public/notes.txt")
+	modTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	var se *SyncEngine
+	var uploads []string
+	se = newUploadTestEngine(t, func(body []byte) {
+		uploads = append(uploads, string(body))
+		if len(uploads) == 1 {
+			// written while the first upload is in flight
+			writeLocalFile(t, se, relPath, "final content", modTime.Add(time.Second))
+		}
+	})
+
+	metadata := writeLocalFile(t, se, relPath, "first content", modTime)
+	se.handleRemoteWrites(context.Background(), BatchRemoteWrite{
+		relPath: &SyncOperation{Type: OpWriteRemote, RelPath: relPath, Local: metadata},
+	})
+
+	assert.Equal(t, []string{"first content", "final content"}, uploads)
+
+	journaled, err := se.journal.Get(relPath)
+	require.NoError(t, err)
+	require.NotNil(t, journaled)
+	sum := md5.Sum([]byte("final content"))
+	assert.Equal(t, hex.EncodeToString(sum[:]), journaled.ETag)
+
+	// completed clean files are no longer tracked
+	_, tracked := se.syncStatus.GetStatus(relPath)
+	assert.False(t, tracked)
+}
+
+func TestUploadGivesUpOnConstantlyChangingFile(t *testing.T) {
+	relPath := SyncPath("user@example.com/public/app.log")
+	modTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	var se *SyncEngine
+	uploads := 0
+	se = newUploadTestEngine(t, func(body []byte) {
+		uploads++
+		writeLocalFile(t, se, relPath, fmt.Sprintf("line %d", uploads), modTime.Add(time.Duration(uploads)*time.Second))
+	})
+
+	metadata := writeLocalFile(t, se, relPath, "line 0", modTime)
+	se.handleRemoteWrites(context.Background(), BatchRemoteWrite{
+		relPath: &SyncOperation{Type: OpWriteRemote, RelPath: relPath, Local: metadata},
+	})
+
+	assert.Equal(t, DefaultUploadRequeue+1, uploads)
+
+	// not journaled, so the next sync uploads it again
+	journaled, err := se.journal.Get(relPath)
+	require.NoError(t, err)
+	assert.Nil(t, journaled)
+
+	status, ok := se.syncStatus.GetStatus(relPath)
+	require.True(t, ok)
+	assert.Equal(t, SyncStateError, status.SyncState)
+	assert.ErrorIs(t, status.Error, ErrFileChangedDuringUpload)
+}
+
+func TestUploadRequeueConfigured(t *testing.T) {
+	for _, tc := range []struct {
+		requeue int
+		uploads int
+	}{
+		{requeue: 0, uploads: DefaultUploadRequeue + 1},
+		{requeue: 1, uploads: 2},
+		{requeue: -1, uploads: 1},
+	} {
+		t.Run(fmt.Sprint(tc.requeue), func(t *testing.T) {
+			relPath := SyncPath("user@example.com/public/app.log")
+			modTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+			var se *SyncEngine
+			uploads := 0
+			se = newUploadTestEngine(t, func(body []byte) {
+				uploads++
+				writeLocalFile(t, se, relPath, fmt.Sprintf("line %d", uploads), modTime.Add(time.Duration(uploads)*time.Second))
+			})
+			se.requeue = tc.requeue
+
+			metadata := writeLocalFile(t, se, relPath, "line 0", modTime)
+			se.handleRemoteWrites(context.Background(), BatchRemoteWrite{
+				relPath: &SyncOperation{Type: OpWriteRemote, RelPath: relPath, Local: metadata},
+			})
+
+			assert.Equal(t, tc.uploads, uploads)
+		})
+	}
+}
+
+func TestUploadDeniedIsRejected(t *testing.T) {
+	relPath := SyncPath("user@example.com/public/notes.txt")
+	modTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	uploads := 0
+	se := newDeniedUploadTestEngine(t, &uploads)
+
+	metadata := writeLocalFile(t, se, relPath, "not allowed", modTime)
+	se.handleRemoteWrites(context.Background(), BatchRemoteWrite{
+		relPath: &SyncOperation{Type: OpWriteRemote, RelPath: relPath, Local: metadata},
+	})
+	assert.Equal(t, 1, uploads)
+
+	status, ok := se.syncStatus.GetStatus(relPath)
+	require.True(t, ok)
+	assert.Equal(t, ConflictStateRejected, status.ConflictState)
+	assert.Equal(t, "no write access", status.Reason)
+	assert.Zero(t, status.ErrorCount, "a reject is not an error to retry")
+
+	// moved aside, so the next sync has nothing to upload
+	assert.NoFileExists(t, se.workspace.DatasiteAbsPath(relPath.String()))
+	assert.True(t, RejectedFileExists(se.workspace.DatasiteAbsPath(relPath.String())))
+
+	journaled, err := se.journal.Get(relPath)
+	require.NoError(t, err)
+	assert.Nil(t, journaled)
+}
+
+func TestUploadNotNFCKeptInPlace(t *testing.T) {
+	relPath := SyncPath("user@example.com/public/cafe\u0301.txt")
+	modTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	uploads := 0
+	se := newUploadTestEngineWithHandler(t, func(w http.ResponseWriter, r *http.Request) {
+		uploads++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(syftsdk.NewAPIError(syftsdk.CodeDatasiteKeyNotNFC, "invalid key: not in Unicode NFC"))
+	})
+
+	metadata := writeLocalFile(t, se, relPath, "accented", modTime)
+	se.handleRemoteWrites(context.Background(), BatchRemoteWrite{
+		relPath: &SyncOperation{Type: OpWriteRemote, RelPath: relPath, Local: metadata},
+	})
+	assert.Equal(t, 1, uploads)
+
+	status, ok := se.syncStatus.GetStatus(relPath)
+	require.True(t, ok)
+	assert.Equal(t, ConflictStateRejected, status.ConflictState)
+	assert.Contains(t, status.Reason, "rename the file")
+
+	// left for the user to rename, not moved aside
+	assert.FileExists(t, se.workspace.DatasiteAbsPath(relPath.String()))
+	assert.False(t, RejectedFileExists(se.workspace.DatasiteAbsPath(relPath.String())))
+}
+
+func TestUploadDeniedKeptInPlace(t *testing.T) {
+	relPath := SyncPath("user@example.com/public/notes.txt")
+	modTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	uploads := 0
+	se := newDeniedUploadTestEngine(t, &uploads)
+	se.keepRejected = true
+
+	metadata := writeLocalFile(t, se, relPath, "not allowed", modTime)
+	se.handleRemoteWrites(context.Background(), BatchRemoteWrite{
+		relPath: &SyncOperation{Type: OpWriteRemote, RelPath: relPath, Local: metadata},
+	})
+	assert.Equal(t, 1, uploads)
+	assert.FileExists(t, se.workspace.DatasiteAbsPath(relPath.String()))
+
+	status, ok := se.syncStatus.GetStatus(relPath)
+	require.True(t, ok)
+	assert.Equal(t, ConflictStateRejected, status.ConflictState)
+	assert.Equal(t, "no write access", status.Reason)
+
+	// not uploaded again while unchanged, and still reported
+	noRemote := map[SyncPath]*FileMetadata{}
+	result := se.reconcile(map[SyncPath]*FileMetadata{relPath: metadata}, noRemote, noRemote)
+	assert.Empty(t, result.RemoteWrites)
+	assert.Contains(t, result.Ignored, relPath)
+	se.cleanupResolvedMarkers()
+	assert.Equal(t, 1, se.syncStatus.GetRejectedFileCount())
+
+	// an edit is uploaded again
+	edited := writeLocalFile(t, se, relPath, "try again", time.Now().Add(time.Minute))
+	result = se.reconcile(map[SyncPath]*FileMetadata{relPath: edited}, noRemote, noRemote)
+	assert.Contains(t, result.RemoteWrites, relPath)
+}
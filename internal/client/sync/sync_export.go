@@ -0,0 +1,189 @@
+package sync
+
+import (
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/openmined/syftbox/internal/client/workspace"
+	"github.com/openmined/syftbox/internal/utils"
+)
+
+const exportTmpPattern = ".syft.tmp."
+
+var errNotSynced = errors.New("local file differs from the synced version")
+
+// SyncExport keeps a plain copy of the synced datasites in a directory outside the workspace,
+// for external tools that shouldn't deal with the daemon's layout.
+// Only files as they were last synced are exported: no markers, temp files or journal,
+// and local changes show up once they are uploaded.
+type SyncExport struct {
+	dir    string
+	srcDir string
+
+	// etag of the exported copy of each file, empty when it has to be copied again
+	exported map[SyncPath]string
+	loaded   bool
+}
+
+func NewSyncExport(dir string, srcDir string) *SyncExport {
+	return &SyncExport{
+		dir:      filepath.Clean(dir),
+		srcDir:   srcDir,
+		exported: make(map[SyncPath]string),
+	}
+}
+
+// Update copies the files that changed since the last update and removes the ones that are gone
+func (e *SyncExport) Update(synced map[SyncPath]*FileMetadata) error {
+	if !e.loaded {
+		if err := e.load(synced); err != nil {
+			return fmt.Errorf("load export: %w", err)
+		}
+		e.loaded = true
+	}
+
+	copied, removed := 0, 0
+	for path, meta := range synced {
+		if IsMarkedPath(path.String()) {
+			continue
+		}
+		if etag, ok := e.exported[path]; ok && etag == meta.ETag && etag != "" {
+			continue
+		}
+		if err := e.exportFile(path, meta); err != nil {
+			if !errors.Is(err, errNotSynced) && !errors.Is(err, fs.ErrNotExist) {
+				slog.Warn("sync export", "path", path, "error", err)
+			}
+			continue
+		}
+		e.exported[path] = meta.ETag
+		copied++
+	}
+
+	for path := range e.exported {
+		if _, ok := synced[path]; ok && !IsMarkedPath(path.String()) {
+			continue
+		}
+		if err := e.removeFile(path); err != nil {
+			slog.Warn("sync export", "path", path, "error", err)
+			continue
+		}
+		delete(e.exported, path)
+		removed++
+	}
+
+	if copied > 0 || removed > 0 {
+		slog.Debug("sync export updated", "dir", e.dir, "copied", copied, "removed", removed)
+	}
+	return nil
+}
+
+// load picks up the files exported by a previous run. Those that still match their source are
+// kept as they are, the others are copied again or removed by the update.
+func (e *SyncExport) load(synced map[SyncPath]*FileMetadata) error {
+	if err := utils.EnsureDir(e.dir); err != nil {
+		return err
+	}
+	return filepath.WalkDir(e.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if strings.Contains(d.Name(), exportTmpPattern) {
+			// left behind by an interrupted copy
+			return os.Remove(path)
+		}
+
+		relPath, err := filepath.Rel(e.dir, path)
+		if err != nil {
+			return err
+		}
+		syncPath := SyncPath(workspace.NormPath(relPath))
+		e.exported[syncPath] = ""
+
+		meta, ok := synced[syncPath]
+		if !ok {
+			return nil
+		}
+		dst, err := d.Info()
+		if err != nil {
+			return err
+		}
+		src, err := os.Stat(filepath.Join(e.srcDir, filepath.FromSlash(relPath)))
+		if err == nil && src.Size() == dst.Size() && src.ModTime().Equal(dst.ModTime()) {
+			e.exported[syncPath] = meta.ETag
+		}
+		return nil
+	})
+}
+
+// exportFile copies a synced file into the export, replacing the previous copy at once
+func (e *SyncExport) exportFile(path SyncPath, meta *FileMetadata) error {
+	srcPath := filepath.Join(e.srcDir, filepath.FromSlash(path.String()))
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() != meta.Size {
+		return errNotSynced
+	}
+
+	dstPath := filepath.Join(e.dir, filepath.FromSlash(path.String()))
+	if err := utils.EnsureParent(dstPath); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dstPath), "."+filepath.Base(dstPath)+exportTmpPattern+"*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath) // no-op once renamed
+
+	hasher := md5.New()
+	_, err = io.Copy(io.MultiWriter(tmp, hasher), src)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	// multipart etags aren't the md5 of the content, the size check has to do for those
+	if !strings.Contains(meta.ETag, "-") && fmt.Sprintf("%x", hasher.Sum(nil)) != meta.ETag {
+		return errNotSynced
+	}
+
+	if err := os.Chtimes(tmpPath, info.ModTime(), info.ModTime()); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, dstPath)
+}
+
+// removeFile removes a file from the export, and its parent dirs left empty
+func (e *SyncExport) removeFile(path SyncPath) error {
+	dstPath := filepath.Join(e.dir, filepath.FromSlash(path.String()))
+	if err := os.Remove(dstPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	for dir := filepath.Dir(dstPath); dir != e.dir && strings.HasPrefix(dir, e.dir); dir = filepath.Dir(dir) {
+		if err := os.Remove(dir); err != nil {
+			break // not empty
+		}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package sync
+
+import (
+	"crypto/md5"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeSynced writes a file into the datasites dir and returns its journal entry
+func writeSynced(t *testing.T, srcDir string, path string, content string) *FileMetadata {
+	t.Helper()
+	absPath := filepath.Join(srcDir, filepath.FromSlash(path))
+	require.NoError(t, os.MkdirAll(filepath.Dir(absPath), 0o755))
+	require.NoError(t, os.WriteFile(absPath, []byte(content), 0o644))
+	return &FileMetadata{
+		Path: SyncPath(path),
+		Size: int64(len(content)),
+		ETag: fmt.Sprintf("%x", md5.Sum([]byte(content))),
+	}
+}
+
+func assertExported(t *testing.T, exportDir string, path string, content string) {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(exportDir, filepath.FromSlash(path)))
+	require.NoError(t, err, path)
+	assert.Equal(t, content, string(data), path)
+}
+
+func TestSyncExport(t *testing.T) {
+	srcDir := t.TempDir()
+	exportDir := filepath.Join(t.TempDir(), "export")
+	export := NewSyncExport(exportDir, srcDir)
+
+	readme := writeSynced(t, srcDir, "alice@example.com/public/readme.md", "hello")
+	data := writeSynced(t, srcDir, "bob@example.com/public/data/results.csv", "a,b\n1,2\n")
+	// on disk but never synced, e.g. a conflict or a file not uploaded yet
+	writeSynced(t, srcDir, "bob@example.com/public/data/results.conflict.csv", "a,b\n3,4\n")
+	writeSynced(t, srcDir, "alice@example.com/public/draft.md", "wip")
+
+	synced := map[SyncPath]*FileMetadata{readme.Path: readme, data.Path: data}
+	require.NoError(t, export.Update(synced))
+
+	assertExported(t, exportDir, "alice@example.com/public/readme.md", "hello")
+	assertExported(t, exportDir, "bob@example.com/public/data/results.csv", "a,b\n1,2\n")
+	assert.NoFileExists(t, filepath.Join(exportDir, "bob@example.com/public/data/results.conflict.csv"))
+	assert.NoFileExists(t, filepath.Join(exportDir, "alice@example.com/public/draft.md"))
+
+	// a synced update is exported, a local edit that isn't synced yet is not
+	synced[readme.Path] = writeSynced(t, srcDir, "alice@example.com/public/readme.md", "hello again")
+	require.NoError(t, export.Update(synced))
+	assertExported(t, exportDir, "alice@example.com/public/readme.md", "hello again")
+
+	writeSynced(t, srcDir, "alice@example.com/public/readme.md", "local edit")
+	require.NoError(t, export.Update(synced))
+	assertExported(t, exportDir, "alice@example.com/public/readme.md", "hello again")
+
+	// deletes are exported, and the dirs left empty go with them
+	delete(synced, data.Path)
+	require.NoError(t, export.Update(synced))
+	assert.NoDirExists(t, filepath.Join(exportDir, "bob@example.com"))
+}
+
+func TestSyncExportRestart(t *testing.T) {
+	srcDir := t.TempDir()
+	exportDir := t.TempDir()
+
+	readme := writeSynced(t, srcDir, "alice@example.com/public/readme.md", "hello")
+	notes := writeSynced(t, srcDir, "alice@example.com/public/notes.md", "notes")
+	synced := map[SyncPath]*FileMetadata{readme.Path: readme, notes.Path: notes}
+	require.NoError(t, NewSyncExport(exportDir, srcDir).Update(synced))
+
+	// while the daemon was stopped, notes were deleted and a copy was interrupted
+	delete(synced, notes.Path)
+	tmpPath := filepath.Join(exportDir, "alice@example.com/public/.readme.md"+exportTmpPattern+"123")
+	require.NoError(t, os.WriteFile(tmpPath, []byte("hel"), 0o644))
+
+	export := NewSyncExport(exportDir, srcDir)
+	require.NoError(t, export.Update(synced))
+
+	assertExported(t, exportDir, "alice@example.com/public/readme.md", "hello")
+	assert.NoFileExists(t, filepath.Join(exportDir, "alice@example.com/public/notes.md"))
+	assert.NoFileExists(t, tmpPath)
+	assert.Equal(t, map[SyncPath]string{readme.Path: readme.ETag}, export.exported)
+}
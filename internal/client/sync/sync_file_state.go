@@ -0,0 +1,94 @@
+package sync
+
+import (
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+// FileSyncState is the sync state of a file in the datasites dir, as reported to the control plane
+type FileSyncState string
+
+const (
+	FileSynced     FileSyncState = "synced"
+	FilePending    FileSyncState = "pending"
+	FileSyncing    FileSyncState = "syncing"
+	FileError      FileSyncState = "error"
+	FileIgnored    FileSyncState = "ignored"
+	FileConflicted FileSyncState = "conflicted"
+	FileRejected   FileSyncState = "rejected"
+	// a newer version failed verification, the file on disk is the last one that passed
+	FileQuarantined FileSyncState = "quarantined"
+)
+
+// FileState returns the sync state of a path relative to the datasites dir.
+// Directories have no state of their own, they are either ignored or empty.
+func (m *SyncManager) FileState(path SyncPath) FileSyncState {
+	info, err := os.Stat(filepath.Join(m.workspace.DatasitesDir, filepath.FromSlash(path.String())))
+	if err != nil {
+		return ""
+	}
+	return m.engine.fileState(path, info)
+}
+
+func (se *SyncEngine) fileState(path SyncPath, info fs.FileInfo) FileSyncState {
+	if info.IsDir() {
+		if se.isIgnoredFile(path.String() + "/") {
+			return FileIgnored
+		}
+		return ""
+	}
+
+	// an operation in flight or a failed one says more than the journal
+	if status, ok := se.syncStatus.GetStatus(path); ok {
+		switch {
+		case status.ConflictState == ConflictStateConflicted:
+			return FileConflicted
+		case status.ConflictState == ConflictStateRejected:
+			return FileRejected
+		case status.ConflictState == ConflictStateQuarantined:
+			return FileQuarantined
+		case status.SyncState == SyncStateSyncing:
+			return FileSyncing
+		case status.SyncState == SyncStatePending:
+			return FilePending
+		case status.SyncState == SyncStateError:
+			return FileError
+		}
+	}
+
+	if se.isIgnoredFile(path.String()) {
+		return FileIgnored
+	}
+
+	synced, err := se.journal.Get(path)
+	if errors.Is(err, ErrJournalNotOpen) {
+		// the sync hasn't started yet
+		return ""
+	} else if err != nil {
+		slog.Warn("sync file state", "path", path, "error", err)
+		return FileError
+	}
+	if synced == nil {
+		// not uploaded yet
+		return FilePending
+	}
+
+	// the etag of the last scan is only good while the file is unchanged since
+	local, ok := se.localState.Get(path)
+	if ok && local.Size == info.Size() && local.LastModified.Equal(info.ModTime()) {
+		if local.ETag != synced.ETag {
+			return FilePending
+		}
+		return FileSynced
+	}
+	if ok || info.Size() != synced.Size {
+		// changed since the last scan
+		return FilePending
+	}
+
+	// written by a download after the last scan
+	return FileSynced
+}
@@ -0,0 +1,105 @@
+package sync
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/openmined/syftbox/internal/client/workspace"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newFileStateTestManager(t *testing.T) *SyncManager {
+	t.Helper()
+	ws, err := workspace.NewWorkspace(t.TempDir(), "alice@example.com")
+	require.NoError(t, err)
+
+	journal, err := NewSyncJournal(filepath.Join(t.TempDir(), "journal.db"))
+	require.NoError(t, err)
+	require.NoError(t, journal.Open())
+	t.Cleanup(func() { journal.Close() })
+
+	ignoreList := NewSyncIgnoreList(ws.DatasitesDir, true)
+	ignoreList.Load()
+
+	return &SyncManager{
+		workspace: ws,
+		ignore:    ignoreList,
+		engine: &SyncEngine{
+			workspace:  ws,
+			journal:    journal,
+			localState: NewSyncLocalState(ws.DatasitesDir),
+			ignoreList: ignoreList,
+			syncStatus: NewSyncStatus(),
+		},
+	}
+}
+
+// writeLocal writes a file in the datasites dir, and records it as synced when synced is set
+func writeLocal(t *testing.T, m *SyncManager, path SyncPath, content string, synced bool) {
+	t.Helper()
+	meta := writeSynced(t, m.workspace.DatasitesDir, path.String(), content)
+	if synced {
+		meta.LastModified = time.Now()
+		require.NoError(t, m.engine.journal.Set(meta))
+	}
+}
+
+func TestFileState(t *testing.T) {
+	m := newFileStateTestManager(t)
+
+	report := SyncPath("alice@example.com/public/report.csv")
+	draft := SyncPath("alice@example.com/public/draft.csv")
+	writeLocal(t, m, report, "a,b\n1,2\n", true)
+	writeLocal(t, m, draft, "wip", false)
+	writeLocal(t, m, "alice@example.com/public/run.log", "log", false)
+	_, err := m.engine.localState.Scan()
+	require.NoError(t, err)
+
+	assert.Equal(t, FileSynced, m.FileState(report))
+	assert.Equal(t, FilePending, m.FileState(draft), "never uploaded")
+	assert.Equal(t, FileIgnored, m.FileState("alice@example.com/public/run.log"))
+	assert.Empty(t, m.FileState("alice@example.com/public"), "folders have no state")
+	assert.Empty(t, m.FileState("alice@example.com/public/missing.csv"))
+
+	// an edit since the last scan is pending until it's uploaded
+	writeLocal(t, m, report, "a,b\n1,2\n3,4\n", false)
+	assert.Equal(t, FilePending, m.FileState(report))
+	_, err = m.engine.localState.Scan()
+	require.NoError(t, err)
+	assert.Equal(t, FilePending, m.FileState(report))
+
+	// the sync status of an operation takes precedence
+	m.engine.syncStatus.SetSyncing(report)
+	assert.Equal(t, FileSyncing, m.FileState(report))
+	m.engine.syncStatus.SetError(report, errors.New("upload failed"))
+	assert.Equal(t, FileError, m.FileState(report))
+	m.engine.syncStatus.SetRejected(report, "no write access")
+	assert.Equal(t, FileRejected, m.FileState(report))
+
+	require.True(t, m.engine.syncStatus.StartPending(draft))
+	assert.Equal(t, FilePending, m.FileState(draft))
+	m.engine.syncStatus.SetCompleted(draft)
+	assert.Equal(t, FilePending, m.FileState(draft), "the journal has the last word once the operation is done")
+}
+
+func TestFileStateNotStarted(t *testing.T) {
+	m := newFileStateTestManager(t)
+	m.engine.journal, _ = NewSyncJournal(filepath.Join(t.TempDir(), "journal.db"))
+	m.engine.ignoreList = NewSyncIgnoreList(m.workspace.DatasitesDir, true)
+	writeLocal(t, m, "alice@example.com/public/report.csv", "a,b\n", false)
+
+	assert.Empty(t, m.FileState("alice@example.com/public/report.csv"))
+	assert.Empty(t, m.FileState("alice@example.com/public"))
+}
+
+func TestFileStateIgnoredDir(t *testing.T) {
+	m := newFileStateTestManager(t)
+	require.NoError(t, os.MkdirAll(filepath.Join(m.workspace.DatasitesDir, "alice@example.com", "app", "__pycache__"), 0o755))
+
+	assert.Equal(t, FileIgnored, m.FileState("alice@example.com/app/__pycache__"))
+	assert.Empty(t, m.FileState("alice@example.com/app"))
+}
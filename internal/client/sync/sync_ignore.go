@@ -19,7 +19,6 @@ var defaultIgnoreLines = []string{
 	"**/*syftconflict*", // legacy marker
 	"**/*.conflict.*",
 	"**/*.rejected.*",
-	"*.syft.tmp.*", // temporary files
 	".syftkeep",
 	// python
 	".ipynb_checkpoints/",
@@ -42,18 +41,37 @@ var defaultIgnoreLines = []string{
 	"Icon",
 }
 
+// hiddenIgnoreLines skip dotfiles and everything in hidden dirs, when hidden files aren't synced
+var hiddenIgnoreLines = []string{
+	".*",
+}
+
+// neverSyncLines are the daemon's own files, syftignore rules can't sync them
+var neverSyncLines = []string{
+	".syftbox/",
+	"*.syft.tmp.*", // temporary files
+}
+
+var neverSync = gitignore.CompileIgnoreLines(neverSyncLines...)
+
 type SyncIgnoreList struct {
-	baseDir string
-	ignore  *gitignore.GitIgnore
+	baseDir       string
+	includeHidden bool
+	ignore        *gitignore.GitIgnore
 }
 
-func NewSyncIgnoreList(baseDir string) *SyncIgnoreList {
-	return &SyncIgnoreList{baseDir: baseDir}
+func NewSyncIgnoreList(baseDir string, includeHidden bool) *SyncIgnoreList {
+	return &SyncIgnoreList{baseDir: baseDir, includeHidden: includeHidden}
 }
 
+// Load compiles the default rules, then the hidden files policy, then the syftignore rules.
+// The last matching rule wins, so syftignore can re-include hidden files with "!" rules.
 func (s *SyncIgnoreList) Load() {
 	ignorePath := filepath.Join(s.baseDir, "syftignore")
-	ignoreLines := defaultIgnoreLines
+	ignoreLines := append([]string{}, defaultIgnoreLines...)
+	if !s.includeHidden {
+		ignoreLines = append(ignoreLines, hiddenIgnoreLines...)
+	}
 
 	// read the syftignore file if it exists
 	if utils.FileExists(ignorePath) {
@@ -69,8 +87,9 @@ func (s *SyncIgnoreList) Load() {
 	s.ignore = gitignore.CompileIgnoreLines(ignoreLines...)
 }
 
+// ShouldIgnore reports whether a path is ignored. Until the rules are loaded, only the daemon's own files are.
 func (s *SyncIgnoreList) ShouldIgnore(path string) bool {
-	return s.ignore.MatchesPath(path)
+	return neverSync.MatchesPath(path) || (s.ignore != nil && s.ignore.MatchesPath(path))
 }
 
 func readIgnoreFile(path string) ([]string, error) {
@@ -0,0 +1,57 @@
+package sync
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyncIgnoreListHidden(t *testing.T) {
+	paths := []string{
+		"alice@example.com/public/.hidden-1",
+		"alice@example.com/public/.config/settings.toml",
+		"alice@example.com/.env",
+	}
+
+	included := NewSyncIgnoreList(t.TempDir(), true)
+	included.Load()
+	for _, path := range paths {
+		assert.False(t, included.ShouldIgnore(path), path)
+	}
+
+	skipped := NewSyncIgnoreList(t.TempDir(), false)
+	skipped.Load()
+	for _, path := range paths {
+		assert.True(t, skipped.ShouldIgnore(path), path)
+	}
+
+	// files that merely contain a dot are synced either way
+	assert.False(t, skipped.ShouldIgnore("alice@example.com/public/results.v2.csv"))
+	assert.False(t, skipped.ShouldIgnore("alice@example.com/public/syft.pub.yaml"))
+}
+
+func TestSyncIgnoreListHiddenSyftignore(t *testing.T) {
+	baseDir := t.TempDir()
+	rules := "# share the env, keep the rest of the dotfiles local\n!.env\n\n.hidden-*\n!.syftbox/\n"
+	require.NoError(t, os.WriteFile(filepath.Join(baseDir, "syftignore"), []byte(rules), 0o644))
+
+	// syftignore rules come after the policy, they can re-include or exclude hidden files
+	skipped := NewSyncIgnoreList(baseDir, false)
+	skipped.Load()
+	assert.False(t, skipped.ShouldIgnore("alice@example.com/.env"))
+	assert.True(t, skipped.ShouldIgnore("alice@example.com/.bashrc"))
+
+	included := NewSyncIgnoreList(baseDir, true)
+	included.Load()
+	assert.True(t, included.ShouldIgnore("alice@example.com/public/.hidden-1"))
+	assert.False(t, included.ShouldIgnore("alice@example.com/.bashrc"))
+
+	// the daemon's own files never sync
+	for _, list := range []*SyncIgnoreList{skipped, included} {
+		assert.True(t, list.ShouldIgnore("alice@example.com/.syftbox/state.json"))
+		assert.True(t, list.ShouldIgnore("alice@example.com/public/file.txt.syft.tmp.123"))
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/openmined/syftbox/internal/client/workspace"
 	"github.com/openmined/syftbox/internal/db"
 	"github.com/openmined/syftbox/internal/utils"
 )
@@ -37,12 +38,19 @@ type dbFileMetadata struct {
 	LastModified string   `db:"last_modified"`
 }
 
+var ErrJournalNotOpen = errors.New("sync journal not open")
+
 // SyncJournal manages the persistent state of synced files using SQLite.
 type SyncJournal struct {
 	db     *sqlx.DB
 	dbPath string
 }
 
+// JournalPath returns the path of the sync journal of a workspace
+func JournalPath(ws *workspace.Workspace) string {
+	return filepath.Join(ws.MetadataDir, syncDbName)
+}
+
 // NewSyncJournal creates or opens a SyncJournal backed by an SQLite database.
 func NewSyncJournal(dbPath string) (*SyncJournal, error) {
 	return &SyncJournal{
@@ -68,7 +76,7 @@ func (s *SyncJournal) Open() error {
 	}
 
 	// Create table if it doesn't exist
-	if _, err := db.Exec(schema); err != nil {
+	if _, err := db.Exec(schema + opsSchema); err != nil {
 		db.Close() // Close the connection if schema init fails
 		return fmt.Errorf("failed to initialize journal schema: %w", err)
 	}
@@ -80,7 +88,7 @@ func (s *SyncJournal) Open() error {
 // Close closes the underlying database connection.
 func (s *SyncJournal) Close() error {
 	if s.db == nil {
-		return fmt.Errorf("sync journal not open")
+		return ErrJournalNotOpen
 	}
 	if err := s.db.Close(); err != nil {
 		slog.Error("Failed to close sync journal database", "error", err)
@@ -90,8 +98,23 @@ func (s *SyncJournal) Close() error {
 	return nil
 }
 
+// Flush checkpoints the write-ahead log into the database file.
+func (s *SyncJournal) Flush() error {
+	if s.db == nil {
+		return ErrJournalNotOpen
+	}
+	if _, err := s.db.Exec("PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+		return fmt.Errorf("checkpoint sync journal: %w", err)
+	}
+	return nil
+}
+
 // Get retrieves the metadata for a specific path.
 func (s *SyncJournal) Get(path SyncPath) (*FileMetadata, error) {
+	if s.db == nil {
+		return nil, ErrJournalNotOpen
+	}
+
 	var dbMeta dbFileMetadata
 	err := s.db.Get(&dbMeta, "SELECT path, size, etag, version, last_modified FROM sync_journal WHERE path = ?", path)
 	if err != nil {
@@ -119,6 +142,10 @@ func (s *SyncJournal) Get(path SyncPath) (*FileMetadata, error) {
 }
 
 func (s *SyncJournal) ContentsChanged(path SyncPath, etag string) (bool, error) {
+	if s.db == nil {
+		return false, ErrJournalNotOpen
+	}
+
 	// select etag from sync_journal where path = ?
 	var dbEtag string
 	err := s.db.Get(&dbEtag, "SELECT etag FROM sync_journal WHERE path = ?", path)
@@ -136,6 +163,9 @@ func (s *SyncJournal) Set(state *FileMetadata) error {
 	if state == nil {
 		return fmt.Errorf("cannot set nil state")
 	}
+	if s.db == nil {
+		return ErrJournalNotOpen
+	}
 
 	data := dbFileMetadata{
 		Path:         state.Path,
@@ -0,0 +1,124 @@
+package sync
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+const (
+	// maxJournalOps is how many recorded operations the journal keeps
+	maxJournalOps = 1000
+	// opLogBufferSize is the status events buffered for the operation log.
+	// it is larger than the event stream's, as outcomes are dropped when it is full
+	opLogBufferSize = 256
+)
+
+const opsSchema = `
+CREATE TABLE IF NOT EXISTS sync_ops (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    path TEXT NOT NULL,
+    state TEXT NOT NULL,
+    conflict_state TEXT NOT NULL,
+    error TEXT NOT NULL DEFAULT '',
+    error_count INTEGER NOT NULL DEFAULT 0,
+    reason TEXT NOT NULL DEFAULT '',
+    recorded_at TEXT NOT NULL -- Store as RFC3339 string
+);
+`
+
+// JournalOp is the outcome of a sync operation, as recorded in the journal.
+// It holds the file's metadata and state, never its contents.
+type JournalOp struct {
+	ID            int64         `json:"id" db:"id"`
+	Path          SyncPath      `json:"path" db:"path"`
+	State         SyncState     `json:"state" db:"state"`
+	ConflictState ConflictState `json:"conflictState" db:"conflict_state"`
+	Error         string        `json:"error,omitempty" db:"error"`
+	ErrorCount    int           `json:"errorCount,omitempty" db:"error_count"`
+	Reason        string        `json:"reason,omitempty" db:"reason"`
+	RecordedAt    time.Time     `json:"recordedAt" db:"-"`
+}
+
+// dbJournalOp is used for scanning from the database where time is stored as TEXT.
+type dbJournalOp struct {
+	JournalOp
+	RecordedAt string `db:"recorded_at"`
+}
+
+// RecordOp records the outcome of an operation on path, and drops the oldest ones past maxJournalOps.
+func (s *SyncJournal) RecordOp(path SyncPath, status *PathStatus) error {
+	if s.db == nil {
+		return ErrJournalNotOpen
+	}
+
+	var errMsg string
+	if status.Error != nil {
+		errMsg = status.Error.Error()
+	}
+
+	recordedAt := status.LastUpdated
+	if recordedAt.IsZero() {
+		recordedAt = time.Now()
+	}
+
+	if _, err := s.db.Exec(
+		`INSERT INTO sync_ops (path, state, conflict_state, error, error_count, reason, recorded_at) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		path, status.SyncState, status.ConflictState, errMsg, status.ErrorCount, status.Reason, recordedAt.UTC().Format(time.RFC3339Nano),
+	); err != nil {
+		return fmt.Errorf("failed to record op for path %s: %w", path, err)
+	}
+
+	if _, err := s.db.Exec(
+		`DELETE FROM sync_ops WHERE id <= (SELECT MAX(id) FROM sync_ops) - ?`, maxJournalOps,
+	); err != nil {
+		return fmt.Errorf("failed to prune ops: %w", err)
+	}
+
+	return nil
+}
+
+// RecentOps returns the last recorded operations, newest first. limit <= 0 returns all of them.
+func (s *SyncJournal) RecentOps(limit int) ([]*JournalOp, error) {
+	if s.db == nil {
+		return nil, ErrJournalNotOpen
+	}
+	if limit <= 0 {
+		limit = maxJournalOps
+	}
+
+	var dbOps []dbJournalOp
+	if err := s.db.Select(&dbOps,
+		`SELECT id, path, state, conflict_state, error, error_count, reason, recorded_at FROM sync_ops ORDER BY id DESC LIMIT ?`, limit,
+	); err != nil {
+		return nil, fmt.Errorf("failed to query ops: %w", err)
+	}
+
+	ops := make([]*JournalOp, 0, len(dbOps))
+	for _, dbOp := range dbOps {
+		op := dbOp.JournalOp
+		recordedAt, err := time.Parse(time.RFC3339Nano, dbOp.RecordedAt)
+		if err != nil {
+			slog.Error("Failed to parse recorded_at timestamp", "id", op.ID, "value", dbOp.RecordedAt, "error", err)
+		}
+		op.RecordedAt = recordedAt
+		ops = append(ops, &op)
+	}
+
+	return ops, nil
+}
+
+// recordOps writes the outcome of every operation reported on events to the journal, until events is closed
+func (se *SyncEngine) recordOps(events <-chan *SyncStatusEvent) {
+	for event := range events {
+		switch event.Status.SyncState {
+		case SyncStateCompleted, SyncStateError:
+		default:
+			// only outcomes are recorded, not the progress
+			continue
+		}
+		if err := se.journal.RecordOp(event.Path, event.Status); err != nil {
+			slog.Warn("sync journal record op", "path", event.Path, "error", err)
+		}
+	}
+}
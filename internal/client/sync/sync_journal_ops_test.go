@@ -0,0 +1,78 @@
+package sync
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newOpsTestJournal(t *testing.T) *SyncJournal {
+	t.Helper()
+	journal, err := NewSyncJournal(filepath.Join(t.TempDir(), syncDbName))
+	require.NoError(t, err)
+	require.NoError(t, journal.Open())
+	t.Cleanup(func() { journal.Close() })
+	return journal
+}
+
+func TestRecordOpsFromStatus(t *testing.T) {
+	se := &SyncEngine{
+		journal:    newOpsTestJournal(t),
+		syncStatus: NewSyncStatus(),
+	}
+
+	done := make(chan struct{})
+	events := se.syncStatus.subscribe(opLogBufferSize)
+	go func() {
+		defer close(done)
+		se.recordOps(events)
+	}()
+
+	se.syncStatus.SetSyncing("user@example.com/public/a.txt")
+	se.syncStatus.SetProgress("user@example.com/public/a.txt", 50)
+	se.syncStatus.SetCompleted("user@example.com/public/a.txt")
+	se.syncStatus.SetSyncing("user@example.com/public/b.txt")
+	se.syncStatus.SetError("user@example.com/public/b.txt", errors.New("boom"))
+	se.syncStatus.SetConflicted("user@example.com/public/c.txt")
+
+	se.syncStatus.Close()
+	<-done
+
+	ops, err := se.journal.RecentOps(0)
+	require.NoError(t, err)
+	require.Len(t, ops, 3, "only the outcomes are recorded")
+
+	assert.Equal(t, SyncPath("user@example.com/public/c.txt"), ops[0].Path)
+	assert.Equal(t, ConflictStateConflicted, ops[0].ConflictState)
+
+	assert.Equal(t, SyncPath("user@example.com/public/b.txt"), ops[1].Path)
+	assert.Equal(t, SyncStateError, ops[1].State)
+	assert.Equal(t, "boom", ops[1].Error)
+	assert.Equal(t, 1, ops[1].ErrorCount)
+
+	assert.Equal(t, SyncPath("user@example.com/public/a.txt"), ops[2].Path)
+	assert.Equal(t, SyncStateCompleted, ops[2].State)
+}
+
+func TestRecordOpsPrunes(t *testing.T) {
+	journal := newOpsTestJournal(t)
+
+	for i := range maxJournalOps + 10 {
+		require.NoError(t, journal.RecordOp(SyncPath(fmt.Sprintf("user@example.com/public/%d.txt", i)), &PathStatus{
+			SyncState:     SyncStateCompleted,
+			ConflictState: ConflictStateNone,
+			LastUpdated:   time.Now(),
+		}))
+	}
+
+	ops, err := journal.RecentOps(0)
+	require.NoError(t, err)
+	require.Len(t, ops, maxJournalOps)
+	assert.Equal(t, SyncPath(fmt.Sprintf("user@example.com/public/%d.txt", maxJournalOps+9)), ops[0].Path)
+	assert.Equal(t, SyncPath("user@example.com/public/10.txt"), ops[len(ops)-1].Path)
+}
@@ -0,0 +1,111 @@
+package sync
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/openmined/syftbox/internal/utils"
+)
+
+const (
+	// latencyWindowSize is the number of recent samples kept for each direction
+	latencyWindowSize = 1024
+)
+
+// LatencyKind is the direction of a replicated file
+type LatencyKind string
+
+const (
+	// LatencyUpload is the time from a local change being detected to the server confirming the upload.
+	// Peers downloading the file is not observable from the uploader.
+	LatencyUpload LatencyKind = "upload"
+	// LatencyDownload is the time from a remote change being notified or detected to the file being written locally
+	LatencyDownload LatencyKind = "download"
+)
+
+// LatencyStats summarizes the latencies of the recent samples of one direction
+type LatencyStats struct {
+	Count   int64 // total samples since start, including the ones out of the window
+	Samples int   // samples in the window, used for the percentiles
+	P50     time.Duration
+	P90     time.Duration
+	P99     time.Duration
+	Max     time.Duration
+}
+
+// SyncLatency tracks end-to-end replication latencies over a sliding window of recent samples
+type SyncLatency struct {
+	windows map[LatencyKind]*latencyWindow
+	mu      sync.Mutex
+}
+
+type latencyWindow struct {
+	samples []time.Duration
+	next    int // ring index of the next sample, once the window is full
+	count   int64
+}
+
+func NewSyncLatency() *SyncLatency {
+	return &SyncLatency{
+		windows: map[LatencyKind]*latencyWindow{
+			LatencyUpload:   {samples: make([]time.Duration, 0, latencyWindowSize)},
+			LatencyDownload: {samples: make([]time.Duration, 0, latencyWindowSize)},
+		},
+	}
+}
+
+// Observe records a latency sample
+func (l *SyncLatency) Observe(kind LatencyKind, latency time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	w, ok := l.windows[kind]
+	if !ok {
+		return
+	}
+
+	w.count++
+	if len(w.samples) < latencyWindowSize {
+		w.samples = append(w.samples, latency)
+		return
+	}
+
+	w.samples[w.next] = latency
+	w.next = (w.next + 1) % latencyWindowSize
+}
+
+// ObserveSince records the time since start as a sample. A zero start is not recorded.
+func (l *SyncLatency) ObserveSince(kind LatencyKind, start time.Time) {
+	if start.IsZero() {
+		return
+	}
+	l.Observe(kind, time.Since(start))
+}
+
+// Stats returns the percentiles of the samples in the window
+func (l *SyncLatency) Stats(kind LatencyKind) *LatencyStats {
+	l.mu.Lock()
+	w, ok := l.windows[kind]
+	if !ok {
+		l.mu.Unlock()
+		return &LatencyStats{}
+	}
+	sorted := slices.Clone(w.samples)
+	count := w.count
+	l.mu.Unlock()
+
+	slices.Sort(sorted)
+
+	stats := &LatencyStats{
+		Count:   count,
+		Samples: len(sorted),
+	}
+	if len(sorted) > 0 {
+		stats.P50 = utils.Percentile(sorted, 0.50)
+		stats.P90 = utils.Percentile(sorted, 0.90)
+		stats.P99 = utils.Percentile(sorted, 0.99)
+		stats.Max = sorted[len(sorted)-1]
+	}
+	return stats
+}
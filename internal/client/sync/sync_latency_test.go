@@ -0,0 +1,60 @@
+package sync
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncLatencyPercentiles(t *testing.T) {
+	l := NewSyncLatency()
+
+	// 1ms..100ms, out of order
+	for i := 100; i >= 1; i-- {
+		l.Observe(LatencyUpload, time.Duration(i)*time.Millisecond)
+	}
+
+	assert.Equal(t, &LatencyStats{
+		Count:   100,
+		Samples: 100,
+		P50:     50 * time.Millisecond,
+		P90:     90 * time.Millisecond,
+		P99:     99 * time.Millisecond,
+		Max:     100 * time.Millisecond,
+	}, l.Stats(LatencyUpload))
+
+	// directions are tracked separately
+	assert.Equal(t, &LatencyStats{}, l.Stats(LatencyDownload))
+}
+
+func TestSyncLatencySlidingWindow(t *testing.T) {
+	l := NewSyncLatency()
+
+	// slow samples are pushed out of the window by newer fast ones
+	for range latencyWindowSize {
+		l.Observe(LatencyDownload, time.Second)
+	}
+	for range latencyWindowSize {
+		l.Observe(LatencyDownload, time.Millisecond)
+	}
+
+	stats := l.Stats(LatencyDownload)
+	assert.Equal(t, int64(2*latencyWindowSize), stats.Count)
+	assert.Equal(t, latencyWindowSize, stats.Samples)
+	assert.Equal(t, time.Millisecond, stats.P99)
+	assert.Equal(t, time.Millisecond, stats.Max)
+}
+
+func TestSyncLatencyObserveSince(t *testing.T) {
+	l := NewSyncLatency()
+
+	// unknown detection time is not a sample
+	l.ObserveSince(LatencyUpload, time.Time{})
+	assert.Equal(t, int64(0), l.Stats(LatencyUpload).Count)
+
+	l.ObserveSince(LatencyUpload, time.Now().Add(-time.Second))
+	stats := l.Stats(LatencyUpload)
+	assert.Equal(t, int64(1), stats.Count)
+	assert.GreaterOrEqual(t, stats.P50, time.Second)
+}
@@ -15,7 +15,8 @@ import (
 type SyncLocalState struct {
 	rootDir   string
 	lastState map[SyncPath]*FileMetadata // Stores the result of the last successful scan
-	mu        sync.RWMutex
+	mu        sync.RWMutex               // guards lastState
+	scanMu    sync.Mutex                 // one scan at a time, lookups don't wait for it
 }
 
 func NewSyncLocalState(rootDir string) *SyncLocalState {
@@ -26,8 +27,8 @@ func NewSyncLocalState(rootDir string) *SyncLocalState {
 }
 
 func (s *SyncLocalState) Scan() (map[SyncPath]*FileMetadata, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	s.scanMu.Lock()
+	defer s.scanMu.Unlock()
 
 	newState := make(map[SyncPath]*FileMetadata)
 
@@ -91,9 +92,19 @@ func (s *SyncLocalState) Scan() (map[SyncPath]*FileMetadata, error) {
 	}
 
 	// Update the cache for the next run
+	s.mu.Lock()
 	s.lastState = newState
+	s.mu.Unlock()
+
+	return newState, nil
+}
 
-	return s.lastState, nil
+// Get returns the metadata of a file as of the last scan
+func (s *SyncLocalState) Get(path SyncPath) (*FileMetadata, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	meta, ok := s.lastState[path]
+	return meta, ok
 }
 
 // calculateETag opens a file, calculates its MD5 hash, and returns it as a hex string.
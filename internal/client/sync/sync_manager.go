@@ -3,6 +3,7 @@ package sync
 import (
 	"context"
 	"fmt"
+	"io"
 	"log/slog"
 
 	"github.com/openmined/syftbox/internal/client/workspace"
@@ -17,10 +18,14 @@ type SyncManager struct {
 	priority  *SyncPriorityList
 }
 
-func NewManager(workspace *workspace.Workspace, sdk *syftsdk.SyftSDK) (*SyncManager, error) {
-	ignoreList := NewSyncIgnoreList(workspace.DatasitesDir)
+func NewManager(workspace *workspace.Workspace, sdk *syftsdk.SyftSDK, opts *SyncOptions) (*SyncManager, error) {
+	if opts == nil {
+		opts = &SyncOptions{}
+	}
+
+	ignoreList := NewSyncIgnoreList(workspace.DatasitesDir, opts.IncludeHidden)
 	priorityList := NewSyncPriorityList(workspace.DatasitesDir)
-	engine, err := NewSyncEngine(workspace, sdk, ignoreList, priorityList)
+	engine, err := NewSyncEngine(workspace, sdk, ignoreList, priorityList, opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create sync engine: %w", err)
 	}
@@ -50,3 +55,56 @@ func (m *SyncManager) Stop() error {
 	slog.Info("sync manager stop")
 	return m.engine.Stop()
 }
+
+// DryRun returns the operations the next full sync would run, without running them.
+// It opens the journal itself, so it is meant for a manager that isn't started.
+func (m *SyncManager) DryRun(ctx context.Context) (*ReconcileOperations, error) {
+	m.ignore.Load()
+
+	if err := m.engine.journal.Open(); err != nil {
+		return nil, fmt.Errorf("sync journal: %w", err)
+	}
+	defer m.engine.journal.Close()
+
+	return m.engine.DryRun(ctx)
+}
+
+// GetSyncStatus returns the sync status tracker. Subscribe to it for sync events.
+func (m *SyncManager) GetSyncStatus() *SyncStatus {
+	return m.engine.syncStatus
+}
+
+// GetSyncLatency returns the replication latency tracker
+func (m *SyncManager) GetSyncLatency() *SyncLatency {
+	return m.engine.latency
+}
+
+// InitialSynced is closed once the first full sync completed
+func (m *SyncManager) InitialSynced() <-chan struct{} {
+	return m.engine.InitialSynced()
+}
+
+// SetDownloadPriority replaces the ranking of the downloads. It must be set before Start
+func (m *SyncManager) SetDownloadPriority(priority DownloadPriority) {
+	m.engine.SetDownloadPriority(priority)
+}
+
+// IsResuming reports whether the system resumed from sleep and sync is catching up
+func (m *SyncManager) IsResuming() bool {
+	return m.engine.IsResuming()
+}
+
+// IsReconnecting reports whether the network changed and sync is catching up
+func (m *SyncManager) IsReconnecting() bool {
+	return m.engine.IsReconnecting()
+}
+
+// IsReadOnly reports whether sync only follows the remote changes, and never uploads
+func (m *SyncManager) IsReadOnly() bool {
+	return m.engine.IsReadOnly()
+}
+
+// Ingest writes src to relPath in the datasites directory and uploads it without waiting for the next sync
+func (m *SyncManager) Ingest(ctx context.Context, relPath SyncPath, src io.Reader, size int64) (*FileMetadata, error) {
+	return m.engine.Ingest(ctx, relPath, src, size)
+}
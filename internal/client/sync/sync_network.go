@@ -0,0 +1,111 @@
+package sync
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"slices"
+	"time"
+)
+
+const networkCheckInterval = 5 * time.Second
+
+// networkDetector notices when the addresses of the network interfaces change, e.g. after switching
+// from wifi to ethernet or toggling a vpn. The connections made over the previous addresses are likely stale.
+type networkDetector struct {
+	addrs func() ([]string, error)
+	last  []string // sorted, nil until the first check
+}
+
+func newNetworkDetector() *networkDetector {
+	return &networkDetector{
+		addrs: interfaceAddrs,
+	}
+}
+
+// networkChange has the addresses that came up and the ones that went away since the previous check
+type networkChange struct {
+	Added   []string
+	Removed []string
+}
+
+// check returns how the addresses changed since the previous check, and whether they did
+func (d *networkDetector) check() (*networkChange, bool) {
+	addrs, err := d.addrs()
+	if err != nil {
+		slog.Warn("network check", "error", err)
+		return nil, false
+	}
+	if addrs == nil {
+		addrs = []string{}
+	}
+	slices.Sort(addrs)
+
+	last := d.last
+	d.last = addrs
+	if last == nil {
+		return nil, false
+	}
+
+	change := &networkChange{}
+	for _, addr := range addrs {
+		if _, found := slices.BinarySearch(last, addr); !found {
+			change.Added = append(change.Added, addr)
+		}
+	}
+	for _, addr := range last {
+		if _, found := slices.BinarySearch(addrs, addr); !found {
+			change.Removed = append(change.Removed, addr)
+		}
+	}
+	if len(change.Added) == 0 && len(change.Removed) == 0 {
+		return nil, false
+	}
+	return change, true
+}
+
+// run checks at every tick and calls onChange when the addresses changed, until ctx is done
+func (d *networkDetector) run(ctx context.Context, ticks <-chan time.Time, onChange func(change *networkChange)) {
+	// the addresses to compare the first tick with
+	d.check()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticks:
+			if change, changed := d.check(); changed {
+				onChange(change)
+			}
+		}
+	}
+}
+
+// interfaceAddrs lists the addresses of the interfaces that are up, as name=addr.
+// Loopback and link-local addresses are left out, connections to the server don't go over them.
+func interfaceAddrs() ([]string, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	var addrs []string
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+
+		ifaceAddrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range ifaceAddrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if !ok || ipNet.IP.IsLoopback() || ipNet.IP.IsLinkLocalUnicast() {
+				continue
+			}
+			addrs = append(addrs, iface.Name+"="+ipNet.String())
+		}
+	}
+	return addrs, nil
+}
@@ -0,0 +1,130 @@
+package sync
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	gosync "sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeInterfaces stands in for the interface addresses of the machine
+type fakeInterfaces struct {
+	mu    gosync.Mutex
+	addrs []string
+}
+
+func (f *fakeInterfaces) set(addrs ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.addrs = addrs
+}
+
+func (f *fakeInterfaces) get() ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.addrs), nil
+}
+
+func TestNetworkDetectorCheck(t *testing.T) {
+	ifaces := &fakeInterfaces{}
+	ifaces.set("en0=192.168.1.10/24", "en0=2001:db8::10/64")
+	d := &networkDetector{addrs: ifaces.get}
+
+	_, changed := d.check()
+	assert.False(t, changed, "first check has nothing to compare to")
+
+	// the order of the interfaces doesn't matter
+	ifaces.set("en0=2001:db8::10/64", "en0=192.168.1.10/24")
+	_, changed = d.check()
+	assert.False(t, changed)
+
+	// switched from wifi to ethernet
+	ifaces.set("en1=10.0.0.5/8")
+	change, changed := d.check()
+	require.True(t, changed)
+	assert.Equal(t, []string{"en1=10.0.0.5/8"}, change.Added)
+	assert.Equal(t, []string{"en0=192.168.1.10/24", "en0=2001:db8::10/64"}, change.Removed)
+
+	// a vpn came up
+	ifaces.set("en1=10.0.0.5/8", "utun0=100.64.0.2/32")
+	change, changed = d.check()
+	require.True(t, changed)
+	assert.Equal(t, []string{"utun0=100.64.0.2/32"}, change.Added)
+	assert.Empty(t, change.Removed)
+
+	// offline
+	ifaces.set()
+	change, changed = d.check()
+	require.True(t, changed)
+	assert.Empty(t, change.Added)
+	assert.Len(t, change.Removed, 2)
+}
+
+func TestNetworkChangeReconnects(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var conns atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/events" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		conn, err := websocket.Accept(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.CloseNow()
+		conns.Add(1)
+		for {
+			if _, _, err := conn.Read(r.Context()); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	se := newPeerTestEngine(t, srv.URL, "alice@example.com", nil)
+	t.Cleanup(func() { se.sdk.Close() })
+	require.NoError(t, se.sdk.Events.Connect(ctx))
+	require.Eventually(t, func() bool { return conns.Load() == 1 }, time.Second, 10*time.Millisecond)
+
+	ifaces := &fakeInterfaces{}
+	ifaces.set("en0=192.168.1.10/24")
+	ticks := make(chan time.Time)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		(&networkDetector{addrs: ifaces.get}).run(ctx, ticks, func(change *networkChange) {
+			se.handleNetworkChange(ctx, change)
+		})
+	}()
+
+	// same addresses, the connection is kept
+	ticks <- time.Now()
+	ticks <- time.Now()
+	assert.False(t, se.IsReconnecting())
+	assert.EqualValues(t, 1, conns.Load())
+
+	// the laptop joined another network
+	ifaces.set("en0=10.0.0.5/8")
+	ticks <- time.Now()
+	assert.Eventually(t, func() bool { return conns.Load() == 2 }, 5*time.Second, 10*time.Millisecond, "websocket not reconnected")
+	// the resync fails against this server, so it's still reported
+	assert.True(t, se.IsReconnecting())
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		require.Fail(t, "detector did not stop")
+	}
+}
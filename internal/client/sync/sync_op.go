@@ -1,5 +1,7 @@
 package sync
 
+import "time"
+
 type OpType string
 
 const (
@@ -8,14 +10,30 @@ const (
 	OpDeleteRemote OpType = "DeleteRemote"
 	OpDeleteLocal  OpType = "DeleteLocal"
 	OpConflict     OpType = "Conflict"
+	OpMerge        OpType = "Merge"
 	OpError        OpType = "Error"
 	OpSkipped      OpType = "Skipped"
 )
 
+// Reasons a full sync plans an operation, relative to the journal
+const (
+	ReasonLocalCreated   = "local created"
+	ReasonLocalModified  = "local modified"
+	ReasonLocalDeleted   = "local deleted"
+	ReasonRemoteCreated  = "remote created"
+	ReasonRemoteModified = "remote modified"
+	ReasonRemoteDeleted  = "remote deleted"
+	ReasonBothCreated    = "created locally and remotely"
+	ReasonBothModified   = "modified locally and remotely"
+	ReasonBothDeleted    = "deleted locally and remotely"
+)
+
 type SyncOperation struct {
 	Type       OpType
 	RelPath    SyncPath
 	Local      *FileMetadata
 	Remote     *FileMetadata
 	LastSynced *FileMetadata
+	DetectedAt time.Time // when the change was detected, for latency tracking
+	Reason     string    // why the operation is planned, one of the Reason constants
 }
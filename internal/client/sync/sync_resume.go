@@ -0,0 +1,60 @@
+package sync
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	resumeCheckInterval = 5 * time.Second
+	// resumeThreshold is how much longer than resumeCheckInterval a check may take before it's taken as a resume
+	resumeThreshold = 30 * time.Second
+)
+
+// resumeDetector notices when the system resumed from sleep.
+// The monotonic clock doesn't advance while the system is suspended, but the wall clock does,
+// so a resume shows up as the wall clock jumping ahead, or on some platforms as a check running late.
+type resumeDetector struct {
+	interval  time.Duration
+	threshold time.Duration
+	last      time.Time
+}
+
+func newResumeDetector() *resumeDetector {
+	return &resumeDetector{
+		interval:  resumeCheckInterval,
+		threshold: resumeThreshold,
+	}
+}
+
+// check returns how long the system was away since the previous check at now, and whether it resumed
+func (d *resumeDetector) check(now time.Time) (time.Duration, bool) {
+	last := d.last
+	d.last = now
+	if last.IsZero() {
+		return 0, false
+	}
+
+	elapsed := now.Sub(last)                       // monotonic, when available
+	wallElapsed := now.Round(0).Sub(last.Round(0)) // wall clock only
+	away := max(elapsed, wallElapsed) - d.interval
+	if away < d.threshold {
+		return 0, false
+	}
+	return away, true
+}
+
+// run checks at every tick and calls onResume when the system resumed, until ctx is done.
+// The ticks are expected to carry the current time, as the ones of a time.Ticker.
+func (d *resumeDetector) run(ctx context.Context, ticks <-chan time.Time, onResume func(away time.Duration)) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticks:
+			if away, resumed := d.check(now); resumed {
+				onResume(away)
+			}
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package sync
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResumeDetectorClockJump(t *testing.T) {
+	// wall clock times only, like a system where the monotonic clock kept running while asleep
+	now := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	d := newResumeDetector()
+
+	_, resumed := d.check(now)
+	assert.False(t, resumed, "first check has nothing to compare to")
+
+	// regular checks
+	for range 3 {
+		now = now.Add(resumeCheckInterval)
+		_, resumed = d.check(now)
+		assert.False(t, resumed)
+	}
+
+	// a little late is not a resume
+	now = now.Add(resumeCheckInterval + resumeThreshold/2)
+	_, resumed = d.check(now)
+	assert.False(t, resumed)
+
+	// the lid was closed for an hour
+	now = now.Add(time.Hour + resumeCheckInterval)
+	away, resumed := d.check(now)
+	assert.True(t, resumed)
+	assert.Equal(t, time.Hour, away)
+
+	// and back to normal
+	now = now.Add(resumeCheckInterval)
+	_, resumed = d.check(now)
+	assert.False(t, resumed)
+}
+
+func TestResumeDetectorRunTriggersResync(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ticks := make(chan time.Time)
+	resumes := make(chan time.Duration, 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		newResumeDetector().run(ctx, ticks, func(away time.Duration) { resumes <- away })
+	}()
+
+	now := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	for range 3 {
+		ticks <- now
+		now = now.Add(resumeCheckInterval)
+	}
+	select {
+	case <-resumes:
+		t.Fatal("resync triggered without a resume")
+	default:
+	}
+
+	// the clock jumps after a sleep
+	ticks <- now.Add(10 * time.Minute)
+	select {
+	case away := <-resumes:
+		assert.Equal(t, 10*time.Minute, away)
+	case <-time.After(time.Second):
+		t.Fatal("resync not triggered after the clock jump")
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		require.Fail(t, "detector did not stop")
+	}
+}
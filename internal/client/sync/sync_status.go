@@ -30,6 +30,8 @@ const (
 	ConflictStateNone       ConflictState = "none"
 	ConflictStateConflicted ConflictState = "conflicted"
 	ConflictStateRejected   ConflictState = "rejected"
+	// the downloaded file failed a verify hook
+	ConflictStateQuarantined ConflictState = "quarantined"
 )
 
 // PathStatus represents the complete status of a file
@@ -39,6 +41,7 @@ type PathStatus struct {
 	Progress      float64
 	Error         error
 	ErrorCount    int
+	Reason        string // why the server rejected the file, or the verify hook the download
 	LastUpdated   time.Time
 }
 
@@ -57,7 +60,7 @@ type SyncStatus struct {
 	files map[SyncPath]*PathStatus
 	mu    sync.RWMutex
 
-	// Event broadcasting (for future control plane API)
+	// Event broadcasting for the control plane API
 	eventSubs []chan *SyncStatusEvent
 	eventMu   sync.RWMutex
 }
@@ -71,10 +74,14 @@ func NewSyncStatus() *SyncStatus {
 
 // Subscribe returns a channel for receiving sync status events
 func (s *SyncStatus) Subscribe() <-chan *SyncStatusEvent {
+	return s.subscribe(syncEventBufferSize)
+}
+
+func (s *SyncStatus) subscribe(size int) <-chan *SyncStatusEvent {
 	s.eventMu.Lock()
 	defer s.eventMu.Unlock()
 
-	ch := make(chan *SyncStatusEvent, syncEventBufferSize)
+	ch := make(chan *SyncStatusEvent, size)
 	s.eventSubs = append(s.eventSubs, ch)
 	return ch
 }
@@ -98,7 +105,9 @@ func (s *SyncStatus) broadcastEvent(path SyncPath, status *PathStatus) {
 	s.eventMu.RLock()
 	defer s.eventMu.RUnlock()
 
-	event := &SyncStatusEvent{Path: path, Status: status}
+	// send a copy, the status is mutated under s.mu after the event is sent
+	statusCopy := *status
+	event := &SyncStatusEvent{Path: path, Status: &statusCopy}
 	for _, sub := range s.eventSubs {
 		select {
 		case sub <- event:
@@ -125,6 +134,28 @@ func (s *SyncStatus) getOrCreateStatus(path SyncPath) *PathStatus {
 	return status
 }
 
+// StartPending sets a file to pending state, unless it is already pending or syncing.
+// It reports whether the file was set, so that only one of concurrent writers of a file gets to sync it.
+func (s *SyncStatus) StartPending(path SyncPath) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if status, exists := s.files[path]; exists {
+		if status.SyncState == SyncStatePending || status.SyncState == SyncStateSyncing {
+			return false
+		}
+	}
+
+	status := s.getOrCreateStatus(path)
+	status.SyncState = SyncStatePending
+	status.Progress = progressMin
+	status.Error = nil
+	status.LastUpdated = time.Now()
+
+	s.broadcastEvent(path, status)
+	return true
+}
+
 // SetSyncing sets a file to syncing state, preserving conflicted/rejected file state
 func (s *SyncStatus) SetSyncing(path SyncPath) {
 	s.mu.Lock()
@@ -181,6 +212,7 @@ func (s *SyncStatus) SetCompletedAndRemove(path SyncPath) {
 	status.ConflictState = ConflictStateNone
 	status.Progress = progressMax
 	status.Error = nil
+	status.Reason = ""
 	status.LastUpdated = time.Now()
 
 	s.broadcastEvent(path, status)
@@ -217,13 +249,14 @@ func (s *SyncStatus) SetConflicted(path SyncPath) {
 	status.ConflictState = ConflictStateConflicted
 	status.Progress = progressMax
 	status.Error = nil
+	status.Reason = ""
 	status.LastUpdated = time.Now()
 
 	s.broadcastEvent(path, status)
 }
 
-// SetRejected marks a file as rejected
-func (s *SyncStatus) SetRejected(path SyncPath) {
+// SetRejected marks a file as rejected, with the reason given by the server if any
+func (s *SyncStatus) SetRejected(path SyncPath, reason string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -232,6 +265,23 @@ func (s *SyncStatus) SetRejected(path SyncPath) {
 	status.ConflictState = ConflictStateRejected
 	status.Progress = progressMax
 	status.Error = nil
+	status.Reason = reason
+	status.LastUpdated = time.Now()
+
+	s.broadcastEvent(path, status)
+}
+
+// SetQuarantined marks a downloaded file as quarantined, with the reason given by the verify hook
+func (s *SyncStatus) SetQuarantined(path SyncPath, reason string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := s.getOrCreateStatus(path)
+	status.SyncState = SyncStateCompleted
+	status.ConflictState = ConflictStateQuarantined
+	status.Progress = progressMax
+	status.Error = nil
+	status.Reason = reason
 	status.LastUpdated = time.Now()
 
 	s.broadcastEvent(path, status)
@@ -326,6 +376,20 @@ func (s *SyncStatus) GetRejectedFileCount() int {
 	return count
 }
 
+// GetQuarantinedFileCount returns the number of quarantined files
+func (s *SyncStatus) GetQuarantinedFileCount() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	count := 0
+	for _, status := range s.files {
+		if status.ConflictState == ConflictStateQuarantined {
+			count++
+		}
+	}
+	return count
+}
+
 // GetAllStatus returns a copy of all file statuses
 func (s *SyncStatus) GetAllStatus() map[SyncPath]*PathStatus {
 	s.mu.RLock()
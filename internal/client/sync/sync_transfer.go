@@ -0,0 +1,99 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+
+	"github.com/openmined/syftbox/internal/aclspec"
+	"github.com/openmined/syftbox/internal/blobbatch"
+	"github.com/openmined/syftbox/internal/syftsdk"
+)
+
+const (
+	DefaultTransferWorkers = 8
+	DefaultBatchThreshold  = 64 * 1024 // 64KB
+	DefaultBatchSize       = 100
+)
+
+// TransferOptions tune the parallelism of the uploads and the downloads, and the batching of small files.
+// Files under the batch threshold are sent BatchSize at a time in a single request, instead of a request each,
+// which cuts the per-file overhead of syncing many tiny files. Zero values use the defaults
+type TransferOptions struct {
+	Workers        int   // concurrent uploads, downloads and batches
+	BatchThreshold int64 // files smaller than this are transferred in batches. Negative turns batching off
+	BatchSize      int   // files in a batch
+}
+
+func (o *TransferOptions) workers() int {
+	if o == nil || o.Workers <= 0 {
+		return DefaultTransferWorkers
+	}
+	return o.Workers
+}
+
+func (o *TransferOptions) batchSize() int {
+	if o == nil || o.BatchSize <= 0 {
+		return DefaultBatchSize
+	}
+	return o.BatchSize
+}
+
+func (o *TransferOptions) batchThreshold() int64 {
+	if o == nil || o.BatchThreshold == 0 {
+		return DefaultBatchThreshold
+	}
+	return min(o.BatchThreshold, blobbatch.MaxEntrySize)
+}
+
+// batched checks if a file is transferred in a batch. ACL files always go on their own, the server parses them
+func (se *SyncEngine) batched(key string, size int64) bool {
+	return size > 0 && size < se.transfer.batchThreshold() &&
+		!aclspec.IsACLFile(key) &&
+		!se.batchUnsupported.Load()
+}
+
+// batchFailed checks if a batch failed because the server doesn't support batches,
+// in which case the files of the batch, and of the next ones, are transferred on their own
+func (se *SyncEngine) batchFailed(err error) bool {
+	if !errors.Is(err, syftsdk.ErrBatchNotSupported) {
+		return false
+	}
+	if !se.batchUnsupported.Swap(true) {
+		slog.Warn("sync", "type", SyncStandard, "status", "the server does not support batches, transferring small files on their own")
+	}
+	return true
+}
+
+// runTransfers runs the transfers on the configured number of workers, until they are done or ctx is done
+func (se *SyncEngine) runTransfers(ctx context.Context, transfers []func(ctx context.Context)) {
+	var wg sync.WaitGroup
+	transfersChan := make(chan func(ctx context.Context), len(transfers))
+
+	workers := min(se.transfer.workers(), len(transfers))
+	wg.Add(workers)
+	for range workers {
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return // Context cancelled
+				case transfer, ok := <-transfersChan:
+					if !ok {
+						return // Channel closed
+					}
+					transfer(ctx)
+				}
+			}
+		}()
+	}
+
+	for _, transfer := range transfers {
+		transfersChan <- transfer
+	}
+	close(transfersChan)
+
+	wg.Wait()
+}
@@ -351,7 +351,7 @@ func TestWriteFileWithIntegrityCheckSyftTmpFiltering(t *testing.T) {
 	fw := NewFileWatcher(tempDir)
 
 	// Create actual SyncIgnoreList to test the real filtering logic
-	ignoreList := NewSyncIgnoreList(tempDir)
+	ignoreList := NewSyncIgnoreList(tempDir, true)
 	ignoreList.Load() // This loads the default ignore patterns including *.syft.tmp.*
 
 	// Set up filter using the actual ignore list logic
@@ -0,0 +1,218 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	gitignore "github.com/sabhiram/go-gitignore"
+)
+
+const (
+	DefaultVerifyTimeout = 30 * time.Second
+	verifyWaitDelay      = time.Second // for the output pipes once a timed out hook is killed
+	verifyMaxOutput      = 512         // bytes of the hook output kept as the reason
+	quarantineDir        = "quarantine"
+)
+
+var ErrQuarantined = errors.New("file quarantined")
+
+// VerifyHook checks the downloaded files matching Pattern, a gitignore pattern relative to the datasites dir,
+// before they are synced. Command runs with the path of the downloaded file as last argument, and
+// SYFTBOX_VERIFY_PATH set to its sync path. A non-zero exit rejects the file.
+type VerifyHook struct {
+	Pattern string
+	Command []string
+	Timeout time.Duration // DefaultVerifyTimeout if zero
+}
+
+type verifyHook struct {
+	*VerifyHook
+	matcher *gitignore.GitIgnore
+}
+
+// SyncVerifier runs the verify hooks on the downloaded files. The files a hook rejects never reach the datasites dir,
+// they are moved to the quarantine dir for inspection instead, and not downloaded again until they change remotely.
+// The quarantine is kept in memory, the rejected files are verified again after a restart.
+type SyncVerifier struct {
+	hooks []*verifyHook
+	dir   string
+
+	// etag of the quarantined content of each file
+	quarantined map[SyncPath]string
+	mu          sync.Mutex
+}
+
+func NewSyncVerifier(hooks []VerifyHook, metadataDir string) *SyncVerifier {
+	compiled := make([]*verifyHook, 0, len(hooks))
+	for _, hook := range hooks {
+		compiled = append(compiled, &verifyHook{
+			VerifyHook: &hook,
+			matcher:    gitignore.CompileIgnoreLines(hook.Pattern),
+		})
+	}
+	return &SyncVerifier{
+		hooks:       compiled,
+		dir:         filepath.Join(metadataDir, quarantineDir),
+		quarantined: make(map[SyncPath]string),
+	}
+}
+
+// Verify runs the hooks matching the path on the downloaded file, in order. The file is rejected by the first that fails,
+// and is quarantined. Hooks that can't run or time out reject the file too
+func (v *SyncVerifier) Verify(ctx context.Context, path SyncPath, etag string, downloadPath string) error {
+	for _, hook := range v.hooks {
+		if !hook.matcher.MatchesPath(path.String()) {
+			continue
+		}
+		if err := hook.run(ctx, path, downloadPath); err != nil {
+			// the sync stopping is no verdict on the file
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if qErr := v.quarantine(path, etag, downloadPath); qErr != nil {
+				return fmt.Errorf("%w: %w, and failed to keep it aside: %w", ErrQuarantined, err, qErr)
+			}
+			return fmt.Errorf("%w: %w", ErrQuarantined, err)
+		}
+	}
+	return nil
+}
+
+// VerifyContent verifies a download held in memory, written to a temp file in tmpDir for the hooks if any matches
+func (v *SyncVerifier) VerifyContent(ctx context.Context, path SyncPath, etag string, content []byte, tmpDir string) error {
+	if !v.matches(path) {
+		return nil
+	}
+
+	if err := os.MkdirAll(tmpDir, 0o755); err != nil {
+		return err
+	}
+	tmpFile, err := os.CreateTemp(tmpDir, filepath.Base(path.String())+".syft.tmp.*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+	if _, err := tmpFile.Write(content); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+
+	return v.Verify(ctx, path, etag, tmpFile.Name())
+}
+
+func (v *SyncVerifier) matches(path SyncPath) bool {
+	for _, hook := range v.hooks {
+		if hook.matcher.MatchesPath(path.String()) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsQuarantined reports whether this content of the file was rejected
+func (v *SyncVerifier) IsQuarantined(path SyncPath, etag string) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	quarantined, ok := v.quarantined[path]
+	return ok && quarantined == etag
+}
+
+// Release drops the quarantined copy of the file, once another content of it passed. It reports whether it was quarantined
+func (v *SyncVerifier) Release(path SyncPath) bool {
+	v.mu.Lock()
+	_, ok := v.quarantined[path]
+	delete(v.quarantined, path)
+	v.mu.Unlock()
+
+	if ok {
+		os.Remove(v.QuarantinePath(path))
+	}
+	return ok
+}
+
+// QuarantinePath is where the rejected content of the file is kept
+func (v *SyncVerifier) QuarantinePath(path SyncPath) string {
+	return filepath.Join(v.dir, filepath.FromSlash(path.String()))
+}
+
+func (v *SyncVerifier) quarantine(path SyncPath, etag string, downloadPath string) error {
+	v.mu.Lock()
+	v.quarantined[path] = etag
+	v.mu.Unlock()
+
+	return copyLocal(downloadPath, v.QuarantinePath(path))
+}
+
+func (h *verifyHook) run(ctx context.Context, path SyncPath, downloadPath string) error {
+	if len(h.Command) == 0 {
+		return fmt.Errorf("verify hook %q: no command", h.Pattern)
+	}
+
+	timeout := h.Timeout
+	if timeout <= 0 {
+		timeout = DefaultVerifyTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	args := append(append([]string{}, h.Command[1:]...), downloadPath)
+	cmd := exec.CommandContext(ctx, h.Command[0], args...)
+	cmd.Env = append(os.Environ(), "SYFTBOX_VERIFY_PATH="+path.String())
+	cmd.WaitDelay = verifyWaitDelay
+
+	output, err := cmd.CombinedOutput()
+	if err == nil {
+		return nil
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("verify hook %q timed out after %s", h.Pattern, timeout)
+	}
+
+	reason := strings.TrimSpace(string(output))
+	if len(reason) > verifyMaxOutput {
+		reason = reason[:verifyMaxOutput] + "..."
+	}
+	if reason == "" {
+		return fmt.Errorf("verify hook %q: %w", h.Pattern, err)
+	}
+	return fmt.Errorf("verify hook %q: %w: %s", h.Pattern, err, reason)
+}
+
+// verifyPriorityDownload verifies the content of a priority download before it's written to the datasites dir
+func (se *SyncEngine) verifyPriorityDownload(path SyncPath, etag string, content []byte, tmpDir string) error {
+	if se.verifier == nil {
+		return nil
+	}
+	ctx := se.opsCtx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return se.verifier.VerifyContent(ctx, path, etag, content, tmpDir)
+}
+
+// setDownloaded marks a download as completed, and drops the quarantine of a previous version that failed verification
+func (se *SyncEngine) setDownloaded(path SyncPath) {
+	if se.verifier != nil && se.verifier.Release(path) {
+		se.syncStatus.SetCompletedAndRemove(path)
+		return
+	}
+	se.syncStatus.SetCompleted(path)
+}
+
+// setQuarantined reports a download that failed verification, and was kept aside
+func (se *SyncEngine) setQuarantined(syncType string, path SyncPath, err error) {
+	slog.Warn("sync", "type", syncType, "op", OpWriteLocal, "status", "Quarantined", "path", path, "quarantine", se.verifier.QuarantinePath(path), "error", err)
+	se.syncStatus.SetQuarantined(path, err.Error())
+}
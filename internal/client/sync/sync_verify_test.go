@@ -0,0 +1,113 @@
+package sync
+
+import (
+	"context"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyHookQuarantine(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the hooks are shell scripts")
+	}
+
+	// the test server serves each file with its key as content
+	srv, _ := newOrderTestServer(t)
+	bob := newPeerTestEngine(t, srv.URL, "bob@example.com", nil)
+	bob.verifier = NewSyncVerifier([]VerifyHook{
+		{Pattern: "*.txt", Command: []string{"sh", "-c", `grep -q signed "$1" || { echo "no signature" >&2; exit 1; }`, "verify"}},
+		{Pattern: "*.bin", Command: []string{"sh", "-c", "sleep 10", "verify"}, Timeout: 100 * time.Millisecond},
+	}, bob.workspace.MetadataDir)
+
+	signed := SyncPath("alice@example.com/public/signed.txt")
+	forged := SyncPath("alice@example.com/public/forged.txt")
+	slow := SyncPath("alice@example.com/public/model.bin")
+	unchecked := SyncPath("alice@example.com/public/data.csv")
+
+	events := bob.syncStatus.Subscribe()
+	defer bob.syncStatus.Unsubscribe(events)
+
+	remote := map[SyncPath]*FileMetadata{}
+	batch := BatchLocalWrite{}
+	for _, path := range []SyncPath{signed, forged, slow, unchecked} {
+		remote[path] = &FileMetadata{Path: path, ETag: path.String(), Size: int64(len(path))}
+		batch[path] = &SyncOperation{Type: OpWriteLocal, RelPath: path, Remote: remote[path]}
+	}
+	bob.handleLocalWrites(context.Background(), batch)
+
+	assert.FileExists(t, bob.workspace.DatasiteAbsPath(signed.String()))
+	assert.FileExists(t, bob.workspace.DatasiteAbsPath(unchecked.String()))
+
+	// the rejected files never reach the datasites dir, they are kept aside and reported
+	for path, reason := range map[SyncPath]string{forged: "no signature", slow: "timed out"} {
+		assert.NoFileExists(t, bob.workspace.DatasiteAbsPath(path.String()))
+		content, err := os.ReadFile(bob.verifier.QuarantinePath(path))
+		require.NoError(t, err)
+		assert.Equal(t, path.String(), string(content))
+
+		status, ok := bob.syncStatus.GetStatus(path)
+		require.True(t, ok)
+		assert.Equal(t, ConflictStateQuarantined, status.ConflictState)
+		assert.Contains(t, status.Reason, reason)
+
+		synced, err := bob.journal.Get(path)
+		require.NoError(t, err)
+		assert.Nil(t, synced)
+	}
+	assert.Equal(t, 2, bob.syncStatus.GetQuarantinedFileCount())
+
+	quarantinedEvents := 0
+	for len(events) > 0 {
+		if event := <-events; event.Status.ConflictState == ConflictStateQuarantined {
+			quarantinedEvents++
+		}
+	}
+	assert.Equal(t, 2, quarantinedEvents)
+
+	// not downloaded again, until the remote file changes
+	local := map[SyncPath]*FileMetadata{signed: remote[signed], unchecked: remote[unchecked]}
+	journal := map[SyncPath]*FileMetadata{signed: remote[signed], unchecked: remote[unchecked]}
+	ops := bob.reconcile(local, remote, journal)
+	assert.Empty(t, ops.LocalWrites)
+	assert.Contains(t, ops.Ignored, forged)
+
+	remote[forged] = &FileMetadata{Path: forged, ETag: "alice@example.com/public/signed-forged.txt", Size: 10}
+	ops = bob.reconcile(local, remote, journal)
+	assert.Contains(t, ops.LocalWrites, forged)
+}
+
+func TestVerifyHookReleasesQuarantine(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the hooks are shell scripts")
+	}
+
+	srv, _ := newOrderTestServer(t)
+	bob := newPeerTestEngine(t, srv.URL, "bob@example.com", nil)
+	bob.verifier = NewSyncVerifier([]VerifyHook{
+		{Pattern: "/alice@example.com/", Command: []string{"sh", "-c", `grep -q v2 "$1"`, "verify"}},
+	}, bob.workspace.MetadataDir)
+
+	path := SyncPath("alice@example.com/public/report.txt")
+	bob.handleLocalWrites(context.Background(), BatchLocalWrite{
+		path: {Type: OpWriteLocal, RelPath: path, Remote: &FileMetadata{Path: path, ETag: "v1", Size: 2}},
+	})
+	status, ok := bob.syncStatus.GetStatus(path)
+	require.True(t, ok)
+	assert.Equal(t, ConflictStateQuarantined, status.ConflictState)
+
+	// the priority downloads are verified too, a version that passes clears the quarantine
+	tmpDir := t.TempDir()
+	assert.ErrorIs(t, bob.verifyPriorityDownload(path, "v1b", []byte("v1b"), tmpDir), ErrQuarantined)
+	require.NoError(t, bob.verifyPriorityDownload(path, "v2", []byte("v2"), tmpDir))
+	bob.setDownloaded(path)
+
+	_, ok = bob.syncStatus.GetStatus(path)
+	assert.False(t, ok)
+	assert.NoFileExists(t, bob.verifier.QuarantinePath(path))
+	assert.False(t, bob.verifier.IsQuarantined(path, "v1"))
+}
@@ -19,6 +19,7 @@ const (
 	datasitesDir       = "datasites"
 	publicDir          = "public"
 	metadataDir        = ".data"
+	trashDir           = ".trash"
 	pathSep            = string(filepath.Separator)
 	lockFile           = "syftbox.lock"
 	legacyMetadataFile = ".metadata.json"
@@ -37,6 +38,8 @@ type Workspace struct {
 	LogsDir       string
 	UserDir       string
 	UserPublicDir string
+	// deleted items kept for restoring, outside the datasites dir so they are never synced
+	TrashDir string
 
 	flock *flock.Flock
 }
@@ -59,6 +62,7 @@ func NewWorkspace(rootDir string, user string) (*Workspace, error) {
 		MetadataDir:   filepath.Join(root, metadataDir),
 		UserDir:       filepath.Join(root, datasitesDir, user),
 		UserPublicDir: filepath.Join(root, datasitesDir, user, publicDir),
+		TrashDir:      filepath.Join(root, trashDir),
 		flock:         flock,
 	}, nil
 }
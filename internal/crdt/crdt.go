@@ -0,0 +1,114 @@
+// Package crdt has conflict-free replicated data types that apps coordinate over the datasites with.
+// Each one is a json file ending in .crdt.json. Instead of setting aside one of two concurrent
+// versions of the file as a conflict, sync merges them, and every peer converges to the same state.
+package crdt
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Ext is the extension of the crdt files
+const Ext = ".crdt.json"
+
+// Type is the data type of a crdt file
+type Type string
+
+const (
+	TypeGCounter Type = "g-counter" // a counter that only grows
+	TypeORSet    Type = "or-set"    // a set where an add wins over a concurrent remove
+)
+
+var (
+	ErrUnknownType  = errors.New("unknown crdt type")
+	ErrTypeMismatch = errors.New("crdt types do not match")
+)
+
+// IsCRDTFile checks if a path is a crdt file
+func IsCRDTFile(path string) bool {
+	return strings.HasSuffix(path, Ext)
+}
+
+// Document is the content of a crdt file. Only the field of its type is set
+type Document struct {
+	Type    Type      `json:"type"`
+	Counter *GCounter `json:"counter,omitempty"`
+	Set     *ORSet    `json:"set,omitempty"`
+}
+
+// New returns an empty document of a type
+func New(typ Type) (*Document, error) {
+	switch typ {
+	case TypeGCounter:
+		return &Document{Type: typ, Counter: NewGCounter()}, nil
+	case TypeORSet:
+		return &Document{Type: typ, Set: NewORSet()}, nil
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownType, typ)
+	}
+}
+
+// Parse parses the content of a crdt file
+func Parse(data []byte) (*Document, error) {
+	var doc Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid crdt file: %w", err)
+	}
+
+	// the field of the type may be missing from a file written by hand
+	switch doc.Type {
+	case TypeGCounter:
+		if doc.Counter == nil {
+			doc.Counter = NewGCounter()
+		}
+		doc.Set = nil
+	case TypeORSet:
+		if doc.Set == nil {
+			doc.Set = NewORSet()
+		}
+		doc.Counter = nil
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownType, doc.Type)
+	}
+	doc.Counter.init()
+	doc.Set.init()
+	return &doc, nil
+}
+
+// Merge merges another replica of the document into this one
+func (d *Document) Merge(other *Document) error {
+	if d.Type != other.Type {
+		return fmt.Errorf("%w: %q and %q", ErrTypeMismatch, d.Type, other.Type)
+	}
+	switch d.Type {
+	case TypeGCounter:
+		d.Counter.Merge(other.Counter)
+	case TypeORSet:
+		d.Set.Merge(other.Set)
+	}
+	return nil
+}
+
+// Value is the value of the document, the count of a counter or the sorted elements of a set
+func (d *Document) Value() any {
+	if d.Type == TypeGCounter {
+		return d.Counter.Value()
+	}
+	return d.Set.Elements()
+}
+
+// Marshal encodes the document. The same state always encodes to the same bytes,
+// so that converged replicas have the same etag and sync doesn't upload them again
+func (d *Document) Marshal() ([]byte, error) {
+	d.Set.compact()
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(d); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
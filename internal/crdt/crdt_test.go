@@ -0,0 +1,127 @@
+package crdt
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGCounterMerge(t *testing.T) {
+	alice := NewGCounter()
+	alice.Increment("alice@example.com", 2)
+	bob := NewGCounter()
+	bob.Increment("bob@example.com", 3)
+	bob.Increment("alice@example.com", 1) // an older state of alice's count
+
+	alice.Merge(bob)
+	bob.Merge(alice)
+	assert.Equal(t, uint64(5), alice.Value())
+	assert.Equal(t, alice, bob)
+
+	// merging again changes nothing
+	alice.Merge(bob)
+	assert.Equal(t, uint64(5), alice.Value())
+}
+
+func TestORSetMerge(t *testing.T) {
+	alice := NewORSet()
+	alice.Add("alice@example.com", "x")
+	alice.Add("alice@example.com", "y")
+
+	bob := NewORSet()
+	bob.Merge(alice)
+
+	// bob removes x while alice adds it again, and removes y
+	bob.Remove("x")
+	alice.Add("alice@example.com", "x")
+	alice.Remove("y")
+	bob.Add("bob@example.com", "z")
+
+	alice.Merge(bob)
+	bob.Merge(alice)
+	assert.Equal(t, []string{"x", "z"}, alice.Elements(), "the concurrent add wins")
+	assert.Equal(t, alice.Elements(), bob.Elements())
+	assert.True(t, bob.Contains("x"))
+	assert.False(t, bob.Contains("y"))
+
+	// tags stay unique after a merge
+	bob.Add("alice@example.com", "w")
+	assert.Equal(t, []string{"alice@example.com#4"}, bob.Adds["w"])
+}
+
+func TestDocumentMarshal(t *testing.T) {
+	doc, err := New(TypeORSet)
+	require.NoError(t, err)
+	doc.Set.Add("bob@example.com", "b")
+	doc.Set.Add("alice@example.com", "a")
+
+	other, err := New(TypeORSet)
+	require.NoError(t, err)
+	other.Set.Add("alice@example.com", "a")
+	other.Set.Add("bob@example.com", "b")
+
+	data, err := doc.Marshal()
+	require.NoError(t, err)
+	otherData, err := other.Marshal()
+	require.NoError(t, err)
+	assert.Equal(t, data, otherData, "the same state encodes the same")
+
+	parsed, err := Parse(data)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, parsed.Value())
+
+	counter, err := New(TypeGCounter)
+	require.NoError(t, err)
+	assert.ErrorIs(t, parsed.Merge(counter), ErrTypeMismatch)
+
+	_, err = Parse([]byte(`{"type":"lww-register"}`))
+	assert.ErrorIs(t, err, ErrUnknownType)
+
+	parsed, err = Parse([]byte(`{"type":"g-counter"}`))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), parsed.Value())
+}
+
+func TestUpdateFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app_data", "votes"+Ext)
+	assert.True(t, IsCRDTFile(path))
+
+	increment := func(doc *Document) error {
+		doc.Counter.Increment("alice@example.com", 1)
+		return nil
+	}
+	_, err := UpdateFile(path, TypeGCounter, increment)
+	require.NoError(t, err)
+	doc, err := UpdateFile(path, TypeGCounter, increment)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), doc.Value())
+
+	_, err = UpdateFile(path, TypeORSet, func(doc *Document) error { return nil })
+	assert.ErrorIs(t, err, ErrTypeMismatch)
+
+	remote, err := New(TypeGCounter)
+	require.NoError(t, err)
+	remote.Counter.Increment("bob@example.com", 3)
+	data, err := remote.Marshal()
+	require.NoError(t, err)
+
+	merged, err := MergeFile(path, data)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(5), merged.Value())
+
+	doc, err = ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, merged, doc)
+
+	tmpFiles, err := filepath.Glob(filepath.Join(filepath.Dir(path), "*.syft.tmp.*"))
+	require.NoError(t, err)
+	assert.Empty(t, tmpFiles)
+
+	_, err = MergeFile(path, []byte("not json"))
+	assert.Error(t, err)
+	_, err = os.Stat(path)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,120 @@
+package crdt
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// the updates of the apps and the merges of sync take turns on each file
+var (
+	locksMu sync.Mutex
+	locks   = make(map[string]*sync.Mutex)
+)
+
+func lock(path string) func() {
+	locksMu.Lock()
+	mu, ok := locks[path]
+	if !ok {
+		mu = &sync.Mutex{}
+		locks[path] = mu
+	}
+	locksMu.Unlock()
+
+	mu.Lock()
+	return mu.Unlock
+}
+
+// ReadFile reads a crdt file
+func ReadFile(path string) (*Document, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// UpdateFile applies an update to a crdt file, creating it with the given type if it doesn't exist
+func UpdateFile(path string, typ Type, update func(doc *Document) error) (*Document, error) {
+	unlock := lock(path)
+	defer unlock()
+
+	doc, err := ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		doc, err = New(typ)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if doc.Type != typ {
+		return nil, fmt.Errorf("%w: %q is a %s", ErrTypeMismatch, filepath.Base(path), doc.Type)
+	}
+
+	if err := update(doc); err != nil {
+		return nil, err
+	}
+	if err := writeFile(path, doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// MergeFile merges the content of another replica into a crdt file, and returns the merged document
+func MergeFile(path string, other []byte) (*Document, error) {
+	unlock := lock(path)
+	defer unlock()
+
+	remote, err := Parse(other)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		doc = remote
+	} else if err != nil {
+		return nil, err
+	} else if err := doc.Merge(remote); err != nil {
+		return nil, err
+	}
+
+	if err := writeFile(path, doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// writeFile replaces the file, so that sync and the apps never read it half-written
+func writeFile(path string, doc *Document) error {
+	data, err := doc.Marshal()
+	if err != nil {
+		return err
+	}
+	if current, err := os.ReadFile(path); err == nil && bytes.Equal(current, data) {
+		return nil // unchanged, don't touch it
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	// *.syft.tmp.* files are ignored by sync
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".syft.tmp.*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,38 @@
+package crdt
+
+// GCounter is a grow-only counter. Each replica only increments its own count,
+// and a merge keeps the highest count of every replica
+type GCounter struct {
+	Counts map[string]uint64 `json:"counts"`
+}
+
+func NewGCounter() *GCounter {
+	return &GCounter{Counts: make(map[string]uint64)}
+}
+
+func (c *GCounter) init() {
+	if c != nil && c.Counts == nil {
+		c.Counts = make(map[string]uint64)
+	}
+}
+
+// Increment adds n to the count of a replica
+func (c *GCounter) Increment(replica string, n uint64) {
+	c.Counts[replica] += n
+}
+
+// Value is the sum of the counts of the replicas
+func (c *GCounter) Value() uint64 {
+	var value uint64
+	for _, count := range c.Counts {
+		value += count
+	}
+	return value
+}
+
+// Merge merges another replica of the counter into this one
+func (c *GCounter) Merge(other *GCounter) {
+	for replica, count := range other.Counts {
+		c.Counts[replica] = max(c.Counts[replica], count)
+	}
+}
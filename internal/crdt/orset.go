@@ -0,0 +1,94 @@
+package crdt
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// ORSet is an observed-remove set. Every add tags the element with a tag unique to the replica,
+// and a remove tombstones the tags it has seen. An element is in the set while it has a live tag,
+// so an add concurrent with a remove wins
+type ORSet struct {
+	Adds    map[string][]string `json:"adds"`    // live tags of the elements
+	Removed []string            `json:"removed"` // tombstoned tags, sorted
+	Clock   map[string]uint64   `json:"clock"`   // tags issued by each replica
+}
+
+func NewORSet() *ORSet {
+	s := &ORSet{}
+	s.init()
+	return s
+}
+
+func (s *ORSet) init() {
+	if s == nil {
+		return
+	}
+	if s.Adds == nil {
+		s.Adds = make(map[string][]string)
+	}
+	if s.Removed == nil {
+		s.Removed = make([]string, 0)
+	}
+	if s.Clock == nil {
+		s.Clock = make(map[string]uint64)
+	}
+}
+
+// Add adds an element with a new tag of the replica
+func (s *ORSet) Add(replica string, element string) {
+	s.Clock[replica]++
+	s.Adds[element] = append(s.Adds[element], fmt.Sprintf("%s#%d", replica, s.Clock[replica]))
+}
+
+// Remove removes an element, tombstoning the tags it has on this replica
+func (s *ORSet) Remove(element string) {
+	s.Removed = append(s.Removed, s.Adds[element]...)
+	delete(s.Adds, element)
+	s.compact()
+}
+
+// Contains checks if an element is in the set
+func (s *ORSet) Contains(element string) bool {
+	return len(s.Adds[element]) > 0
+}
+
+// Elements returns the elements of the set, sorted
+func (s *ORSet) Elements() []string {
+	return slices.Sorted(maps.Keys(s.Adds))
+}
+
+// Merge merges another replica of the set into this one
+func (s *ORSet) Merge(other *ORSet) {
+	for element, tags := range other.Adds {
+		s.Adds[element] = append(s.Adds[element], tags...)
+	}
+	s.Removed = append(s.Removed, other.Removed...)
+	for replica, clock := range other.Clock {
+		s.Clock[replica] = max(s.Clock[replica], clock)
+	}
+	s.compact()
+}
+
+// compact sorts and dedups the tags, and drops the tombstoned ones from the elements
+func (s *ORSet) compact() {
+	if s == nil {
+		return
+	}
+	slices.Sort(s.Removed)
+	s.Removed = slices.Compact(s.Removed)
+	for element, tags := range s.Adds {
+		slices.Sort(tags)
+		tags = slices.Compact(tags)
+		tags = slices.DeleteFunc(tags, func(tag string) bool {
+			_, removed := slices.BinarySearch(s.Removed, tag)
+			return removed
+		})
+		if len(tags) == 0 {
+			delete(s.Adds, element)
+		} else {
+			s.Adds[element] = tags
+		}
+	}
+}
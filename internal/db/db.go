@@ -20,6 +20,15 @@ PRAGMA mmap_size=268435456;
 PRAGMA synchronous=NORMAL;
 `
 
+// pragmas of the read-only connections, which can't change the journal mode
+const readOnlyPragma = `
+PRAGMA busy_timeout=5000;
+PRAGMA temp_store=MEMORY;
+PRAGMA cache_size=10000;
+PRAGMA mmap_size=268435456;
+PRAGMA query_only=ON;
+`
+
 // config holds internal configuration for DB creation
 type config struct {
 	path            string
@@ -27,6 +36,7 @@ type config struct {
 	maxOpenConns    int
 	maxIdleConns    int
 	connMaxLifetime time.Duration
+	readOnly        bool
 }
 
 // SqliteOption defines a function that configures the DB
@@ -69,6 +79,17 @@ func WithConnMaxLifetime(d time.Duration) SqliteOption {
 	}
 }
 
+// WithReadOnly opens an existing database without ever writing to it, e.g. a replica maintained by another process.
+// It uses read-only pragmas, unless set with WithPragmas
+func WithReadOnly() SqliteOption {
+	return func(c *config) {
+		c.readOnly = true
+		if c.pragmas == defaultPragma {
+			c.pragmas = readOnlyPragma
+		}
+	}
+}
+
 // NewSqliteDB creates a new sqlx.DB with the provided options
 func NewSqliteDB(opts ...SqliteOption) (*sqlx.DB, error) {
 	// Default configuration
@@ -86,7 +107,9 @@ func NewSqliteDB(opts ...SqliteOption) (*sqlx.DB, error) {
 
 	// Ensure parent directory exists for file-based DBs
 	var dsn string
-	if cfg.path != ":memory:" {
+	if cfg.path != ":memory:" && cfg.readOnly {
+		dsn = fmt.Sprintf("file:%s?mode=ro", cfg.path)
+	} else if cfg.path != ":memory:" {
 		if err := utils.EnsureParent(cfg.path); err != nil {
 			return nil, fmt.Errorf("ensure parent directory: %w", err)
 		}
@@ -0,0 +1,226 @@
+// Package fileattr preserves file attributes beyond the content of a file, i.e. the executable bit and selected
+// extended attributes. Attributes travel as blob metadata and are reapplied where the file is downloaded.
+package fileattr
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"runtime"
+	"strconv"
+)
+
+const (
+	// MetaExecutable and MetaXattrs are the blob metadata keys of the attributes
+	MetaExecutable = "syft-executable"
+	MetaXattrs     = "syft-xattrs"
+
+	// MaxXattrsSize is the maximum encoded size of the extended attributes of a file.
+	// Blob metadata is small, so only short attributes can be preserved.
+	MaxXattrsSize = 1024
+
+	metaHeaderPrefix = "X-Amz-Meta-"
+)
+
+var (
+	ErrXattrUnsupported = errors.New("extended attributes are not supported")
+	ErrXattrsTooLarge   = fmt.Errorf("extended attributes larger than %d bytes", MaxXattrsSize)
+)
+
+// Options selects the attributes to preserve
+type Options struct {
+	// Executable preserves the executable permission bit
+	Executable bool
+	// Xattrs are the names of the extended attributes to preserve
+	Xattrs []string
+}
+
+// Enabled returns true if any attribute is preserved
+func (o *Options) Enabled() bool {
+	return o != nil && (o.Executable || len(o.Xattrs) > 0)
+}
+
+// Attrs are the preserved attributes of a file.
+// A nil Executable means the executable bit isn't known and is left as-is.
+type Attrs struct {
+	Executable *bool
+	Xattrs     map[string][]byte
+}
+
+// Read reads the attributes of the file at path selected by opts.
+// Returns nil attrs if no attribute is preserved.
+// Extended attributes are skipped on platforms without support for them.
+func Read(path string, opts *Options) (*Attrs, error) {
+	if !opts.Enabled() {
+		return nil, nil
+	}
+
+	attrs := &Attrs{}
+
+	if opts.Executable {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		executable := isExecutable(info.Mode())
+		attrs.Executable = &executable
+	}
+
+	for _, name := range opts.Xattrs {
+		value, ok, err := getxattr(path, name)
+		if errors.Is(err, ErrXattrUnsupported) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("xattr %q: %w", name, err)
+		}
+
+		if ok {
+			if attrs.Xattrs == nil {
+				attrs.Xattrs = make(map[string][]byte)
+			}
+			attrs.Xattrs[name] = value
+		}
+	}
+
+	if len(attrs.Xattrs) > 0 {
+		if encoded, err := encodeXattrs(attrs.Xattrs); err != nil {
+			return nil, err
+		} else if len(encoded) > MaxXattrsSize {
+			return nil, ErrXattrsTooLarge
+		}
+	}
+
+	return attrs, nil
+}
+
+// Apply sets the attributes on the file at path, limited to the ones selected by opts.
+// Extended attributes are skipped on platforms without support for them.
+func Apply(path string, attrs *Attrs, opts *Options) error {
+	if attrs == nil || !opts.Enabled() {
+		return nil
+	}
+
+	// windows has no executable bit
+	if opts.Executable && attrs.Executable != nil && runtime.GOOS != "windows" {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+
+		if mode := withExecutable(info.Mode(), *attrs.Executable); mode != info.Mode() {
+			if err := os.Chmod(path, mode); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, name := range opts.Xattrs {
+		value, ok := attrs.Xattrs[name]
+		if !ok {
+			continue
+		}
+
+		if err := setxattr(path, name, value); errors.Is(err, ErrXattrUnsupported) {
+			break
+		} else if err != nil {
+			return fmt.Errorf("xattr %q: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// Metadata returns the attributes as blob metadata
+func (a *Attrs) Metadata() map[string]string {
+	if a == nil {
+		return nil
+	}
+
+	meta := make(map[string]string, 2)
+	if a.Executable != nil {
+		meta[MetaExecutable] = strconv.FormatBool(*a.Executable)
+	}
+
+	if len(a.Xattrs) > 0 {
+		// encoded before, can't fail
+		encoded, _ := encodeXattrs(a.Xattrs)
+		meta[MetaXattrs] = encoded
+	}
+
+	return meta
+}
+
+// Parse parses the attributes from their encoded values, as sent on upload.
+// Empty values are not set. Returns nil attrs if none are set.
+func Parse(executable string, xattrs string) (*Attrs, error) {
+	if executable == "" && xattrs == "" {
+		return nil, nil
+	}
+
+	attrs := &Attrs{}
+	if executable != "" {
+		v, err := strconv.ParseBool(executable)
+		if err != nil {
+			return nil, fmt.Errorf("invalid executable %q", executable)
+		}
+		attrs.Executable = &v
+	}
+
+	if xattrs != "" {
+		if len(xattrs) > MaxXattrsSize {
+			return nil, ErrXattrsTooLarge
+		}
+
+		decoded, err := decodeXattrs(xattrs)
+		if err != nil {
+			return nil, err
+		}
+		attrs.Xattrs = decoded
+	}
+
+	return attrs, nil
+}
+
+// FromHeader parses the attributes from the metadata headers of a blob download.
+// Returns nil attrs if the blob has none.
+func FromHeader(header http.Header) (*Attrs, error) {
+	return Parse(header.Get(metaHeaderPrefix+MetaExecutable), header.Get(metaHeaderPrefix+MetaXattrs))
+}
+
+func encodeXattrs(xattrs map[string][]byte) (string, error) {
+	data, err := json.Marshal(xattrs)
+	if err != nil {
+		return "", err
+	}
+	// metadata values must be ascii
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeXattrs(encoded string) (map[string][]byte, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid xattrs: %w", err)
+	}
+
+	var xattrs map[string][]byte
+	if err := json.Unmarshal(data, &xattrs); err != nil {
+		return nil, fmt.Errorf("invalid xattrs: %w", err)
+	}
+
+	return xattrs, nil
+}
+
+func isExecutable(mode os.FileMode) bool {
+	return mode.Perm()&0o111 != 0
+}
+
+// withExecutable sets the executable bits for everyone that can read the file, or clears them
+func withExecutable(mode os.FileMode, executable bool) os.FileMode {
+	if !executable {
+		return mode &^ 0o111
+	}
+	return mode | (mode&0o444)>>2
+}
@@ -0,0 +1,122 @@
+package fileattr
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, mode os.FileMode) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "run.sh")
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\necho hi\n"), mode))
+	require.NoError(t, os.Chmod(path, mode))
+	return path
+}
+
+func TestExecutableRoundTrip(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no executable bit on windows")
+	}
+
+	opts := &Options{Executable: true}
+
+	src := writeFile(t, 0o755)
+	attrs, err := Read(src, opts)
+	require.NoError(t, err)
+	require.NotNil(t, attrs.Executable)
+	assert.True(t, *attrs.Executable)
+
+	// through blob metadata, as on a peer
+	header := http.Header{}
+	for k, v := range attrs.Metadata() {
+		header.Set("x-amz-meta-"+k, v)
+	}
+	received, err := FromHeader(header)
+	require.NoError(t, err)
+
+	dst := writeFile(t, 0o644)
+	require.NoError(t, Apply(dst, received, opts))
+
+	info, err := os.Stat(dst)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o755), info.Mode().Perm())
+
+	// and back
+	attrs, err = Read(writeFile(t, 0o600), opts)
+	require.NoError(t, err)
+	require.NoError(t, Apply(dst, attrs, opts))
+	info, err = os.Stat(dst)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o644), info.Mode().Perm())
+}
+
+func TestApplyRespectsOptions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no executable bit on windows")
+	}
+
+	executable := true
+	dst := writeFile(t, 0o644)
+
+	// not enabled locally
+	require.NoError(t, Apply(dst, &Attrs{Executable: &executable}, &Options{}))
+	info, err := os.Stat(dst)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o644), info.Mode().Perm())
+
+	// unknown, left as-is
+	require.NoError(t, Apply(dst, &Attrs{}, &Options{Executable: true}))
+	info, err = os.Stat(dst)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o644), info.Mode().Perm())
+}
+
+func TestXattrsRoundTrip(t *testing.T) {
+	src := writeFile(t, 0o644)
+	if err := setxattr(src, "user.syftbox.test", []byte("tagged")); errors.Is(err, ErrXattrUnsupported) {
+		t.Skip("xattrs not supported")
+	} else {
+		require.NoError(t, err)
+	}
+
+	opts := &Options{Xattrs: []string{"user.syftbox.test", "user.syftbox.missing"}}
+	attrs, err := Read(src, opts)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"user.syftbox.test": []byte("tagged")}, attrs.Xattrs)
+	assert.Nil(t, attrs.Executable)
+
+	meta := attrs.Metadata()
+	received, err := Parse(meta[MetaExecutable], meta[MetaXattrs])
+	require.NoError(t, err)
+
+	dst := writeFile(t, 0o644)
+	require.NoError(t, Apply(dst, received, opts))
+
+	value, ok, err := getxattr(dst, "user.syftbox.test")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("tagged"), value)
+}
+
+func TestParse(t *testing.T) {
+	attrs, err := Parse("", "")
+	require.NoError(t, err)
+	assert.Nil(t, attrs)
+
+	_, err = Parse("maybe", "")
+	assert.Error(t, err)
+
+	_, err = Parse("", "not base64!")
+	assert.Error(t, err)
+
+	_, err = Parse("", strings.Repeat("a", MaxXattrsSize+1))
+	assert.ErrorIs(t, err, ErrXattrsTooLarge)
+}
@@ -0,0 +1,6 @@
+package fileattr
+
+import "golang.org/x/sys/unix"
+
+// returned for attributes the file doesn't have
+const errNoXattr = unix.ENODATA
@@ -0,0 +1,11 @@
+//go:build !linux && !darwin
+
+package fileattr
+
+func getxattr(path string, name string) ([]byte, bool, error) {
+	return nil, false, ErrXattrUnsupported
+}
+
+func setxattr(path string, name string, value []byte) error {
+	return ErrXattrUnsupported
+}
@@ -0,0 +1,45 @@
+//go:build linux || darwin
+
+package fileattr
+
+import (
+	"errors"
+
+	"golang.org/x/sys/unix"
+)
+
+// getxattr returns the value of the extended attribute, and false if the file doesn't have it
+func getxattr(path string, name string) ([]byte, bool, error) {
+	for {
+		size, err := unix.Getxattr(path, name, nil)
+		if errors.Is(err, errNoXattr) {
+			return nil, false, nil
+		} else if err != nil {
+			return nil, false, xattrError(err)
+		}
+
+		buf := make([]byte, size)
+		n, err := unix.Getxattr(path, name, buf)
+		if errors.Is(err, unix.ERANGE) {
+			// grew in between, try again
+			continue
+		} else if errors.Is(err, errNoXattr) {
+			return nil, false, nil
+		} else if err != nil {
+			return nil, false, xattrError(err)
+		}
+
+		return buf[:n], true, nil
+	}
+}
+
+func setxattr(path string, name string, value []byte) error {
+	return xattrError(unix.Setxattr(path, name, value, 0))
+}
+
+func xattrError(err error) error {
+	if errors.Is(err, unix.ENOTSUP) || errors.Is(err, unix.EOPNOTSUPP) {
+		return ErrXattrUnsupported
+	}
+	return err
+}
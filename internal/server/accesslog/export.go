@@ -0,0 +1,215 @@
+package accesslog
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultExportLimit = 1000
+	MaxExportLimit     = 10000
+	maxLogLineSize     = 1024 * 1024
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ExportFilter selects the entries of an export. Zero values match everything.
+type ExportFilter struct {
+	Types  []AccessType // access types to include. AccessTypeDeny also matches every denied access
+	Since  time.Time    // include entries at or after this time
+	Until  time.Time    // include entries before this time
+	Cursor string       // continue after the last entry of a previous export
+	Limit  int          // max entries, DefaultExportLimit if zero
+}
+
+func (f *ExportFilter) match(entry *AccessLogEntry) bool {
+	if !f.Since.IsZero() && entry.Timestamp.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !entry.Timestamp.Before(f.Until) {
+		return false
+	}
+	if len(f.Types) == 0 {
+		return true
+	}
+	return slices.Contains(f.Types, entry.AccessType) ||
+		(!entry.Allowed && slices.Contains(f.Types, AccessTypeDeny))
+}
+
+// exportCursor points after the skip-th entry logged at the given millisecond.
+// entries are logged with millisecond precision, so several of them can share a timestamp.
+type exportCursor struct {
+	at   time.Time
+	skip int
+}
+
+func parseCursor(cursor string) (*exportCursor, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+
+	ms, skip, ok := strings.Cut(cursor, "-")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+	at, err := strconv.ParseInt(ms, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	n, err := strconv.Atoi(skip)
+	if err != nil || n < 1 {
+		return nil, ErrInvalidCursor
+	}
+	return &exportCursor{at: time.UnixMilli(at).UTC(), skip: n}, nil
+}
+
+func (c *exportCursor) String() string {
+	return fmt.Sprintf("%d-%d", c.at.UnixMilli(), c.skip)
+}
+
+// Export returns the entries of all users matching the filter, oldest first, and the cursor to continue from.
+// The cursor is empty once there is nothing left. Entries only hold what was logged about a request,
+// never its credentials.
+func (al *AccessLogger) Export(filter *ExportFilter) ([]AccessLogEntry, string, error) {
+	cursor, err := parseCursor(filter.Cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultExportLimit
+	}
+	limit = min(limit, MaxExportLimit)
+
+	// nothing older than the cursor is of interest
+	since := filter.Since
+	if cursor != nil && cursor.at.After(since) {
+		since = cursor.at
+	}
+
+	entries, err := al.readAll(since)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// stable, so that entries sharing a timestamp keep the same order between exports
+	slices.SortStableFunc(entries, func(a, b AccessLogEntry) int {
+		if c := a.Timestamp.Compare(b.Timestamp); c != 0 {
+			return c
+		}
+		return strings.Compare(a.User, b.User)
+	})
+
+	var (
+		result   []AccessLogEntry
+		position exportCursor // of the current entry
+		last     exportCursor // of the last exported entry
+		more     bool
+	)
+	for i := range entries {
+		entry := &entries[i]
+
+		// count the entries sharing a timestamp, a cursor skips the ones exported already
+		if entry.Timestamp.Equal(position.at) {
+			position.skip++
+		} else {
+			position = exportCursor{at: entry.Timestamp, skip: 1}
+		}
+
+		if cursor != nil && (entry.Timestamp.Before(cursor.at) ||
+			entry.Timestamp.Equal(cursor.at) && position.skip <= cursor.skip) {
+			continue
+		}
+
+		if !filter.match(entry) {
+			continue
+		}
+
+		if len(result) == limit {
+			more = true
+			break
+		}
+		result = append(result, *entry)
+		last = position
+	}
+
+	if !more {
+		return result, "", nil
+	}
+	return result, last.String(), nil
+}
+
+// readAll reads the entries of all users, skipping the files last written before since
+func (al *AccessLogger) readAll(since time.Time) ([]AccessLogEntry, error) {
+	userDirs, err := os.ReadDir(al.baseDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var entries []AccessLogEntry
+	for _, userDir := range userDirs {
+		if !userDir.IsDir() {
+			continue
+		}
+
+		dir := filepath.Join(al.baseDir, userDir.Name())
+		files, err := os.ReadDir(dir)
+		if err != nil {
+			al.logger.Warn("failed to read log dir", "dir", dir, "error", err)
+			continue
+		}
+
+		for _, file := range files {
+			if file.IsDir() || filepath.Ext(file.Name()) != ".log" {
+				continue
+			}
+			if info, err := file.Info(); err == nil && !since.IsZero() && info.ModTime().Before(since) {
+				continue
+			}
+
+			logPath := filepath.Join(dir, file.Name())
+			fileEntries, err := readLogLines(logPath)
+			if err != nil {
+				al.logger.Warn("failed to read log file", "file", logPath, "error", err)
+				continue
+			}
+			entries = append(entries, fileEntries...)
+		}
+	}
+
+	return entries, nil
+}
+
+// readLogLines reads a log file line by line, skipping lines that don't parse, like one being written
+func readLogLines(path string) ([]AccessLogEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []AccessLogEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLogLineSize)
+	for scanner.Scan() {
+		var entry AccessLogEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, scanner.Err()
+}
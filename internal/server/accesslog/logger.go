@@ -12,6 +12,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/openmined/syftbox/internal/server/acl"
+	"github.com/openmined/syftbox/internal/server/redact"
 )
 
 type AccessLogger struct {
@@ -19,18 +20,33 @@ type AccessLogger struct {
 	writers     map[string]*userLogWriter
 	writerMutex sync.RWMutex
 	logger      *slog.Logger
+	redactor    *redact.Redactor
 }
 
-func New(baseDir string, logger *slog.Logger) (*AccessLogger, error) {
+// Option configures the access logger
+type Option func(*AccessLogger)
+
+// WithRedactor scrubs the path, user agent and denied reason of the entries before they are written
+func WithRedactor(r *redact.Redactor) Option {
+	return func(al *AccessLogger) {
+		al.redactor = r
+	}
+}
+
+func New(baseDir string, logger *slog.Logger, opts ...Option) (*AccessLogger, error) {
 	if err := os.MkdirAll(baseDir, LogDirPermission); err != nil {
 		return nil, fmt.Errorf("failed to create log directory: %w", err)
 	}
 
-	return &AccessLogger{
+	al := &AccessLogger{
 		baseDir: baseDir,
 		writers: make(map[string]*userLogWriter),
 		logger:  logger.With("component", "access_logger"),
-	}, nil
+	}
+	for _, opt := range opts {
+		opt(al)
+	}
+	return al, nil
 }
 
 // LogAccess logs an access attempt
@@ -44,22 +60,22 @@ func (al *AccessLogger) LogAccess(ctx *gin.Context, path string, accessType Acce
 
 	entry := AccessLogEntry{
 		Timestamp:    time.Now().UTC(),
-		Path:         path,
+		Path:         al.redactor.String(path),
 		AccessType:   accessType,
 		User:         user,
 		IP:           ctx.ClientIP(),
-		UserAgent:    ctx.Request.UserAgent(),
+		UserAgent:    al.redactor.String(ctx.Request.UserAgent()),
 		Method:       ctx.Request.Method,
 		StatusCode:   ctx.Writer.Status(),
 		Allowed:      allowed,
-		DeniedReason: deniedReason,
+		DeniedReason: al.redactor.String(deniedReason),
 	}
 
 	if err := al.writeLog(user, entry); err != nil {
 		al.logger.Error("failed to write access log",
 			"user", user,
 			"error", err,
-			"path", path)
+			"path", entry.Path)
 	}
 }
 
@@ -11,6 +11,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/openmined/syftbox/internal/server/acl"
+	"github.com/openmined/syftbox/internal/server/redact"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -175,4 +176,26 @@ func TestAccessLogMiddleware(t *testing.T) {
 	router.ServeHTTP(w, req)
 	
 	assert.Equal(t, http.StatusOK, w.Code)
-}
\ No newline at end of file
+}
+
+func TestAccessLoggerRedacts(t *testing.T) {
+	redactor, err := redact.New(&redact.Config{Patterns: []string{`/private/[^/]+`}})
+	require.NoError(t, err)
+
+	logger, err := New(t.TempDir(), slog.Default(), WithRedactor(redactor))
+	require.NoError(t, err)
+	defer logger.Close()
+
+	gin.SetMode(gin.TestMode)
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	ctx.Set("user", "bob@example.com")
+
+	logger.LogAccess(ctx, "alice@example.com/private/diagnosis.pdf", AccessTypeRead, acl.AccessRead, false, "no read access to alice@example.com/private/diagnosis.pdf")
+
+	logs, err := logger.GetUserLogs("bob@example.com", 10)
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+	assert.Equal(t, "alice@example.com[REDACTED]", logs[0].Path)
+	assert.Equal(t, "no read access to alice@example.com[REDACTED]", logs[0].DeniedReason)
+}
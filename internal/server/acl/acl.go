@@ -2,29 +2,111 @@ package acl
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/openmined/syftbox/internal/aclspec"
 	"github.com/openmined/syftbox/internal/server/blob"
+	"github.com/openmined/syftbox/internal/utils"
+	"golang.org/x/sync/singleflight"
+)
+
+var (
+	ErrACLNotLoaded = errors.New("acl rulesets not loaded")
 )
 
 // ACLService helps to manage and enforce access control rules for file system operations.
 type ACLService struct {
-	blob  blob.Service
-	tree  *ACLTree
-	cache *ACLCache
+	blob   blob.Service
+	tree   *ACLTree
+	cache  *ACLCache
+	loaded atomic.Bool
+
+	// held for writing while the tree changes, so that access checks don't see a partial batch
+	mu sync.RWMutex
+
+	// idle datasites are evicted from the tree and reloaded on their next access
+	idleTimeout time.Duration
+	evicted     map[string]struct{} // guarded by mu
+	activityMu  sync.Mutex
+	lastAccess  map[string]time.Time // guarded by activityMu
+	reloads     singleflight.Group
+	now         func() time.Time
+
+	// groups and claims of the requesters, for the conditions of the access lists
+	groups GroupResolver
+	claims ClaimResolver
+
+	// caps on the acl files, nil is unlimited
+	limits *aclspec.RuleSetLimits
+
+	// canonical form of the emails, that the owners and the users of the rules are compared in
+	normalizeEmail func(email string) string
+}
+
+// ACLOption configures the ACL service
+type ACLOption func(*ACLService)
+
+// WithIdleTimeout evicts the rulesets of datasites not accessed for longer than timeout.
+// They are reloaded from the blob storage on their next access. 0 never evicts.
+func WithIdleTimeout(timeout time.Duration) ACLOption {
+	return func(s *ACLService) {
+		s.idleTimeout = timeout
+	}
+}
+
+// WithGroups resolves the groups of the requesters that the conditions of the access lists match on.
+// Without it, the group conditions never match.
+func WithGroups(resolve GroupResolver) ACLOption {
+	return func(s *ACLService) {
+		s.groups = resolve
+	}
+}
+
+// WithClaims resolves the claims of the requesters that the conditions of the access lists match on.
+// Without it, the claims conditions never match.
+func WithClaims(resolve ClaimResolver) ACLOption {
+	return func(s *ACLService) {
+		s.claims = resolve
+	}
+}
+
+// WithEmailNormalizer compares the owners of the datasites and the users of the rules in the canonical form of their emails,
+// so that the rules and the datasites naming any spelling of an email match the requester it normalizes to.
+// Without it, the emails are compared lowercased.
+func WithEmailNormalizer(normalize func(email string) string) ACLOption {
+	return func(s *ACLService) {
+		s.normalizeEmail = normalize
+	}
+}
+
+// WithRuleSetLimits rejects the acl files over the limits. Stored ones over them load as owner-only.
+func WithRuleSetLimits(limits *aclspec.RuleSetLimits) ACLOption {
+	return func(s *ACLService) {
+		s.limits = limits
+	}
 }
 
 // NewACLService creates a new ACL service instance
-func NewACLService(blob blob.Service) *ACLService {
-	return &ACLService{
-		blob:  blob,
-		tree:  NewACLTree(),
-		cache: NewACLCache(),
+func NewACLService(blob blob.Service, opts ...ACLOption) *ACLService {
+	s := &ACLService{
+		blob:           blob,
+		tree:           NewACLTree(),
+		cache:          NewACLCache(),
+		evicted:        make(map[string]struct{}),
+		lastAccess:     make(map[string]time.Time),
+		now:            time.Now,
+		normalizeEmail: utils.NewEmailNormalizer(nil).Normalize,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 func (s *ACLService) Start(ctx context.Context) error {
@@ -48,6 +130,10 @@ func (s *ACLService) Start(ctx context.Context) error {
 
 	if len(ruleSets) == 0 {
 		slog.Warn("no ACL rulesets found")
+		s.loaded.Store(true)
+		if s.idleTimeout > 0 {
+			go s.runEvictions(ctx)
+		}
 		return nil
 	}
 
@@ -61,10 +147,23 @@ func (s *ACLService) Start(ctx context.Context) error {
 	slog.Debug("acl build", "count", len(ruleSets), "took", time.Since(start))
 
 	s.blob.OnBlobChange(s.onBlobChange)
+	s.loaded.Store(true)
+
+	if s.idleTimeout > 0 {
+		go s.runEvictions(ctx)
+	}
 
 	return nil
 }
 
+// Ping returns ErrACLNotLoaded until the ACL rulesets have been loaded on start
+func (s *ACLService) Ping(ctx context.Context) error {
+	if !s.loaded.Load() {
+		return ErrACLNotLoaded
+	}
+	return nil
+}
+
 func (s *ACLService) Shutdown(ctx context.Context) error {
 	slog.Debug("acl service shutdown")
 	return nil
@@ -72,7 +171,15 @@ func (s *ACLService) Shutdown(ctx context.Context) error {
 
 // AddRuleSet adds or updates a new set of rules to the service.
 func (s *ACLService) AddRuleSet(ruleSet *aclspec.RuleSet) (ACLVersion, error) {
-	node, err := s.tree.AddRuleSet(ruleSet)
+	if err := s.lockDatasites(true, getOwner(ACLNormPath(ruleSet.Path))); err != nil {
+		return 0, err
+	}
+	defer s.mu.Unlock()
+	return s.addRuleSet(ruleSet)
+}
+
+func (s *ACLService) addRuleSet(ruleSet *aclspec.RuleSet) (ACLVersion, error) {
+	node, err := s.tree.AddRuleSet(ruleSet.NormalizeUsers(s.normalizeEmail))
 	if err != nil {
 		return 0, err
 	}
@@ -92,6 +199,15 @@ func (s *ACLService) AddRuleSet(ruleSet *aclspec.RuleSet) (ACLVersion, error) {
 // Returns true if a ruleset was removed, false otherwise.
 // path must be a dir or dir/syft.pub.yaml
 func (s *ACLService) RemoveRuleSet(path string) bool {
+	if err := s.lockDatasites(true, getOwner(ACLNormPath(path))); err != nil {
+		slog.Error("ruleset remove error", "path", path, "error", err)
+		return false
+	}
+	defer s.mu.Unlock()
+	return s.removeRuleSet(path)
+}
+
+func (s *ACLService) removeRuleSet(path string) bool {
 	path = aclspec.WithoutACLPath(path)
 	if ok := s.tree.RemoveRuleSet(path); ok {
 		deleted := s.cache.DeletePrefix(path)
@@ -106,13 +222,64 @@ func (s *ACLService) RemoveRuleSet(path string) bool {
 	return false
 }
 
+// ApplyRuleSets adds or updates the rulesets and removes the rulesets at the removed paths as one change.
+// All rulesets are validated first, so either everything is applied or nothing is,
+// and access checks never observe a partially applied change.
+func (s *ACLService) ApplyRuleSets(ruleSets []*aclspec.RuleSet, removed []string) error {
+	for _, ruleSet := range ruleSets {
+		if err := ValidateRuleSet(ruleSet); err != nil {
+			return err
+		}
+	}
+
+	owners := make([]string, 0, len(ruleSets)+len(removed))
+	for _, ruleSet := range ruleSets {
+		owners = append(owners, getOwner(ACLNormPath(ruleSet.Path)))
+	}
+	for _, path := range removed {
+		owners = append(owners, getOwner(ACLNormPath(path)))
+	}
+	if err := s.lockDatasites(true, owners...); err != nil {
+		return err
+	}
+	defer s.mu.Unlock()
+
+	for _, path := range removed {
+		s.removeRuleSet(path)
+	}
+	for _, ruleSet := range ruleSets {
+		if _, err := s.addRuleSet(ruleSet); err != nil {
+			// validated above, so this is a bug in the tree
+			return fmt.Errorf("apply ruleset %s: %w", ruleSet.Path, err)
+		}
+	}
+	return nil
+}
+
 // CanAccess checks if a user has the specified access permission for a file.
 func (s *ACLService) CanAccess(req *ACLRequest) error {
 	// early return if user is the owner
-	if isOwner(req.Path, req.User.ID) {
+	if s.isOwner(req.Path, req.User.ID) {
 		return nil
 	}
 
+	if err := s.lockDatasites(false, getOwner(ACLNormPath(req.Path))); err != nil {
+		return err
+	}
+	defer s.mu.RUnlock()
+
+	if req.User.Attrs == nil {
+		// don't change the caller's user
+		attrs := &Attributes{}
+		if s.groups != nil {
+			attrs.Groups = s.groups(req.User.ID)
+		}
+		if s.claims != nil {
+			attrs.Claims = s.claims(req.User.ID)
+		}
+		req.User = &User{ID: req.User.ID, Attrs: attrs}
+	}
+
 	// check against access cache
 	canAccess, exists := s.cache.Get(req)
 	if exists {
@@ -152,15 +319,29 @@ func (s *ACLService) CanAccess(req *ACLRequest) error {
 	return nil
 }
 
+// isOwner checks if the user is the owner of the datasite of the path.
+// The requesters are authenticated as the canonical email, and datasites created before it was enforced may be named after another spelling.
+func (s *ACLService) isOwner(path string, user string) bool {
+	owner := getOwner(ACLNormPath(path))
+	return owner != "" && (owner == user || s.normalizeEmail(owner) == user)
+}
+
 // String returns a string representation of the ACL service's rule tree.
 func (s *ACLService) String() string {
 	return s.tree.String()
 }
 
-// fetchAcls fetches the ACL rulesets from the blob storage
+// LoadRuleSet parses an acl file, rejecting it with aclspec.ErrRuleSetTooLarge if it's over the limits
+func (s *ACLService) LoadRuleSet(path string, reader io.Reader) (*aclspec.RuleSet, error) {
+	return aclspec.LoadFromReaderWithLimits(path, reader, s.limits)
+}
+
+// fetchAcls fetches the ACL rulesets from the blob storage.
+// A ruleset that can't be fetched fails the whole fetch, as skipping it would fall back to the parent acl
 func (s *ACLService) fetchAcls(ctx context.Context, aclBlobs []*blob.BlobInfo) ([]*aclspec.RuleSet, error) {
 	var mu sync.Mutex
 	var wg sync.WaitGroup
+	var errs []error
 
 	workers := 16
 	jobs := make(chan *blob.BlobInfo)
@@ -180,13 +361,20 @@ func (s *ACLService) fetchAcls(ctx context.Context, aclBlobs []*blob.BlobInfo) (
 				obj, err := blobBackend.GetObject(ctx, blob.Key)
 				if err != nil {
 					slog.Error("ruleset fetch error", "path", blob.Key, "error", err)
+					mu.Lock()
+					errs = append(errs, fmt.Errorf("ruleset %s: %w", blob.Key, err))
+					mu.Unlock()
 					continue
 				}
 
 				// Parse the ACL file
-				ruleset, err := aclspec.LoadFromReader(blob.Key, obj.Body)
+				ruleset, err := s.LoadRuleSet(blob.Key, obj.Body)
 				obj.Body.Close()
-				if err != nil {
+				if errors.Is(err, aclspec.ErrRuleSetTooLarge) {
+					// skipping it would fall back to the parent acl, which may grant more
+					slog.Warn("ruleset over the limits, loaded as owner-only", "path", blob.Key, "error", err)
+					ruleset = aclspec.NewRuleSet(blob.Key, aclspec.Terminal, aclspec.NewDefaultRule(aclspec.PrivateAccess(), aclspec.DefaultLimits()))
+				} else if err != nil {
 					slog.Error("ruleset parse error", "path", blob.Key, "error", err)
 					continue
 				}
@@ -206,6 +394,9 @@ func (s *ACLService) fetchAcls(ctx context.Context, aclBlobs []*blob.BlobInfo) (
 	close(jobs)
 	wg.Wait()
 
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
 	return results, nil
 }
 
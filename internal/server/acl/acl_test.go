@@ -3,13 +3,16 @@ package acl
 import (
 	"context"
 	"iter"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/openmined/syftbox/internal/aclspec"
 	"github.com/openmined/syftbox/internal/server/blob"
+	"github.com/openmined/syftbox/internal/utils"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 // MockBlobService is a mock implementation of blob.Service
@@ -27,6 +30,10 @@ func (m *MockBlobService) Index() blob.IBlobIndex {
 	return args.Get(0).(blob.IBlobIndex)
 }
 
+func (m *MockBlobService) ReadIndex() blob.IBlobIndex {
+	return m.Index()
+}
+
 func (m *MockBlobService) OnBlobChange(callback blob.BlobChangeCallback) {
 	m.Called(callback)
 }
@@ -56,6 +63,16 @@ func (m *MockBlobIndex) Remove(key string) error {
 	return args.Error(0)
 }
 
+func (m *MockBlobIndex) Usage(datasite string) int64 {
+	args := m.Called(datasite)
+	return args.Get(0).(int64)
+}
+
+func (m *MockBlobIndex) Revisions(key string) ([]*blob.BlobRevision, error) {
+	args := m.Called(key)
+	return args.Get(0).([]*blob.BlobRevision), args.Error(1)
+}
+
 func (m *MockBlobIndex) List() ([]*blob.BlobInfo, error) {
 	args := m.Called()
 	return args.Get(0).([]*blob.BlobInfo), args.Error(1)
@@ -121,8 +138,8 @@ func (m *MockBlobBackend) PutObject(ctx context.Context, params *blob.PutObjectP
 	return args.Get(0).(*blob.PutObjectResponse), args.Error(1)
 }
 
-func (m *MockBlobBackend) PutObjectPresigned(ctx context.Context, key string) (string, error) {
-	args := m.Called(ctx, key)
+func (m *MockBlobBackend) PutObjectPresigned(ctx context.Context, key string, size int64) (string, error) {
+	args := m.Called(ctx, key, size)
 	return args.String(0), args.Error(1)
 }
 
@@ -321,6 +338,36 @@ func TestAclServiceCanAccess(t *testing.T) {
 	assert.ErrorIs(t, err, ErrNoAdminAccess)
 }
 
+func TestAclServiceNormalizedEmails(t *testing.T) {
+	service := aclSvc()
+	WithEmailNormalizer(utils.NewEmailNormalizer([]string{"gmail.com"}).Normalize)(service)
+
+	// a datasite created before the normalization, sharing with other spellings of the emails
+	_, err := service.AddRuleSet(aclspec.NewRuleSet(
+		"A.Lice@gmail.com",
+		aclspec.Terminal,
+		aclspec.NewRule("shared/*.txt", aclspec.SharedReadAccess("Bob@Example.com", "c.arol+work@gmail.com"), aclspec.DefaultLimits()),
+		aclspec.NewRule("**", aclspec.PrivateAccess(), aclspec.DefaultLimits()),
+	))
+	require.NoError(t, err)
+
+	// the requesters are authenticated as the canonical emails
+	owner := &User{ID: "alice@gmail.com"}
+	bob := &User{ID: "bob@example.com"}
+	carol := &User{ID: "carol@gmail.com"}
+	dave := &User{ID: "dave@gmail.com"}
+
+	assert.NoError(t, service.CanAccess(NewRequest("A.Lice@gmail.com/private/a.txt", owner, AccessWrite)))
+	assert.NoError(t, service.CanAccess(NewRequest(aclspec.AsACLPath("A.Lice@gmail.com"), owner, AccessAdmin)))
+	assert.NoError(t, service.CanAccess(NewRequest("A.Lice@gmail.com/shared/a.txt", bob, AccessRead)))
+	assert.NoError(t, service.CanAccess(NewRequest("A.Lice@gmail.com/shared/a.txt", carol, AccessRead)))
+	assert.ErrorIs(t, service.CanAccess(NewRequest("A.Lice@gmail.com/shared/a.txt", dave, AccessRead)), ErrNoReadAccess)
+	assert.ErrorIs(t, service.CanAccess(NewRequest("A.Lice@gmail.com/private/a.txt", bob, AccessRead)), ErrNoReadAccess)
+
+	// the owner must be the whole datasite name, not a prefix of it
+	assert.False(t, service.isOwner("alice@gmail.com.au/a.txt", "alice@gmail.com"))
+}
+
 func TestAclServiceFileLimits(t *testing.T) {
 	service := aclSvc()
 
@@ -727,3 +774,28 @@ func TestAclServiceComplexTemplatePatterns(t *testing.T) {
 		assert.NotEmpty(t, rule.rule.Pattern)
 	}
 }
+
+func TestAclServiceRuleSetLimits(t *testing.T) {
+	twoRulesACL := publicReadACL + "  - pattern: 'data/**'\n    access:\n      read: ['bob@example.com']\n"
+	blobs := &fakeBlobs{objects: map[string]string{
+		"alice@example.com/syft.pub.yaml":        publicReadACL,
+		"alice@example.com/public/syft.pub.yaml": twoRulesACL,
+		"alice@example.com/public/file.txt":      "hello",
+		"bob@example.com/public/syft.pub.yaml":   publicReadACL,
+	}}
+
+	s := NewACLService(blobs, WithRuleSetLimits(&aclspec.RuleSetLimits{MaxRules: 1}))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, s.Start(ctx))
+
+	// a stored acl over the limits is owner-only, not skipped for the acl above it
+	assert.False(t, canRead(s, "bob@example.com", "alice@example.com/public/file.txt"))
+	assert.True(t, canRead(s, "alice@example.com", "alice@example.com/public/file.txt"))
+	assert.True(t, canRead(s, "alice@example.com", "bob@example.com/public/file.txt"))
+
+	_, err := s.LoadRuleSet("alice@example.com/public/syft.pub.yaml", strings.NewReader(twoRulesACL))
+	assert.ErrorIs(t, err, aclspec.ErrRuleSetTooLarge)
+	_, err = s.LoadRuleSet("alice@example.com/public/syft.pub.yaml", strings.NewReader(publicReadACL))
+	assert.NoError(t, err)
+}
@@ -19,6 +19,9 @@ type aclCacheKey string
 // but it is required to keep things fast for static/templated/user-specific rules.
 // the TTL & max size should keep it in check + selective sync will reduce the number of keys
 func newCacheKeyByUser(req *ACLRequest) aclCacheKey {
+	if attrs := req.User.Attrs.cacheKey(); attrs != "" {
+		return aclCacheKey(fmt.Sprintf("%s:%s:%d:%s", req.Path, req.User.ID, req.Level, attrs))
+	}
 	return aclCacheKey(fmt.Sprintf("%s:%s:%d", req.Path, req.User.ID, req.Level))
 }
 
@@ -0,0 +1,65 @@
+package acl
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/openmined/syftbox/internal/aclspec"
+)
+
+// matchConditions returns true if the user matches any of the conditions
+func matchConditions(conditions []*aclspec.Condition, user *User) bool {
+	return slices.ContainsFunc(conditions, func(cond *aclspec.Condition) bool {
+		return matchCondition(cond, user)
+	})
+}
+
+// matchCondition returns true if the user matches all the fields of the condition
+func matchCondition(cond *aclspec.Condition, user *User) bool {
+	if cond.Domain != "" {
+		_, domain, ok := strings.Cut(user.ID, "@")
+		if !ok || !strings.EqualFold(domain, cond.Domain) {
+			return false
+		}
+	}
+
+	if len(cond.Claims) == 0 && cond.Group == "" {
+		return true
+	}
+
+	// everything else is matched on the attributes
+	if user.Attrs == nil {
+		return false
+	}
+
+	// the claim names are case-insensitive, as the server config lowercases them
+	for name, value := range cond.Claims {
+		if claim, ok := user.Attrs.Claims[strings.ToLower(name)]; !ok || claim != value {
+			return false
+		}
+	}
+
+	if cond.Group != "" && !slices.ContainsFunc(user.Attrs.Groups, func(group string) bool {
+		return strings.EqualFold(group, cond.Group)
+	}) {
+		return false
+	}
+
+	return true
+}
+
+// cacheKey identifies the attributes in the access cache, as the same user may get a different access with others.
+func (a *Attributes) cacheKey() string {
+	if a == nil || (len(a.Groups) == 0 && len(a.Claims) == 0) {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString(strings.Join(slices.Sorted(slices.Values(a.Groups)), ","))
+	for _, name := range slices.Sorted(maps.Keys(a.Claims)) {
+		fmt.Fprintf(&b, ";%s=%s", name, a.Claims[name])
+	}
+	return b.String()
+}
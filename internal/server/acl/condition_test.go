@@ -0,0 +1,93 @@
+package acl
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/openmined/syftbox/internal/aclspec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const conditionsACL = `
+rules:
+  - pattern: "reports/**"
+    access:
+      read:
+        - bob@example.com
+        - domain: lab.org
+          claims:
+            org_verified: "true"
+  - pattern: "shared/**"
+    access:
+      write:
+        - group: researchers
+  - pattern: "domain/**"
+    access:
+      read:
+        - domain: Lab.org
+`
+
+func conditionsSvc(t *testing.T, groups map[string][]string, claims map[string]map[string]string) *ACLService {
+	t.Helper()
+	service := aclSvc()
+	service.groups = func(user string) []string { return groups[user] }
+	service.claims = func(user string) map[string]string { return claims[user] }
+
+	ruleset, err := aclspec.LoadFromReader("alice@example.com", strings.NewReader(conditionsACL))
+	require.NoError(t, err)
+	_, err = service.AddRuleSet(ruleset)
+	require.NoError(t, err)
+	return service
+}
+
+func TestAclServiceDomainCondition(t *testing.T) {
+	service := conditionsSvc(t, nil, nil)
+
+	// the domain is matched without the case
+	assert.NoError(t, service.CanAccess(NewRequest("alice@example.com/domain/doc.txt", &User{ID: "carol@LAB.org"}, AccessRead)))
+	assert.ErrorIs(t, service.CanAccess(NewRequest("alice@example.com/domain/doc.txt", &User{ID: "carol@lab.org.evil.com"}, AccessRead)), ErrNoReadAccess)
+	assert.ErrorIs(t, service.CanAccess(NewRequest("alice@example.com/domain/doc.txt", &User{ID: "carol@sub.lab.org"}, AccessRead)), ErrNoReadAccess)
+	assert.ErrorIs(t, service.CanAccess(NewRequest("alice@example.com/domain/doc.txt", &User{ID: aclspec.TokenEveryone}, AccessRead)), ErrNoReadAccess)
+
+	// a read condition doesn't grant writes
+	assert.ErrorIs(t, service.CanAccess(NewRequest("alice@example.com/domain/doc.txt", &User{ID: "carol@lab.org"}, AccessWrite)), ErrNoWriteAccess)
+}
+
+func TestAclServiceClaimCondition(t *testing.T) {
+	claims := map[string]map[string]string{
+		"verified@lab.org":   {"org_verified": "true"},
+		"pending@lab.org":    {"org_verified": "false"},
+		"verified@other.org": {"org_verified": "true"},
+	}
+	service := conditionsSvc(t, nil, claims)
+
+	path := "alice@example.com/reports/q3.csv"
+
+	// the domain and the claim of the user must both match
+	assert.NoError(t, service.CanAccess(NewRequest(path, &User{ID: "verified@lab.org"}, AccessRead)))
+	assert.ErrorIs(t, service.CanAccess(NewRequest(path, &User{ID: "pending@lab.org"}, AccessRead)), ErrNoReadAccess)
+	assert.ErrorIs(t, service.CanAccess(NewRequest(path, &User{ID: "verified@other.org"}, AccessRead)), ErrNoReadAccess)
+	assert.ErrorIs(t, service.CanAccess(NewRequest(path, &User{ID: "nobody@lab.org"}, AccessRead)), ErrNoReadAccess)
+
+	// the users of the plain list keep their access
+	assert.NoError(t, service.CanAccess(NewRequest(path, &User{ID: "bob@example.com"}, AccessRead)))
+
+	// the claims are resolved on every request, a cached grant doesn't outlive them
+	claims["verified@lab.org"] = nil
+	assert.ErrorIs(t, service.CanAccess(NewRequest(path, &User{ID: "verified@lab.org"}, AccessRead)), ErrNoReadAccess)
+}
+
+func TestAclServiceGroupCondition(t *testing.T) {
+	service := conditionsSvc(t, map[string][]string{
+		"dana@example.com": {"researchers"},
+		"erin@example.com": {"interns"},
+	}, nil)
+
+	path := "alice@example.com/shared/data.parquet"
+
+	assert.NoError(t, service.CanAccess(NewRequest(path, &User{ID: "dana@example.com"}, AccessWrite)))
+	assert.NoError(t, service.CanAccess(NewRequest(path, &User{ID: "dana@example.com"}, AccessRead)), "writers can read")
+	assert.ErrorIs(t, service.CanAccess(NewRequest(path, &User{ID: "erin@example.com"}, AccessWrite)), ErrNoWriteAccess)
+	assert.ErrorIs(t, service.CanAccess(NewRequest(path, &User{ID: "frank@example.com"}, AccessRead)), ErrNoReadAccess)
+}
@@ -0,0 +1,149 @@
+package acl
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/openmined/syftbox/internal/aclspec"
+)
+
+// lockDatasites takes the service lock, for writing or reading, with the rulesets of the owners'
+// datasites loaded. Evicted datasites are reloaded first, the lock is only returned once none
+// of them is evicted, so an eviction racing with the reload can't leave the caller with a
+// partial tree.
+func (s *ACLService) lockDatasites(write bool, owners ...string) error {
+	lock, unlock := s.mu.RLock, s.mu.RUnlock
+	if write {
+		lock, unlock = s.mu.Lock, s.mu.Unlock
+	}
+
+	if s.idleTimeout <= 0 {
+		lock()
+		return nil
+	}
+
+	s.touch(owners...)
+
+	for {
+		lock()
+		var evicted []string
+		for _, owner := range owners {
+			if _, ok := s.evicted[owner]; ok {
+				evicted = append(evicted, owner)
+			}
+		}
+		if len(evicted) == 0 {
+			return nil
+		}
+		unlock()
+
+		for _, owner := range evicted {
+			if err := s.reload(owner); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// touch marks the datasites as accessed now
+func (s *ACLService) touch(owners ...string) {
+	now := s.now()
+	s.activityMu.Lock()
+	defer s.activityMu.Unlock()
+	for _, owner := range owners {
+		if owner != "" {
+			s.lastAccess[owner] = now
+		}
+	}
+}
+
+// reload loads the rulesets of an evicted datasite from the blob storage.
+// Concurrent reloads of the same datasite share one fetch.
+func (s *ACLService) reload(owner string) error {
+	_, err, _ := s.reloads.Do(owner, func() (any, error) {
+		s.mu.RLock()
+		_, evicted := s.evicted[owner]
+		s.mu.RUnlock()
+		if !evicted {
+			return nil, nil
+		}
+
+		start := time.Now()
+		blobs, err := s.blob.Index().FilterByPrefix(owner + ACLPathSep)
+		if err != nil {
+			return nil, fmt.Errorf("error listing acls of %s: %w", owner, err)
+		}
+		acls := blobs[:0]
+		for _, blob := range blobs {
+			if aclspec.IsACLFile(blob.Key) {
+				acls = append(acls, blob)
+			}
+		}
+
+		ruleSets, err := s.fetchAcls(context.Background(), acls)
+		if err != nil {
+			return nil, fmt.Errorf("error fetching acls of %s: %w", owner, err)
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for _, ruleSet := range ruleSets {
+			if _, err := s.addRuleSet(ruleSet); err != nil {
+				slog.Warn("ruleset update error", "path", ruleSet.Path, "error", err)
+			}
+		}
+		delete(s.evicted, owner)
+
+		slog.Debug("acl datasite reloaded", "owner", owner, "count", len(ruleSets), "took", time.Since(start))
+		return nil, nil
+	})
+	return err
+}
+
+// runEvictions periodically evicts the idle datasites until ctx is done
+func (s *ACLService) runEvictions(ctx context.Context) {
+	ticker := time.NewTicker(max(s.idleTimeout/2, time.Second))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if evicted := s.evictIdle(s.now()); len(evicted) > 0 {
+				slog.Debug("acl evicted idle datasites", "count", len(evicted), "idle_timeout", s.idleTimeout)
+			}
+		}
+	}
+}
+
+// evictIdle removes the rulesets and cached access checks of the datasites not accessed
+// since idleTimeout before now, and returns their owners
+func (s *ACLService) evictIdle(now time.Time) []string {
+	cutoff := now.Add(-s.idleTimeout)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.activityMu.Lock()
+	defer s.activityMu.Unlock()
+
+	var evicted []string
+	for owner, lastAccess := range s.lastAccess {
+		if lastAccess.After(cutoff) {
+			continue
+		}
+		delete(s.lastAccess, owner)
+
+		// nothing loaded for datasites without rulesets
+		if _, ok := s.tree.root.GetChild(owner); !ok {
+			continue
+		}
+		s.tree.root.DeleteChild(owner)
+		s.cache.DeletePrefix(owner + ACLPathSep)
+		s.evicted[owner] = struct{}{}
+		evicted = append(evicted, owner)
+	}
+	return evicted
+}
@@ -0,0 +1,192 @@
+package acl
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/openmined/syftbox/internal/server/blob"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const publicReadACL = "rules:\n  - pattern: '**'\n    access:\n      read: ['*']\n"
+
+// fakeBlobs serves ACL files from memory and counts the fetches
+type fakeBlobs struct {
+	blob.IBlobBackend
+	blob.IBlobIndex
+	objects map[string]string
+	fetches atomic.Int32
+	failing atomic.Value // fetches of this key fail, like an unreachable storage
+}
+
+func (f *fakeBlobs) Backend() blob.IBlobBackend                    { return f }
+func (f *fakeBlobs) Index() blob.IBlobIndex                        { return f }
+func (f *fakeBlobs) ReadIndex() blob.IBlobIndex                    { return f.Index() }
+func (f *fakeBlobs) OnBlobChange(callback blob.BlobChangeCallback) {}
+func (f *fakeBlobs) Count() int                                    { return len(f.objects) }
+
+func (f *fakeBlobs) GetObject(ctx context.Context, key string) (*blob.GetObjectResponse, error) {
+	f.fetches.Add(1)
+	if failing, _ := f.failing.Load().(string); failing == key {
+		return nil, errors.New("storage unavailable")
+	}
+	content, ok := f.objects[key]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return &blob.GetObjectResponse{Body: io.NopCloser(bytes.NewReader([]byte(content))), Size: int64(len(content))}, nil
+}
+
+func (f *fakeBlobs) FilterByPrefix(prefix string) ([]*blob.BlobInfo, error) {
+	return f.filter(func(key string) bool { return strings.HasPrefix(key, prefix) }), nil
+}
+
+func (f *fakeBlobs) FilterBySuffix(suffix string) ([]*blob.BlobInfo, error) {
+	return f.filter(func(key string) bool { return strings.HasSuffix(key, suffix) }), nil
+}
+
+func (f *fakeBlobs) filter(match func(string) bool) []*blob.BlobInfo {
+	var blobs []*blob.BlobInfo
+	for key := range f.objects {
+		if match(key) {
+			blobs = append(blobs, &blob.BlobInfo{Key: key})
+		}
+	}
+	return blobs
+}
+
+func newIdleTestService(t *testing.T, now *time.Time) (*ACLService, *fakeBlobs) {
+	t.Helper()
+	blobs := &fakeBlobs{objects: map[string]string{
+		"alice@example.com/public/syft.pub.yaml": publicReadACL,
+		"alice@example.com/public/file.txt":      "hello",
+		"bob@example.com/public/syft.pub.yaml":   publicReadACL,
+	}}
+
+	s := NewACLService(blobs, WithIdleTimeout(time.Hour))
+	s.now = func() time.Time { return *now }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	require.NoError(t, s.Start(ctx))
+	return s, blobs
+}
+
+func canRead(s *ACLService, user string, path string) bool {
+	return s.CanAccess(NewRequest(path, &User{ID: user}, AccessRead)) == nil
+}
+
+func TestAclServiceEvictIdle(t *testing.T) {
+	now := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	s, blobs := newIdleTestService(t, &now)
+	require.EqualValues(t, 2, blobs.fetches.Load())
+
+	assert.True(t, canRead(s, "carol@example.com", "alice@example.com/public/file.txt"))
+	assert.False(t, canRead(s, "carol@example.com", "alice@example.com/private/file.txt"))
+
+	// only bob's datasite is accessed after a while
+	now = now.Add(30 * time.Minute)
+	assert.True(t, canRead(s, "carol@example.com", "bob@example.com/public/file.txt"))
+
+	now = now.Add(40 * time.Minute)
+	assert.Equal(t, []string{"alice@example.com"}, s.evictIdle(now))
+
+	_, ok := s.tree.root.GetChild("alice@example.com")
+	assert.False(t, ok, "alice's rulesets are evicted")
+	_, ok = s.tree.root.GetChild("bob@example.com")
+	assert.True(t, ok, "bob's rulesets are kept")
+	assert.Zero(t, s.cache.DeletePrefix("alice@example.com/"), "alice's cached checks are dropped")
+
+	// the next access reloads alice's rulesets, and only hers
+	assert.True(t, canRead(s, "carol@example.com", "alice@example.com/public/file.txt"))
+	assert.False(t, canRead(s, "carol@example.com", "alice@example.com/private/file.txt"))
+	assert.EqualValues(t, 3, blobs.fetches.Load())
+
+	_, ok = s.tree.root.GetChild("alice@example.com")
+	assert.True(t, ok)
+	assert.Empty(t, s.evictIdle(now), "alice was just accessed")
+}
+
+func TestAclServiceEvictIdleWrites(t *testing.T) {
+	now := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	s, _ := newIdleTestService(t, &now)
+
+	now = now.Add(2 * time.Hour)
+	assert.Len(t, s.evictIdle(now), 2)
+
+	// removing a ruleset of an evicted datasite reloads it first, so the removal isn't lost
+	assert.True(t, s.RemoveRuleSet("alice@example.com/public/syft.pub.yaml"))
+	assert.False(t, canRead(s, "carol@example.com", "alice@example.com/public/file.txt"))
+	assert.True(t, canRead(s, "carol@example.com", "bob@example.com/public/file.txt"))
+}
+
+func TestAclServiceReloadFailed(t *testing.T) {
+	now := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	s, blobs := newIdleTestService(t, &now)
+	private := "alice@example.com/public/private/syft.pub.yaml"
+	blobs.objects[private] = "terminal: true\nrules: []\n"
+	ruleSet, err := s.LoadRuleSet(private, strings.NewReader(blobs.objects[private]))
+	require.NoError(t, err)
+	_, err = s.AddRuleSet(ruleSet)
+	require.NoError(t, err)
+	assert.False(t, canRead(s, "carol@example.com", "alice@example.com/public/private/file.txt"))
+
+	now = now.Add(2 * time.Hour)
+	assert.Len(t, s.evictIdle(now), 2)
+
+	// a failed reload denies access, instead of falling back to the parent ruleset
+	blobs.failing.Store(private)
+	assert.False(t, canRead(s, "carol@example.com", "alice@example.com/public/private/file.txt"))
+	assert.False(t, canRead(s, "carol@example.com", "alice@example.com/public/file.txt"))
+	_, evicted := s.evicted["alice@example.com"]
+	assert.True(t, evicted, "alice's datasite stays evicted")
+
+	// the next access reloads it again
+	blobs.failing.Store("")
+	assert.True(t, canRead(s, "carol@example.com", "alice@example.com/public/file.txt"))
+	assert.False(t, canRead(s, "carol@example.com", "alice@example.com/public/private/file.txt"))
+}
+
+func TestAclServiceEvictIdleConcurrent(t *testing.T) {
+	now := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	s, _ := newIdleTestService(t, &now)
+
+	// access checks run while everything is evicted over and over
+	var denied atomic.Int32
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 200 {
+				if !canRead(s, "carol@example.com", "alice@example.com/public/file.txt") {
+					denied.Add(1)
+				}
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	for evicting := true; evicting; {
+		select {
+		case <-done:
+			evicting = false
+		default:
+			s.evictIdle(now.Add(2 * time.Hour))
+		}
+	}
+
+	assert.Zero(t, denied.Load(), "access checks never see an evicted datasite")
+}
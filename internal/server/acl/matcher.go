@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/bmatcuk/doublestar/v4"
+	"github.com/openmined/syftbox/internal/aclspec"
 )
 
 type MatchContext any // i don't know what all we'll need, so just putting it as any
@@ -161,7 +162,7 @@ func (t *TemplateMatcher) Type() MatcherType {
 }
 
 func hasTemplatePattern(pattern string) bool {
-	return strings.Contains(pattern, "{{") && strings.Contains(pattern, "}}")
+	return aclspec.IsTemplatePattern(pattern)
 }
 
 // ----------------------------------------------------------------------------
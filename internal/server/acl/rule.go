@@ -83,10 +83,11 @@ func (r *ACLRule) CheckAccess(req *ACLRequest) error {
 	writeUsers := r.resolveAccessList(r.rule.Access.Write, req.User.ID)
 	readUsers := r.resolveAccessList(r.rule.Access.Read, req.User.ID)
 
-	// Check permissions hierarchically
-	isAdmin := r.hasAccess(adminUsers, req.User.ID)
-	isWriter := isAdmin || r.hasAccess(writeUsers, req.User.ID)
-	isReader := isWriter || r.hasAccess(readUsers, req.User.ID)
+	// Check permissions hierarchically, on the users then the conditions
+	access := r.rule.Access
+	isAdmin := r.hasAccess(adminUsers, req.User.ID) || matchConditions(access.AdminConditions, req.User)
+	isWriter := isAdmin || r.hasAccess(writeUsers, req.User.ID) || matchConditions(access.WriteConditions, req.User)
+	isReader := isWriter || r.hasAccess(readUsers, req.User.ID) || matchConditions(access.ReadConditions, req.User)
 
 	// Use a switch with fallthrough for permission hierarchy
 	switch req.Level {
@@ -203,6 +204,10 @@ func (r *ACLRule) Compile(user *User) *ACLRule {
 			Admin: r.resolveAccessList(r.rule.Access.Admin, user.ID),
 			Write: r.resolveAccessList(r.rule.Access.Write, user.ID),
 			Read:  r.resolveAccessList(r.rule.Access.Read, user.ID),
+
+			AdminConditions: r.rule.Access.AdminConditions,
+			WriteConditions: r.rule.Access.WriteConditions,
+			ReadConditions:  r.rule.Access.ReadConditions,
 		},
 		Limits: r.rule.Limits,
 	}
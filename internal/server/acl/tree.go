@@ -32,35 +32,45 @@ func NewACLTree() *ACLTree {
 	}
 }
 
-// Add or update a ruleset in the tree.
-func (t *ACLTree) AddRuleSet(ruleset *aclspec.RuleSet) (*ACLNode, error) {
-	// Validate the ruleset
+// ValidateRuleSet checks that a ruleset can be added to the tree.
+func ValidateRuleSet(ruleset *aclspec.RuleSet) error {
 	if ruleset == nil {
-		return nil, fmt.Errorf("%w: ruleset is nil", ErrInvalidRuleset)
+		return fmt.Errorf("%w: ruleset is nil", ErrInvalidRuleset)
 	}
 
-	allRules := ruleset.AllRules()
-	if len(allRules) == 0 {
-		return nil, fmt.Errorf("%w: ruleset is empty", ErrInvalidRuleset)
+	if len(ruleset.AllRules()) == 0 {
+		return fmt.Errorf("%w: ruleset is empty", ErrInvalidRuleset)
 	}
 
-	// Clean and split the path
 	cleanPath := ACLNormPath(ruleset.Path)
-	parts := strings.Split(cleanPath, ACLPathSep)
-	pathDepth := strings.Count(cleanPath, ACLPathSep)
 
 	// owner is assumed to be the first part of the path.
 	// but in future we can always bake it as a part of the acl schema
-	owner := parts[0]
-	if owner == "" {
-		return nil, fmt.Errorf("%w: owner is empty", ErrInvalidRuleset)
+	if owner, _, _ := strings.Cut(cleanPath, ACLPathSep); owner == "" {
+		return fmt.Errorf("%w: owner is empty", ErrInvalidRuleset)
 	}
 
 	// Check path depth limit (u8)
-	if pathDepth > ACLMaxDepth {
-		return nil, ErrMaxDepthExceeded
+	if strings.Count(cleanPath, ACLPathSep) > ACLMaxDepth {
+		return ErrMaxDepthExceeded
 	}
 
+	return nil
+}
+
+// Add or update a ruleset in the tree.
+func (t *ACLTree) AddRuleSet(ruleset *aclspec.RuleSet) (*ACLNode, error) {
+	if err := ValidateRuleSet(ruleset); err != nil {
+		return nil, err
+	}
+
+	allRules := ruleset.AllRules()
+
+	// Clean and split the path
+	cleanPath := ACLNormPath(ruleset.Path)
+	parts := strings.Split(cleanPath, ACLPathSep)
+	owner := parts[0]
+
 	// Start at the root node
 	current := t.root
 	currentDepth := 0
@@ -1,9 +1,22 @@
 package acl
 
 type User struct {
-	ID string
+	ID    string
+	Attrs *Attributes // resolved by the service when nil
 }
 
+// Attributes of a user that the conditions of the access lists match on
+type Attributes struct {
+	Groups []string          // groups the user is a member of
+	Claims map[string]string // claims of the user, with lowercased names
+}
+
+// GroupResolver returns the groups a user is a member of
+type GroupResolver func(userID string) []string
+
+// ClaimResolver returns the claims of a user, with lowercased names
+type ClaimResolver func(userID string) map[string]string
+
 type File struct {
 	IsDir     bool
 	IsSymlink bool
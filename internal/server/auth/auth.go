@@ -6,7 +6,11 @@ import (
 	"crypto/rand"
 	"fmt"
 	"log/slog"
+	"maps"
 	"math/big"
+	"slices"
+	"strings"
+	"sync/atomic"
 	"text/template"
 	"time"
 
@@ -22,26 +26,179 @@ type EmailString = string
 type OTPString = string
 
 type AuthService struct {
-	config        *Config
-	codes         *expirable.LRU[EmailString, OTPString]
+	config        atomic.Pointer[Config]
+	codes         atomic.Pointer[expirable.LRU[EmailString, OTPString]]
+	rules         atomic.Pointer[emailRules]
 	emailTemplate *template.Template
 	emailSvc      email.Service
+	normalizer    *utils.EmailNormalizer
+	revocations   *Revocations // nil when tokens can't be revoked
 }
 
-func NewAuthService(config *Config, emailSvc email.Service) *AuthService {
-	return &AuthService{
-		config:        config,
-		codes:         expirable.NewLRU[EmailString, OTPString](0, nil, config.EmailOTPExpiry), // 0 = LRU off
+// emailRules are the rules built from the allowed, denied, group and user claims emails of the config
+type emailRules struct {
+	filter *EmailFilter
+	groups map[string][]string // normalized member rules of each group
+	claims []UserClaims        // normalized emails or patterns, and lowercased claims
+}
+
+func newEmailRules(config *Config) (*emailRules, error) {
+	filter, err := NewEmailFilter(config.AllowedEmails, config.DeniedEmails)
+	if err != nil {
+		return nil, fmt.Errorf("email filter: %w", err)
+	}
+
+	groups := make(map[string][]string, len(config.Groups))
+	for name, members := range config.Groups {
+		if groups[strings.ToLower(name)], err = normalizeEmailRules(members); err != nil {
+			return nil, fmt.Errorf("group %q: %w", name, err)
+		}
+	}
+
+	claims := make([]UserClaims, 0, len(config.UserClaims))
+	for i, user := range config.UserClaims {
+		normalized, err := normalizeUserClaims(user)
+		if err != nil {
+			return nil, fmt.Errorf("user_claims[%d]: %w", i, err)
+		}
+		claims = append(claims, normalized)
+	}
+	return &emailRules{filter: filter, groups: groups, claims: claims}, nil
+}
+
+// normalizeUserClaims lowercases the email or pattern and the claim names
+func normalizeUserClaims(user UserClaims) (UserClaims, error) {
+	rules, err := normalizeEmailRules([]string{user.Email})
+	if err != nil {
+		return UserClaims{}, err
+	}
+	if len(rules) == 0 {
+		return UserClaims{}, fmt.Errorf("email required")
+	}
+
+	claims := make(map[string]string, len(user.Claims))
+	for name, value := range user.Claims {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			return UserClaims{}, fmt.Errorf("empty claim name")
+		}
+		claims[name] = value
+	}
+	return UserClaims{Email: rules[0], Claims: claims}, nil
+}
+
+func NewAuthService(config *Config, emailSvc email.Service, revocations *Revocations) (*AuthService, error) {
+	rules, err := newEmailRules(config)
+	if err != nil {
+		return nil, err
+	}
+
+	svc := &AuthService{
 		emailTemplate: template.Must(template.New("emailTemplate").Parse(emailTemplate)),
 		emailSvc:      emailSvc,
+		normalizer:    utils.NewEmailNormalizer(config.EmailDotPlusDomains),
+		revocations:   revocations,
+	}
+	svc.config.Store(config)
+	svc.codes.Store(newCodes(config.EmailOTPExpiry))
+	svc.rules.Store(rules)
+	return svc, nil
+}
+
+func newCodes(expiry time.Duration) *expirable.LRU[EmailString, OTPString] {
+	return expirable.NewLRU[EmailString, OTPString](0, nil, expiry) // 0 = LRU off
+}
+
+// SetConfig applies the settings of config that can change while running: the expiry of the tokens issued
+// from now on, the otp emails, the allowed, denied, admin and group emails, and the claims of the users.
+// The issuer, the secrets and the email normalization are kept, they are only applied on restart.
+// Issued tokens keep their expiry, and the codes sent before are dropped if the otp length or expiry changed.
+func (s *AuthService) SetConfig(config *Config) error {
+	rules, err := newEmailRules(config)
+	if err != nil {
+		return err
 	}
+
+	current := *s.config.Load()
+	if current.EmailOTPLength != config.EmailOTPLength || current.EmailOTPExpiry != config.EmailOTPExpiry {
+		s.codes.Store(newCodes(config.EmailOTPExpiry))
+	}
+	current.AccessTokenExpiry = config.AccessTokenExpiry
+	current.RefreshTokenExpiry = config.RefreshTokenExpiry
+	current.EmailAddr = config.EmailAddr
+	current.EmailOTPLength = config.EmailOTPLength
+	current.EmailOTPExpiry = config.EmailOTPExpiry
+	current.AllowedEmails = config.AllowedEmails
+	current.DeniedEmails = config.DeniedEmails
+	current.AdminEmails = config.AdminEmails
+	current.Groups = config.Groups
+	current.UserClaims = config.UserClaims
+	s.config.Store(&current)
+	s.rules.Store(rules)
+	return nil
 }
 
 func (s *AuthService) IsEnabled() bool {
-	return s.config.Enabled
+	return s.config.Load().Enabled
+}
+
+// NormalizeEmail returns the canonical form of an email, the one its tokens and its datasite are issued for
+func (s *AuthService) NormalizeEmail(userEmail EmailString) EmailString {
+	return s.normalizer.Normalize(userEmail)
+}
+
+// CheckEmail returns ErrEmailNotAllowed if the email is gated by the allowed/denied email rules
+func (s *AuthService) CheckEmail(userEmail EmailString) error {
+	return s.rules.Load().filter.Check(userEmail)
+}
+
+// IsAdmin returns true if the email is one of the configured admin emails
+func (s *AuthService) IsAdmin(userEmail EmailString) bool {
+	return slices.ContainsFunc(s.config.Load().AdminEmails, func(admin string) bool {
+		return s.NormalizeEmail(admin) == s.NormalizeEmail(userEmail)
+	})
+}
+
+// Claims returns the claims of the entries matching the email, with lowercased names. nil without any
+func (s *AuthService) Claims(userEmail EmailString) map[string]string {
+	userEmail = strings.ToLower(strings.TrimSpace(userEmail))
+
+	var claims map[string]string
+	for _, user := range s.rules.Load().claims {
+		if !matchEmailRules([]string{user.Email}, userEmail) {
+			continue
+		}
+		if claims == nil {
+			claims = make(map[string]string, len(user.Claims))
+		}
+		maps.Copy(claims, user.Claims)
+	}
+	return claims
+}
+
+// Groups returns the groups the email is a member of, sorted
+func (s *AuthService) Groups(userEmail EmailString) []string {
+	userEmail = strings.ToLower(strings.TrimSpace(userEmail))
+
+	var groups []string
+	for name, members := range s.rules.Load().groups {
+		if matchEmailRules(members, userEmail) {
+			groups = append(groups, name)
+		}
+	}
+	slices.Sort(groups)
+	return groups
 }
 
 func (s *AuthService) SendOTP(ctx context.Context, userEmail EmailString) error {
+	// the code is for the normalized email, but it's sent to the address as typed
+	toEmail := strings.TrimSpace(userEmail)
+	userEmail = s.NormalizeEmail(userEmail)
+
+	if err := s.CheckEmail(userEmail); err != nil {
+		return err
+	}
+
 	// Generate an OTP
 	otp, err := s.generateOTP(userEmail)
 	if err != nil {
@@ -55,18 +212,22 @@ func (s *AuthService) SendOTP(ctx context.Context, userEmail EmailString) error
 	}
 
 	// Send the OTP to the user's email
-	return s.sendOTPEmail(ctx, userEmail, otp)
+	return s.sendOTPEmail(ctx, toEmail, otp)
 }
 
 func (s *AuthService) GenerateTokensPair(ctx context.Context, userEmail EmailString, otp OTPString) (string, string, error) {
+	userEmail = s.NormalizeEmail(userEmail)
+	if err := s.CheckEmail(userEmail); err != nil {
+		return "", "", fmt.Errorf("failed to generate token pair: %w", err)
+	}
+
 	// Verify the OTP
 	if err := s.verifyOTP(userEmail, otp); err != nil {
 		return "", "", fmt.Errorf("failed to generate token pair: %w", err)
 	}
 
 	// Generate tokens
-	// maybe persist the refresh token id in a db for revocation
-	accessToken, refreshToken, err := generateTokenPair(userEmail, s.config)
+	accessToken, refreshToken, err := generateTokenPair(userEmail, s.config.Load())
 	if err != nil {
 		return "", "", fmt.Errorf("failed to generate token pair: %w", err)
 	}
@@ -85,9 +246,13 @@ func (s *AuthService) RefreshToken(ctx context.Context, oldRefreshToken string)
 		return "", "", fmt.Errorf("failed to refresh token pair: %w", err)
 	}
 
+	// the rules may have changed since the token was issued
+	if err := s.CheckEmail(claims.Subject); err != nil {
+		return "", "", fmt.Errorf("failed to refresh token pair: %w", err)
+	}
+
 	// generate a new token pair
-	// maybe persist the refresh token id in a db for revocation?
-	accessToken, refreshToken, err := generateTokenPair(claims.Subject, s.config)
+	accessToken, refreshToken, err := generateTokenPair(claims.Subject, s.config.Load())
 	if err != nil {
 		return "", "", fmt.Errorf("failed to refresh token pair: %w", err)
 	}
@@ -101,7 +266,7 @@ func (s *AuthService) ValidateAccessToken(ctx context.Context, accessToken strin
 	}
 
 	// parse the claims
-	claims, err := ParseClaims(accessToken, s.config.AccessTokenSecret)
+	claims, err := ParseClaims(accessToken, s.config.Load().AccessTokenSecret)
 	if err != nil {
 		return nil, fmt.Errorf("invalid access token: %w", err)
 	}
@@ -111,7 +276,12 @@ func (s *AuthService) ValidateAccessToken(ctx context.Context, accessToken strin
 		return nil, fmt.Errorf("invalid access token: wrong token type got %q", claims.Type)
 	}
 
-	// maybe add more checks here if needed (e.g., check against a denylist)
+	// tokens issued before the normalization was configured carry the email as it was typed
+	claims.Subject = s.NormalizeEmail(claims.Subject)
+
+	if s.isRevoked(claims) {
+		return nil, fmt.Errorf("invalid access token: %w", ErrTokenRevoked)
+	}
 
 	return claims, nil
 }
@@ -121,7 +291,7 @@ func (s *AuthService) ValidateRefreshToken(ctx context.Context, refreshToken str
 		return nil, fmt.Errorf("invalid refresh token")
 	}
 
-	claims, err := ParseClaims(refreshToken, s.config.RefreshTokenSecret)
+	claims, err := ParseClaims(refreshToken, s.config.Load().RefreshTokenSecret)
 	if err != nil {
 		return nil, fmt.Errorf("invalid refresh token: %w", err)
 	}
@@ -130,20 +300,65 @@ func (s *AuthService) ValidateRefreshToken(ctx context.Context, refreshToken str
 		return nil, fmt.Errorf("invalid refresh token: wrong token type got %q", claims.Type)
 	}
 
+	claims.Subject = s.NormalizeEmail(claims.Subject)
+
+	if s.isRevoked(claims) {
+		return nil, fmt.Errorf("invalid refresh token: %w", ErrTokenRevoked)
+	}
+
 	return claims, nil
 }
 
+// RevokeToken revokes a refresh or an access token of the user, e.g. on logout or of a lost device.
+// A token of another user is not revoked
+func (s *AuthService) RevokeToken(ctx context.Context, userEmail EmailString, token string) error {
+	if s.revocations == nil {
+		return ErrRevocationUnavailable
+	}
+	if token == "" {
+		return ErrInvalidToken
+	}
+
+	config := s.config.Load()
+	claims, err := ParseClaims(token, config.RefreshTokenSecret)
+	if err != nil || claims.Type != RefreshToken {
+		claims, err = ParseClaims(token, config.AccessTokenSecret)
+		if err != nil || claims.Type != AccessToken {
+			return ErrInvalidToken
+		}
+	}
+
+	claims.Subject = s.NormalizeEmail(claims.Subject)
+	if claims.Subject != s.NormalizeEmail(userEmail) {
+		return ErrTokenNotOwned
+	}
+
+	return s.revocations.RevokeToken(claims)
+}
+
+// LogoutAll revokes every token issued to the user until now, on all their devices
+func (s *AuthService) LogoutAll(ctx context.Context, userEmail EmailString) error {
+	if s.revocations == nil {
+		return ErrRevocationUnavailable
+	}
+	return s.revocations.RevokeAll(s.NormalizeEmail(userEmail))
+}
+
+func (s *AuthService) isRevoked(claims *Claims) bool {
+	return s.revocations != nil && s.revocations.IsRevoked(claims)
+}
+
 func (s *AuthService) generateOTP(userEmail EmailString) (OTPString, error) {
 	if !utils.IsValidEmail(userEmail) {
 		return "", ErrInvalidEmail
 	}
 
-	otp, err := randOTP(s.config.EmailOTPLength)
+	otp, err := randOTP(s.config.Load().EmailOTPLength)
 	if err != nil {
 		return "", fmt.Errorf("failed to generate OTP: %w", err)
 	}
 
-	s.codes.Add(userEmail, otp)
+	s.codes.Load().Add(userEmail, otp)
 
 	return otp, nil
 }
@@ -153,16 +368,17 @@ func (s *AuthService) verifyOTP(userEmail EmailString, otp OTPString) error {
 		return err
 	}
 
-	if len(otp) != s.config.EmailOTPLength {
+	if len(otp) != s.config.Load().EmailOTPLength {
 		return ErrInvalidOTP
 	}
 
-	storedOTP, ok := s.codes.Get(userEmail)
+	codes := s.codes.Load()
+	storedOTP, ok := codes.Get(userEmail)
 	if !ok || storedOTP != otp {
 		return ErrInvalidOTP
 	}
 
-	s.codes.Remove(userEmail)
+	codes.Remove(userEmail)
 	return nil
 }
 
@@ -174,7 +390,7 @@ func (s *AuthService) sendOTPEmail(ctx context.Context, to EmailString, code OTP
 
 	return s.emailSvc.Send(ctx, &email.EmailInfo{
 		FromName:  "SyftBox",
-		FromEmail: s.config.EmailAddr,
+		FromEmail: s.config.Load().EmailAddr,
 		Subject:   "SyftBox Verification Code",
 		ToEmail:   to,
 		HTMLBody:  htmlBody,
@@ -188,7 +404,7 @@ func (s *AuthService) generateOTPEmail(to EmailString, code OTPString) (string,
 		"Email":        to,
 		"Code":         code,
 		"Year":         time.Now().Year(),
-		"ValidityMins": s.config.EmailOTPExpiry.Minutes(),
+		"ValidityMins": s.config.Load().EmailOTPExpiry.Minutes(),
 	}); err != nil {
 		return "", err
 	}
@@ -198,7 +414,6 @@ func (s *AuthService) generateOTPEmail(to EmailString, code OTPString) (string,
 
 func generateTokenPair(subject EmailString, config *Config) (accessToken string, refreshToken string, err error) {
 	// generate access token
-	// maybe save the access token ID for revocation?
 	accessToken, err = newAccessToken(subject, config.TokenIssuer, config.AccessTokenSecret, config.AccessTokenExpiry)
 	if err != nil {
 		return "", "", fmt.Errorf("failed to generate access token: %w", err)
@@ -3,21 +3,40 @@ package auth
 import (
 	"fmt"
 	"log/slog"
+	"strings"
 	"time"
 
 	"github.com/openmined/syftbox/internal/utils"
 )
 
 type Config struct {
-	Enabled            bool          `mapstructure:"enabled"`
-	TokenIssuer        string        `mapstructure:"token_issuer"`
-	RefreshTokenSecret string        `mapstructure:"refresh_token_secret"`
-	RefreshTokenExpiry time.Duration `mapstructure:"refresh_token_expiry"`
-	AccessTokenSecret  string        `mapstructure:"access_token_secret"`
-	AccessTokenExpiry  time.Duration `mapstructure:"access_token_expiry"`
-	EmailAddr          string        `mapstructure:"email_addr"`
-	EmailOTPLength     int           `mapstructure:"email_otp_length"`
-	EmailOTPExpiry     time.Duration `mapstructure:"email_otp_expiry"`
+	Enabled            bool                `mapstructure:"enabled"`
+	TokenIssuer        string              `mapstructure:"token_issuer"`
+	RefreshTokenSecret string              `mapstructure:"refresh_token_secret"`
+	RefreshTokenExpiry time.Duration       `mapstructure:"refresh_token_expiry"`
+	AccessTokenSecret  string              `mapstructure:"access_token_secret"`
+	AccessTokenExpiry  time.Duration       `mapstructure:"access_token_expiry"`
+	EmailAddr          string              `mapstructure:"email_addr"`
+	EmailOTPLength     int                 `mapstructure:"email_otp_length"`
+	EmailOTPExpiry     time.Duration       `mapstructure:"email_otp_expiry"`
+	AllowedEmails      []string            `mapstructure:"allowed_emails"` // Emails or patterns (e.g. *@lab.org) allowed to use the server. Empty allows all.
+	DeniedEmails       []string            `mapstructure:"denied_emails"`  // Emails or patterns denied from using the server. Takes precedence over AllowedEmails.
+	AdminEmails        []string            `mapstructure:"admin_emails"`   // Emails of the operators allowed to use the admin api.
+	Groups             map[string][]string `mapstructure:"groups"`         // Emails or patterns of the members of each group, for the group conditions of the ACLs.
+
+	// Claims of the users matching each email or pattern, for the claims conditions of the ACLs.
+	// Later entries take precedence. Claim names are case-insensitive.
+	UserClaims []UserClaims `mapstructure:"user_claims"`
+
+	// Domains whose addresses ignore the dots and the +tag of the local part, like gmail.com.
+	// Emails are always trimmed and lowercased, so that one person maps to one datasite.
+	EmailDotPlusDomains []string `mapstructure:"email_dot_plus_domains"`
+}
+
+// UserClaims are the claims of the users matching Email
+type UserClaims struct {
+	Email  string            `mapstructure:"email"` // email or pattern, e.g. *@lab.org
+	Claims map[string]string `mapstructure:"claims"`
 }
 
 func (c *Config) Validate() error {
@@ -39,6 +58,36 @@ func (c *Config) Validate() error {
 			return fmt.Errorf("invalid sender email %q", c.EmailAddr)
 		}
 	}
+
+	// email rules apply even when auth is disabled
+	if _, err := NewEmailFilter(c.AllowedEmails, c.DeniedEmails); err != nil {
+		return err
+	}
+
+	for name, members := range c.Groups {
+		if _, err := normalizeEmailRules(members); err != nil {
+			return fmt.Errorf("group %q: %w", name, err)
+		}
+	}
+
+	for i, user := range c.UserClaims {
+		if _, err := normalizeUserClaims(user); err != nil {
+			return fmt.Errorf("user_claims[%d]: %w", i, err)
+		}
+	}
+
+	for _, email := range c.AdminEmails {
+		if !utils.IsValidEmail(email) {
+			return fmt.Errorf("invalid admin email %q", email)
+		}
+	}
+
+	for _, domain := range c.EmailDotPlusDomains {
+		if !utils.IsValidEmail("user@" + strings.TrimSpace(domain)) {
+			return fmt.Errorf("invalid email_dot_plus_domains domain %q", domain)
+		}
+	}
+
 	return nil
 }
 
@@ -53,5 +102,11 @@ func (c Config) LogValue() slog.Value {
 		slog.String("email_addr", c.EmailAddr),
 		slog.Int("email_otp_length", c.EmailOTPLength),
 		slog.Duration("email_otp_expiry", c.EmailOTPExpiry),
+		slog.Any("allowed_emails", c.AllowedEmails),
+		slog.Any("denied_emails", c.DeniedEmails),
+		slog.Any("admin_emails", c.AdminEmails),
+		slog.Any("groups", c.Groups),
+		slog.Int("user_claims", len(c.UserClaims)),
+		slog.Any("email_dot_plus_domains", c.EmailDotPlusDomains),
 	)
 }
@@ -88,3 +88,45 @@ func TestConfigValidate_Disabled(t *testing.T) {
 	err := cfg.Validate()
 	require.NoError(t, err)
 }
+
+func TestConfigValidate_InvalidAdminEmail(t *testing.T) {
+	cfg := &Config{
+		AdminEmails: []string{"ops@example.com", "not-an-email"},
+	}
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid admin email")
+}
+
+func TestConfigValidate_InvalidGroup(t *testing.T) {
+	cfg := &Config{
+		Groups: map[string][]string{"researchers": {"*@lab.org", "not-an-email"}},
+	}
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `group "researchers"`)
+}
+
+func TestConfigValidate_InvalidUserClaims(t *testing.T) {
+	cfg := &Config{
+		UserClaims: []UserClaims{{Email: "not-an-email", Claims: map[string]string{"org_verified": "true"}}},
+	}
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "user_claims[0]")
+
+	cfg.UserClaims = []UserClaims{{Claims: map[string]string{"org_verified": "true"}}}
+	assert.Error(t, cfg.Validate(), "the email is required")
+
+	cfg.UserClaims = []UserClaims{{Email: "alice@lab.org", Claims: map[string]string{" ": "true"}}}
+	assert.Error(t, cfg.Validate())
+}
+
+func TestConfigValidate_InvalidDotPlusDomain(t *testing.T) {
+	cfg := &Config{
+		EmailDotPlusDomains: []string{"gmail.com", "@gmail.com"},
+	}
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "email_dot_plus_domains")
+}
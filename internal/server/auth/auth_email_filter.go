@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// EmailFilter gates which identities may authenticate with the server.
+// Rules are either exact emails (alice@lab.org) or glob patterns (*@lab.org, *@*.lab.org).
+// A denied match always wins. If the allow list is empty, every email that is not denied is allowed.
+type EmailFilter struct {
+	allow []string
+	deny  []string
+}
+
+// NewEmailFilter creates a filter from allow and deny rules. Rules are case-insensitive.
+func NewEmailFilter(allow []string, deny []string) (*EmailFilter, error) {
+	allowRules, err := normalizeEmailRules(allow)
+	if err != nil {
+		return nil, fmt.Errorf("allowed_emails: %w", err)
+	}
+
+	denyRules, err := normalizeEmailRules(deny)
+	if err != nil {
+		return nil, fmt.Errorf("denied_emails: %w", err)
+	}
+
+	return &EmailFilter{
+		allow: allowRules,
+		deny:  denyRules,
+	}, nil
+}
+
+// Enabled returns true if the filter has any rules
+func (f *EmailFilter) Enabled() bool {
+	return len(f.allow) > 0 || len(f.deny) > 0
+}
+
+// Check returns ErrEmailNotAllowed if the email is not permitted by the filter
+func (f *EmailFilter) Check(email string) error {
+	email = strings.ToLower(strings.TrimSpace(email))
+
+	if matchEmailRules(f.deny, email) {
+		return fmt.Errorf("%w: %q", ErrEmailNotAllowed, email)
+	}
+
+	if len(f.allow) > 0 && !matchEmailRules(f.allow, email) {
+		return fmt.Errorf("%w: %q", ErrEmailNotAllowed, email)
+	}
+
+	return nil
+}
+
+func matchEmailRules(rules []string, email string) bool {
+	for _, rule := range rules {
+		// patterns are validated in NewEmailFilter, so the error can be ignored
+		if ok, _ := path.Match(rule, email); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeEmailRules(rules []string) ([]string, error) {
+	normalized := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		rule = strings.ToLower(strings.TrimSpace(rule))
+		if rule == "" {
+			continue
+		}
+
+		if !strings.Contains(rule, "@") {
+			return nil, fmt.Errorf("invalid rule %q: must be an email or pattern like *@example.com", rule)
+		}
+
+		if _, err := path.Match(rule, ""); err != nil {
+			return nil, fmt.Errorf("invalid rule %q: %w", rule, err)
+		}
+
+		normalized = append(normalized, rule)
+	}
+	return normalized, nil
+}
@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmailFilter_NoRules(t *testing.T) {
+	f, err := NewEmailFilter(nil, nil)
+	require.NoError(t, err)
+	assert.False(t, f.Enabled())
+	assert.NoError(t, f.Check("anyone@example.com"))
+}
+
+func TestEmailFilter_Allow(t *testing.T) {
+	f, err := NewEmailFilter([]string{"alice@example.com", "*@lab.org", "*@*.uni.edu"}, nil)
+	require.NoError(t, err)
+	assert.True(t, f.Enabled())
+
+	tests := []struct {
+		email   string
+		allowed bool
+	}{
+		{"alice@example.com", true},
+		{"ALICE@Example.com", true},
+		{"bob@example.com", false},
+		{"bob@lab.org", true},
+		{"bob@sub.lab.org", false},
+		{"bob@lab.org.evil.com", false},
+		{"carol@cs.uni.edu", true},
+		{"carol@uni.edu", false},
+	}
+	for _, tt := range tests {
+		err := f.Check(tt.email)
+		if tt.allowed {
+			assert.NoError(t, err, tt.email)
+		} else {
+			assert.ErrorIs(t, err, ErrEmailNotAllowed, tt.email)
+		}
+	}
+}
+
+func TestEmailFilter_Deny(t *testing.T) {
+	f, err := NewEmailFilter(nil, []string{"spam@example.com", "*@evil.com"})
+	require.NoError(t, err)
+
+	assert.NoError(t, f.Check("alice@example.com"))
+	assert.ErrorIs(t, f.Check("spam@example.com"), ErrEmailNotAllowed)
+	assert.ErrorIs(t, f.Check("anyone@evil.com"), ErrEmailNotAllowed)
+	assert.ErrorIs(t, f.Check("Anyone@EVIL.com"), ErrEmailNotAllowed)
+}
+
+func TestEmailFilter_DenyTakesPrecedence(t *testing.T) {
+	f, err := NewEmailFilter([]string{"*@lab.org"}, []string{"intern@lab.org"})
+	require.NoError(t, err)
+
+	assert.NoError(t, f.Check("researcher@lab.org"))
+	assert.ErrorIs(t, f.Check("intern@lab.org"), ErrEmailNotAllowed)
+}
+
+func TestEmailFilter_InvalidRules(t *testing.T) {
+	_, err := NewEmailFilter([]string{"lab.org"}, nil)
+	assert.Error(t, err)
+
+	_, err = NewEmailFilter(nil, []string{"[@lab.org"})
+	assert.Error(t, err)
+
+	// blank rules are ignored
+	f, err := NewEmailFilter([]string{"", "  "}, nil)
+	require.NoError(t, err)
+	assert.False(t, f.Enabled())
+}
+
+func TestAuthService_EmailGating(t *testing.T) {
+	cfg := getTestAuthConfig()
+	cfg.AllowedEmails = []string{"*@lab.org"}
+	svc := newTestAuthService(t, cfg, NewMockEmailService())
+
+	assert.ErrorIs(t, svc.SendOTP(t.Context(), "user@other.org"), ErrEmailNotAllowed)
+	assert.NoError(t, svc.SendOTP(t.Context(), "user@lab.org"))
+
+	// a valid otp does not bypass the rules
+	otp, err := svc.generateOTP("user@other.org")
+	require.NoError(t, err)
+	_, _, err = svc.GenerateTokensPair(t.Context(), "user@other.org", otp)
+	assert.ErrorIs(t, err, ErrEmailNotAllowed)
+
+	// tokens issued before the rules changed cannot be refreshed
+	_, refreshToken, err := generateTokenPair("user@other.org", cfg)
+	require.NoError(t, err)
+	_, _, err = svc.RefreshToken(t.Context(), refreshToken)
+	assert.ErrorIs(t, err, ErrEmailNotAllowed)
+}
+
+func TestConfigValidate_InvalidEmailRules(t *testing.T) {
+	cfg := &Config{AllowedEmails: []string{"not-an-email-rule"}}
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "allowed_emails")
+}
@@ -0,0 +1,174 @@
+package auth
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const revocationsSchemaSQL = `
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+	jti TEXT PRIMARY KEY,
+	email TEXT NOT NULL,
+	expires_at INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS revoked_sessions (
+	email TEXT PRIMARY KEY,
+	revoked_at INTEGER NOT NULL
+);
+`
+
+// prune the revoked tokens that expired at most this often
+const revocationsPruneInterval = time.Hour
+
+// Revocations is the list of the revoked tokens, by token id (jti), and of the emails logged out everywhere,
+// for which every token issued until then is revoked. It is stored in the database and cached in memory,
+// as it's checked on every request. Revoked tokens are dropped once they expire, they are rejected anyway.
+type Revocations struct {
+	db       *sqlx.DB
+	tokens   map[string]time.Time // expiry of each revoked token, zero if it never expires
+	sessions map[string]time.Time // when each email was logged out everywhere
+	prunedAt time.Time
+	now      func() time.Time
+	mu       sync.RWMutex
+}
+
+func NewRevocations(db *sqlx.DB) (*Revocations, error) {
+	if _, err := db.Exec(revocationsSchemaSQL); err != nil {
+		return nil, fmt.Errorf("failed to initialize token revocations: %w", err)
+	}
+
+	r := &Revocations{
+		db:       db,
+		tokens:   make(map[string]time.Time),
+		sessions: make(map[string]time.Time),
+		now:      time.Now,
+	}
+
+	if err := r.load(); err != nil {
+		return nil, fmt.Errorf("failed to load token revocations: %w", err)
+	}
+
+	return r, nil
+}
+
+// IsRevoked checks if the token of the claims was revoked, on its own or by logging out its email everywhere
+func (r *Revocations) IsRevoked(claims *Claims) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if _, ok := r.tokens[claims.ID]; ok {
+		return true
+	}
+	// tokens without an issue time predate the revocations
+	revokedAt, ok := r.sessions[claims.Subject]
+	return ok && (claims.IssuedAt == nil || !claims.IssuedAt.After(revokedAt))
+}
+
+// RevokeToken revokes the token of the claims
+func (r *Revocations) RevokeToken(claims *Claims) error {
+	var expiresAt time.Time
+	if claims.ExpiresAt != nil {
+		expiresAt = claims.ExpiresAt.Time
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, err := r.db.Exec(`
+		INSERT INTO revoked_tokens (jti, email, expires_at) VALUES (?, ?, ?)
+		ON CONFLICT(jti) DO NOTHING
+	`, claims.ID, claims.Subject, unixOrZero(expiresAt)); err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+	r.tokens[claims.ID] = expiresAt
+
+	r.prune()
+	return nil
+}
+
+// RevokeAll revokes every token issued to the email until now
+func (r *Revocations) RevokeAll(email EmailString) error {
+	// issue times are in seconds, a token issued in this second is revoked too
+	revokedAt := r.now().Truncate(time.Second)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, err := r.db.Exec(`
+		INSERT INTO revoked_sessions (email, revoked_at) VALUES (?, ?)
+		ON CONFLICT(email) DO UPDATE SET revoked_at = excluded.revoked_at
+	`, email, revokedAt.Unix()); err != nil {
+		return fmt.Errorf("failed to revoke tokens: %w", err)
+	}
+	r.sessions[email] = revokedAt
+
+	r.prune()
+	return nil
+}
+
+// prune drops the revoked tokens that expired. Must hold the lock
+func (r *Revocations) prune() {
+	now := r.now()
+	if now.Sub(r.prunedAt) < revocationsPruneInterval {
+		return
+	}
+	r.prunedAt = now
+
+	// best effort, they are pruned on the next load otherwise
+	if _, err := r.db.Exec("DELETE FROM revoked_tokens WHERE expires_at > 0 AND expires_at < ?", now.Unix()); err != nil {
+		return
+	}
+	for id, expiresAt := range r.tokens {
+		if !expiresAt.IsZero() && expiresAt.Before(now) {
+			delete(r.tokens, id)
+		}
+	}
+}
+
+func (r *Revocations) load() error {
+	if _, err := r.db.Exec("DELETE FROM revoked_tokens WHERE expires_at > 0 AND expires_at < ?", r.now().Unix()); err != nil {
+		return err
+	}
+	r.prunedAt = r.now()
+
+	var tokens []struct {
+		ID        string `db:"jti"`
+		ExpiresAt int64  `db:"expires_at"`
+	}
+	if err := r.db.Select(&tokens, "SELECT jti, expires_at FROM revoked_tokens"); err != nil {
+		return err
+	}
+	for _, token := range tokens {
+		r.tokens[token.ID] = timeOrZero(token.ExpiresAt)
+	}
+
+	var sessions []struct {
+		Email     string `db:"email"`
+		RevokedAt int64  `db:"revoked_at"`
+	}
+	if err := r.db.Select(&sessions, "SELECT email, revoked_at FROM revoked_sessions"); err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		r.sessions[session.Email] = time.Unix(session.RevokedAt, 0)
+	}
+
+	return nil
+}
+
+func unixOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+func timeOrZero(unix int64) time.Time {
+	if unix == 0 {
+		return time.Time{}
+	}
+	return time.Unix(unix, 0)
+}
@@ -0,0 +1,147 @@
+package auth
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jmoiron/sqlx"
+	"github.com/openmined/syftbox/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRevocations(t *testing.T) (*Revocations, *sqlx.DB) {
+	t.Helper()
+	sqliteDb, err := db.NewSqliteDB(db.WithPath(filepath.Join(t.TempDir(), "test.db")), db.WithMaxOpenConns(1))
+	require.NoError(t, err)
+	t.Cleanup(func() { sqliteDb.Close() })
+
+	revocations, err := NewRevocations(sqliteDb)
+	require.NoError(t, err)
+	return revocations, sqliteDb
+}
+
+func loginTestUser(t *testing.T, svc *AuthService, user string) (string, string) {
+	t.Helper()
+	otp, err := svc.generateOTP(user)
+	require.NoError(t, err)
+	access, refresh, err := svc.GenerateTokensPair(context.Background(), user, otp)
+	require.NoError(t, err)
+	return access, refresh
+}
+
+func TestAuthService_RevokeToken(t *testing.T) {
+	ctx := context.Background()
+	cfg := getTestAuthConfig()
+	revocations, sqliteDb := newTestRevocations(t)
+	svc, err := NewAuthService(cfg, NewMockEmailService(), revocations)
+	require.NoError(t, err)
+
+	user := "user@email.com"
+	access, refresh := loginTestUser(t, svc, user)
+	_, otherRefresh := loginTestUser(t, svc, user)
+
+	assert.ErrorIs(t, svc.RevokeToken(ctx, "other@email.com", refresh), ErrTokenNotOwned)
+	assert.ErrorIs(t, svc.RevokeToken(ctx, user, "invalid.token"), ErrInvalidToken)
+	require.NoError(t, svc.RevokeToken(ctx, user, refresh))
+
+	// a revoked refresh token can't mint access tokens anymore
+	_, _, err = svc.RefreshToken(ctx, refresh)
+	assert.ErrorIs(t, err, ErrTokenRevoked)
+
+	// the other tokens are still valid
+	_, err = svc.ValidateAccessToken(ctx, access)
+	assert.NoError(t, err)
+	_, _, err = svc.RefreshToken(ctx, otherRefresh)
+	assert.NoError(t, err)
+
+	require.NoError(t, svc.RevokeToken(ctx, user, access))
+	_, err = svc.ValidateAccessToken(ctx, access)
+	assert.ErrorIs(t, err, ErrTokenRevoked)
+
+	// the revocations survive a restart
+	revocations, err = NewRevocations(sqliteDb)
+	require.NoError(t, err)
+	svc, err = NewAuthService(cfg, NewMockEmailService(), revocations)
+	require.NoError(t, err)
+	_, _, err = svc.RefreshToken(ctx, refresh)
+	assert.ErrorIs(t, err, ErrTokenRevoked)
+	_, err = svc.ValidateAccessToken(ctx, access)
+	assert.ErrorIs(t, err, ErrTokenRevoked)
+}
+
+func TestAuthService_LogoutAll(t *testing.T) {
+	ctx := context.Background()
+	cfg := getTestAuthConfig()
+	revocations, sqliteDb := newTestRevocations(t)
+	svc, err := NewAuthService(cfg, NewMockEmailService(), revocations)
+	require.NoError(t, err)
+
+	user := "user@email.com"
+	access, refresh := loginTestUser(t, svc, user)
+	_, laptopRefresh := loginTestUser(t, svc, user)
+	otherAccess, otherRefresh := loginTestUser(t, svc, "other@email.com")
+
+	require.NoError(t, svc.LogoutAll(ctx, "User@Email.com"))
+
+	for _, token := range []string{refresh, laptopRefresh} {
+		_, _, err = svc.RefreshToken(ctx, token)
+		assert.ErrorIs(t, err, ErrTokenRevoked)
+	}
+	_, err = svc.ValidateAccessToken(ctx, access)
+	assert.ErrorIs(t, err, ErrTokenRevoked)
+
+	// the other users stay logged in
+	_, err = svc.ValidateAccessToken(ctx, otherAccess)
+	assert.NoError(t, err)
+	_, _, err = svc.RefreshToken(ctx, otherRefresh)
+	assert.NoError(t, err)
+
+	// tokens issued afterwards are valid, also after a restart
+	revocations, err = NewRevocations(sqliteDb)
+	require.NoError(t, err)
+	svc, err = NewAuthService(cfg, NewMockEmailService(), revocations)
+	require.NoError(t, err)
+
+	later := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        "later",
+			Subject:   user,
+			Issuer:    cfg.TokenIssuer,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			IssuedAt:  jwt.NewNumericDate(time.Now().Add(2 * time.Second)),
+		},
+		Type: RefreshToken,
+	})
+	laterRefresh, err := later.SignedString([]byte(cfg.RefreshTokenSecret))
+	require.NoError(t, err)
+	_, _, err = svc.RefreshToken(ctx, laterRefresh)
+	assert.NoError(t, err)
+	_, _, err = svc.RefreshToken(ctx, refresh)
+	assert.ErrorIs(t, err, ErrTokenRevoked)
+}
+
+func TestRevocationsPrune(t *testing.T) {
+	now := time.Now()
+	revocations, sqliteDb := newTestRevocations(t)
+	revocations.now = func() time.Time { return now }
+
+	expiring := &Claims{RegisteredClaims: jwt.RegisteredClaims{ID: "expiring", Subject: "user@email.com", ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute))}}
+	forever := &Claims{RegisteredClaims: jwt.RegisteredClaims{ID: "forever", Subject: "user@email.com"}}
+	require.NoError(t, revocations.RevokeToken(expiring))
+	require.NoError(t, revocations.RevokeToken(forever))
+
+	// expired tokens are rejected anyway, they are dropped from the list
+	now = now.Add(revocationsPruneInterval + time.Minute)
+	require.NoError(t, revocations.RevokeToken(&Claims{RegisteredClaims: jwt.RegisteredClaims{ID: "another", Subject: "user@email.com"}}))
+	assert.NotContains(t, revocations.tokens, "expiring")
+	assert.True(t, revocations.IsRevoked(forever))
+
+	reloaded, err := NewRevocations(sqliteDb)
+	require.NoError(t, err)
+	assert.Len(t, reloaded.tokens, 2)
+	assert.True(t, reloaded.IsRevoked(forever))
+}
@@ -54,19 +54,26 @@ func NewMockEmailServiceDisabled() *MockEmailService {
 
 var _ email.Service = (*MockEmailService)(nil)
 
+func newTestAuthService(t *testing.T, cfg *Config, emailSvc email.Service) *AuthService {
+	t.Helper()
+	svc, err := NewAuthService(cfg, emailSvc, nil)
+	require.NoError(t, err)
+	return svc
+}
+
 func TestAuthService_IsEnabled(t *testing.T) {
 	cfg := getTestAuthConfig()
-	svc := NewAuthService(cfg, NewMockEmailService())
+	svc := newTestAuthService(t, cfg, NewMockEmailService())
 	assert.True(t, svc.IsEnabled())
 
 	cfg.Enabled = false
-	svc = NewAuthService(cfg, NewMockEmailServiceDisabled())
+	svc = newTestAuthService(t, cfg, NewMockEmailServiceDisabled())
 	assert.False(t, svc.IsEnabled())
 }
 
 func TestAuthService_OTP(t *testing.T) {
 	cfg := getTestAuthConfig()
-	svc := NewAuthService(cfg, NewMockEmailService())
+	svc := newTestAuthService(t, cfg, NewMockEmailService())
 
 	otp, err := svc.generateOTP("user@email.com")
 	assert.NoError(t, err)
@@ -92,7 +99,7 @@ func TestAuthService_OTP(t *testing.T) {
 
 func TestAuthService_GenerateTokensPair(t *testing.T) {
 	cfg := getTestAuthConfig()
-	svc := NewAuthService(cfg, NewMockEmailService())
+	svc := newTestAuthService(t, cfg, NewMockEmailService())
 
 	user := "user@email.com"
 	otp, err := svc.generateOTP(user)
@@ -116,7 +123,7 @@ func TestAuthService_GenerateTokensPair(t *testing.T) {
 
 func TestAuthService_RefreshToken(t *testing.T) {
 	cfg := getTestAuthConfig()
-	svc := NewAuthService(cfg, NewMockEmailService())
+	svc := newTestAuthService(t, cfg, NewMockEmailService())
 
 	user := "user@email.com"
 	otp, err := svc.generateOTP(user)
@@ -134,9 +141,111 @@ func TestAuthService_RefreshToken(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestAuthService_SetConfigTokenExpiry(t *testing.T) {
+	cfg := getTestAuthConfig()
+	svc := newTestAuthService(t, cfg, NewMockEmailService())
+
+	user := "user@email.com"
+	otp, err := svc.generateOTP(user)
+	require.NoError(t, err)
+	access, refresh, err := svc.GenerateTokensPair(context.Background(), user, otp)
+	require.NoError(t, err)
+
+	reloaded := getTestAuthConfig()
+	reloaded.AccessTokenExpiry = time.Hour
+	reloaded.RefreshTokenExpiry = 24 * time.Hour
+	require.NoError(t, svc.SetConfig(reloaded))
+	assert.Equal(t, time.Second*10, cfg.AccessTokenExpiry, "the config it was created with is unchanged")
+
+	// the tokens issued before keep their expiry
+	claims, err := svc.ValidateAccessToken(context.Background(), access)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(10*time.Second), claims.ExpiresAt.Time, 2*time.Second)
+
+	access2, refresh2, err := svc.RefreshToken(context.Background(), refresh)
+	require.NoError(t, err)
+
+	claims, err = svc.ValidateAccessToken(context.Background(), access2)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), claims.ExpiresAt.Time, 2*time.Second)
+
+	rclaims, err := svc.ValidateRefreshToken(context.Background(), refresh2)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(24*time.Hour), rclaims.ExpiresAt.Time, 2*time.Second)
+}
+
+func TestAuthService_SetConfigEmails(t *testing.T) {
+	cfg := getTestAuthConfig()
+	svc := newTestAuthService(t, cfg, NewMockEmailService())
+
+	user := "user@email.com"
+	otp, err := svc.generateOTP(user)
+	require.NoError(t, err)
+
+	reloaded := getTestAuthConfig()
+	reloaded.EmailOTPLength = 8
+	reloaded.DeniedEmails = []string{"*@email.com"}
+	reloaded.AdminEmails = []string{"ops@lab.org"}
+	reloaded.Groups = map[string][]string{"lab": {"*@lab.org"}}
+	reloaded.UserClaims = []UserClaims{{Email: "ops@lab.org", Claims: map[string]string{"org_verified": "true"}}}
+	require.NoError(t, svc.SetConfig(reloaded))
+
+	assert.ErrorIs(t, svc.CheckEmail(user), ErrEmailNotAllowed)
+	assert.True(t, svc.IsAdmin("ops@lab.org"))
+	assert.Equal(t, []string{"lab"}, svc.Groups("ops@lab.org"))
+	assert.Equal(t, map[string]string{"org_verified": "true"}, svc.Claims("ops@lab.org"))
+
+	// the codes sent before don't match the new length
+	assert.ErrorIs(t, svc.verifyOTP(user, otp), ErrInvalidOTP)
+	otp, err = svc.generateOTP(user)
+	require.NoError(t, err)
+	assert.Len(t, otp, 8)
+
+	// invalid rules aren't applied
+	reloaded = getTestAuthConfig()
+	reloaded.AllowedEmails = []string{"not an email"}
+	assert.Error(t, svc.SetConfig(reloaded))
+	assert.ErrorIs(t, svc.CheckEmail(user), ErrEmailNotAllowed)
+}
+
+func TestAuthService_Groups(t *testing.T) {
+	cfg := getTestAuthConfig()
+	cfg.Groups = map[string][]string{
+		"researchers": {"*@lab.org", "alice@example.com"},
+		"Interns":     {"intern@lab.org"},
+	}
+	svc := newTestAuthService(t, cfg, NewMockEmailService())
+
+	assert.Equal(t, []string{"interns", "researchers"}, svc.Groups("Intern@lab.org"))
+	assert.Equal(t, []string{"researchers"}, svc.Groups("alice@example.com"))
+	assert.Empty(t, svc.Groups("bob@example.com"))
+
+	cfg.Groups = map[string][]string{"invalid": {"not-an-email"}}
+	_, err := NewAuthService(cfg, NewMockEmailService(), nil)
+	assert.Error(t, err)
+}
+
+func TestAuthService_Claims(t *testing.T) {
+	cfg := getTestAuthConfig()
+	cfg.UserClaims = []UserClaims{
+		{Email: "*@lab.org", Claims: map[string]string{"Org_Verified": "true", "tier": "silver"}},
+		{Email: "Alice@Lab.org", Claims: map[string]string{"tier": "gold"}},
+	}
+	svc := newTestAuthService(t, cfg, NewMockEmailService())
+
+	// the emails and the claim names are matched without the case, the later entries take precedence
+	assert.Equal(t, map[string]string{"org_verified": "true", "tier": "gold"}, svc.Claims("alice@lab.org"))
+	assert.Equal(t, map[string]string{"org_verified": "true", "tier": "silver"}, svc.Claims("bob@lab.org"))
+	assert.Nil(t, svc.Claims("carol@example.com"))
+
+	// callers get their own copy
+	svc.Claims("alice@lab.org")["tier"] = "platinum"
+	assert.Equal(t, "gold", svc.Claims("alice@lab.org")["tier"])
+}
+
 func TestAuthService_ValidateAccessToken_Errors(t *testing.T) {
 	cfg := getTestAuthConfig()
-	svc := NewAuthService(cfg, NewMockEmailService())
+	svc := newTestAuthService(t, cfg, NewMockEmailService())
 
 	_, err := svc.ValidateAccessToken(context.Background(), "")
 	assert.Error(t, err)
@@ -149,7 +258,7 @@ func TestAuthService_ValidateAccessToken_Errors(t *testing.T) {
 
 func TestAuthService_ValidateRefreshToken_Errors(t *testing.T) {
 	cfg := getTestAuthConfig()
-	svc := NewAuthService(cfg, NewMockEmailService())
+	svc := newTestAuthService(t, cfg, NewMockEmailService())
 
 	_, err := svc.ValidateRefreshToken(context.Background(), "")
 	assert.Error(t, err)
@@ -162,7 +271,7 @@ func TestAuthService_ValidateRefreshToken_Errors(t *testing.T) {
 
 func TestAuthService_generateOTPEmail(t *testing.T) {
 	cfg := getTestAuthConfig()
-	svc := NewAuthService(cfg, NewMockEmailService())
+	svc := newTestAuthService(t, cfg, NewMockEmailService())
 
 	email := "user@email.com"
 	code := "ABC123"
@@ -178,7 +287,7 @@ func TestAuthService_generateOTPEmail(t *testing.T) {
 func TestAuthService_SendOTP(t *testing.T) {
 	cfg := getTestAuthConfig()
 	emailSvc := NewMockEmailService()
-	svc := NewAuthService(cfg, emailSvc)
+	svc := newTestAuthService(t, cfg, emailSvc)
 
 	email := "user@email.com"
 
@@ -190,7 +299,7 @@ func TestAuthService_SendOTP(t *testing.T) {
 func TestAuthService_SendOTP_EmailDisabled(t *testing.T) {
 	cfg := getTestAuthConfig()
 	emailSvc := NewMockEmailServiceDisabled()
-	svc := NewAuthService(cfg, emailSvc)
+	svc := newTestAuthService(t, cfg, emailSvc)
 
 	email := "user@email.com"
 
@@ -198,3 +307,39 @@ func TestAuthService_SendOTP_EmailDisabled(t *testing.T) {
 	assert.NoError(t, err)
 	emailSvc.AssertExpectations(t)
 }
+
+func TestAuthService_NormalizedEmails(t *testing.T) {
+	cfg := getTestAuthConfig()
+	cfg.EmailDotPlusDomains = []string{"gmail.com"}
+	emailSvc := NewMockEmailService()
+	svc := newTestAuthService(t, cfg, emailSvc)
+	ctx := context.Background()
+
+	// the code is sent to the address as typed, for the normalized email
+	require.NoError(t, svc.SendOTP(ctx, " A.Lice+work@Gmail.com "))
+	emailSvc.AssertCalled(t, "Send", mock.Anything, mock.MatchedBy(func(info *email.EmailInfo) bool {
+		return info.ToEmail == "A.Lice+work@Gmail.com"
+	}))
+	otp, ok := svc.codes.Load().Get("alice@gmail.com")
+	require.True(t, ok)
+
+	// any spelling of the email verifies it, and gets tokens for the same datasite
+	access, refresh, err := svc.GenerateTokensPair(ctx, "ALICE@gmail.com", otp)
+	require.NoError(t, err)
+	claims, err := svc.ValidateAccessToken(ctx, access)
+	require.NoError(t, err)
+	assert.Equal(t, "alice@gmail.com", claims.Subject)
+	rclaims, err := svc.ValidateRefreshToken(ctx, refresh)
+	require.NoError(t, err)
+	assert.Equal(t, "alice@gmail.com", rclaims.Subject)
+
+	// the other domains are only lowercased
+	assert.Equal(t, "a.lice+work@example.com", svc.NormalizeEmail("A.Lice+work@Example.com"))
+
+	// tokens issued before the normalization carry the email as it was typed
+	legacyAccess, _, err := generateTokenPair("Alice@Gmail.com", cfg)
+	require.NoError(t, err)
+	claims, err = svc.ValidateAccessToken(ctx, legacyAccess)
+	require.NoError(t, err)
+	assert.Equal(t, "alice@gmail.com", claims.Subject)
+}
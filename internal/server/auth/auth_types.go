@@ -18,4 +18,9 @@ var (
 	ErrInvalidRequestToken = errors.New("invalid request token")
 	ErrInvalidAccessToken  = errors.New("invalid access token")
 	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrEmailNotAllowed     = errors.New("email not allowed on this server")
+	ErrTokenRevoked        = errors.New("token revoked")
+	ErrTokenNotOwned       = errors.New("token issued to another user")
+
+	ErrRevocationUnavailable = errors.New("token revocation is not available")
 )
@@ -2,31 +2,62 @@ package blob
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
+	"net/http"
 	"sync"
 	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/openmined/syftbox/internal/db"
 )
 
 type BlobService struct {
-	backend     *S3Backend
-	index       *BlobIndex
-	indexer     *blobIndexer
-	callbacks   []BlobChangeCallback
-	callbacksMu sync.RWMutex
+	backend       IBlobBackend
+	limited       *limitedBackend
+	index         *BlobIndex
+	indexer       *blobIndexer
+	callbacks     []BlobChangeCallback
+	callbacksMu   sync.RWMutex
+	keyRules      KeyRules
+	uploadHeaders map[string]string
+	presigns      *Presigns
+
+	// serves the reads of the index when a replica is configured, nil otherwise
+	replica *ReplicatedIndex
 }
 
-func NewBlobService(cfg *S3Config, db *sqlx.DB) (*BlobService, error) {
-	index, err := newBlobIndex(db)
+func NewBlobService(cfg *S3Config, sqlDB *sqlx.DB) (*BlobService, error) {
+	index, err := newBlobIndex(sqlDB)
 	if err != nil {
 		return nil, err
 	}
 
-	svc := &BlobService{}
+	svc := &BlobService{keyRules: KeyRules{MaxLength: cfg.MaxKeyLength}, uploadHeaders: cfg.UploadHeaders(), presigns: NewPresigns()}
 	svc.index = index
-	svc.backend = NewS3BackendWithConfig(cfg)
-	svc.indexer = newBlobIndexer(svc.backend, svc.index)
+	index.revisionsKept = cfg.RevisionsKept
+	index.revisionMaxAge = cfg.RevisionMaxAge
+	switch cfg.Backend {
+	case BackendFilesystem:
+		fs, err := NewFSBackend(cfg)
+		if err != nil {
+			return nil, err
+		}
+		fs.presigns = svc.presigns
+		svc.backend = fs
+	default:
+		svc.backend = NewS3BackendWithConfig(cfg)
+	}
+	svc.limited = newLimitedBackend(svc.backend, cfg)
+	svc.indexer = newBlobIndexer(svc.backend, svc.index, svc.indexWritten)
+
+	if cfg.IndexReplicaPath != "" {
+		replicaDB, err := db.NewSqliteDB(db.WithPath(cfg.IndexReplicaPath), db.WithReadOnly())
+		if err != nil {
+			return nil, fmt.Errorf("open index replica: %w", err)
+		}
+		svc.replica = newReplicatedIndex(index, &BlobIndex{db: replicaDB}, cfg.IndexReplicaMaxLag, cfg.IndexReplicaConsistency)
+	}
 
 	return svc, nil
 }
@@ -61,25 +92,90 @@ func (b *BlobService) Start(ctx context.Context) error {
 		AfterDeleteObject: b.afterDeleteObjects,
 		AfterCopyObject:   b.afterCopyObject,
 	})
+	if b.replica != nil {
+		go b.replica.run(ctx)
+	}
 	return b.indexer.Start(ctx)
 }
 
 // Shutdown releases any resources used by the service
 func (b *BlobService) Shutdown(ctx context.Context) error {
 	slog.Debug("blob service shutdown")
+	if b.replica != nil {
+		b.replica.Close()
+	}
 	return b.index.Close()
 }
 
-// Backend returns the underlying blob backend instance
+// Backend returns the blob backend, with the configured concurrency limits applied.
+// The indexer uses the backend directly, its periodic listing isn't limited.
 func (b *BlobService) Backend() IBlobBackend {
-	return b.backend
+	return b.limited
+}
+
+// LimitStats returns the state of the concurrency limits of the backend
+func (b *BlobService) LimitStats() *BlobLimitStats {
+	return b.limited.Stats()
+}
+
+// PresignHandler returns the handler of the presigned urls, when the server serves them itself.
+// Returns nil for backends whose presigned urls point elsewhere, like S3.
+func (b *BlobService) PresignHandler() http.Handler {
+	if fs, ok := b.backend.(*FSBackend); ok {
+		return fs
+	}
+	return nil
 }
 
-// Index returns the blob index
+// Presigns returns the presigned urls issued by the server
+func (b *BlobService) Presigns() *Presigns {
+	return b.presigns
+}
+
+// RevokesPresigns checks if revoked presigned urls are rejected, which only the urls the server serves itself are
+func (b *BlobService) RevokesPresigns() bool {
+	return b.PresignHandler() != nil
+}
+
+// Index returns the primary blob index, for the reads that decide on a write
 func (b *BlobService) Index() IBlobIndex {
 	return b.index
 }
 
+// ReadIndex returns the index for the views, listings and presign lookups.
+// With a replica, the reads are served from it while it's fresh enough, so they may be stale by the max lag
+func (b *BlobService) ReadIndex() IBlobIndex {
+	if b.replica != nil {
+		return b.replica
+	}
+	return b.index
+}
+
+// IndexReplicaStats returns the state of the replica of the index, nil without one
+func (b *BlobService) IndexReplicaStats() *IndexReplicaStats {
+	if b.replica == nil {
+		return nil
+	}
+	return b.replica.Stats()
+}
+
+// indexWritten records a write to the primary index, for the read_your_writes reads to wait for the replica to have it
+func (b *BlobService) indexWritten() {
+	if b.replica != nil {
+		b.replica.written()
+	}
+}
+
+// KeyRules returns the rules applied to the keys of uploaded objects
+func (b *BlobService) KeyRules() KeyRules {
+	return b.keyRules
+}
+
+// UploadHeaders returns the headers the uploads to presigned urls must send
+func (b *BlobService) UploadHeaders() map[string]string {
+	return b.uploadHeaders
+}
+
 // SetOnBlobChangeCallback sets the callback function for blob changes
 func (b *BlobService) OnBlobChange(callback BlobChangeCallback) {
 	b.callbacksMu.Lock()
@@ -99,25 +195,54 @@ func (b *BlobService) invokeBlobChangeCallbacks(key string, eventType BlobEventT
 
 // implements the AfterPutObjectHook
 func (b *BlobService) afterPutObject(_ *PutObjectParams, resp *PutObjectResponse) {
-	if err := b.index.Set(&BlobInfo{
+	stored := &objectVersion{ETag: resp.ETag, Version: resp.Version}
+	if resp.StorageETag != "" {
+		stored.ETag = resp.StorageETag
+	}
+	if b.superseded(resp.Key, stored) {
+		slog.Info("update index", "hook", "PutObject", "key", resp.Key, "superseded", true)
+		return
+	}
+
+	info := &BlobInfo{
 		Key:          resp.Key,
 		ETag:         resp.ETag,
 		Size:         resp.Size,
 		LastModified: resp.LastModified.Format(time.RFC3339),
-	}); err != nil {
+		Codec:        resp.Codec,
+		StorageETag:  resp.StorageETag,
+	}
+	if err := b.index.Set(info); err != nil {
 		slog.Error("update index", "hook", "PutObject", "key", resp.Key, "error", err)
 	} else {
-		slog.Info("update index", "hook", "PutObject", "key", resp.Key)
+		b.indexWritten()
+		resp.Revision = info.Revision
+		slog.Info("update index", "hook", "PutObject", "key", resp.Key, "revision", info.Revision)
 		// Call all blob change callbacks
 		b.invokeBlobChangeCallbacks(resp.Key, BlobEventPut)
 	}
 }
 
+// superseded checks if the backend already replaced the object a write stored, by a write that got here first
+// or that is still on its way. The revisions follow the order the backend stored the writes in, rather than the
+// order they got here, and the write the backend replaced is left out of the history: the later one is indexed.
+// When the backend can't tell, the write is indexed.
+func (b *BlobService) superseded(key string, stored *objectVersion) bool {
+	current, err := b.backend.storedVersion(context.Background(), key)
+	if err != nil {
+		slog.Warn("update index", "key", key, "error", fmt.Errorf("stored version: %w", err))
+		return false
+	}
+	// deleted since, the delete is indexed on its own
+	return current == nil || !current.matches(stored)
+}
+
 // implements the AfterDeleteObjectHook
 func (b *BlobService) afterDeleteObjects(req string, _ bool) {
 	if err := b.index.Remove(req); err != nil {
 		slog.Error("update index", "hook", "DeleteObject", "key", req, "error", err)
 	} else {
+		b.indexWritten()
 		slog.Info("update index", "hook", "DeleteObject", "key", req)
 		// Call all blob change callbacks
 		b.invokeBlobChangeCallbacks(req, BlobEventDelete)
@@ -126,14 +251,32 @@ func (b *BlobService) afterDeleteObjects(req string, _ bool) {
 
 // implements the AfterCopyObjectHook
 func (b *BlobService) afterCopyObject(req *CopyObjectParams, resp *CopyObjectResponse) {
-	if err := b.index.Set(&BlobInfo{
+	if b.superseded(req.DestinationKey, &objectVersion{ETag: resp.ETag, Version: resp.Version}) {
+		slog.Info("update index", "hook", "CopyObject", "dest", req.DestinationKey, "superseded", true)
+		return
+	}
+
+	info := &BlobInfo{
 		Key:          req.DestinationKey,
 		ETag:         resp.ETag,
 		Size:         0,
 		LastModified: resp.LastModified.Format(time.RFC3339),
-	}); err != nil {
+	}
+
+	// the copy keeps the encoding & metadata of the source, so it's compressed the same way
+	if src, ok := b.index.Get(req.SourceKey); ok {
+		info.Size = src.Size
+		if src.Codec != "" {
+			info.ETag = src.ETag
+			info.Codec = src.Codec
+			info.StorageETag = resp.ETag
+		}
+	}
+
+	if err := b.index.Set(info); err != nil {
 		slog.Error("update index", "hook", "CopyObject", "src", req.SourceKey, "dest", req.DestinationKey, "error", err)
 	} else {
+		b.indexWritten()
 		slog.Info("update index", "hook", "CopyObject", "src", req.SourceKey, "dest", req.DestinationKey)
 		// Call all blob change callbacks
 		b.invokeBlobChangeCallbacks(req.DestinationKey, BlobEventCopy)
@@ -3,20 +3,118 @@ package blob
 import (
 	"fmt"
 	"log/slog"
+	"time"
 
 	"github.com/openmined/syftbox/internal/utils"
 )
 
+// Storage backends
+const (
+	BackendS3         = "s3"
+	BackendFilesystem = "filesystem"
+)
+
+// Server-side encryption of the s3 backend
+const (
+	SSENone   = "none"
+	SSEAES256 = "AES256"
+	SSEKMS    = "aws:kms"
+)
+
 type S3Config struct {
+	// where blobs are stored, s3 (default) or filesystem
+	Backend string `mapstructure:"backend"`
+
+	// local directory of the filesystem backend
+	Dir string `mapstructure:"dir"`
+	// base url of the presigned urls of the filesystem backend, which this server serves
+	PublicURL string `mapstructure:"public_url"`
+
 	BucketName    string `mapstructure:"bucket_name"`
 	Region        string `mapstructure:"region"`
 	AccessKey     string `mapstructure:"access_key"`
 	SecretKey     string `mapstructure:"secret_key"`
 	Endpoint      string `mapstructure:"endpoint"`
 	UseAccelerate bool   `mapstructure:"use_accelerate"`
+
+	// server-side encryption of the uploaded objects, none (default), AES256 or aws:kms.
+	// The KMS key defaults to the aws managed key of the bucket.
+	SSE         string `mapstructure:"sse"`
+	SSEKMSKeyID string `mapstructure:"sse_kms_key_id"`
+
+	// how long presigned download urls are reused for the same unchanged blob. 0 disables caching.
+	PresignCacheTTL time.Duration `mapstructure:"presign_cache_ttl"`
+
+	// longest object key accepted on upload, in bytes. 0 is the S3 limit of 1024.
+	MaxKeyLength int `mapstructure:"max_key_length"`
+
+	// concurrent reads and writes sent to the storage, presigning included. 0 is unlimited.
+	MaxConcurrentReads  int `mapstructure:"max_concurrent_reads"`
+	MaxConcurrentWrites int `mapstructure:"max_concurrent_writes"`
+	// how long operations over the limits wait before failing with 503. 0 waits as long as the request.
+	QueueTimeout time.Duration `mapstructure:"queue_timeout"`
+
+	// most keys presigned in one request, larger requests are rejected. 0 is unlimited.
+	MaxPresignKeys int `mapstructure:"max_presign_keys"`
+
+	// read-only replica of the index, e.g. restored by litestream, serving the views, listings and presign lookups.
+	// The writes go to the primary. Empty disables it.
+	IndexReplicaPath string `mapstructure:"index_replica_path"`
+	// most the replica may lag behind the primary to serve the reads, they fall back to the primary past it
+	IndexReplicaMaxLag time.Duration `mapstructure:"index_replica_max_lag"`
+	// bounded (default), or read_your_writes to also read from the primary until the replica has the last write
+	IndexReplicaConsistency string `mapstructure:"index_replica_consistency"`
+
+	// revisions kept per key in the version history, and for how long, pruned with each indexer run. 0 keeps them all
+	RevisionsKept  int           `mapstructure:"revisions_kept"`
+	RevisionMaxAge time.Duration `mapstructure:"revision_max_age"`
 }
 
 func (c *S3Config) Validate() error {
+	switch c.Backend {
+	case "", BackendS3:
+		if err := c.validateS3(); err != nil {
+			return err
+		}
+	case BackendFilesystem:
+		if c.Dir == "" {
+			return fmt.Errorf("dir required")
+		}
+		if !utils.IsValidURL(c.PublicURL) {
+			return fmt.Errorf("invalid public_url %q", c.PublicURL)
+		}
+	default:
+		return fmt.Errorf("unknown backend %q, must be %s or %s", c.Backend, BackendS3, BackendFilesystem)
+	}
+
+	if err := c.validateSSE(); err != nil {
+		return err
+	}
+	if c.PresignCacheTTL < 0 || c.PresignCacheTTL >= DownloadURLExpiry {
+		return fmt.Errorf("presign_cache_ttl must be >= 0 and < %s", DownloadURLExpiry)
+	}
+	if c.MaxKeyLength < 0 || c.MaxKeyLength > MaxKeyLength {
+		return fmt.Errorf("max_key_length must be >= 0 and <= %d", MaxKeyLength)
+	}
+	if c.MaxConcurrentReads < 0 || c.MaxConcurrentWrites < 0 {
+		return fmt.Errorf("max_concurrent_reads and max_concurrent_writes must be >= 0")
+	}
+	if c.QueueTimeout < 0 {
+		return fmt.Errorf("queue_timeout must be >= 0")
+	}
+	if c.MaxPresignKeys < 0 {
+		return fmt.Errorf("max_presign_keys must be >= 0")
+	}
+	if c.RevisionsKept < 0 {
+		return fmt.Errorf("revisions_kept must be >= 0")
+	}
+	if c.RevisionMaxAge < 0 {
+		return fmt.Errorf("revision_max_age must be >= 0")
+	}
+	return c.validateIndexReplica()
+}
+
+func (c *S3Config) validateS3() error {
 	if c.BucketName == "" {
 		return fmt.Errorf("bucket_name required")
 	}
@@ -35,13 +133,78 @@ func (c *S3Config) Validate() error {
 	return nil
 }
 
+func (c *S3Config) validateIndexReplica() error {
+	if c.IndexReplicaPath == "" {
+		return nil
+	}
+	if c.IndexReplicaMaxLag <= 0 {
+		return fmt.Errorf("index_replica_max_lag must be > 0")
+	}
+	switch c.IndexReplicaConsistency {
+	case "", ReplicaConsistencyBounded, ReplicaConsistencyReadYourWrites:
+	default:
+		return fmt.Errorf("unknown index_replica_consistency %q, must be %s or %s", c.IndexReplicaConsistency, ReplicaConsistencyBounded, ReplicaConsistencyReadYourWrites)
+	}
+	return nil
+}
+
+func (c *S3Config) validateSSE() error {
+	switch c.SSE {
+	case "", SSENone:
+	case SSEAES256, SSEKMS:
+		if c.Backend == BackendFilesystem {
+			return fmt.Errorf("sse is not supported by the %s backend", BackendFilesystem)
+		}
+	default:
+		return fmt.Errorf("unknown sse %q, must be %s, %s or %s", c.SSE, SSENone, SSEAES256, SSEKMS)
+	}
+	if c.SSEKMSKeyID != "" && c.SSE != SSEKMS {
+		return fmt.Errorf("sse_kms_key_id requires sse %s", SSEKMS)
+	}
+	return nil
+}
+
+// encrypted checks if the objects are encrypted by s3
+func (c *S3Config) encrypted() bool {
+	return c.SSE == SSEAES256 || c.SSE == SSEKMS
+}
+
+// UploadHeaders are the headers the uploads to presigned urls must send, as they are signed with the url.
+// Returns nil when there are none.
+func (c *S3Config) UploadHeaders() map[string]string {
+	if c.Backend == BackendFilesystem || !c.encrypted() {
+		return nil
+	}
+	headers := map[string]string{"x-amz-server-side-encryption": c.SSE}
+	if c.SSEKMSKeyID != "" {
+		headers["x-amz-server-side-encryption-aws-kms-key-id"] = c.SSEKMSKeyID
+	}
+	return headers
+}
+
 func (s3c S3Config) LogValue() slog.Value {
 	return slog.GroupValue(
+		slog.String("backend", s3c.Backend),
+		slog.String("dir", s3c.Dir),
+		slog.String("public_url", s3c.PublicURL),
 		slog.String("bucket_name", s3c.BucketName),
 		slog.String("region", s3c.Region),
 		slog.String("endpoint", s3c.Endpoint),
 		slog.String("access_key", utils.MaskSecret(s3c.AccessKey)),
 		slog.String("secret_key", utils.MaskSecret(s3c.SecretKey)),
 		slog.Bool("use_accelerate", s3c.UseAccelerate),
+		slog.String("sse", s3c.SSE),
+		slog.String("sse_kms_key_id", s3c.SSEKMSKeyID),
+		slog.Duration("presign_cache_ttl", s3c.PresignCacheTTL),
+		slog.Int("max_key_length", s3c.MaxKeyLength),
+		slog.Int("max_concurrent_reads", s3c.MaxConcurrentReads),
+		slog.Int("max_concurrent_writes", s3c.MaxConcurrentWrites),
+		slog.Duration("queue_timeout", s3c.QueueTimeout),
+		slog.Int("max_presign_keys", s3c.MaxPresignKeys),
+		slog.String("index_replica_path", s3c.IndexReplicaPath),
+		slog.Duration("index_replica_max_lag", s3c.IndexReplicaMaxLag),
+		slog.String("index_replica_consistency", s3c.IndexReplicaConsistency),
+		slog.Int("revisions_kept", s3c.RevisionsKept),
+		slog.Duration("revision_max_age", s3c.RevisionMaxAge),
 	)
 }
@@ -0,0 +1,709 @@
+package blob
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openmined/syftbox/internal/blobcodec"
+)
+
+const (
+	// FSPresignPath is where the server serves the presigned urls of the filesystem backend
+	FSPresignPath = "/blob/"
+
+	// layout of the backend dir
+	fsObjectsDir = "objects"
+	fsMetaDir    = "meta"
+	fsTmpDir     = "tmp"
+	fsUploadsDir = "uploads"
+)
+
+var (
+	ErrNoSuchUpload = errors.New("no such upload")
+	ErrInvalidPart  = errors.New("invalid part")
+)
+
+// FSBackend stores blobs in a local directory, so that a server can run without S3.
+// It is meant for single node and dev deployments.
+// Presigned urls point to the server itself, which serves them with ServeHTTP.
+// Like with S3, objects written through presigned urls reach the index with the next indexer run.
+type FSBackend struct {
+	dir      string
+	baseURL  string
+	secret   []byte // signs the presigned urls, they don't outlive the process
+	hooks    *blobBackendHooks
+	presigns *Presigns // the revoked urls are rejected. Optional
+}
+
+// fsObjectMeta is stored next to each object, what S3 keeps with the object
+type fsObjectMeta struct {
+	ETag        string            `json:"etag"` // of the stored bytes
+	Codec       string            `json:"codec,omitempty"`
+	ContentETag string            `json:"contentEtag,omitempty"`
+	ContentSize int64             `json:"contentSize,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+}
+
+func NewFSBackend(cfg *S3Config) (*FSBackend, error) {
+	for _, sub := range []string{fsObjectsDir, fsMetaDir, fsTmpDir, fsUploadsDir} {
+		if err := os.MkdirAll(filepath.Join(cfg.Dir, sub), 0o755); err != nil {
+			return nil, fmt.Errorf("blob dir: %w", err)
+		}
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+
+	return &FSBackend{
+		dir:     cfg.Dir,
+		baseURL: strings.TrimSuffix(cfg.PublicURL, "/"),
+		secret:  secret,
+		hooks:   &blobBackendHooks{},
+	}, nil
+}
+
+func (f *FSBackend) setHooks(hooks *blobBackendHooks) {
+	if hooks != nil {
+		f.hooks.AfterPutObject = hooks.AfterPutObject
+		f.hooks.AfterDeleteObject = hooks.AfterDeleteObject
+		f.hooks.AfterCopyObject = hooks.AfterCopyObject
+	}
+}
+
+// ===================================================================================================
+
+func (f *FSBackend) GetObject(ctx context.Context, key string) (*GetObjectResponse, error) {
+	if !ValidateKey(key) {
+		return nil, ErrInvalidKey
+	}
+
+	file, info, meta, err := f.openObject(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if !blobcodec.IsCompressed(meta.Codec) {
+		return &GetObjectResponse{
+			Body:         file,
+			Size:         info.Size(),
+			ETag:         meta.ETag,
+			LastModified: info.ModTime().UTC(),
+			Metadata:     meta.Metadata,
+		}, nil
+	}
+
+	// compressed blob, read it back as the original content
+	body, err := blobcodec.NewReader(meta.Codec, file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("decompress %q: %w", key, err)
+	}
+
+	return &GetObjectResponse{
+		Body:         &decompressedBody{ReadCloser: body, raw: file},
+		Size:         meta.ContentSize,
+		ETag:         meta.ContentETag,
+		LastModified: info.ModTime().UTC(),
+		Metadata:     meta.Metadata,
+	}, nil
+}
+
+func (f *FSBackend) GetObjectPresigned(ctx context.Context, key string) (string, error) {
+	if !ValidateKey(key) {
+		return "", ErrInvalidKey
+	}
+	return f.presign(http.MethodGet, key, DownloadURLExpiry, nil), nil
+}
+
+// ===================================================================================================
+
+func (f *FSBackend) PutObject(ctx context.Context, params *PutObjectParams) (*PutObjectResponse, error) {
+	if !ValidateKey(params.Key) {
+		return nil, ErrInvalidKey
+	}
+
+	meta := &fsObjectMeta{}
+	if len(params.Metadata) > 0 {
+		meta.Metadata = maps.Clone(params.Metadata)
+	}
+
+	compressed := blobcodec.IsCompressed(params.Codec)
+	if compressed {
+		meta.Codec = params.Codec
+		meta.ContentETag = params.ContentETag
+		meta.ContentSize = params.ContentSize
+	}
+
+	info, err := f.writeObject(params.Key, params.Body, meta, params.IfNotExists)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &PutObjectResponse{
+		Key:          params.Key,
+		Size:         info.Size(),
+		ETag:         meta.ETag,
+		LastModified: info.ModTime().UTC(),
+	}
+
+	// clients only know about the uncompressed content
+	if compressed {
+		result.Codec = params.Codec
+		result.StorageETag = result.ETag
+		result.ETag = params.ContentETag
+		result.Size = params.ContentSize
+	}
+
+	if f.hooks.AfterPutObject != nil {
+		f.hooks.AfterPutObject(params, result)
+	}
+
+	return result, nil
+}
+
+func (f *FSBackend) PutObjectPresigned(ctx context.Context, key string, size int64) (string, error) {
+	if !ValidateKey(key) {
+		return "", ErrInvalidKey
+	}
+	var params url.Values
+	if size >= 0 {
+		params = url.Values{"size": {strconv.FormatInt(size, 10)}}
+	}
+	return f.presign(http.MethodPut, key, uploadExpiry, params), nil
+}
+
+func (f *FSBackend) PutObjectMultipart(ctx context.Context, params *PutObjectMultipartParams) (*PutObjectMultipartResponse, error) {
+	if !ValidateKey(params.Key) {
+		return nil, ErrInvalidKey
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	uploadID := hex.EncodeToString(id)
+
+	// the upload remembers its key, parts and completion are checked against it
+	uploadDir := filepath.Join(f.dir, fsUploadsDir, uploadID)
+	if err := os.Mkdir(uploadDir, 0o755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(uploadDir, "key"), []byte(params.Key), 0o644); err != nil {
+		return nil, err
+	}
+
+	urls := make([]string, 0, params.Parts)
+	for i := range params.Parts {
+		urls = append(urls, f.presign(http.MethodPut, params.Key, 2*uploadExpiry, url.Values{
+			"uploadId":   {uploadID},
+			"partNumber": {strconv.Itoa(int(i) + 1)},
+		}))
+	}
+
+	return &PutObjectMultipartResponse{
+		Key:      params.Key,
+		UploadID: uploadID,
+		URLs:     urls,
+	}, nil
+}
+
+// CompleteMultipartUpload joins the parts in order. Like S3, the etag is the md5 of the part md5s
+// followed by the number of parts.
+func (f *FSBackend) CompleteMultipartUpload(ctx context.Context, params *CompleteMultipartUploadParams) (*PutObjectResponse, error) {
+	if !ValidateKey(params.Key) {
+		return nil, ErrInvalidKey
+	}
+
+	uploadDir, err := f.uploadDir(params.UploadID, params.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := md5.New()
+	parts := make([]io.Reader, 0, len(params.Parts))
+	for i, part := range params.Parts {
+		if i > 0 && part.PartNumber <= params.Parts[i-1].PartNumber {
+			return nil, fmt.Errorf("%w: parts must be in ascending order", ErrInvalidPart)
+		}
+
+		path := filepath.Join(uploadDir, strconv.Itoa(part.PartNumber))
+		etag, err := fileMD5(path)
+		if err != nil {
+			return nil, fmt.Errorf("%w: part %d: %w", ErrInvalidPart, part.PartNumber, err)
+		}
+		if etag != strings.Trim(part.ETag, "\"") {
+			return nil, fmt.Errorf("%w: part %d: etag mismatch", ErrInvalidPart, part.PartNumber)
+		}
+		sum, _ := hex.DecodeString(etag)
+		hash.Write(sum)
+
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		parts = append(parts, file)
+	}
+
+	meta := &fsObjectMeta{ETag: fmt.Sprintf("%x-%d", hash.Sum(nil), len(params.Parts))}
+	info, err := f.writeObject(params.Key, io.MultiReader(parts...), meta, false)
+	if err != nil {
+		return nil, err
+	}
+	os.RemoveAll(uploadDir)
+
+	return &PutObjectResponse{
+		Key:          params.Key,
+		ETag:         meta.ETag,
+		Size:         info.Size(),
+		LastModified: info.ModTime().UTC(),
+	}, nil
+}
+
+// ===================================================================================================
+
+func (f *FSBackend) CopyObject(ctx context.Context, params *CopyObjectParams) (*CopyObjectResponse, error) {
+	if !ValidateKey(params.SourceKey) {
+		return nil, fmt.Errorf("invalid source key: %s", params.SourceKey)
+	}
+	if !ValidateKey(params.DestinationKey) {
+		return nil, fmt.Errorf("invalid destination key: %s", params.DestinationKey)
+	}
+
+	file, _, meta, err := f.openObject(params.SourceKey)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	// the copy keeps the encoding & metadata of the source
+	info, err := f.writeObject(params.DestinationKey, file, meta, false)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &CopyObjectResponse{
+		ETag:         meta.ETag,
+		LastModified: info.ModTime().UTC(),
+	}
+
+	if f.hooks.AfterCopyObject != nil {
+		f.hooks.AfterCopyObject(params, result)
+	}
+
+	return result, nil
+}
+
+// ===================================================================================================
+
+// DeleteObject removes an object. Like S3, deleting a missing object succeeds.
+func (f *FSBackend) DeleteObject(ctx context.Context, key string) (bool, error) {
+	if !ValidateKey(key) {
+		return false, ErrInvalidKey
+	}
+
+	if err := os.Remove(f.objectPath(key)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return false, err
+	}
+	os.Remove(f.metaPath(key))
+	f.removeEmptyParents(key)
+
+	if f.hooks.AfterDeleteObject != nil {
+		f.hooks.AfterDeleteObject(key, true)
+	}
+	return true, nil
+}
+
+// ===================================================================================================
+
+// ListObjects lists the stored objects, with the size and etag of the stored bytes like S3
+func (f *FSBackend) ListObjects(ctx context.Context) ([]*BlobInfo, error) {
+	var objects []*BlobInfo
+
+	root := filepath.Join(f.dir, fsObjectsDir)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		meta, err := f.readMeta(key)
+		if err != nil {
+			return err
+		}
+
+		objects = append(objects, &BlobInfo{
+			Key:          key,
+			ETag:         meta.ETag,
+			Size:         info.Size(),
+			LastModified: info.ModTime().UTC().Format(time.RFC3339),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return objects, nil
+}
+
+// Ping checks that the blob dir is still there
+func (f *FSBackend) Ping(ctx context.Context) error {
+	info, err := os.Stat(filepath.Join(f.dir, fsObjectsDir))
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", f.dir)
+	}
+	return nil
+}
+
+func (f *FSBackend) Delegate() any {
+	return f.dir
+}
+
+// storedVersion returns the etag of the object, the filesystem keeps no versions
+func (f *FSBackend) storedVersion(ctx context.Context, key string) (*objectVersion, error) {
+	meta, err := f.readMeta(key)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return &objectVersion{ETag: meta.ETag}, nil
+}
+
+// ===================================================================================================
+
+// ServeHTTP serves the presigned urls: downloads, uploads and multipart upload parts.
+// Errors are reported like S3 does, so that clients handle them the same way.
+func (f *FSBackend) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimPrefix(r.URL.Path, FSPresignPath)
+	if !ValidateKey(key) {
+		writeFSError(w, http.StatusBadRequest, "InvalidRequest", "invalid key")
+		return
+	}
+
+	query := r.URL.Query()
+	if !hmac.Equal([]byte(query.Get("signature")), []byte(f.sign(r.Method, key, query))) {
+		writeFSError(w, http.StatusForbidden, "SignatureDoesNotMatch", "The request signature does not match")
+		return
+	}
+	expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		writeFSError(w, http.StatusForbidden, "AccessDenied", "Request has expired")
+		return
+	}
+	if f.presigns != nil && f.presigns.isRevoked(query.Get("signature")) {
+		writeFSError(w, http.StatusForbidden, "AccessDenied", "Request has been revoked")
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet:
+		f.serveObject(w, r, key)
+	case r.Method == http.MethodPut && query.Has("uploadId"):
+		f.servePutPart(w, r, key, query.Get("uploadId"), query.Get("partNumber"))
+	case r.Method == http.MethodPut:
+		// like S3, an upload must have the content length it was signed for
+		if query.Has("size") && strconv.FormatInt(r.ContentLength, 10) != query.Get("size") {
+			writeFSError(w, http.StatusForbidden, "SignatureDoesNotMatch", "The request content length does not match the signed one")
+			return
+		}
+		meta := &fsObjectMeta{}
+		if _, err := f.writeObject(key, r.Body, meta, false); err != nil {
+			writeFSError(w, http.StatusInternalServerError, "InternalError", err.Error())
+			return
+		}
+		w.Header().Set("ETag", strconv.Quote(meta.ETag))
+		w.WriteHeader(http.StatusOK)
+	default:
+		writeFSError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "method not allowed")
+	}
+}
+
+func (f *FSBackend) serveObject(w http.ResponseWriter, r *http.Request, key string) {
+	file, info, meta, err := f.openObject(key)
+	if errors.Is(err, fs.ErrNotExist) {
+		writeFSError(w, http.StatusNotFound, "NoSuchKey", "The specified key does not exist")
+		return
+	} else if err != nil {
+		writeFSError(w, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+	defer file.Close()
+
+	// stored bytes are served as is, compressed blobs with their codec as the content encoding
+	header := w.Header()
+	header.Set("Content-Type", "application/octet-stream")
+	header.Set("ETag", strconv.Quote(meta.ETag))
+	if blobcodec.IsCompressed(meta.Codec) {
+		header.Set("Content-Encoding", meta.Codec)
+	}
+	for name, value := range meta.Metadata {
+		header.Set("X-Amz-Meta-"+name, value)
+	}
+
+	http.ServeContent(w, r, "", info.ModTime(), file)
+}
+
+func (f *FSBackend) servePutPart(w http.ResponseWriter, r *http.Request, key, uploadID, partNumber string) {
+	number, err := strconv.Atoi(partNumber)
+	if err != nil || number < 1 {
+		writeFSError(w, http.StatusBadRequest, "InvalidArgument", "invalid part number")
+		return
+	}
+
+	uploadDir, err := f.uploadDir(uploadID, key)
+	if err != nil {
+		writeFSError(w, http.StatusNotFound, "NoSuchUpload", err.Error())
+		return
+	}
+
+	etag, err := writeAtomic(filepath.Join(f.dir, fsTmpDir), filepath.Join(uploadDir, strconv.Itoa(number)), r.Body)
+	if err != nil {
+		writeFSError(w, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+	w.Header().Set("ETag", strconv.Quote(etag))
+	w.WriteHeader(http.StatusOK)
+}
+
+func writeFSError(w http.ResponseWriter, status int, code string, message string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "<Error><Code>%s</Code><Message>%s</Message></Error>", code, message)
+}
+
+// ===================================================================================================
+
+func (f *FSBackend) presign(method string, key string, expiry time.Duration, params url.Values) string {
+	query := url.Values{}
+	maps.Copy(query, params)
+	query.Set("expires", strconv.FormatInt(time.Now().Add(expiry).Unix(), 10))
+	query.Set("signature", f.sign(method, key, query))
+
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return f.baseURL + FSPresignPath + strings.Join(segments, "/") + "?" + query.Encode()
+}
+
+// sign signs everything a presigned url grants, except the signature itself
+func (f *FSBackend) sign(method string, key string, query url.Values) string {
+	mac := hmac.New(sha256.New, f.secret)
+	for _, value := range []string{method, key, query.Get("expires"), query.Get("uploadId"), query.Get("partNumber"), query.Get("size")} {
+		mac.Write([]byte(value))
+		mac.Write([]byte{0})
+	}
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (f *FSBackend) objectPath(key string) string {
+	return filepath.Join(f.dir, fsObjectsDir, filepath.FromSlash(key))
+}
+
+func (f *FSBackend) metaPath(key string) string {
+	return filepath.Join(f.dir, fsMetaDir, filepath.FromSlash(key)) + ".json"
+}
+
+// uploadDir returns the dir of a multipart upload of key
+func (f *FSBackend) uploadDir(uploadID string, key string) (string, error) {
+	if id, err := hex.DecodeString(uploadID); err != nil || len(id) != 16 {
+		return "", ErrNoSuchUpload
+	}
+
+	dir := filepath.Join(f.dir, fsUploadsDir, uploadID)
+	uploadKey, err := os.ReadFile(filepath.Join(dir, "key"))
+	if err != nil || string(uploadKey) != key {
+		return "", ErrNoSuchUpload
+	}
+	return dir, nil
+}
+
+func (f *FSBackend) openObject(key string) (*os.File, fs.FileInfo, *fsObjectMeta, error) {
+	file, err := os.Open(f.objectPath(key))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	info, err := file.Stat()
+	if err == nil && info.IsDir() {
+		err = fmt.Errorf("%s: %w", key, fs.ErrNotExist)
+	}
+	if err != nil {
+		file.Close()
+		return nil, nil, nil, err
+	}
+
+	meta, err := f.readMeta(key)
+	if err != nil {
+		file.Close()
+		return nil, nil, nil, err
+	}
+
+	return file, info, meta, nil
+}
+
+// readMeta reads the metadata of an object. Objects put in the dir by hand have none, their etag is computed.
+func (f *FSBackend) readMeta(key string) (*fsObjectMeta, error) {
+	data, err := os.ReadFile(f.metaPath(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		etag, err := fileMD5(f.objectPath(key))
+		if err != nil {
+			return nil, err
+		}
+		return &fsObjectMeta{ETag: etag}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var meta fsObjectMeta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("blob metadata %q: %w", key, err)
+	}
+	return &meta, nil
+}
+
+// writeObject replaces the object of the key with the body and its metadata.
+// The etag of the metadata is set to the md5 of the body, unless it's already set.
+// writeObject writes the object and its metadata. An exclusive write fails with ErrObjectExists
+// if the key already exists, leaving the existing object as it is.
+func (f *FSBackend) writeObject(key string, body io.Reader, meta *fsObjectMeta, exclusive bool) (fs.FileInfo, error) {
+	path := f.objectPath(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+
+	var etag string
+	var err error
+	if exclusive {
+		etag, err = writeExclusive(filepath.Join(f.dir, fsTmpDir), path, body)
+		if errors.Is(err, fs.ErrExist) {
+			return nil, ErrObjectExists
+		}
+	} else {
+		// stale metadata must not describe the new object
+		os.Remove(f.metaPath(key))
+		etag, err = writeAtomic(filepath.Join(f.dir, fsTmpDir), path, body)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if meta.ETag == "" {
+		meta.ETag = etag
+	}
+
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(f.metaPath(key)), 0o755); err != nil {
+		return nil, err
+	}
+	if _, err := writeAtomic(filepath.Join(f.dir, fsTmpDir), f.metaPath(key), strings.NewReader(string(data))); err != nil {
+		return nil, err
+	}
+
+	return os.Stat(path)
+}
+
+// removeEmptyParents removes the dirs left empty by a deleted object, S3 has no dirs
+func (f *FSBackend) removeEmptyParents(key string) {
+	for _, root := range []string{fsObjectsDir, fsMetaDir} {
+		dir := path.Dir(key)
+		for dir != "." && dir != "/" {
+			if os.Remove(filepath.Join(f.dir, root, filepath.FromSlash(dir))) != nil {
+				break
+			}
+			dir = path.Dir(dir)
+		}
+	}
+}
+
+// writeAtomic writes the body to a temp file in tmpDir, then moves it to path.
+// Returns the md5 of the body.
+func writeAtomic(tmpDir string, path string, body io.Reader) (string, error) {
+	return writeTemp(tmpDir, path, body, os.Rename)
+}
+
+// writeExclusive is writeAtomic failing with fs.ErrExist if path exists, the temp file is linked to path
+func writeExclusive(tmpDir string, path string, body io.Reader) (string, error) {
+	return writeTemp(tmpDir, path, body, os.Link)
+}
+
+func writeTemp(tmpDir string, path string, body io.Reader, place func(tmp string, path string) error) (string, error) {
+	tmp, err := os.CreateTemp(tmpDir, "blob-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := md5.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, hash), body); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+
+	if err := place(tmp.Name(), path); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func fileMD5(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := md5.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// check if FSBackend implements IBlobBackend interface
+var _ IBlobBackend = (*FSBackend)(nil)
@@ -0,0 +1,373 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openmined/syftbox/internal/blobcodec"
+	"github.com/openmined/syftbox/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newFSTestService returns a blob service on the filesystem backend, with its presigned urls served by srv
+func newFSTestService(t *testing.T) (*BlobService, *httptest.Server) {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	sqlite, err := db.NewSqliteDB(db.WithPath(filepath.Join(t.TempDir(), "test.db")), db.WithMaxOpenConns(1))
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlite.Close() })
+
+	cfg := &S3Config{Backend: BackendFilesystem, Dir: t.TempDir(), PublicURL: srv.URL}
+	require.NoError(t, cfg.Validate())
+
+	svc, err := NewBlobService(cfg, sqlite)
+	require.NoError(t, err)
+	require.NoError(t, svc.Start(t.Context()))
+	mux.Handle(FSPresignPath, svc.PresignHandler())
+
+	return svc, srv
+}
+
+func md5Hex(content []byte) string {
+	sum := md5.Sum(content)
+	return hex.EncodeToString(sum[:])
+}
+
+func httpDo(t *testing.T, method string, url string, body []byte) (*http.Response, []byte) {
+	t.Helper()
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	require.NoError(t, err)
+	// read the stored bytes, like the clients of compressed blobs
+	req.Header.Set("Accept-Encoding", "identity")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	content, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp, content
+}
+
+func TestFSBackendUploadDownloadList(t *testing.T) {
+	svc, _ := newFSTestService(t)
+	ctx := context.Background()
+	content := []byte("hello, world")
+
+	resp, err := svc.Backend().PutObject(ctx, &PutObjectParams{
+		Key:      "alice@example.com/public/hello.txt",
+		Body:     bytes.NewReader(content),
+		Size:     int64(len(content)),
+		Metadata: map[string]string{"syft-executable": "true"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, md5Hex(content), resp.ETag)
+	assert.Equal(t, int64(len(content)), resp.Size)
+	assert.Positive(t, resp.Revision, "indexed on put")
+
+	info, ok := svc.Index().Get("alice@example.com/public/hello.txt")
+	require.True(t, ok)
+	assert.Equal(t, md5Hex(content), info.ETag)
+
+	obj, err := svc.Backend().GetObject(ctx, "alice@example.com/public/hello.txt")
+	require.NoError(t, err)
+	got, err := io.ReadAll(obj.Body)
+	obj.Body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, content, got)
+	assert.Equal(t, md5Hex(content), obj.ETag)
+
+	// presigned downloads carry the metadata, like S3
+	presigned, err := svc.Backend().GetObjectPresigned(ctx, "alice@example.com/public/hello.txt")
+	require.NoError(t, err)
+	httpResp, got := httpDo(t, http.MethodGet, presigned, nil)
+	require.Equal(t, http.StatusOK, httpResp.StatusCode)
+	assert.Equal(t, content, got)
+	assert.Equal(t, "true", httpResp.Header.Get("X-Amz-Meta-syft-executable"))
+	assert.Equal(t, `"`+md5Hex(content)+`"`, httpResp.Header.Get("ETag"))
+
+	objects, err := svc.Backend().ListObjects(ctx)
+	require.NoError(t, err)
+	require.Len(t, objects, 1)
+	assert.Equal(t, "alice@example.com/public/hello.txt", objects[0].Key)
+	assert.Equal(t, md5Hex(content), objects[0].ETag)
+	assert.Equal(t, int64(len(content)), objects[0].Size)
+
+	// missing objects
+	_, err = svc.Backend().GetObject(ctx, "alice@example.com/missing.txt")
+	assert.Error(t, err)
+	presigned, err = svc.Backend().GetObjectPresigned(ctx, "alice@example.com/missing.txt")
+	require.NoError(t, err)
+	httpResp, _ = httpDo(t, http.MethodGet, presigned, nil)
+	assert.Equal(t, http.StatusNotFound, httpResp.StatusCode)
+}
+
+func TestFSBackendPutIfNotExists(t *testing.T) {
+	svc, _ := newFSTestService(t)
+	ctx := context.Background()
+	key := "alice@example.com/syft.pub.yaml"
+
+	_, err := svc.Backend().PutObject(ctx, &PutObjectParams{Key: key, Body: strings.NewReader("owner"), Size: 5, IfNotExists: true})
+	require.NoError(t, err)
+
+	// the existing object and its metadata are left as they are
+	_, err = svc.Backend().PutObject(ctx, &PutObjectParams{Key: key, Body: strings.NewReader("default"), Size: 7, IfNotExists: true})
+	assert.ErrorIs(t, err, ErrObjectExists)
+
+	obj, err := svc.Backend().GetObject(ctx, key)
+	require.NoError(t, err)
+	defer obj.Body.Close()
+	content, err := io.ReadAll(obj.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "owner", string(content))
+	assert.Equal(t, md5Hex([]byte("owner")), obj.ETag)
+}
+
+func TestFSBackendCompressed(t *testing.T) {
+	svc, _ := newFSTestService(t)
+	ctx := context.Background()
+	content := []byte(strings.Repeat("compress me ", 100))
+
+	var compressed bytes.Buffer
+	w, err := blobcodec.NewWriter(blobcodec.Gzip, &compressed)
+	require.NoError(t, err)
+	w.Write(content)
+	require.NoError(t, w.Close())
+
+	resp, err := svc.Backend().PutObject(ctx, &PutObjectParams{
+		Key:         "alice@example.com/data.txt",
+		Body:        bytes.NewReader(compressed.Bytes()),
+		Size:        int64(compressed.Len()),
+		Codec:       blobcodec.Gzip,
+		ContentETag: md5Hex(content),
+		ContentSize: int64(len(content)),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, md5Hex(content), resp.ETag)
+	assert.Equal(t, md5Hex(compressed.Bytes()), resp.StorageETag)
+
+	// read back as the original content
+	obj, err := svc.Backend().GetObject(ctx, "alice@example.com/data.txt")
+	require.NoError(t, err)
+	got, err := io.ReadAll(obj.Body)
+	obj.Body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, content, got)
+	assert.Equal(t, int64(len(content)), obj.Size)
+
+	// served as stored, with the codec as the content encoding
+	presigned, err := svc.Backend().GetObjectPresigned(ctx, "alice@example.com/data.txt")
+	require.NoError(t, err)
+	httpResp, got := httpDo(t, http.MethodGet, presigned, nil)
+	require.Equal(t, http.StatusOK, httpResp.StatusCode)
+	assert.Equal(t, blobcodec.Gzip, httpResp.Header.Get("Content-Encoding"))
+	assert.Equal(t, compressed.Bytes(), got)
+
+	// listed with the stored size and etag, like S3
+	objects, err := svc.Backend().ListObjects(ctx)
+	require.NoError(t, err)
+	require.Len(t, objects, 1)
+	assert.Equal(t, md5Hex(compressed.Bytes()), objects[0].ETag)
+	assert.Equal(t, int64(compressed.Len()), objects[0].Size)
+}
+
+func TestFSBackendPresignedUpload(t *testing.T) {
+	svc, _ := newFSTestService(t)
+	ctx := context.Background()
+	content := []byte("uploaded directly")
+
+	presigned, err := svc.Backend().PutObjectPresigned(ctx, "alice@example.com/direct/file name.txt", int64(len(content)))
+	require.NoError(t, err)
+
+	// the size is signed, uploads of another size are rejected
+	httpResp, _ := httpDo(t, http.MethodPut, presigned, append(content, "!"...))
+	assert.Equal(t, http.StatusForbidden, httpResp.StatusCode)
+	httpResp, _ = httpDo(t, http.MethodPut, strings.Replace(presigned, "size=17", "size=18", 1), append(content, "!"...))
+	assert.Equal(t, http.StatusForbidden, httpResp.StatusCode)
+
+	httpResp, _ = httpDo(t, http.MethodPut, presigned, content)
+	require.Equal(t, http.StatusOK, httpResp.StatusCode)
+	assert.Equal(t, `"`+md5Hex(content)+`"`, httpResp.Header.Get("ETag"))
+
+	// like S3, the indexer picks it up
+	_, ok := svc.Index().Get("alice@example.com/direct/file name.txt")
+	assert.False(t, ok)
+	require.NoError(t, svc.indexer.buildIndex(ctx))
+	info, ok := svc.Index().Get("alice@example.com/direct/file name.txt")
+	require.True(t, ok)
+	assert.Equal(t, md5Hex(content), info.ETag)
+
+	// a url presigned for uploads doesn't download
+	httpResp, _ = httpDo(t, http.MethodGet, presigned, nil)
+	assert.Equal(t, http.StatusForbidden, httpResp.StatusCode)
+}
+
+func TestFSBackendPresignedURLRejected(t *testing.T) {
+	svc, _ := newFSTestService(t)
+	ctx := context.Background()
+
+	_, err := svc.Backend().PutObject(ctx, &PutObjectParams{Key: "alice@example.com/secret.txt", Body: strings.NewReader("secret")})
+	require.NoError(t, err)
+
+	presigned, err := svc.Backend().GetObjectPresigned(ctx, "alice@example.com/secret.txt")
+	require.NoError(t, err)
+
+	// another key with the same signature
+	other := strings.Replace(presigned, "secret.txt", "other.txt", 1)
+	httpResp, body := httpDo(t, http.MethodGet, other, nil)
+	assert.Equal(t, http.StatusForbidden, httpResp.StatusCode)
+	assert.Contains(t, string(body), "SignatureDoesNotMatch")
+
+	// expired, signed as such
+	fs := svc.backend.(*FSBackend)
+	expired, err := url.Parse(fs.presign(http.MethodGet, "alice@example.com/secret.txt", -time.Minute, nil))
+	require.NoError(t, err)
+	httpResp, body = httpDo(t, http.MethodGet, expired.String(), nil)
+	assert.Equal(t, http.StatusForbidden, httpResp.StatusCode)
+	assert.Contains(t, string(body), "expired")
+
+	// expiry pushed back without signing it
+	u, err := url.Parse(presigned)
+	require.NoError(t, err)
+	q := u.Query()
+	q.Set("expires", fmt.Sprint(time.Now().Add(time.Hour).Unix()))
+	u.RawQuery = q.Encode()
+	httpResp, _ = httpDo(t, http.MethodGet, u.String(), nil)
+	assert.Equal(t, http.StatusForbidden, httpResp.StatusCode)
+}
+
+func TestFSBackendMultipart(t *testing.T) {
+	svc, _ := newFSTestService(t)
+	ctx := context.Background()
+	parts := [][]byte{bytes.Repeat([]byte("a"), 1000), bytes.Repeat([]byte("b"), 500)}
+
+	upload, err := svc.Backend().PutObjectMultipart(ctx, &PutObjectMultipartParams{Key: "alice@example.com/big.bin", Parts: 2})
+	require.NoError(t, err)
+	require.Len(t, upload.URLs, 2)
+
+	var completed []*CompletedPart
+	partHashes := md5.New()
+	for i, part := range parts {
+		httpResp, _ := httpDo(t, http.MethodPut, upload.URLs[i], part)
+		require.Equal(t, http.StatusOK, httpResp.StatusCode)
+		completed = append(completed, &CompletedPart{PartNumber: i + 1, ETag: httpResp.Header.Get("ETag")})
+		sum := md5.Sum(part)
+		partHashes.Write(sum[:])
+	}
+
+	// a part etag that doesn't match fails
+	_, err = svc.Backend().CompleteMultipartUpload(ctx, &CompleteMultipartUploadParams{
+		Key: "alice@example.com/big.bin", UploadID: upload.UploadID,
+		Parts: []*CompletedPart{{PartNumber: 1, ETag: md5Hex(parts[1])}, completed[1]},
+	})
+	assert.ErrorIs(t, err, ErrInvalidPart)
+
+	resp, err := svc.Backend().CompleteMultipartUpload(ctx, &CompleteMultipartUploadParams{
+		Key: "alice@example.com/big.bin", UploadID: upload.UploadID, Parts: completed,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("%x-2", partHashes.Sum(nil)), resp.ETag)
+	assert.Equal(t, int64(1500), resp.Size)
+
+	obj, err := svc.Backend().GetObject(ctx, "alice@example.com/big.bin")
+	require.NoError(t, err)
+	got, err := io.ReadAll(obj.Body)
+	obj.Body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, append(parts[0], parts[1]...), got)
+
+	// the upload is gone
+	_, err = svc.Backend().CompleteMultipartUpload(ctx, &CompleteMultipartUploadParams{
+		Key: "alice@example.com/big.bin", UploadID: upload.UploadID, Parts: completed,
+	})
+	assert.ErrorIs(t, err, ErrNoSuchUpload)
+}
+
+func TestFSBackendCopyDelete(t *testing.T) {
+	svc, _ := newFSTestService(t)
+	ctx := context.Background()
+
+	_, err := svc.Backend().PutObject(ctx, &PutObjectParams{Key: "alice@example.com/a/b/src.txt", Body: strings.NewReader("content")})
+	require.NoError(t, err)
+
+	copied, err := svc.Backend().CopyObject(ctx, &CopyObjectParams{SourceKey: "alice@example.com/a/b/src.txt", DestinationKey: "alice@example.com/dst.txt"})
+	require.NoError(t, err)
+	assert.Equal(t, md5Hex([]byte("content")), copied.ETag)
+	_, ok := svc.Index().Get("alice@example.com/dst.txt")
+	assert.True(t, ok)
+
+	ok, err = svc.Backend().DeleteObject(ctx, "alice@example.com/a/b/src.txt")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	_, ok = svc.Index().Get("alice@example.com/a/b/src.txt")
+	assert.False(t, ok)
+
+	// deleting again succeeds, like S3
+	_, err = svc.Backend().DeleteObject(ctx, "alice@example.com/a/b/src.txt")
+	assert.NoError(t, err)
+
+	objects, err := svc.Backend().ListObjects(ctx)
+	require.NoError(t, err)
+	require.Len(t, objects, 1)
+	assert.Equal(t, "alice@example.com/dst.txt", objects[0].Key)
+	assert.NoDirExists(t, filepath.Join(svc.backend.(*FSBackend).dir, fsObjectsDir, "alice@example.com", "a"), "empty dirs are removed")
+}
+
+func TestFSBackendConfig(t *testing.T) {
+	assert.NoError(t, (&S3Config{Backend: BackendFilesystem, Dir: "/tmp/blobs", PublicURL: "http://localhost:8080"}).Validate())
+	assert.Error(t, (&S3Config{Backend: BackendFilesystem, PublicURL: "http://localhost:8080"}).Validate())
+	assert.Error(t, (&S3Config{Backend: BackendFilesystem, Dir: "/tmp/blobs"}).Validate())
+	assert.Error(t, (&S3Config{Backend: "gcs"}).Validate())
+	assert.ErrorContains(t, (&S3Config{}).Validate(), "bucket_name required", "s3 is the default")
+}
+
+func TestBlobServiceOrdersWritesLikeTheBackend(t *testing.T) {
+	svc, _ := newFSTestService(t)
+	ctx := context.Background()
+	const key = "alice@example.com/public/shared.txt"
+
+	put := func(content string) *PutObjectResponse {
+		resp, err := svc.Backend().PutObject(ctx, &PutObjectParams{Key: key, Body: strings.NewReader(content), Size: int64(len(content))})
+		require.NoError(t, err)
+		return resp
+	}
+	first := put("first")
+	second := put("second")
+	assert.Equal(t, int64(2), second.Revision)
+
+	// the index hook of the first write comes after the second one landed, e.g. when both raced
+	late := *first
+	late.Revision = 0
+	svc.afterPutObject(nil, &late)
+	assert.Zero(t, late.Revision, "a write the backend replaced isn't indexed")
+
+	blob, ok := svc.Index().Get(key)
+	require.True(t, ok)
+	assert.Equal(t, second.ETag, blob.ETag)
+	assert.Equal(t, int64(2), blob.Revision)
+	history, err := svc.Index().Revisions(key)
+	require.NoError(t, err)
+	assert.Len(t, history, 2)
+
+	// deleted before its hook ran
+	_, err = svc.Backend().DeleteObject(ctx, key)
+	require.NoError(t, err)
+	late = *second
+	svc.afterPutObject(nil, &late)
+	_, ok = svc.Index().Get(key)
+	assert.False(t, ok)
+}
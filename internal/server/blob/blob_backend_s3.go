@@ -4,7 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"maps"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -13,15 +16,23 @@ import (
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"github.com/openmined/syftbox/internal/blobcodec"
 )
 
 const (
-	uploadExpiry   = 5 * time.Minute
-	downloadExpiry = 5 * time.Minute
+	uploadExpiry = 5 * time.Minute
+	// DownloadURLExpiry is how long presigned download urls are valid for
+	DownloadURLExpiry = 5 * time.Minute
+
+	// object metadata of compressed blobs, describing the uncompressed content
+	metaContentETag = "syft-etag"
+	metaContentSize = "syft-size"
 )
 
 var (
-	ErrInvalidKey = errors.New("invalid key")
+	ErrInvalidKey   = errors.New("invalid key")
+	ErrObjectExists = errors.New("object already exists")
 )
 
 type S3Backend struct {
@@ -105,11 +116,32 @@ func (s *S3Backend) GetObject(ctx context.Context, key string) (*GetObjectRespon
 		return nil, err
 	}
 
+	codec := aws.ToString(resp.ContentEncoding)
+	if !blobcodec.IsCompressed(codec) {
+		return &GetObjectResponse{
+			Body:         resp.Body,
+			Size:         aws.ToInt64(resp.ContentLength),
+			ETag:         strings.ReplaceAll(aws.ToString(resp.ETag), "\"", ""),
+			LastModified: aws.ToTime(resp.LastModified),
+			Metadata:     resp.Metadata,
+		}, nil
+	}
+
+	// compressed blob, read it back as the original content
+	body, err := blobcodec.NewReader(codec, resp.Body)
+	if err != nil {
+		resp.Body.Close()
+		return nil, fmt.Errorf("decompress %q: %w", key, err)
+	}
+
+	size, _ := strconv.ParseInt(resp.Metadata[metaContentSize], 10, 64)
+
 	return &GetObjectResponse{
-		Body:         resp.Body,
-		Size:         aws.ToInt64(resp.ContentLength),
-		ETag:         strings.ReplaceAll(aws.ToString(resp.ETag), "\"", ""),
+		Body:         &decompressedBody{ReadCloser: body, raw: resp.Body},
+		Size:         size,
+		ETag:         resp.Metadata[metaContentETag],
 		LastModified: aws.ToTime(resp.LastModified),
+		Metadata:     resp.Metadata,
 	}, nil
 }
 
@@ -125,15 +157,39 @@ func (s *S3Backend) PutObject(ctx context.Context, params *PutObjectParams) (*Pu
 		return nil, ErrInvalidKey
 	}
 
+	sse, sseKeyID := s.sse()
 	s3Params := &s3.PutObjectInput{
-		Bucket:        &s.config.BucketName,
-		Key:           &params.Key,
-		Body:          params.Body,
-		ContentLength: aws.Int64(params.Size),
+		Bucket:               &s.config.BucketName,
+		Key:                  &params.Key,
+		Body:                 params.Body,
+		ContentLength:        aws.Int64(params.Size),
+		ServerSideEncryption: sse,
+		SSEKMSKeyId:          sseKeyID,
+	}
+
+	if len(params.Metadata) > 0 {
+		s3Params.Metadata = maps.Clone(params.Metadata)
+	}
+
+	compressed := blobcodec.IsCompressed(params.Codec)
+	if compressed {
+		s3Params.ContentEncoding = aws.String(params.Codec)
+		if s3Params.Metadata == nil {
+			s3Params.Metadata = make(map[string]string, 2)
+		}
+		s3Params.Metadata[metaContentETag] = params.ContentETag
+		s3Params.Metadata[metaContentSize] = strconv.FormatInt(params.ContentSize, 10)
+	}
+
+	if params.IfNotExists {
+		s3Params.IfNoneMatch = aws.String("*")
 	}
 
 	resp, err := s.s3Client.PutObject(ctx, s3Params)
-	if err != nil {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && apiErr.ErrorCode() == "PreconditionFailed" {
+		return nil, ErrObjectExists
+	} else if err != nil {
 		return nil, err
 	}
 
@@ -146,6 +202,14 @@ func (s *S3Backend) PutObject(ctx context.Context, params *PutObjectParams) (*Pu
 		LastModified: time.Now().UTC(),
 	}
 
+	// clients only know about the uncompressed content
+	if compressed {
+		result.Codec = params.Codec
+		result.StorageETag = result.ETag
+		result.ETag = params.ContentETag
+		result.Size = params.ContentSize
+	}
+
 	if s.hooks.AfterPutObject != nil {
 		s.hooks.AfterPutObject(params, result)
 	}
@@ -153,8 +217,8 @@ func (s *S3Backend) PutObject(ctx context.Context, params *PutObjectParams) (*Pu
 	return result, nil
 }
 
-func (s *S3Backend) PutObjectPresigned(ctx context.Context, key string) (string, error) {
-	return s.generatePutObjectURL(ctx, key)
+func (s *S3Backend) PutObjectPresigned(ctx context.Context, key string, size int64) (string, error) {
+	return s.generatePutObjectURL(ctx, key, size)
 }
 
 func (s *S3Backend) PutObjectMultipart(ctx context.Context, params *PutObjectMultipartParams) (*PutObjectMultipartResponse, error) {
@@ -162,10 +226,13 @@ func (s *S3Backend) PutObjectMultipart(ctx context.Context, params *PutObjectMul
 		return nil, ErrInvalidKey
 	}
 
-	// Create a multipart upload
+	// Create a multipart upload, the parts are encrypted like it
+	sse, sseKeyID := s.sse()
 	result, err := s.s3Client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
-		Bucket: &s.config.BucketName,
-		Key:    &params.Key,
+		Bucket:               &s.config.BucketName,
+		Key:                  &params.Key,
+		ServerSideEncryption: sse,
+		SSEKMSKeyId:          sseKeyID,
 	})
 
 	if err != nil {
@@ -238,10 +305,14 @@ func (s *S3Backend) CopyObject(ctx context.Context, params *CopyObjectParams) (*
 	if !ValidateKey(params.DestinationKey) {
 		return nil, fmt.Errorf("invalid destination key: %s", params.DestinationKey)
 	}
+	// copies aren't encrypted like their source, unless asked
+	sse, sseKeyID := s.sse()
 	resp, err := s.s3Client.CopyObject(ctx, &s3.CopyObjectInput{
-		Bucket:     &s.config.BucketName,
-		CopySource: aws.String(fmt.Sprintf("%s/%s", s.config.BucketName, params.SourceKey)),
-		Key:        &params.DestinationKey,
+		Bucket:               &s.config.BucketName,
+		CopySource:           aws.String(fmt.Sprintf("%s/%s", s.config.BucketName, params.SourceKey)),
+		Key:                  &params.DestinationKey,
+		ServerSideEncryption: sse,
+		SSEKMSKeyId:          sseKeyID,
 		// we can use these later!
 		// CopySourceIfMatch: ,
 		// CopySourceIfModifiedSince: ,
@@ -252,6 +323,7 @@ func (s *S3Backend) CopyObject(ctx context.Context, params *CopyObjectParams) (*
 
 	result := &CopyObjectResponse{
 		ETag:         strings.ReplaceAll(aws.ToString(resp.CopyObjectResult.ETag), "\"", ""),
+		Version:      aws.ToString(resp.VersionId),
 		LastModified: aws.ToTime(resp.CopyObjectResult.LastModified),
 	}
 
@@ -310,15 +382,24 @@ func (s *S3Backend) ListObjects(ctx context.Context) ([]*BlobInfo, error) {
 
 // ===================================================================================================
 
-func (s *S3Backend) generatePutObjectURL(ctx context.Context, key string) (string, error) {
+func (s *S3Backend) generatePutObjectURL(ctx context.Context, key string, size int64) (string, error) {
 	if !ValidateKey(key) {
 		return "", ErrInvalidKey
 	}
 
-	url, err := s.s3Presigner.PresignPutObject(ctx, &s3.PutObjectInput{
-		Bucket: &s.config.BucketName,
-		Key:    &key,
-	}, func(opts *s3.PresignOptions) {
+	// the encryption headers are signed, uploads must send them (see S3Config.UploadHeaders)
+	sse, sseKeyID := s.sse()
+	input := &s3.PutObjectInput{
+		Bucket:               &s.config.BucketName,
+		Key:                  &key,
+		ServerSideEncryption: sse,
+		SSEKMSKeyId:          sseKeyID,
+	}
+	// so is the content length, S3 rejects the uploads of another size
+	if size >= 0 {
+		input.ContentLength = aws.Int64(size)
+	}
+	url, err := s.s3Presigner.PresignPutObject(ctx, input, func(opts *s3.PresignOptions) {
 		opts.Expires = uploadExpiry
 	})
 	if err != nil {
@@ -327,6 +408,18 @@ func (s *S3Backend) generatePutObjectURL(ctx context.Context, key string) (strin
 	return url.URL, nil
 }
 
+// sse returns the server-side encryption of the uploads, empty when they aren't encrypted
+func (s *S3Backend) sse() (types.ServerSideEncryption, *string) {
+	if !s.config.encrypted() {
+		return "", nil
+	}
+	var keyID *string
+	if s.config.SSEKMSKeyID != "" {
+		keyID = aws.String(s.config.SSEKMSKeyID)
+	}
+	return types.ServerSideEncryption(s.config.SSE), keyID
+}
+
 func (s *S3Backend) generateGetObjectURL(ctx context.Context, key string) (string, error) {
 	if !ValidateKey(key) {
 		return "", ErrInvalidKey
@@ -336,7 +429,7 @@ func (s *S3Backend) generateGetObjectURL(ctx context.Context, key string) (strin
 		Bucket: &s.config.BucketName,
 		Key:    &key,
 	}, func(opts *s3.PresignOptions) {
-		opts.Expires = downloadExpiry
+		opts.Expires = DownloadURLExpiry
 	})
 	if err != nil {
 		return "", err
@@ -344,9 +437,48 @@ func (s *S3Backend) generateGetObjectURL(ctx context.Context, key string) (strin
 	return url.URL, nil
 }
 
+// Ping checks that the bucket exists and the credentials can access it
+func (s *S3Backend) Ping(ctx context.Context) error {
+	_, err := s.s3Client.HeadBucket(ctx, &s3.HeadBucketInput{
+		Bucket: &s.config.BucketName,
+	})
+	return err
+}
+
 func (s *S3Backend) Delegate() any {
 	return s.s3Client
 }
 
+func (s *S3Backend) storedVersion(ctx context.Context, key string) (*objectVersion, error) {
+	resp, err := s.s3Client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: &s.config.BucketName,
+		Key:    &key,
+	})
+	var notFound *types.NotFound
+	if errors.As(err, &notFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return &objectVersion{
+		ETag:    strings.ReplaceAll(aws.ToString(resp.ETag), "\"", ""),
+		Version: aws.ToString(resp.VersionId),
+	}, nil
+}
+
+// decompressedBody closes both the decompressor and the underlying object body
+type decompressedBody struct {
+	io.ReadCloser
+	raw io.Closer
+}
+
+func (b *decompressedBody) Close() error {
+	err := b.ReadCloser.Close()
+	if rawErr := b.raw.Close(); err == nil {
+		err = rawErr
+	}
+	return err
+}
+
 // check if BlobClient implements IBlobClient interface
 var _ IBlobBackend = (*S3Backend)(nil)
@@ -0,0 +1,92 @@
+package blob
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newS3TestConfig(endpoint string) *S3Config {
+	return &S3Config{
+		BucketName: "bucket",
+		Region:     "us-east-1",
+		AccessKey:  "access",
+		SecretKey:  "secret",
+		Endpoint:   endpoint,
+	}
+}
+
+func TestS3ConfigSSE(t *testing.T) {
+	cfg := newS3TestConfig("")
+	assert.NoError(t, cfg.Validate())
+	assert.Nil(t, cfg.UploadHeaders())
+
+	cfg.SSE = SSENone
+	assert.NoError(t, cfg.Validate())
+
+	cfg.SSE = SSEAES256
+	assert.NoError(t, cfg.Validate())
+	assert.Equal(t, map[string]string{"x-amz-server-side-encryption": "AES256"}, cfg.UploadHeaders())
+
+	cfg.SSE = SSEKMS
+	cfg.SSEKMSKeyID = "key-id"
+	assert.NoError(t, cfg.Validate())
+	assert.Equal(t, map[string]string{
+		"x-amz-server-side-encryption":                "aws:kms",
+		"x-amz-server-side-encryption-aws-kms-key-id": "key-id",
+	}, cfg.UploadHeaders())
+
+	cfg.SSE = SSEAES256
+	assert.ErrorContains(t, cfg.Validate(), "sse_kms_key_id requires sse aws:kms")
+	cfg.SSE = ""
+	assert.ErrorContains(t, cfg.Validate(), "sse_kms_key_id requires sse aws:kms")
+
+	cfg.SSE = "aes256"
+	cfg.SSEKMSKeyID = ""
+	assert.ErrorContains(t, cfg.Validate(), "unknown sse")
+
+	fs := &S3Config{Backend: BackendFilesystem, Dir: "/tmp/blobs", PublicURL: "http://localhost:8080", SSE: SSEAES256}
+	assert.ErrorContains(t, fs.Validate(), "not supported")
+}
+
+func TestS3BackendSSE(t *testing.T) {
+	var headers http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header.Clone()
+		w.Header().Set("ETag", `"etag"`)
+	}))
+	t.Cleanup(srv.Close)
+
+	cfg := newS3TestConfig(srv.URL)
+	cfg.SSE = SSEKMS
+	cfg.SSEKMSKeyID = "key-id"
+	// not from the default config, which reads the aws env of the machine
+	backend := NewS3Backend(s3.New(s3.Options{
+		Region:       cfg.Region,
+		Credentials:  credentials.NewStaticCredentialsProvider(cfg.AccessKey, cfg.SecretKey, ""),
+		BaseEndpoint: aws.String(cfg.Endpoint),
+		UsePathStyle: true,
+	}), cfg)
+
+	_, err := backend.PutObject(t.Context(), &PutObjectParams{
+		Key:  "alice@example.com/public/a.txt",
+		Body: strings.NewReader("hello"),
+		Size: 5,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "aws:kms", headers.Get("x-amz-server-side-encryption"))
+	assert.Equal(t, "key-id", headers.Get("x-amz-server-side-encryption-aws-kms-key-id"))
+
+	// presigned uploads sign the headers, the uploads must send them
+	url, err := backend.PutObjectPresigned(t.Context(), "alice@example.com/public/a.txt", 5)
+	require.NoError(t, err)
+	assert.Contains(t, url, "x-amz-server-side-encryption%3Bx-amz-server-side-encryption-aws-kms-key-id")
+	assert.Contains(t, url, "X-Amz-SignedHeaders=content-length%3B")
+}
@@ -33,8 +33,9 @@ type IBlobBackend interface {
 	// PutObject uploads a single object to storage
 	PutObject(ctx context.Context, params *PutObjectParams) (*PutObjectResponse, error)
 
-	// PutObjectPresigned generates a presigned URL for uploading an object
-	PutObjectPresigned(ctx context.Context, key string) (string, error)
+	// PutObjectPresigned generates a presigned URL for uploading an object of size bytes.
+	// The size is signed into the url, uploads of another size are rejected. A negative size isn't signed
+	PutObjectPresigned(ctx context.Context, key string, size int64) (string, error)
 
 	// PutObjectMultipart initiates a multipart upload and returns upload URLs
 	PutObjectMultipart(ctx context.Context, params *PutObjectMultipartParams) (*PutObjectMultipartResponse, error)
@@ -51,12 +52,34 @@ type IBlobBackend interface {
 	// ListObjects returns a list of all objects in storage
 	ListObjects(ctx context.Context) ([]*BlobInfo, error)
 
+	// Ping checks that the storage is reachable
+	Ping(ctx context.Context) error
+
 	// Delegate returns the underlying backend implementation
 	Delegate() any
 
 	// setHooks sets the notification hooks for backend operations
 	// This is a private method used internally by the blob service
 	setHooks(hooks *blobBackendHooks)
+
+	// storedVersion returns the version of the object the backend stores under the key now, nil without one,
+	// for the blob service to order the writes like the backend did
+	storedVersion(ctx context.Context, key string) (*objectVersion, error)
+}
+
+// objectVersion identifies an object stored by the backend. The etag is of the stored bytes,
+// the version is set by the backends that keep versions, like a versioned S3 bucket
+type objectVersion struct {
+	ETag    string
+	Version string
+}
+
+// matches checks if both are the same object, by version when the backend has them or else by etag
+func (v *objectVersion) matches(other *objectVersion) bool {
+	if v.Version != "" && other.Version != "" {
+		return v.Version == other.Version
+	}
+	return v.ETag == other.ETag
 }
 
 // ===================================================================================================
@@ -66,6 +89,7 @@ type GetObjectResponse struct {
 	ETag         string
 	Size         int64
 	LastModified time.Time
+	Metadata     map[string]string // stored with the object, e.g. file attributes
 }
 
 // ===================================================================================================
@@ -75,6 +99,17 @@ type PutObjectParams struct {
 	ETag string
 	Size int64
 	Body io.Reader
+
+	// Codec the body is compressed with. ContentETag and ContentSize describe the uncompressed content.
+	Codec       string
+	ContentETag string
+	ContentSize int64
+
+	// Metadata is stored with the object, e.g. file attributes
+	Metadata map[string]string
+
+	// IfNotExists only creates the object, the put fails with ErrObjectExists if the key already exists
+	IfNotExists bool
 }
 
 type PutObjectResponse struct {
@@ -83,6 +118,13 @@ type PutObjectResponse struct {
 	ETag         string
 	Size         int64
 	LastModified time.Time
+
+	// Codec the object is stored with. StorageETag is the etag of the stored (compressed) object.
+	Codec       string
+	StorageETag string
+
+	// Revision of the key assigned by the index, 0 when another write replaced the object before it was indexed
+	Revision int64
 }
 
 type PutObjectPresignedResponse struct {
@@ -126,6 +168,7 @@ type CopyObjectParams struct {
 
 type CopyObjectResponse struct {
 	ETag         string
+	Version      string
 	LastModified time.Time
 }
 
@@ -136,4 +179,7 @@ type BlobInfo struct {
 	ETag         string `json:"etag" db:"etag"`
 	Size         int64  `json:"size" db:"size"`
 	LastModified string `json:"lastModified" db:"last_modified"`
+	Codec        string `json:"codec,omitempty" db:"codec"`
+	StorageETag  string `json:"-" db:"storage_etag"`
+	Revision     int64  `json:"revision" db:"revision"`
 }
@@ -6,26 +6,126 @@ import (
 	"fmt"
 	"iter"
 	"log/slog"
+	"strings"
 	"time"
 
 	"github.com/jmoiron/sqlx"
 )
 
-const schemaSQL = `
+var schemaSQL = `
 CREATE TABLE IF NOT EXISTS blobs (
 	key TEXT PRIMARY KEY,
 	etag TEXT NOT NULL,
 	size INTEGER NOT NULL,
-	last_modified TEXT NOT NULL
+	last_modified TEXT NOT NULL,
+	codec TEXT NOT NULL DEFAULT '',
+	storage_etag TEXT NOT NULL DEFAULT '',
+	revision INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS blob_revisions (
+	key TEXT NOT NULL,
+	revision INTEGER NOT NULL,
+	etag TEXT NOT NULL,
+	size INTEGER NOT NULL,
+	deleted INTEGER NOT NULL DEFAULT 0,
+	created_at TEXT NOT NULL,
+	PRIMARY KEY (key, revision)
 );
 
 CREATE INDEX IF NOT EXISTS idx_blobs_etag ON blobs(etag);
 CREATE INDEX IF NOT EXISTS idx_blobs_last_modified ON blobs(last_modified);
+
+CREATE TABLE IF NOT EXISTS index_heartbeat (
+	id INTEGER PRIMARY KEY CHECK (id = 1),
+	ts INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS datasite_usage (
+	datasite TEXT PRIMARY KEY,
+	size INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TRIGGER IF NOT EXISTS blobs_usage_insert AFTER INSERT ON blobs BEGIN
+	INSERT INTO datasite_usage (datasite, size) VALUES (` + fmt.Sprintf(datasiteOfSQL, "NEW.key") + `, NEW.size)
+	ON CONFLICT(datasite) DO UPDATE SET size = size + excluded.size;
+END;
+
+CREATE TRIGGER IF NOT EXISTS blobs_usage_update AFTER UPDATE OF size ON blobs BEGIN
+	UPDATE datasite_usage SET size = size - OLD.size + NEW.size WHERE datasite = ` + fmt.Sprintf(datasiteOfSQL, "NEW.key") + `;
+END;
+
+CREATE TRIGGER IF NOT EXISTS blobs_usage_delete AFTER DELETE ON blobs BEGIN
+	UPDATE datasite_usage SET size = size - OLD.size WHERE datasite = ` + fmt.Sprintf(datasiteOfSQL, "OLD.key") + `;
+END;
+`
+
+// SnapshotsPrefix is where the snapshots are stored, outside of every datasite.
+// The snapshots of a datasite are under SnapshotsPrefix + datasite + "/"
+const SnapshotsPrefix = "_snapshots/"
+
+// datasiteOfSQL is the datasite a key belongs to, the part before the first separator.
+// Snapshots belong to the datasite they were taken of (the 11 characters of SnapshotsPrefix are skipped).
+// Keys outside of a datasite are accounted to the empty one
+const datasiteOfSQL = `CASE
+	WHEN substr(%[1]s, 1, 11) = '_snapshots/' AND instr(substr(%[1]s, 12), '/') > 0 THEN substr(%[1]s, 12, instr(substr(%[1]s, 12), '/') - 1)
+	WHEN instr(%[1]s, '/') > 0 THEN substr(%[1]s, 1, instr(%[1]s, '/') - 1)
+	ELSE '' END`
+
+// resetSnapshotUsageSQL clears the usage computed when the snapshots were accounted to a datasite of their own,
+// so that seedUsageSQL computes it again
+const resetSnapshotUsageSQL = `DELETE FROM datasite_usage WHERE EXISTS (SELECT 1 FROM datasite_usage WHERE datasite = '_snapshots')`
+
+// seedUsageSQL computes the usage of indexes created before it was tracked
+var seedUsageSQL = `
+	INSERT INTO datasite_usage (datasite, size)
+	SELECT ` + fmt.Sprintf(datasiteOfSQL, "key") + ` AS datasite, SUM(size) FROM blobs
+	WHERE NOT EXISTS (SELECT 1 FROM datasite_usage)
+	GROUP BY datasite
+`
+
+// columns added after the initial schema, created on existing databases by migrate
+var addedColumns = []struct{ name, ddl string }{
+	{"codec", `ALTER TABLE blobs ADD COLUMN codec TEXT NOT NULL DEFAULT ''`},
+	{"storage_etag", `ALTER TABLE blobs ADD COLUMN storage_etag TEXT NOT NULL DEFAULT ''`},
+	{"revision", `ALTER TABLE blobs ADD COLUMN revision INTEGER NOT NULL DEFAULT 0`},
+}
+
+const blobColumns = "key, etag, size, last_modified, codec, storage_etag, revision"
+
+// nextRevisionSQL is the revision following the last one recorded for a key.
+// Revisions are never reused, even after the key was deleted
+const nextRevisionSQL = `COALESCE((SELECT MAX(r.revision) FROM blob_revisions r WHERE r.key = %s), 0) + 1`
+
+// upsertBlobSQL adds or updates a blob and returns its revision.
+// New content gets the next revision of the key, rewriting the same content keeps the current one.
+// Blobs indexed before revisions were tracked (revision 0) get their first one on the next write
+var upsertBlobSQL = `
+	INSERT INTO blobs (` + blobColumns + `)
+	VALUES (?, ?, ?, ?, ?, ?, ` + fmt.Sprintf(nextRevisionSQL, "?") + `)
+	ON CONFLICT(key) DO UPDATE SET
+		etag = excluded.etag,
+		size = excluded.size,
+		last_modified = excluded.last_modified,
+		codec = excluded.codec,
+		storage_etag = excluded.storage_etag,
+		revision = CASE WHEN blobs.etag = excluded.etag AND blobs.revision > 0 THEN blobs.revision ELSE excluded.revision END
+	RETURNING revision
+`
+
+// insertRevisionSQL records a revision of a key. Rewrites of the same revision are ignored
+const insertRevisionSQL = `
+	INSERT OR IGNORE INTO blob_revisions (key, revision, etag, size, deleted, created_at)
+	VALUES (?, ?, ?, ?, 0, ?)
 `
 
 // BlobIndex provides access to the blob metadata stored in SQLite
 type BlobIndex struct {
 	db *sqlx.DB
+
+	// how many revisions of a key are kept, and for how long. 0 keeps them all
+	revisionsKept  int
+	revisionMaxAge time.Duration
 }
 
 // newBlobIndex creates a new index using an existing database connection
@@ -35,9 +135,36 @@ func newBlobIndex(db *sqlx.DB) (*BlobIndex, error) {
 		return nil, fmt.Errorf("failed to initialize index: %w", err)
 	}
 
+	if err := idx.migrate(); err != nil {
+		return nil, fmt.Errorf("failed to migrate index: %w", err)
+	}
+
 	return idx, nil
 }
 
+// migrate adds the columns missing from indexes created by older versions, and (re)computes their datasite usage
+func (bi *BlobIndex) migrate() error {
+	for _, col := range addedColumns {
+		var exists bool
+		if err := bi.db.Get(&exists, "SELECT COUNT(*) > 0 FROM pragma_table_info('blobs') WHERE name = ?", col.name); err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+		if _, err := bi.db.Exec(col.ddl); err != nil {
+			return fmt.Errorf("add column %s: %w", col.name, err)
+		}
+	}
+	if _, err := bi.db.Exec(resetSnapshotUsageSQL); err != nil {
+		return fmt.Errorf("reset datasite usage: %w", err)
+	}
+	if _, err := bi.db.Exec(seedUsageSQL); err != nil {
+		return fmt.Errorf("seed datasite usage: %w", err)
+	}
+	return nil
+}
+
 // Close releases resources used by the index
 func (bi *BlobIndex) Close() error {
 	return bi.db.Close()
@@ -46,7 +173,7 @@ func (bi *BlobIndex) Close() error {
 // Get retrieves blob info by key
 func (bi *BlobIndex) Get(key string) (*BlobInfo, bool) {
 	var blob BlobInfo
-	err := bi.db.Get(&blob, "SELECT key, etag, size, last_modified FROM blobs WHERE key = ?", key)
+	err := bi.db.Get(&blob, "SELECT "+blobColumns+" FROM blobs WHERE key = ?", key)
 	if err != nil {
 		if !errors.Is(err, sql.ErrNoRows) {
 			slog.Error("sqlite error", "op", "Get", "key", key, "error", err)
@@ -57,16 +184,47 @@ func (bi *BlobIndex) Get(key string) (*BlobInfo, bool) {
 	return &blob, true
 }
 
-// Set adds or updates a blob in the index
+// Set adds or updates a blob in the index. The revision assigned to the blob is set on it
 func (bi *BlobIndex) Set(blob *BlobInfo) error {
-	_, err := bi.db.Exec(
-		`INSERT OR REPLACE INTO blobs (key, etag, size, last_modified) VALUES (?, ?, ?, ?)`,
-		blob.Key, blob.ETag, blob.Size, blob.LastModified,
-	)
+	tx, err := bi.db.Beginx()
 	if err != nil {
 		slog.Error("sqlite error", "op", "Set", "key", blob.Key, "error", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	if err := setBlob(tx, blob); err != nil {
+		tx.Rollback()
+		slog.Error("sqlite error", "op", "Set", "key", blob.Key, "error", err)
+		return err
 	}
-	return err
+
+	if err := tx.Commit(); err != nil {
+		slog.Error("sqlite error commit", "op", "Set", "key", blob.Key, "error", err)
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// setBlob upserts a blob and records its revision. Writes are serialized by sqlite,
+// so concurrent writes to a key get distinct revisions in the order they were indexed
+func setBlob(tx *sqlx.Tx, blob *BlobInfo) error {
+	var revision int64
+	err := tx.Get(&revision, upsertBlobSQL,
+		blob.Key, blob.ETag, blob.Size, blob.LastModified, blob.Codec, blob.StorageETag, blob.Key,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to upsert blob %s: %w", blob.Key, err)
+	}
+
+	if _, err := tx.Exec(insertRevisionSQL,
+		blob.Key, revision, blob.ETag, blob.Size, time.Now().UTC().Format(time.RFC3339Nano),
+	); err != nil {
+		return fmt.Errorf("failed to record revision of blob %s: %w", blob.Key, err)
+	}
+
+	blob.Revision = revision
+	return nil
 }
 
 // SetMany adds or updates multiple blobs in the index in a single transaction
@@ -81,21 +239,11 @@ func (bi *BlobIndex) SetMany(blobs []*BlobInfo) error {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 
-	stmt, err := tx.Preparex(
-		`INSERT OR REPLACE INTO blobs (key, etag, size, last_modified) VALUES (?, ?, ?, ?)`,
-	)
-	if err != nil {
-		tx.Rollback()
-		slog.Error("sqlite error preparex", "op", "SetMany", "error", err)
-		return fmt.Errorf("failed to prepare statement: %w", err)
-	}
-
 	for _, blob := range blobs {
-		_, err := stmt.Exec(blob.Key, blob.ETag, blob.Size, blob.LastModified)
-		if err != nil {
+		if err := setBlob(tx, blob); err != nil {
 			tx.Rollback()
 			slog.Error("sqlite error exec insert", "op", "SetMany", "key", blob.Key, "error", err)
-			return fmt.Errorf("failed to insert blob %s: %w", blob.Key, err)
+			return err
 		}
 	}
 
@@ -107,19 +255,94 @@ func (bi *BlobIndex) SetMany(blobs []*BlobInfo) error {
 	return nil
 }
 
-// Remove deletes a blob from the index
+// Remove deletes a blob from the index, recording the deletion as a revision of the key
 func (bi *BlobIndex) Remove(key string) error {
-	_, err := bi.db.Exec("DELETE FROM blobs WHERE key = ?", key)
+	tx, err := bi.db.Beginx()
 	if err != nil {
 		slog.Error("sqlite error", "op", "Remove", "key", key, "error", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
-	return err
+
+	_, err = tx.Exec(`
+		INSERT INTO blob_revisions (key, revision, etag, size, deleted, created_at)
+		SELECT b.key, `+fmt.Sprintf(nextRevisionSQL, "b.key")+`, '', 0, 1, ?
+		FROM blobs b WHERE b.key = ?`,
+		time.Now().UTC().Format(time.RFC3339Nano), key,
+	)
+	if err == nil {
+		_, err = tx.Exec("DELETE FROM blobs WHERE key = ?", key)
+	}
+	if err != nil {
+		tx.Rollback()
+		slog.Error("sqlite error", "op", "Remove", "key", key, "error", err)
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		slog.Error("sqlite error commit", "op", "Remove", "key", key, "error", err)
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// Usage returns the total size of the blobs stored in a datasite
+func (bi *BlobIndex) Usage(datasite string) int64 {
+	var size int64
+	err := bi.db.Get(&size, "SELECT size FROM datasite_usage WHERE datasite = ?", datasite)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		slog.Error("sqlite error", "op", "Usage", "datasite", datasite, "error", err)
+	}
+	return size
+}
+
+// pruneRevisions deletes the revisions past the retention: beyond the last revisionsKept of a key,
+// or older than revisionMaxAge. The last revision of a key is kept while the key exists, so revisions
+// aren't reused. A key deleted for longer than the max age starts over at revision 1.
+func (bi *BlobIndex) pruneRevisions(now time.Time) (int64, error) {
+	var conds []string
+	var args []any
+	if bi.revisionsKept > 0 {
+		conds = append(conds, "(r.revision < l.latest AND r.revision <= l.latest - ?)")
+		args = append(args, bi.revisionsKept)
+	}
+	if bi.revisionMaxAge > 0 {
+		conds = append(conds, "((r.revision < l.latest OR r.deleted = 1) AND r.created_at < ?)")
+		args = append(args, now.Add(-bi.revisionMaxAge).UTC().Format(time.RFC3339Nano))
+	}
+	if len(conds) == 0 {
+		return 0, nil
+	}
+
+	res, err := bi.db.Exec(`
+		DELETE FROM blob_revisions WHERE rowid IN (
+			SELECT r.rowid FROM blob_revisions r
+			JOIN (SELECT key, MAX(revision) AS latest FROM blob_revisions GROUP BY key) l ON l.key = r.key
+			WHERE `+strings.Join(conds, " OR ")+`
+		)`, args...)
+	if err != nil {
+		slog.Error("sqlite error", "op", "pruneRevisions", "error", err)
+		return 0, fmt.Errorf("failed to prune revisions: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// Revisions returns the version history of a key, oldest first
+func (bi *BlobIndex) Revisions(key string) ([]*BlobRevision, error) {
+	revisions := make([]*BlobRevision, 0)
+	err := bi.db.Select(&revisions,
+		"SELECT key, revision, etag, size, deleted, created_at FROM blob_revisions WHERE key = ? ORDER BY revision", key,
+	)
+	if err != nil {
+		slog.Error("sqlite error", "op", "Revisions", "key", key, "error", err)
+		return nil, fmt.Errorf("failed to list revisions: %w", err)
+	}
+	return revisions, nil
 }
 
 // List returns all blobs in the index
 func (bi *BlobIndex) List() ([]*BlobInfo, error) {
 	blobs := make([]*BlobInfo, 0)
-	err := bi.db.Select(&blobs, "SELECT key, etag, size, last_modified FROM blobs")
+	err := bi.db.Select(&blobs, "SELECT "+blobColumns+" FROM blobs")
 	if err != nil {
 		slog.Error("sqlite error", "op", "List", "error", err)
 		return nil, fmt.Errorf("failed to list blobs: %w", err)
@@ -132,7 +355,7 @@ func (bi *BlobIndex) List() ([]*BlobInfo, error) {
 func (bi *BlobIndex) Iter() iter.Seq[*BlobInfo] {
 	return func(yield func(*BlobInfo) bool) {
 		// Use a prepared statement for better performance
-		stmt, err := bi.db.Preparex("SELECT key, etag, size, last_modified FROM blobs")
+		stmt, err := bi.db.Preparex("SELECT " + blobColumns + " FROM blobs")
 		if err != nil {
 			slog.Error("failed to prepare blob query", "error", err)
 			return
@@ -148,12 +371,12 @@ func (bi *BlobIndex) Iter() iter.Seq[*BlobInfo] {
 		defer rows.Close()
 
 		// Use direct field mapping to avoid reflection overhead from StructScan
-		var key, etag, lastModified string
-		var size int64
+		var key, etag, lastModified, codec, storageETag string
+		var size, revision int64
 
 		// Get raw columns to avoid StructScan overhead
 		for rows.Next() {
-			err := rows.Scan(&key, &etag, &size, &lastModified)
+			err := rows.Scan(&key, &etag, &size, &lastModified, &codec, &storageETag, &revision)
 			if err != nil {
 				slog.Error("failed to scan blob row", "error", err)
 				continue
@@ -165,6 +388,9 @@ func (bi *BlobIndex) Iter() iter.Seq[*BlobInfo] {
 				ETag:         etag,
 				Size:         size,
 				LastModified: lastModified,
+				Codec:        codec,
+				StorageETag:  storageETag,
+				Revision:     revision,
 			}
 
 			if !yield(blob) {
@@ -191,7 +417,7 @@ func (bi *BlobIndex) Count() int {
 // FilterByKeyGlob returns blobs with keys matching the given SQL LIKE pattern
 func (bi *BlobIndex) FilterByKeyGlob(pattern string) ([]*BlobInfo, error) {
 	blobs := make([]*BlobInfo, 0)
-	err := bi.db.Select(&blobs, "SELECT key, etag, size, last_modified FROM blobs WHERE key GLOB ?", pattern)
+	err := bi.db.Select(&blobs, "SELECT "+blobColumns+" FROM blobs WHERE key GLOB ?", pattern)
 	if err != nil {
 		slog.Error("sqlite error", "op", "FilterByKeyGlob", "pattern", pattern, "error", err)
 		return nil, fmt.Errorf("failed to filter blobs by pattern: %w", err)
@@ -209,7 +435,7 @@ func (bi *BlobIndex) FilterByPrefix(prefix string) ([]*BlobInfo, error) {
 
 // FilterByTime returns blobs modified after the given time
 func (bi *BlobIndex) FilterByTime(filter TimeFilter) ([]*BlobInfo, error) {
-	query := "SELECT key, etag, size, last_modified FROM blobs WHERE 1=1"
+	query := "SELECT " + blobColumns + " FROM blobs WHERE 1=1"
 
 	if filter.Before != nil {
 		query += " AND last_modified < '" + filter.Before.Format(time.RFC3339) + "'"
@@ -238,6 +464,14 @@ func (bi *BlobIndex) FilterBeforeTime(before time.Time) ([]*BlobInfo, error) {
 	return bi.FilterByTime(TimeFilter{Before: &before})
 }
 
+// blobChangedSQL matches indexed blobs (b) that differ from the listed objects (t).
+// Listings describe the stored object, so compressed blobs can only be compared by their storage etag
+const blobChangedSQL = `
+	CASE WHEN b.codec != '' THEN t.etag != b.storage_etag
+	ELSE t.etag != b.etag OR t.last_modified != b.last_modified OR t.size != b.size
+	END
+`
+
 // bulkUpdate updates the index with a set of blobs, adding new ones, updating changed ones,
 // and removing blobs that no longer exist
 func (bi *BlobIndex) bulkUpdate(blobs []*BlobInfo) (*bulkUpdateResult, error) {
@@ -298,6 +532,21 @@ func (bi *BlobIndex) bulkUpdate(blobs []*BlobInfo) (*bulkUpdateResult, error) {
 		return nil, fmt.Errorf("failed to count deleted blobs: %w", err)
 	}
 
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+
+	// Record the deletions in the version history
+	_, err = tx.Exec(`
+		INSERT INTO blob_revisions (key, revision, etag, size, deleted, created_at)
+		SELECT b.key, `+fmt.Sprintf(nextRevisionSQL, "b.key")+`, '', 0, 1, ?
+		FROM blobs b
+		LEFT JOIN temp_blobs t ON b.key = t.key
+		WHERE t.key IS NULL
+	`, now)
+	if err != nil {
+		slog.Error("sqlite error exec delete revisions", "op", "bulkUpdate", "error", err)
+		return nil, fmt.Errorf("failed to record deleted blobs: %w", err)
+	}
+
 	// Delete those blobs using LEFT JOIN for better index usage
 	_, err = tx.Exec(`
 		DELETE FROM blobs
@@ -327,21 +576,33 @@ func (bi *BlobIndex) bulkUpdate(blobs []*BlobInfo) (*bulkUpdateResult, error) {
 	err = tx.Get(&result.Updated, `
 		SELECT COUNT(*) FROM temp_blobs t
 		JOIN blobs b ON t.key = b.key
-		WHERE t.etag != b.etag OR t.last_modified != b.last_modified OR t.size != b.size
-	`)
+		WHERE `+blobChangedSQL)
 	if err != nil {
 		slog.Error("sqlite error count updated blobs", "op", "bulkUpdate", "error", err)
 		return nil, fmt.Errorf("failed to count updated blobs: %w", err)
 	}
 
-	// Update or insert blobs that have changed or are new
+	// Blobs that have changed or are new get the next revision of their key
 	_, err = tx.Exec(`
-		INSERT OR REPLACE INTO blobs (key, etag, size, last_modified)
-		SELECT t.key, t.etag, t.size, t.last_modified
+		INSERT INTO blob_revisions (key, revision, etag, size, deleted, created_at)
+		SELECT t.key, `+fmt.Sprintf(nextRevisionSQL, "t.key")+`, t.etag, t.size, 0, ?
 		FROM temp_blobs t
 		LEFT JOIN blobs b ON t.key = b.key
-		WHERE b.key IS NULL OR t.etag != b.etag OR t.last_modified != b.last_modified OR t.size != b.size
-	`)
+		WHERE b.key IS NULL OR `+blobChangedSQL, now)
+	if err != nil {
+		slog.Error("sqlite error exec revisions", "op", "bulkUpdate", "error", err)
+		return nil, fmt.Errorf("failed to record changed blobs: %w", err)
+	}
+
+	// Update or insert blobs that have changed or are new.
+	// A changed blob was overwritten, so it's no longer known to be compressed
+	_, err = tx.Exec(`
+		INSERT OR REPLACE INTO blobs (` + blobColumns + `)
+		SELECT t.key, t.etag, t.size, t.last_modified, '', '',
+			(SELECT MAX(r.revision) FROM blob_revisions r WHERE r.key = t.key)
+		FROM temp_blobs t
+		LEFT JOIN blobs b ON t.key = b.key
+		WHERE b.key IS NULL OR ` + blobChangedSQL)
 	if err != nil {
 		slog.Error("sqlite error exec update or insert", "op", "bulkUpdate", "error", err)
 		return nil, fmt.Errorf("failed to update or insert blobs: %w", err)
@@ -0,0 +1,248 @@
+package blob
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"iter"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+// How the reads are served from the replica of the index
+const (
+	// from the replica while it lags at most the max lag behind the primary
+	ReplicaConsistencyBounded = "bounded"
+	// also from the primary until the replica caught up with the last write of this server
+	ReplicaConsistencyReadYourWrites = "read_your_writes"
+)
+
+// how often the primary writes its heartbeat, and the lag of the replica is measured
+const replicaHeartbeatInterval = time.Second
+
+// ReplicatedIndex serves the reads of the index from a read-only replica, and sends the writes to the primary.
+// The replica is kept up to date by another process, e.g. litestream or litefs, this server only reads it.
+//
+// The primary writes its time to a heartbeat row every second, the lag of the replica is how old the heartbeat
+// it has is. Reads fall back to the primary while the replica lags more than the max lag, so that they are never
+// staler than that, or with read_your_writes until the replica has the last write of this server.
+type ReplicatedIndex struct {
+	primary     *BlobIndex
+	replica     *BlobIndex
+	maxLag      time.Duration
+	consistency string
+	now         func() time.Time
+
+	replicaAt atomic.Int64 // unix ms of the heartbeat the replica last had, 0 until it had one
+	writtenAt atomic.Int64 // unix ms of the last write to the primary
+
+	replicaReads atomic.Int64
+	primaryReads atomic.Int64
+}
+
+// IndexReplicaStats is the state of the replica of the index, reported to the admins
+type IndexReplicaStats struct {
+	Consistency  string `json:"consistency"`
+	LagMs        int64  `json:"lagMs"` // -1 until the replica had a heartbeat
+	MaxLagMs     int64  `json:"maxLagMs"`
+	Serving      string `json:"serving"` // where the reads go now, replica or primary
+	ReplicaReads int64  `json:"replicaReads"`
+	PrimaryReads int64  `json:"primaryReads"`
+}
+
+func newReplicatedIndex(primary *BlobIndex, replica *BlobIndex, maxLag time.Duration, consistency string) *ReplicatedIndex {
+	if consistency == "" {
+		consistency = ReplicaConsistencyBounded
+	}
+	return &ReplicatedIndex{
+		primary:     primary,
+		replica:     replica,
+		maxLag:      maxLag,
+		consistency: consistency,
+		now:         time.Now,
+	}
+}
+
+// run writes the heartbeat of the primary and measures the lag of the replica, until ctx is done
+func (ri *ReplicatedIndex) run(ctx context.Context) {
+	ticker := time.NewTicker(replicaHeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		ri.beat()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (ri *ReplicatedIndex) beat() {
+	if err := ri.primary.setHeartbeat(ri.now()); err != nil {
+		slog.Error("index heartbeat", "error", err)
+	}
+
+	// a replica that can't be read ages out on its last heartbeat
+	replicaAt, err := ri.replica.heartbeat()
+	if err != nil {
+		slog.Warn("index replica heartbeat", "error", err)
+		return
+	}
+	ri.replicaAt.Store(replicaAt.UnixMilli())
+}
+
+// Lag is how far the replica is behind the primary, and false until it had a heartbeat
+func (ri *ReplicatedIndex) Lag() (time.Duration, bool) {
+	replicaAt := ri.replicaAt.Load()
+	if replicaAt == 0 {
+		return 0, false
+	}
+	return time.Duration(ri.now().UnixMilli()-replicaAt) * time.Millisecond, true
+}
+
+// Ping fails while the reads are served from the primary because the replica lags too much
+func (ri *ReplicatedIndex) Ping(ctx context.Context) error {
+	lag, ok := ri.Lag()
+	if !ok {
+		return fmt.Errorf("no heartbeat in the replica")
+	}
+	if lag > ri.maxLag {
+		return fmt.Errorf("replica lags %s behind, over the max lag of %s", lag.Round(time.Millisecond), ri.maxLag)
+	}
+	return nil
+}
+
+// Stats returns the state of the replica
+func (ri *ReplicatedIndex) Stats() *IndexReplicaStats {
+	stats := &IndexReplicaStats{
+		Consistency:  ri.consistency,
+		LagMs:        -1,
+		MaxLagMs:     ri.maxLag.Milliseconds(),
+		Serving:      "primary",
+		ReplicaReads: ri.replicaReads.Load(),
+		PrimaryReads: ri.primaryReads.Load(),
+	}
+	if lag, ok := ri.Lag(); ok {
+		stats.LagMs = lag.Milliseconds()
+	}
+	if ri.fresh() {
+		stats.Serving = "replica"
+	}
+	return stats
+}
+
+// fresh checks if the replica is recent enough to serve the reads
+func (ri *ReplicatedIndex) fresh() bool {
+	lag, ok := ri.Lag()
+	if !ok || lag > ri.maxLag {
+		return false
+	}
+	// the heartbeat the replica has was written after the last write, both at the ms
+	return ri.consistency != ReplicaConsistencyReadYourWrites || ri.replicaAt.Load() > ri.writtenAt.Load()
+}
+
+func (ri *ReplicatedIndex) reader() *BlobIndex {
+	if ri.fresh() {
+		ri.replicaReads.Add(1)
+		return ri.replica
+	}
+	ri.primaryReads.Add(1)
+	return ri.primary
+}
+
+func (ri *ReplicatedIndex) written() {
+	ri.writtenAt.Store(ri.now().UnixMilli())
+}
+
+// Close closes the replica, the primary is closed with the service
+func (ri *ReplicatedIndex) Close() error {
+	return ri.replica.Close()
+}
+
+func (ri *ReplicatedIndex) Get(key string) (*BlobInfo, bool) {
+	return ri.reader().Get(key)
+}
+
+func (ri *ReplicatedIndex) Set(blob *BlobInfo) error {
+	defer ri.written()
+	return ri.primary.Set(blob)
+}
+
+func (ri *ReplicatedIndex) SetMany(blobs []*BlobInfo) error {
+	defer ri.written()
+	return ri.primary.SetMany(blobs)
+}
+
+func (ri *ReplicatedIndex) Remove(key string) error {
+	defer ri.written()
+	return ri.primary.Remove(key)
+}
+
+func (ri *ReplicatedIndex) Usage(datasite string) int64 {
+	return ri.reader().Usage(datasite)
+}
+
+func (ri *ReplicatedIndex) Revisions(key string) ([]*BlobRevision, error) {
+	return ri.reader().Revisions(key)
+}
+
+func (ri *ReplicatedIndex) List() ([]*BlobInfo, error) {
+	return ri.reader().List()
+}
+
+func (ri *ReplicatedIndex) Iter() iter.Seq[*BlobInfo] {
+	return ri.reader().Iter()
+}
+
+func (ri *ReplicatedIndex) Count() int {
+	return ri.reader().Count()
+}
+
+func (ri *ReplicatedIndex) FilterByKeyGlob(pattern string) ([]*BlobInfo, error) {
+	return ri.reader().FilterByKeyGlob(pattern)
+}
+
+func (ri *ReplicatedIndex) FilterByPrefix(prefix string) ([]*BlobInfo, error) {
+	return ri.reader().FilterByPrefix(prefix)
+}
+
+func (ri *ReplicatedIndex) FilterBySuffix(suffix string) ([]*BlobInfo, error) {
+	return ri.reader().FilterBySuffix(suffix)
+}
+
+func (ri *ReplicatedIndex) FilterByTime(filter TimeFilter) ([]*BlobInfo, error) {
+	return ri.reader().FilterByTime(filter)
+}
+
+func (ri *ReplicatedIndex) FilterAfterTime(after time.Time) ([]*BlobInfo, error) {
+	return ri.reader().FilterAfterTime(after)
+}
+
+func (ri *ReplicatedIndex) FilterBeforeTime(before time.Time) ([]*BlobInfo, error) {
+	return ri.reader().FilterBeforeTime(before)
+}
+
+// setHeartbeat writes the time of the primary, for its replicas to measure their lag
+func (bi *BlobIndex) setHeartbeat(now time.Time) error {
+	_, err := bi.db.Exec(`
+		INSERT INTO index_heartbeat (id, ts) VALUES (1, ?)
+		ON CONFLICT(id) DO UPDATE SET ts = excluded.ts
+	`, now.UnixMilli())
+	return err
+}
+
+// heartbeat reads the last time the primary wrote, as replicated
+func (bi *BlobIndex) heartbeat() (time.Time, error) {
+	var ts int64
+	if err := bi.db.Get(&ts, "SELECT ts FROM index_heartbeat WHERE id = 1"); errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, fmt.Errorf("no heartbeat yet")
+	} else if err != nil {
+		return time.Time{}, err
+	}
+	return time.UnixMilli(ts), nil
+}
+
+var _ IBlobIndex = (*ReplicatedIndex)(nil)
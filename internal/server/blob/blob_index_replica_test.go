@@ -0,0 +1,190 @@
+package blob
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/openmined/syftbox/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestReplicatedIndex sets up a primary and a replica in two databases. The replication is played by
+// the returned writer, that writes to the replica what the test wants it to have replicated
+func newTestReplicatedIndex(t *testing.T, consistency string) (*ReplicatedIndex, *BlobIndex, *time.Time) {
+	dir := t.TempDir()
+
+	primaryDB, err := db.NewSqliteDB(db.WithPath(filepath.Join(dir, "primary.db")))
+	require.NoError(t, err)
+	primary, err := newBlobIndex(primaryDB)
+	require.NoError(t, err)
+	t.Cleanup(func() { primary.Close() })
+
+	replicaPath := filepath.Join(dir, "replica.db")
+	writerDB, err := db.NewSqliteDB(db.WithPath(replicaPath))
+	require.NoError(t, err)
+	writer, err := newBlobIndex(writerDB)
+	require.NoError(t, err)
+	t.Cleanup(func() { writer.Close() })
+
+	replicaDB, err := db.NewSqliteDB(db.WithPath(replicaPath), db.WithReadOnly())
+	require.NoError(t, err)
+
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	ri := newReplicatedIndex(primary, &BlobIndex{db: replicaDB}, 5*time.Second, consistency)
+	ri.now = func() time.Time { return now }
+	t.Cleanup(func() { ri.Close() })
+
+	return ri, writer, &now
+}
+
+func TestReplicatedIndexBoundedStaleness(t *testing.T) {
+	ri, writer, now := newTestReplicatedIndex(t, ReplicaConsistencyBounded)
+
+	// no heartbeat replicated yet
+	require.NoError(t, ri.Set(&BlobInfo{Key: "alice@example.com/a.txt", ETag: "primary", LastModified: now.Format(time.RFC3339)}))
+	blob, ok := ri.Get("alice@example.com/a.txt")
+	require.True(t, ok)
+	assert.Equal(t, "primary", blob.ETag)
+	assert.Error(t, ri.Ping(context.Background()))
+
+	// the writes go to the primary only, the replica can't be written to
+	_, ok = writer.Get("alice@example.com/a.txt")
+	assert.False(t, ok)
+	assert.Error(t, ri.replica.Set(&BlobInfo{Key: "alice@example.com/b.txt", ETag: "replica"}))
+
+	// replicated, with a content that tells which database served the read
+	require.NoError(t, writer.Set(&BlobInfo{Key: "alice@example.com/a.txt", ETag: "replica", LastModified: now.Format(time.RFC3339)}))
+	require.NoError(t, writer.setHeartbeat(*now))
+	*now = now.Add(2 * time.Second)
+	ri.beat()
+
+	blob, ok = ri.Get("alice@example.com/a.txt")
+	require.True(t, ok)
+	assert.Equal(t, "replica", blob.ETag)
+	blobs, err := ri.FilterByPrefix("alice@example.com/")
+	require.NoError(t, err)
+	require.Len(t, blobs, 1)
+	assert.Equal(t, "replica", blobs[0].ETag)
+	assert.NoError(t, ri.Ping(context.Background()))
+
+	// a write doesn't wait for the replica with bounded staleness
+	require.NoError(t, ri.Set(&BlobInfo{Key: "alice@example.com/a.txt", ETag: "primary-2", LastModified: now.Format(time.RFC3339)}))
+	blob, _ = ri.Get("alice@example.com/a.txt")
+	assert.Equal(t, "replica", blob.ETag)
+
+	stats := ri.Stats()
+	assert.Equal(t, int64(2000), stats.LagMs)
+	assert.Equal(t, int64(5000), stats.MaxLagMs)
+	assert.Equal(t, "replica", stats.Serving)
+	assert.Equal(t, int64(3), stats.ReplicaReads)
+	assert.Equal(t, int64(1), stats.PrimaryReads)
+
+	// past the max lag, the reads fall back to the primary
+	*now = now.Add(4 * time.Second)
+	ri.beat()
+
+	blob, _ = ri.Get("alice@example.com/a.txt")
+	assert.Equal(t, "primary-2", blob.ETag)
+	assert.ErrorContains(t, ri.Ping(context.Background()), "over the max lag")
+	assert.Equal(t, "primary", ri.Stats().Serving)
+	assert.Equal(t, int64(6000), ri.Stats().LagMs)
+
+	// the primary wrote its heartbeat for the replication
+	heartbeat, err := ri.primary.heartbeat()
+	require.NoError(t, err)
+	assert.Equal(t, *now, heartbeat.UTC())
+}
+
+func TestReplicatedIndexReadYourWrites(t *testing.T) {
+	ri, writer, now := newTestReplicatedIndex(t, ReplicaConsistencyReadYourWrites)
+
+	require.NoError(t, writer.Set(&BlobInfo{Key: "alice@example.com/a.txt", ETag: "replica"}))
+	require.NoError(t, writer.setHeartbeat(*now))
+	ri.beat()
+
+	blob, _ := ri.Get("alice@example.com/a.txt")
+	assert.Equal(t, "replica", blob.ETag)
+
+	// the reads go to the primary until the replica has a heartbeat written after the write
+	*now = now.Add(time.Second)
+	require.NoError(t, ri.Set(&BlobInfo{Key: "alice@example.com/a.txt", ETag: "primary"}))
+	require.NoError(t, writer.setHeartbeat(*now))
+	ri.beat()
+
+	blob, _ = ri.Get("alice@example.com/a.txt")
+	assert.Equal(t, "primary", blob.ETag)
+	assert.Equal(t, "primary", ri.Stats().Serving)
+	// still within the max lag, so healthy
+	assert.NoError(t, ri.Ping(context.Background()))
+
+	*now = now.Add(time.Second)
+	require.NoError(t, writer.Set(&BlobInfo{Key: "alice@example.com/a.txt", ETag: "replica-2"}))
+	require.NoError(t, writer.setHeartbeat(*now))
+	ri.beat()
+
+	blob, _ = ri.Get("alice@example.com/a.txt")
+	assert.Equal(t, "replica-2", blob.ETag)
+	assert.Equal(t, int64(2), ri.Stats().ReplicaReads)
+	assert.Equal(t, int64(1), ri.Stats().PrimaryReads)
+}
+
+// listedBackend lists the given objects, like a backend the presigned uploads went to directly
+type listedBackend struct {
+	IBlobBackend
+	objects []*BlobInfo
+}
+
+func (b *listedBackend) ListObjects(ctx context.Context) ([]*BlobInfo, error) {
+	return b.objects, nil
+}
+
+func TestReplicatedIndexReadYourIndexerWrites(t *testing.T) {
+	ri, writer, now := newTestReplicatedIndex(t, ReplicaConsistencyReadYourWrites)
+
+	require.NoError(t, writer.setHeartbeat(*now))
+	ri.beat()
+	assert.Equal(t, "replica", ri.Stats().Serving)
+
+	// the indexer writes to the primary directly
+	*now = now.Add(time.Second)
+	backend := &listedBackend{objects: []*BlobInfo{{Key: "alice@example.com/a.txt", ETag: "primary", LastModified: now.Format(time.RFC3339)}}}
+	indexer := newBlobIndexer(backend, ri.primary, ri.written)
+	require.NoError(t, indexer.buildIndex(context.Background()))
+
+	blob, ok := ri.Get("alice@example.com/a.txt")
+	require.True(t, ok)
+	assert.Equal(t, "primary", blob.ETag)
+
+	// nothing changed, the replica serves the reads again once it caught up
+	*now = now.Add(time.Second)
+	require.NoError(t, writer.setHeartbeat(*now))
+	ri.beat()
+	require.NoError(t, indexer.buildIndex(context.Background()))
+	assert.Equal(t, "replica", ri.Stats().Serving)
+}
+
+func TestBlobServiceReadIndex(t *testing.T) {
+	ri, _, _ := newTestReplicatedIndex(t, ReplicaConsistencyBounded)
+	svc := &BlobService{index: ri.primary, replica: ri}
+
+	// the reads that decide on a write never see a stale replica
+	assert.Same(t, ri.primary, svc.Index())
+	assert.Same(t, ri, svc.ReadIndex())
+
+	svc = &BlobService{index: ri.primary}
+	assert.Same(t, ri.primary, svc.ReadIndex())
+}
+
+func TestS3ConfigValidateIndexReplica(t *testing.T) {
+	cfg := &S3Config{Backend: BackendFilesystem, Dir: t.TempDir(), PublicURL: "http://localhost:8080", IndexReplicaPath: "replica.db"}
+	assert.ErrorContains(t, cfg.Validate(), "index_replica_max_lag")
+
+	cfg.IndexReplicaMaxLag = time.Second
+	assert.NoError(t, cfg.Validate())
+
+	cfg.IndexReplicaConsistency = "strong"
+	assert.ErrorContains(t, cfg.Validate(), "unknown index_replica_consistency")
+}
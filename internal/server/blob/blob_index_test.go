@@ -75,3 +75,269 @@ func TestBlobIndexBurstInsert(t *testing.T) {
 		return true
 	})
 }
+
+func TestBlobIndexBulkUpdateKeepsCompressedBlobs(t *testing.T) {
+	db, err := db.NewSqliteDB(db.WithPath(filepath.Join(t.TempDir(), "test.db")), db.WithMaxOpenConns(1))
+	require.NoError(t, err)
+	defer db.Close()
+
+	index, err := newBlobIndex(db)
+	require.NoError(t, err)
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	require.NoError(t, index.SetMany([]*BlobInfo{
+		{Key: "a@example.com/compressed.csv", ETag: "content-etag", Size: 1000, LastModified: now, Codec: "gzip", StorageETag: "storage-etag"},
+		{Key: "a@example.com/overwritten.csv", ETag: "content-etag", Size: 1000, LastModified: now, Codec: "gzip", StorageETag: "storage-etag"},
+	}))
+
+	// listings only know about the stored objects
+	later := time.Now().UTC().Add(time.Minute).Format(time.RFC3339)
+	result, err := index.bulkUpdate([]*BlobInfo{
+		{Key: "a@example.com/compressed.csv", ETag: "storage-etag", Size: 100, LastModified: later},
+		{Key: "a@example.com/overwritten.csv", ETag: "new-etag", Size: 1200, LastModified: later},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Updated)
+
+	compressed, ok := index.Get("a@example.com/compressed.csv")
+	require.True(t, ok)
+	assert.Equal(t, "content-etag", compressed.ETag)
+	assert.Equal(t, int64(1000), compressed.Size)
+	assert.Equal(t, "gzip", compressed.Codec)
+
+	overwritten, ok := index.Get("a@example.com/overwritten.csv")
+	require.True(t, ok)
+	assert.Equal(t, "new-etag", overwritten.ETag)
+	assert.Equal(t, int64(1200), overwritten.Size)
+	assert.Empty(t, overwritten.Codec)
+	assert.Empty(t, overwritten.StorageETag)
+}
+
+func TestBlobIndexMigratesOldSchema(t *testing.T) {
+	db, err := db.NewSqliteDB(db.WithPath(filepath.Join(t.TempDir(), "test.db")), db.WithMaxOpenConns(1))
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE blobs (key TEXT PRIMARY KEY, etag TEXT NOT NULL, size INTEGER NOT NULL, last_modified TEXT NOT NULL)`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO blobs VALUES ('a@example.com/old.txt', 'etag', 10, '2025-01-01T00:00:00Z')`)
+	require.NoError(t, err)
+
+	index, err := newBlobIndex(db)
+	require.NoError(t, err)
+
+	// migrating again is a no-op
+	_, err = newBlobIndex(db)
+	require.NoError(t, err)
+
+	old, ok := index.Get("a@example.com/old.txt")
+	require.True(t, ok)
+	assert.Equal(t, "etag", old.ETag)
+	assert.Empty(t, old.Codec)
+	assert.Zero(t, old.Revision)
+	assert.Equal(t, int64(10), index.Usage("a@example.com"), "the usage of existing blobs is computed once")
+
+	// the first write after the migration starts the history, even with the same content
+	require.NoError(t, index.Set(old))
+	assert.Equal(t, int64(1), old.Revision)
+}
+
+func TestBlobIndexUsage(t *testing.T) {
+	db, err := db.NewSqliteDB(db.WithPath(filepath.Join(t.TempDir(), "test.db")), db.WithMaxOpenConns(1))
+	require.NoError(t, err)
+	defer db.Close()
+
+	index, err := newBlobIndex(db)
+	require.NoError(t, err)
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	require.NoError(t, index.SetMany([]*BlobInfo{
+		{Key: "a@example.com/one.txt", ETag: "1", Size: 100, LastModified: now},
+		{Key: "a@example.com/dir/two.txt", ETag: "2", Size: 200, LastModified: now},
+		{Key: "b@example.com/one.txt", ETag: "3", Size: 50, LastModified: now},
+	}))
+	assert.Equal(t, int64(300), index.Usage("a@example.com"))
+	assert.Equal(t, int64(50), index.Usage("b@example.com"))
+	assert.Zero(t, index.Usage("c@example.com"))
+
+	// overwrites count the new size only
+	require.NoError(t, index.Set(&BlobInfo{Key: "a@example.com/one.txt", ETag: "4", Size: 10, LastModified: now}))
+	assert.Equal(t, int64(210), index.Usage("a@example.com"))
+
+	require.NoError(t, index.Remove("a@example.com/dir/two.txt"))
+	assert.Equal(t, int64(10), index.Usage("a@example.com"))
+
+	// so do the changes picked up by the indexer
+	_, err = index.bulkUpdate([]*BlobInfo{
+		{Key: "a@example.com/one.txt", ETag: "4", Size: 10, LastModified: now},
+		{Key: "a@example.com/three.txt", ETag: "5", Size: 5, LastModified: now},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(15), index.Usage("a@example.com"))
+	assert.Zero(t, index.Usage("b@example.com"))
+}
+
+func TestBlobIndexUsageSnapshots(t *testing.T) {
+	db, err := db.NewSqliteDB(db.WithPath(filepath.Join(t.TempDir(), "test.db")), db.WithMaxOpenConns(1))
+	require.NoError(t, err)
+	defer db.Close()
+
+	index, err := newBlobIndex(db)
+	require.NoError(t, err)
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	require.NoError(t, index.SetMany([]*BlobInfo{
+		{Key: "a@example.com/one.txt", ETag: "1", Size: 100, LastModified: now},
+		{Key: SnapshotsPrefix + "a@example.com/objects/1", ETag: "1", Size: 100, LastModified: now},
+		{Key: SnapshotsPrefix + "a@example.com/manifests/daily.json", ETag: "2", Size: 20, LastModified: now},
+	}))
+	// the snapshots count towards the datasite they were taken of
+	assert.Equal(t, int64(220), index.Usage("a@example.com"))
+	assert.Zero(t, index.Usage("_snapshots"))
+
+	// the usage of indexes that accounted the snapshots on their own is computed again
+	_, err = db.Exec(`UPDATE datasite_usage SET size = 100 WHERE datasite = 'a@example.com'`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO datasite_usage (datasite, size) VALUES ('_snapshots', 120)`)
+	require.NoError(t, err)
+	index, err = newBlobIndex(db)
+	require.NoError(t, err)
+	assert.Equal(t, int64(220), index.Usage("a@example.com"))
+	assert.Zero(t, index.Usage("_snapshots"))
+}
+
+func TestBlobIndexConcurrentPutsRevisionOrder(t *testing.T) {
+	db, err := db.NewSqliteDB(db.WithPath(filepath.Join(t.TempDir(), "test.db")), db.WithMaxOpenConns(1))
+	require.NoError(t, err)
+	defer db.Close()
+
+	index, err := newBlobIndex(db)
+	require.NoError(t, err)
+
+	const key = "a@example.com/shared.txt"
+	const numWriters = 50
+
+	// every writer uploads different content to the same key
+	revisions := make([]int64, numWriters)
+	var wg sync.WaitGroup
+	wg.Add(numWriters)
+	for i := range numWriters {
+		go func() {
+			defer wg.Done()
+			blob := &BlobInfo{
+				Key:          key,
+				ETag:         utils.TokenHex(16),
+				Size:         int64(i + 1),
+				LastModified: time.Now().UTC().Format(time.RFC3339),
+			}
+			assert.NoError(t, index.Set(blob))
+			revisions[i] = blob.Revision
+		}()
+	}
+	wg.Wait()
+
+	// each write got its own revision, with no gaps
+	seen := make(map[int64]bool, numWriters)
+	for _, rev := range revisions {
+		assert.False(t, seen[rev], "revision %d assigned twice", rev)
+		seen[rev] = true
+	}
+
+	history, err := index.Revisions(key)
+	require.NoError(t, err)
+	require.Len(t, history, numWriters)
+	for i, rev := range history {
+		assert.Equal(t, int64(i+1), rev.Revision)
+		assert.True(t, seen[rev.Revision])
+	}
+
+	// the indexed blob is the last revision
+	blob, ok := index.Get(key)
+	require.True(t, ok)
+	last := history[len(history)-1]
+	assert.Equal(t, last.Revision, blob.Revision)
+	assert.Equal(t, last.ETag, blob.ETag)
+	assert.Equal(t, last.Size, blob.Size)
+}
+
+func TestBlobIndexRevisionHistory(t *testing.T) {
+	db, err := db.NewSqliteDB(db.WithPath(filepath.Join(t.TempDir(), "test.db")), db.WithMaxOpenConns(1))
+	require.NoError(t, err)
+	defer db.Close()
+
+	index, err := newBlobIndex(db)
+	require.NoError(t, err)
+
+	const key = "a@example.com/file.txt"
+	now := time.Now().UTC().Format(time.RFC3339)
+
+	require.NoError(t, index.Set(&BlobInfo{Key: key, ETag: "v1", Size: 1, LastModified: now}))
+
+	// same content is the same revision
+	blob := &BlobInfo{Key: key, ETag: "v1", Size: 1, LastModified: now}
+	require.NoError(t, index.Set(blob))
+	assert.Equal(t, int64(1), blob.Revision)
+
+	// revisions continue after a delete
+	require.NoError(t, index.Remove(key))
+	blob = &BlobInfo{Key: key, ETag: "v2", Size: 2, LastModified: now}
+	require.NoError(t, index.Set(blob))
+	assert.Equal(t, int64(3), blob.Revision)
+
+	// changes found by the indexer are revisions too
+	_, err = index.bulkUpdate([]*BlobInfo{{Key: key, ETag: "v3", Size: 3, LastModified: now}})
+	require.NoError(t, err)
+
+	history, err := index.Revisions(key)
+	require.NoError(t, err)
+	require.Len(t, history, 4)
+	assert.Equal(t, []string{"v1", "", "v2", "v3"}, []string{history[0].ETag, history[1].ETag, history[2].ETag, history[3].ETag})
+	assert.True(t, history[1].Deleted)
+
+	got, ok := index.Get(key)
+	require.True(t, ok)
+	assert.Equal(t, int64(4), got.Revision)
+}
+
+func TestBlobIndexPruneRevisions(t *testing.T) {
+	db, err := db.NewSqliteDB(db.WithPath(filepath.Join(t.TempDir(), "test.db")), db.WithMaxOpenConns(1))
+	require.NoError(t, err)
+	defer db.Close()
+
+	index, err := newBlobIndex(db)
+	require.NoError(t, err)
+	index.revisionsKept = 2
+	index.revisionMaxAge = time.Hour
+
+	now := time.Now()
+	for _, etag := range []string{"v1", "v2", "v3", "v4"} {
+		require.NoError(t, index.Set(&BlobInfo{Key: "a@example.com/a.txt", ETag: etag, LastModified: now.Format(time.RFC3339)}))
+	}
+	require.NoError(t, index.Set(&BlobInfo{Key: "a@example.com/b.txt", ETag: "v1", LastModified: now.Format(time.RFC3339)}))
+	require.NoError(t, index.Remove("a@example.com/b.txt"))
+
+	// the last revisions are kept
+	pruned, err := index.pruneRevisions(now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), pruned)
+	history, err := index.Revisions("a@example.com/a.txt")
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, "v3", history[0].ETag)
+
+	// past the max age only the last revision of the keys that exist is kept
+	_, err = index.pruneRevisions(now.Add(2 * time.Hour))
+	require.NoError(t, err)
+	history, err = index.Revisions("a@example.com/a.txt")
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, int64(4), history[0].Revision)
+	history, err = index.Revisions("a@example.com/b.txt")
+	require.NoError(t, err)
+	assert.Empty(t, history)
+
+	// revisions keep counting on from the last one kept
+	blob := &BlobInfo{Key: "a@example.com/a.txt", ETag: "v5", LastModified: now.Format(time.RFC3339)}
+	require.NoError(t, index.Set(blob))
+	assert.Equal(t, int64(5), blob.Revision)
+}
@@ -23,6 +23,12 @@ type IBlobIndex interface {
 	// Remove deletes a blob from the index by its key
 	Remove(key string) error
 
+	// Usage returns the total size in bytes of the blobs stored in a datasite
+	Usage(datasite string) int64
+
+	// Revisions returns the version history of a key, oldest first
+	Revisions(key string) ([]*BlobRevision, error)
+
 	// List returns all blobs in the index as a slice
 	List() ([]*BlobInfo, error)
 
@@ -56,6 +62,17 @@ type TimeFilter struct {
 	After  *time.Time
 }
 
+// BlobRevision is an entry in the version history of a key.
+// Revisions of a key follow the order the backend stored the writes in, see BlobService.superseded
+type BlobRevision struct {
+	Key       string `json:"key" db:"key"`
+	Revision  int64  `json:"revision" db:"revision"`
+	ETag      string `json:"etag" db:"etag"`
+	Size      int64  `json:"size" db:"size"`
+	Deleted   bool   `json:"deleted" db:"deleted"`
+	CreatedAt string `json:"createdAt" db:"created_at"`
+}
+
 // bulkUpdateResult contains statistics about a bulk update operation
 type bulkUpdateResult struct {
 	Added   int
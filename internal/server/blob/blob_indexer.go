@@ -11,15 +11,17 @@ const indexInterval = 15 * time.Minute
 
 // blobIndexer handles the periodic updating of the blob index
 type blobIndexer struct {
-	backend *S3Backend
+	backend IBlobBackend
 	index   *BlobIndex
+	written func() // called once changes were written to the index
 }
 
 // newBlobIndexer creates a new indexer that updates the provided index
-func newBlobIndexer(backend *S3Backend, index *BlobIndex) *blobIndexer {
+func newBlobIndexer(backend IBlobBackend, index *BlobIndex, written func()) *blobIndexer {
 	return &blobIndexer{
 		backend: backend,
 		index:   index,
+		written: written,
 	}
 }
 
@@ -67,6 +69,14 @@ func (bi *blobIndexer) buildIndex(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("failed to update index: %w", err)
 	}
+	if result.Added+result.Updated+result.Deleted > 0 {
+		bi.written()
+	}
+
+	pruned, err := bi.index.pruneRevisions(time.Now())
+	if err != nil {
+		return err
+	}
 
 	// Log statistics
 	slog.Debug("blob indexer update result",
@@ -74,6 +84,7 @@ func (bi *blobIndexer) buildIndex(ctx context.Context) error {
 		"added", result.Added,
 		"updated", result.Updated,
 		"deleted", result.Deleted,
+		"prunedRevisions", pruned,
 		"took", time.Since(start),
 	)
 
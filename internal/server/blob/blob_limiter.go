@@ -0,0 +1,203 @@
+package blob
+
+import (
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/openmined/syftbox/internal/server/busy"
+)
+
+// ErrBackendBusy is returned when a blob operation waited longer than the queue timeout for a slot
+var ErrBackendBusy = busy.ErrBlobStorage
+
+// opLimiter caps the number of concurrent operations of one kind. Operations over the limit
+// queue until a slot frees up, the queue timeout passes or their context is done.
+type opLimiter struct {
+	slots   chan struct{}
+	timeout time.Duration
+	queued  atomic.Int64
+}
+
+// newOpLimiter returns nil for an unlimited operation, nil limiters never block
+func newOpLimiter(limit int, timeout time.Duration) *opLimiter {
+	if limit <= 0 {
+		return nil
+	}
+	return &opLimiter{
+		slots:   make(chan struct{}, limit),
+		timeout: timeout,
+	}
+}
+
+func (l *opLimiter) acquire(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	// fast path, no timer when a slot is free
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	l.queued.Add(1)
+	defer l.queued.Add(-1)
+
+	var timeout <-chan time.Time
+	if l.timeout > 0 {
+		timer := time.NewTimer(l.timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	case <-timeout:
+		return ErrBackendBusy
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l *opLimiter) release() {
+	if l != nil {
+		<-l.slots
+	}
+}
+
+func (l *opLimiter) stats() *OpLimitStats {
+	if l == nil {
+		return &OpLimitStats{}
+	}
+	return &OpLimitStats{
+		Limit:    cap(l.slots),
+		InFlight: len(l.slots),
+		Queued:   int(l.queued.Load()),
+	}
+}
+
+// OpLimitStats is the state of the limiter of one kind of blob operation. A zero limit is unlimited.
+type OpLimitStats struct {
+	Limit    int `json:"limit"`
+	InFlight int `json:"inFlight"`
+	Queued   int `json:"queued"`
+}
+
+// BlobLimitStats is the state of the blob operation limiters
+type BlobLimitStats struct {
+	Reads  *OpLimitStats `json:"reads"`
+	Writes *OpLimitStats `json:"writes"`
+}
+
+// limitedBackend caps the concurrent reads and writes to the backend, so that bursts of requests
+// are queued and eventually rejected with ErrBackendBusy instead of overloading the storage.
+// Presigning counts as the kind of operation the url is for. Ping is never limited.
+type limitedBackend struct {
+	IBlobBackend
+	reads  *opLimiter
+	writes *opLimiter
+}
+
+func newLimitedBackend(backend IBlobBackend, cfg *S3Config) *limitedBackend {
+	return &limitedBackend{
+		IBlobBackend: backend,
+		reads:        newOpLimiter(cfg.MaxConcurrentReads, cfg.QueueTimeout),
+		writes:       newOpLimiter(cfg.MaxConcurrentWrites, cfg.QueueTimeout),
+	}
+}
+
+func (b *limitedBackend) Stats() *BlobLimitStats {
+	return &BlobLimitStats{
+		Reads:  b.reads.stats(),
+		Writes: b.writes.stats(),
+	}
+}
+
+// GetObject holds its slot until the body is closed, as the object is streamed from the storage
+func (b *limitedBackend) GetObject(ctx context.Context, key string) (*GetObjectResponse, error) {
+	if err := b.reads.acquire(ctx); err != nil {
+		return nil, err
+	}
+	resp, err := b.IBlobBackend.GetObject(ctx, key)
+	if err != nil {
+		b.reads.release()
+		return nil, err
+	}
+	resp.Body = &releaseOnClose{ReadCloser: resp.Body, release: b.reads.release}
+	return resp, nil
+}
+
+func (b *limitedBackend) GetObjectPresigned(ctx context.Context, key string) (string, error) {
+	return withLimit(ctx, b.reads, func() (string, error) {
+		return b.IBlobBackend.GetObjectPresigned(ctx, key)
+	})
+}
+
+func (b *limitedBackend) ListObjects(ctx context.Context) ([]*BlobInfo, error) {
+	return withLimit(ctx, b.reads, func() ([]*BlobInfo, error) {
+		return b.IBlobBackend.ListObjects(ctx)
+	})
+}
+
+func (b *limitedBackend) PutObject(ctx context.Context, params *PutObjectParams) (*PutObjectResponse, error) {
+	return withLimit(ctx, b.writes, func() (*PutObjectResponse, error) {
+		return b.IBlobBackend.PutObject(ctx, params)
+	})
+}
+
+func (b *limitedBackend) PutObjectPresigned(ctx context.Context, key string, size int64) (string, error) {
+	return withLimit(ctx, b.writes, func() (string, error) {
+		return b.IBlobBackend.PutObjectPresigned(ctx, key, size)
+	})
+}
+
+func (b *limitedBackend) PutObjectMultipart(ctx context.Context, params *PutObjectMultipartParams) (*PutObjectMultipartResponse, error) {
+	return withLimit(ctx, b.writes, func() (*PutObjectMultipartResponse, error) {
+		return b.IBlobBackend.PutObjectMultipart(ctx, params)
+	})
+}
+
+func (b *limitedBackend) CompleteMultipartUpload(ctx context.Context, params *CompleteMultipartUploadParams) (*PutObjectResponse, error) {
+	return withLimit(ctx, b.writes, func() (*PutObjectResponse, error) {
+		return b.IBlobBackend.CompleteMultipartUpload(ctx, params)
+	})
+}
+
+func (b *limitedBackend) CopyObject(ctx context.Context, params *CopyObjectParams) (*CopyObjectResponse, error) {
+	return withLimit(ctx, b.writes, func() (*CopyObjectResponse, error) {
+		return b.IBlobBackend.CopyObject(ctx, params)
+	})
+}
+
+func (b *limitedBackend) DeleteObject(ctx context.Context, key string) (bool, error) {
+	return withLimit(ctx, b.writes, func() (bool, error) {
+		return b.IBlobBackend.DeleteObject(ctx, key)
+	})
+}
+
+func withLimit[T any](ctx context.Context, l *opLimiter, op func() (T, error)) (T, error) {
+	if err := l.acquire(ctx); err != nil {
+		var zero T
+		return zero, err
+	}
+	defer l.release()
+	return op()
+}
+
+// releaseOnClose releases a limiter slot once, when the body is closed
+type releaseOnClose struct {
+	io.ReadCloser
+	release func()
+	once    sync.Once
+}
+
+func (r *releaseOnClose) Close() error {
+	err := r.ReadCloser.Close()
+	r.once.Do(r.release)
+	return err
+}
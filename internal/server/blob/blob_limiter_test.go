@@ -0,0 +1,131 @@
+package blob
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingBackend blocks every operation until unblocked, and records the peak concurrency
+type blockingBackend struct {
+	IBlobBackend
+	unblock  chan struct{}
+	inFlight atomic.Int32
+	peak     atomic.Int32
+}
+
+func newBlockingBackend() *blockingBackend {
+	return &blockingBackend{unblock: make(chan struct{})}
+}
+
+func (b *blockingBackend) op() {
+	n := b.inFlight.Add(1)
+	for {
+		peak := b.peak.Load()
+		if n <= peak || b.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	<-b.unblock
+	b.inFlight.Add(-1)
+}
+
+func (b *blockingBackend) PutObject(ctx context.Context, params *PutObjectParams) (*PutObjectResponse, error) {
+	b.op()
+	return &PutObjectResponse{Key: params.Key}, nil
+}
+
+func (b *blockingBackend) GetObjectPresigned(ctx context.Context, key string) (string, error) {
+	b.op()
+	return "https://example.com/" + key, nil
+}
+
+func (b *blockingBackend) GetObject(ctx context.Context, key string) (*GetObjectResponse, error) {
+	return &GetObjectResponse{Body: io.NopCloser(strings.NewReader("hello"))}, nil
+}
+
+func TestLimitedBackendCapsConcurrency(t *testing.T) {
+	raw := newBlockingBackend()
+	backend := newLimitedBackend(raw, &S3Config{MaxConcurrentWrites: 2, MaxConcurrentReads: 4})
+
+	var wg sync.WaitGroup
+	for i := range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := backend.PutObject(context.Background(), &PutObjectParams{Key: fmt.Sprintf("alice@example.com/file%d", i)})
+			assert.NoError(t, err)
+		}()
+	}
+
+	// the writes over the limit queue, the reads aren't affected by them
+	require.Eventually(t, func() bool {
+		return backend.Stats().Writes.Queued == 3
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, &OpLimitStats{Limit: 2, InFlight: 2, Queued: 3}, backend.Stats().Writes)
+	assert.Equal(t, &OpLimitStats{Limit: 4}, backend.Stats().Reads)
+
+	close(raw.unblock)
+	wg.Wait()
+
+	assert.EqualValues(t, 2, raw.peak.Load())
+	assert.Equal(t, &OpLimitStats{Limit: 2}, backend.Stats().Writes)
+}
+
+func TestLimitedBackendQueueTimeout(t *testing.T) {
+	raw := newBlockingBackend()
+	defer close(raw.unblock)
+	backend := newLimitedBackend(raw, &S3Config{MaxConcurrentReads: 1, QueueTimeout: 20 * time.Millisecond})
+
+	go backend.GetObjectPresigned(context.Background(), "alice@example.com/a")
+	require.Eventually(t, func() bool {
+		return backend.Stats().Reads.InFlight == 1
+	}, time.Second, time.Millisecond)
+
+	_, err := backend.GetObjectPresigned(context.Background(), "alice@example.com/b")
+	assert.ErrorIs(t, err, ErrBackendBusy)
+
+	// a request that goes away stops queueing with it
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = backend.GetObjectPresigned(ctx, "alice@example.com/c")
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Zero(t, backend.Stats().Reads.Queued)
+}
+
+func TestLimitedBackendGetObjectHoldsSlot(t *testing.T) {
+	backend := newLimitedBackend(newBlockingBackend(), &S3Config{MaxConcurrentReads: 1, QueueTimeout: 10 * time.Millisecond})
+
+	resp, err := backend.GetObject(context.Background(), "alice@example.com/a")
+	require.NoError(t, err)
+
+	// the body is still streaming, the slot is taken
+	_, err = backend.GetObject(context.Background(), "alice@example.com/b")
+	assert.ErrorIs(t, err, ErrBackendBusy)
+
+	require.NoError(t, resp.Body.Close())
+	require.NoError(t, resp.Body.Close(), "closing twice releases once")
+	assert.Zero(t, backend.Stats().Reads.InFlight)
+
+	resp, err = backend.GetObject(context.Background(), "alice@example.com/b")
+	require.NoError(t, err)
+	resp.Body.Close()
+}
+
+func TestLimitedBackendUnlimited(t *testing.T) {
+	raw := newBlockingBackend()
+	close(raw.unblock)
+	backend := newLimitedBackend(raw, &S3Config{})
+
+	_, err := backend.PutObject(context.Background(), &PutObjectParams{Key: "alice@example.com/a"})
+	require.NoError(t, err)
+	assert.Equal(t, &BlobLimitStats{Reads: &OpLimitStats{}, Writes: &OpLimitStats{}}, backend.Stats())
+}
@@ -0,0 +1,161 @@
+package blob
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// prune the expired urls at most this often
+const presignsPruneInterval = time.Minute
+
+// PresignedURL is a presigned url issued by the server. The url isn't kept, a digest of its signature identifies it
+type PresignedURL struct {
+	ID        string    `json:"id"`
+	Key       string    `json:"key"`
+	User      string    `json:"user"`
+	Method    string    `json:"method"`
+	IssuedAt  time.Time `json:"issuedAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	Revoked   bool      `json:"revoked"`
+}
+
+// Presigns keeps the presigned urls issued by the server until they expire, for auditing and revocation.
+// Revoked urls are rejected by the presigned urls the server serves itself (see BlobService.PresignHandler),
+// the others can only be revoked by rotating the credentials of the backend.
+type Presigns struct {
+	mu       sync.Mutex
+	issued   map[string]*PresignedURL
+	prunedAt time.Time
+	now      func() time.Time
+}
+
+func NewPresigns() *Presigns {
+	return &Presigns{
+		issued: make(map[string]*PresignedURL),
+		now:    time.Now,
+	}
+}
+
+// Record records a url presigned for user, to download (GET) or upload (PUT) a key. A url already issued is kept as-is
+func (p *Presigns) Record(rawURL string, key string, user string, method string) {
+	id := presignID(presignSignature(rawURL))
+	expiry := DownloadURLExpiry
+	if method == http.MethodPut {
+		expiry = uploadExpiry
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	p.prune(now)
+	if _, ok := p.issued[id]; ok {
+		return
+	}
+	p.issued[id] = &PresignedURL{
+		ID:        id,
+		Key:       key,
+		User:      user,
+		Method:    method,
+		IssuedAt:  now,
+		ExpiresAt: now.Add(expiry),
+	}
+}
+
+// List returns the unexpired urls of the blobs of a datasite, oldest first
+func (p *Presigns) List(datasite string) []*PresignedURL {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	p.prune(now)
+	urls := make([]*PresignedURL, 0)
+	for _, issued := range p.issued {
+		if presignDatasite(issued.Key) == datasite && !now.After(issued.ExpiresAt) {
+			copied := *issued
+			urls = append(urls, &copied)
+		}
+	}
+	slices.SortFunc(urls, func(a, b *PresignedURL) int {
+		return a.IssuedAt.Compare(b.IssuedAt)
+	})
+	return urls
+}
+
+// Revoke revokes the urls of a datasite with the given ids, or all of them when there are none.
+// Ids of other datasites, or of expired urls, are ignored. It returns the revoked urls
+func (p *Presigns) Revoke(datasite string, ids []string) []*PresignedURL {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	p.prune(now)
+	revoked := make([]*PresignedURL, 0)
+	for id, issued := range p.issued {
+		if presignDatasite(issued.Key) != datasite || now.After(issued.ExpiresAt) || (len(ids) > 0 && !slices.Contains(ids, id)) {
+			continue
+		}
+		issued.Revoked = true
+		copied := *issued
+		revoked = append(revoked, &copied)
+	}
+	return revoked
+}
+
+// IsRevoked checks if a presigned url was revoked
+func (p *Presigns) IsRevoked(rawURL string) bool {
+	return p.isRevoked(presignSignature(rawURL))
+}
+
+func (p *Presigns) isRevoked(signature string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	issued, ok := p.issued[presignID(signature)]
+	return ok && issued.Revoked
+}
+
+// prune drops the expired urls, they aren't accepted anymore. Must hold the lock
+func (p *Presigns) prune(now time.Time) {
+	if now.Sub(p.prunedAt) < presignsPruneInterval {
+		return
+	}
+	p.prunedAt = now
+	for id, issued := range p.issued {
+		if now.After(issued.ExpiresAt) {
+			delete(p.issued, id)
+		}
+	}
+}
+
+// presignSignature returns the signature of a presigned url of the filesystem or the s3 backend
+func presignSignature(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	query := u.Query()
+	if signature := query.Get("signature"); signature != "" {
+		return signature
+	}
+	if signature := query.Get("X-Amz-Signature"); signature != "" {
+		return signature
+	}
+	return rawURL
+}
+
+func presignID(signature string) string {
+	sum := sha256.Sum256([]byte(signature))
+	return hex.EncodeToString(sum[:8])
+}
+
+func presignDatasite(key string) string {
+	datasite, _, _ := strings.Cut(key, "/")
+	return datasite
+}
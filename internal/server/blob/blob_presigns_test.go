@@ -0,0 +1,37 @@
+package blob
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPresignsExpire(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	presigns := NewPresigns()
+	presigns.now = func() time.Time { return now }
+
+	download := "http://localhost:8080/blobs/alice@example.com/a.txt?expires=1&signature=aaa"
+	upload := "https://bucket.s3.amazonaws.com/alice@example.com/b.txt?X-Amz-Expires=300&X-Amz-Signature=bbb"
+	presigns.Record(download, "alice@example.com/a.txt", "bob@example.com", http.MethodGet)
+	presigns.Record(download, "alice@example.com/a.txt", "bob@example.com", http.MethodGet) // served again from the cache
+	presigns.Record(upload, "alice@example.com/b.txt", "alice@example.com", http.MethodPut)
+
+	urls := presigns.List("alice@example.com")
+	require.Len(t, urls, 2)
+	assert.Empty(t, presigns.List("bob@example.com"), "listed by the datasite of the blobs")
+
+	require.Len(t, presigns.Revoke("alice@example.com", nil), 2)
+	assert.True(t, presigns.IsRevoked(download))
+	assert.True(t, presigns.isRevoked("bbb"))
+
+	// expired urls are dropped, they are rejected anyway
+	now = now.Add(DownloadURLExpiry + presignsPruneInterval)
+	assert.Empty(t, presigns.List("alice@example.com"))
+	assert.Empty(t, presigns.Revoke("alice@example.com", nil))
+	assert.False(t, presigns.IsRevoked(download))
+	assert.Empty(t, presigns.issued)
+}
@@ -16,6 +16,9 @@ type Service interface {
 	// Index returns the blob index for metadata management
 	Index() IBlobIndex
 
+	// ReadIndex returns the index for reads that may be slightly stale, like views and listings
+	ReadIndex() IBlobIndex
+
 	// OnBlobChange registers a callback for blob change events
 	OnBlobChange(callback BlobChangeCallback)
 }
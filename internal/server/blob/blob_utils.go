@@ -1,8 +1,13 @@
 package blob
 
 import (
+	"fmt"
 	"regexp"
+	"strings"
+	"unicode"
 	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
 )
 
 // Match: starts with one or more / OR contains \ OR contains ..
@@ -11,7 +16,7 @@ var regexForbiddenPatterns = regexp.MustCompile(`^/+|\\+|\.\.`)
 // Validate a key for S3 and local file system compatibility
 func ValidateKey(key string) bool {
 	// S3 keys must be between 1 and 1024 bytes long
-	if len(key) == 0 || len(key) > 1024 {
+	if len(key) == 0 || len(key) > MaxKeyLength {
 		return false
 	} else if key == "." || key == ".." {
 		return false
@@ -25,3 +30,60 @@ func ValidateKey(key string) bool {
 	// S3 keys must be valid UTF-8 strings
 	return utf8.ValidString(key)
 }
+
+// MaxKeyLength is the longest object key S3 accepts, in bytes
+const MaxKeyLength = 1024
+
+// ErrKeyNotNFC rejects a key that isn't in Unicode NFC. It is not converted, as the client would then
+// see its own file come back under another name
+var ErrKeyNotNFC = fmt.Errorf("%w: not in Unicode NFC", ErrInvalidKey)
+
+// KeyRules validates the object keys sent by clients, so that the same file name
+// maps to the same key whatever platform it was written on. See docs/object-keys.md.
+type KeyRules struct {
+	// MaxLength is the longest accepted key in bytes. 0 is MaxKeyLength.
+	MaxLength int
+}
+
+// Validate returns an error wrapping ErrInvalidKey explaining why a key is rejected.
+// Keys must be in Unicode NFC (ErrKeyNotNFC otherwise), and must not contain control characters, backslashes,
+// ".." or empty path segments. Case, spaces and other characters are kept as they are.
+func (r KeyRules) Validate(key string) error {
+	if key == "" {
+		return fmt.Errorf("%w: empty key", ErrInvalidKey)
+	}
+
+	if !utf8.ValidString(key) {
+		return fmt.Errorf("%w: not valid UTF-8", ErrInvalidKey)
+	}
+
+	if !norm.NFC.IsNormalString(key) {
+		return fmt.Errorf("%w: %q is %q in NFC", ErrKeyNotNFC, key, norm.NFC.String(key))
+	}
+
+	for i, c := range key {
+		if unicode.IsControl(c) {
+			return fmt.Errorf("%w: control character %U at byte %d", ErrInvalidKey, c, i)
+		}
+	}
+
+	if regexForbiddenPatterns.MatchString(key) {
+		return fmt.Errorf("%w: leading slash, backslash or \"..\" not allowed", ErrInvalidKey)
+	}
+
+	for segment := range strings.SplitSeq(key, "/") {
+		if segment == "" || segment == "." {
+			return fmt.Errorf("%w: empty or \".\" path segment", ErrInvalidKey)
+		}
+	}
+
+	maxLength := r.MaxLength
+	if maxLength <= 0 || maxLength > MaxKeyLength {
+		maxLength = MaxKeyLength
+	}
+	if len(key) > maxLength {
+		return fmt.Errorf("%w: %d bytes, max %d", ErrInvalidKey, len(key), maxLength)
+	}
+
+	return nil
+}
@@ -45,3 +45,66 @@ func TestValidateKey(t *testing.T) {
 		assert.Equal(t, test.want, ValidateKey(test.key), test.name)
 	}
 }
+
+func TestKeyRulesValidate(t *testing.T) {
+	rules := KeyRules{}
+
+	tests := []struct {
+		name  string
+		key   string
+		valid bool
+	}{
+		// kept as they are
+		{name: "spaces", key: "alice@example.com/public/my file .txt", valid: true},
+		{name: "emoji", key: "alice@example.com/public/🚀 launch.md", valid: true},
+		{name: "uppercase", key: "alice@example.com/public/README.MD", valid: true},
+		{name: "dots", key: "alice@example.com/public/.hidden.tar.gz.", valid: true},
+		{name: "nfc", key: "alice@example.com/public/caf\u00e9.txt", valid: true},
+		// rejected
+		{name: "empty", key: ""},
+		{name: "invalid-utf8", key: "alice@example.com/public/\xff.txt"},
+		{name: "nfd", key: "alice@example.com/public/cafe\u0301.txt"},
+		{name: "nfd-hangul", key: "alice@example.com/public/\u1112\u1161\u11ab.txt"},
+		{name: "nul", key: "alice@example.com/public/a\x00b.txt"},
+		{name: "newline", key: "alice@example.com/public/a\nb.txt"},
+		{name: "tab", key: "alice@example.com/public/a\tb.txt"},
+		{name: "del", key: "alice@example.com/public/a\x7fb.txt"},
+		{name: "c1-control", key: "alice@example.com/public/a\u0085b.txt"},
+		{name: "leading-slash", key: "/alice@example.com/public/file.txt"},
+		{name: "backslash", key: "alice@example.com\\public\\file.txt"},
+		{name: "dot-dot", key: "alice@example.com/public/../secret.txt"},
+		{name: "empty-segment", key: "alice@example.com//file.txt"},
+		{name: "trailing-slash", key: "alice@example.com/public/"},
+		{name: "dot-segment", key: "alice@example.com/./file.txt"},
+		{name: "too-long", key: "alice@example.com/" + strings.Repeat("a", MaxKeyLength)},
+	}
+
+	for _, test := range tests {
+		err := rules.Validate(test.key)
+		if test.valid {
+			assert.NoError(t, err, test.name)
+		} else {
+			assert.ErrorIs(t, err, ErrInvalidKey, test.name)
+		}
+	}
+}
+
+func TestKeyRulesNotNFC(t *testing.T) {
+	// not converted, the client renames the file to the NFC form
+	err := KeyRules{}.Validate("alice@example.com/public/cafe\u0301.txt")
+	assert.ErrorIs(t, err, ErrKeyNotNFC)
+	assert.Contains(t, err.Error(), "caf\u00e9.txt")
+
+	assert.NotErrorIs(t, KeyRules{}.Validate("alice@example.com/a\nb.txt"), ErrKeyNotNFC)
+}
+
+func TestKeyRulesMaxLength(t *testing.T) {
+	rules := KeyRules{MaxLength: 32}
+
+	assert.NoError(t, rules.Validate("alice@example.com/public/a.txt"))
+	assert.ErrorIs(t, rules.Validate("alice@example.com/public/abcdefgh.txt"), ErrInvalidKey)
+
+	// the length is in bytes, "é" is 2 bytes
+	assert.NoError(t, rules.Validate("alice@example.com/public/\u00e9\u00e9\u00e9"))
+	assert.ErrorIs(t, rules.Validate("alice@example.com/public/\u00e9\u00e9\u00e9\u00e9"), ErrInvalidKey)
+}
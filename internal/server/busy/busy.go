@@ -0,0 +1,9 @@
+// Package busy holds the errors of the services at their concurrency limit.
+// It has no dependencies, so that the services return them and the handlers
+// answer them with a 503 without importing each other.
+package busy
+
+import "errors"
+
+// ErrBlobStorage is returned when a blob operation waited longer than the queue timeout for a slot
+var ErrBlobStorage = errors.New("blob storage busy, retry later")
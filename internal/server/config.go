@@ -3,21 +3,30 @@ package server
 import (
 	"fmt"
 	"log/slog"
+	"net"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/openmined/syftbox/internal/server/auth"
 	"github.com/openmined/syftbox/internal/server/blob"
+	"github.com/openmined/syftbox/internal/server/datasite"
 	"github.com/openmined/syftbox/internal/server/email"
+	"github.com/openmined/syftbox/internal/server/redact"
 	"github.com/openmined/syftbox/internal/utils"
 )
 
 // Config holds the overall server configuration.
 type Config struct {
-	HTTP    HTTPConfig    `mapstructure:"http"`
-	Blob    blob.S3Config `mapstructure:"blob"`
-	Auth    auth.Config   `mapstructure:"auth"`
-	Email   email.Config  `mapstructure:"email"`
-	DataDir string        `mapstructure:"data_dir"`
-	LogDir  string        `mapstructure:"log_dir"`
+	HTTP     HTTPConfig      `mapstructure:"http"`
+	Blob     blob.S3Config   `mapstructure:"blob"`
+	Auth     auth.Config     `mapstructure:"auth"`
+	Email    email.Config    `mapstructure:"email"`
+	Datasite datasite.Config `mapstructure:"datasite"`
+	Redact   redact.Config   `mapstructure:"redact"`
+	DataDir  string          `mapstructure:"data_dir"`
+	LogDir   string          `mapstructure:"log_dir"`
+	LogLevel string          `mapstructure:"log_level"` // debug, info, warn or error
 }
 
 // LogValue for Config
@@ -25,10 +34,13 @@ func (c Config) LogValue() slog.Value {
 	return slog.GroupValue(
 		slog.String("data_dir", c.DataDir),
 		slog.String("log_dir", c.LogDir),
+		slog.String("log_level", c.LogLevel),
 		slog.Any("http", c.HTTP),
 		slog.Any("blob", c.Blob),
 		slog.Any("auth", c.Auth),
 		slog.Any("email", c.Email),
+		slog.Any("datasite", c.Datasite),
+		slog.Any("redact", c.Redact),
 	)
 }
 
@@ -50,10 +62,38 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("invalid log directory: %w", err)
 	}
 
+	if _, err := c.Level(); err != nil {
+		return err
+	}
+
 	if err := c.HTTP.Validate(); err != nil {
 		return fmt.Errorf("invalid http config: %w", err)
 	}
 
+	// the filesystem backend works without any config, keeping the blobs with the rest of the data
+	if c.Blob.Backend == blob.BackendFilesystem {
+		if c.Blob.Dir == "" {
+			c.Blob.Dir = filepath.Join(c.DataDir, "blobs")
+		}
+		c.Blob.Dir, err = utils.ResolvePath(c.Blob.Dir)
+		if err != nil {
+			return fmt.Errorf("invalid blob directory: %w", err)
+		}
+		if c.Blob.PublicURL == "" {
+			c.Blob.PublicURL = c.HTTP.BaseURL()
+		}
+	}
+
+	if c.Blob.IndexReplicaPath != "" {
+		c.Blob.IndexReplicaPath, err = utils.ResolvePath(c.Blob.IndexReplicaPath)
+		if err != nil {
+			return fmt.Errorf("invalid index replica path: %w", err)
+		}
+		if c.Blob.IndexReplicaPath == filepath.Join(c.DataDir, "state.db") {
+			return fmt.Errorf("invalid blob config: index_replica_path is the primary database")
+		}
+	}
+
 	if err := c.Blob.Validate(); err != nil {
 		return fmt.Errorf("invalid blob config: %w", err)
 	}
@@ -66,15 +106,49 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("invalid email config: %w", err)
 	}
 
+	if err := c.Datasite.Validate(); err != nil {
+		return fmt.Errorf("invalid datasite config: %w", err)
+	}
+
+	if err := c.Redact.Validate(); err != nil {
+		return fmt.Errorf("invalid redact config: %w", err)
+	}
+
 	return nil
 }
 
+// Level is the parsed log level. Empty is debug
+func (c *Config) Level() (slog.Level, error) {
+	if c.LogLevel == "" {
+		return slog.LevelDebug, nil
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.LogLevel)); err != nil {
+		return 0, fmt.Errorf("invalid log level %q", c.LogLevel)
+	}
+	return level, nil
+}
+
 // HTTPConfig holds HTTP server specific configuration.
 type HTTPConfig struct {
-	Addr         string `mapstructure:"addr"`
-	CertFilePath string `mapstructure:"cert_file"`
-	KeyFilePath  string `mapstructure:"key_file"`
-	Domain       string `mapstructure:"domain"` // Main domain for subdomain routing (e.g., "syftbox.net")
+	Addr         string   `mapstructure:"addr"`
+	CertFilePath string   `mapstructure:"cert_file"`
+	KeyFilePath  string   `mapstructure:"key_file"`
+	Domain       string   `mapstructure:"domain"`       // Main domain for subdomain routing (e.g., "syftbox.net")
+	LocalHosts   []string `mapstructure:"local_hosts"`  // Hosts, IPs or CIDRs served without subdomain routing
+	StrictHosts  bool     `mapstructure:"strict_hosts"` // Reject malformed Host headers with 400
+
+	// Minimum TLS version of the https listener, e.g. 1.2. Empty is 1.2
+	TLSMinVersion string `mapstructure:"tls_min_version"`
+	// Cipher suites allowed up to TLS 1.2, by IANA name. Empty keeps the go defaults
+	TLSCipherSuites []string `mapstructure:"tls_cipher_suites"`
+
+	// Window in which the websocket notifications are coalesced per recipient. 0 disables it
+	NotifyCoalesceWindow time.Duration `mapstructure:"notify_coalesce_window"`
+
+	// Clients a broadcast sends to at once, the others in batches every broadcast interval. 0 disables it
+	BroadcastMaxFanout int           `mapstructure:"broadcast_max_fanout"`
+	BroadcastInterval  time.Duration `mapstructure:"broadcast_interval"`
 }
 
 // LogValue for HTTPConfig
@@ -84,6 +158,13 @@ func (hc HTTPConfig) LogValue() slog.Value {
 		slog.String("cert_file", hc.CertFilePath),
 		slog.String("key_file", hc.KeyFilePath),
 		slog.String("domain", hc.Domain),
+		slog.Any("local_hosts", hc.LocalHosts),
+		slog.Bool("strict_hosts", hc.StrictHosts),
+		slog.String("tls_min_version", hc.TLSMinVersion),
+		slog.Any("tls_cipher_suites", hc.TLSCipherSuites),
+		slog.Duration("notify_coalesce_window", hc.NotifyCoalesceWindow),
+		slog.Int("broadcast_max_fanout", hc.BroadcastMaxFanout),
+		slog.Duration("broadcast_interval", hc.BroadcastInterval),
 	)
 }
 
@@ -91,6 +172,24 @@ func (c *HTTPConfig) HTTPSEnabled() bool {
 	return c.CertFilePath != "" && c.KeyFilePath != ""
 }
 
+// BaseURL is the url the server listens on, with localhost for all interfaces.
+// It isn't the public url of a server behind a proxy.
+func (c *HTTPConfig) BaseURL() string {
+	scheme := "http"
+	if c.HTTPSEnabled() {
+		scheme = "https"
+	}
+
+	host, port, err := net.SplitHostPort(c.Addr)
+	if err != nil {
+		return scheme + "://" + c.Addr
+	}
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "localhost"
+	}
+	return scheme + "://" + net.JoinHostPort(host, port)
+}
+
 func (c *HTTPConfig) Validate() error {
 	if c.Addr == "" {
 		return fmt.Errorf("http addr required")
@@ -98,5 +197,24 @@ func (c *HTTPConfig) Validate() error {
 	if (c.CertFilePath != "" && c.KeyFilePath == "") || (c.CertFilePath == "" && c.KeyFilePath != "") {
 		return fmt.Errorf("cert_file and key_file paths are required together")
 	}
+	if _, err := c.TLSConfig(); err != nil {
+		return err
+	}
+	if c.NotifyCoalesceWindow < 0 {
+		return fmt.Errorf("notify_coalesce_window must not be negative")
+	}
+	if c.BroadcastMaxFanout < 0 {
+		return fmt.Errorf("broadcast_max_fanout must not be negative")
+	}
+	if c.BroadcastMaxFanout > 0 && c.BroadcastInterval <= 0 {
+		return fmt.Errorf("broadcast_interval must be positive with broadcast_max_fanout")
+	}
+	for _, host := range c.LocalHosts {
+		if strings.Contains(host, "/") {
+			if _, _, err := net.ParseCIDR(host); err != nil {
+				return fmt.Errorf("invalid local_hosts cidr %q: %w", host, err)
+			}
+		}
+	}
 	return nil
 }
@@ -7,10 +7,12 @@ import (
 	"log/slog"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/openmined/syftbox/internal/aclspec"
 	"github.com/openmined/syftbox/internal/server/acl"
 	"github.com/openmined/syftbox/internal/server/blob"
+	"github.com/openmined/syftbox/internal/utils"
 )
 
 var (
@@ -20,19 +22,39 @@ var (
 type DatasiteService struct {
 	blob             blob.Service
 	acl              *acl.ACLService
+	config           *Config
 	subdomainMapping *SubdomainMapping
 	domain           string // Main domain for generating hash subdomains
+	datasites        map[string]struct{}
+	pendingACLs      map[string]*pendingDatasite // new datasites that don't have their default ACLs yet
+	datasitesMu      sync.Mutex
+	reserved         map[string]int64 // bytes admitted by AdmitSize for the writes in progress, per datasite
+	quotaMu          sync.Mutex
+	normalizeEmail   func(email string) string // canonical form of the emails new datasites are named after
 }
 
-func NewDatasiteService(blobSvc blob.Service, aclSvc *acl.ACLService, domain string) *DatasiteService {
+func NewDatasiteService(blobSvc blob.Service, aclSvc *acl.ACLService, domain string, config *Config) *DatasiteService {
 	return &DatasiteService{
 		blob:             blobSvc,
 		acl:              aclSvc,
+		config:           config,
 		subdomainMapping: NewSubdomainMapping(),
 		domain:           domain,
+		datasites:        make(map[string]struct{}),
+		pendingACLs:      make(map[string]*pendingDatasite),
+		reserved:         make(map[string]int64),
+		normalizeEmail:   utils.NewEmailNormalizer(nil).Normalize,
 	}
 }
 
+// SetEmailNormalizer sets the canonical form of the emails that new datasites must be named after,
+// the one the auth service issues tokens for. By default the emails are trimmed and lowercased.
+func (d *DatasiteService) SetEmailNormalizer(normalize func(email string) string) {
+	d.datasitesMu.Lock()
+	defer d.datasitesMu.Unlock()
+	d.normalizeEmail = normalize
+}
+
 func (d *DatasiteService) Start(ctx context.Context) error {
 	slog.Debug("datasite service start")
 
@@ -52,15 +74,15 @@ func (d *DatasiteService) Shutdown(ctx context.Context) error {
 	return nil
 }
 
-func (d *DatasiteService) GetView(user string) []*blob.BlobInfo {
+func (d *DatasiteService) GetView(user *acl.User) []*blob.BlobInfo {
 	// First collect all accessible blobs
-	blobs, _ := d.blob.Index().List()
+	blobs, _ := d.blob.ReadIndex().List()
 	view := make([]*blob.BlobInfo, 0, len(blobs))
 
 	// Filter blobs based on ACL
 	for _, blob := range blobs {
 		if err := d.acl.CanAccess(
-			acl.NewRequest(blob.Key, &acl.User{ID: user}, acl.AccessRead),
+			acl.NewRequest(blob.Key, user, acl.AccessRead),
 		); err == nil {
 			view = append(view, blob)
 		}
@@ -97,6 +119,9 @@ func (d *DatasiteService) loadDatasiteSubdomains() error {
 		datasites = append(datasites, datasite)
 	}
 
+	// Count them against the datasite cap
+	d.trackDatasites(datasites...)
+
 	// Load mappings
 	d.subdomainMapping.LoadMappings(datasites)
 
@@ -150,6 +175,23 @@ func (d *DatasiteService) loadVanityDomain(datasite string) error {
 			domain = hash + "." + d.domain
 		}
 
+		// Wildcard domains (e.g., *.apps.alice.dev) are checked against their suffix
+		if suffix, ok := strings.CutPrefix(domain, "*."); ok {
+			if !d.isAllowedWildcard(suffix, datasite) {
+				slog.Warn("user tried to claim unauthorized wildcard domain",
+					"datasite", datasite,
+					"domain", domain,
+					"action", "rejected")
+				continue
+			}
+			if err := d.subdomainMapping.AddWildcardDomain(domain, datasite, path); err != nil {
+				slog.Error("invalid wildcard domain", "datasite", datasite, "domain", domain, "error", err)
+				continue
+			}
+			slog.Info("added wildcard domain", "datasite", datasite, "domain", domain, "path", path)
+			continue
+		}
+
 		// Security check: validate domain ownership
 		if !d.isAllowedDomain(domain, datasite) {
 			slog.Warn("user tried to claim unauthorized domain",
@@ -186,6 +228,13 @@ func (d *DatasiteService) handleBlobChange(key string, eventType blob.BlobEventT
 		return
 	}
 
+	// the first write to a new datasite creates it, along with its default ACLs
+	if eventType&blob.BlobEventPut != 0 {
+		d.landed(GetOwner(key))
+	} else {
+		d.emptied(GetOwner(key))
+	}
+
 	if !strings.Contains(key, aclspec.FileName) && !strings.Contains(key, SettingsFileName) {
 		return
 	}
@@ -199,8 +248,14 @@ func (d *DatasiteService) handleBlobChange(key string, eventType blob.BlobEventT
 
 	// Check if this datasite is already in our mapping
 	if !d.subdomainMapping.HasDatasite(datasite) {
+		// a delete doesn't bring it back
+		if eventType&blob.BlobEventPut == 0 {
+			return
+		}
+
 		// New datasite detected! Add it to the subdomain mapping
 		slog.Info("new datasite detected, adding to subdomain mapping", "datasite", datasite, "key", key)
+		d.trackDatasites(datasite)
 
 		if err := d.ReloadVanityDomains(datasite); err != nil {
 			if !errors.Is(err, ErrNoSettingsYAML) {
@@ -242,6 +297,25 @@ func (d *DatasiteService) addDefaultHashMapping(email string) {
 	slog.Debug("added default domain", "datasite", email, "domain", hashDomain, "path", "/public")
 }
 
+// isAllowedWildcard checks if a user is allowed to claim the wildcard domain of a suffix (without "*.").
+// A wildcard can't cover the main domain, nor any domain under it but the user's own hash subdomain
+func (d *DatasiteService) isAllowedWildcard(suffix string, email string) bool {
+	if d.domain == "" {
+		return true
+	}
+
+	suffix = strings.ToLower(suffix)
+	if suffix == d.domain || strings.HasSuffix(d.domain, "."+suffix) {
+		return false
+	}
+	if !strings.HasSuffix(suffix, "."+d.domain) {
+		return true
+	}
+
+	userDomain := EmailToSubdomainHash(email) + "." + d.domain
+	return suffix == userDomain || strings.HasSuffix(suffix, "."+userDomain)
+}
+
 // isAllowedDomain checks if a user is allowed to claim a domain
 func (d *DatasiteService) isAllowedDomain(domain string, email string) bool {
 	// Calculate this user's hash
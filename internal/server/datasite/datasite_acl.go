@@ -0,0 +1,70 @@
+package datasite
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/openmined/syftbox/internal/aclspec"
+	"github.com/openmined/syftbox/internal/server/blob"
+)
+
+// defaultRuleSets returns the rulesets every datasite starts with: an owner-only root,
+// and a public dir following the configured policy
+func (d *DatasiteService) defaultRuleSets(datasite string) []*aclspec.RuleSet {
+	publicAccess := aclspec.PublicReadAccess()
+	if d.config.PublicACL == PublicACLPrivate {
+		publicAccess = aclspec.PrivateAccess()
+	}
+
+	return []*aclspec.RuleSet{
+		aclspec.NewRuleSet(
+			datasite,
+			aclspec.NotTerminal,
+			aclspec.NewDefaultRule(aclspec.PrivateAccess(), aclspec.DefaultLimits()),
+		),
+		aclspec.NewRuleSet(
+			datasite+"/public",
+			aclspec.NotTerminal,
+			aclspec.NewDefaultRule(publicAccess, aclspec.DefaultLimits()),
+		),
+	}
+}
+
+// ensureDefaultACLs writes the default rulesets a datasite doesn't have yet.
+// Rulesets already written by the owner are kept, the defaults are only created where none exists.
+func (d *DatasiteService) ensureDefaultACLs(ctx context.Context, datasite string) error {
+	for _, ruleset := range d.defaultRuleSets(datasite) {
+		key := aclspec.AsACLPath(ruleset.Path)
+		if _, exists := d.blob.Index().Get(key); exists {
+			continue
+		}
+
+		var buf bytes.Buffer
+		if err := ruleset.Encode(&buf); err != nil {
+			return err
+		}
+
+		// the blob is the ground truth, the acl service follows
+		if _, err := d.blob.Backend().PutObject(ctx, &blob.PutObjectParams{
+			Key:         key,
+			Size:        int64(buf.Len()),
+			Body:        &buf,
+			IfNotExists: true,
+		}); errors.Is(err, blob.ErrObjectExists) {
+			continue
+		} else if err != nil {
+			return fmt.Errorf("put %s: %w", key, err)
+		}
+
+		if _, err := d.acl.AddRuleSet(ruleset); err != nil {
+			return fmt.Errorf("add %s: %w", key, err)
+		}
+
+		slog.Info("datasite default acl created", "datasite", datasite, "path", key)
+	}
+
+	return nil
+}
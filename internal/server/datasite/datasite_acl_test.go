@@ -0,0 +1,208 @@
+package datasite
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/openmined/syftbox/internal/server/acl"
+	"github.com/openmined/syftbox/internal/server/blob"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeBlobs keeps objects in memory, the index is derived from them
+type fakeBlobs struct {
+	blob.IBlobBackend
+	blob.IBlobIndex
+	objects  map[string][]byte
+	putErr   error               // returned by the puts when set
+	unlisted map[string]struct{} // objects missing from the index
+}
+
+func (f *fakeBlobs) Backend() blob.IBlobBackend                    { return f }
+func (f *fakeBlobs) Index() blob.IBlobIndex                        { return f }
+func (f *fakeBlobs) ReadIndex() blob.IBlobIndex                    { return f.Index() }
+func (f *fakeBlobs) OnBlobChange(callback blob.BlobChangeCallback) {}
+func (f *fakeBlobs) Count() int                                    { return len(f.objects) }
+
+func (f *fakeBlobs) GetObject(ctx context.Context, key string) (*blob.GetObjectResponse, error) {
+	content, ok := f.objects[key]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return &blob.GetObjectResponse{Body: io.NopCloser(bytes.NewReader(content)), Size: int64(len(content))}, nil
+}
+
+func (f *fakeBlobs) PutObject(ctx context.Context, params *blob.PutObjectParams) (*blob.PutObjectResponse, error) {
+	if f.putErr != nil {
+		return nil, f.putErr
+	}
+	if _, exists := f.objects[params.Key]; exists && params.IfNotExists {
+		return nil, blob.ErrObjectExists
+	}
+	content, err := io.ReadAll(params.Body)
+	if err != nil {
+		return nil, err
+	}
+	f.objects[params.Key] = content
+	return &blob.PutObjectResponse{Key: params.Key, Size: int64(len(content))}, nil
+}
+
+func (f *fakeBlobs) Get(key string) (*blob.BlobInfo, bool) {
+	if _, ok := f.objects[key]; !ok {
+		return nil, false
+	}
+	if _, ok := f.unlisted[key]; ok {
+		return nil, false
+	}
+	return &blob.BlobInfo{Key: key, Size: int64(len(f.objects[key]))}, true
+}
+
+func (f *fakeBlobs) Usage(datasite string) int64 {
+	var size int64
+	for key, content := range f.objects {
+		if quotaOwner(key) == datasite {
+			size += int64(len(content))
+		}
+	}
+	return size
+}
+
+func (f *fakeBlobs) FilterByPrefix(prefix string) ([]*blob.BlobInfo, error) {
+	var blobs []*blob.BlobInfo
+	for key := range f.objects {
+		if strings.HasPrefix(key, prefix) {
+			blobs = append(blobs, &blob.BlobInfo{Key: key})
+		}
+	}
+	return blobs, nil
+}
+
+func newDefaultACLTestService(t *testing.T, config *Config) (*DatasiteService, *fakeBlobs, *acl.ACLService) {
+	t.Helper()
+	blobs := &fakeBlobs{objects: make(map[string][]byte)}
+	aclSvc := acl.NewACLService(blobs)
+	return NewDatasiteService(blobs, aclSvc, "", config), blobs, aclSvc
+}
+
+// write admits a write to a datasite and stores it, like the upload handlers do
+func write(t *testing.T, svc *DatasiteService, blobs *fakeBlobs, key string, content string) {
+	t.Helper()
+	require.NoError(t, svc.Admit(GetOwner(key)))
+	defer svc.Release(GetOwner(key))
+	blobs.objects[key] = []byte(content)
+	svc.handleBlobChange(key, blob.BlobEventPut)
+}
+
+func canAccess(aclSvc *acl.ACLService, user string, path string, level acl.AccessLevel) bool {
+	return aclSvc.CanAccess(acl.NewRequest(path, &acl.User{ID: user}, level)) == nil
+}
+
+func TestDefaultACLs(t *testing.T) {
+	svc, blobs, aclSvc := newDefaultACLTestService(t, &Config{PublicACL: PublicACLRead})
+
+	write(t, svc, blobs, "alice@example.com/notes.txt", "notes")
+	assert.Contains(t, blobs.objects, "alice@example.com/syft.pub.yaml")
+	assert.Contains(t, blobs.objects, "alice@example.com/public/syft.pub.yaml")
+
+	// the root is owner-only, the public dir is readable by everyone
+	assert.True(t, canAccess(aclSvc, "alice@example.com", "alice@example.com/notes.txt", acl.AccessWrite))
+	assert.False(t, canAccess(aclSvc, "bob@example.com", "alice@example.com/notes.txt", acl.AccessRead))
+	assert.True(t, canAccess(aclSvc, "bob@example.com", "alice@example.com/public/report.csv", acl.AccessRead))
+	assert.False(t, canAccess(aclSvc, "bob@example.com", "alice@example.com/public/report.csv", acl.AccessWrite))
+}
+
+func TestDefaultACLsPrivate(t *testing.T) {
+	svc, blobs, aclSvc := newDefaultACLTestService(t, &Config{PublicACL: PublicACLPrivate})
+
+	write(t, svc, blobs, "alice@example.com/public/report.csv", "a,b\n")
+	assert.Contains(t, blobs.objects, "alice@example.com/public/syft.pub.yaml")
+	assert.False(t, canAccess(aclSvc, "bob@example.com", "alice@example.com/public/report.csv", acl.AccessRead))
+}
+
+func TestDefaultACLsKeepOwnerACLs(t *testing.T) {
+	svc, blobs, _ := newDefaultACLTestService(t, &Config{})
+
+	// the client uploads its own public ruleset first
+	publicACL := "rules:\n  - pattern: '**'\n    access:\n      write: ['*']\n"
+	write(t, svc, blobs, "alice@example.com/public/syft.pub.yaml", publicACL)
+	assert.Equal(t, publicACL, string(blobs.objects["alice@example.com/public/syft.pub.yaml"]))
+	assert.Contains(t, blobs.objects, "alice@example.com/syft.pub.yaml")
+}
+
+func TestDefaultACLsOnlyForNewDatasites(t *testing.T) {
+	svc, blobs, _ := newDefaultACLTestService(t, &Config{})
+	svc.trackDatasites("alice@example.com")
+
+	write(t, svc, blobs, "alice@example.com/notes.txt", "notes")
+	assert.Len(t, blobs.objects, 1, "existing datasites are left alone")
+
+	// admitted, but nothing was written
+	require.NoError(t, svc.Admit("bob@example.com"))
+	assert.Len(t, blobs.objects, 1)
+}
+
+func TestDefaultACLsWaitForWritesInProgress(t *testing.T) {
+	svc, blobs, aclSvc := newDefaultACLTestService(t, &Config{})
+
+	// the owner uploads a file and its root ruleset at once
+	require.NoError(t, svc.Admit("alice@example.com"))
+	require.NoError(t, svc.Admit("alice@example.com"))
+	blobs.objects["alice@example.com/notes.txt"] = []byte("notes")
+	svc.handleBlobChange("alice@example.com/notes.txt", blob.BlobEventPut)
+	svc.Release("alice@example.com")
+	assert.NotContains(t, blobs.objects, "alice@example.com/syft.pub.yaml", "the other write is still in progress")
+
+	rootACL := "rules:\n  - pattern: '**'\n    access:\n      read: ['*']\n"
+	blobs.objects["alice@example.com/syft.pub.yaml"] = []byte(rootACL)
+	svc.Release("alice@example.com")
+	assert.Equal(t, rootACL, string(blobs.objects["alice@example.com/syft.pub.yaml"]))
+	assert.Contains(t, blobs.objects, "alice@example.com/public/syft.pub.yaml")
+	assert.Empty(t, svc.pendingACLs)
+	assert.False(t, canAccess(aclSvc, "bob@example.com", "alice@example.com/public/report.csv", acl.AccessWrite))
+}
+
+func TestDefaultACLsConditionalPut(t *testing.T) {
+	svc, blobs, _ := newDefaultACLTestService(t, &Config{})
+
+	// the owner's ruleset landed, but the index doesn't list it yet
+	ownerACL := "rules:\n  - pattern: '**'\n    access:\n      read: ['*']\n"
+	blobs.objects["alice@example.com/syft.pub.yaml"] = []byte(ownerACL)
+	blobs.unlisted = map[string]struct{}{"alice@example.com/syft.pub.yaml": {}}
+
+	write(t, svc, blobs, "alice@example.com/notes.txt", "notes")
+	assert.Equal(t, ownerACL, string(blobs.objects["alice@example.com/syft.pub.yaml"]))
+	assert.Contains(t, blobs.objects, "alice@example.com/public/syft.pub.yaml")
+}
+
+func TestDefaultACLsRetriedOnFailure(t *testing.T) {
+	svc, blobs, _ := newDefaultACLTestService(t, &Config{})
+
+	blobs.putErr = errors.New("storage unavailable")
+	write(t, svc, blobs, "alice@example.com/notes.txt", "notes")
+	assert.NotContains(t, blobs.objects, "alice@example.com/syft.pub.yaml")
+	assert.Contains(t, svc.pendingACLs, "alice@example.com", "pending until the defaults are written")
+
+	blobs.putErr = nil
+	write(t, svc, blobs, "alice@example.com/more.txt", "more")
+	assert.Contains(t, blobs.objects, "alice@example.com/syft.pub.yaml")
+	assert.NotContains(t, svc.pendingACLs, "alice@example.com")
+}
+
+func TestDefaultACLsPresignedUpload(t *testing.T) {
+	svc, blobs, _ := newDefaultACLTestService(t, &Config{MaxDatasites: 1})
+
+	// the presigned upload lands after its admission ended, the datasite keeps its slot meanwhile
+	require.NoError(t, svc.Admit("alice@example.com"))
+	svc.Detach("alice@example.com")
+	assert.ErrorIs(t, svc.Admit("bob@example.com"), ErrDatasiteLimitReached)
+
+	blobs.objects["alice@example.com/notes.txt"] = []byte("notes")
+	svc.handleBlobChange("alice@example.com/notes.txt", blob.BlobEventPut)
+	assert.Contains(t, blobs.objects, "alice@example.com/syft.pub.yaml")
+	assert.Contains(t, blobs.objects, "alice@example.com/public/syft.pub.yaml")
+}
@@ -0,0 +1,58 @@
+package datasite
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+const (
+	PublicACLRead    = "read"    // everyone can read the public dir of a new datasite
+	PublicACLPrivate = "private" // the public dir of a new datasite is owner-only until it's shared
+)
+
+type Config struct {
+	MaxDatasites int            `mapstructure:"max_datasites"` // Maximum number of datasites on the server. 0 is unlimited.
+	Features     FeaturesConfig `mapstructure:"features"`      // Server-wide state of the features. Can be overridden for each datasite.
+	IdleTimeout  time.Duration  `mapstructure:"idle_timeout"`  // Evict the in-memory state of datasites idle for longer. 0 never evicts.
+	PublicACL    string         `mapstructure:"public_acl"`    // Default ACL of the public dir of new datasites, read or private. Empty is read.
+	QuotaBytes   int64          `mapstructure:"quota_bytes"`   // Maximum size of the blobs stored in each datasite. 0 is unlimited.
+	ACLMaxSize   int64          `mapstructure:"acl_max_size"`  // Maximum size of an ACL file in bytes. 0 is unlimited.
+	ACLMaxRules  int            `mapstructure:"acl_max_rules"` // Maximum number of rules of an ACL file. 0 is unlimited.
+}
+
+func (c *Config) Validate() error {
+	if c.MaxDatasites < 0 {
+		return fmt.Errorf("max_datasites must be >= 0")
+	}
+	if c.IdleTimeout < 0 {
+		return fmt.Errorf("idle_timeout must be >= 0")
+	}
+	if c.QuotaBytes < 0 {
+		return fmt.Errorf("quota_bytes must be >= 0")
+	}
+	if c.ACLMaxSize < 0 {
+		return fmt.Errorf("acl_max_size must be >= 0")
+	}
+	if c.ACLMaxRules < 0 {
+		return fmt.Errorf("acl_max_rules must be >= 0")
+	}
+	switch c.PublicACL {
+	case "", PublicACLRead, PublicACLPrivate:
+	default:
+		return fmt.Errorf("public_acl must be %q or %q", PublicACLRead, PublicACLPrivate)
+	}
+	return nil
+}
+
+func (c Config) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Int("max_datasites", c.MaxDatasites),
+		slog.Any("features", c.Features),
+		slog.Duration("idle_timeout", c.IdleTimeout),
+		slog.String("public_acl", c.PublicACL),
+		slog.Int64("quota_bytes", c.QuotaBytes),
+		slog.Int64("acl_max_size", c.ACLMaxSize),
+		slog.Int("acl_max_rules", c.ACLMaxRules),
+	)
+}
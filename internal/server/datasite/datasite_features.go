@@ -0,0 +1,186 @@
+package datasite
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Feature is a server feature that can be turned on or off for each datasite
+type Feature string
+
+const (
+	FeaturePublicHosting Feature = "public_hosting" // serving the datasite on its subdomains
+	FeatureRPC           Feature = "rpc"            // sending rpc messages to the datasite
+)
+
+// AllFeatures are the features that can be turned on or off
+var AllFeatures = []Feature{FeaturePublicHosting, FeatureRPC}
+
+var ErrUnknownFeature = errors.New("unknown feature")
+
+const featuresSchemaSQL = `
+CREATE TABLE IF NOT EXISTS datasite_features (
+	datasite TEXT NOT NULL,
+	feature TEXT NOT NULL,
+	enabled INTEGER NOT NULL,
+	PRIMARY KEY (datasite, feature)
+);
+`
+
+// FeaturesConfig has the server-wide state of each feature, for datasites without an override
+type FeaturesConfig struct {
+	PublicHosting bool `mapstructure:"public_hosting"`
+	RPC           bool `mapstructure:"rpc"`
+}
+
+func (c FeaturesConfig) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Bool("public_hosting", c.PublicHosting),
+		slog.Bool("rpc", c.RPC),
+	)
+}
+
+// DatasiteFeatures is the state of the features of a datasite
+type DatasiteFeatures struct {
+	Datasite  string           `json:"datasite"`
+	Features  map[Feature]bool `json:"features"`  // effective state of every feature
+	Overrides map[Feature]bool `json:"overrides"` // features set for this datasite, the rest follow the server config
+}
+
+// FeatureFlags stores per-datasite overrides of the features in the database.
+// Overrides are cached in memory, as they're checked on every request.
+type FeatureFlags struct {
+	db        *sqlx.DB
+	defaults  map[Feature]bool
+	overrides map[string]map[Feature]bool
+	mu        sync.RWMutex
+}
+
+func NewFeatureFlags(db *sqlx.DB, config *FeaturesConfig) (*FeatureFlags, error) {
+	if _, err := db.Exec(featuresSchemaSQL); err != nil {
+		return nil, fmt.Errorf("failed to initialize feature flags: %w", err)
+	}
+
+	f := &FeatureFlags{
+		db: db,
+		defaults: map[Feature]bool{
+			FeaturePublicHosting: config.PublicHosting,
+			FeatureRPC:           config.RPC,
+		},
+		overrides: make(map[string]map[Feature]bool),
+	}
+
+	if err := f.load(); err != nil {
+		return nil, fmt.Errorf("failed to load feature flags: %w", err)
+	}
+
+	return f, nil
+}
+
+// ParseFeature parses the name of a feature
+func ParseFeature(name string) (Feature, error) {
+	for _, feature := range AllFeatures {
+		if string(feature) == name {
+			return feature, nil
+		}
+	}
+	return "", fmt.Errorf("%w %q", ErrUnknownFeature, name)
+}
+
+// Enabled returns true if the feature is on for the datasite
+func (f *FeatureFlags) Enabled(datasite string, feature Feature) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if enabled, ok := f.overrides[datasite][feature]; ok {
+		return enabled
+	}
+	return f.defaults[feature]
+}
+
+// Get returns the state of the features of the datasite
+func (f *FeatureFlags) Get(datasite string) *DatasiteFeatures {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	features := maps.Clone(f.defaults)
+	overrides := maps.Clone(f.overrides[datasite])
+	if overrides == nil {
+		overrides = make(map[Feature]bool)
+	}
+	maps.Copy(features, overrides)
+
+	return &DatasiteFeatures{
+		Datasite:  datasite,
+		Features:  features,
+		Overrides: overrides,
+	}
+}
+
+// Set overrides the feature for the datasite. A nil enabled removes the override,
+// so that the datasite follows the server config again.
+func (f *FeatureFlags) Set(datasite string, feature Feature, enabled *bool) error {
+	if _, ok := f.defaults[feature]; !ok {
+		return fmt.Errorf("%w %q", ErrUnknownFeature, feature)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if enabled == nil {
+		if _, err := f.db.Exec("DELETE FROM datasite_features WHERE datasite = ? AND feature = ?", datasite, feature); err != nil {
+			return fmt.Errorf("failed to reset feature: %w", err)
+		}
+
+		delete(f.overrides[datasite], feature)
+		if len(f.overrides[datasite]) == 0 {
+			delete(f.overrides, datasite)
+		}
+		return nil
+	}
+
+	if _, err := f.db.Exec(`
+		INSERT INTO datasite_features (datasite, feature, enabled) VALUES (?, ?, ?)
+		ON CONFLICT(datasite, feature) DO UPDATE SET enabled = excluded.enabled
+	`, datasite, feature, *enabled); err != nil {
+		return fmt.Errorf("failed to set feature: %w", err)
+	}
+
+	if f.overrides[datasite] == nil {
+		f.overrides[datasite] = make(map[Feature]bool)
+	}
+	f.overrides[datasite][feature] = *enabled
+	return nil
+}
+
+func (f *FeatureFlags) load() error {
+	var rows []struct {
+		Datasite string `db:"datasite"`
+		Feature  string `db:"feature"`
+		Enabled  bool   `db:"enabled"`
+	}
+	if err := f.db.Select(&rows, "SELECT datasite, feature, enabled FROM datasite_features"); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		feature, err := ParseFeature(row.Feature)
+		if err != nil {
+			// removed in a newer version
+			slog.Warn("ignoring feature flag", "datasite", row.Datasite, "feature", row.Feature)
+			continue
+		}
+
+		if f.overrides[row.Datasite] == nil {
+			f.overrides[row.Datasite] = make(map[Feature]bool)
+		}
+		f.overrides[row.Datasite][feature] = row.Enabled
+	}
+
+	return nil
+}
@@ -0,0 +1,82 @@
+package datasite
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/openmined/syftbox/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestFeaturesDB(t *testing.T) *sqlx.DB {
+	t.Helper()
+	sqliteDb, err := db.NewSqliteDB(db.WithPath(filepath.Join(t.TempDir(), "test.db")), db.WithMaxOpenConns(1))
+	require.NoError(t, err)
+	t.Cleanup(func() { sqliteDb.Close() })
+	return sqliteDb
+}
+
+func TestFeatureFlagsDefaultToConfig(t *testing.T) {
+	features, err := NewFeatureFlags(newTestFeaturesDB(t), &FeaturesConfig{PublicHosting: true, RPC: false})
+	require.NoError(t, err)
+
+	assert.True(t, features.Enabled("alice@example.com", FeaturePublicHosting))
+	assert.False(t, features.Enabled("alice@example.com", FeatureRPC))
+	assert.Equal(t, &DatasiteFeatures{
+		Datasite:  "alice@example.com",
+		Features:  map[Feature]bool{FeaturePublicHosting: true, FeatureRPC: false},
+		Overrides: map[Feature]bool{},
+	}, features.Get("alice@example.com"))
+}
+
+func TestFeatureFlagsOverrideDatasiteOnly(t *testing.T) {
+	features, err := NewFeatureFlags(newTestFeaturesDB(t), &FeaturesConfig{PublicHosting: true, RPC: true})
+	require.NoError(t, err)
+
+	disabled := false
+	require.NoError(t, features.Set("alice@example.com", FeatureRPC, &disabled))
+
+	assert.False(t, features.Enabled("alice@example.com", FeatureRPC))
+	assert.True(t, features.Enabled("alice@example.com", FeaturePublicHosting))
+	assert.True(t, features.Enabled("bob@example.com", FeatureRPC))
+
+	// reset follows the config again
+	require.NoError(t, features.Set("alice@example.com", FeatureRPC, nil))
+	assert.True(t, features.Enabled("alice@example.com", FeatureRPC))
+	assert.Empty(t, features.Get("alice@example.com").Overrides)
+}
+
+func TestFeatureFlagsPersisted(t *testing.T) {
+	sqliteDb := newTestFeaturesDB(t)
+	config := &FeaturesConfig{PublicHosting: true, RPC: true}
+
+	features, err := NewFeatureFlags(sqliteDb, config)
+	require.NoError(t, err)
+
+	disabled := false
+	require.NoError(t, features.Set("alice@example.com", FeaturePublicHosting, &disabled))
+	require.NoError(t, features.Set("alice@example.com", FeaturePublicHosting, &disabled)) // upsert
+
+	// as after a restart
+	features, err = NewFeatureFlags(sqliteDb, config)
+	require.NoError(t, err)
+	assert.False(t, features.Enabled("alice@example.com", FeaturePublicHosting))
+	assert.Equal(t, map[Feature]bool{FeaturePublicHosting: false}, features.Get("alice@example.com").Overrides)
+}
+
+func TestFeatureFlagsUnknownFeature(t *testing.T) {
+	features, err := NewFeatureFlags(newTestFeaturesDB(t), &FeaturesConfig{})
+	require.NoError(t, err)
+
+	enabled := true
+	assert.ErrorIs(t, features.Set("alice@example.com", Feature("hotlink"), &enabled), ErrUnknownFeature)
+
+	_, err = ParseFeature("hotlink")
+	assert.ErrorIs(t, err, ErrUnknownFeature)
+
+	feature, err := ParseFeature("rpc")
+	require.NoError(t, err)
+	assert.Equal(t, FeatureRPC, feature)
+}
@@ -0,0 +1,268 @@
+package datasite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openmined/syftbox/internal/server/blob"
+)
+
+// how long a new datasite keeps its slot without a write landing, longer than the presigned uploads are valid.
+// The presigned uploads aren't released when they fail, as they go to the backend directly
+const pendingDatasiteTTL = 15 * time.Minute
+
+var (
+	ErrDatasiteLimitReached  = errors.New("datasite limit reached")
+	ErrDatasiteNotNormalized = errors.New("datasite is not named after a normalized email")
+	ErrQuotaExceeded         = errors.New("datasite quota exceeded")
+)
+
+// DatasiteStats is the number of datasites on the server against the configured cap
+type DatasiteStats struct {
+	Count        int  `json:"count"`
+	Max          int  `json:"max"` // 0 is unlimited
+	LimitReached bool `json:"limitReached"`
+}
+
+// pendingDatasite is a new datasite that doesn't have its default ACLs yet
+type pendingDatasite struct {
+	writes     int  // admitted writes not released yet
+	detached   bool // a write may still land outside the server, see Detach
+	admittedAt time.Time
+	creating   chan struct{} // closed once the default ACLs are written, nil when not writing them
+}
+
+// Admit checks if a write to the datasite is allowed under the datasite cap.
+// Existing datasites are always admitted. A new datasite takes up a slot right away,
+// so that concurrent writes can't go over the cap, and gets its default ACLs once the first write lands.
+// A new datasite must be named after the normalized email, so that one person maps to one datasite.
+// Every admitted write is released with Release once done, so that failed writes give the slot back.
+func (d *DatasiteService) Admit(datasite string) error {
+	d.datasitesMu.Lock()
+	defer d.datasitesMu.Unlock()
+
+	// no write lands while the default ACLs are written, so that they never replace the owner's
+	for pending, ok := d.pendingACLs[datasite]; ok && pending.creating != nil; pending, ok = d.pendingACLs[datasite] {
+		creating := pending.creating
+		d.datasitesMu.Unlock()
+		<-creating
+		d.datasitesMu.Lock()
+	}
+
+	if pending, ok := d.pendingACLs[datasite]; ok {
+		pending.writes++
+		pending.admittedAt = time.Now()
+		return nil
+	}
+	if _, ok := d.datasites[datasite]; ok {
+		return nil
+	}
+
+	if normalized := d.normalizeEmail(datasite); normalized != datasite {
+		return fmt.Errorf("%w: %q is %q", ErrDatasiteNotNormalized, datasite, normalized)
+	}
+
+	if d.limitReached() {
+		d.expirePending()
+	}
+	if d.limitReached() {
+		return fmt.Errorf("%w: %d datasites", ErrDatasiteLimitReached, d.config.MaxDatasites)
+	}
+
+	d.datasites[datasite] = struct{}{}
+	d.pendingACLs[datasite] = &pendingDatasite{writes: 1, admittedAt: time.Now()}
+	return nil
+}
+
+// Release ends a write admitted by Admit, whether it landed or not.
+// A new datasite gets its default ACLs once all its writes are released and one of them landed,
+// and gives its slot back if none did.
+func (d *DatasiteService) Release(datasite string) {
+	d.datasitesMu.Lock()
+	defer d.datasitesMu.Unlock()
+
+	pending, ok := d.pendingACLs[datasite]
+	if !ok {
+		return
+	}
+	if pending.writes--; pending.writes > 0 || pending.creating != nil {
+		return
+	}
+
+	if d.hasBlobs(datasite) {
+		d.createDefaultACLs(datasite, pending)
+	} else if !pending.detached {
+		delete(d.pendingACLs, datasite)
+		delete(d.datasites, datasite)
+	}
+}
+
+// Detach ends a write admitted by Admit that lands outside the server, like a presigned upload.
+// A new datasite keeps its slot for pendingDatasiteTTL, and gets its default ACLs once the write lands.
+func (d *DatasiteService) Detach(datasite string) {
+	d.datasitesMu.Lock()
+	defer d.datasitesMu.Unlock()
+
+	if pending, ok := d.pendingACLs[datasite]; ok {
+		pending.writes--
+		pending.detached = true
+	}
+}
+
+// landed creates the default ACLs of a new datasite once a write landed in it, if no admitted write is still
+// in progress. Those get them on their Release instead.
+func (d *DatasiteService) landed(datasite string) {
+	d.datasitesMu.Lock()
+	defer d.datasitesMu.Unlock()
+
+	if pending, ok := d.pendingACLs[datasite]; ok && pending.writes == 0 && pending.creating == nil {
+		d.createDefaultACLs(datasite, pending)
+	}
+}
+
+// emptied drops a datasite from the count once its last blob is deleted, so that it gives its slot back.
+// A new datasite with writes in progress keeps it.
+func (d *DatasiteService) emptied(datasite string) {
+	d.datasitesMu.Lock()
+	defer d.datasitesMu.Unlock()
+
+	if pending, ok := d.pendingACLs[datasite]; ok && (pending.writes > 0 || pending.detached || pending.creating != nil) {
+		return
+	}
+	if d.hasBlobs(datasite) {
+		return
+	}
+	delete(d.pendingACLs, datasite)
+	delete(d.datasites, datasite)
+}
+
+// createDefaultACLs writes the default ACLs of a new datasite, with datasitesMu held. The lock is released
+// while they are written, and the datasite stays pending until they are, so that the next write tries again.
+func (d *DatasiteService) createDefaultACLs(datasite string, pending *pendingDatasite) {
+	pending.creating = make(chan struct{})
+	d.datasitesMu.Unlock()
+	err := d.ensureDefaultACLs(context.Background(), datasite)
+	d.datasitesMu.Lock()
+
+	close(pending.creating)
+	pending.creating = nil
+	if err != nil {
+		slog.Warn("failed to create default acls", "datasite", datasite, "error", err)
+		return
+	}
+	delete(d.pendingACLs, datasite)
+}
+
+// expirePending gives back the slots of the new datasites admitted a while ago that still have no blobs
+func (d *DatasiteService) expirePending() {
+	for datasite, pending := range d.pendingACLs {
+		if time.Since(pending.admittedAt) > pendingDatasiteTTL && !d.hasBlobs(datasite) {
+			delete(d.pendingACLs, datasite)
+			delete(d.datasites, datasite)
+		}
+	}
+}
+
+func (d *DatasiteService) hasBlobs(datasite string) bool {
+	if d.blob == nil {
+		return false
+	}
+	blobs, err := d.blob.Index().FilterByPrefix(datasite + "/")
+	// keeps the slot when unsure
+	return err != nil || len(blobs) > 0
+}
+
+// AdmitSize checks if a write of size bytes to the key fits in the quota of its datasite, and reserves
+// the size until the returned func is called once the write is done, so that concurrent writes can't go
+// over the quota together. A write that landed before its release counts twice for a moment, in favour of the quota.
+// An overwrite only counts the difference to the blob it replaces. A write of unknown size (negative)
+// can't be checked, so it's rejected.
+// Snapshots count towards the quota of the datasite they were taken of.
+func (d *DatasiteService) AdmitSize(key string, size int64) (func(), error) {
+	if d.config.QuotaBytes <= 0 {
+		return func() {}, nil
+	}
+	if size < 0 {
+		return nil, fmt.Errorf("%w: the size of the write is required", ErrQuotaExceeded)
+	}
+
+	var replaced int64
+	if existing, ok := d.blob.Index().Get(key); ok {
+		replaced = existing.Size
+	}
+	return d.reserve(quotaOwner(key), size, replaced)
+}
+
+// AdmitGrowth is AdmitSize for the writes to several keys of a datasite at once, e.g. a snapshot or a restore,
+// that grow the datasite by size bytes in total. Writes that don't grow it are always admitted.
+func (d *DatasiteService) AdmitGrowth(datasite string, size int64) (func(), error) {
+	if d.config.QuotaBytes <= 0 || size <= 0 {
+		return func() {}, nil
+	}
+	return d.reserve(datasite, size, 0)
+}
+
+// reserve reserves size bytes of the quota of the datasite, for a write replacing blobs of the replaced size
+func (d *DatasiteService) reserve(datasite string, size int64, replaced int64) (func(), error) {
+	d.quotaMu.Lock()
+	defer d.quotaMu.Unlock()
+
+	usage := d.blob.Index().Usage(datasite) + d.reserved[datasite] - replaced
+	if usage+size > d.config.QuotaBytes || (size <= 0 && usage >= d.config.QuotaBytes) {
+		return nil, fmt.Errorf("%w: %d of %d bytes used", ErrQuotaExceeded, usage, d.config.QuotaBytes)
+	}
+	if size <= 0 {
+		return func() {}, nil
+	}
+
+	d.reserved[datasite] += size
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			d.quotaMu.Lock()
+			defer d.quotaMu.Unlock()
+			if d.reserved[datasite] -= size; d.reserved[datasite] <= 0 {
+				delete(d.reserved, datasite)
+			}
+		})
+	}, nil
+}
+
+// quotaOwner is the datasite whose quota a key counts towards, the same the index accounts its size to
+func quotaOwner(key string) string {
+	if rest, ok := strings.CutPrefix(key, blob.SnapshotsPrefix); ok {
+		return GetOwner(rest)
+	}
+	return GetOwner(key)
+}
+
+// Stats returns the current datasite count and cap
+func (d *DatasiteService) Stats() *DatasiteStats {
+	d.datasitesMu.Lock()
+	defer d.datasitesMu.Unlock()
+
+	return &DatasiteStats{
+		Count:        len(d.datasites),
+		Max:          d.config.MaxDatasites,
+		LimitReached: d.limitReached(),
+	}
+}
+
+// trackDatasites adds existing datasites to the count, regardless of the cap
+func (d *DatasiteService) trackDatasites(datasites ...string) {
+	d.datasitesMu.Lock()
+	defer d.datasitesMu.Unlock()
+
+	for _, datasite := range datasites {
+		d.datasites[datasite] = struct{}{}
+	}
+}
+
+func (d *DatasiteService) limitReached() bool {
+	return d.config.MaxDatasites > 0 && len(d.datasites) >= d.config.MaxDatasites
+}
@@ -0,0 +1,167 @@
+package datasite
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/openmined/syftbox/internal/server/blob"
+	"github.com/openmined/syftbox/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdmitUpToMaxDatasites(t *testing.T) {
+	svc := NewDatasiteService(nil, nil, "", &Config{MaxDatasites: 3})
+
+	// existing datasites count against the cap
+	svc.trackDatasites("alice@example.com")
+
+	for i := range 2 {
+		require.NoError(t, svc.Admit(fmt.Sprintf("user%d@example.com", i)))
+	}
+
+	err := svc.Admit("late@example.com")
+	assert.ErrorIs(t, err, ErrDatasiteLimitReached)
+
+	// existing datasites are still writable
+	assert.NoError(t, svc.Admit("alice@example.com"))
+	assert.NoError(t, svc.Admit("user0@example.com"))
+
+	assert.Equal(t, &DatasiteStats{Count: 3, Max: 3, LimitReached: true}, svc.Stats())
+}
+
+func TestAdmitUnlimited(t *testing.T) {
+	svc := NewDatasiteService(nil, nil, "", &Config{})
+
+	for i := range 100 {
+		require.NoError(t, svc.Admit(fmt.Sprintf("user%d@example.com", i)))
+	}
+
+	assert.Equal(t, &DatasiteStats{Count: 100, Max: 0, LimitReached: false}, svc.Stats())
+}
+
+func TestAdmitNormalizedDatasites(t *testing.T) {
+	svc := NewDatasiteService(nil, nil, "", &Config{})
+
+	// existing datasites are admitted however they are spelled
+	svc.trackDatasites("Legacy@Example.com")
+	assert.NoError(t, svc.Admit("Legacy@Example.com"))
+
+	require.NoError(t, svc.Admit("alice@example.com"))
+	assert.ErrorIs(t, svc.Admit("Alice@Example.com"), ErrDatasiteNotNormalized)
+
+	// the dots and the +tag are dropped once the auth service is configured to
+	svc.SetEmailNormalizer(utils.NewEmailNormalizer([]string{"gmail.com"}).Normalize)
+	require.NoError(t, svc.Admit("bob@gmail.com"))
+	assert.ErrorIs(t, svc.Admit("b.o.b+sync@gmail.com"), ErrDatasiteNotNormalized)
+
+	assert.Equal(t, 3, svc.Stats().Count, "one datasite for each person")
+}
+
+func TestAdmitReleasesFailedWrites(t *testing.T) {
+	svc, blobs, _ := newDefaultACLTestService(t, &Config{MaxDatasites: 1})
+
+	// the upload failed, the datasite gets its slot back
+	require.NoError(t, svc.Admit("alice@example.com"))
+	assert.ErrorIs(t, svc.Admit("bob@example.com"), ErrDatasiteLimitReached)
+	svc.Release("alice@example.com")
+	assert.Equal(t, 0, svc.Stats().Count)
+
+	// only once all the writes to the new datasite are done
+	require.NoError(t, svc.Admit("bob@example.com"))
+	require.NoError(t, svc.Admit("bob@example.com"))
+	svc.Release("bob@example.com")
+	assert.ErrorIs(t, svc.Admit("carol@example.com"), ErrDatasiteLimitReached)
+
+	// the other write landed, the datasite stays
+	blobs.objects["bob@example.com/notes.txt"] = []byte("notes")
+	svc.Release("bob@example.com")
+	assert.ErrorIs(t, svc.Admit("carol@example.com"), ErrDatasiteLimitReached)
+	assert.Equal(t, 1, svc.Stats().Count)
+}
+
+func TestAdmitExpiresPendingDatasites(t *testing.T) {
+	svc, _, _ := newDefaultACLTestService(t, &Config{MaxDatasites: 1})
+
+	// a presigned upload that never happened
+	require.NoError(t, svc.Admit("alice@example.com"))
+	assert.ErrorIs(t, svc.Admit("bob@example.com"), ErrDatasiteLimitReached)
+
+	svc.pendingACLs["alice@example.com"].admittedAt = time.Now().Add(-pendingDatasiteTTL - time.Second)
+	require.NoError(t, svc.Admit("bob@example.com"))
+	assert.Equal(t, 1, svc.Stats().Count)
+}
+
+func TestEmptiedDatasiteGivesSlotBack(t *testing.T) {
+	svc, blobs, _ := newDefaultACLTestService(t, &Config{MaxDatasites: 1})
+	svc.trackDatasites("alice@example.com")
+	blobs.objects["alice@example.com/notes.txt"] = []byte("notes")
+	blobs.objects["alice@example.com/syft.pub.yaml"] = []byte("rules: []")
+
+	// alice still has blobs
+	delete(blobs.objects, "alice@example.com/notes.txt")
+	svc.handleBlobChange("alice@example.com/notes.txt", blob.BlobEventDelete)
+	assert.ErrorIs(t, svc.Admit("bob@example.com"), ErrDatasiteLimitReached)
+
+	// and none once the last one is deleted
+	delete(blobs.objects, "alice@example.com/syft.pub.yaml")
+	svc.handleBlobChange("alice@example.com/syft.pub.yaml", blob.BlobEventDelete)
+	assert.Equal(t, 0, svc.Stats().Count)
+	require.NoError(t, svc.Admit("bob@example.com"))
+
+	// a new datasite with a write in progress keeps its slot
+	svc.handleBlobChange("bob@example.com/notes.txt", blob.BlobEventDelete)
+	assert.Equal(t, 1, svc.Stats().Count)
+}
+
+func TestAdmitSizeReservesConcurrentWrites(t *testing.T) {
+	svc, blobs, _ := newDefaultACLTestService(t, &Config{QuotaBytes: 300})
+	blobs.objects["alice@example.com/a.txt"] = make([]byte, 100)
+
+	release, err := svc.AdmitSize("alice@example.com/b.txt", 150)
+	require.NoError(t, err)
+
+	// the write in progress counts before it lands
+	_, err = svc.AdmitSize("alice@example.com/c.txt", 100)
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+
+	// releasing more than once gives the size back once
+	release()
+	release()
+	releaseC, err := svc.AdmitSize("alice@example.com/c.txt", 100)
+	require.NoError(t, err)
+	releaseD, err := svc.AdmitSize("alice@example.com/d.txt", 100)
+	require.NoError(t, err)
+	_, err = svc.AdmitSize("alice@example.com/e.txt", 1)
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+	releaseC()
+	releaseD()
+
+	// the quota of a datasite includes its snapshots
+	blobs.objects["_snapshots/alice@example.com/objects/etag"] = make([]byte, 150)
+	_, err = svc.AdmitSize("alice@example.com/c.txt", 100)
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+	_, err = svc.AdmitSize("_snapshots/alice@example.com/objects/other", 100)
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+	_, err = svc.AdmitSize("bob@example.com/c.txt", 100)
+	assert.NoError(t, err)
+}
+
+func TestAdmitGrowth(t *testing.T) {
+	svc, blobs, _ := newDefaultACLTestService(t, &Config{QuotaBytes: 300})
+	blobs.objects["alice@example.com/a.txt"] = make([]byte, 250)
+
+	release, err := svc.AdmitGrowth("alice@example.com", 50)
+	require.NoError(t, err)
+	_, err = svc.AdmitGrowth("alice@example.com", 1)
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+
+	// shrinking a full datasite is always admitted
+	_, err = svc.AdmitGrowth("alice@example.com", -100)
+	assert.NoError(t, err)
+
+	release()
+	_, err = svc.AdmitGrowth("alice@example.com", 50)
+	assert.NoError(t, err)
+}
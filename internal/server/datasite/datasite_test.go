@@ -76,6 +76,20 @@ func TestDomainOwnershipValidation(t *testing.T) {
 	}
 }
 
+func TestIsAllowedWildcard(t *testing.T) {
+	ds := &DatasiteService{domain: "eu.syftbox.net"}
+
+	// alice's hash is ff8d9819fc0e12bf
+	assert.True(t, ds.isAllowedWildcard("apps.alice.dev", "alice@example.com"))
+	assert.True(t, ds.isAllowedWildcard("ff8d9819fc0e12bf.eu.syftbox.net", "alice@example.com"))
+	assert.True(t, ds.isAllowedWildcard("apps.ff8d9819fc0e12bf.eu.syftbox.net", "alice@example.com"))
+
+	assert.False(t, ds.isAllowedWildcard("eu.syftbox.net", "alice@example.com"), "the main domain")
+	assert.False(t, ds.isAllowedWildcard("syftbox.net", "alice@example.com"), "above the main domain")
+	assert.False(t, ds.isAllowedWildcard("www.eu.syftbox.net", "alice@example.com"), "under the main domain")
+	assert.False(t, ds.isAllowedWildcard("1234567890abcdef.eu.syftbox.net", "alice@example.com"), "another hash subdomain")
+}
+
 func TestHelperFunctions(t *testing.T) {
 	t.Run("IsHexString", func(t *testing.T) {
 		tests := []struct {
@@ -2,18 +2,34 @@ package datasite
 
 import (
 	"errors"
+	"regexp"
+	"strings"
 	"sync"
+
+	"golang.org/x/net/publicsuffix"
 )
 
 var (
-	ErrSubdomainNotFound = errors.New("subdomain not found")
-	ErrEmailNotFound     = errors.New("email not found")
+	ErrSubdomainNotFound   = errors.New("subdomain not found")
+	ErrEmailNotFound       = errors.New("email not found")
+	ErrInvalidWildcardHost = errors.New("wildcard domain must be of the form *.example.com")
+	ErrBroadWildcardHost   = errors.New("wildcard domain must be under a registrable domain, like *.example.com")
 )
 
+// WildcardLabel is replaced with the label a wildcard domain matched, in its path
+const WildcardLabel = "{label}"
+
+// regexHostLabels matches one or more dot separated DNS labels
+var regexHostLabels = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?(\.[a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?)*$`)
+
+// regexNumericLabel matches a DNS label of digits only, like the parts of an IP address
+var regexNumericLabel = regexp.MustCompile(`^[0-9]+$`)
+
 // VanityDomainConfig stores the configuration for a vanity domain
 type VanityDomainConfig struct {
 	Email string
 	Path  string // Custom path within the datasite (e.g., "/blog", "/portfolio/2024")
+	Label string // The labels a wildcard domain matched (e.g., "foo" for foo.apps.alice.dev)
 }
 
 // SubdomainMapping handles bidirectional mapping between email hashes and emails
@@ -22,6 +38,7 @@ type SubdomainMapping struct {
 	hashToEmail   map[string]string
 	emailToHash   map[string]string
 	vanityDomains map[string]*VanityDomainConfig // maps vanity domains to config
+	wildcards     map[string]*VanityDomainConfig // maps wildcard suffixes (without "*.") to config
 }
 
 // NewSubdomainMapping creates a new subdomain mapping service
@@ -30,6 +47,7 @@ func NewSubdomainMapping() *SubdomainMapping {
 		hashToEmail:   make(map[string]string),
 		emailToHash:   make(map[string]string),
 		vanityDomains: make(map[string]*VanityDomainConfig),
+		wildcards:     make(map[string]*VanityDomainConfig),
 	}
 }
 
@@ -126,20 +144,90 @@ func (s *SubdomainMapping) AddVanityDomain(domain string, email string, path str
 	}
 }
 
-// GetVanityDomain returns the configuration for a vanity domain
+// AddWildcardDomain adds a wildcard vanity domain mapping (e.g., *.apps.alice.dev).
+// The path may contain {label}, which is replaced with the labels the domain matched.
+func (s *SubdomainMapping) AddWildcardDomain(pattern string, email string, path string) error {
+	suffix, ok := strings.CutPrefix(pattern, "*.")
+	if !ok || !regexHostLabels.MatchString(suffix) {
+		return ErrInvalidWildcardHost
+	}
+	if !isRegistrableSuffix(suffix) {
+		return ErrBroadWildcardHost
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.wildcards[suffix] = &VanityDomainConfig{
+		Email: email,
+		Path:  path,
+	}
+	return nil
+}
+
+// isRegistrableSuffix reports if a wildcard suffix is a registrable domain or under one, so that
+// with the wildcard label it has at least two non-numeric labels below the public suffix.
+// This rejects wildcards like *.net, *.co.uk or *.0.0.1
+func isRegistrableSuffix(suffix string) bool {
+	suffix = strings.ToLower(suffix)
+	if _, err := publicsuffix.EffectiveTLDPlusOne(suffix); err != nil {
+		return false
+	}
+	for _, label := range strings.Split(suffix, ".") {
+		if regexNumericLabel.MatchString(label) {
+			return false
+		}
+	}
+	return true
+}
+
+// GetVanityDomain returns the configuration for a vanity domain.
+// Exact domains are matched first, then the wildcard domain with the longest suffix.
 func (s *SubdomainMapping) GetVanityDomain(domain string) (*VanityDomainConfig, bool) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	config, exists := s.vanityDomains[domain]
-	return config, exists
+	if config, exists := s.vanityDomains[domain]; exists {
+		return config, true
+	}
+
+	return s.matchWildcard(domain)
 }
 
-// RemoveVanityDomain removes a vanity domain mapping
+// matchWildcard returns a copy of the longest matching wildcard config, with the label resolved
+func (s *SubdomainMapping) matchWildcard(domain string) (*VanityDomainConfig, bool) {
+	var match string
+	for suffix := range s.wildcards {
+		if len(suffix) > len(match) && strings.HasSuffix(domain, "."+suffix) {
+			match = suffix
+		}
+	}
+	if match == "" {
+		return nil, false
+	}
+
+	label := strings.TrimSuffix(domain, "."+match)
+	if !regexHostLabels.MatchString(label) {
+		return nil, false
+	}
+
+	config := s.wildcards[match]
+	return &VanityDomainConfig{
+		Email: config.Email,
+		Path:  strings.ReplaceAll(config.Path, WildcardLabel, label),
+		Label: label,
+	}, true
+}
+
+// RemoveVanityDomain removes a vanity domain mapping, or a wildcard one when given *.example.com
 func (s *SubdomainMapping) RemoveVanityDomain(domain string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if suffix, ok := strings.CutPrefix(domain, "*."); ok {
+		delete(s.wildcards, suffix)
+		return
+	}
 	delete(s.vanityDomains, domain)
 }
 
@@ -154,21 +242,32 @@ func (s *SubdomainMapping) ClearVanityDomains(email string) {
 			delete(s.vanityDomains, domain)
 		}
 	}
+	for suffix, config := range s.wildcards {
+		if config.Email == email {
+			delete(s.wildcards, suffix)
+		}
+	}
 }
 
-// GetAllVanityDomains returns all vanity domain mappings
+// GetAllVanityDomains returns all vanity domain mappings, wildcard ones keyed as *.example.com
 func (s *SubdomainMapping) GetAllVanityDomains() map[string]*VanityDomainConfig {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	// Create a copy to avoid race conditions
-	result := make(map[string]*VanityDomainConfig, len(s.vanityDomains))
+	result := make(map[string]*VanityDomainConfig, len(s.vanityDomains)+len(s.wildcards))
 	for domain, config := range s.vanityDomains {
 		result[domain] = &VanityDomainConfig{
 			Email: config.Email,
 			Path:  config.Path,
 		}
 	}
+	for suffix, config := range s.wildcards {
+		result["*."+suffix] = &VanityDomainConfig{
+			Email: config.Email,
+			Path:  config.Path,
+		}
+	}
 
 	return result
 }
@@ -183,6 +282,10 @@ func (s *SubdomainMapping) GetMapping(domain string) *VanityDomainConfig {
 		return config
 	}
 
+	if config, exists := s.matchWildcard(domain); exists {
+		return config
+	}
+
 	return nil
 }
 
@@ -0,0 +1,134 @@
+package datasite
+
+import (
+	"testing"
+
+	"github.com/openmined/syftbox/internal/server/acl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWildcardDomain(t *testing.T) {
+	sm := NewSubdomainMapping()
+	require.NoError(t, sm.AddWildcardDomain("*.apps.alice.dev", "alice@example.com", "/apps/{label}"))
+
+	foo, ok := sm.GetVanityDomain("foo.apps.alice.dev")
+	require.True(t, ok)
+	assert.Equal(t, "alice@example.com", foo.Email)
+	assert.Equal(t, "foo", foo.Label)
+	assert.Equal(t, "/apps/foo", foo.Path)
+
+	bar, ok := sm.GetVanityDomain("bar.apps.alice.dev")
+	require.True(t, ok)
+	assert.Equal(t, "alice@example.com", bar.Email)
+	assert.Equal(t, "bar", bar.Label)
+	assert.Equal(t, "/apps/bar", bar.Path)
+
+	// multi-level labels are captured as a whole
+	nested, ok := sm.GetVanityDomain("v2.foo.apps.alice.dev")
+	require.True(t, ok)
+	assert.Equal(t, "v2.foo", nested.Label)
+
+	// the suffix itself is not matched
+	_, ok = sm.GetVanityDomain("apps.alice.dev")
+	assert.False(t, ok)
+	_, ok = sm.GetVanityDomain("fooapps.alice.dev")
+	assert.False(t, ok)
+}
+
+func TestWildcardDomainPrecedence(t *testing.T) {
+	sm := NewSubdomainMapping()
+	require.NoError(t, sm.AddWildcardDomain("*.alice.dev", "alice@example.com", "/public"))
+	require.NoError(t, sm.AddWildcardDomain("*.apps.alice.dev", "alice@example.com", "/apps/{label}"))
+	sm.AddVanityDomain("www.apps.alice.dev", "alice@example.com", "/www")
+
+	// exact domains win over wildcards
+	config, ok := sm.GetVanityDomain("www.apps.alice.dev")
+	require.True(t, ok)
+	assert.Equal(t, "/www", config.Path)
+	assert.Empty(t, config.Label)
+
+	// the longest suffix wins
+	config, ok = sm.GetVanityDomain("foo.apps.alice.dev")
+	require.True(t, ok)
+	assert.Equal(t, "/apps/foo", config.Path)
+
+	config, ok = sm.GetVanityDomain("blog.alice.dev")
+	require.True(t, ok)
+	assert.Equal(t, "/public", config.Path)
+	assert.Equal(t, "blog", config.Label)
+}
+
+func TestWildcardDomainInvalid(t *testing.T) {
+	sm := NewSubdomainMapping()
+	assert.ErrorIs(t, sm.AddWildcardDomain("apps.alice.dev", "alice@example.com", "/"), ErrInvalidWildcardHost)
+	assert.ErrorIs(t, sm.AddWildcardDomain("*.", "alice@example.com", "/"), ErrInvalidWildcardHost)
+	assert.ErrorIs(t, sm.AddWildcardDomain("*.*.alice.dev", "alice@example.com", "/"), ErrInvalidWildcardHost)
+
+	// too broad, it would capture the domains of everyone else
+	for _, pattern := range []string{"*.net", "*.co.uk", "*.0.0.1", "*.168.1.1", "*.localhost"} {
+		assert.ErrorIs(t, sm.AddWildcardDomain(pattern, "alice@example.com", "/"), ErrBroadWildcardHost, pattern)
+	}
+
+	require.NoError(t, sm.AddWildcardDomain("*.apps.alice.dev", "alice@example.com", "/apps/{label}"))
+	_, ok := sm.GetVanityDomain("..apps.alice.dev")
+	assert.False(t, ok)
+	_, ok = sm.GetVanityDomain("-foo.apps.alice.dev")
+	assert.False(t, ok)
+}
+
+func TestWildcardDomainClearAndRemove(t *testing.T) {
+	sm := NewSubdomainMapping()
+	require.NoError(t, sm.AddWildcardDomain("*.apps.alice.dev", "alice@example.com", "/apps"))
+	require.NoError(t, sm.AddWildcardDomain("*.apps.bob.dev", "bob@example.com", "/apps"))
+
+	all := sm.GetAllVanityDomains()
+	assert.Contains(t, all, "*.apps.alice.dev")
+	assert.Contains(t, all, "*.apps.bob.dev")
+
+	sm.ClearVanityDomains("alice@example.com")
+	_, ok := sm.GetVanityDomain("foo.apps.alice.dev")
+	assert.False(t, ok)
+	_, ok = sm.GetVanityDomain("foo.apps.bob.dev")
+	assert.True(t, ok)
+
+	sm.RemoveVanityDomain("*.apps.bob.dev")
+	_, ok = sm.GetVanityDomain("foo.apps.bob.dev")
+	assert.False(t, ok)
+}
+
+func TestLoadWildcardDomains(t *testing.T) {
+	blobs := &fakeBlobs{objects: make(map[string][]byte)}
+	svc := NewDatasiteService(blobs, acl.NewACLService(blobs), "syftbox.net", &Config{})
+
+	blobs.objects["alice@example.com/settings.yaml"] = []byte(`domains:
+  "*.apps.alice.dev": /apps/{label}
+  "*.syftbox.net": /public
+  "*.www.syftbox.net": /public
+  "*.4b5f6e3a2c1d0e9f.syftbox.net": /public
+  "*.ff8d9819fc0e12bf.syftbox.net": /sites/{label}
+`)
+	require.NoError(t, svc.ReloadVanityDomains("alice@example.com"))
+
+	mapping := svc.GetSubdomainMapping()
+	config, ok := mapping.GetVanityDomain("foo.apps.alice.dev")
+	require.True(t, ok)
+	assert.Equal(t, "/apps/foo", config.Path)
+
+	// the hash subdomain keeps its exact mapping
+	config, ok = mapping.GetVanityDomain("ff8d9819fc0e12bf.syftbox.net")
+	require.True(t, ok)
+	assert.Equal(t, "/public", config.Path)
+
+	config, ok = mapping.GetVanityDomain("docs.ff8d9819fc0e12bf.syftbox.net")
+	require.True(t, ok)
+	assert.Equal(t, "/sites/docs", config.Path)
+
+	// the main domain can't be claimed with a wildcard, nor anything under it but the own hash subdomain
+	_, ok = mapping.GetVanityDomain("4b5f6e3a2c1d0e9f.syftbox.net")
+	assert.False(t, ok)
+	_, ok = mapping.GetVanityDomain("foo.4b5f6e3a2c1d0e9f.syftbox.net")
+	assert.False(t, ok)
+	_, ok = mapping.GetVanityDomain("foo.www.syftbox.net")
+	assert.False(t, ok)
+}
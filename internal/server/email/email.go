@@ -5,6 +5,8 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"net/http"
+	"sync/atomic"
 
 	"github.com/sendgrid/sendgrid-go"
 	"github.com/sendgrid/sendgrid-go/helpers/mail"
@@ -17,15 +19,22 @@ var (
 )
 
 type EmailService struct {
-	config *Config
+	config atomic.Pointer[Config]
 }
 
 func NewEmailService(config *Config) *EmailService {
-	return &EmailService{config: config}
+	svc := &EmailService{}
+	svc.config.Store(config)
+	return svc
+}
+
+// SetConfig replaces the config of the emails sent from now on
+func (s *EmailService) SetConfig(config *Config) {
+	s.config.Store(config)
 }
 
 func (s *EmailService) IsEnabled() bool {
-	return s.config.Enabled
+	return s.config.Load().Enabled
 }
 
 func (s *EmailService) Send(ctx context.Context, data *EmailInfo) error {
@@ -54,7 +63,7 @@ func (s *EmailService) Send(ctx context.Context, data *EmailInfo) error {
 	to := mail.NewEmail(data.ToName, data.ToEmail)
 
 	message := mail.NewSingleEmail(from, data.Subject, to, "", data.HTMLBody)
-	client := sendgrid.NewSendClient(s.config.SendgridAPIKey)
+	client := sendgrid.NewSendClient(s.config.Load().SendgridAPIKey)
 
 	resp, err := client.SendWithContext(ctx, message)
 	if err != nil {
@@ -66,4 +75,23 @@ func (s *EmailService) Send(ctx context.Context, data *EmailInfo) error {
 	return nil
 }
 
+// Ping checks that the email provider is reachable and accepts the API key
+func (s *EmailService) Ping(ctx context.Context) error {
+	if !s.IsEnabled() {
+		return ErrEmailDisabled
+	}
+
+	req := sendgrid.GetRequest(s.config.Load().SendgridAPIKey, "/v3/scopes", "")
+	resp, err := sendgrid.MakeRequestWithContext(ctx, req)
+	if err != nil {
+		return fmt.Errorf("sendgrid: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("sendgrid: unexpected status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
 var _ Service = (*EmailService)(nil)
@@ -2,19 +2,31 @@ package acl
 
 import (
 	"net/http"
+	"sync"
 
 	"github.com/gin-gonic/gin"
 	"github.com/openmined/syftbox/internal/server/acl"
+	"github.com/openmined/syftbox/internal/server/blob"
+	"github.com/openmined/syftbox/internal/server/datasite"
 	"github.com/openmined/syftbox/internal/server/handlers/api"
 )
 
 type ACLHandler struct {
-	aclSvc *acl.ACLService
+	aclSvc    *acl.ACLService
+	blob      blob.Service
+	datasites *datasite.DatasiteService
+	keys      blob.KeyRules
+
+	// batches are applied one at a time, so that a rollback never undoes another batch
+	batchMu sync.Mutex
 }
 
-func NewACLHandler(svc *acl.ACLService) *ACLHandler {
+func NewACLHandler(svc *acl.ACLService, blobSvc blob.Service, datasites *datasite.DatasiteService, keys blob.KeyRules) *ACLHandler {
 	return &ACLHandler{
-		aclSvc: svc,
+		aclSvc:    svc,
+		blob:      blobSvc,
+		datasites: datasites,
+		keys:      keys,
 	}
 }
 
@@ -0,0 +1,272 @@
+package acl
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/openmined/syftbox/internal/aclspec"
+	"github.com/openmined/syftbox/internal/server/acl"
+	"github.com/openmined/syftbox/internal/server/blob"
+	"github.com/openmined/syftbox/internal/server/datasite"
+	"github.com/openmined/syftbox/internal/server/handlers/api"
+)
+
+// maxACLBatchFiles caps the number of ACL files changed by one batch
+const maxACLBatchFiles = 100
+
+// aclChange is a validated file of a batch
+type aclChange struct {
+	*ACLFileChange
+	ruleSet *aclspec.RuleSet // nil for a delete
+}
+
+// aclSnapshot is the content of an ACL file before the batch, to restore it on failure
+type aclSnapshot struct {
+	path    string
+	exists  bool
+	content []byte
+}
+
+// ApplyBatch validates a set of ACL file changes and applies them all at once.
+// If any file fails validation nothing is changed, and the errors of every failed file are returned.
+// The files are written to the blob storage first, as it's the ground truth, and the rulesets are
+// swapped into the ACL tree together only once all writes succeeded.
+func (h *ACLHandler) ApplyBatch(ctx *gin.Context) {
+	var req ACLBatchRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		api.AbortWithError(ctx, http.StatusBadRequest, api.CodeInvalidRequest, fmt.Errorf("failed to bind json: %w", err))
+		return
+	}
+
+	if len(req.Files) > maxACLBatchFiles {
+		api.AbortWithError(ctx, http.StatusBadRequest, api.CodeInvalidRequest, fmt.Errorf("too many files: %d, max %d", len(req.Files), maxACLBatchFiles))
+		return
+	}
+
+	user := ctx.GetString("user")
+
+	h.batchMu.Lock()
+	defer h.batchMu.Unlock()
+
+	changes, release, fileErrs := h.validateBatch(&acl.User{ID: user}, req.Files)
+	// gives back the slots of the new datasites when the batch is rejected or fails to write
+	defer release()
+	if len(fileErrs) > 0 {
+		status := http.StatusBadRequest
+		for _, fileErr := range fileErrs {
+			if fileErr.Code == api.CodeAccessDenied || fileErr.Code == api.CodeDatasiteLimitReached {
+				status = http.StatusForbidden
+				break
+			}
+		}
+		ctx.Abort()
+		ctx.PureJSON(status, &ACLBatchError{
+			Code:    api.CodeACLInvalid,
+			Message: fmt.Sprintf("%d of %d files failed validation, no changes applied", len(fileErrs), len(req.Files)),
+			Files:   fileErrs,
+		})
+		return
+	}
+
+	reqCtx := ctx.Request.Context()
+	snapshots, err := h.snapshot(reqCtx, changes)
+	if err != nil {
+		api.AbortWithError(ctx, http.StatusInternalServerError, api.CodeBlobGetFailed, err)
+		return
+	}
+
+	results, err := h.writeBatch(reqCtx, changes)
+	if err != nil {
+		// rollback on a fresh context, the request's might be the reason the write failed
+		h.rollback(context.Background(), snapshots[:len(results)+1])
+		api.AbortWithError(ctx, http.StatusInternalServerError, api.CodeACLUpdateFailed, fmt.Errorf("no changes applied: %w", err))
+		return
+	}
+
+	var ruleSets []*aclspec.RuleSet
+	var removed []string
+	for _, change := range changes {
+		if change.Delete {
+			removed = append(removed, change.Path)
+		} else {
+			ruleSets = append(ruleSets, change.ruleSet)
+		}
+	}
+	if err := h.aclSvc.ApplyRuleSets(ruleSets, removed); err != nil {
+		// if this error happens, there's a pretty serious bug in the acl service
+		h.rollback(context.Background(), snapshots)
+		api.AbortWithError(ctx, http.StatusInternalServerError, api.CodeACLUpdateFailed, fmt.Errorf("failed to update rulesets: %w", err))
+		return
+	}
+
+	slog.Info("acl batch applied", "user", user, "files", len(changes), "updated", len(ruleSets), "removed", len(removed))
+	ctx.PureJSON(http.StatusOK, &ACLBatchResponse{Files: results})
+}
+
+// validateBatch checks every file of the batch, and returns the errors of all files that failed.
+// The datasites of the files are admitted along the way, the returned func releases them once the batch is done.
+func (h *ACLHandler) validateBatch(user *acl.User, files []*ACLFileChange) ([]*aclChange, func(), []*ACLFileError) {
+	changes := make([]*aclChange, 0, len(files))
+	var fileErrs []*ACLFileError
+	seen := make(map[string]struct{}, len(files))
+
+	var admitted []string
+	release := func() {
+		for _, owner := range admitted {
+			h.datasites.Release(owner)
+		}
+	}
+
+	fail := func(path string, code string, err error) {
+		fileErrs = append(fileErrs, &ACLFileError{Path: path, Code: code, Message: err.Error()})
+	}
+
+	for _, file := range files {
+		if err := h.keys.Validate(file.Path); err != nil {
+			code := api.CodeDatasiteInvalidPath
+			if errors.Is(err, blob.ErrKeyNotNFC) {
+				code = api.CodeDatasiteKeyNotNFC
+			}
+			fail(file.Path, code, err)
+			continue
+		}
+
+		if !(datasite.IsValidPath(file.Path) && aclspec.IsACLFile(file.Path)) {
+			fail(file.Path, api.CodeDatasiteInvalidPath, fmt.Errorf("invalid ruleset path: %s", file.Path))
+			continue
+		}
+
+		if _, ok := seen[file.Path]; ok {
+			fail(file.Path, api.CodeInvalidRequest, fmt.Errorf("duplicate path in batch: %s", file.Path))
+			continue
+		}
+		seen[file.Path] = struct{}{}
+
+		// check if user has admin rights
+		if err := h.checkPermissions(file.Path, user, acl.AccessAdmin); err != nil {
+			fail(file.Path, api.CodeAccessDenied, err)
+			continue
+		}
+
+		if file.Delete {
+			if _, ok := h.blob.Index().Get(file.Path); !ok {
+				fail(file.Path, api.CodeBlobNotFound, fmt.Errorf("ruleset not found: %s", file.Path))
+				continue
+			}
+			changes = append(changes, &aclChange{ACLFileChange: file})
+			continue
+		}
+
+		owner := datasite.GetOwner(file.Path)
+		if err := h.datasites.Admit(owner); err != nil {
+			code := api.CodeDatasiteLimitReached
+			if errors.Is(err, datasite.ErrDatasiteNotNormalized) {
+				code = api.CodeDatasiteInvalidPath
+			}
+			fail(file.Path, code, err)
+			continue
+		}
+		admitted = append(admitted, owner)
+
+		ruleSet, err := h.aclSvc.LoadRuleSet(file.Path, strings.NewReader(file.Content))
+		if err != nil {
+			fail(file.Path, api.CodeACLInvalid, fmt.Errorf("failed to read ruleset: %w", err))
+			continue
+		}
+
+		if err := acl.ValidateRuleSet(ruleSet); err != nil {
+			fail(file.Path, api.CodeACLInvalid, err)
+			continue
+		}
+
+		changes = append(changes, &aclChange{ACLFileChange: file, ruleSet: ruleSet})
+	}
+
+	return changes, release, fileErrs
+}
+
+// snapshot reads the current content of the files changed by the batch
+func (h *ACLHandler) snapshot(ctx context.Context, changes []*aclChange) ([]*aclSnapshot, error) {
+	snapshots := make([]*aclSnapshot, 0, len(changes))
+	for _, change := range changes {
+		snap := &aclSnapshot{path: change.Path}
+		if _, ok := h.blob.Index().Get(change.Path); ok {
+			obj, err := h.blob.Backend().GetObject(ctx, change.Path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get object %s: %w", change.Path, err)
+			}
+			snap.content, err = io.ReadAll(obj.Body)
+			obj.Body.Close()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read object %s: %w", change.Path, err)
+			}
+			snap.exists = true
+		}
+		snapshots = append(snapshots, snap)
+	}
+	return snapshots, nil
+}
+
+// writeBatch writes the changes to the blob storage in order, and stops at the first failure.
+// The results are those of the changes written before the failure.
+func (h *ACLHandler) writeBatch(ctx context.Context, changes []*aclChange) ([]*ACLFileResult, error) {
+	results := make([]*ACLFileResult, 0, len(changes))
+	for _, change := range changes {
+		if change.Delete {
+			if _, err := h.blob.Backend().DeleteObject(ctx, change.Path); err != nil {
+				return results, fmt.Errorf("failed to delete object %s: %w", change.Path, err)
+			}
+			results = append(results, &ACLFileResult{Path: change.Path, Deleted: true})
+			continue
+		}
+
+		resp, err := h.blob.Backend().PutObject(ctx, &blob.PutObjectParams{
+			Key:  change.Path,
+			Size: int64(len(change.Content)),
+			Body: strings.NewReader(change.Content),
+		})
+		if err != nil {
+			return results, fmt.Errorf("failed to put object %s: %w", change.Path, err)
+		}
+		results = append(results, &ACLFileResult{Path: change.Path, ETag: resp.ETag, Revision: resp.Revision})
+	}
+	return results, nil
+}
+
+// rollback restores the files to their snapshots, in reverse order
+func (h *ACLHandler) rollback(ctx context.Context, snapshots []*aclSnapshot) {
+	var errs []error
+	for i := len(snapshots) - 1; i >= 0; i-- {
+		snap := snapshots[i]
+		var err error
+		if snap.exists {
+			_, err = h.blob.Backend().PutObject(ctx, &blob.PutObjectParams{
+				Key:  snap.path,
+				Size: int64(len(snap.content)),
+				Body: bytes.NewReader(snap.content),
+			})
+		} else {
+			_, err = h.blob.Backend().DeleteObject(ctx, snap.path)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", snap.path, err))
+		}
+	}
+	if len(errs) > 0 {
+		slog.Error("acl batch rollback", "error", errors.Join(errs...))
+	}
+}
+
+func (h *ACLHandler) checkPermissions(key string, user *acl.User, access acl.AccessLevel) error {
+	if datasite.IsOwner(key, user.ID) {
+		return nil
+	}
+	return h.aclSvc.CanAccess(acl.NewRequest(key, user, access))
+}
@@ -0,0 +1,242 @@
+package acl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/openmined/syftbox/internal/server/acl"
+	"github.com/openmined/syftbox/internal/server/blob"
+	"github.com/openmined/syftbox/internal/server/datasite"
+	"github.com/openmined/syftbox/internal/server/handlers/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	publicACL  = "rules:\n  - pattern: '**'\n    access:\n      read: ['*']\n"
+	privateACL = "rules:\n  - pattern: '**'\n    access:\n      read: []\n"
+	invalidACL = "rules: [pattern: '**'\n"
+)
+
+type fakeBlobService struct {
+	backend *fakeBackend
+}
+
+func (s *fakeBlobService) Backend() blob.IBlobBackend                    { return s.backend }
+func (s *fakeBlobService) Index() blob.IBlobIndex                        { return &fakeIndex{backend: s.backend} }
+func (s *fakeBlobService) ReadIndex() blob.IBlobIndex                    { return s.Index() }
+func (s *fakeBlobService) OnBlobChange(callback blob.BlobChangeCallback) {}
+
+type fakeBackend struct {
+	blob.IBlobBackend
+	objects map[string][]byte
+	writes  int
+	failPut string // key for which PutObject fails
+}
+
+func (b *fakeBackend) GetObject(ctx context.Context, key string) (*blob.GetObjectResponse, error) {
+	content, ok := b.objects[key]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return &blob.GetObjectResponse{Body: io.NopCloser(bytes.NewReader(content)), Size: int64(len(content))}, nil
+}
+
+func (b *fakeBackend) PutObject(ctx context.Context, params *blob.PutObjectParams) (*blob.PutObjectResponse, error) {
+	b.writes++
+	if params.Key == b.failPut {
+		return nil, errors.New("storage unavailable")
+	}
+	content, err := io.ReadAll(params.Body)
+	if err != nil {
+		return nil, err
+	}
+	b.objects[params.Key] = content
+	return &blob.PutObjectResponse{Key: params.Key, Size: int64(len(content))}, nil
+}
+
+func (b *fakeBackend) DeleteObject(ctx context.Context, key string) (bool, error) {
+	b.writes++
+	delete(b.objects, key)
+	return true, nil
+}
+
+type fakeIndex struct {
+	blob.IBlobIndex
+	backend *fakeBackend
+}
+
+func (i *fakeIndex) Get(key string) (*blob.BlobInfo, bool) {
+	if _, ok := i.backend.objects[key]; !ok {
+		return nil, false
+	}
+	return &blob.BlobInfo{Key: key}, true
+}
+
+func (i *fakeIndex) Count() int { return len(i.backend.objects) }
+
+func (i *fakeIndex) FilterByPrefix(prefix string) ([]*blob.BlobInfo, error) {
+	var blobs []*blob.BlobInfo
+	for key := range i.backend.objects {
+		if strings.HasPrefix(key, prefix) {
+			blobs = append(blobs, &blob.BlobInfo{Key: key})
+		}
+	}
+	return blobs, nil
+}
+
+func newBatchTestRouter(t *testing.T, objects map[string][]byte) (*gin.Engine, *fakeBackend, *acl.ACLService) {
+	return newBatchTestRouterWithConfig(t, objects, &datasite.Config{})
+}
+
+func newBatchTestRouterWithConfig(t *testing.T, objects map[string][]byte, config *datasite.Config) (*gin.Engine, *fakeBackend, *acl.ACLService) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	backend := &fakeBackend{objects: objects}
+	blobSvc := &fakeBlobService{backend: backend}
+	aclSvc := acl.NewACLService(blobSvc)
+	h := NewACLHandler(aclSvc, blobSvc, datasite.NewDatasiteService(blobSvc, aclSvc, "", config), blob.KeyRules{})
+
+	r := gin.New()
+	r.Use(func(ctx *gin.Context) {
+		ctx.Set("user", ctx.GetHeader("X-User"))
+	})
+	r.POST("/acl/batch", h.ApplyBatch)
+	return r, backend, aclSvc
+}
+
+func postBatch(t *testing.T, r *gin.Engine, user string, files ...*ACLFileChange) *httptest.ResponseRecorder {
+	t.Helper()
+	body, err := json.Marshal(&ACLBatchRequest{Files: files})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/acl/batch", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-User", user)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func canRead(aclSvc *acl.ACLService, user string, path string) bool {
+	return aclSvc.CanAccess(acl.NewRequest(path, &acl.User{ID: user}, acl.AccessRead)) == nil
+}
+
+func TestApplyBatch(t *testing.T) {
+	r, backend, aclSvc := newBatchTestRouter(t, map[string][]byte{})
+
+	w := postBatch(t, r, "alice@example.com",
+		&ACLFileChange{Path: "alice@example.com/public/syft.pub.yaml", Content: publicACL},
+		&ACLFileChange{Path: "alice@example.com/shared/syft.pub.yaml", Content: publicACL},
+	)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp ACLBatchResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Files, 2)
+	assert.Equal(t, "alice@example.com/public/syft.pub.yaml", resp.Files[0].Path)
+
+	assert.Equal(t, []byte(publicACL), backend.objects["alice@example.com/public/syft.pub.yaml"])
+	assert.True(t, canRead(aclSvc, "bob@example.com", "alice@example.com/public/file.txt"))
+	assert.True(t, canRead(aclSvc, "bob@example.com", "alice@example.com/shared/file.txt"))
+
+	// delete one and restrict the other in the same batch
+	w = postBatch(t, r, "alice@example.com",
+		&ACLFileChange{Path: "alice@example.com/public/syft.pub.yaml", Delete: true},
+		&ACLFileChange{Path: "alice@example.com/shared/syft.pub.yaml", Content: privateACL},
+	)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	assert.NotContains(t, backend.objects, "alice@example.com/public/syft.pub.yaml")
+	assert.False(t, canRead(aclSvc, "bob@example.com", "alice@example.com/public/file.txt"))
+	assert.False(t, canRead(aclSvc, "bob@example.com", "alice@example.com/shared/file.txt"))
+}
+
+func TestApplyBatchInvalidFileBlocksAll(t *testing.T) {
+	r, backend, aclSvc := newBatchTestRouter(t, map[string][]byte{})
+
+	w := postBatch(t, r, "alice@example.com",
+		&ACLFileChange{Path: "alice@example.com/public/syft.pub.yaml", Content: publicACL},
+		&ACLFileChange{Path: "alice@example.com/broken/syft.pub.yaml", Content: invalidACL},
+		&ACLFileChange{Path: "alice@example.com/not-an-acl.yaml", Content: publicACL},
+		&ACLFileChange{Path: "alice@example.com/shared/syft.pub.yaml", Content: publicACL},
+	)
+	require.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+
+	var resp ACLBatchError
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, api.CodeACLInvalid, resp.Code)
+	require.Len(t, resp.Files, 2)
+	assert.Equal(t, "alice@example.com/broken/syft.pub.yaml", resp.Files[0].Path)
+	assert.Equal(t, api.CodeACLInvalid, resp.Files[0].Code)
+	assert.Equal(t, "alice@example.com/not-an-acl.yaml", resp.Files[1].Path)
+	assert.Equal(t, api.CodeDatasiteInvalidPath, resp.Files[1].Code)
+
+	// the valid files were not applied either
+	assert.Zero(t, backend.writes)
+	assert.Empty(t, backend.objects)
+	assert.False(t, canRead(aclSvc, "bob@example.com", "alice@example.com/public/file.txt"))
+	assert.False(t, canRead(aclSvc, "bob@example.com", "alice@example.com/shared/file.txt"))
+}
+
+func TestApplyBatchRejectedReleasesDatasites(t *testing.T) {
+	r, backend, _ := newBatchTestRouterWithConfig(t, map[string][]byte{}, &datasite.Config{MaxDatasites: 1})
+
+	// the valid file admitted the new datasite before the invalid one failed the batch
+	w := postBatch(t, r, "alice@example.com",
+		&ACLFileChange{Path: "alice@example.com/public/syft.pub.yaml", Content: publicACL},
+		&ACLFileChange{Path: "alice@example.com/broken/syft.pub.yaml", Content: invalidACL},
+	)
+	require.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+
+	// so the slot is still free for another datasite
+	w = postBatch(t, r, "bob@example.com",
+		&ACLFileChange{Path: "bob@example.com/public/syft.pub.yaml", Content: publicACL},
+	)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, backend.objects, "bob@example.com/public/syft.pub.yaml")
+
+	// and taken once the batch is written
+	w = postBatch(t, r, "alice@example.com",
+		&ACLFileChange{Path: "alice@example.com/public/syft.pub.yaml", Content: publicACL},
+	)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestApplyBatchAccessDenied(t *testing.T) {
+	r, backend, _ := newBatchTestRouter(t, map[string][]byte{})
+
+	w := postBatch(t, r, "bob@example.com",
+		&ACLFileChange{Path: "bob@example.com/public/syft.pub.yaml", Content: publicACL},
+		&ACLFileChange{Path: "alice@example.com/public/syft.pub.yaml", Content: publicACL},
+	)
+	require.Equal(t, http.StatusForbidden, w.Code, w.Body.String())
+	assert.Zero(t, backend.writes)
+}
+
+func TestApplyBatchRollbackOnWriteFailure(t *testing.T) {
+	r, backend, aclSvc := newBatchTestRouter(t, map[string][]byte{
+		"alice@example.com/shared/syft.pub.yaml": []byte(privateACL),
+	})
+	backend.failPut = "alice@example.com/public/syft.pub.yaml"
+
+	w := postBatch(t, r, "alice@example.com",
+		&ACLFileChange{Path: "alice@example.com/shared/syft.pub.yaml", Content: publicACL},
+		&ACLFileChange{Path: "alice@example.com/public/syft.pub.yaml", Content: publicACL},
+	)
+	require.Equal(t, http.StatusInternalServerError, w.Code, w.Body.String())
+
+	// the file written before the failure is restored, and the tree is untouched
+	assert.Equal(t, []byte(privateACL), backend.objects["alice@example.com/shared/syft.pub.yaml"])
+	assert.NotContains(t, backend.objects, "alice@example.com/public/syft.pub.yaml")
+	assert.False(t, canRead(aclSvc, "bob@example.com", "alice@example.com/shared/file.txt"))
+}
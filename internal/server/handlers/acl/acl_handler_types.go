@@ -14,3 +14,38 @@ type ACLCheckResponse struct {
 	Path  string `json:"path"`
 	Level string `json:"level"`
 }
+
+type ACLBatchRequest struct {
+	Files []*ACLFileChange `json:"files" binding:"required,min=1,dive"`
+}
+
+// ACLFileChange writes an ACL file with the given content, or deletes it
+type ACLFileChange struct {
+	Path    string `json:"path" binding:"required"`
+	Content string `json:"content"`
+	Delete  bool   `json:"delete"`
+}
+
+type ACLBatchResponse struct {
+	Files []*ACLFileResult `json:"files"`
+}
+
+type ACLFileResult struct {
+	Path     string `json:"path"`
+	ETag     string `json:"etag,omitempty"`
+	Revision int64  `json:"revision,omitempty"`
+	Deleted  bool   `json:"deleted,omitempty"`
+}
+
+// ACLBatchError is returned when a batch is rejected, with the errors of every file that failed validation
+type ACLBatchError struct {
+	Code    string          `json:"code"`
+	Message string          `json:"error"`
+	Files   []*ACLFileError `json:"files,omitempty"`
+}
+
+type ACLFileError struct {
+	Path    string `json:"path"`
+	Code    string `json:"code"`
+	Message string `json:"error"`
+}
@@ -0,0 +1,191 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/openmined/syftbox/internal/server/accesslog"
+	"github.com/openmined/syftbox/internal/server/acl"
+	"github.com/openmined/syftbox/internal/server/blob"
+	"github.com/openmined/syftbox/internal/server/datasite"
+	"github.com/openmined/syftbox/internal/server/handlers/api"
+)
+
+const (
+	auditContentType  = "application/x-ndjson"
+	auditCursorHeader = "X-Next-Cursor"
+)
+
+type AdminHandler struct {
+	features  *datasite.FeatureFlags
+	accessLog *accesslog.AccessLogger
+	blob      *blob.BlobService
+}
+
+func New(features *datasite.FeatureFlags, accessLog *accesslog.AccessLogger, blob *blob.BlobService) *AdminHandler {
+	return &AdminHandler{
+		features:  features,
+		accessLog: accessLog,
+		blob:      blob,
+	}
+}
+
+// GetFeatures returns the state of the features of a datasite
+func (h *AdminHandler) GetFeatures(ctx *gin.Context) {
+	ds := ctx.Param("datasite")
+	if !datasite.IsValidDatasite(ds) {
+		api.AbortWithError(ctx, http.StatusBadRequest, api.CodeDatasiteInvalidPath, fmt.Errorf("invalid datasite %q", ds))
+		return
+	}
+
+	ctx.PureJSON(http.StatusOK, h.features.Get(ds))
+}
+
+// UpdateFeatures overrides the features of a datasite. Features not in the request are left as-is.
+func (h *AdminHandler) UpdateFeatures(ctx *gin.Context) {
+	ds := ctx.Param("datasite")
+	if !datasite.IsValidDatasite(ds) {
+		api.AbortWithError(ctx, http.StatusBadRequest, api.CodeDatasiteInvalidPath, fmt.Errorf("invalid datasite %q", ds))
+		return
+	}
+
+	var req FeaturesUpdateRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		api.AbortWithError(ctx, http.StatusBadRequest, api.CodeInvalidRequest, fmt.Errorf("failed to bind json: %w", err))
+		return
+	}
+
+	// validate everything first, so that a bad request changes nothing
+	features := make(map[datasite.Feature]*bool, len(req))
+	for name, enabled := range req {
+		feature, err := datasite.ParseFeature(name)
+		if err != nil {
+			api.AbortWithError(ctx, http.StatusBadRequest, api.CodeInvalidRequest, err)
+			return
+		}
+		features[feature] = enabled
+	}
+
+	for feature, enabled := range features {
+		if err := h.features.Set(ds, feature, enabled); err != nil {
+			api.AbortWithError(ctx, http.StatusInternalServerError, api.CodeInternalError, err)
+			return
+		}
+	}
+
+	slog.Info("datasite features updated", "datasite", ds, "admin", ctx.GetString("user"), "features", h.features.Get(ds).Features)
+	if logger := accesslog.GetAccessLogger(ctx); logger != nil {
+		logger.LogAccess(ctx, ctx.Request.URL.Path, accesslog.AccessTypeAdmin, acl.AccessAdmin, true, "")
+	}
+	ctx.PureJSON(http.StatusOK, h.features.Get(ds))
+}
+
+// ListPresigns returns the unexpired presigned urls issued for the blobs of a datasite
+func (h *AdminHandler) ListPresigns(ctx *gin.Context) {
+	ds := ctx.Param("datasite")
+	if !datasite.IsValidDatasite(ds) {
+		api.AbortWithError(ctx, http.StatusBadRequest, api.CodeDatasiteInvalidPath, fmt.Errorf("invalid datasite %q", ds))
+		return
+	}
+
+	ctx.PureJSON(http.StatusOK, &PresignsResponse{
+		URLs:      h.blob.Presigns().List(ds),
+		Revocable: h.blob.RevokesPresigns(),
+	})
+}
+
+// RevokePresigns revokes presigned urls of a datasite, all of them when no id is given.
+// Only the urls the server serves itself are rejected once revoked, those of S3 stay valid
+// until they expire, or the access key of the bucket is rotated.
+func (h *AdminHandler) RevokePresigns(ctx *gin.Context) {
+	ds := ctx.Param("datasite")
+	if !datasite.IsValidDatasite(ds) {
+		api.AbortWithError(ctx, http.StatusBadRequest, api.CodeDatasiteInvalidPath, fmt.Errorf("invalid datasite %q", ds))
+		return
+	}
+
+	var req PresignsRevokeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		api.AbortWithError(ctx, http.StatusBadRequest, api.CodeInvalidRequest, fmt.Errorf("failed to bind json: %w", err))
+		return
+	}
+
+	revoked := h.blob.Presigns().Revoke(ds, req.IDs)
+	revocable := h.blob.RevokesPresigns()
+
+	slog.Info("presigned urls revoked", "datasite", ds, "admin", ctx.GetString("user"), "count", len(revoked), "revocable", revocable)
+	if logger := accesslog.GetAccessLogger(ctx); logger != nil {
+		logger.LogAccess(ctx, ctx.Request.URL.Path, accesslog.AccessTypeAdmin, acl.AccessAdmin, true, "")
+	}
+	ctx.PureJSON(http.StatusOK, &PresignsResponse{
+		URLs:      revoked,
+		Revocable: revocable,
+	})
+}
+
+// ExportAuditLog streams the access log entries of all users as JSON lines, oldest first.
+// The X-Next-Cursor header is set when there are more entries to export.
+func (h *AdminHandler) ExportAuditLog(ctx *gin.Context) {
+	var req AuditExportRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		api.AbortWithError(ctx, http.StatusBadRequest, api.CodeInvalidRequest, fmt.Errorf("failed to bind query: %w", err))
+		return
+	}
+
+	types := make([]accesslog.AccessType, 0, len(req.Types))
+	for _, name := range req.Types {
+		accessType, err := parseAccessType(name)
+		if err != nil {
+			api.AbortWithError(ctx, http.StatusBadRequest, api.CodeInvalidRequest, err)
+			return
+		}
+		types = append(types, accessType)
+	}
+
+	if !req.Since.IsZero() && !req.Until.IsZero() && !req.Until.After(req.Since) {
+		api.AbortWithError(ctx, http.StatusBadRequest, api.CodeInvalidRequest, fmt.Errorf("until must be after since"))
+		return
+	}
+
+	entries, next, err := h.accessLog.Export(&accesslog.ExportFilter{
+		Types:  types,
+		Since:  req.Since,
+		Until:  req.Until,
+		Cursor: req.Cursor,
+		Limit:  req.Limit,
+	})
+	if errors.Is(err, accesslog.ErrInvalidCursor) {
+		api.AbortWithError(ctx, http.StatusBadRequest, api.CodeInvalidRequest, err)
+		return
+	} else if err != nil {
+		api.AbortWithError(ctx, http.StatusInternalServerError, api.CodeInternalError, err)
+		return
+	}
+
+	if next != "" {
+		ctx.Header(auditCursorHeader, next)
+	}
+	ctx.Header("Content-Type", auditContentType)
+	ctx.Status(http.StatusOK)
+
+	enc := json.NewEncoder(ctx.Writer)
+	for _, entry := range entries {
+		if err := enc.Encode(entry); err != nil {
+			slog.Warn("audit log export", "error", err)
+			return
+		}
+	}
+}
+
+func parseAccessType(name string) (accesslog.AccessType, error) {
+	switch accessType := accesslog.AccessType(name); accessType {
+	case accesslog.AccessTypeRead, accesslog.AccessTypeWrite, accesslog.AccessTypeAdmin, accesslog.AccessTypeDeny:
+		return accessType, nil
+	default:
+		return "", fmt.Errorf("unknown access type %q", name)
+	}
+}
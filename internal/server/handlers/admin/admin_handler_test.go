@@ -0,0 +1,363 @@
+package admin
+
+import (
+	"bufio"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
+	"github.com/openmined/syftbox/internal/db"
+	"github.com/openmined/syftbox/internal/server/accesslog"
+	"github.com/openmined/syftbox/internal/server/auth"
+	"github.com/openmined/syftbox/internal/server/blob"
+	"github.com/openmined/syftbox/internal/server/datasite"
+	"github.com/openmined/syftbox/internal/server/middlewares"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRouter(t *testing.T) (*gin.Engine, *datasite.FeatureFlags, string) {
+	t.Helper()
+	sqliteDb, err := db.NewSqliteDB(db.WithPath(filepath.Join(t.TempDir(), "test.db")), db.WithMaxOpenConns(1))
+	require.NoError(t, err)
+	t.Cleanup(func() { sqliteDb.Close() })
+
+	blobSvc, err := blob.NewBlobService(&blob.S3Config{
+		Backend:   blob.BackendFilesystem,
+		Dir:       t.TempDir(),
+		PublicURL: "http://localhost:8080",
+	}, sqliteDb)
+	require.NoError(t, err)
+	require.NoError(t, blobSvc.Start(t.Context()))
+
+	return newTestRouterWithBlob(t, sqliteDb, blobSvc)
+}
+
+func newTestRouterWithBlob(t *testing.T, sqliteDb *sqlx.DB, blobSvc *blob.BlobService) (*gin.Engine, *datasite.FeatureFlags, string) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	features, err := datasite.NewFeatureFlags(sqliteDb, &datasite.FeaturesConfig{PublicHosting: true, RPC: true})
+	require.NoError(t, err)
+
+	authSvc, err := auth.NewAuthService(&auth.Config{AdminEmails: []string{"ops@example.com"}}, nil, nil)
+	require.NoError(t, err)
+
+	logDir := t.TempDir()
+	accessLog, err := accesslog.New(logDir, slog.Default())
+	require.NoError(t, err)
+	t.Cleanup(func() { accessLog.Close() })
+
+	h := New(features, accessLog, blobSvc)
+	r := gin.New()
+	r.Use(accesslog.NewMiddleware(accessLog).Handler(), func(ctx *gin.Context) {
+		ctx.Set("user", ctx.Query("user"))
+	}, middlewares.AdminOnly(authSvc))
+	r.GET("/datasites/:datasite/features", h.GetFeatures)
+	r.PATCH("/datasites/:datasite/features", h.UpdateFeatures)
+	r.GET("/audit/export", h.ExportAuditLog)
+	r.GET("/datasites/:datasite/presigns", h.ListPresigns)
+	r.POST("/datasites/:datasite/presigns/revoke", h.RevokePresigns)
+
+	return r, features, logDir
+}
+
+func doRequest(r *gin.Engine, method string, url string, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(method, url, strings.NewReader(body)))
+	return w
+}
+
+func TestUpdateFeatures(t *testing.T) {
+	r, features, _ := newTestRouter(t)
+
+	w := doRequest(r, http.MethodPatch, "/datasites/alice@example.com/features?user=ops@example.com", `{"rpc": false}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp datasite.DatasiteFeatures
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, map[datasite.Feature]bool{datasite.FeaturePublicHosting: true, datasite.FeatureRPC: false}, resp.Features)
+	assert.Equal(t, map[datasite.Feature]bool{datasite.FeatureRPC: false}, resp.Overrides)
+
+	// only for that datasite
+	assert.False(t, features.Enabled("alice@example.com", datasite.FeatureRPC))
+	assert.True(t, features.Enabled("bob@example.com", datasite.FeatureRPC))
+
+	// null resets to the server config
+	w = doRequest(r, http.MethodPatch, "/datasites/alice@example.com/features?user=ops@example.com", `{"rpc": null}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.True(t, features.Enabled("alice@example.com", datasite.FeatureRPC))
+
+	w = doRequest(r, http.MethodGet, "/datasites/alice@example.com/features?user=ops@example.com", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var got datasite.DatasiteFeatures
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+	assert.Empty(t, got.Overrides)
+}
+
+func TestUpdateFeaturesInvalid(t *testing.T) {
+	r, features, _ := newTestRouter(t)
+
+	// a bad request changes nothing
+	w := doRequest(r, http.MethodPatch, "/datasites/alice@example.com/features?user=ops@example.com", `{"rpc": false, "hotlink": false}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.True(t, features.Enabled("alice@example.com", datasite.FeatureRPC))
+
+	w = doRequest(r, http.MethodGet, "/datasites/not-a-datasite/features?user=ops@example.com", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestFeaturesAdminOnly(t *testing.T) {
+	r, features, _ := newTestRouter(t)
+
+	w := doRequest(r, http.MethodPatch, "/datasites/alice@example.com/features?user=alice@example.com", `{"public_hosting": false}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.True(t, features.Enabled("alice@example.com", datasite.FeaturePublicHosting))
+
+	w = doRequest(r, http.MethodGet, "/datasites/alice@example.com/features?user=alice@example.com", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+var auditBase = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// writeAuditLog writes the entries to the access log of their user, as the access logger would
+func writeAuditLog(t *testing.T, logDir string, entries ...accesslog.AccessLogEntry) {
+	t.Helper()
+	for _, entry := range entries {
+		dir := filepath.Join(logDir, entry.User)
+		require.NoError(t, os.MkdirAll(dir, 0o700))
+		file, err := os.OpenFile(filepath.Join(dir, "access_20260301.log"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		require.NoError(t, err)
+		require.NoError(t, json.NewEncoder(file).Encode(entry))
+		require.NoError(t, file.Close())
+	}
+}
+
+func auditEntry(user string, at time.Duration, accessType accesslog.AccessType, path string, allowed bool) accesslog.AccessLogEntry {
+	entry := accesslog.AccessLogEntry{
+		Timestamp:  auditBase.Add(at),
+		Path:       path,
+		AccessType: accessType,
+		User:       user,
+		Method:     http.MethodGet,
+		StatusCode: http.StatusOK,
+		Allowed:    allowed,
+	}
+	if !allowed {
+		entry.StatusCode = http.StatusForbidden
+		entry.DeniedReason = "access denied"
+	}
+	return entry
+}
+
+// exportAudit exports with the query and returns the paths of the entries and the next cursor
+func exportAudit(t *testing.T, r *gin.Engine, query url.Values) ([]string, string) {
+	t.Helper()
+	query.Set("user", "ops@example.com")
+	w := doRequest(r, http.MethodGet, "/audit/export?"+query.Encode(), "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+
+	var paths []string
+	scanner := bufio.NewScanner(w.Body)
+	for scanner.Scan() {
+		var entry accesslog.AccessLogEntry
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+		paths = append(paths, entry.Path)
+	}
+	return paths, w.Header().Get("X-Next-Cursor")
+}
+
+func TestExportAuditLog(t *testing.T) {
+	r, _, logDir := newTestRouter(t)
+	writeAuditLog(t, logDir,
+		auditEntry("alice@example.com", 0, accesslog.AccessTypeRead, "alice@example.com/public/a.txt", true),
+		auditEntry("alice@example.com", time.Minute, accesslog.AccessTypeWrite, "bob@example.com/private/b.txt", false),
+		auditEntry("bob@example.com", time.Minute, accesslog.AccessTypeRead, "bob@example.com/public/c.txt", true),
+		auditEntry("bob@example.com", 2*time.Minute, accesslog.AccessTypeWrite, "bob@example.com/public/d.txt", true),
+		auditEntry("alice@example.com", 3*time.Minute, accesslog.AccessTypeRead, "bob@example.com/private/e.txt", false),
+	)
+
+	paths, next := exportAudit(t, r, url.Values{})
+	assert.Equal(t, []string{
+		"alice@example.com/public/a.txt",
+		"bob@example.com/private/b.txt",
+		"bob@example.com/public/c.txt",
+		"bob@example.com/public/d.txt",
+		"bob@example.com/private/e.txt",
+	}, paths, "all users, oldest first")
+	assert.Empty(t, next)
+
+	// deny matches every denied access
+	paths, _ = exportAudit(t, r, url.Values{"type": {"deny"}})
+	assert.Equal(t, []string{"bob@example.com/private/b.txt", "bob@example.com/private/e.txt"}, paths)
+
+	paths, _ = exportAudit(t, r, url.Values{
+		"type":  {"read", "write"},
+		"since": {auditBase.Add(time.Minute).Format(time.RFC3339)},
+		"until": {auditBase.Add(3 * time.Minute).Format(time.RFC3339)},
+	})
+	assert.Equal(t, []string{"bob@example.com/private/b.txt", "bob@example.com/public/c.txt", "bob@example.com/public/d.txt"}, paths)
+}
+
+func TestExportAuditLogCursor(t *testing.T) {
+	r, _, logDir := newTestRouter(t)
+	writeAuditLog(t, logDir,
+		auditEntry("alice@example.com", 0, accesslog.AccessTypeRead, "1", true),
+		auditEntry("alice@example.com", time.Second, accesslog.AccessTypeRead, "2", true),
+		auditEntry("bob@example.com", time.Second, accesslog.AccessTypeRead, "3", true),
+		auditEntry("bob@example.com", time.Second, accesslog.AccessTypeWrite, "4", false),
+		auditEntry("carol@example.com", 2*time.Second, accesslog.AccessTypeRead, "5", true),
+	)
+
+	var paths []string
+	query := url.Values{"limit": {"2"}}
+	for pages := 0; pages < 5; pages++ {
+		page, next := exportAudit(t, r, query)
+		paths = append(paths, page...)
+		if next == "" {
+			break
+		}
+		query.Set("cursor", next)
+	}
+	assert.Equal(t, []string{"1", "2", "3", "4", "5"}, paths, "entries sharing a timestamp are exported once")
+
+	// the cursor works with filters too
+	page, next := exportAudit(t, r, url.Values{"type": {"read"}, "limit": {"1"}, "cursor": {query.Get("cursor")}})
+	assert.Equal(t, []string{"5"}, page)
+	assert.Empty(t, next)
+
+	w := doRequest(r, http.MethodGet, "/audit/export?user=ops@example.com&cursor=nope", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doRequest(r, http.MethodGet, "/audit/export?user=ops@example.com&type=delete", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestExportAuditLogAdminActions(t *testing.T) {
+	r, _, _ := newTestRouter(t)
+
+	req := httptest.NewRequest(http.MethodPatch, "/datasites/alice@example.com/features?user=ops@example.com", strings.NewReader(`{"rpc": false}`))
+	req.Header.Set("Authorization", "Bearer secret-token")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = doRequest(r, http.MethodGet, "/audit/export?user=alice@example.com", "")
+	require.Equal(t, http.StatusForbidden, w.Code)
+
+	query := url.Values{"type": {"admin"}, "user": {"ops@example.com"}}
+	w = doRequest(r, http.MethodGet, "/audit/export?"+query.Encode(), "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.NotContains(t, w.Body.String(), "secret-token")
+
+	// by user, both can be logged in the same millisecond
+	entries := make(map[string]accesslog.AccessLogEntry)
+	scanner := bufio.NewScanner(w.Body)
+	for scanner.Scan() {
+		var entry accesslog.AccessLogEntry
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+		entries[entry.User] = entry
+	}
+	require.Len(t, entries, 2)
+	assert.Equal(t, "/datasites/alice@example.com/features", entries["ops@example.com"].Path)
+	assert.True(t, entries["ops@example.com"].Allowed)
+	assert.False(t, entries["alice@example.com"].Allowed, "the denied export is logged")
+	assert.Equal(t, http.StatusForbidden, entries["alice@example.com"].StatusCode)
+}
+
+func TestRevokePresigns(t *testing.T) {
+	sqliteDb, err := db.NewSqliteDB(db.WithPath(filepath.Join(t.TempDir(), "test.db")), db.WithMaxOpenConns(1))
+	require.NoError(t, err)
+	t.Cleanup(func() { sqliteDb.Close() })
+
+	// the server serving the presigned urls of the filesystem backend
+	var presignH http.Handler
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { presignH.ServeHTTP(w, r) }))
+	t.Cleanup(srv.Close)
+
+	blobSvc, err := blob.NewBlobService(&blob.S3Config{Backend: blob.BackendFilesystem, Dir: t.TempDir(), PublicURL: srv.URL}, sqliteDb)
+	require.NoError(t, err)
+	require.NoError(t, blobSvc.Start(t.Context()))
+	presignH = blobSvc.PresignHandler()
+
+	r, _, _ := newTestRouterWithBlob(t, sqliteDb, blobSvc)
+
+	keys := []string{"alice@example.com/public/a.txt", "alice@example.com/public/b.txt"}
+	urls := make([]string, 0, len(keys))
+	for _, key := range keys {
+		_, err := blobSvc.Backend().PutObject(t.Context(), &blob.PutObjectParams{Key: key, Body: strings.NewReader("hello"), Size: 5})
+		require.NoError(t, err)
+		url, err := blobSvc.Backend().GetObjectPresigned(t.Context(), key)
+		require.NoError(t, err)
+		blobSvc.Presigns().Record(url, key, "bob@example.com", http.MethodGet)
+		urls = append(urls, url)
+	}
+	// another datasite
+	url, err := blobSvc.Backend().PutObjectPresigned(t.Context(), "carol@example.com/public/c.txt", -1)
+	require.NoError(t, err)
+	blobSvc.Presigns().Record(url, "carol@example.com/public/c.txt", "carol@example.com", http.MethodPut)
+
+	w := doRequest(r, http.MethodGet, "/datasites/alice@example.com/presigns?user=ops@example.com", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var listed PresignsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &listed))
+	assert.True(t, listed.Revocable)
+	require.Len(t, listed.URLs, 2)
+	assert.ElementsMatch(t, keys, []string{listed.URLs[0].Key, listed.URLs[1].Key})
+	for _, issued := range listed.URLs {
+		assert.Equal(t, "bob@example.com", issued.User)
+		assert.Equal(t, http.MethodGet, issued.Method)
+		assert.False(t, issued.Revoked)
+		assert.True(t, issued.ExpiresAt.After(issued.IssuedAt))
+	}
+
+	// admins only
+	w = doRequest(r, http.MethodGet, "/datasites/alice@example.com/presigns?user=alice@example.com", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// revoke a.txt
+	var revokedID string
+	for _, issued := range listed.URLs {
+		if issued.Key == keys[0] {
+			revokedID = issued.ID
+		}
+	}
+	w = doRequest(r, http.MethodPost, "/datasites/alice@example.com/presigns/revoke?user=ops@example.com", `{"ids": ["`+revokedID+`"]}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var revoked PresignsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &revoked))
+	require.Len(t, revoked.URLs, 1)
+	assert.Equal(t, keys[0], revoked.URLs[0].Key)
+	assert.True(t, revoked.URLs[0].Revoked)
+
+	// the revoked url is rejected, the other one still works
+	resp, err := http.Get(urls[0])
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	resp, err = http.Get(urls[1])
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// all of the datasite, but not the others
+	w = doRequest(r, http.MethodPost, "/datasites/alice@example.com/presigns/revoke?user=ops@example.com", `{}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &revoked))
+	assert.Len(t, revoked.URLs, 2)
+	resp, err = http.Get(urls[1])
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.False(t, blobSvc.Presigns().IsRevoked(url))
+}
@@ -0,0 +1,32 @@
+package admin
+
+import (
+	"time"
+
+	"github.com/openmined/syftbox/internal/server/blob"
+)
+
+// FeaturesUpdateRequest sets the features of a datasite by name.
+// A null value removes the override, so that the datasite follows the server config again.
+type FeaturesUpdateRequest map[string]*bool
+
+// AuditExportRequest filters an export of the access log. Types can be repeated, e.g. ?type=deny&type=admin
+type AuditExportRequest struct {
+	Types  []string  `form:"type"`
+	Since  time.Time `form:"since" time_format:"2006-01-02T15:04:05Z07:00"`
+	Until  time.Time `form:"until" time_format:"2006-01-02T15:04:05Z07:00"`
+	Cursor string    `form:"cursor"`
+	Limit  int       `form:"limit" binding:"omitempty,min=1,max=10000"`
+}
+
+// PresignsResponse lists presigned urls. Revocable is false when the backend serves them, like S3,
+// as revoked urls are only rejected when the server serves them itself.
+type PresignsResponse struct {
+	URLs      []*blob.PresignedURL `json:"urls"`
+	Revocable bool                 `json:"revocable"`
+}
+
+// PresignsRevokeRequest revokes presigned urls by id, all of the datasite when empty
+type PresignsRevokeRequest struct {
+	IDs []string `json:"ids"`
+}
@@ -13,10 +13,18 @@ const (
 	CodeAuthOTPVerificationFailed = "E_AUTH_OTP_VERIFICATION_FAILED" // Email One-Time Password (OTP) verification failed.
 	CodeAuthTokenRefreshFailed    = "E_AUTH_TOKEN_REFRESH_FAILED"    // a failure during the attempt to refresh an authentication token.
 	CodeAuthNotificationFailed    = "E_AUTH_NOTIFICATION_FAILED"     // a failure in sending an authentication-related notification (e.g., OTP email/SMS).
+	CodeAuthEmailNotAllowed       = "E_AUTH_EMAIL_NOT_ALLOWED"       // the email is not permitted by the server's allowed/denied email rules.
+	CodeAuthTokenRevokeFailed     = "E_AUTH_TOKEN_REVOKE_FAILED"     // a failure during the revocation of authentication tokens.
 
 	// Datasite errors
-	CodeDatasiteNotFound    = "E_DATASITE_NOT_FOUND"    // the specified datasite resource could not be found.
-	CodeDatasiteInvalidPath = "E_DATASITE_INVALID_PATH" // the provided path for a datasite resource is invalid or malformed.
+	CodeDatasiteNotFound     = "E_DATASITE_NOT_FOUND"     // the specified datasite resource could not be found.
+	CodeDatasiteInvalidPath  = "E_DATASITE_INVALID_PATH"  // the provided path for a datasite resource is invalid or malformed.
+	CodeDatasiteKeyNotNFC    = "E_DATASITE_KEY_NOT_NFC"   // the path is not in Unicode NFC, the file must be renamed to its NFC form.
+	CodeDatasiteLimitReached = "E_DATASITE_LIMIT_REACHED" // the server has reached its maximum number of datasites, new ones can't be created.
+	CodeQuotaExceeded        = "E_QUOTA_EXCEEDED"         // the write would take the datasite over its storage quota.
+	CodeFeatureDisabled      = "E_FEATURE_DISABLED"       // the feature is turned off for the datasite.
+	CodeSnapshotNotFound     = "E_SNAPSHOT_NOT_FOUND"     // the datasite has no snapshot with this name.
+	CodeSnapshotExists       = "E_SNAPSHOT_EXISTS"        // the datasite already has a snapshot with this name.
 
 	// Blob errors
 	CodeBlobNotFound     = "E_BLOB_NOT_FOUND"               // the specified blob could not be found.
@@ -24,7 +32,10 @@ const (
 	CodeBlobPutFailed    = "E_BLOB_PUT_OPERATION_FAILED"    // a failure during the operation to upload/put a blob.
 	CodeBlobGetFailed    = "E_BLOB_GET_OPERATION_FAILED"    // a failure during the operation to download/get a blob.
 	CodeBlobDeleteFailed = "E_BLOB_DELETE_OPERATION_FAILED" // a failure during the operation to delete a blob.
+	CodeBlobBusy         = "E_BLOB_BUSY"                    // the blob storage is at its concurrency limit, retry later.
+	CodeBlobTooManyKeys  = "E_BLOB_TOO_MANY_KEYS"           // the request has more keys than the server accepts at once, split it up.
 
 	// ACL errors
 	CodeACLUpdateFailed = "E_ACL_UPDATE_FAILED" // a failure during the operation to update an ACL.
+	CodeACLInvalid      = "E_ACL_INVALID"       // the ACL file could not be parsed or its ruleset is invalid.
 )
@@ -1,13 +1,23 @@
 package api
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/openmined/syftbox/internal/server/busy"
 )
 
+// blobBusyRetryAfter is the Retry-After sent when the blob storage is at its concurrency limit
+const blobBusyRetryAfter = "1"
+
 func AbortWithError(ctx *gin.Context, status int, code string, err error) {
 	ctx.Abort()
+	// a busy storage is the same for every handler, the client should retry rather than give up
+	if errors.Is(err, busy.ErrBlobStorage) {
+		status, code = http.StatusServiceUnavailable, CodeBlobBusy
+		ctx.Header("Retry-After", blobBusyRetryAfter)
+	}
 	ctx.Error(err)
 	ctx.PureJSON(status, SyftAPIError{
 		Code:    code,
@@ -0,0 +1,234 @@
+package archive
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/openmined/syftbox/internal/aclspec"
+	"github.com/openmined/syftbox/internal/server/blob"
+	"github.com/openmined/syftbox/internal/server/datasite"
+	"github.com/openmined/syftbox/internal/server/handlers/api"
+)
+
+type ArchiveHandler struct {
+	blob    blob.Service
+	isAdmin func(user string) bool
+}
+
+func New(blobSvc blob.Service, isAdmin func(user string) bool) *ArchiveHandler {
+	return &ArchiveHandler{
+		blob:    blobSvc,
+		isAdmin: isAdmin,
+	}
+}
+
+// Download streams a datasite as a single archive, including its ACL files, with a manifest as the first entry.
+// Only the owner of the datasite and the admins can download it, and they get the whole datasite.
+// Tar archives can be resumed with a Range request, as long as the datasite hasn't changed (If-Range on the ETag).
+func (h *ArchiveHandler) Download(ctx *gin.Context) {
+	var req ArchiveRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		api.AbortWithError(ctx, http.StatusBadRequest, api.CodeInvalidRequest, fmt.Errorf("failed to bind query: %w", err))
+		return
+	}
+
+	user := ctx.GetString("user")
+	if req.Datasite == "" {
+		req.Datasite = user
+	}
+
+	if !datasite.IsValidDatasite(req.Datasite) {
+		api.AbortWithError(ctx, http.StatusBadRequest, api.CodeDatasiteInvalidPath, fmt.Errorf("invalid datasite %q", req.Datasite))
+		return
+	}
+
+	if req.Format == "" {
+		req.Format = FormatTar
+	}
+
+	if req.Format != FormatTar && req.Format != FormatZip {
+		api.AbortWithError(ctx, http.StatusBadRequest, api.CodeInvalidRequest, fmt.Errorf("invalid format %q", req.Format))
+		return
+	}
+
+	if req.Datasite != user && !h.isAdmin(user) {
+		api.AbortWithError(ctx, http.StatusForbidden, api.CodeAccessDenied, fmt.Errorf("only the owner of the datasite and the admins can download it"))
+		return
+	}
+
+	manifest, err := h.buildManifest(req.Datasite)
+	if err != nil {
+		api.AbortWithError(ctx, http.StatusInternalServerError, api.CodeBlobListFailed, err)
+		return
+	}
+
+	if len(manifest.Objects) == 0 {
+		api.AbortWithError(ctx, http.StatusNotFound, api.CodeDatasiteNotFound, fmt.Errorf("datasite not found"))
+		return
+	}
+
+	manifestBytes, err := json.Marshal(manifest)
+	if err != nil {
+		api.AbortWithError(ctx, http.StatusInternalServerError, api.CodeInternalError, err)
+		return
+	}
+
+	// the archive is generated from the manifest, so the same manifest gives the same bytes
+	sum := md5.Sum(append([]byte(req.Format), manifestBytes...))
+	etag := `"` + hex.EncodeToString(sum[:]) + `"`
+
+	ctx.Header("ETag", etag)
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, req.Datasite, req.Format))
+
+	slog.Info("archive download", "datasite", req.Datasite, "user", user, "format", req.Format, "objects", len(manifest.Objects))
+
+	switch req.Format {
+	case FormatZip:
+		h.serveZip(ctx, manifest, manifestBytes)
+	default:
+		h.serveTar(ctx, manifest, manifestBytes, etag)
+	}
+}
+
+func (h *ArchiveHandler) serveZip(ctx *gin.Context, manifest *Manifest, manifestBytes []byte) {
+	ctx.Header("Content-Type", "application/zip")
+	ctx.Status(http.StatusOK)
+
+	if err := writeZip(ctx, ctx.Writer, h.blob.Backend(), manifest, manifestBytes); err != nil {
+		// headers are already sent, the client gets an incomplete archive
+		ctx.Error(err)
+		slog.Error("archive download", "datasite", manifest.Datasite, "format", FormatZip, "error", err)
+	}
+}
+
+func (h *ArchiveHandler) serveTar(ctx *gin.Context, manifest *Manifest, manifestBytes []byte, etag string) {
+	layout, err := newTarLayout(manifest, manifestBytes)
+	if err != nil {
+		api.AbortWithError(ctx, http.StatusInternalServerError, api.CodeInternalError, err)
+		return
+	}
+
+	ctx.Header("Content-Type", "application/x-tar")
+	ctx.Header("Accept-Ranges", "bytes")
+
+	start, end := int64(0), layout.size-1
+	status := http.StatusOK
+
+	// a stale If-Range means the datasite changed, so the whole archive is sent again
+	if rangeHeader := ctx.GetHeader("Range"); rangeHeader != "" && ifRangeMatches(ctx.GetHeader("If-Range"), etag) {
+		var ok bool
+		start, end, ok, err = parseRange(rangeHeader, layout.size)
+		if err != nil {
+			ctx.Header("Content-Range", fmt.Sprintf("bytes */%d", layout.size))
+			api.AbortWithError(ctx, http.StatusRequestedRangeNotSatisfiable, api.CodeInvalidRequest, err)
+			return
+		}
+
+		if ok {
+			status = http.StatusPartialContent
+			ctx.Header("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, layout.size))
+		}
+	}
+
+	ctx.Header("Content-Length", strconv.FormatInt(end-start+1, 10))
+	ctx.Status(status)
+
+	if ctx.Request.Method == http.MethodHead {
+		return
+	}
+
+	if err := layout.writeRange(ctx, ctx.Writer, h.blob.Backend(), start, end); err != nil {
+		// headers are already sent, the client sees a short body
+		ctx.Error(err)
+		slog.Error("archive download", "datasite", manifest.Datasite, "format", FormatTar, "error", err)
+	}
+}
+
+// buildManifest lists the objects of the datasite, sorted by key
+func (h *ArchiveHandler) buildManifest(ds string) (*Manifest, error) {
+	prefix := ds + "/"
+
+	blobs, err := h.blob.Index().FilterByPrefix(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*ManifestEntry, 0, len(blobs))
+	for _, info := range blobs {
+		if !strings.HasPrefix(info.Key, prefix) {
+			continue
+		}
+
+		entries = append(entries, &ManifestEntry{
+			Key:          info.Key,
+			ETag:         info.ETag,
+			Size:         info.Size,
+			LastModified: info.LastModified,
+			ACL:          aclspec.IsACLFile(info.Key),
+		})
+	}
+
+	slices.SortFunc(entries, func(a, b *ManifestEntry) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+
+	return &Manifest{
+		Datasite: ds,
+		Objects:  entries,
+	}, nil
+}
+
+// ifRangeMatches checks the If-Range header. An empty header always matches.
+func ifRangeMatches(ifRange string, etag string) bool {
+	return ifRange == "" || ifRange == etag
+}
+
+var errInvalidRange = errors.New("invalid range")
+
+// parseRange parses a single byte range of a body of the given size, returning inclusive offsets.
+// ok is false for headers that are ignored, like multiple ranges or other units.
+func parseRange(header string, size int64) (start int64, end int64, ok bool, err error) {
+	spec, found := strings.CutPrefix(header, "bytes=")
+	if !found || strings.Contains(spec, ",") {
+		return 0, size - 1, false, nil
+	}
+
+	first, last, found := strings.Cut(strings.TrimSpace(spec), "-")
+	if !found {
+		return 0, 0, false, errInvalidRange
+	}
+
+	if first == "" {
+		// suffix range, the last n bytes
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n <= 0 {
+			return 0, 0, false, errInvalidRange
+		}
+		return max(size-n, 0), size - 1, true, nil
+	}
+
+	start, err = strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 || start >= size {
+		return 0, 0, false, errInvalidRange
+	}
+
+	end = size - 1
+	if last != "" {
+		end, err = strconv.ParseInt(last, 10, 64)
+		if err != nil || end < start {
+			return 0, 0, false, errInvalidRange
+		}
+		end = min(end, size-1)
+	}
+
+	return start, end, true, nil
+}
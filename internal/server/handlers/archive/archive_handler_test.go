@@ -0,0 +1,275 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/openmined/syftbox/internal/server/blob"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeBlobService struct {
+	backend *fakeBackend
+	index   *fakeIndex
+}
+
+func (s *fakeBlobService) Backend() blob.IBlobBackend                    { return s.backend }
+func (s *fakeBlobService) Index() blob.IBlobIndex                        { return s.index }
+func (s *fakeBlobService) ReadIndex() blob.IBlobIndex                    { return s.Index() }
+func (s *fakeBlobService) OnBlobChange(callback blob.BlobChangeCallback) {}
+
+type fakeBackend struct {
+	blob.IBlobBackend
+	objects map[string][]byte
+	gets    int
+}
+
+func (b *fakeBackend) GetObject(ctx context.Context, key string) (*blob.GetObjectResponse, error) {
+	b.gets++
+	content, ok := b.objects[key]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return &blob.GetObjectResponse{
+		Body: io.NopCloser(bytes.NewReader(content)),
+		ETag: etagOf(content),
+		Size: int64(len(content)),
+	}, nil
+}
+
+type fakeIndex struct {
+	blob.IBlobIndex
+	blobs []*blob.BlobInfo
+}
+
+func (i *fakeIndex) FilterByPrefix(prefix string) ([]*blob.BlobInfo, error) {
+	var res []*blob.BlobInfo
+	for _, info := range i.blobs {
+		if strings.HasPrefix(info.Key, prefix) {
+			res = append(res, info)
+		}
+	}
+	return res, nil
+}
+
+const testAdmin = "admin@example.com"
+
+func etagOf(content []byte) string {
+	sum := md5.Sum(content)
+	return hex.EncodeToString(sum[:])
+}
+
+func newArchiveTestRouter(t *testing.T, objects map[string][]byte) (*gin.Engine, *fakeBackend) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	index := &fakeIndex{}
+	for key, content := range objects {
+		index.blobs = append(index.blobs, &blob.BlobInfo{
+			Key:          key,
+			ETag:         etagOf(content),
+			Size:         int64(len(content)),
+			LastModified: "2025-01-02T03:04:05Z",
+		})
+	}
+
+	backend := &fakeBackend{objects: objects}
+	h := New(&fakeBlobService{backend: backend, index: index}, func(user string) bool { return user == testAdmin })
+
+	r := gin.New()
+	r.Use(func(ctx *gin.Context) {
+		ctx.Set("user", ctx.GetHeader("X-User"))
+	})
+	r.GET("/archive", h.Download)
+	return r, backend
+}
+
+func testObjects() map[string][]byte {
+	return map[string][]byte{
+		"alice@example.com/syft.pub.yaml":                                 []byte("rules: []\n"),
+		"alice@example.com/public/syft.pub.yaml":                          []byte("rules:\n- pattern: '**'\n"),
+		"alice@example.com/public/report.csv":                             bytes.Repeat([]byte("a,b,c\n"), 300),
+		"alice@example.com/private/secret.txt":                            []byte("secret"),
+		"alice@example.com/" + strings.Repeat("nested/", 20) + "deep.txt": []byte("deep"),
+		"bob@example.com/public/other.txt":                                []byte("not alice"),
+	}
+}
+
+func download(r *gin.Engine, user string, query string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/archive"+query, nil)
+	req.Header.Set("X-User", user)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func readTar(t *testing.T, data []byte) (names []string, contents map[string][]byte) {
+	t.Helper()
+	contents = map[string][]byte{}
+	tr := tar.NewReader(bytes.NewReader(data))
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		content, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		names = append(names, hdr.Name)
+		contents[hdr.Name] = content
+	}
+	return names, contents
+}
+
+func TestArchiveTarContainsDatasite(t *testing.T) {
+	objects := testObjects()
+	r, _ := newArchiveTestRouter(t, objects)
+
+	w := download(r, "alice@example.com", "", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "application/x-tar", w.Header().Get("Content-Type"))
+	assert.Equal(t, "bytes", w.Header().Get("Accept-Ranges"))
+	assert.NotEmpty(t, w.Header().Get("ETag"))
+
+	names, contents := readTar(t, w.Body.Bytes())
+	require.NotEmpty(t, names)
+	assert.Equal(t, ManifestName, names[0])
+	assert.Len(t, names, 6)
+
+	for key, content := range objects {
+		if strings.HasPrefix(key, "bob@example.com/") {
+			assert.NotContains(t, contents, key)
+			continue
+		}
+		assert.Equal(t, content, contents[key], key)
+	}
+
+	var manifest Manifest
+	require.NoError(t, json.Unmarshal(contents[ManifestName], &manifest))
+	assert.Equal(t, "alice@example.com", manifest.Datasite)
+	require.Len(t, manifest.Objects, 5)
+
+	acls := 0
+	for _, obj := range manifest.Objects {
+		assert.Equal(t, etagOf(objects[obj.Key]), obj.ETag)
+		if obj.ACL {
+			acls++
+		}
+	}
+	assert.Equal(t, 2, acls)
+}
+
+func TestArchiveZipContainsDatasite(t *testing.T) {
+	objects := testObjects()
+	r, _ := newArchiveTestRouter(t, objects)
+
+	w := download(r, "alice@example.com", "?format=zip", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "application/zip", w.Header().Get("Content-Type"))
+
+	zr, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	require.NoError(t, err)
+	require.Len(t, zr.File, 6)
+	assert.Equal(t, ManifestName, zr.File[0].Name)
+
+	for _, f := range zr.File[1:] {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		require.NoError(t, err)
+		assert.Equal(t, objects[f.Name], content, f.Name)
+	}
+}
+
+func TestArchiveOwnerOrAdminOnly(t *testing.T) {
+	objects := testObjects()
+	r, _ := newArchiveTestRouter(t, objects)
+
+	// bob can read alice's public files, but not download her datasite
+	w := download(r, "bob@example.com", "?datasite=alice@example.com", nil)
+	assert.Equal(t, http.StatusForbidden, w.Code, w.Body.String())
+	w = download(r, "bob@example.com", "?datasite=nobody@example.com", nil)
+	assert.Equal(t, http.StatusForbidden, w.Code, "doesn't reveal if the datasite exists")
+
+	// an admin gets the whole datasite
+	w = download(r, testAdmin, "?datasite=alice@example.com", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	_, contents := readTar(t, w.Body.Bytes())
+	for key, content := range objects {
+		if strings.HasPrefix(key, "alice@example.com/") {
+			assert.Equal(t, content, contents[key], key)
+		} else {
+			assert.NotContains(t, contents, key)
+		}
+	}
+
+	// an empty datasite
+	w = download(r, testAdmin, "?datasite=nobody@example.com", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestArchiveInvalidRequest(t *testing.T) {
+	r, _ := newArchiveTestRouter(t, testObjects())
+
+	assert.Equal(t, http.StatusBadRequest, download(r, "alice@example.com", "?format=rar", nil).Code)
+	assert.Equal(t, http.StatusBadRequest, download(r, "alice@example.com", "?datasite=notanemail", nil).Code)
+}
+
+func TestArchiveTarResume(t *testing.T) {
+	r, backend := newArchiveTestRouter(t, testObjects())
+
+	full := download(r, "alice@example.com", "", nil)
+	require.Equal(t, http.StatusOK, full.Code)
+	etag := full.Header().Get("ETag")
+	body := full.Body.Bytes()
+	assert.Equal(t, strconv.Itoa(len(body)), full.Header().Get("Content-Length"))
+
+	for _, start := range []int{1, 700, len(body) / 2, len(body) - 1100, len(body) - 1} {
+		w := download(r, "alice@example.com", "", map[string]string{
+			"Range":    "bytes=" + strconv.Itoa(start) + "-",
+			"If-Range": etag,
+		})
+		require.Equal(t, http.StatusPartialContent, w.Code)
+		assert.Equal(t, "bytes "+strconv.Itoa(start)+"-"+strconv.Itoa(len(body)-1)+"/"+strconv.Itoa(len(body)), w.Header().Get("Content-Range"))
+		assert.Equal(t, body[start:], w.Body.Bytes(), "start %d", start)
+	}
+
+	// a bounded range in the middle
+	w := download(r, "alice@example.com", "", map[string]string{"Range": "bytes=600-1700"})
+	require.Equal(t, http.StatusPartialContent, w.Code)
+	assert.Equal(t, body[600:1701], w.Body.Bytes())
+
+	// entries before the range aren't fetched
+	backend.gets = 0
+	w = download(r, "alice@example.com", "", map[string]string{"Range": "bytes=-10"})
+	require.Equal(t, http.StatusPartialContent, w.Code)
+	assert.Equal(t, body[len(body)-10:], w.Body.Bytes())
+	assert.Zero(t, backend.gets)
+
+	// changed archive, send it whole
+	w = download(r, "alice@example.com", "", map[string]string{"Range": "bytes=100-", "If-Range": `"stale"`})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, body, w.Body.Bytes())
+
+	w = download(r, "alice@example.com", "", map[string]string{"Range": "bytes=" + strconv.Itoa(len(body)) + "-"})
+	assert.Equal(t, http.StatusRequestedRangeNotSatisfiable, w.Code)
+	assert.Equal(t, "bytes */"+strconv.Itoa(len(body)), w.Header().Get("Content-Range"))
+}
@@ -0,0 +1,30 @@
+package archive
+
+const (
+	FormatTar = "tar"
+	FormatZip = "zip"
+
+	// ManifestName is the first entry of every archive
+	ManifestName = "syftbox-manifest.json"
+)
+
+type ArchiveRequest struct {
+	// defaults to the user's own datasite
+	Datasite string `form:"datasite"`
+	// tar (default) or zip
+	Format string `form:"format"`
+}
+
+// Manifest lists the objects of the archive. Objects are stored in the archive under their key.
+type Manifest struct {
+	Datasite string           `json:"datasite"`
+	Objects  []*ManifestEntry `json:"objects"`
+}
+
+type ManifestEntry struct {
+	Key          string `json:"key"`
+	ETag         string `json:"etag"`
+	Size         int64  `json:"size"`
+	LastModified string `json:"lastModified"`
+	ACL          bool   `json:"acl,omitempty"`
+}
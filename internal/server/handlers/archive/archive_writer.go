@@ -0,0 +1,230 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/openmined/syftbox/internal/server/blob"
+)
+
+const (
+	tarBlockSize   = 512
+	tarTrailerSize = 2 * tarBlockSize
+)
+
+var (
+	errRangeDone     = errors.New("range written")
+	errObjectChanged = errors.New("object changed while archiving")
+)
+
+// tarEntry is an entry of the archive, with the exact number of bytes it takes up
+type tarEntry struct {
+	header *tar.Header
+	len    int64
+	object *ManifestEntry // nil for the manifest
+	data   []byte         // content of the manifest
+}
+
+// tarLayout is the byte layout of a tar archive.
+// Knowing where each entry starts lets a range skip over entries without fetching them.
+type tarLayout struct {
+	entries []*tarEntry
+	size    int64
+}
+
+func newTarLayout(manifest *Manifest, manifestBytes []byte) (*tarLayout, error) {
+	layout := &tarLayout{
+		entries: make([]*tarEntry, 0, len(manifest.Objects)+1),
+	}
+
+	var latest time.Time
+	for _, obj := range manifest.Objects {
+		if modTime := parseModTime(obj.LastModified); modTime.After(latest) {
+			latest = modTime
+		}
+	}
+
+	if err := layout.add(&tarEntry{
+		header: newTarHeader(ManifestName, int64(len(manifestBytes)), latest),
+		data:   manifestBytes,
+	}); err != nil {
+		return nil, err
+	}
+
+	for _, obj := range manifest.Objects {
+		if err := layout.add(&tarEntry{
+			header: newTarHeader(obj.Key, obj.Size, parseModTime(obj.LastModified)),
+			object: obj,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	layout.size += tarTrailerSize
+	return layout, nil
+}
+
+func (l *tarLayout) add(entry *tarEntry) error {
+	// long names need extra header blocks, so measure the header by writing it
+	var cw countingWriter
+	if err := tar.NewWriter(&cw).WriteHeader(entry.header); err != nil {
+		return fmt.Errorf("tar header %q: %w", entry.header.Name, err)
+	}
+
+	entry.len = cw.n + padded(entry.header.Size)
+	l.entries = append(l.entries, entry)
+	l.size += entry.len
+	return nil
+}
+
+// writeRange writes the bytes [start, end] of the archive to w
+func (l *tarLayout) writeRange(ctx context.Context, w io.Writer, backend blob.IBlobBackend, start int64, end int64) error {
+	rw := &rangeWriter{w: w, start: start, end: end}
+
+	for _, entry := range l.entries {
+		if rw.pos+entry.len <= start {
+			rw.pos += entry.len
+			continue
+		}
+
+		if err := writeTarEntry(ctx, rw, backend, entry); err != nil {
+			if errors.Is(err, errRangeDone) {
+				return nil
+			}
+			return err
+		}
+	}
+
+	if _, err := rw.Write(make([]byte, tarTrailerSize)); err != nil && !errors.Is(err, errRangeDone) {
+		return err
+	}
+
+	return nil
+}
+
+func writeTarEntry(ctx context.Context, w io.Writer, backend blob.IBlobBackend, entry *tarEntry) error {
+	tw := tar.NewWriter(w)
+	if err := tw.WriteHeader(entry.header); err != nil {
+		return err
+	}
+
+	if entry.object == nil {
+		if _, err := tw.Write(entry.data); err != nil {
+			return err
+		}
+	} else if err := copyObject(ctx, tw, backend, entry.object); err != nil {
+		return err
+	}
+
+	// pads the content, the trailer is written once after all entries
+	return tw.Flush()
+}
+
+func writeZip(ctx context.Context, w io.Writer, backend blob.IBlobBackend, manifest *Manifest, manifestBytes []byte) error {
+	zw := zip.NewWriter(w)
+
+	f, err := zw.CreateHeader(&zip.FileHeader{Name: ManifestName, Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(manifestBytes); err != nil {
+		return err
+	}
+
+	for _, obj := range manifest.Objects {
+		f, err := zw.CreateHeader(&zip.FileHeader{Name: obj.Key, Method: zip.Deflate, Modified: parseModTime(obj.LastModified)})
+		if err != nil {
+			return err
+		}
+
+		if err := copyObject(ctx, f, backend, obj); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+// copyObject writes the content of the object, failing if it doesn't match the manifest anymore
+func copyObject(ctx context.Context, w io.Writer, backend blob.IBlobBackend, obj *ManifestEntry) error {
+	resp, err := backend.GetObject(ctx, obj.Key)
+	if err != nil {
+		return fmt.Errorf("get object %q: %w", obj.Key, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.ETag != obj.ETag || resp.Size != obj.Size {
+		return fmt.Errorf("%w: %q", errObjectChanged, obj.Key)
+	}
+
+	if _, err := io.CopyN(w, resp.Body, obj.Size); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func newTarHeader(name string, size int64, modTime time.Time) *tar.Header {
+	return &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Size:     size,
+		Mode:     0o644,
+		ModTime:  modTime,
+	}
+}
+
+func parseModTime(lastModified string) time.Time {
+	t, err := time.Parse(time.RFC3339, lastModified)
+	if err != nil {
+		return time.Unix(0, 0).UTC()
+	}
+	return t.UTC()
+}
+
+func padded(size int64) int64 {
+	return (size + tarBlockSize - 1) / tarBlockSize * tarBlockSize
+}
+
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+// rangeWriter writes only the bytes [start, end] of everything written to it.
+// It returns errRangeDone once the range is written.
+type rangeWriter struct {
+	w     io.Writer
+	pos   int64
+	start int64
+	end   int64
+}
+
+func (w *rangeWriter) Write(p []byte) (int, error) {
+	n := int64(len(p))
+	from := min(max(w.start-w.pos, 0), n)
+	to := max(min(w.end-w.pos+1, n), from)
+	w.pos += n
+
+	if from < to {
+		if _, err := w.w.Write(p[from:to]); err != nil {
+			return 0, err
+		}
+	}
+
+	if w.pos > w.end {
+		return len(p), errRangeDone
+	}
+
+	return len(p), nil
+}
@@ -2,7 +2,9 @@ package auth
 
 import (
 	_ "embed"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -37,6 +39,10 @@ func (h *AuthHandler) OTPRequest(ctx *gin.Context) {
 	}
 
 	if err := h.auth.SendOTP(ctx, req.Email); err != nil {
+		if errors.Is(err, auth.ErrEmailNotAllowed) {
+			api.AbortWithError(ctx, http.StatusForbidden, api.CodeAuthEmailNotAllowed, err)
+			return
+		}
 		api.AbortWithError(ctx, http.StatusInternalServerError, api.CodeAuthNotificationFailed, fmt.Errorf("failed to send OTP: %w", err))
 		return
 	}
@@ -53,6 +59,10 @@ func (h *AuthHandler) OTPVerify(ctx *gin.Context) {
 
 	accessToken, refreshToken, err := h.auth.GenerateTokensPair(ctx, req.Email, req.Code)
 	if err != nil {
+		if errors.Is(err, auth.ErrEmailNotAllowed) {
+			api.AbortWithError(ctx, http.StatusForbidden, api.CodeAuthEmailNotAllowed, err)
+			return
+		}
 		api.AbortWithError(ctx, http.StatusUnauthorized, api.CodeAuthTokenGenerationFailed, err)
 		return
 	}
@@ -60,6 +70,7 @@ func (h *AuthHandler) OTPVerify(ctx *gin.Context) {
 	ctx.PureJSON(http.StatusOK, &OTPVerifyResponse{
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
+		Email:        h.auth.NormalizeEmail(req.Email),
 	})
 }
 
@@ -71,6 +82,17 @@ func (h *AuthHandler) Refresh(ctx *gin.Context) {
 	}
 
 	accessToken, refreshToken, err := h.auth.RefreshToken(ctx, req.OldRefreshToken)
+	if err != nil {
+		if errors.Is(err, auth.ErrEmailNotAllowed) {
+			api.AbortWithError(ctx, http.StatusForbidden, api.CodeAuthEmailNotAllowed, err)
+			return
+		}
+		api.AbortWithError(ctx, http.StatusUnauthorized, api.CodeAuthTokenRefreshFailed, err)
+		return
+	}
+
+	// the subject of the new tokens is the canonical email, which the client may not have yet
+	claims, err := h.auth.ValidateAccessToken(ctx, accessToken)
 	if err != nil {
 		api.AbortWithError(ctx, http.StatusUnauthorized, api.CodeAuthTokenRefreshFailed, err)
 		return
@@ -79,9 +101,47 @@ func (h *AuthHandler) Refresh(ctx *gin.Context) {
 	ctx.PureJSON(http.StatusOK, &RefreshResponse{
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
+		Email:        claims.Subject,
 	})
 }
 
+// Revoke revokes a refresh or an access token of the user, e.g. on logout or of a lost device
+func (h *AuthHandler) Revoke(ctx *gin.Context) {
+	var req RevokeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		api.AbortWithError(ctx, http.StatusBadRequest, api.CodeInvalidRequest, fmt.Errorf("failed to bind json: %w", err))
+		return
+	}
+
+	user := ctx.GetString("user")
+	if err := h.auth.RevokeToken(ctx, user, req.Token); err != nil {
+		switch {
+		case errors.Is(err, auth.ErrInvalidToken):
+			api.AbortWithError(ctx, http.StatusBadRequest, api.CodeInvalidRequest, err)
+		case errors.Is(err, auth.ErrTokenNotOwned):
+			api.AbortWithError(ctx, http.StatusForbidden, api.CodeAccessDenied, err)
+		default:
+			api.AbortWithError(ctx, http.StatusInternalServerError, api.CodeAuthTokenRevokeFailed, err)
+		}
+		return
+	}
+
+	slog.Info("token revoked", "user", user)
+	ctx.PureJSON(http.StatusOK, &RevokeResponse{Revoked: true})
+}
+
+// LogoutAll revokes every token issued to the user until now, logging out all their devices
+func (h *AuthHandler) LogoutAll(ctx *gin.Context) {
+	user := ctx.GetString("user")
+	if err := h.auth.LogoutAll(ctx, user); err != nil {
+		api.AbortWithError(ctx, http.StatusInternalServerError, api.CodeAuthTokenRevokeFailed, err)
+		return
+	}
+
+	slog.Info("tokens revoked, logged out everywhere", "user", user)
+	ctx.PureJSON(http.StatusOK, &RevokeResponse{Revoked: true})
+}
+
 func (h *AuthHandler) AuthTokenUI(ctx *gin.Context) {
 	ctx.Header("Content-Type", "text/html; charset=utf-8")
 	ctx.String(http.StatusOK, authdashHTML)
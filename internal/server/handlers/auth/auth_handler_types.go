@@ -28,4 +28,15 @@ type RefreshRequest struct {
 type RefreshResponse struct {
 	AccessToken  string `json:"accessToken"`
 	RefreshToken string `json:"refreshToken"`
+	Email        string `json:"email"` // the canonical email the tokens are issued to
+}
+
+// RevokeRequest is the request to revoke a refresh or an access token.
+type RevokeRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// RevokeResponse is the response for revoked tokens.
+type RevokeResponse struct {
+	Revoked bool `json:"revoked"`
 }
@@ -1,6 +1,7 @@
 package blob
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
@@ -13,12 +14,15 @@ import (
 )
 
 type BlobHandler struct {
-	blob *blob.BlobService
-	acl  *acl.ACLService
+	blob           *blob.BlobService
+	acl            *acl.ACLService
+	datasites      *datasite.DatasiteService
+	presignCache   *PresignCache
+	maxPresignKeys int // 0 is unlimited
 }
 
-func New(blob *blob.BlobService, acl *acl.ACLService) *BlobHandler {
-	return &BlobHandler{blob: blob, acl: acl}
+func New(blob *blob.BlobService, acl *acl.ACLService, datasites *datasite.DatasiteService, presignCache *PresignCache, maxPresignKeys int) *BlobHandler {
+	return &BlobHandler{blob: blob, acl: acl, datasites: datasites, presignCache: presignCache, maxPresignKeys: maxPresignKeys}
 }
 
 func (h *BlobHandler) UploadMultipart(ctx *gin.Context) {
@@ -32,7 +36,7 @@ func (h *BlobHandler) UploadComplete(ctx *gin.Context) {
 }
 
 func (h *BlobHandler) ListObjects(ctx *gin.Context) {
-	res, err := h.blob.Index().List()
+	res, err := h.blob.ReadIndex().List()
 	if err != nil {
 		api.AbortWithError(ctx, http.StatusInternalServerError, api.CodeBlobListFailed, err)
 		return
@@ -43,7 +47,9 @@ func (h *BlobHandler) ListObjects(ctx *gin.Context) {
 	})
 }
 
-func (h *BlobHandler) checkPermissions(key string, user string, access acl.AccessLevel) error {
+// checkPermissions checks the access of the request's user, with the claims of its access token
+func (h *BlobHandler) checkPermissions(ctx *gin.Context, key string, access acl.AccessLevel) error {
+	user := ctx.GetString("user")
 	if datasite.IsOwner(key, user) {
 		return nil
 	}
@@ -55,6 +61,38 @@ func (h *BlobHandler) checkPermissions(key string, user string, access acl.Acces
 	return nil
 }
 
+// checkPresignKeys rejects presign requests over the configured number of keys, before any of them is presigned
+func (h *BlobHandler) checkPresignKeys(ctx *gin.Context, keys []string) bool {
+	if h.maxPresignKeys > 0 && len(keys) > h.maxPresignKeys {
+		api.AbortWithError(ctx, http.StatusBadRequest, api.CodeBlobTooManyKeys,
+			fmt.Errorf("%d keys over the limit of %d per request, split them into requests of at most %d keys", len(keys), h.maxPresignKeys, h.maxPresignKeys))
+		return false
+	}
+	return true
+}
+
+// keyErrorCode returns the api code of a key rejected by the key rules
+func keyErrorCode(err error) string {
+	if errors.Is(err, blob.ErrKeyNotNFC) {
+		return api.CodeDatasiteKeyNotNFC
+	}
+	return api.CodeDatasiteInvalidPath
+}
+
+// admitDatasite rejects writes that would create a new datasite over the server's datasite cap,
+// or one that isn't named after a normalized email. It returns the api code of the rejection,
+// and otherwise the func to release the admission with once the write is done.
+func (h *BlobHandler) admitDatasite(key string) (func(), string, error) {
+	owner := datasite.GetOwner(key)
+	if err := h.datasites.Admit(owner); err != nil {
+		if errors.Is(err, datasite.ErrDatasiteNotNormalized) {
+			return nil, api.CodeDatasiteInvalidPath, err
+		}
+		return nil, api.CodeDatasiteLimitReached, err
+	}
+	return func() { h.datasites.Release(owner) }, "", nil
+}
+
 // IsReservedPath checks if a path contains reserved system paths
 func IsReservedPath(path string) bool {
 	// Clean the path
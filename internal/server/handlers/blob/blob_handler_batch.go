@@ -0,0 +1,223 @@
+package blob
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/openmined/syftbox/internal/aclspec"
+	"github.com/openmined/syftbox/internal/blobbatch"
+	"github.com/openmined/syftbox/internal/fileattr"
+	"github.com/openmined/syftbox/internal/server/accesslog"
+	"github.com/openmined/syftbox/internal/server/acl"
+	"github.com/openmined/syftbox/internal/server/blob"
+	"github.com/openmined/syftbox/internal/server/datasite"
+	"github.com/openmined/syftbox/internal/server/handlers/api"
+)
+
+// UploadBatch stores the files of a batch, a tar stream with an entry for each file (see blobbatch).
+// Every file goes through the checks of Upload, the rejected ones are listed in the errors.
+// ACL files and files over blobbatch.MaxEntrySize are uploaded on their own.
+func (h *BlobHandler) UploadBatch(ctx *gin.Context) {
+	user := ctx.GetString("user")
+	reader := blobbatch.NewReader(ctx.Request.Body)
+
+	uploaded := make([]*UploadResponse, 0)
+	errs := make([]*BlobAPIError, 0)
+	for count := 1; ; count++ {
+		entry, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			// the files before it are stored, a retry of the batch uploads them again
+			api.AbortWithError(ctx, http.StatusBadRequest, api.CodeInvalidRequest, fmt.Errorf("invalid batch: %w", err))
+			return
+		}
+
+		if h.maxPresignKeys > 0 && count > h.maxPresignKeys {
+			errs = append(errs, NewBlobAPIError(api.CodeBlobTooManyKeys,
+				fmt.Sprintf("over the limit of %d files per batch", h.maxPresignKeys), entry.Key))
+			continue
+		}
+
+		res, code, err := h.uploadBatchEntry(ctx, user, entry, reader)
+		if err != nil {
+			errs = append(errs, NewBlobAPIError(code, err.Error(), entry.Key))
+			continue
+		}
+		uploaded = append(uploaded, res)
+	}
+
+	code := http.StatusOK
+	if len(errs) > 0 {
+		code = http.StatusMultiStatus
+	}
+
+	ctx.PureJSON(code, &UploadBatchResponse{
+		Uploaded: uploaded,
+		Errors:   errs,
+	})
+}
+
+// uploadBatchEntry stores a file of a batch. It returns the api code of a rejection
+func (h *BlobHandler) uploadBatchEntry(ctx *gin.Context, user string, entry *blobbatch.Entry, content io.Reader) (*UploadResponse, string, error) {
+	key := entry.Key
+	if err := h.blob.KeyRules().Validate(key); err != nil {
+		return nil, keyErrorCode(err), err
+	}
+
+	if !datasite.IsValidPath(key) {
+		return nil, api.CodeDatasiteInvalidPath, fmt.Errorf("invalid key: %s", key)
+	}
+
+	if IsReservedPath(key) {
+		return nil, api.CodeDatasiteInvalidPath, fmt.Errorf("reserved path: %s", key)
+	}
+
+	if aclspec.IsACLFile(key) {
+		return nil, api.CodeInvalidRequest, fmt.Errorf("acl files are uploaded on their own")
+	}
+
+	if entry.Size <= 0 {
+		return nil, api.CodeInvalidRequest, fmt.Errorf("invalid file: size is 0")
+	}
+
+	if entry.Size > blobbatch.MaxEntrySize {
+		return nil, api.CodeInvalidRequest, fmt.Errorf("file of %d bytes is too large for a batch, upload it on its own", entry.Size)
+	}
+
+	attrs, err := fileattr.Parse(entry.Metadata[fileattr.MetaExecutable], entry.Metadata[fileattr.MetaXattrs])
+	if err != nil {
+		return nil, api.CodeInvalidRequest, fmt.Errorf("invalid file attributes: %w", err)
+	}
+
+	if err := h.checkPermissions(ctx, key, acl.AccessWrite); err != nil {
+		if logger := accesslog.GetAccessLogger(ctx); logger != nil {
+			logger.LogAccess(ctx, key, accesslog.AccessTypeWrite, acl.AccessWrite, false, err.Error())
+		}
+		slog.Warn("blob_upload_rejected", "path", key, "user", user, "required_access", "write", "error", err.Error())
+		return nil, api.CodeAccessDenied, err
+	}
+
+	if logger := accesslog.GetAccessLogger(ctx); logger != nil {
+		logger.LogAccess(ctx, key, accesslog.AccessTypeWrite, acl.AccessWrite, true, "")
+	}
+
+	release, code, err := h.admitDatasite(key)
+	if err != nil {
+		return nil, code, err
+	}
+	defer release()
+
+	releaseSize, err := h.datasites.AdmitSize(key, entry.Size)
+	if err != nil {
+		return nil, api.CodeQuotaExceeded, err
+	}
+	defer releaseSize()
+
+	// read the file first, the backends want a body of a known size they can retry
+	body := make([]byte, entry.Size)
+	if _, err := io.ReadFull(content, body); err != nil {
+		return nil, api.CodeInvalidRequest, fmt.Errorf("invalid file: %w", err)
+	}
+
+	result, err := h.blob.Backend().PutObject(ctx.Request.Context(), &blob.PutObjectParams{
+		Key:      key,
+		Size:     entry.Size,
+		Body:     bytes.NewReader(body),
+		Metadata: attrs.Metadata(),
+	})
+	if err != nil {
+		return nil, api.CodeBlobPutFailed, fmt.Errorf("failed to put object: %w", err)
+	}
+
+	return &UploadResponse{
+		Key:          result.Key,
+		Version:      result.Version,
+		ETag:         result.ETag,
+		Size:         result.Size,
+		LastModified: result.LastModified.Format(time.RFC3339),
+		Revision:     result.Revision,
+	}, "", nil
+}
+
+// DownloadBatch streams the requested blobs as a batch, a tar stream with an entry for each key (see blobbatch).
+// A key that can't be downloaded gets an entry with the error, instead of the content.
+func (h *BlobHandler) DownloadBatch(ctx *gin.Context) {
+	var req PresignURLRequest
+	user := ctx.GetString("user")
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		api.AbortWithError(ctx, http.StatusBadRequest, api.CodeInvalidRequest, fmt.Errorf("failed to bind json: %w", err))
+		return
+	}
+
+	if !h.checkPresignKeys(ctx, req.Keys) {
+		return
+	}
+
+	ctx.Header("Content-Type", blobbatch.ContentType)
+	ctx.Status(http.StatusOK)
+
+	writer := blobbatch.NewWriter(ctx.Writer)
+	for _, key := range req.Keys {
+		if err := h.downloadBatchEntry(ctx, user, key, writer); err != nil {
+			// headers are already sent, the client gets an incomplete batch
+			ctx.Error(err)
+			slog.Error("blob batch download", "user", user, "key", key, "error", err)
+			return
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		ctx.Error(err)
+		slog.Error("blob batch download", "user", user, "error", err)
+	}
+}
+
+// downloadBatchEntry writes the entry of a key to the batch. Only failures to write the batch are returned
+func (h *BlobHandler) downloadBatchEntry(ctx *gin.Context, user string, key string, writer *blobbatch.Writer) error {
+	rejected := func(code string, message string) error {
+		return writer.WriteEntry(&blobbatch.Entry{Key: key, ErrorCode: code, ErrorMessage: message}, nil)
+	}
+
+	if !datasite.IsValidPath(key) {
+		return rejected(api.CodeDatasiteInvalidPath, "invalid key")
+	}
+
+	if err := h.checkPermissions(ctx, key, acl.AccessRead); err != nil {
+		if logger := accesslog.GetAccessLogger(ctx); logger != nil {
+			logger.LogAccess(ctx, key, accesslog.AccessTypeRead, acl.AccessRead, false, err.Error())
+		}
+		slog.Warn("blob_download_rejected", "path", key, "user", user, "required_access", "read", "error", err.Error())
+		return rejected(api.CodeAccessDenied, err.Error())
+	}
+
+	if logger := accesslog.GetAccessLogger(ctx); logger != nil {
+		logger.LogAccess(ctx, key, accesslog.AccessTypeRead, acl.AccessRead, true, "")
+	}
+
+	if _, ok := h.blob.Index().Get(key); !ok {
+		return rejected(api.CodeBlobNotFound, "object not found")
+	}
+
+	obj, err := h.blob.Backend().GetObject(ctx.Request.Context(), key)
+	if err != nil {
+		return rejected(presignErrorCode(err), err.Error())
+	}
+	defer obj.Body.Close()
+
+	return writer.WriteEntry(&blobbatch.Entry{
+		Key:          key,
+		ETag:         obj.ETag,
+		Size:         obj.Size,
+		LastModified: obj.LastModified,
+		Metadata:     obj.Metadata,
+	}, obj.Body)
+}
@@ -0,0 +1,209 @@
+package blob
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/openmined/syftbox/internal/db"
+	"github.com/openmined/syftbox/internal/fileattr"
+	"github.com/openmined/syftbox/internal/server/acl"
+	"github.com/openmined/syftbox/internal/server/blob"
+	"github.com/openmined/syftbox/internal/server/datasite"
+	"github.com/openmined/syftbox/internal/server/handlers/api"
+	"github.com/openmined/syftbox/internal/syftsdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newBatchTestServer serves the blob api of a filesystem backend, with alice as the user of every request
+func newBatchTestServer(tb testing.TB) (*syftsdk.SyftSDK, *blob.BlobService) {
+	tb.Helper()
+	var router http.Handler
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		router.ServeHTTP(w, r)
+	}))
+	tb.Cleanup(srv.Close)
+
+	sqlite, err := db.NewSqliteDB(db.WithPath(filepath.Join(tb.TempDir(), "test.db")), db.WithMaxOpenConns(1))
+	require.NoError(tb, err)
+	tb.Cleanup(func() { sqlite.Close() })
+
+	blobSvc, err := blob.NewBlobService(&blob.S3Config{
+		Backend:   blob.BackendFilesystem,
+		Dir:       tb.TempDir(),
+		PublicURL: srv.URL,
+	}, sqlite)
+	require.NoError(tb, err)
+	require.NoError(tb, blobSvc.Start(tb.Context()))
+
+	datasites := datasite.NewDatasiteService(blobSvc, acl.NewACLService(blobSvc), "", &datasite.Config{})
+	h := New(blobSvc, nil, datasites, NewPresignCache(0), 0)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	if presignH := blobSvc.PresignHandler(); presignH != nil {
+		r.GET(blob.FSPresignPath+"*key", gin.WrapH(presignH))
+	}
+	v1 := r.Group("/api/v1")
+	v1.Use(func(ctx *gin.Context) { ctx.Set("user", "alice@example.com") })
+	v1.PUT("/blob/upload", h.Upload)
+	v1.POST("/blob/upload/batch", h.UploadBatch)
+	v1.POST("/blob/download", h.DownloadObjectsPresigned)
+	v1.POST("/blob/download/batch", h.DownloadBatch)
+	router = r
+
+	sdk, err := syftsdk.New(&syftsdk.SyftSDKConfig{
+		BaseURL:      srv.URL,
+		Email:        "alice@example.com",
+		RefreshToken: "token",
+	})
+	require.NoError(tb, err)
+	return sdk, blobSvc
+}
+
+// writeTestFiles writes count small files, returning their upload params
+func writeTestFiles(tb testing.TB, count int) []*syftsdk.UploadParams {
+	tb.Helper()
+	dir := tb.TempDir()
+	files := make([]*syftsdk.UploadParams, 0, count)
+	for i := range count {
+		path := filepath.Join(dir, fmt.Sprintf("%d.txt", i))
+		require.NoError(tb, os.WriteFile(path, []byte(fmt.Sprintf("file %d", i)), 0o644))
+		files = append(files, &syftsdk.UploadParams{Key: fmt.Sprintf("alice@example.com/public/%d.txt", i), FilePath: path})
+	}
+	return files
+}
+
+func TestBatchRoundTrip(t *testing.T) {
+	sdk, blobSvc := newBatchTestServer(t)
+	files := writeTestFiles(t, 3)
+	executable := true
+	files[0].Attrs = &fileattr.Attrs{Executable: &executable}
+	files = append(files,
+		&syftsdk.UploadParams{Key: "../escape.txt", FilePath: files[1].FilePath},
+		&syftsdk.UploadParams{Key: "alice@example.com/public/syft.pub.yaml", FilePath: files[1].FilePath},
+	)
+
+	uploaded, err := sdk.Blob.UploadBatch(t.Context(), &syftsdk.UploadBatchParams{Files: files})
+	require.NoError(t, err)
+	require.Len(t, uploaded.Uploaded, 3)
+	require.Len(t, uploaded.Errors, 2)
+	assert.Equal(t, api.CodeDatasiteInvalidPath, uploaded.Errors[0].Code)
+	assert.Equal(t, api.CodeInvalidRequest, uploaded.Errors[1].Code) // acl files go on their own
+
+	for _, res := range uploaded.Uploaded {
+		_, ok := blobSvc.Index().Get(res.Key)
+		assert.True(t, ok, res.Key)
+	}
+
+	keys := []string{files[0].Key, files[1].Key, files[2].Key, "alice@example.com/public/missing.txt"}
+	results, err := sdk.Blob.DownloadBatch(t.Context(), &syftsdk.PresignedParams{Keys: keys}, t.TempDir())
+	require.NoError(t, err)
+	require.Len(t, results, 4)
+
+	for i, res := range results[:3] {
+		require.NoError(t, res.Error)
+		assert.Equal(t, keys[i], res.Key)
+		assert.Equal(t, uploaded.Uploaded[i].ETag, res.ETag)
+		content, err := os.ReadFile(res.DownloadPath)
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("file %d", i), string(content))
+	}
+	require.NotNil(t, results[0].Attrs)
+	assert.Equal(t, &executable, results[0].Attrs.Executable)
+	assert.Nil(t, results[1].Attrs)
+
+	var blobErr *syftsdk.BlobError
+	require.ErrorAs(t, results[3].Error, &blobErr)
+	assert.Equal(t, api.CodeBlobNotFound, blobErr.Code)
+}
+
+// BenchmarkSmallFileSync compares transferring 1000 tiny files one request at a time and in batches of 100
+func BenchmarkSmallFileSync(b *testing.B) {
+	const workers = 8
+	sdk, _ := newBatchTestServer(b)
+	files := writeTestFiles(b, 1000)
+	keys := make([]string, 0, len(files))
+	for _, file := range files {
+		keys = append(keys, file.Key)
+	}
+
+	// parallel runs fn for each item, on the workers
+	parallel := func(n int, fn func(i int)) {
+		var wg sync.WaitGroup
+		items := make(chan int, n)
+		for i := range n {
+			items <- i
+		}
+		close(items)
+		wg.Add(workers)
+		for range workers {
+			go func() {
+				defer wg.Done()
+				for i := range items {
+					fn(i)
+				}
+			}()
+		}
+		wg.Wait()
+	}
+
+	b.Run("upload", func(b *testing.B) {
+		b.Run("unbatched", func(b *testing.B) {
+			for b.Loop() {
+				parallel(len(files), func(i int) {
+					_, err := sdk.Blob.Upload(b.Context(), files[i])
+					require.NoError(b, err)
+				})
+			}
+		})
+		b.Run("batched", func(b *testing.B) {
+			batches := slices.Collect(slices.Chunk(files, 100))
+			for b.Loop() {
+				parallel(len(batches), func(i int) {
+					res, err := sdk.Blob.UploadBatch(b.Context(), &syftsdk.UploadBatchParams{Files: batches[i]})
+					require.NoError(b, err)
+					require.Empty(b, res.Errors)
+				})
+			}
+		})
+	})
+
+	b.Run("download", func(b *testing.B) {
+		b.Run("unbatched", func(b *testing.B) {
+			chunks := slices.Collect(slices.Chunk(keys, 100))
+			for b.Loop() {
+				dir := b.TempDir()
+				for _, chunk := range chunks {
+					urls, err := sdk.Blob.Download(b.Context(), &syftsdk.PresignedParams{Keys: chunk})
+					require.NoError(b, err)
+					jobs := make([]*syftsdk.DownloadJob, 0, len(urls.URLs))
+					for _, url := range urls.URLs {
+						jobs = append(jobs, &syftsdk.DownloadJob{URL: url.URL, TargetDir: dir, Name: filepath.Base(url.Key)})
+					}
+					for res := range syftsdk.Downloader(b.Context(), &syftsdk.DownloadOpts{Workers: workers, Jobs: jobs}) {
+						require.NoError(b, res.Error)
+					}
+				}
+			}
+		})
+		b.Run("batched", func(b *testing.B) {
+			batches := slices.Collect(slices.Chunk(keys, 100))
+			for b.Loop() {
+				dir := b.TempDir()
+				parallel(len(batches), func(i int) {
+					results, err := sdk.Blob.DownloadBatch(b.Context(), &syftsdk.PresignedParams{Keys: batches[i]}, dir)
+					require.NoError(b, err)
+					require.Len(b, results, len(batches[i]))
+				})
+			}
+		})
+	})
+}
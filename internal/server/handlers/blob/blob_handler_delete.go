@@ -14,7 +14,6 @@ import (
 
 func (h *BlobHandler) DeleteObjects(ctx *gin.Context) {
 	var req DeleteRequest
-	user := ctx.GetString("user")
 
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		api.AbortWithError(ctx, http.StatusBadRequest, api.CodeInvalidRequest, fmt.Errorf("failed to bind json: %w", err))
@@ -29,7 +28,7 @@ func (h *BlobHandler) DeleteObjects(ctx *gin.Context) {
 			continue
 		}
 
-		if err := h.checkPermissions(key, user, acl.AccessWrite); err != nil {
+		if err := h.checkPermissions(ctx, key, acl.AccessWrite); err != nil {
 			errors = append(errors, NewBlobAPIError(api.CodeAccessDenied, err.Error(), key))
 			continue
 		}
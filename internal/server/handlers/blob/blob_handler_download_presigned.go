@@ -1,6 +1,7 @@
 package blob
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
@@ -8,6 +9,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/openmined/syftbox/internal/server/accesslog"
 	"github.com/openmined/syftbox/internal/server/acl"
+	"github.com/openmined/syftbox/internal/server/blob"
 	"github.com/openmined/syftbox/internal/server/datasite"
 	"github.com/openmined/syftbox/internal/server/handlers/api"
 )
@@ -21,9 +23,13 @@ func (h *BlobHandler) DownloadObjectsPresigned(ctx *gin.Context) {
 		return
 	}
 
+	if !h.checkPresignKeys(ctx, req.Keys) {
+		return
+	}
+
 	urls := make([]*BlobURL, 0, len(req.Keys))
 	errors := make([]*BlobAPIError, 0)
-	index := h.blob.Index()
+	index := h.blob.ReadIndex()
 	for _, key := range req.Keys {
 		if !datasite.IsValidPath(key) {
 			errors = append(errors, &BlobAPIError{
@@ -36,7 +42,7 @@ func (h *BlobHandler) DownloadObjectsPresigned(ctx *gin.Context) {
 			continue
 		}
 
-		if err := h.checkPermissions(key, user, acl.AccessRead); err != nil {
+		if err := h.checkPermissions(ctx, key, acl.AccessRead); err != nil {
 			if logger := accesslog.GetAccessLogger(ctx); logger != nil {
 				logger.LogAccess(ctx, key, accesslog.AccessTypeRead, acl.AccessRead, false, err.Error())
 			}
@@ -55,7 +61,7 @@ func (h *BlobHandler) DownloadObjectsPresigned(ctx *gin.Context) {
 			logger.LogAccess(ctx, key, accesslog.AccessTypeRead, acl.AccessRead, true, "")
 		}
 
-		_, ok := index.Get(key)
+		info, ok := index.Get(key)
 		if !ok {
 			errors = append(errors, &BlobAPIError{
 				SyftAPIError: api.SyftAPIError{
@@ -67,17 +73,26 @@ func (h *BlobHandler) DownloadObjectsPresigned(ctx *gin.Context) {
 			continue
 		}
 
-		url, err := h.blob.Backend().GetObjectPresigned(ctx, key)
+		presign := func() (string, error) {
+			return h.blob.Backend().GetObjectPresigned(ctx, key)
+		}
+		url, err := h.presignCache.GetOrPresign(user, key, info.ETag, presign)
+		if err == nil && h.blob.Presigns().IsRevoked(url) {
+			// a cached url was revoked, the user gets a new one
+			h.presignCache.Forget(user, key, info.ETag)
+			url, err = h.presignCache.GetOrPresign(user, key, info.ETag, presign)
+		}
 		if err != nil {
 			errors = append(errors, &BlobAPIError{
 				SyftAPIError: api.SyftAPIError{
-					Code:    api.CodeBlobGetFailed,
+					Code:    presignErrorCode(err),
 					Message: err.Error(),
 				},
 				Key: key,
 			})
 			continue
 		}
+		h.blob.Presigns().Record(url, key, user, http.MethodGet)
 		urls = append(urls, &BlobURL{
 			Key: key,
 			Url: url,
@@ -96,3 +111,11 @@ func (h *BlobHandler) DownloadObjectsPresigned(ctx *gin.Context) {
 		Errors: errors,
 	})
 }
+
+// presignErrorCode tells the keys that can be retried once the blob storage is less busy
+func presignErrorCode(err error) string {
+	if errors.Is(err, blob.ErrBackendBusy) {
+		return api.CodeBlobBusy
+	}
+	return api.CodeBlobGetFailed
+}
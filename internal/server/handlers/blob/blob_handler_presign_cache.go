@@ -0,0 +1,90 @@
+package blob
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/golang-lru/v2/expirable"
+	"github.com/openmined/syftbox/internal/server/blob"
+)
+
+const (
+	presignCacheSize = 100_000
+	// cached urls are only served while they have at least this long to live,
+	// so that clients have enough time to start the download
+	presignMinRemaining = time.Minute
+)
+
+type presignCacheKey string
+
+// the etag is part of the key, so a change to the blob never serves the old url
+func newPresignCacheKey(user, key, etag string) presignCacheKey {
+	return presignCacheKey(fmt.Sprintf("%s:%s:%s", user, key, etag))
+}
+
+type presignedURL struct {
+	url       string
+	expiresAt time.Time
+}
+
+// PresignCache reuses presigned download urls of unchanged blobs, for clients that repeatedly ask for the same keys
+type PresignCache struct {
+	index     *expirable.LRU[presignCacheKey, *presignedURL]
+	urlExpiry time.Duration
+	now       func() time.Time
+}
+
+// NewPresignCache creates a cache that keeps urls for ttl. A ttl of 0 disables the cache.
+func NewPresignCache(ttl time.Duration) *PresignCache {
+	if ttl <= 0 {
+		return &PresignCache{}
+	}
+
+	return &PresignCache{
+		index:     expirable.NewLRU[presignCacheKey, *presignedURL](presignCacheSize, nil, ttl),
+		urlExpiry: blob.DownloadURLExpiry,
+		now:       time.Now,
+	}
+}
+
+// GetOrPresign returns the cached url for the blob, or presigns a new one and caches it
+func (c *PresignCache) GetOrPresign(user, key, etag string, presign func() (string, error)) (string, error) {
+	if c.index == nil {
+		return presign()
+	}
+
+	cacheKey := newPresignCacheKey(user, key, etag)
+	now := c.now()
+
+	if cached, ok := c.index.Get(cacheKey); ok && now.Add(presignMinRemaining).Before(cached.expiresAt) {
+		return cached.url, nil
+	}
+
+	url, err := presign()
+	if err != nil {
+		return "", err
+	}
+
+	c.index.Add(cacheKey, &presignedURL{
+		url:       url,
+		expiresAt: now.Add(c.urlExpiry),
+	})
+
+	return url, nil
+}
+
+// Forget drops the cached url of the blob
+func (c *PresignCache) Forget(user, key, etag string) {
+	if c.index == nil {
+		return
+	}
+	c.index.Remove(newPresignCacheKey(user, key, etag))
+}
+
+// Count returns the number of cached urls
+func (c *PresignCache) Count() int {
+	if c.index == nil {
+		return 0
+	}
+	return c.index.Len()
+}
@@ -0,0 +1,91 @@
+package blob
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakePresigner struct {
+	calls int
+}
+
+func (p *fakePresigner) presign() (string, error) {
+	p.calls++
+	return fmt.Sprintf("https://bucket/object?sig=%d", p.calls), nil
+}
+
+func TestPresignCacheHit(t *testing.T) {
+	cache := NewPresignCache(time.Minute)
+	presigner := &fakePresigner{}
+
+	first, err := cache.GetOrPresign("bob@example.com", "alice@example.com/public/a.txt", "etag1", presigner.presign)
+	require.NoError(t, err)
+
+	second, err := cache.GetOrPresign("bob@example.com", "alice@example.com/public/a.txt", "etag1", presigner.presign)
+	require.NoError(t, err)
+
+	assert.Equal(t, first, second)
+	assert.Equal(t, 1, presigner.calls)
+}
+
+func TestPresignCacheETagChange(t *testing.T) {
+	cache := NewPresignCache(time.Minute)
+	presigner := &fakePresigner{}
+
+	first, err := cache.GetOrPresign("bob@example.com", "alice@example.com/public/a.txt", "etag1", presigner.presign)
+	require.NoError(t, err)
+
+	second, err := cache.GetOrPresign("bob@example.com", "alice@example.com/public/a.txt", "etag2", presigner.presign)
+	require.NoError(t, err)
+
+	assert.NotEqual(t, first, second)
+	assert.Equal(t, 2, presigner.calls)
+}
+
+func TestPresignCacheNearExpiry(t *testing.T) {
+	cache := NewPresignCache(time.Hour) // longer than the url expiry, to exercise the url's own expiry
+	presigner := &fakePresigner{}
+
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	first, err := cache.GetOrPresign("bob@example.com", "alice@example.com/public/a.txt", "etag1", presigner.presign)
+	require.NoError(t, err)
+
+	// never serve a url that is about to expire
+	now = now.Add(cache.urlExpiry - presignMinRemaining)
+
+	second, err := cache.GetOrPresign("bob@example.com", "alice@example.com/public/a.txt", "etag1", presigner.presign)
+	require.NoError(t, err)
+
+	assert.NotEqual(t, first, second)
+	assert.Equal(t, 2, presigner.calls)
+}
+
+func TestPresignCacheDisabled(t *testing.T) {
+	cache := NewPresignCache(0)
+	presigner := &fakePresigner{}
+
+	for range 3 {
+		_, err := cache.GetOrPresign("bob@example.com", "alice@example.com/public/a.txt", "etag1", presigner.presign)
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, 3, presigner.calls)
+	assert.Zero(t, cache.Count())
+}
+
+func TestPresignCacheSkipsErrors(t *testing.T) {
+	cache := NewPresignCache(time.Minute)
+
+	_, err := cache.GetOrPresign("bob@example.com", "alice@example.com/public/a.txt", "etag1", func() (string, error) {
+		return "", errors.New("presign failed")
+	})
+	assert.Error(t, err)
+	assert.Zero(t, cache.Count())
+}
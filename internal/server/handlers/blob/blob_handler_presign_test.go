@@ -0,0 +1,39 @@
+package blob
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/openmined/syftbox/internal/server/handlers/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPresignTooManyKeys(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// the request is rejected before any key is looked at, so no services are needed
+	h := &BlobHandler{maxPresignKeys: 2}
+	r := gin.New()
+	r.POST("/blob/download", h.DownloadObjectsPresigned)
+	r.POST("/blob/upload/presigned", h.UploadPresigned)
+
+	keys := []string{"alice@example.com/a.txt", "alice@example.com/b.txt", "alice@example.com/c.txt"}
+	body, err := json.Marshal(&PresignURLRequest{Keys: keys})
+	require.NoError(t, err)
+
+	for _, path := range []string{"/blob/download", "/blob/upload/presigned"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body)))
+		require.Equal(t, http.StatusBadRequest, w.Code, path)
+
+		var resp api.SyftAPIError
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, api.CodeBlobTooManyKeys, resp.Code, path)
+		assert.Equal(t, "3 keys over the limit of 2 per request, split them into requests of at most 2 keys", resp.Message, path)
+	}
+}
@@ -0,0 +1,139 @@
+package blob
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/openmined/syftbox/internal/db"
+	"github.com/openmined/syftbox/internal/server/acl"
+	"github.com/openmined/syftbox/internal/server/blob"
+	"github.com/openmined/syftbox/internal/server/datasite"
+	"github.com/openmined/syftbox/internal/server/handlers/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newQuotaTestRouter(t *testing.T, quota int64) (*gin.Engine, *blob.BlobService) {
+	t.Helper()
+	sqlite, err := db.NewSqliteDB(db.WithPath(filepath.Join(t.TempDir(), "test.db")), db.WithMaxOpenConns(1))
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlite.Close() })
+
+	blobSvc, err := blob.NewBlobService(&blob.S3Config{
+		Backend:   blob.BackendFilesystem,
+		Dir:       t.TempDir(),
+		PublicURL: "http://localhost:8080",
+	}, sqlite)
+	require.NoError(t, err)
+	require.NoError(t, blobSvc.Start(t.Context()))
+
+	// alice's datasite exists already, so that its default ACLs don't take up the quota
+	_, err = blobSvc.Backend().PutObject(t.Context(), &blob.PutObjectParams{Key: "alice@example.com/syft.pub.yaml", Body: strings.NewReader("")})
+	require.NoError(t, err)
+	datasites := datasite.NewDatasiteService(blobSvc, acl.NewACLService(blobSvc), "", &datasite.Config{QuotaBytes: quota})
+	require.NoError(t, datasites.Start(t.Context()))
+	h := New(blobSvc, nil, datasites, nil, 0)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(ctx *gin.Context) { ctx.Set("user", "alice@example.com") })
+	r.PUT("/blob/upload", h.Upload)
+	r.POST("/blob/upload/presigned", h.UploadPresigned)
+	return r, blobSvc
+}
+
+func uploadFile(t *testing.T, r *gin.Engine, key string, size int) *httptest.ResponseRecorder {
+	t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", filepath.Base(key))
+	require.NoError(t, err)
+	part.Write(bytes.Repeat([]byte("x"), size))
+	require.NoError(t, form.Close())
+
+	req := httptest.NewRequest(http.MethodPut, "/blob/upload?key="+key, &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestUploadOverQuota(t *testing.T) {
+	r, blobSvc := newQuotaTestRouter(t, 300)
+
+	for i := range 3 {
+		w := uploadFile(t, r, fmt.Sprintf("alice@example.com/public/%d.txt", i), 100)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	}
+	assert.Equal(t, int64(300), blobSvc.Index().Usage("alice@example.com"))
+
+	// the next upload goes over the quota
+	w := uploadFile(t, r, "alice@example.com/public/3.txt", 100)
+	require.Equal(t, http.StatusForbidden, w.Code)
+	var apiErr api.SyftAPIError
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &apiErr))
+	assert.Equal(t, api.CodeQuotaExceeded, apiErr.Code)
+
+	// so are presigned uploads
+	presigned := presignUploads(t, r, &PresignURLRequest{Keys: []string{"alice@example.com/public/3.txt"}, Sizes: []int64{100}})
+	require.Len(t, presigned.Errors, 1)
+	assert.Equal(t, api.CodeQuotaExceeded, presigned.Errors[0].Code)
+
+	// overwrites only count the difference
+	w = uploadFile(t, r, "alice@example.com/public/0.txt", 50)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	// deletes free up space
+	_, err := blobSvc.Backend().DeleteObject(t.Context(), "alice@example.com/public/1.txt")
+	require.NoError(t, err)
+	w = uploadFile(t, r, "alice@example.com/public/3.txt", 150)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, int64(300), blobSvc.Index().Usage("alice@example.com"))
+}
+
+func presignUploads(t *testing.T, r *gin.Engine, req *PresignURLRequest) *PresignURLResponse {
+	t.Helper()
+	body, err := json.Marshal(req)
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/blob/upload/presigned", bytes.NewReader(body)))
+	var resp PresignURLResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp), w.Body.String())
+	return &resp
+}
+
+func TestUploadPresignedQuota(t *testing.T) {
+	r, _ := newQuotaTestRouter(t, 300)
+
+	w := uploadFile(t, r, "alice@example.com/public/0.txt", 100)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	// the size of the upload is signed into the url
+	presigned := presignUploads(t, r, &PresignURLRequest{Keys: []string{"alice@example.com/public/1.txt"}, Sizes: []int64{150}})
+	require.Empty(t, presigned.Errors)
+	require.Len(t, presigned.URLs, 1)
+	assert.Contains(t, presigned.URLs[0].Url, "size=150")
+
+	// the sizes of the keys of a request add up
+	presigned = presignUploads(t, r, &PresignURLRequest{
+		Keys:  []string{"alice@example.com/public/1.txt", "alice@example.com/public/2.txt"},
+		Sizes: []int64{150, 100},
+	})
+	require.Len(t, presigned.URLs, 1)
+	assert.Equal(t, "alice@example.com/public/1.txt", presigned.URLs[0].Key)
+	require.Len(t, presigned.Errors, 1)
+	assert.Equal(t, api.CodeQuotaExceeded, presigned.Errors[0].Code)
+
+	// an upload of unknown size can't be checked against the quota
+	presigned = presignUploads(t, r, &PresignURLRequest{Keys: []string{"alice@example.com/public/1.txt"}})
+	require.Len(t, presigned.Errors, 1)
+	assert.Equal(t, api.CodeQuotaExceeded, presigned.Errors[0].Code)
+}
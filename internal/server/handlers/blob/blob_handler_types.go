@@ -7,8 +7,9 @@ import (
 )
 
 type BlobURL struct {
-	Key string `json:"key"`
-	Url string `json:"url"`
+	Key     string            `json:"key"`
+	Url     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"` // headers to send with the request
 }
 
 type BlobAPIError struct {
@@ -32,6 +33,13 @@ func (e *BlobAPIError) Error() string {
 
 type UploadRequest struct {
 	Key string `form:"key" binding:"required"`
+	// set when the file is compressed. etag & size are of the uncompressed content
+	Codec string `form:"codec"`
+	ETag  string `form:"etag"`
+	Size  int64  `form:"size"`
+	// file attributes to store with the blob, see fileattr
+	Executable string `form:"syft-executable"`
+	Xattrs     string `form:"syft-xattrs"`
 	// MD5       string `form:"md5"`
 	// CRC64NVME string `form:"crc64nvme"`
 	// CRC32C    string `form:"crc32c"`
@@ -44,10 +52,14 @@ type UploadResponse struct {
 	ETag         string `json:"etag"`
 	Size         int64  `json:"size"`
 	LastModified string `json:"lastModified"`
+	Revision     int64  `json:"revision,omitempty"`
 }
 
 type PresignURLRequest struct {
 	Keys []string `json:"keys" binding:"required,min=1"`
+	// the sizes of the uploads, in the order of the keys. They are signed into the urls, which only accept uploads
+	// of that size. Required when the datasites have a quota
+	Sizes []int64 `json:"sizes,omitempty"`
 }
 
 type PresignURLResponse struct {
@@ -63,3 +75,8 @@ type DeleteResponse struct {
 	Deleted []string        `json:"deleted"`
 	Errors  []*BlobAPIError `json:"errors"`
 }
+
+type UploadBatchResponse struct {
+	Uploaded []*UploadResponse `json:"uploaded"`
+	Errors   []*BlobAPIError   `json:"errors"`
+}
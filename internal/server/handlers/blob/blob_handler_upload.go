@@ -1,13 +1,19 @@
 package blob
 
 import (
+	"crypto/md5"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/openmined/syftbox/internal/aclspec"
+	"github.com/openmined/syftbox/internal/blobcodec"
+	"github.com/openmined/syftbox/internal/fileattr"
 	"github.com/openmined/syftbox/internal/server/accesslog"
 	"github.com/openmined/syftbox/internal/server/acl"
 	"github.com/openmined/syftbox/internal/server/blob"
@@ -31,6 +37,27 @@ func (h *BlobHandler) Upload(ctx *gin.Context) {
 
 	// todo check if new change using etag
 
+	if err := blobcodec.Validate(req.Codec); err != nil {
+		api.AbortWithError(ctx, http.StatusBadRequest, api.CodeInvalidRequest, err)
+		return
+	}
+
+	if blobcodec.IsCompressed(req.Codec) && (req.ETag == "" || req.Size <= 0) {
+		api.AbortWithError(ctx, http.StatusBadRequest, api.CodeInvalidRequest, fmt.Errorf("compressed file requires etag and size"))
+		return
+	}
+
+	attrs, err := fileattr.Parse(req.Executable, req.Xattrs)
+	if err != nil {
+		api.AbortWithError(ctx, http.StatusBadRequest, api.CodeInvalidRequest, fmt.Errorf("invalid file attributes: %w", err))
+		return
+	}
+
+	if err := h.blob.KeyRules().Validate(req.Key); err != nil {
+		api.AbortWithError(ctx, http.StatusBadRequest, keyErrorCode(err), err)
+		return
+	}
+
 	if !datasite.IsValidPath(req.Key) {
 		api.AbortWithError(ctx, http.StatusBadRequest, api.CodeDatasiteInvalidPath, fmt.Errorf("invalid key: %s", req.Key))
 		return
@@ -42,7 +69,7 @@ func (h *BlobHandler) Upload(ctx *gin.Context) {
 		return
 	}
 
-	if err := h.checkPermissions(req.Key, user, acl.AccessWrite); err != nil {
+	if err := h.checkPermissions(ctx, req.Key, acl.AccessWrite); err != nil {
 		if logger := accesslog.GetAccessLogger(ctx); logger != nil {
 			logger.LogAccess(ctx, req.Key, accesslog.AccessTypeWrite, acl.AccessWrite, false, err.Error())
 		}
@@ -55,6 +82,13 @@ func (h *BlobHandler) Upload(ctx *gin.Context) {
 		logger.LogAccess(ctx, req.Key, accesslog.AccessTypeWrite, acl.AccessWrite, true, "")
 	}
 
+	release, code, err := h.admitDatasite(req.Key)
+	if err != nil {
+		api.AbortWithError(ctx, http.StatusForbidden, code, err)
+		return
+	}
+	defer release()
+
 	// get form file
 	file, err := ctx.FormFile("file")
 	if err != nil {
@@ -75,10 +109,37 @@ func (h *BlobHandler) Upload(ctx *gin.Context) {
 	}
 	defer fd.Close()
 
+	// the quota counts the size of the content, like the index
+	size := file.Size
+	if blobcodec.IsCompressed(req.Codec) {
+		// the index stores the etag & size of the content, which the client can't be trusted with
+		if err := verifyCompressed(req.Codec, fd, req.ETag, req.Size); err != nil {
+			api.AbortWithError(ctx, http.StatusBadRequest, api.CodeInvalidRequest, err)
+			return
+		}
+		if _, err := fd.Seek(0, io.SeekStart); err != nil {
+			api.AbortWithError(ctx, http.StatusInternalServerError, api.CodeBlobPutFailed, err)
+			return
+		}
+		size = req.Size
+	}
+	releaseSize, err := h.datasites.AdmitSize(req.Key, size)
+	if err != nil {
+		api.AbortWithError(ctx, http.StatusForbidden, api.CodeQuotaExceeded, err)
+		return
+	}
+	defer releaseSize()
+
 	result, err := h.blob.Backend().PutObject(ctx.Request.Context(), &blob.PutObjectParams{
 		Key:  req.Key,
 		Size: file.Size,
 		Body: fd,
+
+		Codec:       req.Codec,
+		ContentETag: req.ETag,
+		ContentSize: req.Size,
+
+		Metadata: attrs.Metadata(),
 	})
 	if err != nil {
 		api.AbortWithError(ctx, http.StatusInternalServerError, api.CodeBlobPutFailed, fmt.Errorf("failed to put object: %w", err))
@@ -92,5 +153,29 @@ func (h *BlobHandler) Upload(ctx *gin.Context) {
 		ETag:         result.ETag,
 		Size:         result.Size,
 		LastModified: result.LastModified.Format(time.RFC3339),
+		Revision:     result.Revision,
 	})
 }
+
+// verifyCompressed checks that the compressed body decompresses to the content of the given etag & size.
+// It reads at most one byte past the size, so that a small body can't decompress into a large one.
+func verifyCompressed(codec string, body io.Reader, etag string, size int64) error {
+	r, err := blobcodec.NewReader(codec, body)
+	if err != nil {
+		return fmt.Errorf("invalid %s file: %w", codec, err)
+	}
+	defer r.Close()
+
+	h := md5.New()
+	n, err := io.Copy(h, io.LimitReader(r, size+1))
+	if err != nil {
+		return fmt.Errorf("invalid %s file: %w", codec, err)
+	}
+	if n != size {
+		return fmt.Errorf("compressed file decompresses to more or less than %d bytes", size)
+	}
+	if actual := hex.EncodeToString(h.Sum(nil)); !strings.EqualFold(actual, strings.Trim(etag, `"`)) {
+		return fmt.Errorf("etag %s doesn't match the decompressed content %s", etag, actual)
+	}
+	return nil
+}
@@ -17,24 +17,35 @@ import (
 
 func (h *BlobHandler) UploadACL(ctx *gin.Context) {
 	var req UploadRequest
-	user := ctx.GetString("user")
 
 	if err := ctx.ShouldBindQuery(&req); err != nil {
 		api.AbortWithError(ctx, http.StatusBadRequest, api.CodeInvalidRequest, fmt.Errorf("failed to bind query: %w", err))
 		return
 	}
 
+	if err := h.blob.KeyRules().Validate(req.Key); err != nil {
+		api.AbortWithError(ctx, http.StatusBadRequest, keyErrorCode(err), err)
+		return
+	}
+
 	if !(datasite.IsValidPath(req.Key) && aclspec.IsACLFile(req.Key)) {
 		api.AbortWithError(ctx, http.StatusBadRequest, api.CodeDatasiteInvalidPath, fmt.Errorf("invalid ruleset path: %s", req.Key))
 		return
 	}
 
 	// check if user has admin rights
-	if err := h.checkPermissions(req.Key, user, acl.AccessAdmin); err != nil {
+	if err := h.checkPermissions(ctx, req.Key, acl.AccessAdmin); err != nil {
 		api.AbortWithError(ctx, http.StatusForbidden, api.CodeAccessDenied, err)
 		return
 	}
 
+	release, code, err := h.admitDatasite(req.Key)
+	if err != nil {
+		api.AbortWithError(ctx, http.StatusForbidden, code, err)
+		return
+	}
+	defer release()
+
 	// get form file
 	file, err := ctx.FormFile("file")
 	if err != nil {
@@ -65,9 +76,10 @@ func (h *BlobHandler) UploadACL(ctx *gin.Context) {
 	aclBytesReader := bytes.NewReader(fdBytes)
 
 	// load aclspec
-	ruleset, err := aclspec.LoadFromReader(req.Key, aclBytesReader)
+	ruleset, err := h.acl.LoadRuleSet(req.Key, aclBytesReader)
 	if err != nil {
-		api.AbortWithError(ctx, http.StatusBadRequest, api.CodeInvalidRequest, fmt.Errorf("failed to read ruleset: %w", err))
+		api.AbortWithError(ctx, http.StatusBadRequest, api.CodeACLInvalid, fmt.Errorf("failed to read ruleset: %w", err))
+		return
 	}
 
 	// upload file to s3
@@ -97,5 +109,6 @@ func (h *BlobHandler) UploadACL(ctx *gin.Context) {
 		ETag:         result.ETag,
 		Size:         result.Size,
 		LastModified: result.LastModified.Format(time.RFC3339),
+		Revision:     result.Revision,
 	})
 }
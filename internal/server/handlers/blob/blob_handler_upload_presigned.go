@@ -19,9 +19,51 @@ func (h *BlobHandler) UploadPresigned(ctx *gin.Context) {
 		return
 	}
 
+	if !h.checkPresignKeys(ctx, req.Keys) {
+		return
+	}
+
+	if len(req.Sizes) > 0 && len(req.Sizes) != len(req.Keys) {
+		api.AbortWithError(ctx, http.StatusBadRequest, api.CodeInvalidRequest, fmt.Errorf("%d sizes for %d keys", len(req.Sizes), len(req.Keys)))
+		return
+	}
+
+	// the sizes of the keys are reserved until all of them are presigned, so that they add up
+	var releases []func()
+	defer func() {
+		for _, release := range releases {
+			release()
+		}
+	}()
+
 	urls := make([]*BlobURL, 0, len(req.Keys))
 	errors := make([]*BlobAPIError, 0)
-	for _, key := range req.Keys {
+	for i, rawKey := range req.Keys {
+		key := rawKey
+		size := int64(-1) // unknown
+		if len(req.Sizes) > 0 {
+			if size = req.Sizes[i]; size < 0 {
+				errors = append(errors, &BlobAPIError{
+					SyftAPIError: api.SyftAPIError{
+						Code:    api.CodeInvalidRequest,
+						Message: "invalid size",
+					},
+					Key: rawKey,
+				})
+				continue
+			}
+		}
+		if err := h.blob.KeyRules().Validate(key); err != nil {
+			errors = append(errors, &BlobAPIError{
+				SyftAPIError: api.SyftAPIError{
+					Code:    keyErrorCode(err),
+					Message: err.Error(),
+				},
+				Key: rawKey,
+			})
+			continue
+		}
+
 		if !datasite.IsValidPath(key) {
 			errors = append(errors, &BlobAPIError{
 				SyftAPIError: api.SyftAPIError{
@@ -45,7 +87,7 @@ func (h *BlobHandler) UploadPresigned(ctx *gin.Context) {
 			continue
 		}
 
-		if err := h.checkPermissions(key, user, acl.AccessWrite); err != nil {
+		if err := h.checkPermissions(ctx, key, acl.AccessWrite); err != nil {
 			errors = append(errors, &BlobAPIError{
 				SyftAPIError: api.SyftAPIError{
 					Code:    api.CodeAccessDenied,
@@ -56,8 +98,38 @@ func (h *BlobHandler) UploadPresigned(ctx *gin.Context) {
 			continue
 		}
 
-		url, err := h.blob.Backend().PutObjectPresigned(ctx, key)
+		// the upload goes to the backend directly, so a new datasite keeps its slot for a while even if it fails
+		release, code, err := h.admitDatasite(key)
+		if err != nil {
+			errors = append(errors, &BlobAPIError{
+				SyftAPIError: api.SyftAPIError{
+					Code:    code,
+					Message: err.Error(),
+				},
+				Key: key,
+			})
+			continue
+		}
+
+		// the upload goes to the backend directly, its size is signed into the url to keep it within the quota
+		releaseSize, err := h.datasites.AdmitSize(key, size)
+		if err != nil {
+			release()
+			errors = append(errors, &BlobAPIError{
+				SyftAPIError: api.SyftAPIError{
+					Code:    api.CodeQuotaExceeded,
+					Message: err.Error(),
+				},
+				Key: key,
+			})
+			continue
+		}
+
+		releases = append(releases, releaseSize)
+
+		url, err := h.blob.Backend().PutObjectPresigned(ctx, key, size)
 		if err != nil {
+			release()
 			errors = append(errors, &BlobAPIError{
 				SyftAPIError: api.SyftAPIError{
 					Code:    api.CodeBlobPutFailed,
@@ -67,9 +139,12 @@ func (h *BlobHandler) UploadPresigned(ctx *gin.Context) {
 			})
 			continue
 		}
+		h.blob.Presigns().Record(url, key, user, http.MethodPut)
+		h.datasites.Detach(datasite.GetOwner(key))
 		urls = append(urls, &BlobURL{
-			Key: key,
-			Url: url,
+			Key:     key,
+			Url:     url,
+			Headers: h.blob.UploadHeaders(),
 		})
 	}
 
@@ -0,0 +1,79 @@
+package blob
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/md5"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func uploadCompressed(t *testing.T, r *gin.Engine, key string, content []byte, etag string, size int) *httptest.ResponseRecorder {
+	t.Helper()
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	zw.Write(content)
+	require.NoError(t, zw.Close())
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", "file.gz")
+	require.NoError(t, err)
+	part.Write(compressed.Bytes())
+	require.NoError(t, form.Close())
+
+	req := httptest.NewRequest(http.MethodPut, fmt.Sprintf("/blob/upload?key=%s&codec=gzip&etag=%s&size=%d", key, etag, size), &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestUploadCompressedVerified(t *testing.T) {
+	r, blobSvc := newQuotaTestRouter(t, 0)
+	content := bytes.Repeat([]byte("syftbox "), 1024)
+	etag := fmt.Sprintf("%x", md5.Sum(content))
+
+	w := uploadCompressed(t, r, "alice@example.com/public/a.txt", content, etag, len(content))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	blob, ok := blobSvc.Index().Get("alice@example.com/public/a.txt")
+	require.True(t, ok)
+	assert.Equal(t, etag, blob.ETag)
+	assert.Equal(t, int64(len(content)), blob.Size)
+
+	// the etag and size must be of the content the body decompresses to
+	w = uploadCompressed(t, r, "alice@example.com/public/b.txt", content, fmt.Sprintf("%x", md5.Sum([]byte("other"))), len(content))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "doesn't match")
+
+	w = uploadCompressed(t, r, "alice@example.com/public/b.txt", content, etag, 10)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = uploadCompressed(t, r, "alice@example.com/public/b.txt", content, etag, len(content)+1)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	_, ok = blobSvc.Index().Get("alice@example.com/public/b.txt")
+	assert.False(t, ok)
+}
+
+func TestUploadKeyNotNFC(t *testing.T) {
+	r, blobSvc := newQuotaTestRouter(t, 0)
+	content := []byte("hello")
+	etag := fmt.Sprintf("%x", md5.Sum(content))
+
+	// not stored under the NFC key, the client has to rename its file
+	w := uploadCompressed(t, r, url.QueryEscape("alice@example.com/public/cafe\u0301.txt"), content, etag, len(content))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "E_DATASITE_KEY_NOT_NFC")
+
+	_, ok := blobSvc.Index().Get("alice@example.com/public/caf\u00e9.txt")
+	assert.False(t, ok)
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/openmined/syftbox/internal/server/acl"
 	"github.com/openmined/syftbox/internal/server/datasite"
 )
 
@@ -18,7 +19,7 @@ func New(svc *datasite.DatasiteService) *DatasiteHandler {
 }
 
 func (h *DatasiteHandler) GetView(ctx *gin.Context) {
-	user := ctx.GetString("user")
+	user := &acl.User{ID: ctx.GetString("user")}
 	ctx.PureJSON(http.StatusOK, gin.H{
 		"files": h.svc.GetView(user),
 	})
@@ -97,7 +97,7 @@ func (e *ExplorerHandler) listContents(prefix string) *directoryContents {
 		filterPrefix = prefix
 	}
 
-	blobs, err := e.blob.Index().FilterByPrefix(filterPrefix)
+	blobs, err := e.blob.ReadIndex().FilterByPrefix(filterPrefix)
 	if err != nil {
 		slog.Error("Failed to filter blobs by prefix", "error", err)
 		return &directoryContents{
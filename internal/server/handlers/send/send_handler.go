@@ -10,17 +10,19 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/openmined/syftbox/internal/server/acl"
+	"github.com/openmined/syftbox/internal/server/datasite"
 )
 
 // SendHandler handles HTTP requests for sending messages
 type SendHandler struct {
-	service SendServiceInterface
+	service  SendServiceInterface
+	features *datasite.FeatureFlags // optional, rpc is enabled for every datasite if nil
 }
 
 // New creates a new send handler
-func New(msgDispatcher MessageDispatcher, msgStore RPCMsgStore, acl *acl.ACLService) *SendHandler {
+func New(msgDispatcher MessageDispatcher, msgStore RPCMsgStore, acl *acl.ACLService, features *datasite.FeatureFlags) *SendHandler {
 	service := NewSendService(msgDispatcher, msgStore, acl, nil)
-	return &SendHandler{service: service}
+	return &SendHandler{service: service, features: features}
 }
 
 // SendMsg handles sending a message
@@ -45,6 +47,10 @@ func (h *SendHandler) SendMsg(ctx *gin.Context) {
 		return
 	}
 
+	if !h.rpcEnabled(ctx, req.SyftURL.Datasite, "") {
+		return
+	}
+
 	// Bind request method
 	req.Method = ctx.Request.Method
 
@@ -122,6 +128,10 @@ func (h *SendHandler) PollForResponse(ctx *gin.Context) {
 		return
 	}
 
+	if !h.rpcEnabled(ctx, req.SyftURL.Datasite, req.RequestID) {
+		return
+	}
+
 	result, err := h.service.PollForResponse(ctx.Request.Context(), &req)
 	contentTypeHTML := ctx.Request.Header.Get("Content-Type") == "text/html"
 
@@ -221,3 +231,21 @@ func readRequestBody(ctx *gin.Context, maxSize int64) ([]byte, error) {
 
 	return bodyBytes, nil
 }
+
+// rpcEnabled responds with 403 and returns false if rpc is turned off for the datasite
+func (h *SendHandler) rpcEnabled(ctx *gin.Context, ds string, requestID string) bool {
+	if h.features == nil {
+		return true
+	}
+
+	if !h.features.Enabled(ds, datasite.FeatureRPC) {
+		ctx.PureJSON(http.StatusForbidden, APIError{
+			Error:     ErrorFeatureDisabled,
+			Message:   "RPC is disabled for this datasite.",
+			RequestID: requestID,
+		})
+		return false
+	}
+
+	return true
+}
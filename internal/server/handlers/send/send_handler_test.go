@@ -8,14 +8,18 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/gin-gonic/gin"
+	"github.com/openmined/syftbox/internal/db"
 	"github.com/openmined/syftbox/internal/server/acl"
+	"github.com/openmined/syftbox/internal/server/datasite"
 	"github.com/openmined/syftbox/internal/utils"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 //go:embed *.html
@@ -560,3 +564,67 @@ type failingReader struct{}
 func (f *failingReader) Read(p []byte) (n int, err error) {
 	return 0, errors.New("read error")
 }
+
+func TestSendHandler_SendMsg_RPCDisabledForDatasite(t *testing.T) {
+	sqliteDb, err := db.NewSqliteDB(db.WithPath(filepath.Join(t.TempDir(), "test.db")), db.WithMaxOpenConns(1))
+	require.NoError(t, err)
+	defer sqliteDb.Close()
+
+	features, err := datasite.NewFeatureFlags(sqliteDb, &datasite.FeaturesConfig{RPC: true})
+	require.NoError(t, err)
+
+	disabled := false
+	require.NoError(t, features.Set("alice@example.com", datasite.FeatureRPC, &disabled))
+
+	mockService := &MockSendService{}
+	handler := &SendHandler{service: mockService, features: features}
+
+	mockService.On("SendMessage", mock.Anything, mock.MatchedBy(func(req *MessageRequest) bool {
+		return req.SyftURL.Datasite == "bob@example.com"
+	}), mock.Anything).Return(&SendResult{Status: http.StatusOK, RequestID: "test-request-id", Response: map[string]interface{}{"message": "ok"}}, nil)
+	mockService.On("GetConfig").Return(&Config{MaxBodySize: 4 * 1024 * 1024})
+
+	send := func(datasite string) *httptest.ResponseRecorder {
+		c, w := createTestContext("POST", "/send/msg/", strings.NewReader("{}"), map[string]string{
+			"x-syft-url":  "syft://" + datasite + "/app_data/testapp/rpc/endpoint",
+			"x-syft-from": "testuser@example.com",
+		}, nil)
+		handler.SendMsg(c)
+		return w
+	}
+
+	// rejected for alice only
+	w := send("alice@example.com")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	var apiErr APIError
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &apiErr))
+	assert.Equal(t, ErrorFeatureDisabled, apiErr.Error)
+
+	w = send("bob@example.com")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	mockService.AssertNumberOfCalls(t, "SendMessage", 1)
+}
+
+func TestSendHandler_PollForResponse_RPCDisabledForDatasite(t *testing.T) {
+	sqliteDb, err := db.NewSqliteDB(db.WithPath(filepath.Join(t.TempDir(), "test.db")), db.WithMaxOpenConns(1))
+	require.NoError(t, err)
+	defer sqliteDb.Close()
+
+	features, err := datasite.NewFeatureFlags(sqliteDb, &datasite.FeaturesConfig{RPC: false})
+	require.NoError(t, err)
+
+	mockService := &MockSendService{}
+	handler := &SendHandler{service: mockService, features: features}
+
+	c, w := createTestContext("GET", "/send/poll", nil, map[string]string{
+		"x-syft-request-id": "test-request-id",
+		"x-syft-url":        "syft://alice@example.com/app_data/testapp/rpc/endpoint",
+		"x-syft-from":       "testuser@example.com",
+	}, nil)
+	handler.PollForResponse(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	mockService.AssertNotCalled(t, "PollForResponse", mock.Anything, mock.Anything)
+}
@@ -24,6 +24,7 @@ const (
 	ErrorInternal         = "internal_error"
 	ErrorNotFound         = "not_found"
 	ErrorPermissionDenied = "permission_denied"
+	ErrorFeatureDisabled  = "feature_disabled"
 	PollURL               = "/api/v1/send/poll?x-syft-request-id=%s&x-syft-url=%s&x-syft-from=%s&x-syft-raw=%t"
 )
 
@@ -106,7 +106,7 @@ func (s *SendService) SendMessage(ctx context.Context, req *MessageRequest, body
 	)
 
 	// Verify user has write permission to store request files at this path
-	if err := s.checkPermission(requestRelPath, req.From, acl.AccessWrite); err != nil {
+	if err := s.checkPermission(requestRelPath, &acl.User{ID: req.From}, acl.AccessWrite); err != nil {
 		return nil, ErrPermissionDenied
 	}
 
@@ -181,6 +181,7 @@ func (s *SendService) checkForResponse(
 		}
 		return nil, err
 	}
+	defer object.Close()
 
 	// Read the response file from blob storage
 	bodyBytes, err := io.ReadAll(object)
@@ -217,7 +218,7 @@ func (s *SendService) PollForResponse(ctx context.Context, req *PollObjectReques
 		// Try each path until we find an existing request file
 		for i, requestRelPath := range requestRelPaths {
 			// Check if request file exists at this path
-			_, err := s.store.GetMsg(ctx, requestRelPath)
+			object, err := s.store.GetMsg(ctx, requestRelPath)
 			if err != nil {
 				// File not found at this path, try the next candidate
 				if errors.Is(err, ErrMsgNotFound) {
@@ -226,6 +227,7 @@ func (s *SendService) PollForResponse(ctx context.Context, req *PollObjectReques
 				// File found but error occurred, return the error
 				return "", false, err
 			}
+			object.Close()
 
 			// Index 0 = new user-partitioned path, Index 1 = legacy shared path
 			withSender := (i == 0)
@@ -242,7 +244,7 @@ func (s *SendService) PollForResponse(ctx context.Context, req *PollObjectReques
 	}
 
 	// Verify user has permission to read the request file
-	if err := s.checkPermission(requestRelPath, req.From, acl.AccessRead); err != nil {
+	if err := s.checkPermission(requestRelPath, &acl.User{ID: req.From}, acl.AccessRead); err != nil {
 		return nil, ErrPermissionDenied
 	}
 
@@ -260,6 +262,7 @@ func (s *SendService) PollForResponse(ctx context.Context, req *PollObjectReques
 	if err != nil {
 		return nil, err
 	}
+	defer object.Close()
 
 	bodyBytes, err := io.ReadAll(object)
 	if err != nil {
@@ -267,7 +270,7 @@ func (s *SendService) PollForResponse(ctx context.Context, req *PollObjectReques
 	}
 
 	// Verify user has permission to read the response file
-	if err := s.checkPermission(responseRelPath, req.From, acl.AccessRead); err != nil {
+	if err := s.checkPermission(responseRelPath, &acl.User{ID: req.From}, acl.AccessRead); err != nil {
 		return nil, ErrPermissionDenied
 	}
 
@@ -397,16 +400,16 @@ func (s *SendService) getCandidateRequestPaths(req *PollObjectRequest) []string
 
 // checkPermission verifies if a user has the required access level to a path.
 // Datasite owners have full access, others are checked against ACL rules.
-func (s *SendService) checkPermission(path string, user string, level acl.AccessLevel) error {
+func (s *SendService) checkPermission(path string, user *acl.User, level acl.AccessLevel) error {
 	// Datasite owners have full access to all files in their datasite
-	if datasite.IsOwner(path, user) {
+	if datasite.IsOwner(path, user.ID) {
 		return nil
 	}
 
 	// Non-owners must pass ACL permission checks
 	return s.acl.CanAccess(&acl.ACLRequest{
 		Path:  path,
-		User:  &acl.User{ID: user},
+		User:  user,
 		Level: level,
 	})
 }
@@ -68,6 +68,10 @@ func (m *MockBlobService) Index() blob.IBlobIndex {
 	return args.Get(0).(blob.IBlobIndex)
 }
 
+func (m *MockBlobService) ReadIndex() blob.IBlobIndex {
+	return m.Index()
+}
+
 func (m *MockBlobService) OnBlobChange(callback blob.BlobChangeCallback) {
 	m.Called(callback)
 }
@@ -97,6 +101,16 @@ func (m *MockBlobIndex) Remove(key string) error {
 	return args.Error(0)
 }
 
+func (m *MockBlobIndex) Usage(datasite string) int64 {
+	args := m.Called(datasite)
+	return args.Get(0).(int64)
+}
+
+func (m *MockBlobIndex) Revisions(key string) ([]*blob.BlobRevision, error) {
+	args := m.Called(key)
+	return args.Get(0).([]*blob.BlobRevision), args.Error(1)
+}
+
 func (m *MockBlobIndex) List() ([]*blob.BlobInfo, error) {
 	args := m.Called()
 	return args.Get(0).([]*blob.BlobInfo), args.Error(1)
@@ -162,8 +176,8 @@ func (m *MockBlobBackend) PutObject(ctx context.Context, params *blob.PutObjectP
 	return args.Get(0).(*blob.PutObjectResponse), args.Error(1)
 }
 
-func (m *MockBlobBackend) PutObjectPresigned(ctx context.Context, key string) (string, error) {
-	args := m.Called(ctx, key)
+func (m *MockBlobBackend) PutObjectPresigned(ctx context.Context, key string, size int64) (string, error) {
+	args := m.Called(ctx, key, size)
 	return args.String(0), args.Error(1)
 }
 
@@ -0,0 +1,507 @@
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/openmined/syftbox/internal/aclspec"
+	"github.com/openmined/syftbox/internal/server/acl"
+	"github.com/openmined/syftbox/internal/server/blob"
+	"github.com/openmined/syftbox/internal/server/datasite"
+	"github.com/openmined/syftbox/internal/server/handlers/api"
+	"github.com/openmined/syftbox/internal/server/handlers/ws"
+	"github.com/openmined/syftbox/internal/syftmsg"
+)
+
+// snapshotsPrefix is outside of every datasite, so no ACL grants access to the snapshots
+const snapshotsPrefix = blob.SnapshotsPrefix
+
+var regexSnapshotName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+// maxNotifySize is the largest restored file sent to the peers over the websocket, like the priority files.
+// The peers get the larger ones, and the deleted ones, on their next sync
+const maxNotifySize = 4 * 1024 * 1024 // 4MB
+
+var errSnapshotNotFound = errors.New("snapshot not found")
+
+// Broadcaster sends notifications to the connected clients, like the websocket hub
+type Broadcaster interface {
+	BroadcastCoalesced(path string, msg *syftmsg.Message, predicate func(*ws.ClientInfo) bool)
+}
+
+type SnapshotHandler struct {
+	blob      blob.Service
+	aclSvc    *acl.ACLService
+	datasites *datasite.DatasiteService
+	hub       Broadcaster
+
+	// snapshots, restores and deletes run one at a time, so a restore never sees a half written snapshot
+	mu sync.Mutex
+}
+
+func New(blobSvc blob.Service, aclSvc *acl.ACLService, datasites *datasite.DatasiteService, hub Broadcaster) *SnapshotHandler {
+	return &SnapshotHandler{
+		blob:      blobSvc,
+		aclSvc:    aclSvc,
+		datasites: datasites,
+		hub:       hub,
+	}
+}
+
+// Create takes a named snapshot of the user's datasite, ACL files included.
+// Objects already stored by an earlier snapshot aren't copied again, the others count towards the quota of the datasite.
+func (h *SnapshotHandler) Create(ctx *gin.Context) {
+	var req SnapshotRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		api.AbortWithError(ctx, http.StatusBadRequest, api.CodeInvalidRequest, fmt.Errorf("failed to bind json: %w", err))
+		return
+	}
+	if !regexSnapshotName.MatchString(req.Name) {
+		api.AbortWithError(ctx, http.StatusBadRequest, api.CodeInvalidRequest, fmt.Errorf("invalid snapshot name %q", req.Name))
+		return
+	}
+
+	user := ctx.GetString("user")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.blob.Index().Get(manifestKey(user, req.Name)); ok {
+		api.AbortWithError(ctx, http.StatusConflict, api.CodeSnapshotExists, fmt.Errorf("snapshot %q already exists", req.Name))
+		return
+	}
+
+	blobs, err := h.blob.Index().FilterByPrefix(user + "/")
+	if err != nil {
+		api.AbortWithError(ctx, http.StatusInternalServerError, api.CodeBlobListFailed, err)
+		return
+	}
+	if len(blobs) == 0 {
+		api.AbortWithError(ctx, http.StatusNotFound, api.CodeDatasiteNotFound, fmt.Errorf("datasite not found"))
+		return
+	}
+
+	manifest, err := h.createSnapshot(ctx.Request.Context(), user, req.Name, blobs)
+	if errors.Is(err, datasite.ErrQuotaExceeded) {
+		api.AbortWithError(ctx, http.StatusForbidden, api.CodeQuotaExceeded, err)
+		return
+	} else if err != nil {
+		api.AbortWithError(ctx, http.StatusInternalServerError, api.CodeBlobPutFailed, err)
+		return
+	}
+
+	slog.Info("snapshot created", "datasite", user, "name", req.Name, "objects", manifest.Objects, "size", manifest.Size)
+	ctx.PureJSON(http.StatusCreated, &manifest.SnapshotInfo)
+}
+
+// List returns the snapshots of the user's datasite, oldest first
+func (h *SnapshotHandler) List(ctx *gin.Context) {
+	user := ctx.GetString("user")
+
+	blobs, err := h.blob.Index().FilterByPrefix(snapshotsPrefix + user + "/manifests/")
+	if err != nil {
+		api.AbortWithError(ctx, http.StatusInternalServerError, api.CodeBlobListFailed, err)
+		return
+	}
+
+	snapshots := make([]*SnapshotInfo, 0, len(blobs))
+	for _, info := range blobs {
+		manifest, err := h.readManifest(ctx.Request.Context(), info.Key)
+		if err != nil {
+			api.AbortWithError(ctx, http.StatusInternalServerError, api.CodeBlobGetFailed, err)
+			return
+		}
+		snapshots = append(snapshots, &manifest.SnapshotInfo)
+	}
+	slices.SortFunc(snapshots, func(a, b *SnapshotInfo) int {
+		return strings.Compare(a.CreatedAt, b.CreatedAt)
+	})
+
+	ctx.PureJSON(http.StatusOK, &SnapshotListResponse{Snapshots: snapshots})
+}
+
+// Restore brings the user's datasite back to a snapshot. Objects created since are deleted,
+// objects that changed are copied back, and the ACL rulesets are updated to match.
+// The connected clients that can read the restored files are sent them, the others pick up the changes on their next sync.
+func (h *SnapshotHandler) Restore(ctx *gin.Context) {
+	var req SnapshotRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		api.AbortWithError(ctx, http.StatusBadRequest, api.CodeInvalidRequest, fmt.Errorf("failed to bind json: %w", err))
+		return
+	}
+	if !regexSnapshotName.MatchString(req.Name) {
+		api.AbortWithError(ctx, http.StatusBadRequest, api.CodeInvalidRequest, fmt.Errorf("invalid snapshot name %q", req.Name))
+		return
+	}
+
+	user := ctx.GetString("user")
+	reqCtx := ctx.Request.Context()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	manifest, err := h.readManifest(reqCtx, manifestKey(user, req.Name))
+	if errors.Is(err, errSnapshotNotFound) {
+		api.AbortWithError(ctx, http.StatusNotFound, api.CodeSnapshotNotFound, fmt.Errorf("snapshot %q not found", req.Name))
+		return
+	} else if err != nil {
+		api.AbortWithError(ctx, http.StatusInternalServerError, api.CodeBlobGetFailed, err)
+		return
+	}
+
+	// everything is checked before the first write, so a broken snapshot changes nothing
+	ruleSets, err := h.loadRuleSets(reqCtx, user, manifest)
+	if err != nil {
+		api.AbortWithError(ctx, http.StatusInternalServerError, api.CodeACLInvalid, err)
+		return
+	}
+
+	current, err := h.blob.Index().FilterByPrefix(user + "/")
+	if err != nil {
+		api.AbortWithError(ctx, http.StatusInternalServerError, api.CodeBlobListFailed, err)
+		return
+	}
+
+	release, err := h.datasites.AdmitGrowth(user, restoreGrowth(manifest, current))
+	if err != nil {
+		api.AbortWithError(ctx, http.StatusForbidden, api.CodeQuotaExceeded, err)
+		return
+	}
+	defer release()
+
+	result, err := h.restoreObjects(reqCtx, user, manifest, current)
+
+	// the rulesets follow what was written, even when the restore stopped half way
+	var applied []*aclspec.RuleSet
+	for _, entry := range result.written {
+		if aclspec.IsACLFile(entry.Key) {
+			applied = append(applied, ruleSets[entry.Key])
+		}
+	}
+	var removed []string
+	for _, key := range result.deleted {
+		if aclspec.IsACLFile(key) {
+			removed = append(removed, key)
+		}
+	}
+	if aclErr := h.aclSvc.ApplyRuleSets(applied, removed); aclErr != nil {
+		api.AbortWithError(ctx, http.StatusInternalServerError, api.CodeACLUpdateFailed, fmt.Errorf("failed to update rulesets: %w", aclErr))
+		return
+	}
+
+	// once the rulesets are restored, so that the files go to those the snapshot allowed
+	h.notifyRestored(reqCtx, result.written)
+
+	if err != nil {
+		// restoring again picks up where this one stopped
+		api.AbortWithError(ctx, http.StatusInternalServerError, api.CodeBlobPutFailed, fmt.Errorf("restore incomplete: %w", err))
+		return
+	}
+
+	resp := result.RestoreResponse
+	slog.Info("snapshot restored", "datasite", user, "name", req.Name, "restored", resp.Restored, "deleted", resp.Deleted, "unchanged", resp.Unchanged)
+	ctx.PureJSON(http.StatusOK, resp)
+}
+
+// Delete removes a snapshot of the user's datasite, and the stored objects no other snapshot uses
+func (h *SnapshotHandler) Delete(ctx *gin.Context) {
+	var req SnapshotRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		api.AbortWithError(ctx, http.StatusBadRequest, api.CodeInvalidRequest, fmt.Errorf("failed to bind json: %w", err))
+		return
+	}
+	if !regexSnapshotName.MatchString(req.Name) {
+		api.AbortWithError(ctx, http.StatusBadRequest, api.CodeInvalidRequest, fmt.Errorf("invalid snapshot name %q", req.Name))
+		return
+	}
+
+	user := ctx.GetString("user")
+	reqCtx := ctx.Request.Context()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	key := manifestKey(user, req.Name)
+	if _, ok := h.blob.Index().Get(key); !ok {
+		api.AbortWithError(ctx, http.StatusNotFound, api.CodeSnapshotNotFound, fmt.Errorf("snapshot %q not found", req.Name))
+		return
+	}
+
+	// the manifest goes first, a snapshot that failed half way doesn't exist
+	if _, err := h.blob.Backend().DeleteObject(reqCtx, key); err != nil {
+		api.AbortWithError(ctx, http.StatusInternalServerError, api.CodeBlobDeleteFailed, fmt.Errorf("failed to delete manifest: %w", err))
+		return
+	}
+
+	resp, err := h.deleteUnused(reqCtx, user)
+	if err != nil {
+		// deleting another snapshot picks up the objects left
+		api.AbortWithError(ctx, http.StatusInternalServerError, api.CodeBlobDeleteFailed, err)
+		return
+	}
+	resp.Name = req.Name
+
+	slog.Info("snapshot deleted", "datasite", user, "name", req.Name, "objects", resp.Objects, "size", resp.Size)
+	ctx.PureJSON(http.StatusOK, resp)
+}
+
+func (h *SnapshotHandler) createSnapshot(ctx context.Context, datasite string, name string, blobs []*blob.BlobInfo) (*Manifest, error) {
+	manifest := &Manifest{
+		SnapshotInfo: SnapshotInfo{
+			Name:      name,
+			Datasite:  datasite,
+			CreatedAt: time.Now().UTC().Format(time.RFC3339Nano),
+			Objects:   len(blobs),
+		},
+		Entries: make([]*ManifestEntry, 0, len(blobs)),
+	}
+
+	// the objects not stored yet are copied, once per content
+	var copies []*blob.BlobInfo
+	var growth int64
+	stored := make(map[string]struct{}, len(blobs))
+	for _, info := range blobs {
+		manifest.Entries = append(manifest.Entries, &ManifestEntry{Key: info.Key, ETag: info.ETag, Size: info.Size})
+		manifest.Size += info.Size
+
+		objKey := objectKey(datasite, info.ETag)
+		if _, ok := stored[objKey]; ok {
+			continue
+		}
+		stored[objKey] = struct{}{}
+		if _, ok := h.blob.Index().Get(objKey); !ok {
+			copies = append(copies, info)
+			growth += info.Size
+		}
+	}
+	slices.SortFunc(manifest.Entries, func(a, b *ManifestEntry) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	release, err := h.datasites.AdmitGrowth(datasite, growth+int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	for _, info := range copies {
+		if _, err := h.blob.Backend().CopyObject(ctx, &blob.CopyObjectParams{
+			SourceKey:      info.Key,
+			DestinationKey: objectKey(datasite, info.ETag),
+		}); err != nil {
+			return nil, fmt.Errorf("failed to copy object %s: %w", info.Key, err)
+		}
+	}
+
+	// the manifest goes last, a snapshot that failed half way doesn't exist
+	if _, err := h.blob.Backend().PutObject(ctx, &blob.PutObjectParams{
+		Key:  manifestKey(datasite, name),
+		Size: int64(len(data)),
+		Body: bytes.NewReader(data),
+	}); err != nil {
+		return nil, fmt.Errorf("failed to put manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+// restoreResult is what a restore did, including what was done before a failure
+type restoreResult struct {
+	*RestoreResponse
+	written []*ManifestEntry // the objects copied back
+	deleted []string         // the keys of the objects deleted
+}
+
+// restoreObjects writes the snapshot over the datasite. The objects not in the snapshot are deleted first,
+// to make room for the ones copied back.
+func (h *SnapshotHandler) restoreObjects(ctx context.Context, datasite string, manifest *Manifest, current []*blob.BlobInfo) (*restoreResult, error) {
+	result := &restoreResult{RestoreResponse: &RestoreResponse{Name: manifest.Name}}
+
+	inSnapshot := make(map[string]struct{}, len(manifest.Entries))
+	for _, entry := range manifest.Entries {
+		inSnapshot[entry.Key] = struct{}{}
+	}
+	for _, info := range current {
+		if _, ok := inSnapshot[info.Key]; ok {
+			continue
+		}
+		if _, err := h.blob.Backend().DeleteObject(ctx, info.Key); err != nil {
+			return result, fmt.Errorf("failed to delete object %s: %w", info.Key, err)
+		}
+		result.Deleted++
+		result.deleted = append(result.deleted, info.Key)
+	}
+
+	etags := make(map[string]string, len(current))
+	for _, info := range current {
+		etags[info.Key] = info.ETag
+	}
+	for _, entry := range manifest.Entries {
+		if etag, ok := etags[entry.Key]; ok && etag == entry.ETag {
+			result.Unchanged++
+			continue
+		}
+		if _, err := h.blob.Backend().CopyObject(ctx, &blob.CopyObjectParams{
+			SourceKey:      objectKey(datasite, entry.ETag),
+			DestinationKey: entry.Key,
+		}); err != nil {
+			return result, fmt.Errorf("failed to restore object %s: %w", entry.Key, err)
+		}
+		result.Restored++
+		result.written = append(result.written, entry)
+	}
+
+	return result, nil
+}
+
+// restoreGrowth is how much a restore grows the datasite by: the size of the objects copied back,
+// less that of the objects they replace and of the objects deleted
+func restoreGrowth(manifest *Manifest, current []*blob.BlobInfo) int64 {
+	var growth int64
+	byKey := make(map[string]*blob.BlobInfo, len(current))
+	for _, info := range current {
+		byKey[info.Key] = info
+		growth -= info.Size
+	}
+	for _, entry := range manifest.Entries {
+		if info, ok := byKey[entry.Key]; ok && info.ETag == entry.ETag {
+			// left as it is
+			growth += info.Size
+			continue
+		}
+		growth += entry.Size
+	}
+	return growth
+}
+
+// notifyRestored sends the restored files to the connected clients that can read them, the owner's included
+func (h *SnapshotHandler) notifyRestored(ctx context.Context, written []*ManifestEntry) {
+	if h.hub == nil {
+		return
+	}
+
+	for _, entry := range written {
+		if entry.Size > maxNotifySize {
+			continue
+		}
+		obj, err := h.blob.Backend().GetObject(ctx, entry.Key)
+		if err != nil {
+			slog.Warn("snapshot restore notify", "key", entry.Key, "error", err)
+			continue
+		}
+		content, err := io.ReadAll(obj.Body)
+		obj.Body.Close()
+		if err != nil {
+			slog.Warn("snapshot restore notify", "key", entry.Key, "error", err)
+			continue
+		}
+
+		msg := syftmsg.NewFileWrite(entry.Key, entry.ETag, int64(len(content)), content)
+		h.hub.BroadcastCoalesced(entry.Key, msg, func(info *ws.ClientInfo) bool {
+			return h.aclSvc.CanAccess(acl.NewRequest(entry.Key, &acl.User{ID: info.User}, acl.AccessRead)) == nil
+		})
+	}
+}
+
+// deleteUnused deletes the stored objects of the datasite that no snapshot uses anymore
+func (h *SnapshotHandler) deleteUnused(ctx context.Context, datasite string) (*DeleteResponse, error) {
+	manifests, err := h.blob.Index().FilterByPrefix(snapshotsPrefix + datasite + "/manifests/")
+	if err != nil {
+		return nil, err
+	}
+	used := make(map[string]struct{})
+	for _, info := range manifests {
+		manifest, err := h.readManifest(ctx, info.Key)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range manifest.Entries {
+			used[objectKey(datasite, entry.ETag)] = struct{}{}
+		}
+	}
+
+	objects, err := h.blob.Index().FilterByPrefix(snapshotsPrefix + datasite + "/objects/")
+	if err != nil {
+		return nil, err
+	}
+	resp := &DeleteResponse{}
+	for _, info := range objects {
+		if _, ok := used[info.Key]; ok {
+			continue
+		}
+		if _, err := h.blob.Backend().DeleteObject(ctx, info.Key); err != nil {
+			return nil, fmt.Errorf("failed to delete object %s: %w", info.Key, err)
+		}
+		resp.Objects++
+		resp.Size += info.Size
+	}
+	return resp, nil
+}
+
+// loadRuleSets checks that every object of the snapshot is stored, and parses its ACL files
+func (h *SnapshotHandler) loadRuleSets(ctx context.Context, datasite string, manifest *Manifest) (map[string]*aclspec.RuleSet, error) {
+	ruleSets := make(map[string]*aclspec.RuleSet)
+	for _, entry := range manifest.Entries {
+		objKey := objectKey(datasite, entry.ETag)
+		if _, ok := h.blob.Index().Get(objKey); !ok {
+			return nil, fmt.Errorf("snapshot object %s is missing", entry.Key)
+		}
+		if !aclspec.IsACLFile(entry.Key) {
+			continue
+		}
+
+		obj, err := h.blob.Backend().GetObject(ctx, objKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get object %s: %w", entry.Key, err)
+		}
+		ruleSet, err := h.aclSvc.LoadRuleSet(entry.Key, obj.Body)
+		obj.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ruleset %s: %w", entry.Key, err)
+		}
+		ruleSets[entry.Key] = ruleSet
+	}
+	return ruleSets, nil
+}
+
+func (h *SnapshotHandler) readManifest(ctx context.Context, key string) (*Manifest, error) {
+	if _, ok := h.blob.Index().Get(key); !ok {
+		return nil, errSnapshotNotFound
+	}
+
+	obj, err := h.blob.Backend().GetObject(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get manifest %s: %w", key, err)
+	}
+	defer obj.Body.Close()
+
+	var manifest Manifest
+	if err := json.NewDecoder(obj.Body).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("failed to read manifest %s: %w", key, err)
+	}
+	return &manifest, nil
+}
+
+func manifestKey(datasite string, name string) string {
+	return snapshotsPrefix + datasite + "/manifests/" + name + ".json"
+}
+
+func objectKey(datasite string, etag string) string {
+	return snapshotsPrefix + datasite + "/objects/" + etag
+}
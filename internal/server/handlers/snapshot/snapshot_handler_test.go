@@ -0,0 +1,366 @@
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/openmined/syftbox/internal/server/acl"
+	"github.com/openmined/syftbox/internal/server/blob"
+	"github.com/openmined/syftbox/internal/server/datasite"
+	"github.com/openmined/syftbox/internal/server/handlers/api"
+	"github.com/openmined/syftbox/internal/server/handlers/ws"
+	"github.com/openmined/syftbox/internal/syftmsg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const publicACL = "rules:\n  - pattern: '**'\n    access:\n      read: ['*']\n"
+
+// fakeBlobs keeps objects in memory, the index is derived from them
+type fakeBlobs struct {
+	blob.IBlobBackend
+	blob.IBlobIndex
+	objects map[string][]byte
+	copies  int
+}
+
+func (f *fakeBlobs) Backend() blob.IBlobBackend                    { return f }
+func (f *fakeBlobs) Index() blob.IBlobIndex                        { return f }
+func (f *fakeBlobs) ReadIndex() blob.IBlobIndex                    { return f.Index() }
+func (f *fakeBlobs) OnBlobChange(callback blob.BlobChangeCallback) {}
+func (f *fakeBlobs) Count() int                                    { return len(f.objects) }
+
+func (f *fakeBlobs) GetObject(ctx context.Context, key string) (*blob.GetObjectResponse, error) {
+	content, ok := f.objects[key]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return &blob.GetObjectResponse{Body: io.NopCloser(bytes.NewReader(content)), Size: int64(len(content))}, nil
+}
+
+func (f *fakeBlobs) PutObject(ctx context.Context, params *blob.PutObjectParams) (*blob.PutObjectResponse, error) {
+	content, err := io.ReadAll(params.Body)
+	if err != nil {
+		return nil, err
+	}
+	f.objects[params.Key] = content
+	return &blob.PutObjectResponse{Key: params.Key, ETag: etagOf(content), Size: int64(len(content))}, nil
+}
+
+func (f *fakeBlobs) CopyObject(ctx context.Context, params *blob.CopyObjectParams) (*blob.CopyObjectResponse, error) {
+	content, ok := f.objects[params.SourceKey]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	f.copies++
+	f.objects[params.DestinationKey] = bytes.Clone(content)
+	return &blob.CopyObjectResponse{ETag: etagOf(content)}, nil
+}
+
+func (f *fakeBlobs) DeleteObject(ctx context.Context, key string) (bool, error) {
+	delete(f.objects, key)
+	return true, nil
+}
+
+func (f *fakeBlobs) Get(key string) (*blob.BlobInfo, bool) {
+	content, ok := f.objects[key]
+	if !ok {
+		return nil, false
+	}
+	return &blob.BlobInfo{Key: key, ETag: etagOf(content), Size: int64(len(content))}, true
+}
+
+// Usage accounts the snapshots to their datasite, like the index
+func (f *fakeBlobs) Usage(owner string) int64 {
+	var size int64
+	for key, content := range f.objects {
+		if strings.HasPrefix(strings.TrimPrefix(key, snapshotsPrefix), owner+"/") {
+			size += int64(len(content))
+		}
+	}
+	return size
+}
+
+func (f *fakeBlobs) FilterByPrefix(prefix string) ([]*blob.BlobInfo, error) {
+	return f.filter(func(key string) bool { return strings.HasPrefix(key, prefix) }), nil
+}
+
+func (f *fakeBlobs) FilterBySuffix(suffix string) ([]*blob.BlobInfo, error) {
+	return f.filter(func(key string) bool { return strings.HasSuffix(key, suffix) }), nil
+}
+
+func (f *fakeBlobs) filter(match func(string) bool) []*blob.BlobInfo {
+	var blobs []*blob.BlobInfo
+	for key := range f.objects {
+		if match(key) {
+			info, _ := f.Get(key)
+			blobs = append(blobs, info)
+		}
+	}
+	return blobs
+}
+
+// datasite returns the objects of a datasite, without the snapshots
+func (f *fakeBlobs) datasite(owner string) map[string][]byte {
+	objects := make(map[string][]byte)
+	for key, content := range f.objects {
+		if strings.HasPrefix(key, owner+"/") {
+			objects[key] = content
+		}
+	}
+	return objects
+}
+
+// snapshots returns the keys stored for the snapshots of a datasite
+func (f *fakeBlobs) snapshots(owner string) []string {
+	var keys []string
+	for key := range f.objects {
+		if strings.HasPrefix(key, snapshotsPrefix+owner+"/") {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// fakeHub records the notifications, with the connected users each one went to
+type fakeHub struct {
+	users []string
+	sent  map[string][]string
+}
+
+func (f *fakeHub) BroadcastCoalesced(path string, msg *syftmsg.Message, predicate func(*ws.ClientInfo) bool) {
+	for _, user := range f.users {
+		if predicate(&ws.ClientInfo{User: user}) {
+			f.sent[path] = append(f.sent[path], user)
+		}
+	}
+}
+
+func etagOf(content []byte) string {
+	sum := md5.Sum(content)
+	return hex.EncodeToString(sum[:])
+}
+
+func newSnapshotTestRouter(t *testing.T, config *datasite.Config) (*gin.Engine, *fakeBlobs, *acl.ACLService, *fakeHub) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	blobs := &fakeBlobs{objects: map[string][]byte{
+		"alice@example.com/public/syft.pub.yaml": []byte(publicACL),
+		"alice@example.com/public/report.csv":    []byte("a,b\n1,2\n"),
+		"alice@example.com/private/notes.txt":    []byte("notes"),
+		"alice@example.com/private/copy.txt":     []byte("notes"),
+		"bob@example.com/public/other.txt":       []byte("not alice"),
+	}}
+
+	aclSvc := acl.NewACLService(blobs)
+	require.NoError(t, aclSvc.Start(context.Background()))
+	hub := &fakeHub{users: []string{"alice@example.com", "bob@example.com"}, sent: make(map[string][]string)}
+	h := New(blobs, aclSvc, datasite.NewDatasiteService(blobs, aclSvc, "", config), hub)
+
+	r := gin.New()
+	r.Use(func(ctx *gin.Context) {
+		ctx.Set("user", ctx.GetHeader("X-User"))
+	})
+	r.GET("/snapshots", h.List)
+	r.POST("/snapshots", h.Create)
+	r.POST("/snapshots/restore", h.Restore)
+	r.POST("/snapshots/delete", h.Delete)
+	return r, blobs, aclSvc, hub
+}
+
+func doRequest(t *testing.T, r *gin.Engine, method string, path string, user string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		require.NoError(t, err)
+		reader = bytes.NewReader(data)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-User", user)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func errorCode(t *testing.T, w *httptest.ResponseRecorder) string {
+	t.Helper()
+	var apiErr api.SyftAPIError
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &apiErr))
+	return apiErr.Code
+}
+
+func canRead(aclSvc *acl.ACLService, user string, path string) bool {
+	return aclSvc.CanAccess(acl.NewRequest(path, &acl.User{ID: user}, acl.AccessRead)) == nil
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	r, blobs, aclSvc, hub := newSnapshotTestRouter(t, &datasite.Config{})
+	original := maps.Clone(blobs.datasite("alice@example.com"))
+
+	w := doRequest(t, r, http.MethodPost, "/snapshots", "alice@example.com", &SnapshotRequest{Name: "before-cleanup"})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	var info SnapshotInfo
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &info))
+	assert.Equal(t, "before-cleanup", info.Name)
+	assert.Equal(t, 4, info.Objects)
+	assert.Equal(t, 3, blobs.copies, "identical files are stored once")
+
+	// edit, delete and add files, and make the public dir private
+	blobs.objects["alice@example.com/public/report.csv"] = []byte("a,b\n3,4\n")
+	delete(blobs.objects, "alice@example.com/private/notes.txt")
+	blobs.objects["alice@example.com/private/draft.txt"] = []byte("draft")
+	delete(blobs.objects, "alice@example.com/public/syft.pub.yaml")
+	require.True(t, aclSvc.RemoveRuleSet("alice@example.com/public/syft.pub.yaml"))
+	require.False(t, canRead(aclSvc, "bob@example.com", "alice@example.com/public/report.csv"))
+
+	w = doRequest(t, r, http.MethodPost, "/snapshots/restore", "alice@example.com", &SnapshotRequest{Name: "before-cleanup"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp RestoreResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, &RestoreResponse{Name: "before-cleanup", Restored: 3, Deleted: 1, Unchanged: 1}, &resp)
+
+	assert.Equal(t, original, blobs.datasite("alice@example.com"))
+	assert.Equal(t, []byte("not alice"), blobs.objects["bob@example.com/public/other.txt"])
+	assert.True(t, canRead(aclSvc, "bob@example.com", "alice@example.com/public/report.csv"), "the ruleset is restored")
+
+	// the restored files are sent to the connected clients that can read them, by the restored rulesets
+	assert.Equal(t, map[string][]string{
+		"alice@example.com/public/syft.pub.yaml": {"alice@example.com", "bob@example.com"},
+		"alice@example.com/public/report.csv":    {"alice@example.com", "bob@example.com"},
+		"alice@example.com/private/notes.txt":    {"alice@example.com"},
+	}, hub.sent)
+
+	// restoring again changes nothing
+	w = doRequest(t, r, http.MethodPost, "/snapshots/restore", "alice@example.com", &SnapshotRequest{Name: "before-cleanup"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, &RestoreResponse{Name: "before-cleanup", Unchanged: 4}, &resp)
+}
+
+func TestSnapshotList(t *testing.T) {
+	r, blobs, _, _ := newSnapshotTestRouter(t, &datasite.Config{})
+
+	for _, name := range []string{"first", "second"} {
+		w := doRequest(t, r, http.MethodPost, "/snapshots", "alice@example.com", &SnapshotRequest{Name: name})
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	}
+	assert.Equal(t, 3, blobs.copies, "an unchanged datasite is not copied again")
+
+	w := doRequest(t, r, http.MethodGet, "/snapshots", "alice@example.com", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var list SnapshotListResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	require.Len(t, list.Snapshots, 2)
+	assert.Equal(t, "first", list.Snapshots[0].Name)
+	assert.Equal(t, "second", list.Snapshots[1].Name)
+
+	// snapshots are per datasite
+	w = doRequest(t, r, http.MethodGet, "/snapshots", "bob@example.com", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"snapshots":[]}`, w.Body.String())
+}
+
+func TestSnapshotErrors(t *testing.T) {
+	r, _, _, _ := newSnapshotTestRouter(t, &datasite.Config{})
+
+	w := doRequest(t, r, http.MethodPost, "/snapshots", "alice@example.com", &SnapshotRequest{Name: "../bob@example.com"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = doRequest(t, r, http.MethodPost, "/snapshots", "carol@example.com", &SnapshotRequest{Name: "empty"})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, api.CodeDatasiteNotFound, errorCode(t, w))
+
+	w = doRequest(t, r, http.MethodPost, "/snapshots", "alice@example.com", &SnapshotRequest{Name: "daily"})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	w = doRequest(t, r, http.MethodPost, "/snapshots", "alice@example.com", &SnapshotRequest{Name: "daily"})
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, api.CodeSnapshotExists, errorCode(t, w))
+
+	// bob can't restore alice's snapshot over his datasite
+	w = doRequest(t, r, http.MethodPost, "/snapshots/restore", "bob@example.com", &SnapshotRequest{Name: "daily"})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, api.CodeSnapshotNotFound, errorCode(t, w))
+}
+
+func TestSnapshotDelete(t *testing.T) {
+	r, blobs, _, _ := newSnapshotTestRouter(t, &datasite.Config{})
+
+	w := doRequest(t, r, http.MethodPost, "/snapshots", "alice@example.com", &SnapshotRequest{Name: "first"})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	blobs.objects["alice@example.com/public/report.csv"] = []byte("a,b\n3,4\n")
+	w = doRequest(t, r, http.MethodPost, "/snapshots", "alice@example.com", &SnapshotRequest{Name: "second"})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	// only the report of the first snapshot isn't used by the second
+	w = doRequest(t, r, http.MethodPost, "/snapshots/delete", "alice@example.com", &SnapshotRequest{Name: "first"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp DeleteResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, &DeleteResponse{Name: "first", Objects: 1, Size: 8}, &resp)
+
+	w = doRequest(t, r, http.MethodGet, "/snapshots", "alice@example.com", nil)
+	var list SnapshotListResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	require.Len(t, list.Snapshots, 1)
+	assert.Equal(t, "second", list.Snapshots[0].Name)
+
+	w = doRequest(t, r, http.MethodPost, "/snapshots/delete", "alice@example.com", &SnapshotRequest{Name: "first"})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, api.CodeSnapshotNotFound, errorCode(t, w))
+
+	w = doRequest(t, r, http.MethodPost, "/snapshots/delete", "alice@example.com", &SnapshotRequest{Name: "second"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 3, resp.Objects)
+	assert.Empty(t, blobs.snapshots("alice@example.com"))
+	assert.Len(t, blobs.datasite("alice@example.com"), 4, "the datasite is left as it is")
+}
+
+func TestSnapshotQuota(t *testing.T) {
+	config := &datasite.Config{}
+	r, blobs, _, _ := newSnapshotTestRouter(t, config)
+
+	// the snapshots count towards the quota of the datasite
+	config.QuotaBytes = blobs.Usage("alice@example.com") + 10
+	w := doRequest(t, r, http.MethodPost, "/snapshots", "alice@example.com", &SnapshotRequest{Name: "daily"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, api.CodeQuotaExceeded, errorCode(t, w))
+	assert.Empty(t, blobs.snapshots("alice@example.com"))
+
+	config.QuotaBytes = 0
+	w = doRequest(t, r, http.MethodPost, "/snapshots", "alice@example.com", &SnapshotRequest{Name: "daily"})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	// the report grows back by 7 bytes
+	blobs.objects["alice@example.com/public/report.csv"] = []byte("x")
+	config.QuotaBytes = blobs.Usage("alice@example.com") + 6
+	w = doRequest(t, r, http.MethodPost, "/snapshots/restore", "alice@example.com", &SnapshotRequest{Name: "daily"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, api.CodeQuotaExceeded, errorCode(t, w))
+	assert.Equal(t, []byte("x"), blobs.objects["alice@example.com/public/report.csv"])
+
+	// the files deleted by the restore make room for it
+	blobs.objects["alice@example.com/private/draft.txt"] = []byte("draft text")
+	config.QuotaBytes = blobs.Usage("alice@example.com")
+	w = doRequest(t, r, http.MethodPost, "/snapshots/restore", "alice@example.com", &SnapshotRequest{Name: "daily"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, []byte("a,b\n1,2\n"), blobs.objects["alice@example.com/public/report.csv"])
+}
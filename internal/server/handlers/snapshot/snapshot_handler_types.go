@@ -0,0 +1,46 @@
+package snapshot
+
+type SnapshotRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// SnapshotInfo describes a snapshot of a datasite
+type SnapshotInfo struct {
+	Name      string `json:"name"`
+	Datasite  string `json:"datasite"`
+	CreatedAt string `json:"createdAt"`
+	Objects   int    `json:"objects"`
+	Size      int64  `json:"size"`
+}
+
+type SnapshotListResponse struct {
+	Snapshots []*SnapshotInfo `json:"snapshots"`
+}
+
+// RestoreResponse counts the objects written back, deleted and left as they were by a restore
+type RestoreResponse struct {
+	Name      string `json:"name"`
+	Restored  int    `json:"restored"`
+	Deleted   int    `json:"deleted"`
+	Unchanged int    `json:"unchanged"`
+}
+
+// DeleteResponse counts the stored objects a delete freed, those no other snapshot uses
+type DeleteResponse struct {
+	Name    string `json:"name"`
+	Objects int    `json:"objects"`
+	Size    int64  `json:"size"`
+}
+
+// Manifest lists the objects of the datasite when the snapshot was taken.
+// The content of each object is stored once per datasite under its etag, shared by all snapshots.
+type Manifest struct {
+	SnapshotInfo
+	Entries []*ManifestEntry `json:"entries"`
+}
+
+type ManifestEntry struct {
+	Key  string `json:"key"`
+	ETag string `json:"etag"`
+	Size int64  `json:"size"`
+}
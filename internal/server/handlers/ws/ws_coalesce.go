@@ -0,0 +1,98 @@
+package ws
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/openmined/syftbox/internal/syftmsg"
+)
+
+// heldNotify is the latest notification of a path held back during a window
+type heldNotify struct {
+	path      string
+	msg       *syftmsg.Message
+	predicate func(*ClientInfo) bool
+}
+
+// pendingNotify is the notifications held back for a client during a window
+type pendingNotify struct {
+	held      []*heldNotify // in the order of their latest change
+	coalesced int
+	timer     *time.Timer
+}
+
+// notifyCoalescer batches the notifications to a client, so that a storm of changes
+// (e.g. an ACL flipped back and forth) doesn't send one message per change.
+// The first notification of a window is sent right away. The later ones are held, keeping only the
+// latest of each path, and are sent in one message when the window ends, so the client always ends up
+// with the final state.
+type notifyCoalescer struct {
+	window  time.Duration
+	flush   func(connID string, held []*heldNotify, coalesced int)
+	pending map[string]*pendingNotify // map of ConnectionID -> held notifications
+	mu      sync.Mutex
+}
+
+func newNotifyCoalescer(window time.Duration, flush func(string, []*heldNotify, int)) *notifyCoalescer {
+	return &notifyCoalescer{
+		window:  window,
+		flush:   flush,
+		pending: make(map[string]*pendingNotify),
+	}
+}
+
+// offer reports whether msg should be sent now. Otherwise it is held until the window ends,
+// replacing any notification of the same path held before it.
+func (c *notifyCoalescer) offer(connID string, path string, msg *syftmsg.Message, predicate func(*ClientInfo) bool) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if p, ok := c.pending[connID]; ok {
+		before := len(p.held)
+		p.held = slices.DeleteFunc(p.held, func(n *heldNotify) bool { return n.path == path })
+		p.coalesced += before - len(p.held)
+		p.held = append(p.held, &heldNotify{path: path, msg: msg, predicate: predicate})
+		return false
+	}
+
+	p := &pendingNotify{}
+	p.timer = time.AfterFunc(c.window, func() { c.expire(connID) })
+	c.pending[connID] = p
+	return true
+}
+
+// expire sends the notifications held during a window, and opens a new window while the changes keep coming
+func (c *notifyCoalescer) expire(connID string) {
+	c.mu.Lock()
+	p, ok := c.pending[connID]
+	if !ok {
+		c.mu.Unlock()
+		return
+	}
+
+	if len(p.held) == 0 {
+		// quiet window, the next notification is sent right away
+		delete(c.pending, connID)
+		c.mu.Unlock()
+		return
+	}
+
+	held, coalesced := p.held, p.coalesced
+	p.held, p.coalesced = nil, 0
+	p.timer.Reset(c.window)
+	c.mu.Unlock()
+
+	c.flush(connID, held, coalesced)
+}
+
+// stop drops the held notifications
+func (c *notifyCoalescer) stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for connID, p := range c.pending {
+		p.timer.Stop()
+		delete(c.pending, connID)
+	}
+}
@@ -0,0 +1,166 @@
+package ws
+
+import (
+	"sync"
+	"time"
+)
+
+// fanoutJob is the part of a broadcast left to send
+type fanoutJob struct {
+	connIDs []string
+	deliver func(*WebsocketClient)
+}
+
+// BroadcastStats is the state of the broadcast fan-out limiter, reported to the admins
+type BroadcastStats struct {
+	MaxFanout  int   `json:"maxFanout"`
+	IntervalMs int64 `json:"intervalMs"`
+	Backlog    int   `json:"backlog"`  // notifications waiting for their batch
+	Limited    int64 `json:"limited"`  // broadcasts with clients left to a later batch
+	Deferred   int64 `json:"deferred"` // notifications sent in a later batch
+}
+
+// fanoutLimiter spreads the broadcasts to many clients, e.g. a change to a datasite readable by everyone,
+// so that a single change doesn't send to every connected client at once.
+// A broadcast to more than maxFanout clients sends to maxFanout of them right away, the others are sent to
+// in batches of maxFanout, one batch per interval. The batches are sent one at a time and in order, so while
+// there is a backlog new broadcasts above the cap queue up behind it. A broadcast within the cap is sent
+// right away, except to the clients with notifications still queued, so every client keeps receiving
+// the notifications in order.
+type fanoutLimiter struct {
+	maxFanout int
+	interval  time.Duration
+	flush     func(connIDs []string, deliver func(*WebsocketClient))
+
+	queue    []*fanoutJob
+	pending  map[string]int // queued notifications per client
+	backlog  int
+	limited  int64
+	deferred int64
+	timer    *time.Timer // set while batches are pending
+	stopped  bool
+	mu       sync.Mutex
+}
+
+func newFanoutLimiter(maxFanout int, interval time.Duration, flush func([]string, func(*WebsocketClient))) *fanoutLimiter {
+	return &fanoutLimiter{
+		maxFanout: maxFanout,
+		interval:  interval,
+		flush:     flush,
+		pending:   make(map[string]int),
+	}
+}
+
+// admit returns the clients to send to right away, and queues the others
+func (f *fanoutLimiter) admit(connIDs []string, deliver func(*WebsocketClient)) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.stopped || len(connIDs) == 0 {
+		return connIDs
+	}
+
+	// the clients with queued notifications get this one after them
+	var now, later []string
+	for _, connID := range connIDs {
+		if f.pending[connID] > 0 {
+			later = append(later, connID)
+		} else {
+			now = append(now, connID)
+		}
+	}
+
+	if len(connIDs) > f.maxFanout {
+		if f.backlog > 0 {
+			now, later = nil, connIDs
+		} else if len(now) > f.maxFanout {
+			now, later = now[:f.maxFanout], append(later, now[f.maxFanout:]...)
+		}
+	}
+	if len(later) == 0 {
+		return now
+	}
+
+	f.queue = append(f.queue, &fanoutJob{connIDs: later, deliver: deliver})
+	for _, connID := range later {
+		f.pending[connID]++
+	}
+	f.backlog += len(later)
+	f.deferred += int64(len(later))
+	f.limited++
+	if f.timer == nil {
+		f.timer = time.AfterFunc(f.interval, f.next)
+	}
+	return now
+}
+
+// next sends the next batch, and schedules the one after it once sent
+func (f *fanoutLimiter) next() {
+	f.mu.Lock()
+	if f.stopped {
+		f.mu.Unlock()
+		return
+	}
+
+	var batch []*fanoutJob
+	for n := f.maxFanout; n > 0 && len(f.queue) > 0; {
+		job := f.queue[0]
+		if len(job.connIDs) <= n {
+			batch = append(batch, job)
+			f.queue = f.queue[1:]
+			n -= len(job.connIDs)
+			continue
+		}
+		batch = append(batch, &fanoutJob{connIDs: job.connIDs[:n], deliver: job.deliver})
+		job.connIDs = job.connIDs[n:]
+		n = 0
+	}
+	f.mu.Unlock()
+
+	for _, job := range batch {
+		f.flush(job.connIDs, job.deliver)
+		f.mu.Lock()
+		f.backlog -= len(job.connIDs)
+		for _, connID := range job.connIDs {
+			if f.pending[connID]--; f.pending[connID] <= 0 {
+				delete(f.pending, connID)
+			}
+		}
+		f.mu.Unlock()
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.queue) > 0 && !f.stopped {
+		f.timer = time.AfterFunc(f.interval, f.next)
+	} else {
+		f.timer = nil
+	}
+}
+
+func (f *fanoutLimiter) stats() *BroadcastStats {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return &BroadcastStats{
+		MaxFanout:  f.maxFanout,
+		IntervalMs: f.interval.Milliseconds(),
+		Backlog:    f.backlog,
+		Limited:    f.limited,
+		Deferred:   f.deferred,
+	}
+}
+
+// stop drops the pending batches
+func (f *fanoutLimiter) stop() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.stopped = true
+	if f.timer != nil {
+		f.timer.Stop()
+	}
+	f.queue = nil
+	f.pending = make(map[string]int)
+	f.backlog = 0
+}
@@ -2,10 +2,13 @@ package ws
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/coder/websocket"
 	"github.com/gin-gonic/gin"
@@ -18,27 +21,59 @@ const (
 	maxMessageSize = 4 * 1024 * 1024 // 4MB
 )
 
+var (
+	ErrHubNotRunning = errors.New("websocket hub not running")
+)
+
 type WebsocketHub struct {
-	clients  map[string]*WebsocketClient // map of ConnectionID -> Client
-	register chan *WebsocketClient
-	msgs     chan *ClientMessage
+	clients   map[string]*WebsocketClient // map of ConnectionID -> Client
+	register  chan *WebsocketClient
+	msgs      chan *ClientMessage
+	running   atomic.Bool
+	coalescer *notifyCoalescer // nil when notifications aren't coalesced
+	fanout    *fanoutLimiter   // nil when the broadcasts aren't limited
 
 	wg sync.WaitGroup
 	mu sync.RWMutex
 }
 
-func NewHub() *WebsocketHub {
-	return &WebsocketHub{
+// HubOption configures the hub
+type HubOption func(*WebsocketHub)
+
+// WithBroadcastFanout limits a broadcast to maxFanout clients at once, the next ones are sent to in batches
+// of maxFanout every interval. 0 sends to every client at once.
+func WithBroadcastFanout(maxFanout int, interval time.Duration) HubOption {
+	return func(h *WebsocketHub) {
+		if maxFanout > 0 {
+			h.fanout = newFanoutLimiter(maxFanout, interval, h.deliver)
+		}
+	}
+}
+
+// NewHub creates a hub. Notifications to a client within coalesceWindow are coalesced,
+// 0 sends every notification.
+func NewHub(coalesceWindow time.Duration, opts ...HubOption) *WebsocketHub {
+	h := &WebsocketHub{
 		clients:  make(map[string]*WebsocketClient),
 		register: make(chan *WebsocketClient),
 		msgs:     make(chan *ClientMessage, 128),
 	}
+	if coalesceWindow > 0 {
+		h.coalescer = newNotifyCoalescer(coalesceWindow, h.sendCoalesced)
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
 }
 
 func (h *WebsocketHub) Run(ctx context.Context) {
 	slog.Info("wshub started")
 	defer slog.Info("wshub stopped")
 
+	h.running.Store(true)
+	defer h.running.Store(false)
+
 	for {
 		select {
 		case client := <-h.register:
@@ -68,7 +103,15 @@ func (h *WebsocketHub) Run(ctx context.Context) {
 	}
 }
 
-// OnMessage registers a handler function that gets called when a client sends a message
+// Ping returns an error if the hub is not accepting clients
+func (h *WebsocketHub) Ping(ctx context.Context) error {
+	if !h.running.Load() {
+		return ErrHubNotRunning
+	}
+	return nil
+}
+
+// Messages returns the messages sent by the clients
 func (h *WebsocketHub) Messages() <-chan *ClientMessage {
 	return h.msgs
 }
@@ -76,6 +119,13 @@ func (h *WebsocketHub) Messages() <-chan *ClientMessage {
 func (h *WebsocketHub) Shutdown(ctx context.Context) {
 	close(h.register)
 
+	if h.coalescer != nil {
+		h.coalescer.stop()
+	}
+	if h.fanout != nil {
+		h.fanout.stop()
+	}
+
 	for _, client := range h.clients {
 		go func() {
 			// will automatically remove client from hub using the Closed channel
@@ -163,20 +213,126 @@ func (h *WebsocketHub) Broadcast(msg *syftmsg.Message) {
 
 // BroadcastFiltered sends a message to all clients that match the filter
 func (h *WebsocketHub) BroadcastFiltered(msg *syftmsg.Message, predicate func(*ClientInfo) bool) {
+	h.broadcast(predicate, func(client *WebsocketClient) {
+		select {
+		case client.MsgTx <- msg:
+		default:
+			slog.Warn("wshub send buffer full", "connId", client.ConnID, "user", client.Info.User)
+		}
+	})
+}
+
+// BroadcastCoalesced sends a notification about a path to all clients that match the filter.
+// Notifications within the coalescing window are batched per client. When the window ends,
+// the client gets one message with the latest notification of every path.
+func (h *WebsocketHub) BroadcastCoalesced(path string, msg *syftmsg.Message, predicate func(*ClientInfo) bool) {
+	if h.coalescer == nil {
+		h.BroadcastFiltered(msg, predicate)
+		return
+	}
+
+	h.broadcast(predicate, func(client *WebsocketClient) {
+		if !h.coalescer.offer(client.ConnID, path, msg, predicate) {
+			return
+		}
+		select {
+		case client.MsgTx <- msg:
+		default:
+			slog.Warn("wshub send buffer full", "connId", client.ConnID, "user", client.Info.User)
+		}
+	})
+}
+
+// BroadcastStats returns the state of the fan-out limiter, nil when the broadcasts aren't limited
+func (h *WebsocketHub) BroadcastStats() *BroadcastStats {
+	if h.fanout == nil {
+		return nil
+	}
+	return h.fanout.stats()
+}
+
+// broadcast calls deliver for every client that matches the filter, through the fan-out limiter if any.
+// Only the matching clients count towards the fan-out. The filters check the ACLs,
+// so the clients left to a later batch are matched again when their batch is sent.
+func (h *WebsocketHub) broadcast(predicate func(*ClientInfo) bool, deliver func(*WebsocketClient)) {
 	h.mu.RLock()
-	defer h.mu.RUnlock()
+	if h.fanout == nil {
+		defer h.mu.RUnlock()
+		for _, client := range h.clients {
+			if predicate(client.Info) {
+				deliver(client)
+			}
+		}
+		return
+	}
 
-	for _, client := range h.clients {
+	var connIDs []string
+	for connID, client := range h.clients {
 		if predicate(client.Info) {
-			select {
-			case client.MsgTx <- msg:
-			default:
-				slog.Warn("wshub send buffer full", "connId", client.ConnID, "user", client.Info.User)
-			}
+			connIDs = append(connIDs, connID)
+		}
+	}
+	h.mu.RUnlock()
+
+	now := h.fanout.admit(connIDs, func(client *WebsocketClient) {
+		if predicate(client.Info) {
+			deliver(client)
+		}
+	})
+	if len(now) < len(connIDs) {
+		slog.Debug("wshub broadcast limited", "recipients", len(connIDs), "now", len(now))
+	}
+	h.deliver(now, deliver)
+}
+
+// deliver calls deliver for the clients still connected
+func (h *WebsocketHub) deliver(connIDs []string, deliver func(*WebsocketClient)) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for _, connID := range connIDs {
+		if client, ok := h.clients[connID]; ok {
+			deliver(client)
 		}
 	}
 }
 
+// sendCoalesced sends the notifications held by the coalescer in one message,
+// if the client is still connected. Only the notifications the client still matches the filter of are sent.
+func (h *WebsocketHub) sendCoalesced(connID string, held []*heldNotify, coalesced int) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	client, ok := h.clients[connID]
+	if !ok {
+		return
+	}
+
+	msgs := make([]*syftmsg.Message, 0, len(held))
+	for _, n := range held {
+		if n.predicate(client.Info) {
+			msgs = append(msgs, n.msg)
+		}
+	}
+
+	var msg *syftmsg.Message
+	switch len(msgs) {
+	case 0:
+		return
+	case 1:
+		msg = msgs[0]
+	default:
+		msg = syftmsg.NewBatch(msgs)
+	}
+
+	slog.Debug("wshub send coalesced", "connId", connID, "user", client.Info.User, "id", msg.Id, "paths", len(msgs), "coalesced", coalesced)
+	select {
+	case client.MsgTx <- msg:
+	default:
+		slog.Warn("wshub send buffer full", "connId", client.ConnID, "user", client.Info.User)
+	}
+}
+
 // handleClientMessages processes incoming messages from a client and calls registered handlers
 func (h *WebsocketHub) handleClientMessages(client *WebsocketClient) {
 	for {
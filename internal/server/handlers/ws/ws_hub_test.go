@@ -0,0 +1,297 @@
+package ws
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/openmined/syftbox/internal/syftmsg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testCoalesceWindow = 50 * time.Millisecond
+
+func newTestHubClient(h *WebsocketHub, user string) *WebsocketClient {
+	client := &WebsocketClient{
+		ConnID: "conn-" + user,
+		Info:   &ClientInfo{User: user},
+		MsgTx:  make(chan *syftmsg.Message, 64),
+		Closed: make(chan struct{}),
+	}
+	h.mu.Lock()
+	h.clients[client.ConnID] = client
+	h.mu.Unlock()
+	return client
+}
+
+// drain collects the messages received by a client until it is quiet for a few windows
+func drain(client *WebsocketClient) []*syftmsg.Message {
+	var msgs []*syftmsg.Message
+	for {
+		select {
+		case msg := <-client.MsgTx:
+			msgs = append(msgs, msg)
+		case <-time.After(4 * testCoalesceWindow):
+			return msgs
+		}
+	}
+}
+
+func aclWrite(i int) *syftmsg.Message {
+	return syftmsg.NewFileWrite("alice@example.com/public/syft.pub.yaml", fmt.Sprintf("etag-%d", i), 1, []byte(fmt.Sprintf("rev %d", i)))
+}
+
+func TestBroadcastCoalesced(t *testing.T) {
+	h := NewHub(testCoalesceWindow)
+	defer h.coalescer.stop()
+
+	bob := newTestHubClient(h, "bob@example.com")
+	carol := newTestHubClient(h, "carol@example.com")
+	everyone := func(*ClientInfo) bool { return true }
+
+	// a storm of writes to the same acl
+	const flips = 50
+	var last *syftmsg.Message
+	for i := range flips {
+		last = aclWrite(i)
+		h.BroadcastCoalesced("alice@example.com/public/syft.pub.yaml", last, everyone)
+	}
+
+	for _, client := range []*WebsocketClient{bob, carol} {
+		msgs := drain(client)
+		require.Len(t, msgs, 2, "the first write right away, then the coalesced one")
+		assert.Equal(t, "etag-0", msgs[0].Data.(*syftmsg.FileWrite).ETag)
+		assert.Same(t, last, msgs[1], "the final state is delivered")
+	}
+
+	// once quiet, the next write is sent right away again
+	next := aclWrite(flips)
+	h.BroadcastCoalesced("alice@example.com/public/syft.pub.yaml", next, everyone)
+	select {
+	case msg := <-bob.MsgTx:
+		assert.Same(t, next, msg)
+	case <-time.After(testCoalesceWindow / 2):
+		t.Fatal("write after a quiet window was held back")
+	}
+}
+
+func TestBroadcastCoalescedPaths(t *testing.T) {
+	h := NewHub(testCoalesceWindow)
+	defer h.coalescer.stop()
+
+	bob := newTestHubClient(h, "bob@example.com")
+	everyone := func(*ClientInfo) bool { return true }
+	write := func(path string, etag string) {
+		h.BroadcastCoalesced(path, syftmsg.NewFileWrite(path, etag, 1, nil), everyone)
+	}
+
+	// changes to several paths are held in one window, and sent together
+	write("alice@example.com/a.txt", "a-0")
+	write("alice@example.com/b.txt", "b-0")
+	write("alice@example.com/c.txt", "c-0")
+	write("alice@example.com/b.txt", "b-1")
+
+	msgs := drain(bob)
+	require.Len(t, msgs, 2, "the first change right away, then one message for the rest")
+	assert.Equal(t, "a-0", msgs[0].Data.(*syftmsg.FileWrite).ETag)
+	require.Equal(t, syftmsg.MsgBatch, msgs[1].Type)
+
+	// the client decodes the batch, with the latest change of every path
+	data, err := json.Marshal(msgs[1])
+	require.NoError(t, err)
+	var decoded syftmsg.Message
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	batch := decoded.Data.(syftmsg.Batch)
+	var etags []string
+	for _, msg := range batch.Messages {
+		etags = append(etags, msg.Data.(syftmsg.FileWrite).ETag)
+	}
+	assert.Equal(t, []string{"c-0", "b-1"}, etags)
+}
+
+func TestBroadcastCoalescedPathsRevoked(t *testing.T) {
+	h := NewHub(testCoalesceWindow)
+	defer h.coalescer.stop()
+
+	bob := newTestHubClient(h, "bob@example.com")
+	everyone := func(*ClientInfo) bool { return true }
+	var revoked atomic.Bool
+	canRead := func(*ClientInfo) bool { return !revoked.Load() }
+
+	h.BroadcastCoalesced("alice@example.com/a.txt", syftmsg.NewFileWrite("alice@example.com/a.txt", "a", 1, nil), everyone)
+	h.BroadcastCoalesced("alice@example.com/b.txt", syftmsg.NewFileWrite("alice@example.com/b.txt", "b", 1, nil), everyone)
+	h.BroadcastCoalesced("alice@example.com/private/c.txt", syftmsg.NewFileWrite("alice@example.com/private/c.txt", "c", 1, nil), canRead)
+	revoked.Store(true)
+
+	// the path bob can't read anymore is left out
+	msgs := drain(bob)
+	require.Len(t, msgs, 2)
+	assert.Equal(t, "b", msgs[1].Data.(*syftmsg.FileWrite).ETag)
+}
+
+func TestBroadcastCoalescedRevoked(t *testing.T) {
+	h := NewHub(testCoalesceWindow)
+	defer h.coalescer.stop()
+
+	bob := newTestHubClient(h, "bob@example.com")
+
+	// bob loses read access in the middle of the storm
+	var revoked atomic.Bool
+	canRead := func(*ClientInfo) bool { return !revoked.Load() }
+
+	h.BroadcastCoalesced("alice@example.com/public/syft.pub.yaml", aclWrite(0), canRead)
+	h.BroadcastCoalesced("alice@example.com/public/syft.pub.yaml", aclWrite(1), canRead)
+	revoked.Store(true)
+
+	msgs := drain(bob)
+	require.Len(t, msgs, 1, "held writes are not sent once access is gone")
+	assert.Equal(t, "etag-0", msgs[0].Data.(*syftmsg.FileWrite).ETag)
+}
+
+func TestBroadcastCoalescedDisabled(t *testing.T) {
+	h := NewHub(0)
+	require.Nil(t, h.coalescer)
+
+	bob := newTestHubClient(h, "bob@example.com")
+	everyone := func(*ClientInfo) bool { return true }
+
+	for i := range 5 {
+		h.BroadcastCoalesced("alice@example.com/public/syft.pub.yaml", aclWrite(i), everyone)
+	}
+
+	assert.Len(t, drain(bob), 5)
+}
+
+func TestBroadcastFanoutLimited(t *testing.T) {
+	const (
+		clients   = 1000
+		maxFanout = 100
+		interval  = 20 * time.Millisecond
+	)
+	h := NewHub(0, WithBroadcastFanout(maxFanout, interval))
+	defer h.fanout.stop()
+
+	all := make([]*WebsocketClient, clients)
+	for i := range clients {
+		all[i] = newTestHubClient(h, fmt.Sprintf("user%d@example.com", i))
+	}
+	everyone := func(*ClientInfo) bool { return true }
+	received := func() int {
+		n := 0
+		for _, client := range all {
+			n += len(client.MsgTx)
+		}
+		return n
+	}
+
+	// alice's public datasite changes, every client can read it
+	start := time.Now()
+	first, second := aclWrite(0), aclWrite(1)
+	h.BroadcastFiltered(first, everyone)
+	assert.Equal(t, maxFanout, received(), "only a batch is sent right away")
+	assert.Equal(t, clients-maxFanout, h.BroadcastStats().Backlog)
+
+	// a broadcast during the backlog waits behind it
+	h.BroadcastFiltered(second, everyone)
+	assert.Equal(t, maxFanout, received())
+	assert.Equal(t, 2*clients-maxFanout, h.BroadcastStats().Backlog)
+
+	require.Eventually(t, func() bool { return h.BroadcastStats().Backlog == 0 }, 5*time.Second, interval)
+	elapsed := time.Since(start)
+
+	// spread over a batch per interval, and nothing dropped
+	assert.GreaterOrEqual(t, elapsed, (2*clients/maxFanout-1)*interval)
+	for _, client := range all {
+		require.Len(t, client.MsgTx, 2, client.ConnID)
+		assert.Same(t, first, <-client.MsgTx)
+		assert.Same(t, second, <-client.MsgTx)
+	}
+
+	stats := h.BroadcastStats()
+	assert.Equal(t, int64(2), stats.Limited)
+	assert.Equal(t, int64(2*clients-maxFanout), stats.Deferred)
+}
+
+func TestBroadcastFanoutDisconnected(t *testing.T) {
+	h := NewHub(0, WithBroadcastFanout(1, testCoalesceWindow))
+	defer h.fanout.stop()
+	require.Nil(t, NewHub(0).BroadcastStats())
+
+	bob := newTestHubClient(h, "bob@example.com")
+	carol := newTestHubClient(h, "carol@example.com")
+
+	h.BroadcastFiltered(aclWrite(0), func(*ClientInfo) bool { return true })
+	// whoever got the first batch, the other one disconnects before its batch
+	h.mu.Lock()
+	delete(h.clients, bob.ConnID)
+	delete(h.clients, carol.ConnID)
+	h.mu.Unlock()
+
+	require.Eventually(t, func() bool { return h.BroadcastStats().Backlog == 0 }, time.Second, testCoalesceWindow)
+	assert.Equal(t, 1, len(bob.MsgTx)+len(carol.MsgTx))
+}
+
+func TestBroadcastFanoutCountsRecipients(t *testing.T) {
+	const (
+		clients   = 1000
+		maxFanout = 100
+	)
+	h := NewHub(0, WithBroadcastFanout(maxFanout, time.Hour))
+	defer h.fanout.stop()
+
+	all := make([]*WebsocketClient, clients)
+	for i := range clients {
+		all[i] = newTestHubClient(h, fmt.Sprintf("user%d@example.com", i))
+	}
+	only := func(users ...*WebsocketClient) func(*ClientInfo) bool {
+		return func(info *ClientInfo) bool {
+			for _, client := range users {
+				if client.Info == info {
+					return true
+				}
+			}
+			return false
+		}
+	}
+
+	// a broadcast to a few of many clients is within the cap
+	for i := range 50 {
+		h.BroadcastFiltered(aclWrite(i), only(all[0], all[1]))
+	}
+	assert.Len(t, all[0].MsgTx, 50)
+	assert.Len(t, all[1].MsgTx, 50)
+	stats := h.BroadcastStats()
+	assert.Zero(t, stats.Backlog)
+	assert.Zero(t, stats.Limited)
+	for _, client := range all[:2] {
+		for len(client.MsgTx) > 0 {
+			<-client.MsgTx
+		}
+	}
+
+	// a broadcast to everyone is above the cap
+	everyone := func(*ClientInfo) bool { return true }
+	h.BroadcastFiltered(aclWrite(50), everyone)
+	require.Equal(t, clients-maxFanout, h.BroadcastStats().Backlog)
+
+	// during its backlog, a small broadcast still goes right away to the clients with nothing queued,
+	// and waits behind the queued notifications of the others
+	var sent, queued *WebsocketClient
+	for _, client := range all {
+		if len(client.MsgTx) == 0 && queued == nil {
+			queued = client
+		}
+		if len(client.MsgTx) == 1 && sent == nil {
+			sent = client
+		}
+	}
+	require.NotNil(t, sent)
+	require.NotNil(t, queued)
+	h.BroadcastFiltered(aclWrite(51), only(sent, queued))
+	assert.Len(t, sent.MsgTx, 2)
+	assert.Empty(t, queued.MsgTx)
+	assert.Equal(t, clients-maxFanout+1, h.BroadcastStats().Backlog)
+}
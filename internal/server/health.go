@@ -0,0 +1,271 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/openmined/syftbox/internal/server/blob"
+	"github.com/openmined/syftbox/internal/server/datasite"
+	"github.com/openmined/syftbox/internal/server/handlers/ws"
+)
+
+const (
+	healthCheckTimeout = 5 * time.Second
+	// checks of external services are cached, so that polling the health doesn't hit their apis
+	externalCheckTTL = time.Minute
+)
+
+// HealthStatus is the status of a subsystem or the server as a whole
+type HealthStatus string
+
+const (
+	HealthStatusOK       HealthStatus = "ok"
+	HealthStatusDegraded HealthStatus = "degraded"
+	HealthStatusDown     HealthStatus = "down"
+)
+
+// HealthPinger is implemented by subsystems that can report their health
+type HealthPinger interface {
+	Ping(ctx context.Context) error
+}
+
+// HealthCheck is a named subsystem health check
+type HealthCheck struct {
+	Name   string
+	Pinger HealthPinger
+}
+
+// HealthCheckResult is the outcome of a single subsystem check
+type HealthCheckResult struct {
+	Status    HealthStatus `json:"status"`
+	LatencyMs float64      `json:"latencyMs"`
+	Error     string       `json:"error,omitempty"`
+}
+
+// HealthReport is the verbose health report of the server
+type HealthReport struct {
+	Status    HealthStatus                  `json:"status"`
+	Timestamp string                        `json:"ts"`
+	LatencyMs float64                       `json:"latencyMs"`
+	Checks    map[string]*HealthCheckResult `json:"checks"`
+}
+
+// pingFunc adapts a function to a HealthPinger
+type pingFunc func(ctx context.Context) error
+
+func (f pingFunc) Ping(ctx context.Context) error {
+	return f(ctx)
+}
+
+// cachedPinger reuses the result of a check for a while
+type cachedPinger struct {
+	pinger HealthPinger
+	ttl    time.Duration
+	now    func() time.Time
+
+	checkedAt time.Time
+	err       error
+	mu        sync.Mutex
+}
+
+func newCachedPinger(pinger HealthPinger, ttl time.Duration) *cachedPinger {
+	return &cachedPinger{
+		pinger: pinger,
+		ttl:    ttl,
+		now:    time.Now,
+	}
+}
+
+func (p *cachedPinger) Ping(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.checkedAt.IsZero() && p.now().Sub(p.checkedAt) < p.ttl {
+		return p.err
+	}
+
+	err := p.pinger.Ping(ctx)
+	if ctx.Err() != nil {
+		// the caller gave up, the result says nothing about the service
+		return err
+	}
+	p.checkedAt, p.err = p.now(), err
+	return err
+}
+
+// HealthChecker runs the subsystem checks for the health endpoint
+type HealthChecker struct {
+	checks  []HealthCheck
+	timeout time.Duration
+}
+
+func NewHealthChecker(checks ...HealthCheck) *HealthChecker {
+	return &HealthChecker{
+		checks:  checks,
+		timeout: healthCheckTimeout,
+	}
+}
+
+// Check runs all checks concurrently. Each check is bound by the checker's timeout.
+func (h *HealthChecker) Check(ctx context.Context) *HealthReport {
+	start := time.Now()
+
+	results := make([]*HealthCheckResult, len(h.checks))
+	var wg sync.WaitGroup
+	for i, check := range h.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = h.runCheck(ctx, check)
+		}()
+	}
+	wg.Wait()
+
+	report := &HealthReport{
+		Status: HealthStatusOK,
+		Checks: make(map[string]*HealthCheckResult, len(h.checks)),
+	}
+
+	failed := 0
+	for i, check := range h.checks {
+		report.Checks[check.Name] = results[i]
+		if results[i].Status != HealthStatusOK {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		report.Status = HealthStatusDegraded
+		if failed == len(h.checks) {
+			report.Status = HealthStatusDown
+		}
+	}
+
+	report.Timestamp = time.Now().UTC().Format(time.RFC3339)
+	report.LatencyMs = latencyMs(time.Since(start))
+	return report
+}
+
+func (h *HealthChecker) runCheck(ctx context.Context, check HealthCheck) *HealthCheckResult {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check.Pinger.Ping(ctx)
+
+	result := &HealthCheckResult{
+		Status:    HealthStatusOK,
+		LatencyMs: latencyMs(time.Since(start)),
+	}
+	if err != nil {
+		result.Status = HealthStatusDown
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// Handler serves /healthz. The plain endpoint doesn't check anything, so that it stays fast for load balancers.
+// With ?verbose=true it reports the health of every subsystem, and responds with 503 if any of them is failing.
+// The verbose report is for the admins, see ifVerbose.
+func (h *HealthChecker) Handler(ctx *gin.Context) {
+	if !isVerbose(ctx) {
+		HealthHandler(ctx)
+		return
+	}
+
+	report := h.Check(ctx.Request.Context())
+
+	status := http.StatusOK
+	if report.Status != HealthStatusOK {
+		status = http.StatusServiceUnavailable
+	}
+
+	ctx.PureJSON(status, report)
+}
+
+// ifVerbose runs a middleware on the verbose health requests only, e.g. to serve them to the admins
+func ifVerbose(middleware gin.HandlerFunc) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if isVerbose(ctx) {
+			middleware(ctx)
+			return
+		}
+		ctx.Next()
+	}
+}
+
+func isVerbose(ctx *gin.Context) bool {
+	verbose, _ := strconv.ParseBool(ctx.Query("verbose"))
+	return verbose
+}
+
+// newHealthChecker sets up the health checks for the server's subsystems
+func newHealthChecker(svc *Services, hub HealthPinger) *HealthChecker {
+	checks := []HealthCheck{
+		// serving this request is the check
+		{Name: "http", Pinger: pingFunc(func(context.Context) error { return nil })},
+		{Name: "blob", Pinger: svc.Blob.Backend()},
+		{Name: "acl", Pinger: svc.ACL},
+		{Name: "websocket", Pinger: hub},
+	}
+
+	// the reads fall back to the primary past the max lag, so a lagging replica only degrades the server
+	if replica, ok := svc.Blob.ReadIndex().(*blob.ReplicatedIndex); ok {
+		checks = append(checks, HealthCheck{Name: "index_replica", Pinger: replica})
+	}
+
+	if svc.Email.IsEnabled() {
+		checks = append(checks, HealthCheck{Name: "email", Pinger: newCachedPinger(svc.Email, externalCheckTTL)})
+	}
+
+	return NewHealthChecker(checks...)
+}
+
+// StatsReport is the load of the server, served to the admins on /api/v1/admin/stats
+type StatsReport struct {
+	Datasites *datasite.DatasiteStats `json:"datasites"`
+	Blob      *blob.BlobLimitStats    `json:"blob"`
+
+	IndexReplica *blob.IndexReplicaStats `json:"indexReplica,omitempty"`
+	Broadcast    *ws.BroadcastStats      `json:"broadcast,omitempty"`
+}
+
+// BlobStatsReporter reports the state of the blob operation limiters, and of the replica of the index if any
+type BlobStatsReporter interface {
+	LimitStats() *blob.BlobLimitStats
+	IndexReplicaStats() *blob.IndexReplicaStats
+}
+
+// BroadcastStatsReporter reports the backlog of the websocket broadcasts
+type BroadcastStatsReporter interface {
+	BroadcastStats() *ws.BroadcastStats
+}
+
+// ReadyHandler serves /readyz. The server stays ready when the datasite cap is reached,
+// as existing datasites are still served, when blob operations queue up, when the index replica lags,
+// and when the broadcasts have a backlog. Those are served to the admins by StatsHandler.
+func ReadyHandler(ctx *gin.Context) {
+	ctx.PureJSON(http.StatusOK, gin.H{
+		"status": "ready",
+	})
+}
+
+// StatsHandler serves the StatsReport
+func StatsHandler(datasites *datasite.DatasiteService, blobs BlobStatsReporter, broadcasts BroadcastStatsReporter) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.PureJSON(http.StatusOK, &StatsReport{
+			Datasites:    datasites.Stats(),
+			Blob:         blobs.LimitStats(),
+			IndexReplica: blobs.IndexReplicaStats(),
+			Broadcast:    broadcasts.BroadcastStats(),
+		})
+	}
+}
+
+func latencyMs(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000.0
+}
@@ -0,0 +1,198 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/openmined/syftbox/internal/server/blob"
+	"github.com/openmined/syftbox/internal/server/datasite"
+	"github.com/openmined/syftbox/internal/server/handlers/ws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakePinger struct {
+	err   error
+	delay time.Duration
+	calls atomic.Int32
+}
+
+func (p *fakePinger) Ping(ctx context.Context) error {
+	p.calls.Add(1)
+	select {
+	case <-time.After(p.delay):
+		return p.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func newHealthTestRouter(h *HealthChecker) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/healthz", h.Handler)
+	return r
+}
+
+func TestHealthVerboseFailingBlob(t *testing.T) {
+	blobBackend := &fakePinger{err: errors.New("bucket unreachable")}
+	acl := &fakePinger{}
+	hub := &fakePinger{}
+
+	r := newHealthTestRouter(NewHealthChecker(
+		HealthCheck{Name: "blob", Pinger: blobBackend},
+		HealthCheck{Name: "acl", Pinger: acl},
+		HealthCheck{Name: "websocket", Pinger: hub},
+	))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz?verbose=true", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	var report HealthReport
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+
+	assert.Equal(t, HealthStatusDegraded, report.Status)
+	require.Len(t, report.Checks, 3)
+
+	require.Contains(t, report.Checks, "blob")
+	assert.Equal(t, HealthStatusDown, report.Checks["blob"].Status)
+	assert.Equal(t, "bucket unreachable", report.Checks["blob"].Error)
+
+	assert.Equal(t, HealthStatusOK, report.Checks["acl"].Status)
+	assert.Empty(t, report.Checks["acl"].Error)
+	assert.Equal(t, HealthStatusOK, report.Checks["websocket"].Status)
+}
+
+func TestHealthVerboseOK(t *testing.T) {
+	r := newHealthTestRouter(NewHealthChecker(
+		HealthCheck{Name: "blob", Pinger: &fakePinger{delay: 10 * time.Millisecond}},
+		HealthCheck{Name: "acl", Pinger: &fakePinger{}},
+	))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz?verbose=1", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var report HealthReport
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Equal(t, HealthStatusOK, report.Status)
+	assert.GreaterOrEqual(t, report.Checks["blob"].LatencyMs, 10.0)
+	assert.GreaterOrEqual(t, report.LatencyMs, report.Checks["blob"].LatencyMs)
+}
+
+func TestHealthCheckTimeout(t *testing.T) {
+	h := NewHealthChecker(
+		HealthCheck{Name: "blob", Pinger: &fakePinger{delay: time.Minute}},
+	)
+	h.timeout = 10 * time.Millisecond
+
+	report := h.Check(context.Background())
+	assert.Equal(t, HealthStatusDown, report.Status)
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks["blob"].Error)
+}
+
+func TestHealthPlainSkipsChecks(t *testing.T) {
+	blobBackend := &fakePinger{err: errors.New("bucket unreachable")}
+	r := newHealthTestRouter(NewHealthChecker(HealthCheck{Name: "blob", Pinger: blobBackend}))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"status":"ok"}`, w.Body.String())
+	assert.Zero(t, blobBackend.calls.Load())
+}
+
+func TestHealthVerboseAdminsOnly(t *testing.T) {
+	blobBackend := &fakePinger{}
+	h := NewHealthChecker(HealthCheck{Name: "blob", Pinger: blobBackend})
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/healthz", ifVerbose(func(ctx *gin.Context) {
+		ctx.AbortWithStatus(http.StatusForbidden)
+	}), h.Handler)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz?verbose=true", nil))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Zero(t, blobBackend.calls.Load())
+}
+
+func TestCachedPinger(t *testing.T) {
+	sendgrid := &fakePinger{err: errors.New("unauthorized")}
+	now := time.Now()
+	p := newCachedPinger(sendgrid, time.Minute)
+	p.now = func() time.Time { return now }
+
+	assert.ErrorContains(t, p.Ping(context.Background()), "unauthorized")
+	sendgrid.err = nil
+	assert.ErrorContains(t, p.Ping(context.Background()), "unauthorized", "cached")
+	assert.Equal(t, int32(1), sendgrid.calls.Load())
+
+	now = now.Add(time.Minute)
+	assert.NoError(t, p.Ping(context.Background()))
+	assert.Equal(t, int32(2), sendgrid.calls.Load())
+
+	// a check cut short isn't cached
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	now = now.Add(time.Minute)
+	p.Ping(ctx)
+	assert.NoError(t, p.Ping(context.Background()))
+	assert.Equal(t, int32(4), sendgrid.calls.Load())
+}
+
+func TestReadyReportsNoStats(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/readyz", ReadyHandler)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"status":"ready"}`, w.Body.String())
+}
+
+func TestStatsReportsDatasites(t *testing.T) {
+	datasites := datasite.NewDatasiteService(nil, nil, "", &datasite.Config{MaxDatasites: 2})
+	require.NoError(t, datasites.Admit("alice@example.com"))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/api/v1/admin/stats", StatsHandler(datasites, fakeBlobLimits{}, ws.NewHub(0)))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/admin/stats", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{
+		"datasites":{"count":1,"max":2,"limitReached":false},
+		"blob":{"reads":{"limit":4,"inFlight":1,"queued":0},"writes":{"limit":2,"inFlight":2,"queued":3}}
+	}`, w.Body.String())
+}
+
+type fakeBlobLimits struct{}
+
+func (fakeBlobLimits) LimitStats() *blob.BlobLimitStats {
+	return &blob.BlobLimitStats{
+		Reads:  &blob.OpLimitStats{Limit: 4, InFlight: 1},
+		Writes: &blob.OpLimitStats{Limit: 2, InFlight: 2, Queued: 3},
+	}
+}
+
+func (fakeBlobLimits) IndexReplicaStats() *blob.IndexReplicaStats {
+	return nil
+}
@@ -8,7 +8,10 @@ import (
 var (
 	excludedPaths = []string{
 		"/healthz",
+		"/readyz",
 		"/releases",
+		"/api/v1/datasite/archive",
+		"/blob/", // stored blobs are served as is
 	}
 	excludedExtensions = []string{
 		".png", ".gif", ".jpeg", ".jpg", ".webp", ".ico",
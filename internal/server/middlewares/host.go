@@ -0,0 +1,71 @@
+package middlewares
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const maxHostLength = 253
+
+var (
+	ErrMalformedHost = errors.New("malformed host")
+)
+
+var (
+	// regexHostLabel matches a hostname label. underscores are allowed for docker service names
+	regexHostLabel = regexp.MustCompile(`^[a-z0-9_]([a-z0-9_-]{0,61}[a-z0-9_])?$`)
+)
+
+// parseHost returns the normalized hostname of a Host header: without the port,
+// lowercase, without a trailing dot, and without brackets for IPv6 literals
+func parseHost(hostport string) (string, error) {
+	if hostport == "" {
+		return "", fmt.Errorf("%w: empty", ErrMalformedHost)
+	}
+
+	host := hostport
+	if h, port, err := net.SplitHostPort(hostport); err == nil {
+		if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+			return "", fmt.Errorf("%w: invalid port %q", ErrMalformedHost, port)
+		}
+		host = h
+	} else if strings.HasPrefix(hostport, "[") && strings.HasSuffix(hostport, "]") {
+		// IPv6 literal without a port
+		host = hostport[1 : len(hostport)-1]
+	} else if strings.Count(hostport, ":") == 1 {
+		// a single colon that isn't a host:port
+		return "", fmt.Errorf("%w: %q", ErrMalformedHost, hostport)
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.String(), nil
+	}
+	if strings.Contains(host, ":") {
+		return "", fmt.Errorf("%w: invalid IPv6 literal %q", ErrMalformedHost, host)
+	}
+
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "" || len(host) > maxHostLength {
+		return "", fmt.Errorf("%w: %q", ErrMalformedHost, hostport)
+	}
+	for label := range strings.SplitSeq(host, ".") {
+		if !regexHostLabel.MatchString(label) {
+			return "", fmt.Errorf("%w: %q", ErrMalformedHost, hostport)
+		}
+	}
+
+	return host, nil
+}
+
+// normalizeHost lowercases a host and strips its port, for hosts that failed to parse
+func normalizeHost(hostport string) string {
+	host := hostport
+	if idx := strings.LastIndex(host, ":"); idx != -1 {
+		host = host[:idx]
+	}
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
@@ -0,0 +1,112 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/openmined/syftbox/internal/server/datasite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseHost(t *testing.T) {
+	tests := []struct {
+		hostport string
+		expected string
+	}{
+		{"syftbox.net", "syftbox.net"},
+		{"syftbox.net:8080", "syftbox.net"},
+		{"SyftBox.NET", "syftbox.net"},
+		{"blog.alice.dev.", "blog.alice.dev"},
+		{"blog.alice.dev.:443", "blog.alice.dev"},
+		{"syftbox_server:8080", "syftbox_server"},
+		{"127.0.0.1:8080", "127.0.0.1"},
+		{"[::1]:8080", "::1"},
+		{"[::1]", "::1"},
+		{"::1", "::1"},
+		{"[FD00::0001]:8080", "fd00::1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.hostport, func(t *testing.T) {
+			host, err := parseHost(tt.hostport)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, host)
+		})
+	}
+}
+
+func TestParseHostMalformed(t *testing.T) {
+	for _, hostport := range []string{
+		"",
+		".",
+		"syftbox.net:",
+		"syftbox.net:http",
+		"syftbox.net:99999",
+		"[::1",
+		"[fd00::zz]:8080",
+		"fd00::zz",
+		"a..b.syftbox.net",
+		"-alice.syftbox.net",
+		"alice .syftbox.net",
+		"alice/../bob.syftbox.net",
+		"alice@example.com",
+	} {
+		t.Run(hostport, func(t *testing.T) {
+			_, err := parseHost(hostport)
+			assert.ErrorIs(t, err, ErrMalformedHost)
+		})
+	}
+}
+
+func TestSubdomainRewriteHostParsing(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mapping := datasite.NewSubdomainMapping()
+	mapping.AddVanityDomain("blog.alice.dev", "alice@example.com", "/blog")
+
+	newRouter := func(strict bool) *gin.Engine {
+		router := gin.New()
+		router.Use(SubdomainRewrite(router, &SubdomainRewriteConfig{
+			Domain:      "syftbox.net",
+			Mapping:     mapping,
+			StrictHosts: strict,
+		}))
+		router.GET("/*path", func(c *gin.Context) {
+			c.String(http.StatusOK, c.Request.URL.Path)
+		})
+		return router
+	}
+
+	tests := []struct {
+		host           string
+		strict         bool
+		expectedStatus int
+		expectedPath   string
+	}{
+		{"blog.alice.dev", true, http.StatusOK, "/datasites/alice@example.com/blog/index.html"},
+		{"BLOG.alice.dev.:8443", true, http.StatusOK, "/datasites/alice@example.com/blog/index.html"},
+		{"SYFTBOX.NET.", true, http.StatusOK, "/index.html"},
+		{"[::1]:8080", true, http.StatusOK, "/index.html"},
+		{"[fd00::1]:8080", true, http.StatusNotFound, ""},
+		{"blog.alice.dev:http", true, http.StatusBadRequest, ""},
+		{"a..b.syftbox.net", true, http.StatusBadRequest, ""},
+		// without strict hosts, malformed hosts are routed like unknown ones
+		{"a..b.syftbox.net", false, http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/index.html", nil)
+			req.Host = tt.host
+			w := httptest.NewRecorder()
+			newRouter(tt.strict).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedPath != "" {
+				assert.Equal(t, tt.expectedPath, w.Body.String())
+			}
+		})
+	}
+}
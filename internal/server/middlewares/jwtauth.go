@@ -7,6 +7,8 @@ import (
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/openmined/syftbox/internal/server/accesslog"
+	"github.com/openmined/syftbox/internal/server/acl"
 	"github.com/openmined/syftbox/internal/server/auth" // Import your auth package
 	"github.com/openmined/syftbox/internal/server/handlers/api"
 	"github.com/openmined/syftbox/internal/utils"
@@ -32,12 +34,19 @@ func JWTAuth(authService *auth.AuthService, allowGuest bool) gin.HandlerFunc {
 			if user == "" {
 				user = ctx.Query("x-syft-from")
 			}
+			user = authService.NormalizeEmail(user)
 
 			// check if the user is a valid email address
 			if !utils.IsValidEmail(user) {
 				api.AbortWithError(ctx, http.StatusUnauthorized, api.CodeInvalidRequest, fmt.Errorf("invalid email"))
 				return
 			}
+
+			if err := authService.CheckEmail(user); err != nil {
+				api.AbortWithError(ctx, http.StatusForbidden, api.CodeAuthEmailNotAllowed, err)
+				return
+			}
+
 			ctx.Set("user", user)
 			ctx.Next()
 		}
@@ -51,7 +60,7 @@ func JWTAuth(authService *auth.AuthService, allowGuest bool) gin.HandlerFunc {
 				user = ctx.Query("x-syft-from")
 			}
 			if strings.EqualFold(user, utils.GuestEmail) || strings.EqualFold(user, utils.GuestEmailLegacy) {
-				ctx.Set("user", user)
+				ctx.Set("user", authService.NormalizeEmail(user))
 				ctx.Next()
 				return
 			}
@@ -84,7 +93,29 @@ func JWTAuth(authService *auth.AuthService, allowGuest bool) gin.HandlerFunc {
 			return
 		}
 
+		// tokens issued before the email rules changed are still valid, so check again
+		if err := authService.CheckEmail(claims.Subject); err != nil {
+			api.AbortWithError(ctx, http.StatusForbidden, api.CodeAuthEmailNotAllowed, err)
+			return
+		}
+
 		ctx.Set("user", claims.Subject)
 		ctx.Next()
 	}
 }
+
+// AdminOnly aborts requests from users that are not admins. Must run after JWTAuth.
+func AdminOnly(authService *auth.AuthService) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		user := ctx.GetString("user")
+		if !authService.IsAdmin(user) {
+			err := fmt.Errorf("admin access required")
+			api.AbortWithError(ctx, http.StatusForbidden, api.CodeAccessDenied, err)
+			if logger := accesslog.GetAccessLogger(ctx); logger != nil {
+				logger.LogAccess(ctx, ctx.Request.URL.Path, accesslog.AccessTypeAdmin, acl.AccessAdmin, false, err.Error())
+			}
+			return
+		}
+		ctx.Next()
+	}
+}
@@ -0,0 +1,30 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/openmined/syftbox/internal/server/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJWTAuthNormalizesUser(t *testing.T) {
+	authSvc, err := auth.NewAuthService(&auth.Config{EmailDotPlusDomains: []string{"gmail.com"}}, nil, nil)
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(JWTAuth(authSvc, false))
+	r.GET("/whoami", func(c *gin.Context) { c.String(http.StatusOK, c.GetString("user")) })
+
+	for _, user := range []string{"alice@gmail.com", "Alice@Gmail.com", "a.lice+sync@gmail.com"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/whoami?user="+url.QueryEscape(user), nil))
+		require.Equal(t, http.StatusOK, w.Code, user)
+		assert.Equal(t, "alice@gmail.com", w.Body.String(), user)
+	}
+}
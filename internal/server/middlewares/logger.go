@@ -2,13 +2,25 @@ package middlewares
 
 import (
 	"log/slog"
+	"net/http"
+	"slices"
+	"sync/atomic"
 
 	"github.com/gin-gonic/gin"
+	"github.com/openmined/syftbox/internal/server/redact"
 	slogGin "github.com/samber/slog-gin"
 )
 
-func Logger() gin.HandlerFunc {
-	httpLogger := slog.Default().WithGroup("http")
+// health checks are polled by load balancers and monitors,
+// only one in healthCheckSampleRate is logged, unless it fails
+const healthCheckSampleRate = 100
+
+var healthCheckPaths = []string{"/healthz", "/readyz"}
+
+// Logger logs the requests, with their paths, queries and headers scrubbed by redactor.
+// Records go through the default handler, JSON in production and tinted text in dev.
+func Logger(redactor *redact.Redactor) gin.HandlerFunc {
+	httpLogger := slog.New(redactor.Handler(slog.Default().Handler())).WithGroup("http")
 
 	paths := []string{
 		"/favicon.ico",
@@ -20,6 +32,7 @@ func Logger() gin.HandlerFunc {
 		paths = append(paths, "/api/v1/datasite/view")
 	}
 
+	var healthChecks atomic.Uint64
 	return slogGin.NewWithConfig(httpLogger, slogGin.Config{
 		DefaultLevel:      slog.LevelInfo,
 		ClientErrorLevel:  slog.LevelWarn,
@@ -30,6 +43,26 @@ func Logger() gin.HandlerFunc {
 		WithSpanID:        true,
 		Filters: []slogGin.Filter{
 			slogGin.IgnorePath(paths...),
+			sampleHealthChecks(&healthChecks),
+			withRequester, // last, only for the requests that are logged
 		},
 	})
 }
+
+// sampleHealthChecks logs one in healthCheckSampleRate successful health checks
+func sampleHealthChecks(count *atomic.Uint64) slogGin.Filter {
+	return func(c *gin.Context) bool {
+		if !slices.Contains(healthCheckPaths, c.Request.URL.Path) || c.Writer.Status() >= http.StatusBadRequest {
+			return true
+		}
+		return count.Add(1)%healthCheckSampleRate == 1
+	}
+}
+
+// withRequester adds the user of the request, and whether it came through a subdomain.
+// It's a filter as filters are run after the handlers, once the user is authenticated.
+func withRequester(c *gin.Context) bool {
+	slogGin.AddCustomAttributes(c, slog.String("user", c.GetString("user")))
+	slogGin.AddCustomAttributes(c, slog.Bool("subdomain", c.GetBool(KeySubdomainRequest)))
+	return true
+}
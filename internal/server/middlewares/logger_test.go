@@ -0,0 +1,126 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/openmined/syftbox/internal/server/redact"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoggerRedacts(t *testing.T) {
+	var buf bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(defaultLogger) })
+
+	redactor, err := redact.New(&redact.Config{
+		QueryParams: redact.DefaultQueryParams,
+		Patterns:    []string{`/private/[^/]+`},
+	})
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Logger(redactor))
+	r.GET("/*path", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	req := httptest.NewRequest(http.MethodGet, "/alice@example.com/private/diagnosis.pdf?signature=s3cr3t&expires=1", nil)
+	req.Header.Set("Referer", "https://example.com/?token=s3cr3t")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	record := buf.String()
+	require.NotEmpty(t, record)
+	assert.NotContains(t, record, "s3cr3t")
+	assert.NotContains(t, record, "diagnosis")
+	assert.Contains(t, record, `"query":"signature=[REDACTED]&expires=1"`)
+	assert.Contains(t, record, `"path":"/alice@example.com[REDACTED]"`)
+}
+
+func TestLoggerRecord(t *testing.T) {
+	var buf bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(defaultLogger) })
+
+	redactor, err := redact.New(&redact.Config{})
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Logger(redactor), func(c *gin.Context) {
+		c.Set("user", "alice@example.com")
+		c.Set(KeySubdomainRequest, true)
+	})
+	r.GET("/api/v1/blob/list", func(c *gin.Context) { c.String(http.StatusOK, "hello") })
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/blob/list", nil))
+
+	var record struct {
+		Level string `json:"level"`
+		HTTP  struct {
+			Request struct {
+				Method string `json:"method"`
+				Path   string `json:"path"`
+			} `json:"request"`
+			Response struct {
+				Status  int      `json:"status"`
+				Latency *float64 `json:"latency"`
+				Length  int      `json:"length"`
+			} `json:"response"`
+			User      string `json:"user"`
+			Subdomain bool   `json:"subdomain"`
+		} `json:"http"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record), buf.String())
+	assert.Equal(t, "INFO", record.Level)
+	assert.Equal(t, http.MethodGet, record.HTTP.Request.Method)
+	assert.Equal(t, "/api/v1/blob/list", record.HTTP.Request.Path)
+	assert.Equal(t, http.StatusOK, record.HTTP.Response.Status)
+	assert.NotNil(t, record.HTTP.Response.Latency)
+	assert.Equal(t, 5, record.HTTP.Response.Length)
+	assert.Equal(t, "alice@example.com", record.HTTP.User)
+	assert.True(t, record.HTTP.Subdomain)
+}
+
+func TestLoggerSamplesHealthChecks(t *testing.T) {
+	var buf bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(defaultLogger) })
+
+	redactor, err := redact.New(&redact.Config{})
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	healthy := true
+	r := gin.New()
+	r.Use(Logger(redactor))
+	r.GET("/healthz", func(c *gin.Context) {
+		if !healthy {
+			c.Status(http.StatusServiceUnavailable)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+
+	for range 2 * healthCheckSampleRate {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	}
+	assert.Equal(t, 2, strings.Count(buf.String(), "\n"))
+
+	// failures are always logged
+	buf.Reset()
+	healthy = false
+	for range 3 {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	}
+	assert.Equal(t, 3, strings.Count(buf.String(), "\n"))
+}
@@ -3,6 +3,7 @@ package middlewares
 import (
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"strings"
 
@@ -21,8 +22,16 @@ var (
 )
 
 type SubdomainRewriteConfig struct {
-	Domain  string // base domain
-	Mapping *datasite.SubdomainMapping
+	Domain   string // base domain
+	Mapping  *datasite.SubdomainMapping
+	Features *datasite.FeatureFlags // optional, hosting is enabled for every datasite if nil
+
+	// LocalHosts are hostnames, IPs or CIDRs passed through without subdomain routing,
+	// in addition to the local dev hosts (e.g., an internal hostname behind a proxy)
+	LocalHosts []string
+
+	// StrictHosts rejects malformed Host headers with 400, instead of routing them as is
+	StrictHosts bool
 }
 
 func SubdomainRewrite(e *gin.Engine, config *SubdomainRewriteConfig) gin.HandlerFunc {
@@ -35,6 +44,10 @@ func SubdomainRewrite(e *gin.Engine, config *SubdomainRewriteConfig) gin.Handler
 	}
 
 	slog.Debug("subdomain routing enabled", "domain", config.Domain)
+	features := config.Features
+	localHosts := newLocalHosts(config.LocalHosts)
+	strictHosts := config.StrictHosts
+	domain := normalizeHost(config.Domain)
 
 	return func(c *gin.Context) {
 		// this is the exit condition for the subdomain rewrite
@@ -52,14 +65,24 @@ func SubdomainRewrite(e *gin.Engine, config *SubdomainRewriteConfig) gin.Handler
 			return
 		}
 
-		host := c.Request.Host
-		if idx := strings.LastIndex(host, ":"); idx != -1 {
-			// Remove port if present
-			host = host[:idx]
+		host, err := parseHost(c.Request.Host)
+		if err != nil {
+			if strictHosts {
+				abortWithMalformedHost(c, err)
+				return
+			}
+			host = normalizeHost(c.Request.Host)
 		}
 
 		// host is root domain
-		if host == config.Domain {
+		if host == domain {
+			// Continue to the next handler
+			c.Next()
+			return
+		}
+
+		// local dev and internal hosts are never routed, a vanity domain can't claim them
+		if isLocalDevRequest(host) || localHosts.contains(host) {
 			// Continue to the next handler
 			c.Next()
 			return
@@ -81,6 +104,11 @@ func SubdomainRewrite(e *gin.Engine, config *SubdomainRewriteConfig) gin.Handler
 				return
 			}
 
+			if features != nil && !features.Enabled(user, datasite.FeaturePublicHosting) {
+				abortWithHostingDisabled(c, host, user)
+				return
+			}
+
 			// rewrite the path
 			originalPath := c.Request.URL.Path
 			newPath := sandboxedRewrite(originalPath, user, baseDir)
@@ -103,13 +131,6 @@ func SubdomainRewrite(e *gin.Engine, config *SubdomainRewriteConfig) gin.Handler
 			return
 		}
 
-		// fallback check for local dev before erroring out
-		if isLocalDevRequest(host) {
-			// Continue to the next handler
-			c.Next()
-			return
-		}
-
 		// not a valid request
 		abortWithInvalidSubdomain(c, host)
 	}
@@ -143,17 +164,78 @@ func sandboxedRewrite(urlPath string, user string, baseDir string) string {
 
 func abortWithInvalidSubdomain(c *gin.Context, host string) {
 	c.Error(fmt.Errorf("invalid subdomain %s", host))
-	api.ServeErrorHTML(c, http.StatusInternalServerError, "500 Internal Server Error", fmt.Sprintf("The subdomain <b><code>%s</code></b> is not available or has not been configured by the datasite owner.", host))
+	api.ServeErrorHTML(c, http.StatusNotFound, "404 Not Found", fmt.Sprintf("The subdomain <b><code>%s</code></b> is not available or has not been configured by the datasite owner.", host))
+}
+
+func abortWithMalformedHost(c *gin.Context, err error) {
+	c.Error(err)
+	api.ServeErrorHTML(c, http.StatusBadRequest, "400 Bad Request", "The request has a malformed <b><code>Host</code></b> header.")
+}
+
+func abortWithHostingDisabled(c *gin.Context, host string, user string) {
+	c.Error(fmt.Errorf("public hosting disabled for datasite %s", user))
+	api.ServeErrorHTML(c, http.StatusForbidden, "403 Forbidden", fmt.Sprintf("The site <b><code>%s</code></b> is not available.", host))
 }
 
 func isLocalDevRequest(host string) bool {
 	return strings.Contains(host, "127.0.0.1") || 
 		strings.Contains(host, "0.0.0.0") || 
 		strings.Contains(host, "localhost") ||
+		host == "::1" ||
 		strings.Contains(host, "syftbox-server") || // Docker container hostname
 		strings.Contains(host, "host.docker.internal") // Docker host machine
 }
 
+// localHosts matches hosts by name, or by network for IP hosts
+type localHosts struct {
+	names []string
+	nets  []*net.IPNet
+}
+
+func newLocalHosts(hosts []string) *localHosts {
+	l := &localHosts{}
+	for _, host := range hosts {
+		host = strings.TrimSpace(host)
+		if host == "" {
+			continue
+		}
+
+		if _, ipNet, err := net.ParseCIDR(host); err == nil {
+			l.nets = append(l.nets, ipNet)
+		} else if ip := net.ParseIP(host); ip != nil {
+			bits := len(ip) * 8
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 32
+			}
+			l.nets = append(l.nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+		} else if strings.Contains(host, "/") {
+			slog.Warn("invalid local host cidr", "host", host)
+		} else {
+			l.names = append(l.names, strings.ToLower(host))
+		}
+	}
+	return l
+}
+
+func (l *localHosts) contains(host string) bool {
+	if ip := net.ParseIP(strings.Trim(host, "[]")); ip != nil {
+		for _, ipNet := range l.nets {
+			if ipNet.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+
+	host = strings.ToLower(host)
+	for _, name := range l.names {
+		if host == name {
+			return true
+		}
+	}
+	return false
+}
+
 func IsSubdomainRequest(c *gin.Context) bool {
 	return c.GetBool(KeySubdomainRequest)
 }
@@ -3,11 +3,14 @@ package middlewares
 import (
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"testing"
 
 	"github.com/gin-gonic/gin"
+	"github.com/openmined/syftbox/internal/db"
 	"github.com/openmined/syftbox/internal/server/datasite"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestSubdomainRewrite(t *testing.T) {
@@ -152,7 +155,7 @@ func TestSubdomainRewrite(t *testing.T) {
 			mainDomain:     "syftbox.net",
 			vanityDomains:  map[string]struct{ email, path string }{},
 			expectedPath:   "/index.html",
-			expectedStatus: http.StatusInternalServerError, // Should return error for unknown subdomain
+			expectedStatus: http.StatusNotFound, // Should return not found for unknown subdomain
 			isSubdomain:    false,
 		},
 		{
@@ -162,7 +165,7 @@ func TestSubdomainRewrite(t *testing.T) {
 			mainDomain:     "syftbox.net",
 			vanityDomains:  map[string]struct{ email, path string }{},
 			expectedPath:   "/index.html",
-			expectedStatus: http.StatusInternalServerError, // Should return error for unknown vanity domain
+			expectedStatus: http.StatusNotFound, // Should return not found for unknown vanity domain
 			isSubdomain:    false,
 		},
 		{
@@ -172,7 +175,7 @@ func TestSubdomainRewrite(t *testing.T) {
 			mainDomain:     "syftbox.net",
 			vanityDomains:  map[string]struct{ email, path string }{},
 			expectedPath:   "/index.html",
-			expectedStatus: http.StatusInternalServerError, // Should return error for unknown domain
+			expectedStatus: http.StatusNotFound, // Should return not found for unknown domain
 			isSubdomain:    false,
 		},
 		// Port handling
@@ -323,6 +326,47 @@ func TestSubdomainContextSetting(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 }
 
+func TestSubdomainHostingDisabled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	sqliteDb, err := db.NewSqliteDB(db.WithPath(filepath.Join(t.TempDir(), "test.db")), db.WithMaxOpenConns(1))
+	require.NoError(t, err)
+	defer sqliteDb.Close()
+
+	features, err := datasite.NewFeatureFlags(sqliteDb, &datasite.FeaturesConfig{PublicHosting: true})
+	require.NoError(t, err)
+
+	disabled := false
+	require.NoError(t, features.Set("alice@example.com", datasite.FeaturePublicHosting, &disabled))
+
+	subdomainMapping := datasite.NewSubdomainMapping()
+	subdomainMapping.AddVanityDomain("alice.blog", "alice@example.com", "/blog")
+	subdomainMapping.AddVanityDomain("bob.blog", "bob@example.com", "/blog")
+
+	router := gin.New()
+	router.Use(SubdomainRewrite(router, &SubdomainRewriteConfig{
+		Domain:   "syftbox.net",
+		Mapping:  subdomainMapping,
+		Features: features,
+	}))
+	router.GET("/*path", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	// hosting is disabled only for alice
+	for host, expected := range map[string]int{
+		"alice.blog": http.StatusForbidden,
+		"bob.blog":   http.StatusOK,
+	} {
+		req := httptest.NewRequest("GET", "/index.html", nil)
+		req.Host = host
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, expected, w.Code, host)
+	}
+}
+
 func TestPathRewritingEdgeCases(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
@@ -555,3 +599,106 @@ func TestSandboxedRewrite(t *testing.T) {
 		})
 	}
 }
+
+func TestSubdomainRewriteWildcard(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mapping := datasite.NewSubdomainMapping()
+	require.NoError(t, mapping.AddWildcardDomain("*.apps.alice.dev", "alice@example.com", "/apps/{label}"))
+
+	router := gin.New()
+	router.Use(SubdomainRewrite(router, &SubdomainRewriteConfig{
+		Domain:  "syftbox.net",
+		Mapping: mapping,
+	}))
+	router.GET("/*path", func(c *gin.Context) {
+		c.String(http.StatusOK, c.Request.URL.Path)
+	})
+
+	for host, expected := range map[string]string{
+		"foo.apps.alice.dev":      "/datasites/alice@example.com/apps/foo/index.html",
+		"bar.apps.alice.dev:8080": "/datasites/alice@example.com/apps/bar/index.html",
+	} {
+		req := httptest.NewRequest("GET", "/index.html", nil)
+		req.Host = host
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code, host)
+		assert.Equal(t, expected, w.Body.String(), host)
+	}
+}
+
+func TestSubdomainRewriteLocalHosts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(SubdomainRewrite(router, &SubdomainRewriteConfig{
+		Domain:     "syftbox.net",
+		Mapping:    datasite.NewSubdomainMapping(),
+		LocalHosts: []string{"syftbox.internal", "10.0.0.0/8", "fd00::1", "not/a/cidr"},
+	}))
+	router.GET("/*path", func(c *gin.Context) {
+		assert.False(t, IsSubdomainRequest(c))
+		c.String(http.StatusOK, c.Request.URL.Path)
+	})
+
+	tests := []struct {
+		host           string
+		expectedStatus int
+	}{
+		{"syftbox.internal", http.StatusOK},
+		{"SYFTBOX.internal:8080", http.StatusOK},
+		{"10.1.2.3", http.StatusOK},
+		{"10.1.2.3:8080", http.StatusOK},
+		{"[fd00::1]:8080", http.StatusOK},
+		{"localhost:8080", http.StatusOK},
+		// names match exactly, ips only within the networks
+		{"api.syftbox.internal", http.StatusNotFound},
+		{"11.1.2.3", http.StatusNotFound},
+		{"[fd00::2]:8080", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/v1/status", nil)
+			req.Host = tt.host
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus == http.StatusOK {
+				assert.Equal(t, "/api/v1/status", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestSubdomainRewriteLocalHostsNotClaimed(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mapping := datasite.NewSubdomainMapping()
+	mapping.AddVanityDomain("127.0.0.1", "mallory@example.com", "/public")
+	mapping.AddVanityDomain("syftbox.internal", "mallory@example.com", "/public")
+
+	router := gin.New()
+	router.Use(SubdomainRewrite(router, &SubdomainRewriteConfig{
+		Domain:     "syftbox.net",
+		Mapping:    mapping,
+		LocalHosts: []string{"syftbox.internal"},
+	}))
+	router.GET("/*path", func(c *gin.Context) {
+		c.String(http.StatusOK, c.Request.URL.Path)
+	})
+
+	// local hosts are passed through before the vanity domains are looked up
+	for _, host := range []string{"127.0.0.1:8080", "syftbox.internal"} {
+		req := httptest.NewRequest("GET", "/index.html", nil)
+		req.Host = host
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code, host)
+		assert.Equal(t, "/index.html", w.Body.String(), host)
+	}
+}
@@ -0,0 +1,164 @@
+package redact
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// Redacted replaces the scrubbed values
+const Redacted = "[REDACTED]"
+
+// DefaultQueryParams are the query params that carry credentials, e.g. the signature of a presigned url
+var DefaultQueryParams = []string{
+	"token",
+	"access_token",
+	"refresh_token",
+	"code",
+	"signature",
+	"X-Amz-Signature",
+	"X-Amz-Credential",
+	"X-Amz-Security-Token",
+}
+
+// Config holds the rules to scrub from the request logs and the access logs
+type Config struct {
+	QueryParams []string `mapstructure:"query_params"` // query params whose values are redacted, matched without the case
+	Patterns    []string `mapstructure:"patterns"`     // regular expressions whose matches are redacted
+}
+
+func (c Config) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Any("query_params", c.QueryParams),
+		slog.Int("patterns", len(c.Patterns)), // the patterns may describe what they protect
+	)
+}
+
+func (c *Config) Validate() error {
+	_, err := New(c)
+	return err
+}
+
+// Redactor scrubs sensitive substrings from log values
+type Redactor struct {
+	rules []*regexp.Regexp
+	query *regexp.Regexp // matches name=value pairs of the query params, keeping the name
+}
+
+// New compiles the rules of cfg. A nil config redacts nothing
+func New(cfg *Config) (*Redactor, error) {
+	r := &Redactor{}
+	if cfg == nil {
+		return r, nil
+	}
+
+	for _, pattern := range cfg.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redact pattern %q: %w", pattern, err)
+		}
+		r.rules = append(r.rules, re)
+	}
+
+	names := make([]string, 0, len(cfg.QueryParams))
+	for _, name := range cfg.QueryParams {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, regexp.QuoteMeta(name))
+		}
+	}
+	if len(names) > 0 {
+		r.query = regexp.MustCompile(`(?i)((?:^|[?&;])(?:` + strings.Join(names, "|") + `)=)[^&;#\s"]*`)
+	}
+
+	return r, nil
+}
+
+// String returns s with the query param values and pattern matches redacted
+func (r *Redactor) String(s string) string {
+	if r == nil || s == "" {
+		return s
+	}
+	if r.query != nil {
+		s = r.query.ReplaceAllString(s, "${1}"+Redacted)
+	}
+	for _, re := range r.rules {
+		s = re.ReplaceAllLiteralString(s, Redacted)
+	}
+	return s
+}
+
+// Handler wraps next so that the message and the attributes of its records are redacted
+func (r *Redactor) Handler(next slog.Handler) slog.Handler {
+	return &handler{next: next, r: r}
+}
+
+func (r *Redactor) attr(a slog.Attr) slog.Attr {
+	a.Value = r.value(a.Value.Resolve())
+	return a
+}
+
+func (r *Redactor) value(v slog.Value) slog.Value {
+	switch v.Kind() {
+	case slog.KindString:
+		return slog.StringValue(r.String(v.String()))
+	case slog.KindGroup:
+		group := v.Group()
+		attrs := make([]slog.Attr, len(group))
+		for i, a := range group {
+			attrs[i] = r.attr(a)
+		}
+		return slog.GroupValue(attrs...)
+	case slog.KindAny:
+		switch value := v.Any().(type) {
+		case []string: // e.g. the request headers
+			redacted := make([]string, len(value))
+			for i, s := range value {
+				redacted[i] = r.String(s)
+			}
+			return slog.AnyValue(redacted)
+		case map[string]string: // e.g. the route params
+			redacted := make(map[string]string, len(value))
+			for k, s := range value {
+				redacted[k] = r.String(s)
+			}
+			return slog.AnyValue(redacted)
+		case error:
+			if msg := value.Error(); r.String(msg) != msg {
+				return slog.StringValue(r.String(msg))
+			}
+		}
+	}
+	return v
+}
+
+type handler struct {
+	next slog.Handler
+	r    *Redactor
+}
+
+func (h *handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *handler) Handle(ctx context.Context, record slog.Record) error {
+	redacted := slog.NewRecord(record.Time, record.Level, h.r.String(record.Message), record.PC)
+	record.Attrs(func(a slog.Attr) bool {
+		redacted.AddAttrs(h.r.attr(a))
+		return true
+	})
+	return h.next.Handle(ctx, redacted)
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		redacted[i] = h.r.attr(a)
+	}
+	return &handler{next: h.next.WithAttrs(redacted), r: h.r}
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	return &handler{next: h.next.WithGroup(name), r: h.r}
+}
@@ -0,0 +1,77 @@
+package redact
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactString(t *testing.T) {
+	r, err := New(&Config{
+		QueryParams: DefaultQueryParams,
+		Patterns:    []string{`/private/[^/?]+`},
+	})
+	require.NoError(t, err)
+
+	for in, want := range map[string]string{
+		"signature=abc123&expires=1700000000":                "signature=[REDACTED]&expires=1700000000",
+		"https://example.com/blob?X-Amz-Signature=f00&x=1":   "https://example.com/blob?X-Amz-Signature=[REDACTED]&x=1",
+		"/auth/callback?CODE=123456":                         "/auth/callback?CODE=[REDACTED]",
+		"codec=gzip&etag=abc":                                "codec=gzip&etag=abc",
+		"alice@example.com/private/diagnosis.pdf":            "alice@example.com[REDACTED]",
+		"alice@example.com/public/private/x":                 "alice@example.com/public[REDACTED]",
+		"alice@example.com/public/report.csv?token=t&size=1": "alice@example.com/public/report.csv?token=[REDACTED]&size=1",
+	} {
+		assert.Equal(t, want, r.String(in), in)
+	}
+
+	// no rules, or no redactor at all, leave the values alone
+	empty, err := New(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "token=abc", empty.String("token=abc"))
+	assert.Equal(t, "token=abc", (*Redactor)(nil).String("token=abc"))
+}
+
+func TestRedactInvalidPattern(t *testing.T) {
+	cfg := &Config{Patterns: []string{`(unclosed`}}
+	assert.Error(t, cfg.Validate())
+}
+
+func TestRedactHandler(t *testing.T) {
+	r, err := New(&Config{
+		QueryParams: []string{"token"},
+		Patterns:    []string{`sk-[a-z0-9]+`},
+	})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	logger := slog.New(r.Handler(slog.NewJSONHandler(&buf, nil))).With("key", "sk-abc123").WithGroup("http")
+	logger.Info("request sk-def456",
+		slog.Group("request",
+			slog.String("query", "token=secret&limit=10"),
+			slog.Any("header", map[string]string{"X-Api-Key": "sk-ghi789"}),
+			slog.Any("referer", []string{"https://example.com/?token=secret"}),
+		),
+		slog.Any("error", errors.New("fetch https://blob/?token=secret: timeout")),
+		slog.Int("status", 200),
+	)
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.NotContains(t, buf.String(), "secret")
+	assert.NotContains(t, buf.String(), "sk-")
+	assert.Equal(t, "request [REDACTED]", record["msg"])
+	assert.Equal(t, "[REDACTED]", record["key"])
+
+	httpGroup := record["http"].(map[string]any)
+	request := httpGroup["request"].(map[string]any)
+	assert.Equal(t, "token=[REDACTED]&limit=10", request["query"])
+	assert.Equal(t, map[string]any{"X-Api-Key": "[REDACTED]"}, request["header"])
+	assert.Equal(t, "fetch https://blob/?token=[REDACTED] timeout", httpGroup["error"])
+	assert.Equal(t, float64(200), httpGroup["status"])
+}
@@ -10,8 +10,11 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"github.com/openmined/syftbox/internal/server/accesslog"
+	blobsvc "github.com/openmined/syftbox/internal/server/blob"
 	"github.com/openmined/syftbox/internal/server/handlers/acl"
+	"github.com/openmined/syftbox/internal/server/handlers/admin"
 	"github.com/openmined/syftbox/internal/server/handlers/api"
+	"github.com/openmined/syftbox/internal/server/handlers/archive"
 	"github.com/openmined/syftbox/internal/server/handlers/auth"
 	"github.com/openmined/syftbox/internal/server/handlers/blob"
 	"github.com/openmined/syftbox/internal/server/handlers/datasite"
@@ -19,6 +22,7 @@ import (
 	"github.com/openmined/syftbox/internal/server/handlers/explorer"
 	"github.com/openmined/syftbox/internal/server/handlers/install"
 	"github.com/openmined/syftbox/internal/server/handlers/send"
+	"github.com/openmined/syftbox/internal/server/handlers/snapshot"
 	"github.com/openmined/syftbox/internal/server/handlers/ws"
 	"github.com/openmined/syftbox/internal/server/middlewares"
 	"github.com/openmined/syftbox/internal/version"
@@ -34,7 +38,7 @@ func SetupRoutes(cfg *Config, svc *Services, hub *ws.WebsocketHub) http.Handler
 	// --------------------------- middlewares ---------------------------
 
 	r.Use(gin.Recovery())
-	r.Use(middlewares.Logger())
+	r.Use(middlewares.Logger(svc.Redact))
 	r.Use(middlewares.CORS())
 	r.Use(middlewares.GZIP())
 	if cfg.HTTP.HTTPSEnabled() {
@@ -49,8 +53,12 @@ func SetupRoutes(cfg *Config, svc *Services, hub *ws.WebsocketHub) http.Handler
 
 	if cfg.HTTP.Domain != "" {
 		r.Use(middlewares.SubdomainRewrite(r, &middlewares.SubdomainRewriteConfig{
-			Domain:  cfg.HTTP.Domain,
-			Mapping: svc.Datasite.GetSubdomainMapping(),
+			Domain:   cfg.HTTP.Domain,
+			Mapping:  svc.Datasite.GetSubdomainMapping(),
+			Features: svc.Features,
+
+			LocalHosts:  cfg.HTTP.LocalHosts,
+			StrictHosts: cfg.HTTP.StrictHosts,
 		}))
 		// Add security headers for subdomain requests
 		r.Use(middlewares.SubdomainSecurityHeaders())
@@ -62,13 +70,17 @@ func SetupRoutes(cfg *Config, svc *Services, hub *ws.WebsocketHub) http.Handler
 
 	// --------------------------- handlers ---------------------------
 
-	blobH := blob.New(svc.Blob, svc.ACL)
+	blobH := blob.New(svc.Blob, svc.ACL, svc.Datasite, blob.NewPresignCache(cfg.Blob.PresignCacheTTL), cfg.Blob.MaxPresignKeys)
 	dsH := datasite.New(svc.Datasite)
+	archiveH := archive.New(svc.Blob, svc.Auth.IsAdmin)
+	snapshotH := snapshot.New(svc.Blob, svc.ACL, svc.Datasite, hub)
 	explorerH := explorer.New(svc.Blob, svc.ACL)
 	authH := auth.New(svc.Auth)
-	aclH := acl.NewACLHandler(svc.ACL)
-	sendH := send.New(send.NewWSMsgDispatcher(hub), send.NewBlobMsgStore(svc.Blob), svc.ACL)
+	aclH := acl.NewACLHandler(svc.ACL, svc.Blob, svc.Datasite, svc.Blob.KeyRules())
+	sendH := send.New(send.NewWSMsgDispatcher(hub), send.NewBlobMsgStore(svc.Blob), svc.ACL, svc.Features)
 	didH := did.NewDIDHandler(svc.Blob)
+	healthH := newHealthChecker(svc, hub)
+	adminH := admin.New(svc.Features, svc.AccessLog, svc.Blob)
 
 	// --------------------------- routes ---------------------------
 
@@ -77,12 +89,21 @@ func SetupRoutes(cfg *Config, svc *Services, hub *ws.WebsocketHub) http.Handler
 	} else {
 		r.GET("/", IndexHandler)
 	}
-	r.GET("/healthz", HealthHandler)
+	// the verbose health checks every subsystem, only the admins get it
+	r.GET("/healthz", ifVerbose(middlewares.JWTAuth(svc.Auth, false)), ifVerbose(middlewares.AdminOnly(svc.Auth)), healthH.Handler)
+	r.GET("/readyz", ReadyHandler)
 	r.GET("/install.sh", install.ServeSH)
 	r.GET("/install.ps1", install.ServePS1)
 	r.GET("/datasites/*filepath", explorerH.Handler)
 	r.StaticFS("/releases", http.Dir("./releases"))
 	r.GET("/users/:user/did.json", didH.GetDID)
+	r.GET("/api/v1/version", VersionHandler) // before auth, clients check it first
+
+	// presigned urls of the filesystem blob backend, authorized by their signature
+	if presignH := svc.Blob.PresignHandler(); presignH != nil {
+		r.GET(blobsvc.FSPresignPath+"*key", gin.WrapH(presignH))
+		r.PUT(blobsvc.FSPresignPath+"*key", gin.WrapH(presignH))
+	}
 
 	auth := r.Group("/auth")
 	auth.Use(middlewares.RateLimiter("10-M")) // 10 req/min
@@ -104,20 +125,45 @@ func SetupRoutes(cfg *Config, svc *Services, hub *ws.WebsocketHub) http.Handler
 		v1.PUT("/blob/upload", blobH.Upload)
 		v1.PUT("/blob/upload/acl", blobH.UploadACL)
 		v1.POST("/blob/upload/presigned", blobH.UploadPresigned)
+		v1.POST("/blob/upload/batch", blobH.UploadBatch)
 		v1.POST("/blob/upload/multipart", blobH.UploadMultipart)
 		v1.POST("/blob/upload/complete", blobH.UploadComplete)
 		v1.POST("/blob/download", blobH.DownloadObjectsPresigned)
+		v1.POST("/blob/download/batch", blobH.DownloadBatch)
 		v1.POST("/blob/delete", blobH.DeleteObjects)
 
 		// datasite
 		v1.GET("/datasite/view", dsH.GetView)
+		v1.GET("/datasite/archive", archiveH.Download)
+		v1.HEAD("/datasite/archive", archiveH.Download)
+		v1.GET("/datasite/snapshots", snapshotH.List)
+		v1.POST("/datasite/snapshots", snapshotH.Create)
+		v1.POST("/datasite/snapshots/restore", snapshotH.Restore)
+		v1.POST("/datasite/snapshots/delete", snapshotH.Delete)
 
 		v1.PUT("/acl", blobH.UploadACL)
 		v1.GET("/acl/check", aclH.CheckAccess)
+		v1.POST("/acl/batch", aclH.ApplyBatch)
 
 		// websocket events
 		v1.GET("/events", hub.WebsocketHandler)
 
+		// token revocation
+		v1.POST("/auth/revoke", authH.Revoke)
+		v1.POST("/auth/logout-all", authH.LogoutAll)
+
+	}
+
+	// admin api for operators
+	adminG := r.Group("/api/v1/admin")
+	adminG.Use(middlewares.JWTAuth(svc.Auth, false), middlewares.AdminOnly(svc.Auth))
+	{
+		adminG.GET("/datasites/:datasite/features", adminH.GetFeatures)
+		adminG.PATCH("/datasites/:datasite/features", adminH.UpdateFeatures)
+		adminG.GET("/datasites/:datasite/presigns", adminH.ListPresigns)
+		adminG.POST("/datasites/:datasite/presigns/revoke", adminH.RevokePresigns)
+		adminG.GET("/audit/export", adminH.ExportAuditLog)
+		adminG.GET("/stats", StatsHandler(svc.Datasite, svc.Blob, hub))
 	}
 
 	// rpc group with guest access
@@ -197,7 +197,7 @@ func TestEndToEndSubdomainRouting(t *testing.T) {
 			host:               "unknown.syftbox.net",
 			path:               "/test.txt",
 			vanityDomains:      map[string]struct{ email, path string }{},
-			expectedStatusCode: http.StatusInternalServerError, // Changed from 404 to 500 for unknown subdomain
+			expectedStatusCode: http.StatusNotFound,
 			checkHeaders:       false,
 		},
 		{
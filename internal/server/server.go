@@ -3,19 +3,21 @@ package server
 import (
 	"bytes"
 	"context"
-	"crypto/tls"
 	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"path/filepath"
 	"runtime"
+	"slices"
+	"sync"
 	"time"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/openmined/syftbox/internal/db"
 	"github.com/openmined/syftbox/internal/server/acl"
 	"github.com/openmined/syftbox/internal/server/blob"
+	"github.com/openmined/syftbox/internal/server/datasite"
 	"github.com/openmined/syftbox/internal/server/handlers/ws"
 	"github.com/openmined/syftbox/internal/syftmsg"
 	"golang.org/x/sync/errgroup"
@@ -32,8 +34,13 @@ type Server struct {
 	db     *sqlx.DB
 	hub    *ws.WebsocketHub
 	svc    *Services
+
+	reloadMu sync.Mutex
 }
 
+// ErrRestartRequired is returned when a reload changes a setting that can only be applied on restart
+var ErrRestartRequired = errors.New("restart required")
+
 // New creates a new server instance with the provided configuration
 func New(config *Config) (*Server, error) {
 	dbPath := filepath.Join(config.DataDir, "state.db")
@@ -50,7 +57,14 @@ func New(config *Config) (*Server, error) {
 		return nil, fmt.Errorf("initialize services: %w", err)
 	}
 
-	hub := ws.NewHub()
+	tlsConfig, err := config.HTTP.TLSConfig()
+	if err != nil {
+		return nil, fmt.Errorf("tls config: %w", err)
+	}
+
+	hub := ws.NewHub(config.HTTP.NotifyCoalesceWindow,
+		ws.WithBroadcastFanout(config.HTTP.BroadcastMaxFanout, config.HTTP.BroadcastInterval),
+	)
 	httpHandler := SetupRoutes(config, services, hub)
 
 	return &Server{
@@ -68,9 +82,7 @@ func New(config *Config) (*Server, error) {
 			ReadHeaderTimeout: 10 * time.Second,
 			// Connection control
 			MaxHeaderBytes: 1 << 20, // 1 MB,
-			TLSConfig: &tls.Config{
-				MinVersion: tls.VersionTLS12, // TLS 1.2 or higher
-			},
+			TLSConfig:      tlsConfig,
 		},
 	}, nil
 }
@@ -170,6 +182,38 @@ func (s *Server) Stop(ctx context.Context) error {
 	return nil
 }
 
+// Reload applies the hot-reloadable settings of config without restarting the server:
+// the email settings, and the auth settings that can change while running (see auth.AuthService.SetConfig).
+// Changes to the bind address or the blob endpoint are rejected, and nothing is applied.
+// The other settings are only applied on restart.
+func (s *Server) Reload(config *Config) error {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	if config.HTTP.Addr != s.config.HTTP.Addr {
+		return fmt.Errorf("%w: http addr changed from %q to %q", ErrRestartRequired, s.config.HTTP.Addr, config.HTTP.Addr)
+	}
+	if config.HTTP.TLSMinVersion != s.config.HTTP.TLSMinVersion || !slices.Equal(config.HTTP.TLSCipherSuites, s.config.HTTP.TLSCipherSuites) {
+		return fmt.Errorf("%w: tls config changed", ErrRestartRequired)
+	}
+	if config.Blob.Endpoint != s.config.Blob.Endpoint {
+		return fmt.Errorf("%w: blob endpoint changed from %q to %q", ErrRestartRequired, s.config.Blob.Endpoint, config.Blob.Endpoint)
+	}
+
+	authConfig := config.Auth
+	if err := s.svc.Auth.SetConfig(&authConfig); err != nil {
+		return fmt.Errorf("auth config: %w", err)
+	}
+	emailConfig := config.Email
+	s.svc.Email.SetConfig(&emailConfig)
+
+	slog.Info("server config reloaded",
+		"email", emailConfig,
+		"auth", authConfig,
+	)
+	return nil
+}
+
 func (s *Server) runHttpServer() error {
 	if s.config.HTTP.HTTPSEnabled() {
 		slog.Info("server start https",
@@ -211,6 +255,12 @@ func (s *Server) handleFileWrite(msg *ws.ClientMessage) {
 	// get the client info
 	from := msg.ClientInfo.User
 
+	if err := s.svc.Blob.KeyRules().Validate(data.Path); err != nil {
+		slog.Error("wsmsg handler invalid key", "id", msg.Message.Id, "from", from, "path", data.Path, "error", err)
+		s.hub.SendMessage(msg.ConnID, syftmsg.NewError(http.StatusBadRequest, data.Path, err.Error()))
+		return
+	}
+
 	msgGroup := slog.Group("wsmsg", "id", msg.Message.Id, "type", msg.Message.Type, "connId", msg.ConnID, "from", from, "path", data.Path, "size", data.Length)
 
 	// check if the SENDER has permission to write to the file
@@ -223,9 +273,27 @@ func (s *Server) handleFileWrite(msg *ws.ClientMessage) {
 		return
 	}
 
+	// like the http uploads, a write can only create a new datasite under the datasite cap, and must fit in its quota
+	owner := datasite.GetOwner(data.Path)
+	if err := s.svc.Datasite.Admit(owner); err != nil {
+		slog.Error("wsmsg handler datasite rejected", msgGroup, "error", err)
+		s.hub.SendMessage(msg.ConnID, syftmsg.NewError(http.StatusForbidden, data.Path, err.Error()))
+		return
+	}
+
+	releaseSize, err := s.svc.Datasite.AdmitSize(data.Path, data.Length)
+	if err != nil {
+		s.svc.Datasite.Release(owner)
+		slog.Error("wsmsg handler quota exceeded", msgGroup, "error", err)
+		s.hub.SendMessage(msg.ConnID, syftmsg.NewError(http.StatusForbidden, data.Path, err.Error()))
+		return
+	}
+
 	slog.Info("wsmsg handler recieved", msgGroup)
 
 	go func() {
+		defer s.svc.Datasite.Release(owner)
+		defer releaseSize()
 		if _, err := s.svc.Blob.Backend().PutObject(context.Background(), &blob.PutObjectParams{
 			Key:  data.Path,
 			ETag: msg.Message.Id,
@@ -236,8 +304,9 @@ func (s *Server) handleFileWrite(msg *ws.ClientMessage) {
 		}
 	}()
 
-	// broadcast the message to all clients except the sender
-	s.hub.BroadcastFiltered(msg.Message, func(info *ws.ClientInfo) bool {
+	// broadcast the message to all clients except the sender.
+	// rapid writes are coalesced, recipients get the latest one of every path
+	s.hub.BroadcastCoalesced(data.Path, msg.Message, func(info *ws.ClientInfo) bool {
 		to := info.User
 
 		if to == from {
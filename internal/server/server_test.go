@@ -0,0 +1,76 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/openmined/syftbox/internal/server/auth"
+	"github.com/openmined/syftbox/internal/server/blob"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newReloadTestConfig(t *testing.T, dataDir string) *Config {
+	t.Helper()
+	cfg := &Config{
+		HTTP:     HTTPConfig{Addr: "localhost:8080"},
+		Blob:     blob.S3Config{Backend: blob.BackendFilesystem},
+		DataDir:  dataDir,
+		LogDir:   t.TempDir(),
+		LogLevel: "info",
+	}
+	require.NoError(t, cfg.Validate())
+	return cfg
+}
+
+func newReloadTestServer(t *testing.T) (*Server, string) {
+	t.Helper()
+	dataDir := t.TempDir()
+	s, err := New(newReloadTestConfig(t, dataDir))
+	require.NoError(t, err)
+	t.Cleanup(func() { s.Stop(context.Background()) })
+	return s, dataDir
+}
+
+func TestServerReload(t *testing.T) {
+	s, dataDir := newReloadTestServer(t)
+	require.False(t, s.svc.Email.IsEnabled())
+
+	cfg := newReloadTestConfig(t, dataDir)
+	cfg.Email.Enabled = true
+	cfg.Email.SendgridAPIKey = "sendgrid-api-key"
+	cfg.Auth.DeniedEmails = []string{"*@blocked.org"}
+	cfg.Auth.Groups = map[string][]string{"lab": {"*@lab.org"}}
+	cfg.Auth.UserClaims = []auth.UserClaims{{Email: "*@lab.org", Claims: map[string]string{"org_verified": "true"}}}
+	require.NoError(t, s.Reload(cfg))
+
+	assert.True(t, s.svc.Email.IsEnabled())
+	assert.Error(t, s.svc.Auth.CheckEmail("user@blocked.org"))
+	assert.Equal(t, []string{"lab"}, s.svc.Auth.Groups("user@lab.org"))
+	assert.Equal(t, map[string]string{"org_verified": "true"}, s.svc.Auth.Claims("user@lab.org"))
+}
+
+func TestServerReloadRestartRequired(t *testing.T) {
+	s, dataDir := newReloadTestServer(t)
+
+	cfg := newReloadTestConfig(t, dataDir)
+	cfg.HTTP.Addr = "localhost:9090"
+	cfg.Email.Enabled = true
+	cfg.Email.SendgridAPIKey = "sendgrid-api-key"
+	assert.ErrorIs(t, s.Reload(cfg), ErrRestartRequired)
+
+	cfg = newReloadTestConfig(t, dataDir)
+	cfg.Blob.Endpoint = "http://other-endpoint"
+	cfg.Email.Enabled = true
+	cfg.Email.SendgridAPIKey = "sendgrid-api-key"
+	assert.ErrorIs(t, s.Reload(cfg), ErrRestartRequired)
+
+	cfg = newReloadTestConfig(t, dataDir)
+	cfg.HTTP.TLSMinVersion = "1.3"
+	cfg.Email.Enabled = true
+	cfg.Email.SendgridAPIKey = "sendgrid-api-key"
+	assert.ErrorIs(t, s.Reload(cfg), ErrRestartRequired)
+
+	// nothing is applied
+	assert.False(t, s.svc.Email.IsEnabled())
+}
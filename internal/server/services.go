@@ -7,21 +7,25 @@ import (
 	"path/filepath"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/openmined/syftbox/internal/aclspec"
 	"github.com/openmined/syftbox/internal/server/accesslog"
 	"github.com/openmined/syftbox/internal/server/acl"
 	"github.com/openmined/syftbox/internal/server/auth"
 	"github.com/openmined/syftbox/internal/server/blob"
 	"github.com/openmined/syftbox/internal/server/datasite"
 	"github.com/openmined/syftbox/internal/server/email"
+	"github.com/openmined/syftbox/internal/server/redact"
 )
 
 type Services struct {
 	Blob      *blob.BlobService
 	ACL       *acl.ACLService
 	Datasite  *datasite.DatasiteService
+	Features  *datasite.FeatureFlags
 	Auth      *auth.AuthService
 	Email     *email.EmailService
 	AccessLog *accesslog.AccessLogger
+	Redact    *redact.Redactor
 }
 
 func NewServices(config *Config, db *sqlx.DB) (*Services, error) {
@@ -32,15 +36,43 @@ func NewServices(config *Config, db *sqlx.DB) (*Services, error) {
 		return nil, err
 	}
 
-	aclSvc := acl.NewACLService(blobSvc)
+	revocations, err := auth.NewRevocations(db)
+	if err != nil {
+		return nil, err
+	}
 
-	datasiteSvc := datasite.NewDatasiteService(blobSvc, aclSvc, config.HTTP.Domain)
+	authSvc, err := auth.NewAuthService(&config.Auth, emailSvc, revocations)
+	if err != nil {
+		return nil, err
+	}
 
-	authSvc := auth.NewAuthService(&config.Auth, emailSvc)
+	aclSvc := acl.NewACLService(blobSvc,
+		acl.WithIdleTimeout(config.Datasite.IdleTimeout),
+		acl.WithRuleSetLimits(&aclspec.RuleSetLimits{
+			MaxSize:  config.Datasite.ACLMaxSize,
+			MaxRules: config.Datasite.ACLMaxRules,
+		}),
+		acl.WithGroups(authSvc.Groups),
+		acl.WithClaims(authSvc.Claims),
+		acl.WithEmailNormalizer(authSvc.NormalizeEmail),
+	)
+
+	datasiteSvc := datasite.NewDatasiteService(blobSvc, aclSvc, config.HTTP.Domain, &config.Datasite)
+	datasiteSvc.SetEmailNormalizer(authSvc.NormalizeEmail)
+
+	features, err := datasite.NewFeatureFlags(db, &config.Datasite.Features)
+	if err != nil {
+		return nil, err
+	}
+
+	redactor, err := redact.New(&config.Redact)
+	if err != nil {
+		return nil, err
+	}
 
 	// Create access logger
 	accessLogDir := filepath.Join(config.LogDir, "access")
-	accessLogger, err := accesslog.New(accessLogDir, slog.Default())
+	accessLogger, err := accesslog.New(accessLogDir, slog.Default(), accesslog.WithRedactor(redactor))
 	if err != nil {
 		return nil, fmt.Errorf("create access logger: %w", err)
 	}
@@ -49,9 +81,11 @@ func NewServices(config *Config, db *sqlx.DB) (*Services, error) {
 		Blob:      blobSvc,
 		ACL:       aclSvc,
 		Datasite:  datasiteSvc,
+		Features:  features,
 		Auth:      authSvc,
 		Email:     emailSvc,
 		AccessLog: accessLogger,
+		Redact:    redactor,
 	}, nil
 }
 
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"crypto/tls"
+	"fmt"
+	"strings"
+)
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// TLSConfig is the tls config of the https listener. An empty min version is TLS 1.2,
+// and no cipher suites keep the go defaults
+func (c *HTTPConfig) TLSConfig() (*tls.Config, error) {
+	minVersion, err := parseTLSVersion(c.TLSMinVersion)
+	if err != nil {
+		return nil, err
+	}
+	suites, err := parseCipherSuites(c.TLSCipherSuites)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		MinVersion:   minVersion,
+		CipherSuites: suites,
+	}, nil
+}
+
+func parseTLSVersion(value string) (uint16, error) {
+	if value == "" {
+		return tls.VersionTLS12, nil
+	}
+	version, ok := tlsVersions[strings.TrimPrefix(value, "TLS")]
+	if !ok {
+		return 0, fmt.Errorf("invalid tls_min_version %q: expected 1.0, 1.1, 1.2 or 1.3", value)
+	}
+	return version, nil
+}
+
+// parseCipherSuites resolves the suites by their IANA name, e.g. TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256.
+// Only the suites go considers secure are accepted. The TLS 1.3 suites aren't configurable in go
+// so they are rejected, rather than silently ignored
+func parseCipherSuites(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	known := make(map[string]*tls.CipherSuite)
+	for _, suite := range tls.CipherSuites() {
+		known[suite.Name] = suite
+	}
+
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		suite, ok := known[strings.TrimSpace(name)]
+		if !ok {
+			return nil, fmt.Errorf("invalid tls_cipher_suites: unknown or insecure suite %q", name)
+		}
+		if len(suite.SupportedVersions) == 1 && suite.SupportedVersions[0] == tls.VersionTLS13 {
+			return nil, fmt.Errorf("invalid tls_cipher_suites: %q is a TLS 1.3 suite, they are always enabled", name)
+		}
+		ids = append(ids, suite.ID)
+	}
+	return ids, nil
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTLSTestServer(t *testing.T, cfg HTTPConfig) *httptest.Server {
+	t.Helper()
+	tlsConfig, err := cfg.TLSConfig()
+	require.NoError(t, err)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.TLS = tlsConfig
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// dialTLS handshakes with srv, trusting its certificate
+func dialTLS(srv *httptest.Server, clientConfig *tls.Config) error {
+	clientConfig.RootCAs = srv.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs
+	conn, err := tls.Dial("tcp", srv.Listener.Addr().String(), clientConfig)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+func TestTLSConfigDefaults(t *testing.T) {
+	tlsConfig, err := (&HTTPConfig{}).TLSConfig()
+	require.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS12), tlsConfig.MinVersion)
+	assert.Nil(t, tlsConfig.CipherSuites)
+
+	srv := newTLSTestServer(t, HTTPConfig{})
+	assert.NoError(t, dialTLS(srv, &tls.Config{MaxVersion: tls.VersionTLS12}))
+	assert.Error(t, dialTLS(srv, &tls.Config{MaxVersion: tls.VersionTLS11}))
+}
+
+func TestTLSConfigMinVersion(t *testing.T) {
+	srv := newTLSTestServer(t, HTTPConfig{TLSMinVersion: "1.3"})
+
+	assert.NoError(t, dialTLS(srv, &tls.Config{}))
+	assert.Error(t, dialTLS(srv, &tls.Config{MaxVersion: tls.VersionTLS12}), "TLS 1.2 is rejected")
+}
+
+func TestTLSConfigCipherSuites(t *testing.T) {
+	srv := newTLSTestServer(t, HTTPConfig{TLSCipherSuites: []string{
+		"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+		"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256",
+	}})
+
+	assert.NoError(t, dialTLS(srv, &tls.Config{
+		MaxVersion:   tls.VersionTLS12,
+		CipherSuites: []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
+	}))
+	assert.Error(t, dialTLS(srv, &tls.Config{
+		MaxVersion:   tls.VersionTLS12,
+		CipherSuites: []uint16{tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384, tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384},
+	}), "a suite that isn't allowed is rejected")
+}
+
+func TestTLSConfigInvalid(t *testing.T) {
+	for _, cfg := range []HTTPConfig{
+		{TLSMinVersion: "1.4"},
+		{TLSMinVersion: "tls12"},
+		{TLSCipherSuites: []string{"TLS_NOT_A_SUITE"}},
+		{TLSCipherSuites: []string{"TLS_RSA_WITH_RC4_128_SHA"}},
+		{TLSCipherSuites: []string{"TLS_AES_128_GCM_SHA256"}},
+	} {
+		cfg.Addr = "localhost:8080"
+		assert.Error(t, cfg.Validate(), cfg)
+	}
+
+	_, err := parseTLSVersion("TLS1.3")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,25 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/openmined/syftbox/internal/version"
+)
+
+// VersionInfo is the server version, checked by the clients against their own
+type VersionInfo struct {
+	Version        string `json:"version"`
+	Revision       string `json:"revision"`
+	WireVersion    int    `json:"wireVersion"`
+	MinWireVersion int    `json:"minWireVersion"` // oldest client wire version supported
+}
+
+func VersionHandler(ctx *gin.Context) {
+	ctx.PureJSON(http.StatusOK, &VersionInfo{
+		Version:        version.Version,
+		Revision:       version.Revision,
+		WireVersion:    version.WireVersion,
+		MinWireVersion: version.MinWireVersion,
+	})
+}
@@ -65,6 +65,12 @@ func (m *Message) UnmarshalJSON(data []byte) error {
 			return err
 		}
 		m.Data = &httpMsg
+	case MsgBatch:
+		var batch Batch
+		if err := json.Unmarshal(temp.Data, &batch); err != nil {
+			return err
+		}
+		m.Data = batch
 	default:
 		return fmt.Errorf("unknown message type: %d", m.Type)
 	}
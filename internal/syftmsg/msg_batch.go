@@ -0,0 +1,16 @@
+package syftmsg
+
+// Batch carries several messages in one, e.g. the notifications coalesced for a client
+type Batch struct {
+	Messages []*Message `json:"msgs"`
+}
+
+func NewBatch(msgs []*Message) *Message {
+	return &Message{
+		Id:   generateID(),
+		Type: MsgBatch,
+		Data: &Batch{
+			Messages: msgs,
+		},
+	}
+}
@@ -12,6 +12,7 @@ const (
 	MsgAck
 	MsgNack
 	MsgHttp
+	MsgBatch
 )
 
 func (t MessageType) String() string {
@@ -30,6 +31,8 @@ func (t MessageType) String() string {
 		return "NACK"
 	case MsgHttp:
 		return "HTTP"
+	case MsgBatch:
+		return "BATCH"
 	default:
 		return fmt.Sprintf("???(%d)", t)
 	}
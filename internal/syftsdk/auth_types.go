@@ -2,8 +2,10 @@ package syftsdk
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/openmined/syftbox/internal/utils"
 )
 
 type AuthTokenType string
@@ -29,6 +31,15 @@ type RefreshTokenRequest struct {
 type AuthTokenResponse struct {
 	AccessToken  string `json:"accessToken"`
 	RefreshToken string `json:"refreshToken"`
+	Email        string `json:"email,omitempty"` // the canonical email the tokens are issued to
+}
+
+// CanonicalEmail returns the email the tokens are issued to, or email if the server doesn't return it
+func (r *AuthTokenResponse) CanonicalEmail(email string) string {
+	if r.Email == "" {
+		return email
+	}
+	return r.Email
 }
 
 type AuthClaims struct {
@@ -36,10 +47,23 @@ type AuthClaims struct {
 	jwt.RegisteredClaims
 }
 
+// Validate checks that the token is issued to email. The server issues the tokens to the canonical form of the email,
+// so a datasite named after another spelling of it, like A.Lice+news@gmail.com for alice@gmail.com, matches too.
 func (c *AuthClaims) Validate(email string, issuer string) error {
-	if c.Subject != email {
+	if c.Subject != email && !sameAddress(c.Subject, email) {
 		return fmt.Errorf("invalid claims: token subject %q does not match %q", c.Subject, email)
 	}
 
 	return nil
 }
+
+// sameAddress reports if a and b are spellings of the same address, ignoring the case, and the dots and the +tag
+// of the local part. The client doesn't know at which domains the server ignores the latter, so it assumes all.
+func sameAddress(a, b string) bool {
+	at := strings.LastIndex(b, "@")
+	if at < 0 {
+		return false
+	}
+	normalize := utils.NewEmailNormalizer([]string{b[at+1:]}).Normalize
+	return normalize(a) == normalize(b)
+}
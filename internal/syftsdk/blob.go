@@ -1,43 +1,80 @@
 package syftsdk
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
 	"time"
 
 	"github.com/imroc/req/v3"
-	"github.com/openmined/syftbox/internal/utils"
+	"github.com/openmined/syftbox/internal/aclspec"
+	"github.com/openmined/syftbox/internal/blobbatch"
+	"github.com/openmined/syftbox/internal/blobcodec"
+	"github.com/openmined/syftbox/internal/fileattr"
 )
 
 const (
 	v1BlobUpload          = "/api/v1/blob/upload"
 	v1BlobUploadPresigned = "/api/v1/blob/upload/presigned"
+	v1BlobUploadBatch     = "/api/v1/blob/upload/batch"
 	v1BlobDownload        = "/api/v1/blob/download"
+	v1BlobDownloadBatch   = "/api/v1/blob/download/batch"
 	v1BlobDelete          = "/api/v1/blob/delete"
 )
 
 type BlobAPI struct {
-	client *req.Client
+	client               *req.Client
+	compressionCodec     string
+	compressionThreshold int64
 }
 
-func newBlobAPI(client *req.Client) *BlobAPI {
+func newBlobAPI(client *req.Client, codec string, threshold int64) *BlobAPI {
 	return &BlobAPI{
-		client: client,
+		client:               client,
+		compressionCodec:     codec,
+		compressionThreshold: threshold,
 	}
 }
 
-// Upload uploads a file to the blob storage
+// Upload uploads a file to the blob storage.
+// If compression is enabled, the file is compressed before upload, when it is worth compressing.
 func (b *BlobAPI) Upload(ctx context.Context, params *UploadParams) (apiResp *UploadResponse, err error) {
-	if !utils.FileExists(params.FilePath) {
+	info, err := os.Stat(params.FilePath)
+	if err != nil || info.IsDir() {
 		return nil, ErrFileNotFound
 	}
 
-	resp, err := b.client.R().
+	r := b.client.R().
 		SetContext(ctx).
 		SetQueryParam("key", params.Key).
+		SetQueryParams(params.Attrs.Metadata())
+
+	filePath := params.FilePath
+	if b.shouldCompress(params) {
+		compressed, err := blobcodec.CompressFile(b.compressionCodec, params.FilePath, os.TempDir())
+		if err != nil {
+			return nil, fmt.Errorf("sdk: blob upload: compress: %w", err)
+		}
+		defer os.Remove(compressed)
+
+		// only upload the compressed file if it's actually smaller
+		if compressedInfo, err := os.Stat(compressed); err == nil && compressedInfo.Size() < info.Size() {
+			filePath = compressed
+			r.SetQueryParam("codec", b.compressionCodec).
+				SetQueryParam("etag", params.ETag).
+				SetQueryParam("size", strconv.FormatInt(info.Size(), 10))
+		}
+	}
+
+	resp, err := r.
 		// SetQueryParam("crc64nvme", params.ChecksumCRC64NVME).
 		SetRetryCount(0).
-		SetFile("file", params.FilePath).
+		SetFile("file", filePath).
 		SetSuccessResult(&apiResp).
 		SetUploadCallbackWithInterval(func(info req.UploadInfo) {
 			// if file size is less than 1MB, don't show progress
@@ -55,6 +92,15 @@ func (b *BlobAPI) Upload(ctx context.Context, params *UploadParams) (apiResp *Up
 	return apiResp, nil
 }
 
+// shouldCompress checks if the upload is worth compressing.
+// ACL files are never compressed, the server has to parse them.
+func (b *BlobAPI) shouldCompress(params *UploadParams) bool {
+	return blobcodec.IsCompressed(b.compressionCodec) &&
+		params.ETag != "" &&
+		!aclspec.IsACLFile(params.Key) &&
+		blobcodec.ShouldCompress(params.FilePath, b.compressionThreshold)
+}
+
 // UploadPresigned gets presigned URLs for uploading multiple blobs
 func (b *BlobAPI) UploadPresigned(ctx context.Context, params *PresignedParams) (apiResp *PresignedResponse, err error) {
 	resp, err := b.client.R().
@@ -90,6 +136,141 @@ func (b *BlobAPI) Download(ctx context.Context, params *PresignedParams) (apiRes
 	return apiResp, nil
 }
 
+// UploadBatch uploads many small files in a single request, packed as a batch (see blobbatch).
+// The files the server rejects are listed in the errors of the response.
+// The files are sent as they are, batches are meant for files too small to be worth compressing.
+func (b *BlobAPI) UploadBatch(ctx context.Context, params *UploadBatchParams) (apiResp *UploadBatchResponse, err error) {
+	if len(params.Files) == 0 {
+		return nil, fmt.Errorf("no files provided")
+	}
+
+	// batches are small, and a body in memory can be retried
+	var body bytes.Buffer
+	writer := blobbatch.NewWriter(&body)
+	for _, file := range params.Files {
+		if err := writeBatchFile(writer, file); err != nil {
+			return nil, fmt.Errorf("sdk: blob upload batch: %w", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("sdk: blob upload batch: %w", err)
+	}
+
+	resp, err := b.client.R().
+		SetContext(ctx).
+		SetContentType(blobbatch.ContentType).
+		SetBodyBytes(body.Bytes()).
+		SetRetryCondition(retryBatch).
+		SetSuccessResult(&apiResp).
+		Post(v1BlobUploadBatch)
+
+	// checked first, the body of the 404 isn't an api error
+	if resp.GetStatusCode() == http.StatusNotFound {
+		return nil, ErrBatchNotSupported
+	}
+
+	if err := handleAPIError(resp, err, "blob upload batch"); err != nil {
+		return nil, err
+	}
+
+	return apiResp, nil
+}
+
+func writeBatchFile(writer *blobbatch.Writer, file *UploadParams) error {
+	f, err := os.Open(file.FilePath)
+	if err != nil {
+		return ErrFileNotFound
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		return ErrFileNotFound
+	}
+
+	return writer.WriteEntry(&blobbatch.Entry{
+		Key:          file.Key,
+		ETag:         file.ETag,
+		Size:         info.Size(),
+		LastModified: info.ModTime(),
+		Metadata:     file.Attrs.Metadata(),
+	}, f)
+}
+
+// DownloadBatch downloads many small blobs in a single request, as a batch (see blobbatch).
+// The blobs are written to dir. A blob the server couldn't send has the error in its result.
+// If the batch is cut short, the results so far are returned along with the error
+func (b *BlobAPI) DownloadBatch(ctx context.Context, params *PresignedParams, dir string) ([]*DownloadBatchResult, error) {
+	if len(params.Keys) == 0 {
+		return nil, fmt.Errorf("no keys provided")
+	}
+
+	resp, err := b.client.R().
+		SetContext(ctx).
+		SetBody(params).
+		SetRetryCondition(retryBatch).
+		DisableAutoReadResponse().
+		Post(v1BlobDownloadBatch)
+
+	// checked first, the body of the 404 isn't an api error
+	if resp.GetStatusCode() == http.StatusNotFound {
+		return nil, ErrBatchNotSupported
+	}
+	if err != nil {
+		return nil, fmt.Errorf("http request error: blob download batch %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.IsErrorState() {
+		var apiErr APIError
+		if data, err := io.ReadAll(resp.Body); err == nil && jsonUmarshal(data, &apiErr) == nil && apiErr.Code != "" {
+			return nil, fmt.Errorf("blob download batch %w", &apiErr)
+		}
+		return nil, fmt.Errorf("api error: blob download batch: %s", resp.Status)
+	}
+
+	results := make([]*DownloadBatchResult, 0, len(params.Keys))
+	reader := blobbatch.NewReader(resp.Body)
+	for {
+		entry, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return results, nil
+		}
+		if err != nil {
+			return results, fmt.Errorf("sdk: blob download batch: %w", err)
+		}
+
+		result := &DownloadBatchResult{Key: entry.Key, ETag: entry.ETag, Size: entry.Size}
+		if entry.ErrorCode != "" {
+			result.Error = &BlobError{APIError: *NewAPIError(entry.ErrorCode, entry.ErrorMessage), Key: entry.Key}
+			results = append(results, result)
+			continue
+		}
+
+		if result.DownloadPath, err = saveBatchEntry(reader, dir); err != nil {
+			return results, fmt.Errorf("sdk: blob download batch: %q: %w", entry.Key, err)
+		}
+		// invalid attributes are left out, the content still syncs
+		result.Attrs, _ = fileattr.Parse(entry.Metadata[fileattr.MetaExecutable], entry.Metadata[fileattr.MetaXattrs])
+		results = append(results, result)
+	}
+}
+
+// saveBatchEntry writes the content of the current entry of the batch to a new file in dir
+func saveBatchEntry(content io.Reader, dir string) (string, error) {
+	f, err := os.CreateTemp(dir, "batch-*")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(f, content); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
 // Delete deletes multiple blobs
 func (b *BlobAPI) Delete(ctx context.Context, params *DeleteParams) (apiResp *DeleteResponse, err error) {
 	resp, err := b.client.R().
@@ -104,3 +285,8 @@ func (b *BlobAPI) Delete(ctx context.Context, params *DeleteParams) (apiResp *De
 
 	return apiResp, nil
 }
+
+// retryBatch retries the failed batches, but those of a server without batches
+func retryBatch(resp *req.Response, err error) bool {
+	return err != nil && resp.GetStatusCode() != http.StatusNotFound
+}
@@ -0,0 +1,117 @@
+package syftsdk
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/imroc/req/v3"
+	"github.com/openmined/syftbox/internal/blobcodec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlobUploadCompressedRoundTrip(t *testing.T) {
+	content := bytes.Repeat([]byte("id,name,score\n1,alice,42\n"), 2048)
+
+	var stored []byte
+	var query map[string]string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case v1BlobUpload:
+			file, _, err := r.FormFile("file")
+			require.NoError(t, err)
+			defer file.Close()
+
+			stored, err = io.ReadAll(file)
+			require.NoError(t, err)
+
+			query = map[string]string{
+				"codec": r.URL.Query().Get("codec"),
+				"etag":  r.URL.Query().Get("etag"),
+				"size":  r.URL.Query().Get("size"),
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"key":"user@example.com/public/data.csv","etag":"content-md5"}`))
+		case "/download":
+			w.Header().Set("Content-Encoding", blobcodec.Gzip)
+			w.Write(stored)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "data.csv")
+	require.NoError(t, os.WriteFile(src, content, 0o644))
+
+	blobAPI := newBlobAPI(req.C().SetBaseURL(srv.URL), blobcodec.Gzip, blobcodec.DefaultThreshold)
+	_, err := blobAPI.Upload(context.Background(), &UploadParams{
+		Key:      "user@example.com/public/data.csv",
+		FilePath: src,
+		ETag:     "content-md5",
+	})
+	require.NoError(t, err)
+
+	// stored compressed, along with the identity of the original content
+	assert.Less(t, len(stored), len(content))
+	assert.Equal(t, blobcodec.Gzip, query["codec"])
+	assert.Equal(t, "content-md5", query["etag"])
+	assert.Equal(t, "51200", query["size"])
+
+	// downloaded back as the original content
+	path, err := DownloadFile(context.Background(), &DownloadJob{
+		URL:       srv.URL + "/download",
+		TargetDir: dir,
+		Name:      "downloaded.csv",
+	})
+	require.NoError(t, err)
+
+	got, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, content, got)
+}
+
+func TestBlobUploadSkipsCompression(t *testing.T) {
+	content := bytes.Repeat([]byte("a"), blobcodec.DefaultThreshold)
+
+	var stored []byte
+	var codec string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		file, _, err := r.FormFile("file")
+		require.NoError(t, err)
+		defer file.Close()
+
+		stored, err = io.ReadAll(file)
+		require.NoError(t, err)
+		codec = r.URL.Query().Get("codec")
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "archive.zip")
+	require.NoError(t, os.WriteFile(src, content, 0o644))
+
+	blobAPI := newBlobAPI(req.C().SetBaseURL(srv.URL), blobcodec.Gzip, blobcodec.DefaultThreshold)
+	_, err := blobAPI.Upload(context.Background(), &UploadParams{
+		Key:      "user@example.com/public/archive.zip",
+		FilePath: src,
+		ETag:     "content-md5",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, content, stored)
+	assert.Empty(t, codec)
+}
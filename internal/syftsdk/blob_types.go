@@ -2,6 +2,8 @@ package syftsdk
 
 import (
 	"time"
+
+	"github.com/openmined/syftbox/internal/fileattr"
 )
 
 // BlobInfo represents information about a blob
@@ -10,12 +12,14 @@ type BlobInfo struct {
 	ETag         string    `json:"etag"`
 	Size         int       `json:"size"`
 	LastModified time.Time `json:"lastModified"`
+	Revision     int64     `json:"revision,omitempty"`
 }
 
 // BlobURL represents a presigned URL for a blob
 type BlobURL struct {
-	Key string `json:"key"`
-	URL string `json:"url"`
+	Key     string            `json:"key"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"` // headers to send with the request, like the encryption of presigned uploads
 }
 
 // BlobError represents an error for a specific blob operation
@@ -30,7 +34,9 @@ type BlobError struct {
 type UploadParams struct {
 	Key               string
 	FilePath          string
+	ETag              string // md5 of the file. Required for the file to be compressed
 	ChecksumCRC64NVME string
+	Attrs             *fileattr.Attrs // file attributes stored with the blob. Optional
 	Callback          func(uploadedBytes int64, totalBytes int64)
 }
 
@@ -41,13 +47,38 @@ type UploadResponse struct {
 	ETag         string `json:"etag"`
 	Size         int64  `json:"size"`
 	LastModified string `json:"lastModified"`
+	Revision     int64  `json:"revision,omitempty"`
+}
+
+// ===================================================================================================
+
+// UploadBatchParams represents the parameters for uploading many small files at once
+type UploadBatchParams struct {
+	Files []*UploadParams // the callbacks of the files are not called
+}
+
+// UploadBatchResponse represents the response from a batch upload
+type UploadBatchResponse struct {
+	Uploaded []*UploadResponse `json:"uploaded"`
+	Errors   []*BlobError      `json:"errors"`
+}
+
+// DownloadBatchResult represents a blob of a batch download
+type DownloadBatchResult struct {
+	Key          string
+	ETag         string
+	Size         int64
+	DownloadPath string          // where the content was saved
+	Attrs        *fileattr.Attrs // file attributes stored with the blob, nil if it has none
+	Error        error
 }
 
 // ===================================================================================================
 
 // PresignedParams represents the parameters for getting presigned URLs
 type PresignedParams struct {
-	Keys []string `json:"keys"`
+	Keys  []string `json:"keys"`
+	Sizes []int64  `json:"sizes,omitempty"` // sizes of the uploads, in the order of the keys. Required by servers with a datasite quota
 }
 
 // PresignedResponse represents the response from a presigned URL request
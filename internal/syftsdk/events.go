@@ -74,6 +74,19 @@ func (e *EventsAPI) IsConnected() bool {
 	return e.connected
 }
 
+// Reconnect drops the current connection, which is then re-established by the connection manager.
+// Used when the connection is likely stale, e.g. after the system resumed from sleep
+func (e *EventsAPI) Reconnect() {
+	e.mu.RLock()
+	wsClient := e.wsClient
+	e.mu.RUnlock()
+
+	if wsClient != nil {
+		slog.Info("socketmgr reconnecting")
+		wsClient.Close()
+	}
+}
+
 // Get returns a channel for receiving WebSocket messages
 func (e *EventsAPI) Get() <-chan *syftmsg.Message {
 	return e.messages
@@ -0,0 +1,47 @@
+package syftsdk
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/imroc/req/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventsReconnect(t *testing.T) {
+	var connections atomic.Int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Accept(w, r, nil)
+		if err != nil {
+			return
+		}
+		connections.Add(1)
+		defer conn.CloseNow()
+
+		// hold the connection until the client drops it
+		for {
+			if _, _, err := conn.Read(r.Context()); err != nil {
+				return
+			}
+		}
+	}))
+	defer srv.Close()
+
+	events := newEventsAPI(req.C().SetBaseURL(srv.URL))
+	defer events.Close()
+
+	require.NoError(t, events.Connect(context.Background()))
+	require.Eventually(t, func() bool { return connections.Load() == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	// the connection is replaced by a new one
+	events.Reconnect()
+	require.Eventually(t, func() bool { return connections.Load() == 2 }, 5*time.Second, 10*time.Millisecond)
+	assert.Eventually(t, events.IsConnected, 5*time.Second, 10*time.Millisecond)
+}
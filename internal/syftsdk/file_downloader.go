@@ -11,14 +11,42 @@ import (
 	"time"
 
 	"github.com/imroc/req/v3"
+	"github.com/openmined/syftbox/internal/blobcodec"
+	"github.com/openmined/syftbox/internal/fileattr"
 	"github.com/openmined/syftbox/internal/utils"
 )
 
+// downloadClient is shared by the downloads, so that their connections are reused across batches
+var downloadClient = NewDownloadClient(DefaultDownloadClientOpts)
+
+// NewDownloadClient returns a client for the downloads with its connection pool tuned by opts
+func NewDownloadClient(opts DownloadClientOpts) *req.Client {
+	client := HTTPClient.Clone()
+
+	transport := client.GetTransport()
+	if opts.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = opts.MaxIdleConnsPerHost
+		transport.MaxIdleConns = max(transport.MaxIdleConns, opts.MaxIdleConnsPerHost)
+	}
+	transport.SetIdleConnTimeout(opts.IdleConnTimeout)
+	if !opts.ForceAttemptHTTP2 {
+		client.EnableForceHTTP1()
+	}
+
+	return client
+}
+
 // DownloadFile downloads a single file from the provided URL to the temp directory
 // Returns the path to the downloaded file or an error
 func DownloadFile(ctx context.Context, job *DownloadJob) (string, error) {
+	path, _, err := downloadFile(ctx, downloadClient, job)
+	return path, err
+}
+
+// downloadFile downloads the file, along with the file attributes stored in its metadata
+func downloadFile(ctx context.Context, client *req.Client, job *DownloadJob) (string, *fileattr.Attrs, error) {
 	if err := utils.EnsureDir(job.TargetDir); err != nil {
-		return "", fmt.Errorf("sdk: download file: %q: %w", job.URL, err)
+		return "", nil, fmt.Errorf("sdk: download file: %q: %w", job.URL, err)
 	}
 
 	// If no filename is provided, use the last part of the URL
@@ -29,7 +57,7 @@ func DownloadFile(ctx context.Context, job *DownloadJob) (string, error) {
 	destPath := filepath.Join(job.TargetDir, job.Name)
 
 	// Use context for cancelation
-	resp, err := HTTPClient.R().
+	resp, err := client.R().
 		DisableAutoReadResponse().
 		SetContext(ctx).
 		SetOutputFile(destPath).
@@ -41,7 +69,7 @@ func DownloadFile(ctx context.Context, job *DownloadJob) (string, error) {
 		Get(job.URL)
 
 	if err != nil {
-		return "", fmt.Errorf("sdk: download file: '%s': %w", job.URL, err)
+		return "", nil, fmt.Errorf("sdk: download file: '%s': %w", job.URL, err)
 	}
 
 	if resp.IsErrorState() {
@@ -80,10 +108,24 @@ func DownloadFile(ctx context.Context, job *DownloadJob) (string, error) {
 			errorCode = CodeUnknownError
 		}
 
-		return "", fmt.Errorf("sdk: download file: '%s': %w", job.URL, NewPresignedURLError(errorCode, respStr))
+		return "", nil, fmt.Errorf("sdk: download file: '%s': %w", job.URL, NewPresignedURLError(errorCode, respStr))
 	}
 
-	return destPath, nil
+	// compressed blobs are served with their codec as the content encoding.
+	// the transport decompresses them by itself only if it asked for compression
+	if codec := resp.GetHeader("Content-Encoding"); blobcodec.IsCompressed(codec) && !resp.Uncompressed {
+		if err := blobcodec.DecompressFile(codec, destPath); err != nil {
+			return "", nil, fmt.Errorf("sdk: download file: '%s': %w", job.URL, err)
+		}
+	}
+
+	attrs, err := fileattr.FromHeader(resp.Header)
+	if err != nil {
+		// content is fine, don't fail the download over its attributes
+		slog.Warn("download file attributes", "url", job.URL, "error", err)
+	}
+
+	return destPath, attrs, nil
 }
 
 func Downloader(ctx context.Context, opts *DownloadOpts) <-chan *DownloadResult {
@@ -96,6 +138,11 @@ func Downloader(ctx context.Context, opts *DownloadOpts) <-chan *DownloadResult
 		workers = DefaultWorkers
 	}
 
+	client := opts.Client
+	if client == nil {
+		client = downloadClient
+	}
+
 	// Start exactly maxWorkers workers
 	var wg sync.WaitGroup
 	wg.Add(workers)
@@ -109,10 +156,11 @@ func Downloader(ctx context.Context, opts *DownloadOpts) <-chan *DownloadResult
 				case <-ctx.Done():
 					return
 				default:
-					filePath, err := DownloadFile(ctx, file)
+					filePath, attrs, err := downloadFile(ctx, client, file)
 					results <- &DownloadResult{
 						DownloadJob:  *file,
 						DownloadPath: filePath,
+						Attrs:        attrs,
 						Error:        err,
 					}
 				}
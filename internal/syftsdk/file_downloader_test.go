@@ -0,0 +1,104 @@
+package syftsdk
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newObjectServer serves tiny objects, and counts the connections opened to it
+func newObjectServer(tb testing.TB) (*httptest.Server, *atomic.Int64) {
+	tb.Helper()
+	var conns atomic.Int64
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.Path))
+	}))
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	srv.Start()
+	tb.Cleanup(srv.Close)
+	return srv, &conns
+}
+
+func objectJobs(srvURL, dir string, n int) []*DownloadJob {
+	jobs := make([]*DownloadJob, n)
+	for i := range jobs {
+		jobs[i] = &DownloadJob{
+			URL:       fmt.Sprintf("%s/object-%d", srvURL, i),
+			TargetDir: dir,
+			Name:      fmt.Sprintf("object-%d", i),
+		}
+	}
+	return jobs
+}
+
+func TestDownloaderReusesConnections(t *testing.T) {
+	srv, conns := newObjectServer(t)
+
+	results := Downloader(context.Background(), &DownloadOpts{
+		Workers: DefaultWorkers,
+		Jobs:    objectJobs(srv.URL, t.TempDir(), 200),
+		Client:  NewDownloadClient(DefaultDownloadClientOpts),
+	})
+	count := 0
+	for res := range results {
+		require.NoError(t, res.Error)
+		count++
+	}
+	assert.Equal(t, 200, count)
+
+	// about a connection per worker, rather than one per object
+	assert.LessOrEqual(t, conns.Load(), int64(2*DefaultWorkers))
+}
+
+func TestNewDownloadClient(t *testing.T) {
+	transport := NewDownloadClient(DownloadClientOpts{MaxIdleConnsPerHost: 256}).GetTransport()
+	assert.Equal(t, 256, transport.MaxIdleConnsPerHost)
+	assert.Equal(t, 256, transport.MaxIdleConns, "the pool holds the idle connections of a host")
+	assert.Zero(t, transport.IdleConnTimeout)
+
+	transport = NewDownloadClient(DefaultDownloadClientOpts).GetTransport()
+	assert.Equal(t, DefaultDownloadClientOpts.IdleConnTimeout, transport.IdleConnTimeout)
+	assert.Equal(t, 100, transport.MaxIdleConns)
+}
+
+// BenchmarkDownloader downloads 1000 tiny objects, like a sync of many small files
+func BenchmarkDownloader(b *testing.B) {
+	for _, bench := range []struct {
+		name string
+		opts DownloadClientOpts
+	}{
+		{"default", DefaultDownloadClientOpts},
+		{"untuned", DownloadClientOpts{ForceAttemptHTTP2: true}},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			srv, conns := newObjectServer(b)
+			client := NewDownloadClient(bench.opts)
+			dir := b.TempDir()
+
+			for b.Loop() {
+				results := Downloader(context.Background(), &DownloadOpts{
+					Workers: DefaultWorkers,
+					Jobs:    objectJobs(srv.URL, dir, 1000),
+					Client:  client,
+				})
+				for res := range results {
+					if res.Error != nil {
+						b.Fatal(res.Error)
+					}
+				}
+			}
+			b.ReportMetric(float64(conns.Load())/float64(b.N), "conns/op")
+		})
+	}
+}
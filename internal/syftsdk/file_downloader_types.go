@@ -1,10 +1,32 @@
 package syftsdk
 
+import (
+	"time"
+
+	"github.com/imroc/req/v3"
+	"github.com/openmined/syftbox/internal/fileattr"
+)
+
 const (
 	AutoDetectWorkers = 0
 	DefaultWorkers    = 8
 )
 
+// DownloadClientOpts tunes the connection pool of a download client
+type DownloadClientOpts struct {
+	MaxIdleConnsPerHost int           // idle connections kept per host for the next downloads. 0 is the transport's default of 2
+	IdleConnTimeout     time.Duration // how long an idle connection is kept. 0 keeps it until the server closes it
+	ForceAttemptHTTP2   bool          // offer HTTP/2 to the servers that support it, only HTTP/1.1 is used when false
+}
+
+// DefaultDownloadClientOpts keeps a connection per worker open between downloads, so that syncing
+// many small objects doesn't open a connection for each and run out of ephemeral ports
+var DefaultDownloadClientOpts = DownloadClientOpts{
+	MaxIdleConnsPerHost: 4 * DefaultWorkers,
+	IdleConnTimeout:     90 * time.Second,
+	ForceAttemptHTTP2:   true,
+}
+
 const (
 	CodePresignedURLErrors    = "E_PRESIGNED_URL"            // prefix for all presigned url errors
 	CodePresignedURLExpired   = "E_PRESIGNED_URL_EXPIRED"    // presigned URL has expired
@@ -24,10 +46,12 @@ type DownloadJob struct {
 type DownloadResult struct {
 	DownloadJob
 	DownloadPath string
+	Attrs        *fileattr.Attrs // file attributes stored with the blob, nil if it has none
 	Error        error
 }
 
 type DownloadOpts struct {
 	Workers int
 	Jobs    []*DownloadJob
+	Client  *req.Client // client of the downloads, from NewDownloadClient. nil uses the shared one
 }
@@ -54,7 +54,7 @@ func New(config *SyftSDKConfig) (*SyftSDK, error) {
 		SetCommonErrorResult(&APIError{})
 
 	datasiteAPI := newDatasiteAPI(client)
-	blobAPI := newBlobAPI(client)
+	blobAPI := newBlobAPI(client, config.CompressionCodec, config.CompressionThreshold)
 	eventsAPI := newEventsAPI(client)
 
 	return &SyftSDK{
@@ -1,6 +1,7 @@
 package syftsdk
 
 import (
+	"github.com/openmined/syftbox/internal/blobcodec"
 	"github.com/openmined/syftbox/internal/utils"
 )
 
@@ -14,6 +15,9 @@ type SyftSDKConfig struct {
 	Email        string // Email is required
 	RefreshToken string // RefreshToken is required
 	AccessToken  string // AccessToken is optional
+
+	CompressionCodec     string // CompressionCodec is optional. Blobs are compressed with it on upload
+	CompressionThreshold int64  // CompressionThreshold is optional. Minimum size of a blob to compress
 }
 
 func (c *SyftSDKConfig) Validate() error {
@@ -29,5 +33,13 @@ func (c *SyftSDKConfig) Validate() error {
 		return err
 	}
 
+	if err := blobcodec.Validate(c.CompressionCodec); err != nil {
+		return err
+	}
+
+	if c.CompressionThreshold <= 0 {
+		c.CompressionThreshold = blobcodec.DefaultThreshold
+	}
+
 	return nil
 }
@@ -19,6 +19,8 @@ var (
 	// blob
 	ErrNoPermissions = errors.New("sdk: no permissions")
 	ErrFileNotFound  = errors.New("sdk: file not found")
+	// the server predates the batched transfers
+	ErrBatchNotSupported = errors.New("sdk: batches not supported by the server")
 )
 
 const (
@@ -39,6 +41,8 @@ const (
 	// Datasite errors
 	CodeDatasiteNotFound    = "E_DATASITE_NOT_FOUND"    // the specified datasite resource could not be found.
 	CodeDatasiteInvalidPath = "E_DATASITE_INVALID_PATH" // the provided path for a datasite resource is invalid or malformed.
+	CodeDatasiteKeyNotNFC   = "E_DATASITE_KEY_NOT_NFC"  // the path is not in Unicode NFC, the file must be renamed to its NFC form.
+	CodeFeatureDisabled     = "E_FEATURE_DISABLED"      // the feature is turned off for the datasite.
 
 	// Blob errors
 	CodeBlobNotFound     = "E_BLOB_NOT_FOUND"               // the specified blob could not be found.
@@ -49,6 +53,7 @@ const (
 
 	// ACL errors
 	CodeACLUpdateFailed = "E_ACL_UPDATE_FAILED" // a failure during the operation to update an ACL.
+	CodeACLInvalid      = "E_ACL_INVALID"       // the ACL file could not be parsed or its ruleset is invalid.
 )
 
 type SDKError interface {
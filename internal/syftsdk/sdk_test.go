@@ -0,0 +1,66 @@
+package syftsdk
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testToken(t *testing.T, tokenType AuthTokenType, subject string) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &AuthClaims{
+		Type:             tokenType,
+		RegisteredClaims: jwt.RegisteredClaims{Subject: subject},
+	}).SignedString([]byte("secret"))
+	require.NoError(t, err)
+	return token
+}
+
+func TestRefreshAuthTokenEmailNormalized(t *testing.T) {
+	var subject string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(&AuthTokenResponse{
+			AccessToken:  testToken(t, AccessToken, subject),
+			RefreshToken: testToken(t, RefreshToken, subject),
+			Email:        subject,
+		})
+	}))
+	defer srv.Close()
+
+	newSDK := func() *SyftSDK {
+		sdk, err := New(&SyftSDKConfig{
+			BaseURL:      srv.URL,
+			Email:        "A.Lice+news@gmail.com",
+			RefreshToken: testToken(t, RefreshToken, "alice@gmail.com"),
+		})
+		require.NoError(t, err)
+		return sdk
+	}
+
+	// the tokens of the canonical email authenticate the datasite of the old spelling
+	subject = "alice@gmail.com"
+	var refreshToken string
+	sdk := newSDK()
+	sdk.OnAuthTokenUpdate(func(token string) { refreshToken = token })
+	require.NoError(t, sdk.refreshAuthToken(context.Background()))
+	assert.NotEmpty(t, refreshToken)
+
+	// tokens of another address are still rejected
+	subject = "bob@gmail.com"
+	require.Error(t, newSDK().refreshAuthToken(context.Background()))
+}
+
+func TestSameAddress(t *testing.T) {
+	assert.True(t, sameAddress("alice@example.com", "Alice@Example.com"))
+	assert.True(t, sameAddress("alice@gmail.com", "a.lice+news@gmail.com"))
+	assert.False(t, sameAddress("alice@gmail.com", "alice@example.com"))
+	assert.False(t, sameAddress("alice@gmail.com", "bob@gmail.com"))
+	assert.False(t, sameAddress("alice@gmail.com", "alice"))
+}
@@ -0,0 +1,63 @@
+package syftsdk
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/openmined/syftbox/internal/blobcodec"
+)
+
+const (
+	v1Version = "/api/v1/version"
+)
+
+// ServerVersionResponse is the version of the server
+type ServerVersionResponse struct {
+	Version        string `json:"version"`
+	Revision       string `json:"revision"`
+	WireVersion    int    `json:"wireVersion"`
+	MinWireVersion int    `json:"minWireVersion"` // oldest client wire version supported
+}
+
+// wireFeatures are the features that need a minimum wire version on the server
+var wireFeatures = []struct {
+	name    string
+	since   int
+	disable func(s *SyftSDK)
+}{
+	{"compressed uploads", 2, func(s *SyftSDK) { s.Blob.compressionCodec = blobcodec.None }},
+}
+
+// ServerVersion returns the version of the server.
+// Servers from before the version endpoint are reported as wire version 1.
+func (s *SyftSDK) ServerVersion(ctx context.Context) (info *ServerVersionResponse, err error) {
+	res, err := s.client.R().
+		SetContext(ctx).
+		SetRetryCount(0).
+		SetSuccessResult(&info).
+		Get(v1Version)
+
+	// the error body of a 404 may not be json either
+	if res.GetStatusCode() == http.StatusNotFound {
+		return &ServerVersionResponse{WireVersion: 1, MinWireVersion: 1}, nil
+	}
+
+	if err := handleAPIError(res, err, "server version"); err != nil {
+		return nil, err
+	}
+
+	return info, nil
+}
+
+// DisableUnsupported turns off the features that a server with the given wire version does not support.
+// It returns the names of the features turned off.
+func (s *SyftSDK) DisableUnsupported(wireVersion int) []string {
+	var disabled []string
+	for _, feature := range wireFeatures {
+		if wireVersion < feature.since {
+			feature.disable(s)
+			disabled = append(disabled, feature.name)
+		}
+	}
+	return disabled
+}
@@ -0,0 +1,35 @@
+package syftsdk
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openmined/syftbox/internal/blobcodec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerVersionBeforeEndpoint(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	sdk, err := New(&SyftSDKConfig{BaseURL: srv.URL, Email: "alice@example.com"})
+	require.NoError(t, err)
+
+	info, err := sdk.ServerVersion(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, info.WireVersion)
+}
+
+func TestDisableUnsupported(t *testing.T) {
+	sdk, err := New(&SyftSDKConfig{BaseURL: "http://localhost:1", Email: "alice@example.com", CompressionCodec: blobcodec.Gzip})
+	require.NoError(t, err)
+
+	assert.Empty(t, sdk.DisableUnsupported(2))
+	assert.Equal(t, blobcodec.Gzip, sdk.Blob.compressionCodec)
+
+	assert.Equal(t, []string{"compressed uploads"}, sdk.DisableUnsupported(1))
+	assert.Equal(t, blobcodec.None, sdk.Blob.compressionCodec)
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/mail"
 	"regexp"
+	"strings"
 )
 
 var emailRegex = regexp.MustCompile(`^[^\s@]+@[^\s@]+\.[^\s@]+$`)
@@ -33,3 +34,44 @@ func ValidateEmail(email string) error {
 
 	return nil
 }
+
+// EmailNormalizer maps the spellings of an email to one canonical form, so that one person maps to one datasite
+type EmailNormalizer struct {
+	dotPlusDomains map[string]struct{} // domains that ignore the dots and the +tag of the local part, like gmail.com
+}
+
+// NewEmailNormalizer returns a normalizer that also drops the dots and the +tag of the addresses at dotPlusDomains
+func NewEmailNormalizer(dotPlusDomains []string) *EmailNormalizer {
+	n := &EmailNormalizer{dotPlusDomains: make(map[string]struct{}, len(dotPlusDomains))}
+	for _, domain := range dotPlusDomains {
+		n.dotPlusDomains[strings.ToLower(strings.TrimSpace(domain))] = struct{}{}
+	}
+	return n
+}
+
+// Normalize returns email trimmed and lowercased. At the dot/plus domains,
+// A.Lice+news@gmail.com normalizes to alice@gmail.com. A nil normalizer only trims and lowercases.
+func (n *EmailNormalizer) Normalize(email string) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if n == nil || len(n.dotPlusDomains) == 0 {
+		return email
+	}
+
+	at := strings.LastIndex(email, "@")
+	if at <= 0 {
+		return email
+	}
+	local, domain := email[:at], email[at+1:]
+	if _, ok := n.dotPlusDomains[domain]; !ok {
+		return email
+	}
+
+	if plus := strings.Index(local, "+"); plus >= 0 {
+		local = local[:plus]
+	}
+	local = strings.ReplaceAll(local, ".", "")
+	if local == "" {
+		return email // e.g. +tag@gmail.com, left for the validation to reject
+	}
+	return local + "@" + domain
+}
@@ -36,3 +36,31 @@ func TestValidateEmail(t *testing.T) {
 		})
 	}
 }
+
+func TestEmailNormalizer(t *testing.T) {
+	gmail := NewEmailNormalizer([]string{"Gmail.com", "googlemail.com"})
+
+	tests := []struct {
+		name       string
+		normalizer *EmailNormalizer
+		email      string
+		want       string
+	}{
+		{name: "case", normalizer: nil, email: "Alice@Example.com", want: "alice@example.com"},
+		{name: "spaces", normalizer: nil, email: "  alice@example.com\n", want: "alice@example.com"},
+		{name: "plus-kept-by-default", normalizer: nil, email: "a.lice+news@gmail.com", want: "a.lice+news@gmail.com"},
+		{name: "dots", normalizer: gmail, email: "A.Lice@Gmail.com", want: "alice@gmail.com"},
+		{name: "plus", normalizer: gmail, email: "alice+news@googlemail.com", want: "alice@googlemail.com"},
+		{name: "dots-and-plus", normalizer: gmail, email: "a.l.ice+news.letter@gmail.com", want: "alice@gmail.com"},
+		{name: "other-domain", normalizer: gmail, email: "A.Lice+news@Example.com", want: "a.lice+news@example.com"},
+		{name: "subdomain", normalizer: gmail, email: "a.lice@mail.gmail.com", want: "a.lice@mail.gmail.com"},
+		{name: "only-tag", normalizer: gmail, email: "+news@gmail.com", want: "+news@gmail.com"},
+		{name: "not-an-email", normalizer: gmail, email: "Gmail.com", want: "gmail.com"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.want, test.normalizer.Normalize(test.email))
+		})
+	}
+}
@@ -33,32 +33,24 @@ func NewLogInterceptor(target io.WriteCloser) *LogInterceptor {
 }
 
 // writeFormattedLine writes a line with sequence number and timestamp to the target writer.
-// The line should include the newline character if desired.
+// The line should include the newline character if desired. It goes out in a single write,
+// so that the lines of processes appending to the same file don't interleave.
 // Returns the number of bytes written and any error encountered.
 func (i *LogInterceptor) writeFormattedLine(line []byte) (int, error) {
 	lineNum := i.sequenceNumber.Add(1)
-	totalWritten := 0
+
+	var formatted bytes.Buffer
+	formatted.Grow(len(line) + 64)
 
 	// Write the line number
-	lineNumStr := slog.Uint64("line", lineNum).String() + " "
-	n, err := io.WriteString(i.target, lineNumStr)
-	totalWritten += n
-	if err != nil {
-		return totalWritten, err
-	}
+	formatted.WriteString(slog.Uint64("line", lineNum).String() + " ")
 
 	// Write the timestamp
-	timeStr := slog.String("time", time.Now().Format(time.RFC3339)).String() + " "
-	n, err = io.WriteString(i.target, timeStr)
-	totalWritten += n
-	if err != nil {
-		return totalWritten, err
-	}
+	formatted.WriteString(slog.String("time", time.Now().Format(time.RFC3339)).String() + " ")
 
 	// Write the actual line content
-	n, err = i.target.Write(line)
-	totalWritten += n
-	return totalWritten, err
+	formatted.Write(line)
+	return i.target.Write(formatted.Bytes())
 }
 
 // Write implements io.Writer. It processes input data line by line,
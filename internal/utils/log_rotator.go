@@ -0,0 +1,260 @@
+package utils
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	logFilePermission = 0o644
+	// timestamp of the rotated files, sortable by name
+	logBackupTimeFormat = "20060102T150405.000"
+)
+
+// LogRotatorConfig configures the rotation of a log file. Zero values are unbounded
+type LogRotatorConfig struct {
+	MaxSize    int64         // rotate once the file would grow over this many bytes
+	MaxBackups int           // rotated files to keep
+	MaxAge     time.Duration // remove the rotated files older than this
+	Compress   bool          // gzip the rotated files
+}
+
+// LogRotator appends to a log file, and rotates it once it grows over the max size.
+// The rotated files sit next to it as <name>.<timestamp><ext>, gzipped if compressed.
+//
+// Several processes can log to the same file: each write is a single append, and a process
+// that finds the file already rotated by another one reopens it instead of rotating it again.
+// The newest rotated file is compressed on the next rotation only, as the other processes may
+// still be writing to it until they notice the rotation.
+type LogRotator struct {
+	path   string
+	config LogRotatorConfig
+	file   *os.File
+	mu     sync.Mutex
+	wg     sync.WaitGroup // compressions and cleanups in flight
+	now    func() time.Time
+}
+
+// NewLogRotator opens the log file at path for appending, creating it if needed
+func NewLogRotator(path string, config LogRotatorConfig) (*LogRotator, error) {
+	r := &LogRotator{
+		path:   path,
+		config: config,
+		now:    time.Now,
+	}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Write implements io.Writer. The log lines should be written whole, so that they aren't split across files
+func (r *LogRotator) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	if r.config.MaxSize > 0 {
+		// the size on disk, as other processes may be appending too
+		info, err := r.file.Stat()
+		if err == nil && info.Size() > 0 && info.Size()+int64(len(p)) > r.config.MaxSize {
+			if err := r.rotate(); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	return r.file.Write(p)
+}
+
+// Close closes the log file, once the compressions and cleanups in flight are done
+func (r *LogRotator) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.wg.Wait()
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+func (r *LogRotator) open() error {
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, logFilePermission)
+	if err != nil {
+		return err
+	}
+	r.file = file
+	return nil
+}
+
+// rotate moves the log file aside and opens a new one. Must hold the lock
+func (r *LogRotator) rotate() error {
+	current, err := r.file.Stat()
+	if err != nil {
+		return err
+	}
+
+	// the file is closed first, as windows doesn't rename open files
+	r.file.Close()
+	r.file = nil
+
+	// unless another process already rotated it, then it's only reopened
+	if info, err := os.Stat(r.path); err == nil && os.SameFile(info, current) {
+		if err := os.Rename(r.path, r.backupPath()); err != nil && !errors.Is(err, os.ErrNotExist) {
+			// keep logging to the same file rather than not at all
+			r.open()
+			return fmt.Errorf("rotate log file: %w", err)
+		}
+
+		r.wg.Add(1)
+		go func() {
+			defer r.wg.Done()
+			r.cleanup()
+		}()
+	}
+
+	return r.open()
+}
+
+// backupPath names the rotated file after the current time, a millisecond later if that's already taken
+func (r *LogRotator) backupPath() string {
+	ext := filepath.Ext(r.path)
+	stem := strings.TrimSuffix(r.path, ext)
+	for t := r.now(); ; t = t.Add(time.Millisecond) {
+		path := fmt.Sprintf("%s.%s%s", stem, t.Format(logBackupTimeFormat), ext)
+		if !FileExists(path) && !FileExists(path+".gz") {
+			return path
+		}
+	}
+}
+
+// backups lists the rotated files of the log file, from the oldest to the newest
+func (r *LogRotator) backups() ([]string, error) {
+	dir := filepath.Dir(r.path)
+	base := filepath.Base(r.path)
+	ext := filepath.Ext(base)
+	prefix := strings.TrimSuffix(base, ext) + "."
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || name == base || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		if strings.HasSuffix(name, ext) || strings.HasSuffix(name, ext+".gz") {
+			backups = append(backups, name)
+		}
+	}
+
+	// by timestamp, regardless of the compression
+	slices.SortFunc(backups, func(a, b string) int {
+		return strings.Compare(strings.TrimSuffix(a, ".gz"), strings.TrimSuffix(b, ".gz"))
+	})
+	for i, name := range backups {
+		backups[i] = filepath.Join(dir, name)
+	}
+	return backups, nil
+}
+
+// cleanup removes the rotated files over the max backups and the max age, and compresses the others but the newest.
+// It's best effort, and doesn't log its errors: the logs would go to this rotator.
+func (r *LogRotator) cleanup() {
+	backups, err := r.backups()
+	if err != nil {
+		return
+	}
+
+	if r.config.MaxBackups > 0 && len(backups) > r.config.MaxBackups {
+		for _, path := range backups[:len(backups)-r.config.MaxBackups] {
+			os.Remove(path)
+		}
+		backups = backups[len(backups)-r.config.MaxBackups:]
+	}
+
+	if r.config.MaxAge > 0 {
+		cutoff := r.now().Add(-r.config.MaxAge)
+		kept := backups[:0]
+		for _, path := range backups {
+			if info, err := os.Stat(path); err == nil && info.ModTime().Before(cutoff) {
+				os.Remove(path)
+				continue
+			}
+			kept = append(kept, path)
+		}
+		backups = kept
+	}
+
+	if !r.config.Compress || len(backups) < 2 {
+		return
+	}
+	for _, path := range backups[:len(backups)-1] {
+		if strings.HasSuffix(path, ".gz") {
+			continue
+		}
+		compressFile(path)
+	}
+}
+
+// compressFile gzips the file at path to path.gz, and removes it
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	// written aside first, so that a partial file never passes for a compressed log.
+	// another process may be compressing the same file, each writes its own temp file
+	dst, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".gz.tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := dst.Name()
+	defer os.Remove(tmpPath)
+	dst.Chmod(logFilePermission)
+
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+
+	// keep the age of the file for the cleanups
+	os.Chtimes(tmpPath, info.ModTime(), info.ModTime())
+	if err := os.Rename(tmpPath, path+".gz"); err != nil {
+		return err
+	}
+	src.Close()
+	return os.Remove(path)
+}
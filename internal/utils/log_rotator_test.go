@@ -0,0 +1,157 @@
+package utils
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readLogLines reads the lines of the log file and of its rotated files, compressed or not
+func readLogLines(t *testing.T, r *LogRotator) []string {
+	t.Helper()
+	backups, err := r.backups()
+	require.NoError(t, err)
+
+	var lines []string
+	for _, path := range append(backups, r.path) {
+		f, err := os.Open(path)
+		require.NoError(t, err)
+
+		var reader io.Reader = f
+		if strings.HasSuffix(path, ".gz") {
+			gz, err := gzip.NewReader(f)
+			require.NoError(t, err, path)
+			reader = gz
+		}
+
+		scanner := bufio.NewScanner(reader)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		require.NoError(t, scanner.Err(), path)
+		f.Close()
+	}
+	return lines
+}
+
+func TestLogRotatorRotatesBySize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "syftbox.log")
+	r, err := NewLogRotator(path, LogRotatorConfig{MaxSize: 100, MaxBackups: 3, Compress: true})
+	require.NoError(t, err)
+
+	// 10 bytes per line, 10 lines per file
+	for i := range 45 {
+		_, err := fmt.Fprintf(r, "line %04d\n", i)
+		require.NoError(t, err)
+	}
+	// the cleanups and compressions are done on close
+	require.NoError(t, r.Close())
+
+	backups, err := r.backups()
+	require.NoError(t, err)
+	require.Len(t, backups, 3)
+	// all but the newest rotated file are compressed
+	assert.True(t, strings.HasSuffix(backups[0], ".log.gz"), backups[0])
+	assert.True(t, strings.HasSuffix(backups[1], ".log.gz"), backups[1])
+	assert.True(t, strings.HasSuffix(backups[2], ".log"), backups[2])
+
+	// the oldest lines were rotated out, the others are all there in order
+	lines := readLogLines(t, r)
+	require.Len(t, lines, 35)
+	assert.Equal(t, "line 0010", lines[0])
+	assert.Equal(t, "line 0044", lines[34])
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.EqualValues(t, 50, info.Size())
+}
+
+func TestLogRotatorAppendsOnReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "syftbox.log")
+	for run := range 2 {
+		r, err := NewLogRotator(path, LogRotatorConfig{MaxSize: 1024})
+		require.NoError(t, err)
+		fmt.Fprintf(r, "run %d\n", run)
+		require.NoError(t, r.Close())
+	}
+
+	// the logs of the previous run are kept
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "run 0\nrun 1\n", string(content))
+}
+
+func TestLogRotatorRemovesOldBackups(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "syftbox.log")
+	old := filepath.Join(dir, "syftbox.20240101T000000.000.log.gz")
+	recent := filepath.Join(dir, "syftbox.20240201T000000.000.log")
+	other := filepath.Join(dir, "syftbox-7939.20240101T000000.000.log")
+	for _, p := range []string{old, recent, other} {
+		require.NoError(t, os.WriteFile(p, []byte("x\n"), 0o644))
+	}
+	now := time.Now()
+	require.NoError(t, os.Chtimes(old, now.Add(-48*time.Hour), now.Add(-48*time.Hour)))
+
+	r, err := NewLogRotator(path, LogRotatorConfig{MaxSize: 10, MaxAge: 24 * time.Hour})
+	require.NoError(t, err)
+	fmt.Fprintln(r, "first line")
+	fmt.Fprintln(r, "second line")
+	require.NoError(t, r.Close())
+
+	assert.NoFileExists(t, old)
+	assert.FileExists(t, recent)
+	// the rotated files of another log file are left alone
+	assert.FileExists(t, other)
+}
+
+func TestLogRotatorConcurrentProcesses(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "syftbox.log")
+
+	// two daemons logging to the same file, each with its own rotator
+	var wg sync.WaitGroup
+	rotators := make([]*LogRotator, 2)
+	for i := range rotators {
+		r, err := NewLogRotator(path, LogRotatorConfig{MaxSize: 2048, Compress: true})
+		require.NoError(t, err)
+		rotators[i] = r
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 500 {
+				_, err := fmt.Fprintf(r, "daemon %d line %04d\n", i, j)
+				assert.NoError(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+	for _, r := range rotators {
+		require.NoError(t, r.Close())
+	}
+
+	backups, err := rotators[0].backups()
+	require.NoError(t, err)
+	assert.Greater(t, len(backups), 1)
+
+	// every line is there exactly once, and whole
+	seen := make(map[string]int)
+	for _, line := range readLogLines(t, rotators[0]) {
+		seen[line]++
+	}
+	assert.Len(t, seen, 1000)
+	for line, count := range seen {
+		assert.Regexp(t, `^daemon \d line \d{4}$`, line)
+		assert.Equal(t, 1, count, line)
+	}
+}
@@ -0,0 +1,12 @@
+package utils
+
+import "time"
+
+// Percentile returns the p-th percentile of sorted durations, using the nearest rank below
+func Percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(float64(len(sorted)-1) * p)
+	return sorted[idx]
+}
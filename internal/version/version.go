@@ -20,6 +20,15 @@ var (
 	BuildDate = ""
 )
 
+const (
+	// WireVersion is the version of the protocol between the client and the server.
+	// Bump it when a change needs the client and the server to be updated together.
+	WireVersion = 2
+
+	// MinWireVersion is the oldest wire version of a peer that is still understood
+	MinWireVersion = 1
+)
+
 // Short returns a concise version string - `0.1.0 (5e23a4)`
 func Short() string {
 	return fmt.Sprintf("%s (%s)", Version, Revision)